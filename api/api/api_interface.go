@@ -168,6 +168,13 @@ type TenantInterfaceWithV1 interface {
 	CreateVMExport(w http.ResponseWriter, r *http.Request)
 	GetVMExport(w http.ResponseWriter, r *http.Request)
 	CreateVMSnapshot(w http.ResponseWriter, r *http.Request)
+	ListVMSnapshots(w http.ResponseWriter, r *http.Request)
+	DeleteVMSnapshot(w http.ResponseWriter, r *http.Request)
+	RestoreVMSnapshot(w http.ResponseWriter, r *http.Request)
+	GetVMSnapshotSchedule(w http.ResponseWriter, r *http.Request)
+	SetVMSnapshotSchedule(w http.ResponseWriter, r *http.Request)
+	DeleteVMSnapshotSchedule(w http.ResponseWriter, r *http.Request)
+	CloneVM(w http.ResponseWriter, r *http.Request)
 	FileManageService(w http.ResponseWriter, r *http.Request)
 	DeployService(w http.ResponseWriter, r *http.Request)
 	UpgradeService(w http.ResponseWriter, r *http.Request)
@@ -187,6 +194,7 @@ type LogInterface interface {
 	Events(w http.ResponseWriter, r *http.Request)
 	EventLog(w http.ResponseWriter, r *http.Request)
 	MyTeamsEvents(w http.ResponseWriter, r *http.Request)
+	SearchEventLogs(w http.ResponseWriter, r *http.Request)
 }
 
 // PluginInterface plugin interface
@@ -261,6 +269,9 @@ type ApplicationInterface interface {
 	DeleteGovernanceModeCR(w http.ResponseWriter, r *http.Request)
 	GetWatchOperatorManaged(w http.ResponseWriter, r *http.Request)
 	ChangeVolumes(w http.ResponseWriter, r *http.Request)
+	ListGrayReleases(w http.ResponseWriter, r *http.Request)
+	GrayReleaseAction(w http.ResponseWriter, r *http.Request)
+	GetZeroTrustReport(w http.ResponseWriter, r *http.Request)
 }
 
 // Gatewayer gateway api interface
@@ -397,4 +408,7 @@ type KubeBlocksInterface interface {
 	GetClusterParameters(w http.ResponseWriter, r *http.Request)
 	ChangeClusterParameters(w http.ResponseWriter, r *http.Request)
 	RestoreClusterFromBackup(w http.ResponseWriter, r *http.Request)
+	GetClusterRecoverableRanges(w http.ResponseWriter, r *http.Request)
+	RestoreClusterToPointInTime(w http.ResponseWriter, r *http.Request)
+	GetClusterPITRRestore(w http.ResponseWriter, r *http.Request)
 }
@@ -62,6 +62,7 @@ func (v2 *V2) Routes() chi.Router {
 	r.Get("/event", controller.GetManager().Event)
 	r.Mount("/app", v2.appRouter())
 	r.Post("/alertmanager-webhook", controller.GetManager().AlertManagerWebHook)
+	r.Post("/git-webhooks/{provider}", controller.ReceiveGitWebhook)
 	r.Get("/version", controller.GetManager().Version)
 	// deprecated use /gateway/ports
 	r.Mount("/port", v2.portRouter())
@@ -81,6 +82,8 @@ func (v2 *V2) Routes() chi.Router {
 	r.Mount("/proxy-pass", v2.proxyRoute())
 	r.Get("/pods/logs", controller.GetManager().PodLogs)
 	r.Mount("/platform", v2.platformPluginsRouter())
+	r.Mount("/api-tokens", v2.apiTokenRouter())
+	r.Mount("/audit-logs", v2.auditLogRouter())
 
 	return r
 }
@@ -127,6 +130,8 @@ func (v2 *V2) eventsRouter() chi.Router {
 	r.Get("/", controller.GetManager().Events)
 	// get my teams event list with page
 	r.Get("/myteam", controller.GetManager().MyTeamsEvents)
+	// search the logs of target's events
+	r.Get("/search", controller.GetManager().SearchEventLogs)
 	// get target's event content
 	r.Get("/{eventID}/log", controller.GetManager().EventLog)
 	// stream target's event content
@@ -350,6 +355,7 @@ func (v2 *V2) clusterRouter() chi.Router {
 	r.Get("/kubeblocks/clusters/{service_id}/parameters", controller.GetManager().GetClusterParameters)
 	r.Post("/kubeblocks/clusters/{service_id}/parameters", controller.GetManager().ChangeClusterParameters)
 	r.Post("/kubeblocks/clusters/{service_id}/restores", controller.GetManager().RestoreClusterFromBackup)
+	r.Get("/kubeblocks/clusters/{service_id}/recoverable-ranges", controller.GetManager().GetClusterRecoverableRanges)
 	// StorageClasses
 	r.Get("/storageclasses", controller.GetStorageController().ListStorageClasses)
 	r.Post("/storageclasses", controller.GetStorageController().CreateStorageClass)
@@ -425,6 +431,12 @@ func (v2 *V2) tenantNameRouter() chi.Router {
 	r.Post("/batch-build-plugins", controller.GetManager().BatchBuildPlugins)
 	r.Post("/services_status", controller.GetManager().StatusServiceList)
 	r.Mount("/services/{service_alias}", v2.serviceRouter())
+	r.Get("/terminal-recording", controller.GetTerminalRecording)
+	r.Put("/terminal-recording", controller.SetTerminalRecording)
+	r.Get("/terminal-sessions", controller.ListTerminalSessions)
+	r.Get("/terminal-sessions/open", controller.ListOpenTerminalSessions)
+	r.Delete("/terminal-sessions/open/{session_id}", controller.CloseTerminalSession)
+	r.Get("/terminal-sessions/{session_id}/recording", controller.TerminalSessionRecording)
 	r.Mount("/plugin/{plugin_id}", v2.pluginRouter())
 	r.Get("/event", controller.GetManager().Event)
 	//tenant app
@@ -445,8 +457,23 @@ func (v2 *V2) tenantNameRouter() chi.Router {
 	r.Post("/groupapp/backupcopy", controller.BackupCopy)
 	r.Get("/groupapp/backups/{backup_id}", controller.GetBackup)
 	r.Delete("/groupapp/backups/{backup_id}", controller.DeleteBackup)
+	r.Get("/groupapp/backups/{backup_id}/verify", controller.VerifyBackup)
 	r.Post("/groupapp/backups/{backup_id}/restore", controller.Restore)
 	r.Get("/groupapp/backups/{backup_id}/restore/{restore_id}", controller.RestoreResult)
+	r.Get("/groupapp/backup-schedules", controller.BackupSchedules)
+	r.Post("/groupapp/backup-schedules", controller.NewBackupSchedule)
+	r.Put("/groupapp/backup-schedules/{schedule_id}", controller.UpdateBackupSchedule)
+	r.Delete("/groupapp/backup-schedules/{schedule_id}", controller.DeleteBackupSchedule)
+	//团队的外发通知渠道与订阅规则
+	r.Get("/notification-channels", controller.ListNotificationChannels)
+	r.Post("/notification-channels", controller.CreateNotificationChannel)
+	r.Put("/notification-channels/{channel_id}", controller.UpdateNotificationChannel)
+	r.Delete("/notification-channels/{channel_id}", controller.DeleteNotificationChannel)
+	r.Post("/notification-channels/{channel_id}/test", controller.TestNotificationChannel)
+	r.Get("/notification-rules", controller.ListNotificationRules)
+	r.Post("/notification-rules", controller.CreateNotificationRule)
+	r.Put("/notification-rules/{rule_id}", controller.UpdateNotificationRule)
+	r.Delete("/notification-rules/{rule_id}", controller.DeleteNotificationRule)
 	r.Post("/deployversions", controller.GetManager().GetManyDeployVersion)
 	//团队资源限制
 	r.Post("/limit_resource", controller.GetManager().LimitTenantResource)
@@ -489,6 +516,7 @@ func (v2 *V2) tenantNameRouter() chi.Router {
 	r.Get("/helm/releases/{release_name}", controller.GetHelmReleaseController().GetReleaseDetail)
 	r.Get("/helm/releases/{release_name}/history", controller.GetHelmReleaseController().GetReleaseHistory)
 	r.Put("/helm/releases/{release_name}", controller.GetHelmReleaseController().UpgradeRelease)
+	r.Post("/helm/releases/{release_name}/upgrade-diff", controller.GetHelmReleaseController().DiffUpgrade)
 	r.Post("/helm/releases/{release_name}/rollback", controller.GetHelmReleaseController().RollbackRelease)
 	r.Delete("/helm/releases/{release_name}", controller.GetHelmReleaseController().UninstallRelease)
 
@@ -527,6 +555,23 @@ func (v2 *V2) serviceRouter() chi.Router {
 	r.Post("/vm-exports", middleware.WrapEL(controller.GetManager().CreateVMExport, dbmodel.TargetTypeService, "export-vm", dbmodel.SYNEVENTTYPE, true))
 	r.Get("/vm-exports/{name}", controller.GetManager().GetVMExport)
 	r.Post("/vm-snapshots", middleware.WrapEL(controller.GetManager().CreateVMSnapshot, dbmodel.TargetTypeService, "snapshot-vm", dbmodel.SYNEVENTTYPE, true))
+	r.Get("/vm-snapshots", controller.GetManager().ListVMSnapshots)
+	r.Delete("/vm-snapshots/{name}", middleware.WrapEL(controller.GetManager().DeleteVMSnapshot, dbmodel.TargetTypeService, "delete-vm-snapshot", dbmodel.SYNEVENTTYPE, true))
+	r.Post("/vm-snapshots/{name}/restore", middleware.WrapEL(controller.GetManager().RestoreVMSnapshot, dbmodel.TargetTypeService, "restore-vm-snapshot", dbmodel.ASYNEVENTTYPE, true))
+	r.Get("/vm-snapshot-schedule", controller.GetManager().GetVMSnapshotSchedule)
+	r.Put("/vm-snapshot-schedule", controller.GetManager().SetVMSnapshotSchedule)
+	r.Delete("/vm-snapshot-schedule", controller.GetManager().DeleteVMSnapshotSchedule)
+	r.Post("/vm-clone", middleware.WrapEL(controller.GetManager().CloneVM, dbmodel.TargetTypeService, "clone-vm", dbmodel.SYNEVENTTYPE, true))
+	// point-in-time restore of kubeblocks component
+	r.Post("/kubeblocks/pitr-restores", middleware.WrapEL(controller.GetManager().RestoreClusterToPointInTime, dbmodel.TargetTypeService, "kubeblocks-pitr-restore", dbmodel.ASYNEVENTTYPE, true))
+	r.Get("/kubeblocks/pitr-restores/{name}", controller.GetManager().GetClusterPITRRestore)
+	// source build cache
+	r.Get("/build-cache", controller.GetBuildCache)
+	r.Delete("/build-cache", middleware.WrapEL(controller.InvalidateBuildCache, dbmodel.TargetTypeService, "build-cache-invalidate", dbmodel.ASYNEVENTTYPE, true))
+	r.Post("/build-cache/warm", middleware.WrapEL(controller.WarmBuildCache, dbmodel.TargetTypeService, "build-cache-warm", dbmodel.ASYNEVENTTYPE, true))
+	r.Get("/git-webhook", controller.GetGitWebhook)
+	r.Put("/git-webhook", middleware.WrapEL(controller.SetGitWebhook, dbmodel.TargetTypeService, "set-git-webhook", dbmodel.SYNEVENTTYPE, false))
+	r.Delete("/git-webhook", middleware.WrapEL(controller.DeleteGitWebhook, dbmodel.TargetTypeService, "delete-git-webhook", dbmodel.SYNEVENTTYPE, false))
 	r.Post("/start", middleware.WrapEL(controller.GetManager().StartService, dbmodel.TargetTypeService, "start-service", dbmodel.ASYNEVENTTYPE, true))
 	// component stop event set to synchronous event, not wait.
 	r.Post("/stop", middleware.WrapEL(controller.GetManager().StopService, dbmodel.TargetTypeService, "stop-service", dbmodel.SYNEVENTTYPE, true))
@@ -693,6 +738,11 @@ func (v2 *V2) applicationRouter() chi.Router {
 	// Synchronize component information, full coverage
 	r.Post("/components", controller.GetManager().SyncComponents)
 	r.Post("/app-config-groups", controller.GetManager().SyncAppConfigGroups)
+	// gray release
+	r.Get("/gray-releases", controller.GetManager().ListGrayReleases)
+	r.Post("/gray-releases/{rollout_name}/action", controller.GetManager().GrayReleaseAction)
+	// zero trust
+	r.Get("/zero-trust", controller.GetManager().GetZeroTrustReport)
 	return r
 }
 
@@ -752,6 +802,22 @@ func (v2 *V2) portRouter() chi.Router {
 	return r
 }
 
+func (v2 *V2) apiTokenRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", controller.ListAPITokens)
+	r.Post("/", controller.CreateAPIToken)
+	r.Delete("/{name}", controller.RevokeAPIToken)
+	return r
+}
+
+func (v2 *V2) auditLogRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", controller.ListAuditLogs)
+	r.Get("/export", controller.ExportAuditLogs)
+	r.Get("/verify", controller.VerifyAuditLogs)
+	return r
+}
+
 func (v2 *V2) licenseRouter() chi.Router {
 	r := chi.NewRouter()
 	r.Get("/cluster-id", controller.GetLicenseV2Controller().GetClusterID)
@@ -0,0 +1,61 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/api/handler"
+	api_model "github.com/goodrain/rainbond/api/model"
+	httputil "github.com/goodrain/rainbond/util/http"
+)
+
+// ListAPITokens list the scoped api tokens
+func ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := handler.GetAPITokenHandler().ListAPITokens()
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, tokens)
+}
+
+// CreateAPIToken issue a scoped api token, the token is only returned once
+func CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	var req api_model.CreateAPITokenReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	token, err := handler.GetAPITokenHandler().CreateAPIToken(&req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, token)
+}
+
+// RevokeAPIToken revoke a scoped api token
+func RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if err := handler.GetAPITokenHandler().RevokeAPIToken(chi.URLParam(r, "name")); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
@@ -399,6 +399,45 @@ func (a *ApplicationController) ChangeVolumes(w http.ResponseWriter, r *http.Req
 	httputil.ReturnSuccess(r, w, nil)
 }
 
+// ListGrayReleases list the gray releases of the application.
+func (a *ApplicationController) ListGrayReleases(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Context().Value(ctxutil.ContextKey("tenant")).(*dbmodel.Tenants)
+	app := r.Context().Value(ctxutil.ContextKey("application")).(*dbmodel.Application)
+	ret, err := handler.GetApplicationHandler().ListGrayReleases(r.Context(), app, tenant.Namespace)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, ret)
+}
+
+// GrayReleaseAction promote, pause, resume or rollback the gray release.
+func (a *ApplicationController) GrayReleaseAction(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Context().Value(ctxutil.ContextKey("tenant")).(*dbmodel.Tenants)
+	app := r.Context().Value(ctxutil.ContextKey("application")).(*dbmodel.Application)
+	var req model.GrayReleaseActionReq
+	if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+		return
+	}
+	err := handler.GetApplicationHandler().GrayReleaseAction(r.Context(), app, tenant.Namespace, chi.URLParam(r, "rollout_name"), req.Action)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// GetZeroTrustReport lists the consumers allowed by the zero trust policies and the blocked flows.
+func (a *ApplicationController) GetZeroTrustReport(w http.ResponseWriter, r *http.Request) {
+	app := r.Context().Value(ctxutil.ContextKey("application")).(*dbmodel.Application)
+	report, err := handler.GetApplicationHandler().GetZeroTrustReport(app)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, report)
+}
+
 // cleanupAppKubernetesResources 清理与应用相关的 K8s 资源
 func cleanupAppKubernetesResources(tenantID, appID string) error {
 	tenant, err := db.GetManager().TenantDao().GetTenantByUUID(tenantID)
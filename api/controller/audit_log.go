@@ -0,0 +1,110 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodrain/rainbond/api/handler"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	httputil "github.com/goodrain/rainbond/util/http"
+	"github.com/sirupsen/logrus"
+)
+
+// parseAuditLogQuery parses the conditions of the audit logs, the times are RFC3339
+func parseAuditLogQuery(r *http.Request) (*dbmodel.AuditLogQuery, error) {
+	values := r.URL.Query()
+	query := &dbmodel.AuditLogQuery{
+		TenantID:  values.Get("tenant_id"),
+		ServiceID: values.Get("service_id"),
+		Operator:  values.Get("operator"),
+		TokenName: values.Get("token_name"),
+	}
+	for key, target := range map[string]**time.Time{"start_time": &query.StartTime, "end_time": &query.EndTime} {
+		if value := values.Get(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			*target = &t
+		}
+	}
+	return query, nil
+}
+
+// ListAuditLogs list the audit logs
+func ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditLogQuery(r)
+	if err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	logs, total, err := handler.GetAuditLogHandler().ListAuditLogs(query, page, pageSize)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnList(r, w, int(total), page, logs)
+}
+
+// ExportAuditLogs export the audit logs as JSON lines or CSV
+func ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditLogQuery(r)
+	if err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", handler.AuditLogFormatJSONLines:
+		format = handler.AuditLogFormatJSONLines
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	case handler.AuditLogFormatCSV:
+		w.Header().Set("Content-Type", "text/csv")
+	default:
+		httputil.ReturnError(r, w, 400, fmt.Sprintf("unsupported format %q", format))
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit-logs.%s", format))
+	if err := handler.GetAuditLogHandler().ExportAuditLogs(w, format, query); err != nil {
+		// 响应已开始输出，只能记录错误
+		logrus.Errorf("export audit logs: %v", err)
+	}
+}
+
+// VerifyAuditLogs verify the hash chain of the audit logs
+func VerifyAuditLogs(w http.ResponseWriter, r *http.Request) {
+	res, err := handler.GetAuditLogHandler().VerifyAuditLogs()
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, res)
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/goodrain/rainbond/api/handler"
 	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
 	httputil "github.com/goodrain/rainbond/util/http"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
@@ -76,7 +77,8 @@ func (t *TenantStruct) Event(w http.ResponseWriter, r *http.Request) {
 }
 
 //GetNotificationEvents GetNotificationEvent
-//support query from start and end time or all
+//support query from start and end time or all,
+//filter by kind, kind_id, service_id, source and state, e.g. the firing alerts of a component
 // swagger:operation GET  /v2/notificationEvent v2/notificationEvent getevents
 //
 // 获取数据中心通知事件
@@ -103,13 +105,35 @@ func GetNotificationEvents(w http.ResponseWriter, r *http.Request) {
 	if ei, err := strconv.Atoi(end); err == nil {
 		endTime = time.Unix(int64(ei), 0)
 	}
-	res, err := db.GetManager().NotificationEventDao().GetNotificationEventByTime(startTime, endTime)
+	query := &dbmodel.NotificationEventQuery{
+		Kind:        r.FormValue("kind"),
+		KindID:      r.FormValue("kind_id"),
+		Source:      r.FormValue("source"),
+		State:       r.FormValue("state"),
+		Start:       startTime,
+		End:         endTime,
+		WithHandled: r.FormValue("with_handled") == "true",
+	}
+	if serviceID := r.FormValue("service_id"); serviceID != "" {
+		query.Kind, query.KindID = "service", serviceID
+	}
+	var res []*dbmodel.NotificationEvent
+	var err error
+	if query.Kind != "" || query.KindID != "" || query.Source != "" || query.State != "" || query.WithHandled {
+		res, err = db.GetManager().NotificationEventDao().ListNotificationEvents(query)
+	} else {
+		res, err = db.GetManager().NotificationEventDao().GetNotificationEventByTime(startTime, endTime)
+	}
 	if err != nil {
 		logrus.Error(err)
 		httputil.ReturnError(r, w, 500, err.Error())
 		return
 	}
 	for _, v := range res {
+		// only the component events carry the component name
+		if v.Kind != "service" {
+			continue
+		}
 		service, err := db.GetManager().TenantServiceDao().GetServiceByID(v.KindID)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
@@ -23,8 +23,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	httputil "github.com/goodrain/rainbond/util/http"
 
@@ -313,3 +315,63 @@ func (e *EventLogStruct) MyTeamsEvents(w http.ResponseWriter, r *http.Request) {
 	}
 	httputil.ReturnList(r, w, size, page, res)
 }
+
+//SearchEventLogs search the event logs of a tenant or a target
+func (e *EventLogStruct) SearchEventLogs(w http.ResponseWriter, r *http.Request) {
+	req := &api_model.EventLogSearchReq{
+		TenantID: r.FormValue("tenant_id"),
+		Target:   r.FormValue("target"),
+		TargetID: r.FormValue("target-id"),
+		Keyword:  r.FormValue("keyword"),
+		Regex:    r.FormValue("regex"),
+		Step:     r.FormValue("step"),
+		Level:    r.FormValue("level"),
+	}
+	if req.TenantID == "" && (req.Target == "" || req.TargetID == "") {
+		httputil.ReturnError(r, w, 400, "tenant_id or target and target-id is required")
+		return
+	}
+	var err error
+	if req.Since, err = parseSearchTime(r.FormValue("since")); err != nil {
+		httputil.ReturnError(r, w, 400, "invalid since: "+err.Error())
+		return
+	}
+	if req.Until, err = parseSearchTime(r.FormValue("until")); err != nil {
+		httputil.ReturnError(r, w, 400, "invalid until: "+err.Error())
+		return
+	}
+	if req.Regex != "" {
+		if _, err := regexp.Compile(req.Regex); err != nil {
+			httputil.ReturnError(r, w, 400, "invalid regex: "+err.Error())
+			return
+		}
+	}
+	if req.Page, err = strconv.Atoi(r.FormValue("page")); err != nil || req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize, err = strconv.Atoi(r.FormValue("page_size")); err != nil || req.PageSize <= 0 || req.PageSize > 1000 {
+		req.PageSize = 50
+	}
+	result, err := handler.GetEventHandler().SearchEventLogs(req)
+	if err != nil {
+		logrus.Errorf("search event logs error, %v", err)
+		httputil.ReturnError(r, w, 500, "search event logs error: "+err.Error())
+		return
+	}
+	httputil.ReturnList(r, w, result.Total, req.Page, result.Hits)
+}
+
+// parseSearchTime parses unix seconds or RFC3339 time, empty means unbounded
+func parseSearchTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return unix, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/api/handler"
+	api_model "github.com/goodrain/rainbond/api/model"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	httputil "github.com/goodrain/rainbond/util/http"
+)
+
+// gitWebhookMaxPayload the max size of the push payloads, github caps them at 25MB
+const gitWebhookMaxPayload = 25 << 20
+
+// GetGitWebhook get the git push webhook of the component and its latest deliveries
+func GetGitWebhook(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	status, err := handler.GetGitWebhookHandler().GetGitWebhook(serviceID)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+// SetGitWebhook create or update the git push webhook of the component
+func SetGitWebhook(w http.ResponseWriter, r *http.Request) {
+	var req api_model.GitWebhookReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	status, err := handler.GetGitWebhookHandler().SetGitWebhook(tenantID, serviceID, &req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+// DeleteGitWebhook delete the git push webhook of the component
+func DeleteGitWebhook(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	if err := handler.GetGitWebhookHandler().DeleteGitWebhook(serviceID); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// ReceiveGitWebhook receive the push deliveries of github, gitlab, gitea and bitbucket,
+// the deliveries are authenticated by their signatures instead of the api token
+func ReceiveGitWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, gitWebhookMaxPayload))
+	if err != nil {
+		httputil.ReturnError(r, w, 400, "read webhook payload failure")
+		return
+	}
+	result, err := handler.GetGitWebhookHandler().HandleDelivery(chi.URLParam(r, "provider"), r.Header, body)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, result)
+}
@@ -104,6 +104,17 @@ func RestoreResult(w http.ResponseWriter, r *http.Request) {
 	httputil.ReturnSuccess(r, w, bean)
 }
 
+//VerifyBackup verify the integrity of the backup artifacts
+func VerifyBackup(w http.ResponseWriter, r *http.Request) {
+	backupID := chi.URLParam(r, "backup_id")
+	bean, err := handler.GetAPPBackupHandler().VerifyBackup(backupID, r.FormValue("bucket_name"))
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, bean)
+}
+
 //GetBackup get one backup status
 func GetBackup(w http.ResponseWriter, r *http.Request) {
 	backupID := chi.URLParam(r, "backup_id")
@@ -125,8 +136,67 @@ func DeleteBackup(w http.ResponseWriter, r *http.Request) {
 			httputil.ReturnError(r, w, 404, "not found")
 			return
 		}
+		if err == group.ErrBackupHasChildren {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
 		httputil.ReturnError(r, w, 500, err.Error())
 		return
 	}
 	httputil.ReturnSuccess(r, w, nil)
 }
+
+//BackupSchedules list the backup schedules of group app
+func BackupSchedules(w http.ResponseWriter, r *http.Request) {
+	groupID := r.FormValue("group_id")
+	if groupID == "" {
+		httputil.ReturnError(r, w, 400, "group id can not be empty")
+		return
+	}
+	list, err := handler.GetAPPBackupHandler().GetBackupSchedules(groupID)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, list)
+}
+
+//NewBackupSchedule new group app backup schedule
+func NewBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	var bs group.BackupSchedule
+	ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &bs.Body, nil)
+	if !ok {
+		return
+	}
+	bean, err := handler.GetAPPBackupHandler().CreateBackupSchedule(bs)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, bean)
+}
+
+//UpdateBackupSchedule update group app backup schedule
+func UpdateBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	var bs group.BackupSchedule
+	ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &bs.Body, nil)
+	if !ok {
+		return
+	}
+	bs.ScheduleID = chi.URLParam(r, "schedule_id")
+	bean, err := handler.GetAPPBackupHandler().UpdateBackupSchedule(bs)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, bean)
+}
+
+//DeleteBackupSchedule delete group app backup schedule
+func DeleteBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := handler.GetAPPBackupHandler().DeleteBackupSchedule(chi.URLParam(r, "schedule_id")); err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
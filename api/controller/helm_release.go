@@ -124,6 +124,29 @@ func (c *HelmReleaseController) UpgradeRelease(w http.ResponseWriter, r *http.Re
 	httputil.ReturnSuccess(r, w, rel)
 }
 
+// DiffUpgrade renders the upgrade in request body without applying it and returns the diff against the live release.
+func (c *HelmReleaseController) DiffUpgrade(w http.ResponseWriter, r *http.Request) {
+	tenantName := chi.URLParam(r, "tenant_name")
+	releaseName := chi.URLParam(r, "release_name")
+	var req installReleaseReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	req.ReleaseName = releaseName
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		httputil.ReturnBcodeError(r, w, httputil.NewErrBadRequest(err))
+		return
+	}
+	diff, err := handler.GetHelmReleaseHandler().DiffUpgrade(tenantName, releaseName, req.HelmReleaseInstallRequest)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, diff)
+}
+
 // RollbackRelease rolls an existing Helm release back to a previous revision.
 func (c *HelmReleaseController) RollbackRelease(w http.ResponseWriter, r *http.Request) {
 	tenantName := chi.URLParam(r, "tenant_name")
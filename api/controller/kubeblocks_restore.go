@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/api/handler"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	httputil "github.com/goodrain/rainbond/util/http"
+)
+
+// GetClusterRecoverableRanges lists the time ranges the cluster can be restored to
+func (c *KubeBlocksController) GetClusterRecoverableRanges(w http.ResponseWriter, r *http.Request) {
+	ranges, err := handler.GetServiceManager().ListKubeBlocksRecoverableRanges(chi.URLParam(r, "service_id"))
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, ranges)
+}
+
+// RestoreClusterToPointInTime restores the cluster of the current kubeblocks component at a point in time into a new component
+func (c *KubeBlocksController) RestoreClusterToPointInTime(w http.ResponseWriter, r *http.Request) {
+	var req handler.KubeBlocksPITRRestoreRequest
+	if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	status, err := handler.GetServiceManager().RestoreKubeBlocksPITR(r.Context(), tenantID, serviceID, &req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+// GetClusterPITRRestore returns the status and progress of the point-in-time restore into the current component
+func (c *KubeBlocksController) GetClusterPITRRestore(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	status, err := handler.GetServiceManager().GetKubeBlocksRestore(serviceID, chi.URLParam(r, "name"))
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
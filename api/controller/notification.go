@@ -0,0 +1,141 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/api/handler"
+	api_model "github.com/goodrain/rainbond/api/model"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	httputil "github.com/goodrain/rainbond/util/http"
+)
+
+// ListNotificationChannels list the notification channels of the tenant
+func ListNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	channels, err := handler.GetNotificationHandler().ListChannels(tenantID)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, channels)
+}
+
+// CreateNotificationChannel create a notification channel of the tenant
+func CreateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	var req api_model.NotificationChannelReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	channel, err := handler.GetNotificationHandler().CreateChannel(tenantID, &req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, channel)
+}
+
+// UpdateNotificationChannel update a notification channel, the empty url and credentials are kept
+func UpdateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	var req api_model.NotificationChannelReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	channel, err := handler.GetNotificationHandler().UpdateChannel(tenantID, chi.URLParam(r, "channel_id"), &req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, channel)
+}
+
+// DeleteNotificationChannel delete a notification channel and its rules
+func DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	if err := handler.GetNotificationHandler().DeleteChannel(tenantID, chi.URLParam(r, "channel_id")); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// TestNotificationChannel send a test notification to the channel
+func TestNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	if err := handler.GetNotificationHandler().TestChannel(tenantID, chi.URLParam(r, "channel_id")); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// ListNotificationRules list the notification rules of the tenant
+func ListNotificationRules(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	rules, err := handler.GetNotificationHandler().ListRules(tenantID)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, rules)
+}
+
+// CreateNotificationRule create a notification rule of the tenant
+func CreateNotificationRule(w http.ResponseWriter, r *http.Request) {
+	var req api_model.NotificationRuleReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	rule, err := handler.GetNotificationHandler().CreateRule(tenantID, &req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, rule)
+}
+
+// UpdateNotificationRule update a notification rule
+func UpdateNotificationRule(w http.ResponseWriter, r *http.Request) {
+	var req api_model.NotificationRuleReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	rule, err := handler.GetNotificationHandler().UpdateRule(tenantID, chi.URLParam(r, "rule_id"), &req)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, rule)
+}
+
+// DeleteNotificationRule delete a notification rule
+func DeleteNotificationRule(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	if err := handler.GetNotificationHandler().DeleteRule(tenantID, chi.URLParam(r, "rule_id")); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
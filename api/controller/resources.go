@@ -24,7 +24,6 @@ import (
 	apigateway "github.com/goodrain/rainbond/api/controller/apigateway"
 	"github.com/goodrain/rainbond/pkg/component/k8s"
 	validation "github.com/goodrain/rainbond/util/endpoint"
-	"io/ioutil"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
 	"net/url"
@@ -98,19 +97,19 @@ func (v2 *V2Routes) Health(w http.ResponseWriter, r *http.Request) {
 	httputil.ReturnSuccess(r, w, map[string]string{"status": "health", "info": "api service health"})
 }
 
-// AlertManagerWebHook -
+// AlertManagerWebHook receive the alerts of alertmanager and save them as notification events
 func (v2 *V2Routes) AlertManagerWebHook(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("=======>webhook")
-	in, err := ioutil.ReadAll(r.Body)
+	var payload apimodel.AlertManagerWebhook
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httputil.ReturnBcodeError(r, w, bcode.ErrAlertManagerPayload)
+		return
+	}
+	result, err := handler.GetAlertManagerHandler().IngestAlerts(&payload)
 	if err != nil {
-		fmt.Println(err)
-		httputil.ReturnError(r, w, 400, "")
+		httputil.ReturnBcodeError(r, w, err)
 		return
 	}
-	fmt.Println("=====>body")
-	fmt.Println(string(in))
-	httputil.ReturnSuccess(r, w, "")
-
+	httputil.ReturnSuccess(r, w, result)
 }
 
 // Version -
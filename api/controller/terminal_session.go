@@ -0,0 +1,143 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/api/handler"
+	api_model "github.com/goodrain/rainbond/api/model"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	httputil "github.com/goodrain/rainbond/util/http"
+	"github.com/sirupsen/logrus"
+)
+
+// GetTerminalRecording get the terminal recording switch of the tenant
+func GetTerminalRecording(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	enable, err := handler.GetTerminalSessionHandler().GetTerminalRecording(tenantID)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, api_model.TerminalRecordingReq{Enable: enable})
+}
+
+// SetTerminalRecording enable or disable the terminal recording of the tenant
+func SetTerminalRecording(w http.ResponseWriter, r *http.Request) {
+	var req api_model.TerminalRecordingReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	if err := handler.GetTerminalSessionHandler().SetTerminalRecording(tenantID, req.Enable); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, req)
+}
+
+// ListTerminalSessions list the terminal sessions of the tenant by component, user and time
+func ListTerminalSessions(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := &dbmodel.TerminalSessionQuery{
+		TenantID:  r.Context().Value(ctxutil.ContextKey("tenant_id")).(string),
+		ServiceID: values.Get("service_id"),
+		User:      values.Get("user"),
+	}
+	for key, target := range map[string]**time.Time{"start_time": &query.StartTime, "end_time": &query.EndTime} {
+		if value := values.Get(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				httputil.ReturnError(r, w, 400, fmt.Sprintf("invalid %s %q", key, value))
+				return
+			}
+			*target = &t
+		}
+	}
+	page, _ := strconv.Atoi(values.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(values.Get("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	sessions, total, err := handler.GetTerminalSessionHandler().ListTerminalSessions(query, page, pageSize)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnList(r, w, int(total), page, sessions)
+}
+
+// TerminalSessionRecording stream the asciicast recording of the session for playback
+func TerminalSessionRecording(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	sessionID := chi.URLParam(r, "session_id")
+	rw := &lazyHeaderWriter{ResponseWriter: w, header: func() {
+		w.Header().Set("Content-Type", "application/x-asciicast")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s.cast", sessionID))
+	}}
+	if err := handler.GetTerminalSessionHandler().StreamTerminalRecording(rw, tenantID, sessionID); err != nil {
+		if rw.written {
+			logrus.Errorf("stream terminal recording %s: %v", sessionID, err)
+			return
+		}
+		httputil.ReturnBcodeError(r, w, err)
+	}
+}
+
+// ListOpenTerminalSessions list the running terminal sessions of the tenant that can be reattached
+func ListOpenTerminalSessions(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	values := r.URL.Query()
+	httputil.ReturnSuccess(r, w, GetWebCli().ListSessions(tenantID, values.Get("service_id"), values.Get("user")))
+}
+
+// CloseTerminalSession terminate the running terminal session
+func CloseTerminalSession(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	if err := GetWebCli().CloseSession(tenantID, chi.URLParam(r, "session_id")); err != nil {
+		httputil.ReturnError(r, w, 404, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// lazyHeaderWriter sets the headers of the recording on the first write, so that
+// the errors before the recording is read are returned as json.
+type lazyHeaderWriter struct {
+	http.ResponseWriter
+	header  func()
+	written bool
+}
+
+func (l *lazyHeaderWriter) Write(p []byte) (int, error) {
+	if !l.written {
+		l.written = true
+		l.header()
+	}
+	return l.ResponseWriter.Write(p)
+}
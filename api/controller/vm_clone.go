@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/goodrain/rainbond/api/handler"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	httputil "github.com/goodrain/rainbond/util/http"
+)
+
+type VMCloneController struct {
+	cloneVM func(ctx context.Context, serviceID string, req *handler.VMCloneRequest) (*handler.VMCloneResult, error)
+}
+
+var defaultVMCloneController = &VMCloneController{}
+
+func GetVMCloneController() *VMCloneController {
+	return defaultVMCloneController
+}
+
+func (c *VMCloneController) CloneVM(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	var reqBody handler.VMCloneRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httputil.ReturnError(r, w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if reqBody.SourceType != handler.VMCloneSourceSnapshot && reqBody.SourceType != handler.VMCloneSourceExport {
+		httputil.ReturnError(r, w, http.StatusBadRequest, "source_type must be snapshot or export")
+		return
+	}
+	if reqBody.SourceName == "" || reqBody.AppID == "" {
+		httputil.ReturnError(r, w, http.StatusBadRequest, "source_name and app_id are required")
+		return
+	}
+	cloneVM := c.cloneVM
+	if cloneVM == nil {
+		cloneVM = handler.GetServiceManager().CloneVM
+	}
+	result, err := cloneVM(r.Context(), serviceID, &reqBody)
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, result)
+}
+
+func (t *TenantStruct) CloneVM(w http.ResponseWriter, r *http.Request) {
+	GetVMCloneController().CloneVM(w, r)
+}
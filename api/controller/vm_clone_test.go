@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goodrain/rainbond/api/handler"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+)
+
+// capability_id: rainbond.vm.clone.api
+func TestVMCloneControllerCloneVM(t *testing.T) {
+	controller := &VMCloneController{
+		cloneVM: func(ctx context.Context, serviceID string, req *handler.VMCloneRequest) (*handler.VMCloneResult, error) {
+			if serviceID != "service-1" || req.SourceType != handler.VMCloneSourceSnapshot || req.SourceName != "snap-1" || req.TenantID != "tenant-2" {
+				t.Fatalf("unexpected clone %s %#v", serviceID, req)
+			}
+			return &handler.VMCloneResult{ServiceID: "service-2"}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tenants/demo/services/demo/vm-clone", bytes.NewBufferString(`{"source_type":"snapshot","source_name":"snap-1","tenant_id":"tenant-2","app_id":"app-1"}`))
+	req = req.WithContext(context.WithValue(req.Context(), ctxutil.ContextKey("service_id"), "service-1"))
+	recorder := httptest.NewRecorder()
+
+	controller.CloneVM(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+}
+
+// capability_id: rainbond.vm.clone.api-validate
+func TestVMCloneControllerCloneVMRejectsUnknownSource(t *testing.T) {
+	controller := &VMCloneController{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tenants/demo/services/demo/vm-clone", bytes.NewBufferString(`{"source_type":"backup","source_name":"b-1","app_id":"app-1"}`))
+	req = req.WithContext(context.WithValue(req.Context(), ctxutil.ContextKey("service_id"), "service-1"))
+	recorder := httptest.NewRecorder()
+
+	controller.CloneVM(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", recorder.Code)
+	}
+}
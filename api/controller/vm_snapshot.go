@@ -1,16 +1,26 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 
+	"github.com/go-chi/chi"
 	"github.com/goodrain/rainbond/api/handler"
 	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	dbmodel "github.com/goodrain/rainbond/db/model"
 	httputil "github.com/goodrain/rainbond/util/http"
 )
 
 type VMSnapshotController struct {
-	createSnapshot func(serviceID string, req *handler.VMSnapshotRequest) (*handler.VMSnapshotStatus, error)
+	createSnapshot  func(serviceID string, req *handler.VMSnapshotRequest) (*handler.VMSnapshotStatus, error)
+	listSnapshots   func(serviceID string) ([]*handler.VMSnapshotInfo, error)
+	deleteSnapshot  func(serviceID, name string) error
+	restoreSnapshot func(ctx context.Context, tenantID, serviceID, name string, req *handler.VMSnapshotRestoreRequest) (*handler.VMSnapshotRestoreStatus, error)
+	getSchedule     func(serviceID string) (*dbmodel.VMSnapshotSchedule, error)
+	setSchedule     func(tenantID, serviceID string, req *handler.VMSnapshotScheduleRequest) (*dbmodel.VMSnapshotSchedule, error)
+	deleteSchedule  func(serviceID string) error
 }
 
 var defaultVMSnapshotController = &VMSnapshotController{}
@@ -42,6 +52,129 @@ func (c *VMSnapshotController) CreateVMSnapshot(w http.ResponseWriter, r *http.R
 	httputil.ReturnSuccess(r, w, status)
 }
 
+func (c *VMSnapshotController) ListVMSnapshots(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	listSnapshots := c.listSnapshots
+	if listSnapshots == nil {
+		listSnapshots = handler.GetServiceManager().ListVMSnapshots
+	}
+	snapshots, err := listSnapshots(serviceID)
+	if err != nil {
+		httputil.ReturnError(r, w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, snapshots)
+}
+
+func (c *VMSnapshotController) DeleteVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	deleteSnapshot := c.deleteSnapshot
+	if deleteSnapshot == nil {
+		deleteSnapshot = handler.GetServiceManager().DeleteVMSnapshot
+	}
+	if err := deleteSnapshot(serviceID, chi.URLParam(r, "name")); err != nil {
+		httputil.ReturnError(r, w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+func (c *VMSnapshotController) RestoreVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	var reqBody handler.VMSnapshotRestoreRequest
+	// 请求体可以为空，此时按恢复前的运行状态决定是否启动
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil && err != io.EOF {
+		httputil.ReturnError(r, w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	restoreSnapshot := c.restoreSnapshot
+	if restoreSnapshot == nil {
+		restoreSnapshot = handler.GetServiceManager().RestoreVMSnapshot
+	}
+	status, err := restoreSnapshot(r.Context(), tenantID, serviceID, chi.URLParam(r, "name"), &reqBody)
+	if err != nil {
+		httputil.ReturnError(r, w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+func (c *VMSnapshotController) GetVMSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	getSchedule := c.getSchedule
+	if getSchedule == nil {
+		getSchedule = handler.GetServiceManager().GetVMSnapshotSchedule
+	}
+	schedule, err := getSchedule(serviceID)
+	if err != nil {
+		httputil.ReturnError(r, w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, schedule)
+}
+
+func (c *VMSnapshotController) SetVMSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string)
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	var reqBody handler.VMSnapshotScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httputil.ReturnError(r, w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if reqBody.Schedule == "" {
+		httputil.ReturnError(r, w, http.StatusBadRequest, "snapshot schedule is required")
+		return
+	}
+	setSchedule := c.setSchedule
+	if setSchedule == nil {
+		setSchedule = handler.GetServiceManager().SetVMSnapshotSchedule
+	}
+	schedule, err := setSchedule(tenantID, serviceID, &reqBody)
+	if err != nil {
+		httputil.ReturnError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, schedule)
+}
+
+func (c *VMSnapshotController) DeleteVMSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	deleteSchedule := c.deleteSchedule
+	if deleteSchedule == nil {
+		deleteSchedule = handler.GetServiceManager().DeleteVMSnapshotSchedule
+	}
+	if err := deleteSchedule(serviceID); err != nil {
+		httputil.ReturnError(r, w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
 func (t *TenantStruct) CreateVMSnapshot(w http.ResponseWriter, r *http.Request) {
 	GetVMSnapshotController().CreateVMSnapshot(w, r)
 }
+
+func (t *TenantStruct) ListVMSnapshots(w http.ResponseWriter, r *http.Request) {
+	GetVMSnapshotController().ListVMSnapshots(w, r)
+}
+
+func (t *TenantStruct) DeleteVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	GetVMSnapshotController().DeleteVMSnapshot(w, r)
+}
+
+func (t *TenantStruct) RestoreVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	GetVMSnapshotController().RestoreVMSnapshot(w, r)
+}
+
+func (t *TenantStruct) GetVMSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	GetVMSnapshotController().GetVMSnapshotSchedule(w, r)
+}
+
+func (t *TenantStruct) SetVMSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	GetVMSnapshotController().SetVMSnapshotSchedule(w, r)
+}
+
+func (t *TenantStruct) DeleteVMSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	GetVMSnapshotController().DeleteVMSnapshotSchedule(w, r)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi"
 	"github.com/goodrain/rainbond/api/handler"
 	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
 )
@@ -48,3 +49,45 @@ func TestVMSnapshotControllerCreateVMSnapshotRequiresName(t *testing.T) {
 		t.Fatalf("expected status 400, got %d", recorder.Code)
 	}
 }
+
+// capability_id: rainbond.vm.snapshot.restore-api
+func TestVMSnapshotControllerRestoreVMSnapshotAllowsEmptyBody(t *testing.T) {
+	controller := &VMSnapshotController{
+		restoreSnapshot: func(_ context.Context, tenantID, serviceID, name string, req *handler.VMSnapshotRestoreRequest) (*handler.VMSnapshotRestoreStatus, error) {
+			if tenantID != "tenant-1" || serviceID != "service-1" || name != "snap-1" || req.Start != nil {
+				t.Fatalf("unexpected restore %s %s %s %#v", tenantID, serviceID, name, req)
+			}
+			return &handler.VMSnapshotRestoreStatus{RestoreName: "snap-1-restore", SnapshotName: name}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tenants/demo/services/demo/vm-snapshots/snap-1/restore", nil)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("name", "snap-1")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, ctxutil.ContextKey("tenant_id"), "tenant-1")
+	ctx = context.WithValue(ctx, ctxutil.ContextKey("service_id"), "service-1")
+	recorder := httptest.NewRecorder()
+
+	controller.RestoreVMSnapshot(recorder, req.WithContext(ctx))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+}
+
+// capability_id: rainbond.vm.snapshot.schedule-api
+func TestVMSnapshotControllerSetVMSnapshotScheduleRequiresSchedule(t *testing.T) {
+	controller := &VMSnapshotController{}
+
+	req := httptest.NewRequest(http.MethodPut, "/v2/tenants/demo/services/demo/vm-snapshot-schedule", bytes.NewBufferString(`{"keep_last":3}`))
+	ctx := context.WithValue(req.Context(), ctxutil.ContextKey("tenant_id"), "tenant-1")
+	ctx = context.WithValue(ctx, ctxutil.ContextKey("service_id"), "service-1")
+	recorder := httptest.NewRecorder()
+
+	controller.SetVMSnapshotSchedule(recorder, req.WithContext(ctx))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", recorder.Code)
+	}
+}
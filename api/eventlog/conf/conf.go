@@ -114,6 +114,19 @@ type EventStoreConf struct {
 	HandleSubMessageCoreNumber  int
 	HandleDockerLogCoreNumber   int
 	StorageHomePath             string
+	Sink                        SinkConf
+}
+
+// SinkConf 事件日志与容器日志外发配置
+type SinkConf struct {
+	// ConfigFile sink 定义文件（JSON），为空时不外发
+	ConfigFile string
+	// BufferPath 落盘缓冲目录，为空时使用 StorageHomePath/sink-buffer
+	BufferPath string
+	// QueueSize 每个 sink 的内存队列长度，队列满后写入落盘缓冲
+	QueueSize int
+	// BufferMaxBytes 每个 sink 落盘缓冲上限，超出后丢弃最旧的数据
+	BufferMaxBytes int64
 }
 
 // KubernetsConf kubernetes conf
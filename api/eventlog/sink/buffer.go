@@ -0,0 +1,271 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentExt            = ".jsonl"
+	defaultSegmentBytes   = 8 * 1024 * 1024
+	defaultBufferMaxBytes = 512 * 1024 * 1024
+)
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// bufferCursor 一次读取后的位置，提交后才从缓冲中移除
+type bufferCursor struct {
+	seq    uint64
+	offset int64
+}
+
+// diskBuffer sink 的落盘缓冲，按顺序写入若干 JSON Lines 分段文件。
+// 读取位置只保存在内存中，进程重启后从最旧分段的开头重新发送，即至少一次。
+type diskBuffer struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+	lock         sync.Mutex
+	segments     []segment
+	writer       *os.File
+	size         int64
+	readOffset   int64
+	dropped      int64
+}
+
+func openDiskBuffer(dir string, maxBytes int64) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultBufferMaxBytes
+	}
+	b := &diskBuffer{dir: dir, maxBytes: maxBytes, segmentBytes: defaultSegmentBytes}
+	if b.segmentBytes > maxBytes/2 {
+		b.segmentBytes = maxBytes / 2
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, segment{seq: seq, size: info.Size()})
+		b.size += info.Size()
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].seq < b.segments[j].seq })
+	return b, nil
+}
+
+// Pending 尚未发送的字节数
+func (b *diskBuffer) Pending() int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.size - b.readOffset
+}
+
+// Dropped 因超出上限被丢弃的分段数
+func (b *diskBuffer) Dropped() int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.dropped
+}
+
+// Append 追加日志，超出上限时丢弃最旧的分段
+func (b *diskBuffer) Append(records []*Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.writer == nil || b.segments[len(b.segments)-1].size >= b.segmentBytes {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := b.writer.Write(buf.Bytes())
+	b.segments[len(b.segments)-1].size += int64(n)
+	b.size += int64(n)
+	if err != nil {
+		return err
+	}
+	b.trim()
+	return nil
+}
+
+// rotate 关闭当前分段并新建一个分段
+func (b *diskBuffer) rotate() error {
+	b.seal()
+	var seq uint64 = 1
+	if len(b.segments) > 0 {
+		seq = b.segments[len(b.segments)-1].seq + 1
+	}
+	file, err := os.OpenFile(b.path(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	b.writer = file
+	b.segments = append(b.segments, segment{seq: seq})
+	return nil
+}
+
+func (b *diskBuffer) seal() {
+	if b.writer != nil {
+		b.writer.Close()
+		b.writer = nil
+	}
+}
+
+func (b *diskBuffer) trim() {
+	for b.size > b.maxBytes && len(b.segments) > 1 {
+		b.removeFirst()
+		b.dropped++
+	}
+}
+
+func (b *diskBuffer) removeFirst() {
+	first := b.segments[0]
+	if err := os.Remove(b.path(first.seq)); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	b.segments = b.segments[1:]
+	b.size -= first.size
+	b.readOffset = 0
+}
+
+// Read 读取最多 n 条最旧的日志，Commit 返回的位置后才会被移除
+func (b *diskBuffer) Read(n int) ([]*Record, bufferCursor, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for len(b.segments) > 0 {
+		first := b.segments[0]
+		if b.readOffset >= first.size {
+			if len(b.segments) == 1 && b.writer == nil {
+				b.removeFirst()
+				return nil, bufferCursor{}, nil
+			}
+			if len(b.segments) == 1 {
+				return nil, bufferCursor{}, nil
+			}
+			b.removeFirst()
+			continue
+		}
+		if len(b.segments) == 1 {
+			// 正在写入的分段先封存，之后的写入进入新分段
+			b.seal()
+		}
+		records, offset, err := b.readSegment(first.seq, b.readOffset, n)
+		if err != nil {
+			return nil, bufferCursor{}, err
+		}
+		if offset == b.readOffset {
+			// 剩余内容不完整，丢弃该分段
+			b.removeFirst()
+			continue
+		}
+		return records, bufferCursor{seq: first.seq, offset: offset}, nil
+	}
+	return nil, bufferCursor{}, nil
+}
+
+func (b *diskBuffer) readSegment(seq uint64, offset int64, n int) ([]*Record, int64, error) {
+	file, err := os.Open(b.path(seq))
+	if err != nil {
+		return nil, offset, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+	reader := bufio.NewReader(file)
+	var records []*Record
+	for len(records) < n {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, offset, err
+		}
+		offset += int64(len(line))
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	return records, offset, nil
+}
+
+// Commit 确认 Read 返回的日志已发送
+func (b *diskBuffer) Commit(cursor bufferCursor) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.segments) == 0 || b.segments[0].seq != cursor.seq {
+		// 分段已因超出上限被丢弃
+		return
+	}
+	b.readOffset = cursor.offset
+	if b.readOffset >= b.segments[0].size && (len(b.segments) > 1 || b.writer == nil) {
+		b.removeFirst()
+	}
+}
+
+func (b *diskBuffer) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.seal()
+	return nil
+}
+
+func (b *diskBuffer) path(seq uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+package sink
+
+import (
+	"testing"
+)
+
+func TestDiskBufferReadCommit(t *testing.T) {
+	dir := t.TempDir()
+	buffer, err := openDiskBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buffer.Append(testRecords(5)); err != nil {
+		t.Fatal(err)
+	}
+	records, cursor, err := buffer.Read(3)
+	if err != nil || len(records) != 3 || records[0].Message != "line a" {
+		t.Fatalf("unexpected read %v %v", records, err)
+	}
+	// 未提交时重复读取得到相同数据
+	again, _, _ := buffer.Read(3)
+	if again[0].Message != "line a" {
+		t.Fatalf("uncommitted records should be read again")
+	}
+	buffer.Commit(cursor)
+	// 读取时封存的分段之后写入新分段
+	if err := buffer.Append(testRecords(1)); err != nil {
+		t.Fatal(err)
+	}
+	records, cursor, _ = buffer.Read(10)
+	if len(records) != 2 || records[0].Message != "line d" {
+		t.Fatalf("unexpected read after commit %v", records)
+	}
+	buffer.Commit(cursor)
+	buffer.Close()
+
+	// 重启后未发送的数据仍然存在
+	reopened, err := openDiskBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, cursor, _ = reopened.Read(10)
+	if len(records) != 1 || records[0].Message != "line a" {
+		t.Fatalf("unexpected records after reopen %v", records)
+	}
+	reopened.Commit(cursor)
+	if reopened.Pending() != 0 {
+		t.Fatalf("expected empty buffer, pending %d", reopened.Pending())
+	}
+	if records, cursor, _ := reopened.Read(10); len(records) != 0 || cursor.seq != 0 {
+		t.Fatalf("expected nothing to read")
+	}
+}
+
+func TestDiskBufferDropsOldestSegments(t *testing.T) {
+	buffer, err := openDiskBuffer(t.TempDir(), 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := buffer.Append(testRecords(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buffer.Dropped() == 0 {
+		t.Fatal("expected oldest segments dropped")
+	}
+	if pending := buffer.Pending(); pending > 4096+buffer.segmentBytes {
+		t.Fatalf("buffer exceeds limit: %d", pending)
+	}
+}
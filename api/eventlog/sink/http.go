@@ -0,0 +1,261 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// httpSink webhook、loki、elasticsearch 共用的 HTTP 发送
+type httpSink struct {
+	conf        SinkConfig
+	client      *http.Client
+	url         string
+	contentType string
+	encode      func(records []*Record) ([]byte, error)
+	// check 检查 2xx 响应体，elasticsearch bulk 部分失败时返回 200
+	check func(body []byte) error
+}
+
+func newHTTPSink(c SinkConfig, target, contentType string, encode func([]*Record) ([]byte, error)) (*httpSink, error) {
+	if _, err := url.ParseRequestURI(target); err != nil {
+		return nil, fmt.Errorf("sink %s: invalid url %s: %v", c.Name, target, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &httpSink{
+		conf:        c,
+		client:      &http.Client{Transport: transport, Timeout: c.timeout},
+		url:         target,
+		contentType: contentType,
+		encode:      encode,
+	}, nil
+}
+
+func (h *httpSink) Write(ctx context.Context, records []*Record) error {
+	body, err := h.encode(records)
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", h.contentType)
+	for k, v := range h.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	if h.conf.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.conf.BearerToken)
+	} else if h.conf.Username != "" {
+		req.SetBasicAuth(h.conf.Username, h.conf.Password)
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if res.StatusCode/100 != 2 {
+		err := fmt.Errorf("sink %s: %s responded %d: %s", h.conf.Name, h.url, res.StatusCode, strings.TrimSpace(string(truncate(respBody, 256))))
+		// 429 与 5xx 可重试，其余 4xx 说明请求本身有问题
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+			return err
+		}
+		return &permanentError{err: err}
+	}
+	if h.check != nil {
+		return h.check(respBody)
+	}
+	return nil
+}
+
+func (h *httpSink) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+// newWebhookSink 以 JSON 批量 POST 到 webhook
+func newWebhookSink(c SinkConfig) (Sink, error) {
+	return newHTTPSink(c, c.URL, "application/json", func(records []*Record) ([]byte, error) {
+		return json.Marshal(map[string]interface{}{
+			"sink":    c.Name,
+			"records": records,
+		})
+	})
+}
+
+// newLokiSink 使用 Loki push API，按标签集合分组为 stream
+func newLokiSink(c SinkConfig) (Sink, error) {
+	target := c.URL
+	if u, err := url.Parse(target); err == nil && (u.Path == "" || u.Path == "/") {
+		target = strings.TrimSuffix(target, "/") + "/loki/api/v1/push"
+	}
+	return newHTTPSink(c, target, "application/json", func(records []*Record) ([]byte, error) {
+		return encodeLoki(c.Labels, records)
+	})
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func encodeLoki(static map[string]string, records []*Record) ([]byte, error) {
+	var streams []*lokiStream
+	index := make(map[string]*lokiStream)
+	for _, record := range records {
+		// 事件 ID、组件 ID 基数过高，只放在日志行中
+		labels := map[string]string{"source": record.Source}
+		for k, v := range static {
+			labels[k] = v
+		}
+		if record.TenantID != "" {
+			labels["tenant_id"] = record.TenantID
+		}
+		if record.Level != "" {
+			labels["level"] = record.Level
+		}
+		key := labelKey(labels)
+		stream, ok := index[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			index[key] = stream
+			streams = append(streams, stream)
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		stream.Values = append(stream.Values, [2]string{fmt.Sprintf("%d", record.Time.UnixNano()), string(line)})
+	}
+	return json.Marshal(map[string]interface{}{"streams": streams})
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// newElasticsearchSink 使用 _bulk 接口写入
+func newElasticsearchSink(c SinkConfig) (Sink, error) {
+	index := c.Index
+	if index == "" {
+		index = "rainbond-eventlog-{date}"
+	}
+	sink, err := newHTTPSink(c, strings.TrimSuffix(c.URL, "/")+"/_bulk", "application/x-ndjson", func(records []*Record) ([]byte, error) {
+		return encodeBulk(index, records)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sink.check = func(body []byte) error {
+		return checkBulkResponse(c.Name, body)
+	}
+	return sink, nil
+}
+
+func encodeBulk(index string, records []*Record) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		name := strings.ReplaceAll(index, "{date}", record.Time.UTC().Format("2006.01.02"))
+		if err := encoder.Encode(map[string]interface{}{"index": map[string]string{"_index": name}}); err != nil {
+			return nil, err
+		}
+		doc := struct {
+			*Record
+			Timestamp string `json:"@timestamp"`
+		}{Record: record, Timestamp: record.Time.UTC().Format(time.RFC3339Nano)}
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// checkBulkResponse bulk 中有可重试的失败时整批重试，否则视为永久失败
+func checkBulkResponse(name string, body []byte) error {
+	var res bulkResponse
+	if err := json.Unmarshal(body, &res); err != nil || !res.Errors {
+		return nil
+	}
+	failed, retryable := 0, false
+	var first string
+	for _, item := range res.Items {
+		for _, result := range item {
+			if result.Status/100 == 2 {
+				continue
+			}
+			failed++
+			if first == "" {
+				first = string(truncate(result.Error, 256))
+			}
+			if result.Status == http.StatusTooManyRequests || result.Status >= 500 {
+				retryable = true
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("sink %s: %d bulk items failed: %s", name, failed, first)
+	if retryable {
+		return err
+	}
+	return &permanentError{err: err}
+}
+
+func truncate(data []byte, n int) []byte {
+	if len(data) > n {
+		return data[:n]
+	}
+	return data
+}
@@ -0,0 +1,457 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sink
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goodrain/rainbond/api/eventlog/conf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultQueueSize   = 4096
+	maxRetryBackoff    = 30 * time.Second
+	tenantCacheMaxSize = 10000
+	// tenantFailureTTL 租户查询失败的结果缓存时间，避免数据库异常时每条日志都查询一次
+	tenantFailureTTL = time.Minute
+)
+
+// TenantResolver 根据日志来源与事件 ID/组件 ID 查询租户
+type TenantResolver func(source, id string) (string, error)
+
+// Manager 日志外发管理器。Forward 不会阻塞调用方：
+// 日志先进入路由队列，再分发到各 sink 的内存队列，内存队列满或 sink 积压时写入落盘缓冲。
+// 租户未缓存的日志交给租户查询协程处理，数据库查询不会阻塞路由。
+type Manager struct {
+	conf       conf.SinkConf
+	workers    []*worker
+	resolver   TenantResolver
+	needTenant bool
+	tenantLock sync.Mutex
+	tenants    map[string]tenantEntry
+	// pending 各来源等待租户查询的日志数，非 0 时同一来源的后续日志也排队，保证顺序
+	pending   map[string]int
+	resolving chan *Record
+	input     chan *Record
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	dropped   int64
+	log       *logrus.Entry
+}
+
+// NewManager 创建日志外发管理器，未配置 sink 时返回 nil，nil 的 Manager 可以安全调用
+func NewManager(c conf.SinkConf, bufferPath string, resolver TenantResolver, log *logrus.Entry) (*Manager, error) {
+	if c.ConfigFile == "" {
+		return nil, nil
+	}
+	config, err := LoadConfig(c.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Sinks) == 0 {
+		return nil, nil
+	}
+	if c.BufferPath != "" {
+		bufferPath = c.BufferPath
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultQueueSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		conf:     c,
+		resolver: resolver,
+		tenants:  make(map[string]tenantEntry),
+		pending:  make(map[string]int),
+		input:    make(chan *Record, c.QueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		log:      log,
+	}
+	for _, sc := range config.Sinks {
+		s, err := New(sc)
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		buffer, err := openDiskBuffer(filepath.Join(bufferPath, sc.Name), c.BufferMaxBytes)
+		if err != nil {
+			s.Close()
+			m.close()
+			return nil, err
+		}
+		m.workers = append(m.workers, &worker{
+			conf:   sc,
+			sink:   s,
+			queue:  make(chan *Record, c.QueueSize),
+			buffer: buffer,
+			log:    log.WithField("sink", sc.Name),
+		})
+		if len(sc.TenantIDs) > 0 {
+			m.needTenant = true
+		}
+	}
+	if m.needTenant {
+		m.resolving = make(chan *Record, c.QueueSize)
+	}
+	return m, nil
+}
+
+// Forward 外发一条日志，路由队列满时丢弃并计数
+func (m *Manager) Forward(record *Record) {
+	if m == nil || record == nil {
+		return
+	}
+	select {
+	case m.input <- record:
+	default:
+		atomic.AddInt64(&m.dropped, 1)
+	}
+}
+
+// Run 启动路由与各 sink 的发送协程
+func (m *Manager) Run() {
+	if m == nil {
+		return
+	}
+	m.log.Infof("start %d log sinks", len(m.workers))
+	for _, w := range m.workers {
+		m.wg.Add(1)
+		go func(w *worker) {
+			defer m.wg.Done()
+			w.run(m.ctx)
+		}(w)
+	}
+	if m.resolving != nil {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.resolve()
+		}()
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.route()
+	}()
+}
+
+// Stop 停止发送，未发送的日志保存在落盘缓冲中，下次启动后继续发送
+func (m *Manager) Stop() {
+	if m == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+	m.close()
+}
+
+func (m *Manager) close() {
+	for _, w := range m.workers {
+		w.sink.Close()
+		w.buffer.Close()
+	}
+}
+
+func (m *Manager) route() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			// 路由队列中剩余的日志交给各 sink 落盘
+			for {
+				select {
+				case record := <-m.input:
+					m.routeRecord(record)
+				default:
+					if m.resolving != nil {
+						close(m.resolving)
+					}
+					return
+				}
+			}
+		case record := <-m.input:
+			m.routeRecord(record)
+		}
+	}
+}
+
+// routeRecord 租户已缓存的日志直接分发，否则交给租户查询协程，查询队列满时丢弃并计数
+func (m *Manager) routeRecord(record *Record) {
+	key, _ := tenantKey(record)
+	if !m.needTenant || m.resolving == nil || m.resolver == nil || record.TenantID != "" || key == "" {
+		m.dispatch(record)
+		return
+	}
+	m.tenantLock.Lock()
+	if _, ok := m.cachedTenant(key); ok && m.pending[key] == 0 {
+		m.tenantLock.Unlock()
+		m.dispatch(record)
+		return
+	}
+	select {
+	case m.resolving <- record:
+		m.pending[key]++
+	default:
+		atomic.AddInt64(&m.dropped, 1)
+	}
+	m.tenantLock.Unlock()
+}
+
+// resolve 查询租户后分发日志，路由协程关闭查询队列后退出
+func (m *Manager) resolve() {
+	for record := range m.resolving {
+		m.dispatch(record)
+		key, _ := tenantKey(record)
+		m.tenantLock.Lock()
+		if m.pending[key]--; m.pending[key] <= 0 {
+			delete(m.pending, key)
+		}
+		m.tenantLock.Unlock()
+	}
+}
+
+func (m *Manager) dispatch(record *Record) {
+	if m.needTenant && record.TenantID == "" {
+		record.TenantID = m.tenant(record)
+	}
+	for _, w := range m.workers {
+		if w.conf.accept(record) {
+			w.enqueue(record)
+		}
+	}
+}
+
+// tenantEntry 缓存的租户，expire 非空表示查询失败，到期后重新查询
+type tenantEntry struct {
+	tenantID string
+	expire   time.Time
+}
+
+func tenantKey(record *Record) (key, id string) {
+	id = record.EventID
+	if record.Source == SourceContainer {
+		id = record.ServiceID
+	}
+	if id == "" {
+		return "", ""
+	}
+	return record.Source + "/" + id, id
+}
+
+// cachedTenant 调用方需持有 tenantLock
+func (m *Manager) cachedTenant(key string) (string, bool) {
+	entry, ok := m.tenants[key]
+	if !ok || (!entry.expire.IsZero() && time.Now().After(entry.expire)) {
+		return "", false
+	}
+	return entry.tenantID, true
+}
+
+// tenant 查询并缓存租户，查询失败的结果缓存 tenantFailureTTL
+func (m *Manager) tenant(record *Record) string {
+	if m.resolver == nil {
+		return ""
+	}
+	key, id := tenantKey(record)
+	if key == "" {
+		return ""
+	}
+	m.tenantLock.Lock()
+	tenantID, ok := m.cachedTenant(key)
+	m.tenantLock.Unlock()
+	if ok {
+		return tenantID
+	}
+	entry := tenantEntry{}
+	tenantID, err := m.resolver(record.Source, id)
+	if err != nil {
+		m.log.Debugf("resolve tenant of %s: %v", key, err)
+		entry.expire = time.Now().Add(tenantFailureTTL)
+	} else {
+		entry.tenantID = tenantID
+	}
+	m.tenantLock.Lock()
+	if m.tenants == nil || len(m.tenants) >= tenantCacheMaxSize {
+		m.tenants = make(map[string]tenantEntry)
+	}
+	m.tenants[key] = entry
+	m.tenantLock.Unlock()
+	return entry.tenantID
+}
+
+// Scrape prometheus 指标
+func (m *Manager) Scrape(ch chan<- prometheus.Metric, namespace, exporter, from string) {
+	if m == nil {
+		return
+	}
+	labels := []string{"from", "sink"}
+	queueDesc := prometheus.NewDesc(prometheus.BuildFQName(namespace, exporter, "sink_queue_length"), "the in-memory queue length of the log sink.", labels, nil)
+	bufferDesc := prometheus.NewDesc(prometheus.BuildFQName(namespace, exporter, "sink_buffer_bytes"), "the pending bytes in the disk buffer of the log sink.", labels, nil)
+	sentDesc := prometheus.NewDesc(prometheus.BuildFQName(namespace, exporter, "sink_sent_count"), "the count of logs sent by the log sink.", labels, nil)
+	failedDesc := prometheus.NewDesc(prometheus.BuildFQName(namespace, exporter, "sink_failed_count"), "the count of failed sends of the log sink.", labels, nil)
+	droppedDesc := prometheus.NewDesc(prometheus.BuildFQName(namespace, exporter, "sink_dropped_count"), "the count of logs dropped by the log sink, the sink label is empty for the router.", labels, nil)
+	for _, w := range m.workers {
+		ch <- prometheus.MustNewConstMetric(queueDesc, prometheus.GaugeValue, float64(len(w.queue)), from, w.conf.Name)
+		ch <- prometheus.MustNewConstMetric(bufferDesc, prometheus.GaugeValue, float64(w.buffer.Pending()), from, w.conf.Name)
+		ch <- prometheus.MustNewConstMetric(sentDesc, prometheus.CounterValue, float64(atomic.LoadInt64(&w.sent)), from, w.conf.Name)
+		ch <- prometheus.MustNewConstMetric(failedDesc, prometheus.CounterValue, float64(atomic.LoadInt64(&w.failed)), from, w.conf.Name)
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(atomic.LoadInt64(&w.dropped)), from, w.conf.Name)
+	}
+	ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(atomic.LoadInt64(&m.dropped)), from, "")
+}
+
+// worker 单个 sink 的批量发送
+type worker struct {
+	conf   SinkConfig
+	sink   Sink
+	queue  chan *Record
+	buffer *diskBuffer
+	// spilling 落盘缓冲非空时新日志也写入缓冲，保证发送顺序
+	spilling              int32
+	sent, failed, dropped int64
+	log                   *logrus.Entry
+}
+
+func (w *worker) enqueue(record *Record) {
+	if atomic.LoadInt32(&w.spilling) == 0 {
+		select {
+		case w.queue <- record:
+			return
+		default:
+		}
+	}
+	w.spill([]*Record{record})
+}
+
+func (w *worker) spill(records []*Record) {
+	if len(records) == 0 {
+		return
+	}
+	atomic.StoreInt32(&w.spilling, 1)
+	if err := w.buffer.Append(records); err != nil {
+		atomic.AddInt64(&w.dropped, int64(len(records)))
+		w.log.Errorf("buffer %d logs on disk failure: %v", len(records), err)
+	}
+}
+
+func (w *worker) run(ctx context.Context) {
+	if w.buffer.Pending() > 0 {
+		atomic.StoreInt32(&w.spilling, 1)
+	}
+	ticker := time.NewTicker(w.conf.flushInterval)
+	defer ticker.Stop()
+	batch := make([]*Record, 0, w.conf.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			w.shutdown(batch)
+			return
+		case record := <-w.queue:
+			batch = append(batch, record)
+			if len(batch) >= w.conf.BatchSize {
+				if !w.deliver(ctx, batch) {
+					w.shutdown(batch)
+					return
+				}
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				if !w.deliver(ctx, batch) {
+					w.shutdown(batch)
+					return
+				}
+				batch = batch[:0]
+			}
+			if len(w.queue) == 0 && !w.replay(ctx) {
+				w.shutdown(nil)
+				return
+			}
+		}
+	}
+}
+
+// replay 发送落盘缓冲中的日志，内存队列有新日志时让出
+func (w *worker) replay(ctx context.Context) bool {
+	for len(w.queue) == 0 {
+		records, cursor, err := w.buffer.Read(w.conf.BatchSize)
+		if err != nil {
+			w.log.Errorf("read disk buffer failure: %v", err)
+			return true
+		}
+		if cursor.seq == 0 {
+			atomic.StoreInt32(&w.spilling, 0)
+			// 关闭 spilling 前后可能有日志写入缓冲，下次 tick 再发送
+			return true
+		}
+		if len(records) > 0 && !w.deliver(ctx, records) {
+			return false
+		}
+		w.buffer.Commit(cursor)
+	}
+	return true
+}
+
+// deliver 发送一批日志，可重试的错误按指数退避一直重试，返回 false 表示已停止
+func (w *worker) deliver(ctx context.Context, records []*Record) bool {
+	backoff := time.Second
+	for {
+		err := w.sink.Write(ctx, records)
+		if err == nil {
+			atomic.AddInt64(&w.sent, int64(len(records)))
+			return true
+		}
+		atomic.AddInt64(&w.failed, 1)
+		if isPermanent(err) {
+			atomic.AddInt64(&w.dropped, int64(len(records)))
+			w.log.Errorf("send %d logs failure, dropped: %v", len(records), err)
+			return true
+		}
+		w.log.Warningf("send %d logs failure, retry after %s: %v", len(records), backoff, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// shutdown 未发送的日志写入落盘缓冲
+func (w *worker) shutdown(batch []*Record) {
+	pending := append([]*Record{}, batch...)
+	for {
+		select {
+		case record := <-w.queue:
+			pending = append(pending, record)
+		default:
+			w.spill(pending)
+			return
+		}
+	}
+}
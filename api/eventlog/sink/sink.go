@@ -0,0 +1,231 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+const (
+	// SourceEvent 操作事件日志
+	SourceEvent = "event"
+	// SourceContainer 容器日志
+	SourceContainer = "container"
+)
+
+const (
+	// TypeSyslog RFC5424 syslog
+	TypeSyslog = "syslog"
+	// TypeWebhook HTTP/JSON webhook
+	TypeWebhook = "webhook"
+	// TypeLoki Loki push API
+	TypeLoki = "loki"
+	// TypeElasticsearch Elasticsearch bulk API
+	TypeElasticsearch = "elasticsearch"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultTimeout       = 10 * time.Second
+)
+
+var sinkNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,62}$`)
+
+// Record 外发的一条日志
+type Record struct {
+	Source      string    `json:"source"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	EventID     string    `json:"event_id,omitempty"`
+	ServiceID   string    `json:"service_id,omitempty"`
+	ContainerID string    `json:"container_id,omitempty"`
+	Step        string    `json:"step,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Level       string    `json:"level,omitempty"`
+	Message     string    `json:"message"`
+	Time        time.Time `json:"time"`
+}
+
+// Sink 日志外发目标
+type Sink interface {
+	// Write 发送一批日志，返回 permanentError 时这批日志不再重试
+	Write(ctx context.Context, records []*Record) error
+	Close() error
+}
+
+// Config sink 定义文件
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// SinkConfig 单个 sink 的配置
+type SinkConfig struct {
+	// Name sink 名称，同时作为落盘缓冲目录名
+	Name string `json:"name"`
+	// Type syslog/webhook/loki/elasticsearch
+	Type string `json:"type"`
+	// TenantIDs 只外发这些租户的日志，为空时为集群级 sink
+	TenantIDs []string `json:"tenant_ids,omitempty"`
+	// Sources event/container，为空时外发全部
+	Sources []string `json:"sources,omitempty"`
+	// URL syslog 使用 udp://、tcp:// 或 tls://host:port，其余为 HTTP 地址
+	URL                string            `json:"url"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	Username           string            `json:"username,omitempty"`
+	Password           string            `json:"password,omitempty"`
+	BearerToken        string            `json:"bearer_token,omitempty"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+	BatchSize          int               `json:"batch_size,omitempty"`
+	FlushInterval      string            `json:"flush_interval,omitempty"`
+	Timeout            string            `json:"timeout,omitempty"`
+	// syslog
+	Facility int    `json:"facility,omitempty"`
+	AppName  string `json:"app_name,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	// loki 静态标签
+	Labels map[string]string `json:"labels,omitempty"`
+	// Index elasticsearch 索引，{date} 替换为日志日期
+	Index string `json:"index,omitempty"`
+
+	flushInterval time.Duration
+	timeout       time.Duration
+}
+
+// LoadConfig 读取 sink 定义文件
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sink config %s: %v", path, err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse sink config %s: %v", path, err)
+	}
+	names := make(map[string]bool, len(config.Sinks))
+	for i := range config.Sinks {
+		sc := &config.Sinks[i]
+		if err := sc.complete(); err != nil {
+			return nil, err
+		}
+		if names[sc.Name] {
+			return nil, fmt.Errorf("duplicate sink name %s", sc.Name)
+		}
+		names[sc.Name] = true
+	}
+	return &config, nil
+}
+
+// complete 校验配置并填充默认值
+func (c *SinkConfig) complete() error {
+	if !sinkNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("invalid sink name %q", c.Name)
+	}
+	switch c.Type {
+	case TypeSyslog, TypeWebhook, TypeLoki, TypeElasticsearch:
+	default:
+		return fmt.Errorf("sink %s: unsupported type %q", c.Name, c.Type)
+	}
+	if c.URL == "" {
+		return fmt.Errorf("sink %s: url is required", c.Name)
+	}
+	for _, source := range c.Sources {
+		if source != SourceEvent && source != SourceContainer {
+			return fmt.Errorf("sink %s: unsupported source %q", c.Name, source)
+		}
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	var err error
+	if c.flushInterval, err = parseDuration(c.FlushInterval, defaultFlushInterval); err != nil {
+		return fmt.Errorf("sink %s: invalid flush_interval: %v", c.Name, err)
+	}
+	if c.timeout, err = parseDuration(c.Timeout, defaultTimeout); err != nil {
+		return fmt.Errorf("sink %s: invalid timeout: %v", c.Name, err)
+	}
+	return nil
+}
+
+// accept 判断日志是否属于该 sink
+func (c *SinkConfig) accept(record *Record) bool {
+	if len(c.Sources) > 0 && !contains(c.Sources, record.Source) {
+		return false
+	}
+	if len(c.TenantIDs) > 0 && !contains(c.TenantIDs, record.TenantID) {
+		return false
+	}
+	return true
+}
+
+// New 根据配置创建 sink
+func New(c SinkConfig) (Sink, error) {
+	switch c.Type {
+	case TypeSyslog:
+		return newSyslogSink(c)
+	case TypeWebhook:
+		return newWebhookSink(c)
+	case TypeLoki:
+		return newLokiSink(c)
+	case TypeElasticsearch:
+		return newElasticsearchSink(c)
+	}
+	return nil, fmt.Errorf("unsupported sink type %q", c.Type)
+}
+
+// permanentError 不可重试的错误，例如目标返回 4xx
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func isPermanent(err error) bool {
+	_, ok := err.(*permanentError)
+	return ok
+}
+
+func parseDuration(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return def, nil
+	}
+	return d, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
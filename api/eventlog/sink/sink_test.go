@@ -0,0 +1,443 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/api/eventlog/conf"
+	"github.com/sirupsen/logrus"
+)
+
+func testRecords(n int) []*Record {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var records []*Record
+	for i := 0; i < n; i++ {
+		records = append(records, &Record{
+			Source:   SourceEvent,
+			TenantID: "tenant-a",
+			EventID:  "event-1",
+			Step:     "build-code",
+			Level:    "info",
+			Message:  "line " + string(rune('a'+i%26)),
+			Time:     base.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return records
+}
+
+func writeConfig(t *testing.T, sinks ...SinkConfig) string {
+	t.Helper()
+	data, err := json.Marshal(Config{Sinks: sinks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "sinks.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigValidates(t *testing.T) {
+	cases := []struct {
+		name  string
+		sinks []SinkConfig
+		err   string
+	}{
+		{"bad name", []SinkConfig{{Name: "../x", Type: TypeWebhook, URL: "http://a"}}, "invalid sink name"},
+		{"bad type", []SinkConfig{{Name: "a", Type: "kafka", URL: "http://a"}}, "unsupported type"},
+		{"no url", []SinkConfig{{Name: "a", Type: TypeLoki}}, "url is required"},
+		{"bad source", []SinkConfig{{Name: "a", Type: TypeLoki, URL: "http://a", Sources: []string{"audit"}}}, "unsupported source"},
+		{"duplicate", []SinkConfig{{Name: "a", Type: TypeLoki, URL: "http://a"}, {Name: "a", Type: TypeWebhook, URL: "http://b"}}, "duplicate"},
+	}
+	for _, c := range cases {
+		_, err := LoadConfig(writeConfig(t, c.sinks...))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error %q, got %v", c.name, c.err, err)
+		}
+	}
+	config, err := LoadConfig(writeConfig(t, SinkConfig{Name: "central", Type: TypeSyslog, URL: "udp://127.0.0.1:514", FlushInterval: "5s"}))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	sc := config.Sinks[0]
+	if sc.BatchSize != defaultBatchSize || sc.flushInterval != 5*time.Second || sc.timeout != defaultTimeout {
+		t.Fatalf("unexpected defaults: %+v", sc)
+	}
+}
+
+func TestSinkConfigAccept(t *testing.T) {
+	cluster := SinkConfig{}
+	tenant := SinkConfig{TenantIDs: []string{"tenant-a"}, Sources: []string{SourceContainer}}
+	event := &Record{Source: SourceEvent, TenantID: "tenant-a"}
+	container := &Record{Source: SourceContainer, TenantID: "tenant-a"}
+	other := &Record{Source: SourceContainer, TenantID: "tenant-b"}
+	if !cluster.accept(event) || !cluster.accept(other) {
+		t.Fatal("cluster sink should accept all records")
+	}
+	if tenant.accept(event) || !tenant.accept(container) || tenant.accept(other) {
+		t.Fatal("tenant sink should only accept container logs of its tenant")
+	}
+}
+
+func TestFormatSyslog(t *testing.T) {
+	record := &Record{
+		Source:   SourceEvent,
+		TenantID: "tenant-a",
+		EventID:  "event-1",
+		Step:     `say "hi" [x]`,
+		Level:    "error",
+		Message:  "build failed\n",
+		Time:     time.Date(2024, 5, 1, 10, 0, 0, 123456000, time.UTC),
+	}
+	got := formatSyslog(syslogFacilityLocal0, "node 1", "", record)
+	want := `<131>1 2024-05-01T10:00:00.123456Z node1 - - event [rainbond@32473 tenant_id="tenant-a" event_id="event-1" step="say \"hi\" [x\]"] build failed`
+	if got != want {
+		t.Fatalf("unexpected syslog message:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestSyslogSinkTCPFraming(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var frames []string
+		for len(frames) < 2 {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			var n int
+			json.Unmarshal([]byte(strings.TrimSpace(length)), &n)
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+			frames = append(frames, string(frame))
+		}
+		received <- strings.Join(frames, "\n")
+	}()
+	config, err := LoadConfig(writeConfig(t, SinkConfig{Name: "syslog", Type: TypeSyslog, URL: "tcp://" + listener.Addr().String(), Hostname: "node"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(config.Sinks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(context.Background(), testRecords(2)); err != nil {
+		t.Fatalf("write syslog: %v", err)
+	}
+	select {
+	case frames := <-received:
+		if !strings.HasSuffix(frames, "line b") || !strings.Contains(frames, "line a\n<134>1 ") {
+			t.Fatalf("unexpected frames %q", frames)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("syslog frames not received")
+	}
+}
+
+func TestHTTPSinks(t *testing.T) {
+	var lock sync.Mutex
+	bodies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		bodies[r.URL.Path] = string(body)
+		lock.Unlock()
+		if r.URL.Path == "/hook" && r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/_bulk" {
+			w.Write([]byte(`{"errors":false,"items":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	config, err := LoadConfig(writeConfig(t,
+		SinkConfig{Name: "hook", Type: TypeWebhook, URL: server.URL + "/hook", BearerToken: "token"},
+		SinkConfig{Name: "loki", Type: TypeLoki, URL: server.URL, Labels: map[string]string{"cluster": "c1"}},
+		SinkConfig{Name: "es", Type: TypeElasticsearch, URL: server.URL},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sc := range config.Sinks {
+		s, err := New(sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Write(context.Background(), testRecords(2)); err != nil {
+			t.Fatalf("%s write: %v", sc.Name, err)
+		}
+		s.Close()
+	}
+
+	var hook struct {
+		Sink    string    `json:"sink"`
+		Records []*Record `json:"records"`
+	}
+	if err := json.Unmarshal([]byte(bodies["/hook"]), &hook); err != nil || hook.Sink != "hook" || len(hook.Records) != 2 {
+		t.Fatalf("unexpected webhook body %s", bodies["/hook"])
+	}
+	var push struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(bodies["/loki/api/v1/push"]), &push); err != nil || len(push.Streams) != 1 {
+		t.Fatalf("unexpected loki body %s", bodies["/loki/api/v1/push"])
+	}
+	stream := push.Streams[0]
+	if stream.Stream["cluster"] != "c1" || stream.Stream["tenant_id"] != "tenant-a" || len(stream.Values) != 2 {
+		t.Fatalf("unexpected loki stream %+v", stream)
+	}
+	lines := strings.Split(strings.TrimSpace(bodies["/_bulk"]), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], `"_index":"rainbond-eventlog-2024.05.01"`) || !strings.Contains(lines[1], `"@timestamp":"2024-05-01T10:00:00Z"`) {
+		t.Fatalf("unexpected bulk body %s", bodies["/_bulk"])
+	}
+}
+
+func TestHTTPSinkErrorClassification(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	config, _ := LoadConfig(writeConfig(t, SinkConfig{Name: "hook", Type: TypeWebhook, URL: server.URL}))
+	s, _ := New(config.Sinks[0])
+	if err := s.Write(context.Background(), testRecords(1)); err == nil || isPermanent(err) {
+		t.Fatalf("503 should be retryable, got %v", err)
+	}
+	status = http.StatusBadRequest
+	if err := s.Write(context.Background(), testRecords(1)); !isPermanent(err) {
+		t.Fatalf("400 should be permanent, got %v", err)
+	}
+	if err := checkBulkResponse("es", []byte(`{"errors":true,"items":[{"index":{"status":429}}]}`)); err == nil || isPermanent(err) {
+		t.Fatalf("rejected bulk items should be retryable, got %v", err)
+	}
+	if err := checkBulkResponse("es", []byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)); !isPermanent(err) {
+		t.Fatalf("mapping errors should be permanent, got %v", err)
+	}
+}
+
+// blockingSink 模拟不可用的下游
+type blockingSink struct {
+	lock     sync.Mutex
+	down     bool
+	received []*Record
+}
+
+func (b *blockingSink) Write(ctx context.Context, records []*Record) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.down {
+		return io.ErrUnexpectedEOF
+	}
+	b.received = append(b.received, records...)
+	return nil
+}
+
+func (b *blockingSink) Close() error { return nil }
+
+func (b *blockingSink) setDown(down bool) {
+	b.lock.Lock()
+	b.down = down
+	b.lock.Unlock()
+}
+
+func (b *blockingSink) count() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.received)
+}
+
+func TestManagerBuffersOnDiskWhenSinkIsDown(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, SinkConfig{Name: "slow", Type: TypeWebhook, URL: "http://127.0.0.1:1", BatchSize: 10, FlushInterval: "10ms"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := &blockingSink{down: true}
+	buffer, err := openDiskBuffer(filepath.Join(t.TempDir(), "slow"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		conf:    conf.SinkConf{QueueSize: 4},
+		tenants: map[string]tenantEntry{},
+		input:   make(chan *Record, 100),
+		ctx:     ctx,
+		cancel:  cancel,
+		log:     logrus.WithField("test", "sink"),
+		workers: []*worker{{
+			conf:   config.Sinks[0],
+			sink:   down,
+			queue:  make(chan *Record, 4),
+			buffer: buffer,
+			log:    logrus.WithField("sink", "slow"),
+		}},
+	}
+	m.Run()
+	done := make(chan struct{})
+	go func() {
+		for _, record := range testRecords(100) {
+			m.Forward(record)
+			time.Sleep(100 * time.Microsecond)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("forward blocked by a slow sink")
+	}
+	waitFor(t, func() bool { return buffer.Pending() > 0 })
+
+	down.setDown(false)
+	waitFor(t, func() bool { return down.count()+int(atomic.LoadInt64(&m.dropped)) == 100 })
+	waitFor(t, func() bool { return buffer.Pending() == 0 })
+	m.Stop()
+	down.lock.Lock()
+	defer down.lock.Unlock()
+	for i := 1; i < len(down.received); i++ {
+		if down.received[i].Time.Before(down.received[i-1].Time) {
+			t.Fatalf("records are out of order at %d", i)
+		}
+	}
+}
+
+func TestManagerResolvesTenantOnce(t *testing.T) {
+	calls := 0
+	m := &Manager{
+		needTenant: true,
+		tenants:    map[string]tenantEntry{},
+		log:        logrus.WithField("test", "sink"),
+		resolver: func(source, id string) (string, error) {
+			calls++
+			return "tenant-" + id, nil
+		},
+	}
+	for i := 0; i < 3; i++ {
+		record := &Record{Source: SourceContainer, ServiceID: "svc"}
+		m.dispatch(record)
+		if record.TenantID != "tenant-svc" {
+			t.Fatalf("unexpected tenant %s", record.TenantID)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected tenant resolved once, got %d", calls)
+	}
+	var nilManager *Manager
+	nilManager.Forward(&Record{})
+	nilManager.Run()
+	nilManager.Stop()
+}
+
+func TestManagerResolvesTenantOffRoute(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, SinkConfig{Name: "tenant", Type: TypeWebhook, URL: "http://127.0.0.1:1", BatchSize: 1, FlushInterval: "10ms"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err := openDiskBuffer(filepath.Join(t.TempDir(), "tenant"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := &blockingSink{}
+	release := make(chan struct{})
+	var calls int64
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		needTenant: true,
+		tenants:    map[string]tenantEntry{},
+		pending:    map[string]int{},
+		resolving:  make(chan *Record, 10),
+		input:      make(chan *Record, 10),
+		ctx:        ctx,
+		cancel:     cancel,
+		log:        logrus.WithField("test", "sink"),
+		resolver: func(source, id string) (string, error) {
+			atomic.AddInt64(&calls, 1)
+			if id == "slow" {
+				<-release
+				return "tenant-slow", nil
+			}
+			return "", errors.New("db unavailable")
+		},
+		workers: []*worker{{
+			conf:   config.Sinks[0],
+			sink:   received,
+			queue:  make(chan *Record, 10),
+			buffer: buffer,
+			log:    logrus.WithField("sink", "tenant"),
+		}},
+	}
+	m.tenants[SourceContainer+"/fast"] = tenantEntry{tenantID: "tenant-fast"}
+	m.Run()
+	m.Forward(&Record{Source: SourceContainer, ServiceID: "slow", Message: "slow-1"})
+	m.Forward(&Record{Source: SourceContainer, ServiceID: "fast", Message: "fast"})
+	m.Forward(&Record{Source: SourceContainer, ServiceID: "slow", Message: "slow-2"})
+	// 租户查询阻塞时，已缓存租户的日志照常发送
+	waitFor(t, func() bool { return received.count() == 1 })
+	close(release)
+	waitFor(t, func() bool { return received.count() == 3 })
+
+	// 查询失败的结果也会缓存，数据库异常时不会每条日志都查询
+	for i := 0; i < 3; i++ {
+		m.Forward(&Record{Source: SourceContainer, ServiceID: "missing"})
+	}
+	waitFor(t, func() bool { return received.count() == 6 })
+	m.Stop()
+	if n := atomic.LoadInt64(&calls); n != 2 {
+		t.Fatalf("expected 2 tenant lookups, got %d", n)
+	}
+	received.lock.Lock()
+	defer received.lock.Unlock()
+	expected := []string{"fast", "slow-1", "slow-2"}
+	for i, message := range expected {
+		if received.received[i].Message != message {
+			t.Fatalf("record %d: expected %s, got %s", i, message, received.received[i].Message)
+		}
+	}
+	if received.received[1].TenantID != "tenant-slow" || received.received[2].TenantID != "tenant-slow" {
+		t.Fatalf("unexpected tenant %s", received.received[1].TenantID)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacilityLocal0 默认 facility
+	syslogFacilityLocal0 = 16
+	// syslogSDID 结构化数据 ID，32473 为 RFC 5612 保留的文档用企业号
+	syslogSDID = "rainbond@32473"
+)
+
+// syslogSink RFC5424 syslog，udp 每条一个数据报，tcp/tls 使用 RFC6587 octet counting 分帧
+type syslogSink struct {
+	conf     SinkConfig
+	network  string
+	address  string
+	hostname string
+	appName  string
+	facility int
+	lock     sync.Mutex
+	conn     net.Conn
+}
+
+func newSyslogSink(c SinkConfig) (Sink, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("sink %s: invalid syslog url %s", c.Name, c.URL)
+	}
+	switch u.Scheme {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("sink %s: unsupported syslog scheme %q", c.Name, u.Scheme)
+	}
+	s := &syslogSink{
+		conf:     c,
+		network:  u.Scheme,
+		address:  u.Host,
+		hostname: c.Hostname,
+		appName:  c.AppName,
+		facility: c.Facility,
+	}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	if s.appName == "" {
+		s.appName = "rainbond"
+	}
+	if s.facility <= 0 || s.facility > 23 {
+		s.facility = syslogFacilityLocal0
+	}
+	return s, nil
+}
+
+func (s *syslogSink) Write(ctx context.Context, records []*Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	deadline := time.Now().Add(s.conf.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	s.conn.SetWriteDeadline(deadline)
+	for _, record := range records {
+		msg := formatSyslog(s.facility, s.hostname, s.appName, record)
+		if s.network != "udp" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.conf.timeout}
+	if s.network == "tls" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{InsecureSkipVerify: s.conf.InsecureSkipVerify}}
+		return tlsDialer.DialContext(ctx, "tcp", s.address)
+	}
+	return dialer.DialContext(ctx, s.network, s.address)
+}
+
+func (s *syslogSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// formatSyslog 生成 RFC5424 消息：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func formatSyslog(facility int, hostname, appName string, record *Record) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s - %s ",
+		facility*8+syslogSeverity(record.Level),
+		record.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(appName, 48),
+		syslogHeaderField(record.Source, 32))
+	b.WriteString("[" + syslogSDID)
+	for _, param := range [][2]string{
+		{"tenant_id", record.TenantID},
+		{"event_id", record.EventID},
+		{"service_id", record.ServiceID},
+		{"container_id", record.ContainerID},
+		{"step", record.Step},
+		{"status", record.Status},
+	} {
+		if param[1] != "" {
+			fmt.Fprintf(&b, " %s=\"%s\"", param[0], escapeSDParam(param[1]))
+		}
+	}
+	b.WriteString("] ")
+	b.WriteString(strings.TrimRight(record.Message, "\n"))
+	return b.String()
+}
+
+func syslogSeverity(level string) int {
+	switch level {
+	case "error":
+		return 3
+	case "debug":
+		return 7
+	}
+	return 6
+}
+
+// syslogHeaderField 头部字段为不含空格的可打印 ASCII，空值为 NILVALUE
+func syslogHeaderField(value string, max int) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		}
+		if b.Len() >= max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+func escapeSDParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -57,6 +58,7 @@ type JSONLinesFileStore struct {
 	basePath  string
 	fileLocks map[string]*sync.Mutex // 每个文件一个锁，保证并发写入安全
 	lockMutex sync.RWMutex           // 保护fileLocks映射
+	searcher  *EventLogSearcher
 	log       *logrus.Entry
 }
 
@@ -75,6 +77,7 @@ func NewJSONLinesFileStore(basePath string, log *logrus.Entry) (*JSONLinesFileSt
 	return &JSONLinesFileStore{
 		basePath:  basePath,
 		fileLocks: make(map[string]*sync.Mutex),
+		searcher:  NewEventLogSearcher(basePath, log),
 		log:       log,
 	}, nil
 }
@@ -199,10 +202,17 @@ func (s *JSONLinesFileStore) Delete(eventID string) error {
 		return err
 	}
 
+	os.Remove(filepath.Join(s.basePath, eventID+indexFileExt))
+
 	s.log.Debugf("Deleted event log file: %s", filePath)
 	return nil
 }
 
+// Search 检索事件日志
+func (s *JSONLinesFileStore) Search(query *EventLogQuery) (*EventLogSearchResult, error) {
+	return s.searcher.Search(query)
+}
+
 // Clean 清理过期文件（用于定期清理）
 func (s *JSONLinesFileStore) Clean(before time.Time) error {
 	entries, err := os.ReadDir(s.basePath)
@@ -217,7 +227,8 @@ func (s *JSONLinesFileStore) Clean(before time.Time) error {
 			continue
 		}
 
-		if filepath.Ext(entry.Name()) != ".jsonl" {
+		ext := filepath.Ext(entry.Name())
+		if ext != ".jsonl" && ext != indexFileExt {
 			continue
 		}
 
@@ -230,7 +241,9 @@ func (s *JSONLinesFileStore) Clean(before time.Time) error {
 			filePath := filepath.Join(s.basePath, entry.Name())
 			if err := os.Remove(filePath); err != nil {
 				s.log.Errorf("Failed to remove old file %s: %v", filePath, err)
-			} else {
+			} else if ext == ".jsonl" {
+				// 索引随日志文件一起删除
+				os.Remove(strings.TrimSuffix(filePath, ext) + indexFileExt)
 				cleaned++
 			}
 		}
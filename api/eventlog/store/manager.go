@@ -22,6 +22,7 @@ import (
 	"errors"
 	"github.com/goodrain/rainbond/api/eventlog/conf"
 	db2 "github.com/goodrain/rainbond/api/eventlog/db"
+	"github.com/goodrain/rainbond/api/eventlog/sink"
 	"strconv"
 
 	coreutil "github.com/goodrain/rainbond/util"
@@ -81,6 +82,12 @@ func NewManager(conf conf.EventStoreConf, log *logrus.Entry) (Manager, error) {
 		return nil, err
 	}
 
+	// 外发 sink，未配置时为 nil
+	sinks, err := sink.NewManager(conf.Sink, filepath.Join(conf.StorageHomePath, "sink-buffer"), resolveSinkTenant, log.WithField("module", "LogSink"))
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	storeManager := &storeManager{
 		cancel:                cancel,
@@ -97,6 +104,7 @@ func NewManager(conf conf.EventStoreConf, log *logrus.Entry) (Manager, error) {
 		dbPlugin:              dbPlugin,
 		filePlugin:            filePlugin,
 		messageFileStore:      messageFileStore,
+		sinks:                 sinks,
 		errChan:               make(chan error),
 	}
 	handle := NewStore("handle", storeManager)
@@ -128,6 +136,7 @@ type storeManager struct {
 	dbPlugin               db2.Manager
 	filePlugin             db2.Manager
 	messageFileStore       FileStore // 新增：消息文件存储
+	sinks                  *sink.Manager
 	errChan                chan error
 }
 
@@ -155,6 +164,7 @@ func (s *storeManager) Scrape(ch chan<- prometheus.Metric, namespace, exporter,
 	s.dockerLogStore.Scrape(ch, namespace, exporter, from)
 	s.handleMessageStore.Scrape(ch, namespace, exporter, from)
 	s.newmonitorMessageStore.Scrape(ch, namespace, exporter, from)
+	s.sinks.Scrape(ch, namespace, exporter, from)
 	chanDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, exporter, "chan_cache_size"),
 		"the handle chan cache size.",
@@ -265,6 +275,7 @@ func (s *storeManager) Run() error {
 	s.readMessageStore.Run()
 	s.dockerLogStore.Run()
 	s.newmonitorMessageStore.Run()
+	s.sinks.Run()
 	for i := 0; i < s.conf.HandleMessageCoreNumber; i++ {
 		go s.handleReceiveMessage()
 	}
@@ -406,6 +417,7 @@ loop:
 			//s.log.Debug("Receive Message:", string(message.Content))
 			s.handleMessageStore.InsertMessage(message)
 			s.readMessageStore.InsertMessage(message)
+			s.sinks.Forward(eventSinkRecord(message))
 		}
 	}
 	s.errChan <- fmt.Errorf("handle monitor log core exist")
@@ -504,6 +516,13 @@ loop:
 				EventID: serviceID,
 			}
 			s.dockerLogStore.InsertMessage(&message)
+			s.sinks.Forward(&sink.Record{
+				Source:      sink.SourceContainer,
+				ServiceID:   serviceID,
+				ContainerID: string(containerID),
+				Message:     strings.TrimRight(string(log), "\n"),
+				Time:        time.Now(),
+			})
 			buffer.Reset()
 		}
 	}
@@ -537,6 +556,7 @@ func (s *storeManager) Stop() {
 	s.readMessageStore.stop()
 	s.dockerLogStore.stop()
 	s.newmonitorMessageStore.stop()
+	s.sinks.Stop()
 	s.cancel()
 	if s.filePlugin != nil {
 		s.filePlugin.Close()
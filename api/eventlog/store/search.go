@@ -0,0 +1,373 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goodrain/rainbond/api/eventlog/db"
+	"github.com/sirupsen/logrus"
+)
+
+// indexFileExt 事件日志索引文件后缀，与 .jsonl 文件放在同一目录
+const indexFileExt = ".idx"
+
+// EventLogQuery 事件日志检索条件
+type EventLogQuery struct {
+	// EventIDs 检索的事件，按顺序返回结果
+	EventIDs []string
+	// Since Until 时间范围（unix 秒），0 表示不限制
+	Since int64
+	Until int64
+	// Keyword 不区分大小写的关键字
+	Keyword string
+	// Regex 正则匹配
+	Regex *regexp.Regexp
+	// Step 步骤名
+	Step string
+	// Level 日志级别 error/info/debug，与 GetMessages 相同：info 包含 error，debug 包含全部
+	Level  string
+	Offset int
+	Limit  int
+}
+
+// EventLogHit 命中的日志
+type EventLogHit struct {
+	EventID  string `json:"event_id"`
+	Step     string `json:"step"`
+	Status   string `json:"status"`
+	Level    string `json:"level"`
+	Message  string `json:"message"`
+	Time     string `json:"time"`
+	Unixtime int64  `json:"utime"`
+}
+
+// EventLogSearchResult 检索结果
+type EventLogSearchResult struct {
+	// Total 命中总数
+	Total int `json:"total"`
+	// Events 命中的事件数
+	Events int           `json:"events"`
+	Hits   []EventLogHit `json:"hits"`
+}
+
+// eventLogIndex 事件日志文件的摘要，检索时用于跳过不可能命中的文件。
+// 索引是增量维护的，Size 之后追加的内容在下次检索时补充进索引。
+type eventLogIndex struct {
+	// Size 索引覆盖的文件字节数
+	Size    int64          `json:"size"`
+	Lines   int            `json:"lines"`
+	MinTime int64          `json:"min_time"`
+	MaxTime int64          `json:"max_time"`
+	Steps   map[string]int `json:"steps"`
+	Levels  map[string]int `json:"levels"`
+}
+
+func (i *eventLogIndex) add(msg *db.EventLogMessage) {
+	i.Lines++
+	if unix := parseMessageTime(msg.Time); unix != 0 {
+		if i.MinTime == 0 || unix < i.MinTime {
+			i.MinTime = unix
+		}
+		if unix > i.MaxTime {
+			i.MaxTime = unix
+		}
+	}
+	if i.Steps == nil {
+		i.Steps = make(map[string]int)
+	}
+	if i.Levels == nil {
+		i.Levels = make(map[string]int)
+	}
+	i.Steps[msg.Step]++
+	i.Levels[msg.Level]++
+}
+
+// mayMatch 根据索引判断文件中是否可能存在满足条件的日志
+func (i *eventLogIndex) mayMatch(query *EventLogQuery) bool {
+	if i.Lines == 0 {
+		return false
+	}
+	if query.Since != 0 && i.MaxTime != 0 && i.MaxTime < query.Since {
+		return false
+	}
+	if query.Until != 0 && i.MinTime != 0 && i.MinTime > query.Until {
+		return false
+	}
+	if query.Step != "" && i.Steps[query.Step] == 0 {
+		return false
+	}
+	for level, count := range i.Levels {
+		if count > 0 && levelMatches(level, query.Level) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventLogSearcher JSON Lines 事件日志检索
+type EventLogSearcher struct {
+	basePath string
+	mu       sync.Mutex
+	indexes  map[string]*eventLogIndex
+	log      *logrus.Entry
+}
+
+// NewEventLogSearcher 创建事件日志检索，basePath 为 .jsonl 文件所在目录
+func NewEventLogSearcher(basePath string, log *logrus.Entry) *EventLogSearcher {
+	if log == nil {
+		log = logrus.WithField("module", "eventlogsearch")
+	}
+	return &EventLogSearcher{
+		basePath: basePath,
+		indexes:  make(map[string]*eventLogIndex),
+		log:      log,
+	}
+}
+
+// Search 按条件检索事件日志，文件不存在的事件被忽略
+func (s *EventLogSearcher) Search(query *EventLogQuery) (*EventLogSearchResult, error) {
+	result := &EventLogSearchResult{Hits: []EventLogHit{}}
+	keyword := strings.ToLower(query.Keyword)
+	for _, eventID := range query.EventIDs {
+		index, err := s.index(eventID)
+		if err != nil {
+			return nil, err
+		}
+		if index == nil || !index.mayMatch(query) {
+			continue
+		}
+		matched := 0
+		err = s.scan(eventID, index.Size, func(msg *db.EventLogMessage) {
+			if !messageMatches(msg, query, keyword) {
+				return
+			}
+			matched++
+			result.Total++
+			if result.Total <= query.Offset || (query.Limit > 0 && len(result.Hits) >= query.Limit) {
+				return
+			}
+			result.Hits = append(result.Hits, EventLogHit{
+				EventID:  eventID,
+				Step:     msg.Step,
+				Status:   msg.Status,
+				Level:    msg.Level,
+				Message:  msg.Message,
+				Time:     msg.Time,
+				Unixtime: parseMessageTime(msg.Time),
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		if matched > 0 {
+			result.Events++
+		}
+	}
+	return result, nil
+}
+
+// index 返回事件的索引，并把上次索引之后追加的日志补充进索引
+func (s *EventLogSearcher) index(eventID string) (*eventLogIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.logPath(eventID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			delete(s.indexes, eventID)
+			return nil, nil
+		}
+		return nil, err
+	}
+	index, ok := s.indexes[eventID]
+	if !ok {
+		index = s.loadIndex(eventID)
+	}
+	if index.Size > info.Size() {
+		// 文件被重写，重建索引
+		index = &eventLogIndex{}
+	}
+	if index.Size < info.Size() {
+		next := &eventLogIndex{}
+		*next = *index
+		next.Steps = copyCounts(index.Steps)
+		next.Levels = copyCounts(index.Levels)
+		size, err := s.scanFrom(eventID, index.Size, 0, next.add)
+		if err != nil {
+			return nil, err
+		}
+		next.Size = size
+		index = next
+		s.saveIndex(eventID, index)
+	}
+	s.indexes[eventID] = index
+	return index, nil
+}
+
+func (s *EventLogSearcher) loadIndex(eventID string) *eventLogIndex {
+	index := &eventLogIndex{}
+	data, err := os.ReadFile(s.indexPath(eventID))
+	if err != nil {
+		return index
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		s.log.Debugf("rebuild corrupted index of event %s: %v", eventID, err)
+		return &eventLogIndex{}
+	}
+	return index
+}
+
+func (s *EventLogSearcher) saveIndex(eventID string, index *eventLogIndex) {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return
+	}
+	tmp := s.indexPath(eventID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		s.log.Debugf("write index of event %s: %v", eventID, err)
+		return
+	}
+	if err := os.Rename(tmp, s.indexPath(eventID)); err != nil {
+		s.log.Debugf("save index of event %s: %v", eventID, err)
+		os.Remove(tmp)
+	}
+}
+
+// scan 读取事件日志中已被索引的部分
+func (s *EventLogSearcher) scan(eventID string, size int64, fn func(msg *db.EventLogMessage)) error {
+	_, err := s.scanFrom(eventID, 0, size, fn)
+	return err
+}
+
+// scanFrom 从 offset 开始逐行解析，limit 为 0 时读到文件末尾。
+// 返回最后一个完整行之后的偏移，正在写入的半行不会被读取。
+func (s *EventLogSearcher) scanFrom(eventID string, offset, limit int64, fn func(msg *db.EventLogMessage)) (int64, error) {
+	file, err := os.Open(s.logPath(eventID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return offset, nil
+		}
+		return offset, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	var reader io.Reader = file
+	if limit > 0 {
+		reader = io.LimitReader(file, limit-offset)
+	}
+	buffered := bufio.NewReaderSize(reader, 64*1024)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var msg db.EventLogMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		fn(&msg)
+	}
+}
+
+func (s *EventLogSearcher) logPath(eventID string) string {
+	return filepath.Join(s.basePath, eventID+".jsonl")
+}
+
+func (s *EventLogSearcher) indexPath(eventID string) string {
+	return filepath.Join(s.basePath, eventID+indexFileExt)
+}
+
+func messageMatches(msg *db.EventLogMessage, query *EventLogQuery, keyword string) bool {
+	if !levelMatches(msg.Level, query.Level) {
+		return false
+	}
+	if query.Step != "" && msg.Step != query.Step {
+		return false
+	}
+	if query.Since != 0 || query.Until != 0 {
+		unix := parseMessageTime(msg.Time)
+		if unix == 0 || (query.Since != 0 && unix < query.Since) || (query.Until != 0 && unix > query.Until) {
+			return false
+		}
+	}
+	if keyword != "" && !strings.Contains(strings.ToLower(msg.Message), keyword) {
+		return false
+	}
+	if query.Regex != nil && !query.Regex.MatchString(msg.Message) {
+		return false
+	}
+	return true
+}
+
+// levelMatches 与 EventFilePlugin 读取结构化日志时的级别过滤一致
+func levelMatches(logLevel, queryLevel string) bool {
+	switch logLevel {
+	case "error":
+		return true
+	case "debug":
+		return queryLevel == "debug"
+	default:
+		return queryLevel != "error"
+	}
+}
+
+// parseMessageTime 解析日志时间，事件日志使用 RFC3339，兼容旧的无时区格式
+func parseMessageTime(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.Unix()
+	}
+	value = strings.SplitN(value, ".", 2)[0]
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	copied := make(map[string]int, len(counts))
+	for k, v := range counts {
+		copied[k] = v
+	}
+	return copied
+}
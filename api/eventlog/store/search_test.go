@@ -0,0 +1,142 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/api/eventlog/db"
+	"github.com/sirupsen/logrus"
+)
+
+func appendSearchMessages(t *testing.T, store *JSONLinesFileStore, eventID string, base time.Time, messages ...db.EventLogMessage) {
+	t.Helper()
+	for i := range messages {
+		msg := messages[i]
+		msg.EventID = eventID
+		msg.Time = base.Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+		if err := store.Append(eventID, &msg); err != nil {
+			t.Fatalf("append message: %v", err)
+		}
+	}
+}
+
+// capability_id: rainbond.eventlog.search
+func TestEventLogSearch(t *testing.T) {
+	store, err := NewJSONLinesFileStore(t.TempDir(), logrus.WithField("test", "search"))
+	if err != nil {
+		t.Fatalf("create file store: %v", err)
+	}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	appendSearchMessages(t, store, "build", base,
+		db.EventLogMessage{Step: "build-code", Level: "info", Message: "Cloning repository"},
+		db.EventLogMessage{Step: "build-code", Level: "debug", Message: "npm install"},
+		db.EventLogMessage{Step: "build-code", Level: "error", Message: "npm ERR! code ETIMEDOUT"},
+		db.EventLogMessage{Step: "callback", Level: "error", Message: "Build failed", Status: "failure"},
+	)
+	appendSearchMessages(t, store, "deploy", base.Add(time.Hour),
+		db.EventLogMessage{Step: "worker-handle", Level: "info", Message: "Starting pods"},
+		db.EventLogMessage{Step: "worker-handle", Level: "error", Message: "ImagePullBackOff"},
+	)
+	events := []string{"deploy", "build", "missing"}
+
+	tests := []struct {
+		name   string
+		query  EventLogQuery
+		total  int
+		events int
+		first  string
+	}{
+		{name: "all info and error", query: EventLogQuery{}, total: 5, events: 2, first: "Starting pods"},
+		{name: "debug includes everything", query: EventLogQuery{Level: "debug"}, total: 6, events: 2, first: "Starting pods"},
+		{name: "errors only", query: EventLogQuery{Level: "error"}, total: 3, events: 2, first: "ImagePullBackOff"},
+		{name: "keyword ignores case", query: EventLogQuery{Keyword: "NPM err"}, total: 1, events: 1, first: "npm ERR! code ETIMEDOUT"},
+		{name: "regex", query: EventLogQuery{Regex: regexp.MustCompile(`(?i)backoff|timedout`)}, total: 2, events: 2, first: "ImagePullBackOff"},
+		{name: "step", query: EventLogQuery{Step: "callback"}, total: 1, events: 1, first: "Build failed"},
+		{name: "time range", query: EventLogQuery{Since: base.Add(30 * time.Minute).Unix()}, total: 2, events: 1, first: "Starting pods"},
+		{name: "time range within event", query: EventLogQuery{Since: base.Unix(), Until: base.Add(time.Second).Unix(), Level: "debug"}, total: 2, events: 1, first: "Cloning repository"},
+		{name: "pagination", query: EventLogQuery{Offset: 2, Limit: 2}, total: 5, events: 2, first: "Cloning repository"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			query.EventIDs = events
+			result, err := store.Search(&query)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if result.Total != tt.total || result.Events != tt.events {
+				t.Fatalf("expected %d hits in %d events, got %d in %d: %+v", tt.total, tt.events, result.Total, result.Events, result.Hits)
+			}
+			if len(result.Hits) == 0 || result.Hits[0].Message != tt.first {
+				t.Fatalf("expected first hit %q, got %+v", tt.first, result.Hits)
+			}
+			if tt.query.Limit > 0 && len(result.Hits) > tt.query.Limit {
+				t.Fatalf("expected at most %d hits, got %d", tt.query.Limit, len(result.Hits))
+			}
+		})
+	}
+}
+
+// capability_id: rainbond.eventlog.search
+func TestEventLogSearchIndexIsIncremental(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewJSONLinesFileStore(dir, logrus.WithField("test", "search"))
+	if err != nil {
+		t.Fatalf("create file store: %v", err)
+	}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	appendSearchMessages(t, store, "event", base, db.EventLogMessage{Step: "build", Level: "info", Message: "first"})
+
+	result, err := store.Search(&EventLogQuery{EventIDs: []string{"event"}, Step: "deploy"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if result.Total != 0 {
+		t.Fatalf("expected no hits, got %+v", result.Hits)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "event"+indexFileExt)); err != nil {
+		t.Fatalf("expected index file: %v", err)
+	}
+
+	// a half written line is not indexed until it is complete
+	file, err := os.OpenFile(filepath.Join(dir, "event.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"step":"deploy","level":"info","message":"sec`)
+	result, err = store.Search(&EventLogQuery{EventIDs: []string{"event"}, Step: "deploy"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if result.Total != 0 {
+		t.Fatalf("expected partial line ignored, got %+v", result.Hits)
+	}
+	file.WriteString(`ond","time":"` + base.Add(time.Minute).Format(time.RFC3339) + "\"}\n")
+	file.Close()
+
+	// a new searcher reloads the saved index and extends it
+	searcher := NewEventLogSearcher(dir, nil)
+	result, err = searcher.Search(&EventLogQuery{EventIDs: []string{"event"}, Step: "deploy"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if result.Total != 1 || result.Hits[0].Message != "second" {
+		t.Fatalf("expected appended message found, got %+v", result.Hits)
+	}
+	if index := searcher.indexes["event"]; index.Lines != 2 || index.Steps["deploy"] != 1 {
+		t.Fatalf("unexpected index %+v", index)
+	}
+
+	if err := store.Delete("event"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "event"+indexFileExt)); !os.IsNotExist(err) {
+		t.Fatalf("expected index removed with the log, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"time"
+
+	db2 "github.com/goodrain/rainbond/api/eventlog/db"
+	"github.com/goodrain/rainbond/api/eventlog/sink"
+	cdb "github.com/goodrain/rainbond/db"
+)
+
+// eventSinkRecord 事件日志转换为外发记录
+func eventSinkRecord(message *db2.EventLogMessage) *sink.Record {
+	record := &sink.Record{
+		Source:  sink.SourceEvent,
+		EventID: message.EventID,
+		Step:    message.Step,
+		Status:  message.Status,
+		Level:   message.Level,
+		Message: message.Message,
+		Time:    time.Now(),
+	}
+	if unix := parseMessageTime(message.Time); unix != 0 {
+		record.Time = time.Unix(unix, 0)
+	}
+	return record
+}
+
+// resolveSinkTenant 事件日志按事件、容器日志按组件查询租户，平台组件的日志没有租户
+func resolveSinkTenant(source, id string) (string, error) {
+	if source == sink.SourceContainer {
+		switch id {
+		case RBDSERVICEIDAPI, RBDSERVICEIDWORKER, RBDSERVICEIDGATEWAY, RBDSERVICEIDCHAOS:
+			return "", nil
+		}
+		service, err := cdb.GetManager().TenantServiceDao().GetServiceByID(id)
+		if err != nil {
+			return "", err
+		}
+		return service.TenantID, nil
+	}
+	event, err := cdb.GetManager().ServiceEventDao().GetEventByEventID(id)
+	if err != nil {
+		return "", err
+	}
+	return event.TenantID, nil
+}
@@ -0,0 +1,262 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// notificationEventTextLimit message 与 reason 字段的长度限制
+	notificationEventTextLimit = 200
+	// notificationEventSeverityLimit severity 字段的长度限制
+	notificationEventSeverityLimit = 20
+)
+
+// AlertManagerHandler 接入 alertmanager 推送的告警
+type AlertManagerHandler interface {
+	IngestAlerts(payload *model.AlertManagerWebhook) (*model.AlertIngestResult, error)
+}
+
+var defaultAlertManagerHandler AlertManagerHandler
+
+// NewAlertManagerHandler creates a new alertmanager handler
+func NewAlertManagerHandler() AlertManagerHandler {
+	return &AlertManagerAction{}
+}
+
+// GetAlertManagerHandler get alertmanager handler
+func GetAlertManagerHandler() AlertManagerHandler {
+	return defaultAlertManagerHandler
+}
+
+// AlertManagerAction alertmanager action
+type AlertManagerAction struct{}
+
+// alertTarget 告警归属的组件、团队或集群
+type alertTarget struct {
+	kind        string
+	kindID      string
+	serviceName string
+	tenantName  string
+}
+
+// IngestAlerts 将告警按 Rainbond 在 pod 上的标签归属到组件或团队，按 fingerprint 去重保存为通知事件。
+// 告警从恢复再次触发时计数加一，并重新标记为未处理。
+func (a *AlertManagerAction) IngestAlerts(payload *model.AlertManagerWebhook) (*model.AlertIngestResult, error) {
+	if payload.Version != "4" {
+		return nil, bcode.ErrAlertManagerVersion
+	}
+	result := &model.AlertIngestResult{}
+	targets := make(map[string]*alertTarget)
+	for _, alert := range payload.Alerts {
+		result.Received++
+		state := alert.Status
+		if state == "" {
+			state = payload.Status
+		}
+		if state != dbmodel.AlertStateFiring && state != dbmodel.AlertStateResolved {
+			continue
+		}
+		target, err := a.cachedAlertTarget(targets, alert.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.saveAlert(alert, state, target); err != nil {
+			return nil, err
+		}
+		if state == dbmodel.AlertStateFiring {
+			result.Firing++
+		} else {
+			result.Resolved++
+		}
+		if target.kind == "service" {
+			result.Components++
+		}
+	}
+	return result, nil
+}
+
+func (a *AlertManagerAction) saveAlert(alert model.AlertManagerAlert, state string, target *alertTarget) error {
+	event := &dbmodel.NotificationEvent{
+		Kind:        target.kind,
+		KindID:      target.kindID,
+		Hash:        "alert-" + alertFingerprint(alert),
+		Type:        "UnNormal",
+		Message:     truncateAlertText(alertMessage(alert), notificationEventTextLimit),
+		Reason:      truncateAlertText(alert.Labels["alertname"], notificationEventTextLimit),
+		Count:       1,
+		ServiceName: target.serviceName,
+		TenantName:  target.tenantName,
+		Source:      dbmodel.NotificationEventSourceAlertManager,
+		State:       state,
+		Severity:    truncateAlertText(alert.Labels["severity"], notificationEventSeverityLimit),
+	}
+	if state == dbmodel.AlertStateResolved {
+		event.Type = "Normal"
+	}
+	existing, err := db.GetManager().NotificationEventDao().GetNotificationEventByHash(event.Hash)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Wrap(err, "get notification event")
+	}
+	if err == nil {
+		event.Count = existing.Count
+		event.IsHandle = existing.IsHandle
+		event.HandleMessage = existing.HandleMessage
+		if state == dbmodel.AlertStateFiring && existing.State != dbmodel.AlertStateFiring {
+			// fires again after it was resolved
+			event.Count++
+			event.IsHandle = false
+			event.HandleMessage = ""
+		}
+	}
+	return errors.Wrap(db.GetManager().NotificationEventDao().AddModel(event), "save notification event")
+}
+
+func (a *AlertManagerAction) cachedAlertTarget(targets map[string]*alertTarget, labels map[string]string) (*alertTarget, error) {
+	key := strings.Join([]string{
+		alertLabel(labels, "service_id"), alertLabel(labels, "service_alias"),
+		alertLabel(labels, "tenant_id"), alertLabel(labels, "tenant_name"), labels["namespace"],
+	}, "/")
+	if target, ok := targets[key]; ok {
+		return target, nil
+	}
+	target, err := a.resolveAlertTarget(labels)
+	if err != nil {
+		return nil, err
+	}
+	targets[key] = target
+	return target, nil
+}
+
+// resolveAlertTarget 优先按 service_id 归属到组件，其次按团队标签或命名空间归属到团队，都不匹配时归属到集群
+func (a *AlertManagerAction) resolveAlertTarget(labels map[string]string) (*alertTarget, error) {
+	if serviceID := alertLabel(labels, "service_id"); serviceID != "" {
+		service, err := db.GetManager().TenantServiceDao().GetServiceByID(serviceID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrap(err, "get component")
+		}
+		if err == nil {
+			tenant, err := db.GetManager().TenantDao().GetTenantByUUID(service.TenantID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.Wrap(err, "get tenant")
+			}
+			target := &alertTarget{kind: "service", kindID: service.ServiceID, serviceName: service.ServiceAlias}
+			if tenant != nil {
+				target.tenantName = tenant.Name
+			}
+			return target, nil
+		}
+	}
+
+	tenant, err := alertTenant(labels)
+	if err != nil {
+		return nil, err
+	}
+	if tenant == nil {
+		return &alertTarget{kind: "cluster"}, nil
+	}
+	if alias := alertLabel(labels, "service_alias"); alias != "" {
+		service, err := db.GetManager().TenantServiceDao().GetServiceByTenantIDAndServiceAlias(tenant.UUID, alias)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrap(err, "get component")
+		}
+		if err == nil {
+			return &alertTarget{kind: "service", kindID: service.ServiceID, serviceName: service.ServiceAlias, tenantName: tenant.Name}, nil
+		}
+	}
+	return &alertTarget{kind: "tenant", kindID: tenant.UUID, tenantName: tenant.Name}, nil
+}
+
+// alertTenant 按 tenant_id、tenant_name 标签查找团队，团队的命名空间默认为团队 ID
+func alertTenant(labels map[string]string) (*dbmodel.Tenants, error) {
+	lookups := []struct {
+		value string
+		get   func(string) (*dbmodel.Tenants, error)
+	}{
+		{alertLabel(labels, "tenant_id"), db.GetManager().TenantDao().GetTenantByUUID},
+		{alertLabel(labels, "tenant_name"), db.GetManager().TenantDao().GetTenantIDByName},
+		{labels["namespace"], db.GetManager().TenantDao().GetTenantByUUID},
+	}
+	for _, lookup := range lookups {
+		if lookup.value == "" {
+			continue
+		}
+		tenant, err := lookup.get(lookup.value)
+		if err == nil {
+			return tenant, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrap(err, "get tenant")
+		}
+	}
+	return nil, nil
+}
+
+// alertLabel the pod label of the alert, kube-state-metrics exports the pod labels with the label_ prefix
+func alertLabel(labels map[string]string, name string) string {
+	if value := labels[name]; value != "" {
+		return value
+	}
+	return labels["label_"+name]
+}
+
+// alertFingerprint alertmanager always sends the fingerprint, the labels are hashed for the senders which do not
+func alertFingerprint(alert model.AlertManagerAlert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	names := make([]string, 0, len(alert.Labels))
+	for name := range alert.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name + "\xff" + alert.Labels[name] + "\xff"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func alertMessage(alert model.AlertManagerAlert) string {
+	for _, key := range []string{"summary", "description", "message"} {
+		if value := alert.Annotations[key]; value != "" {
+			return value
+		}
+	}
+	return alert.Labels["alertname"]
+}
+
+func truncateAlertText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
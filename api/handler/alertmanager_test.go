@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+type alertTestManager struct {
+	db.Manager
+	events *alertTestEventDao
+}
+
+func (m alertTestManager) NotificationEventDao() dbdao.NotificationEventDao { return m.events }
+
+func (m alertTestManager) TenantDao() dbdao.TenantDao { return alertTestTenantDao{} }
+
+func (m alertTestManager) TenantServiceDao() dbdao.TenantServiceDao { return alertTestServiceDao{} }
+
+type alertTestEventDao struct {
+	dbdao.NotificationEventDao
+	events map[string]*dbmodel.NotificationEvent
+}
+
+func (d *alertTestEventDao) AddModel(mo dbmodel.Interface) error {
+	event := mo.(*dbmodel.NotificationEvent)
+	d.events[event.Hash] = event
+	return nil
+}
+
+func (d *alertTestEventDao) GetNotificationEventByHash(hash string) (*dbmodel.NotificationEvent, error) {
+	if event, ok := d.events[hash]; ok {
+		copied := *event
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type alertTestTenantDao struct {
+	dbdao.TenantDao
+}
+
+func (alertTestTenantDao) GetTenantByUUID(uuid string) (*dbmodel.Tenants, error) {
+	if uuid == "tenant-id" {
+		return &dbmodel.Tenants{UUID: "tenant-id", Name: "team"}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (alertTestTenantDao) GetTenantIDByName(name string) (*dbmodel.Tenants, error) {
+	if name == "team" {
+		return &dbmodel.Tenants{UUID: "tenant-id", Name: "team"}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type alertTestServiceDao struct {
+	dbdao.TenantServiceDao
+}
+
+func (alertTestServiceDao) GetServiceByID(serviceID string) (*dbmodel.TenantServices, error) {
+	if serviceID == "svc-id" {
+		return &dbmodel.TenantServices{TenantID: "tenant-id", ServiceID: "svc-id", ServiceAlias: "gr123456"}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (alertTestServiceDao) GetServiceByTenantIDAndServiceAlias(tenantID, alias string) (*dbmodel.TenantServices, error) {
+	if tenantID == "tenant-id" && alias == "gr123456" {
+		return &dbmodel.TenantServices{TenantID: "tenant-id", ServiceID: "svc-id", ServiceAlias: "gr123456"}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func setupAlertTest(t *testing.T) *alertTestEventDao {
+	events := &alertTestEventDao{events: map[string]*dbmodel.NotificationEvent{}}
+	db.SetTestManager(alertTestManager{events: events})
+	t.Cleanup(func() { db.SetTestManager(nil) })
+	return events
+}
+
+func newAlertTestPayload(status string, alerts ...model.AlertManagerAlert) *model.AlertManagerWebhook {
+	return &model.AlertManagerWebhook{Version: "4", Status: status, Alerts: alerts}
+}
+
+// capability_id: rainbond.monitor.alertmanager-ingest
+func TestIngestAlertsMapsLabelsToTargets(t *testing.T) {
+	events := setupAlertTest(t)
+	handler := &AlertManagerAction{}
+
+	result, err := handler.IngestAlerts(newAlertTestPayload("firing",
+		model.AlertManagerAlert{Status: "firing", Fingerprint: "a1", StartsAt: time.Now(),
+			Labels:      map[string]string{"alertname": "HighMemory", "severity": "warning", "label_service_id": "svc-id"},
+			Annotations: map[string]string{"summary": "memory above 90%"}},
+		model.AlertManagerAlert{Status: "firing", Fingerprint: "a2",
+			Labels: map[string]string{"alertname": "PodRestart", "namespace": "tenant-id", "service_alias": "gr123456"}},
+		model.AlertManagerAlert{Status: "firing", Fingerprint: "a3",
+			Labels: map[string]string{"alertname": "QuotaNearlyFull", "tenant_name": "team"}},
+		model.AlertManagerAlert{Status: "firing", Fingerprint: "a4",
+			Labels: map[string]string{"alertname": "NodeDown", "instance": "10.0.0.1", "severity": strings.Repeat("critical", 5)}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Received != 4 || result.Firing != 4 || result.Components != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	memory := events.events["alert-a1"]
+	if memory == nil || memory.Kind != "service" || memory.KindID != "svc-id" || memory.ServiceName != "gr123456" ||
+		memory.TenantName != "team" || memory.State != dbmodel.AlertStateFiring || memory.Severity != "warning" ||
+		memory.Message != "memory above 90%" || memory.Reason != "HighMemory" || memory.Source != dbmodel.NotificationEventSourceAlertManager {
+		t.Fatalf("unexpected component alert %+v", memory)
+	}
+	if restart := events.events["alert-a2"]; restart.Kind != "service" || restart.KindID != "svc-id" {
+		t.Fatalf("expected the alert to map by namespace and service alias, got %+v", restart)
+	}
+	if quota := events.events["alert-a3"]; quota.Kind != "tenant" || quota.KindID != "tenant-id" {
+		t.Fatalf("expected a tenant alert, got %+v", quota)
+	}
+	if node := events.events["alert-a4"]; node.Kind != "cluster" || node.KindID != "" || len(node.Severity) != 20 {
+		t.Fatalf("expected a cluster alert with the severity truncated, got %+v", node)
+	}
+}
+
+// capability_id: rainbond.monitor.alertmanager-dedup
+func TestIngestAlertsDedupsByFingerprint(t *testing.T) {
+	events := setupAlertTest(t)
+	handler := &AlertManagerAction{}
+	alert := model.AlertManagerAlert{Status: "firing", Fingerprint: "f1",
+		Labels: map[string]string{"alertname": "HighCPU", "service_id": "svc-id"}}
+
+	// repeated notifications of a firing alert
+	for i := 0; i < 2; i++ {
+		if _, err := handler.IngestAlerts(newAlertTestPayload("firing", alert)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events.events) != 1 || events.events["alert-f1"].Count != 1 {
+		t.Fatalf("expected one alert fired once, got %+v", events.events)
+	}
+
+	events.events["alert-f1"].IsHandle = true
+	alert.Status = "resolved"
+	if _, err := handler.IngestAlerts(newAlertTestPayload("resolved", alert)); err != nil {
+		t.Fatal(err)
+	}
+	resolved := events.events["alert-f1"]
+	if resolved.State != dbmodel.AlertStateResolved || resolved.Type != "Normal" || !resolved.IsHandle {
+		t.Fatalf("expected the handled alert to be resolved, got %+v", resolved)
+	}
+
+	alert.Status = "firing"
+	if _, err := handler.IngestAlerts(newAlertTestPayload("firing", alert)); err != nil {
+		t.Fatal(err)
+	}
+	refired := events.events["alert-f1"]
+	if refired.State != dbmodel.AlertStateFiring || refired.Count != 2 || refired.IsHandle {
+		t.Fatalf("expected the alert to fire again unhandled, got %+v", refired)
+	}
+}
+
+// capability_id: rainbond.monitor.alertmanager-ingest
+func TestIngestAlertsRejectsOtherVersions(t *testing.T) {
+	setupAlertTest(t)
+	_, err := (&AlertManagerAction{}).IngestAlerts(&model.AlertManagerWebhook{Version: "3"})
+	if err != bcode.ErrAlertManagerVersion {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// APITokenPrefix 作用域 token 的前缀，便于识别泄露的 token
+const APITokenPrefix = "rbd_"
+
+// APITokenHandler 作用域 api token 的签发与吊销
+type APITokenHandler interface {
+	CreateAPIToken(req *apimodel.CreateAPITokenReq) (*apimodel.CreateAPITokenResp, error)
+	ListAPITokens() ([]*apimodel.APITokenInfo, error)
+	RevokeAPIToken(name string) error
+}
+
+var defaultAPITokenHandler APITokenHandler
+
+// NewAPITokenHandler creates a new api token handler
+func NewAPITokenHandler() APITokenHandler {
+	return &APITokenAction{}
+}
+
+// GetAPITokenHandler get api token handler
+func GetAPITokenHandler() APITokenHandler {
+	return defaultAPITokenHandler
+}
+
+// APITokenAction api token action
+type APITokenAction struct {
+	dbmanager db.Manager
+}
+
+func (a *APITokenAction) getDBManager() db.Manager {
+	if a.dbmanager != nil {
+		return a.dbmanager
+	}
+	return db.GetManager()
+}
+
+// HashAPIToken 数据库中只保存 token 的 sha256
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken 签发 token，明文只在创建时返回一次
+func (a *APITokenAction) CreateAPIToken(req *apimodel.CreateAPITokenReq) (*apimodel.CreateAPITokenResp, error) {
+	verbs, err := normalizeAPITokenVerbs(req.Verbs)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpireTime != nil && !req.ExpireTime.After(time.Now()) {
+		return nil, bcode.ErrAPITokenExpired
+	}
+	if _, err := a.getDBManager().APITokenDao().GetByName(req.Name); err == nil {
+		return nil, bcode.ErrAPITokenExists
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "get api token")
+	}
+	var tenantIDs, tenantNames []string
+	for _, name := range req.Tenants {
+		tenant, err := a.getDBManager().TenantDao().GetTenantIDByName(name)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, bcode.NewBadRequest(fmt.Sprintf("tenant %s not found", name))
+			}
+			return nil, errors.Wrapf(err, "get tenant %s", name)
+		}
+		tenantIDs = append(tenantIDs, tenant.UUID)
+		tenantNames = append(tenantNames, tenant.Name)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "generate api token")
+	}
+	plaintext := APITokenPrefix + hex.EncodeToString(secret)
+	token := &dbmodel.APIToken{
+		Name:       req.Name,
+		TokenHash:  HashAPIToken(plaintext),
+		TenantIDs:  strings.Join(tenantIDs, ","),
+		Verbs:      strings.Join(verbs, ","),
+		ExpireTime: req.ExpireTime,
+	}
+	if err := a.getDBManager().APITokenDao().AddModel(token); err != nil {
+		return nil, errors.Wrap(err, "create api token")
+	}
+	return &apimodel.CreateAPITokenResp{
+		APITokenInfo: apimodel.APITokenInfo{
+			Name:       token.Name,
+			Tenants:    tenantNames,
+			Verbs:      verbs,
+			ExpireTime: token.ExpireTime,
+			CreateTime: token.CreatedAt,
+		},
+		Token: plaintext,
+	}, nil
+}
+
+// ListAPITokens 列出所有 token，不包含明文
+func (a *APITokenAction) ListAPITokens() ([]*apimodel.APITokenInfo, error) {
+	tokens, err := a.getDBManager().APITokenDao().List()
+	if err != nil {
+		return nil, errors.Wrap(err, "list api tokens")
+	}
+	tenantNames := make(map[string]string)
+	res := make([]*apimodel.APITokenInfo, 0, len(tokens))
+	for _, token := range tokens {
+		info := &apimodel.APITokenInfo{
+			Name:         token.Name,
+			Tenants:      []string{},
+			Verbs:        splitAPITokenList(token.Verbs),
+			ExpireTime:   token.ExpireTime,
+			LastUsedTime: token.LastUsedTime,
+			CreateTime:   token.CreatedAt,
+		}
+		for _, tenantID := range splitAPITokenList(token.TenantIDs) {
+			name, ok := tenantNames[tenantID]
+			if !ok {
+				// 租户已删除时返回其 id
+				name = tenantID
+				if tenant, err := a.getDBManager().TenantDao().GetTenantByUUID(tenantID); err == nil {
+					name = tenant.Name
+				}
+				tenantNames[tenantID] = name
+			}
+			info.Tenants = append(info.Tenants, name)
+		}
+		res = append(res, info)
+	}
+	return res, nil
+}
+
+// RevokeAPIToken 吊销 token
+func (a *APITokenAction) RevokeAPIToken(name string) error {
+	if _, err := a.getDBManager().APITokenDao().GetByName(name); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bcode.ErrAPITokenNotFound
+		}
+		return errors.Wrap(err, "get api token")
+	}
+	return a.getDBManager().APITokenDao().DeleteByName(name)
+}
+
+// AuthorizeCrossTenant 校验请求能否以 verb 操作路径租户以外的租户 otherTenantID。
+// rbd-api 不感知用户与团队成员关系，管理员 token 无法代表某个用户授权，
+// 因此跨租户操作必须使用同时绑定两个租户并授予该操作的作用域 token。
+func AuthorizeCrossTenant(ctx context.Context, tenantID, otherTenantID, verb string) error {
+	if otherTenantID == "" || otherTenantID == tenantID {
+		return nil
+	}
+	token, ok := ctx.Value(ctxutil.ContextKey("api_token")).(*dbmodel.APIToken)
+	if ok && token.AllowTenant(tenantID) && token.AllowTenant(otherTenantID) && token.AllowVerb(verb) {
+		return nil
+	}
+	return bcode.ErrCrossTenantForbidden
+}
+
+func normalizeAPITokenVerbs(verbs []string) ([]string, error) {
+	var res []string
+	seen := make(map[string]bool)
+	for _, verb := range verbs {
+		verb = strings.ToLower(strings.TrimSpace(verb))
+		valid := false
+		for _, v := range dbmodel.APITokenVerbs {
+			valid = valid || v == verb
+		}
+		if !valid {
+			return nil, errors.Wrap(bcode.ErrAPITokenInvalidVerb, verb)
+		}
+		if !seen[verb] {
+			seen[verb] = true
+			res = append(res, verb)
+		}
+	}
+	return res, nil
+}
+
+func splitAPITokenList(list string) []string {
+	res := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiTokenTestManager struct {
+	db.Manager
+	tenantDao   dbdao.TenantDao
+	apiTokenDao dbdao.APITokenDao
+}
+
+func (m apiTokenTestManager) TenantDao() dbdao.TenantDao {
+	return m.tenantDao
+}
+
+func (m apiTokenTestManager) APITokenDao() dbdao.APITokenDao {
+	return m.apiTokenDao
+}
+
+type apiTokenTestTenantDao struct {
+	dbdao.TenantDao
+	tenants []*dbmodel.Tenants
+}
+
+func (d *apiTokenTestTenantDao) GetTenantIDByName(name string) (*dbmodel.Tenants, error) {
+	for _, tenant := range d.tenants {
+		if tenant.Name == name {
+			return tenant, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *apiTokenTestTenantDao) GetTenantByUUID(uuid string) (*dbmodel.Tenants, error) {
+	for _, tenant := range d.tenants {
+		if tenant.UUID == uuid {
+			return tenant, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type apiTokenTestDao struct {
+	dbdao.APITokenDao
+	tokens []*dbmodel.APIToken
+}
+
+func (d *apiTokenTestDao) AddModel(mo dbmodel.Interface) error {
+	d.tokens = append(d.tokens, mo.(*dbmodel.APIToken))
+	return nil
+}
+
+func (d *apiTokenTestDao) GetByName(name string) (*dbmodel.APIToken, error) {
+	for _, token := range d.tokens {
+		if token.Name == name {
+			return token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *apiTokenTestDao) List() ([]*dbmodel.APIToken, error) {
+	return d.tokens, nil
+}
+
+func (d *apiTokenTestDao) DeleteByName(name string) error {
+	for i, token := range d.tokens {
+		if token.Name == name {
+			d.tokens = append(d.tokens[:i], d.tokens[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func newAPITokenTestAction() (*APITokenAction, *apiTokenTestDao) {
+	tokenDao := &apiTokenTestDao{}
+	return &APITokenAction{dbmanager: apiTokenTestManager{
+		tenantDao: &apiTokenTestTenantDao{tenants: []*dbmodel.Tenants{
+			{Name: "team-a", UUID: "tenant-a"},
+			{Name: "team-b", UUID: "tenant-b"},
+		}},
+		apiTokenDao: tokenDao,
+	}}, tokenDao
+}
+
+// capability_id: rainbond.api-token.issue-hashed
+func TestCreateAPITokenStoresOnlyHash(t *testing.T) {
+	action, tokenDao := newAPITokenTestAction()
+	expire := time.Now().Add(time.Hour)
+
+	resp, err := action.CreateAPIToken(&apimodel.CreateAPITokenReq{
+		Name:       "ci",
+		Tenants:    []string{"team-a", "team-b"},
+		Verbs:      []string{"Read", "deploy", "read"},
+		ExpireTime: &expire,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(resp.Token, APITokenPrefix))
+	assert.Equal(t, []string{"team-a", "team-b"}, resp.Tenants)
+	assert.Equal(t, []string{"read", "deploy"}, resp.Verbs)
+	require.Len(t, tokenDao.tokens, 1)
+	stored := tokenDao.tokens[0]
+	assert.Equal(t, HashAPIToken(resp.Token), stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, resp.Token)
+	assert.Equal(t, "tenant-a,tenant-b", stored.TenantIDs)
+	assert.Equal(t, "read,deploy", stored.Verbs)
+
+	tokens, err := action.ListAPITokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, []string{"team-a", "team-b"}, tokens[0].Tenants)
+}
+
+// capability_id: rainbond.api-token.issue-validation
+func TestCreateAPITokenRejectsInvalidRequests(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	testCases := []struct {
+		name string
+		req  *apimodel.CreateAPITokenReq
+		want error
+	}{
+		{
+			name: "unknown verb",
+			req:  &apimodel.CreateAPITokenReq{Name: "ci", Tenants: []string{"team-a"}, Verbs: []string{"admin"}},
+			want: bcode.ErrAPITokenInvalidVerb,
+		},
+		{
+			name: "expired",
+			req:  &apimodel.CreateAPITokenReq{Name: "ci", Tenants: []string{"team-a"}, Verbs: []string{"read"}, ExpireTime: &past},
+			want: bcode.ErrAPITokenExpired,
+		},
+		{
+			name: "duplicate name",
+			req:  &apimodel.CreateAPITokenReq{Name: "exists", Tenants: []string{"team-a"}, Verbs: []string{"read"}},
+			want: bcode.ErrAPITokenExists,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action, tokenDao := newAPITokenTestAction()
+			tokenDao.tokens = []*dbmodel.APIToken{{Name: "exists"}}
+
+			_, err := action.CreateAPIToken(tc.req)
+
+			assert.Equal(t, tc.want, errors.Cause(err))
+			assert.Len(t, tokenDao.tokens, 1)
+		})
+	}
+
+	action, _ := newAPITokenTestAction()
+	_, err := action.CreateAPIToken(&apimodel.CreateAPITokenReq{Name: "ci", Tenants: []string{"missing"}, Verbs: []string{"read"}})
+	assert.Error(t, err)
+}
+
+// capability_id: rainbond.api-token.revoke
+func TestRevokeAPIToken(t *testing.T) {
+	action, tokenDao := newAPITokenTestAction()
+	tokenDao.tokens = []*dbmodel.APIToken{{Name: "ci"}}
+
+	require.NoError(t, action.RevokeAPIToken("ci"))
+	assert.Empty(t, tokenDao.tokens)
+	assert.Equal(t, bcode.ErrAPITokenNotFound, action.RevokeAPIToken("ci"))
+}
+
+// capability_id: rainbond.api-token.cross-tenant-grant
+func TestAuthorizeCrossTenant(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, AuthorizeCrossTenant(ctx, "tenant-1", "", dbmodel.APITokenVerbDeploy))
+	assert.NoError(t, AuthorizeCrossTenant(ctx, "tenant-1", "tenant-1", dbmodel.APITokenVerbDeploy))
+	// the admin token carries no grant of the other tenant
+	assert.Equal(t, bcode.ErrCrossTenantForbidden, AuthorizeCrossTenant(ctx, "tenant-1", "tenant-2", dbmodel.APITokenVerbDeploy))
+
+	withToken := func(tenantIDs, verbs string) context.Context {
+		return context.WithValue(ctx, ctxutil.ContextKey("api_token"), &dbmodel.APIToken{TenantIDs: tenantIDs, Verbs: verbs})
+	}
+	assert.NoError(t, AuthorizeCrossTenant(withToken("tenant-1,tenant-2", "read,deploy"), "tenant-1", "tenant-2", dbmodel.APITokenVerbDeploy))
+	assert.Equal(t, bcode.ErrCrossTenantForbidden, AuthorizeCrossTenant(withToken("tenant-1", "deploy"), "tenant-1", "tenant-2", dbmodel.APITokenVerbDeploy))
+	assert.Equal(t, bcode.ErrCrossTenantForbidden, AuthorizeCrossTenant(withToken("tenant-1,tenant-2", "read"), "tenant-1", "tenant-2", dbmodel.APITokenVerbDeploy))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/goodrain/rainbond/db/model"
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
@@ -15,6 +16,37 @@ import (
 type AppGoveranceModeHandler interface {
 	IsInstalledControlPlane() bool
 	GetInjectLabels() map[string]string
+	// GetInjectAnnotations returns the pod annotations the mesh needs
+	GetInjectAnnotations() map[string]string
+	// BuildAuthorizationPolicies translates the dependencies of the component into
+	// the authorization policies of the mesh, nil if the mode has no policies.
+	BuildAuthorizationPolicies(component *MeshComponent) []*unstructured.Unstructured
+}
+
+// MeshComponent the component and the components depending on it
+type MeshComponent struct {
+	Namespace string
+	// Name the workload name of the component
+	Name      string
+	ServiceID string
+	// ServiceAccount the service account set on the component, empty when it has none
+	ServiceAccount string
+	Ports          []MeshPort
+	Consumers      []MeshConsumer
+}
+
+// MeshPort a port of the component
+type MeshPort struct {
+	Port int32
+	// Outer the port is exposed by the gateway, so it accepts traffic from any client
+	Outer bool
+}
+
+// MeshConsumer a component depending on the component
+type MeshConsumer struct {
+	ServiceID string
+	// ServiceAccount the service account set on the consumer, empty when it has none
+	ServiceAccount string
 }
 
 // NewAppGoveranceModeHandler -
@@ -26,6 +58,10 @@ func NewAppGoveranceModeHandler(governanceMode string, kubeClient clientset.Inte
 		return NewBuildInServiceMeshMode(), nil
 	case model.GovernanceModeKubernetesNativeService:
 		return NewKubernetesNativeMode(), nil
+	case model.GovernanceModeLinkerdServiceMesh:
+		return NewLinkerdGoveranceMode(kubeClient), nil
+	case model.GovernanceModeCiliumServiceMesh:
+		return NewCiliumGoveranceMode(kubeClient), nil
 	default:
 		return nil, bcode.ErrInvalidGovernanceMode
 	}
@@ -40,6 +76,8 @@ func IsGovernanceModeValid(governanceMode string, dynamicClient dynamic.Interfac
 		return true
 	case model.GovernanceModeIstioServiceMesh:
 		return true
+	case model.GovernanceModeLinkerdServiceMesh, model.GovernanceModeCiliumServiceMesh:
+		return true
 	default:
 		found := findGovernanceMode(governanceMode, dynamicClient)
 		logrus.Debugf("find governance mode %s, found: %v", governanceMode, found)
@@ -66,3 +104,18 @@ func findGovernanceMode(governanceMode string, dynamicClient dynamic.Interface)
 	}
 	return true
 }
+
+// CheckControlPlane checks the control plane of the mesh the governanceMode relies on is installed.
+func CheckControlPlane(governanceMode string, kubeClient clientset.Interface) error {
+	switch governanceMode {
+	case model.GovernanceModeLinkerdServiceMesh, model.GovernanceModeCiliumServiceMesh:
+		handler, err := NewAppGoveranceModeHandler(governanceMode, kubeClient)
+		if err != nil {
+			return err
+		}
+		if !handler.IsInstalledControlPlane() {
+			return bcode.ErrControlPlaneNotInstall
+		}
+	}
+	return nil
+}
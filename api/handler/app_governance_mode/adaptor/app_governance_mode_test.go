@@ -0,0 +1,154 @@
+package adaptor
+
+import (
+	"testing"
+
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db/model"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testMeshComponent() *MeshComponent {
+	return &MeshComponent{
+		Namespace: "team",
+		Name:      "api",
+		ServiceID: "svc-api",
+		Ports:     []MeshPort{{Port: 8080}, {Port: 80, Outer: true}},
+		Consumers: []MeshConsumer{{ServiceID: "svc-web"}, {ServiceID: "svc-job", ServiceAccount: "job"}},
+	}
+}
+
+func findPolicy(policies []*unstructured.Unstructured, kind, name string) *unstructured.Unstructured {
+	for _, p := range policies {
+		if p.GetKind() == kind && p.GetName() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// capability_id: rainbond.app-governance.linkerd.control-plane
+func TestLinkerdControlPlaneDetection(t *testing.T) {
+	if err := CheckControlPlane(model.GovernanceModeLinkerdServiceMesh, fake.NewSimpleClientset()); err != bcode.ErrControlPlaneNotInstall {
+		t.Fatalf("expected control plane not install, got %v", err)
+	}
+	client := fake.NewSimpleClientset(&admissionv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "linkerd-proxy-injector-webhook-config"},
+	})
+	if err := CheckControlPlane(model.GovernanceModeLinkerdServiceMesh, client); err != nil {
+		t.Fatalf("expected installed control plane, got %v", err)
+	}
+	if err := CheckControlPlane(model.GovernanceModeKubernetesNativeService, nil); err != nil {
+		t.Fatalf("native mode needs no control plane, got %v", err)
+	}
+}
+
+// capability_id: rainbond.app-governance.cilium.control-plane
+func TestCiliumControlPlaneDetection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	if NewCiliumGoveranceMode(client).IsInstalledControlPlane() {
+		t.Fatal("cilium should not be detected without its resources")
+	}
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: "cilium.io/v2",
+		APIResources: []metav1.APIResource{{Name: "ciliumnetworkpolicies", Kind: "CiliumNetworkPolicy"}},
+	}}
+	if !NewCiliumGoveranceMode(client).IsInstalledControlPlane() {
+		t.Fatal("cilium should be detected by ciliumnetworkpolicies")
+	}
+}
+
+// capability_id: rainbond.app-governance.linkerd.authorization-policy
+func TestLinkerdBuildAuthorizationPolicies(t *testing.T) {
+	mode := NewLinkerdGoveranceMode(nil)
+	if mode.GetInjectAnnotations()["linkerd.io/inject"] != "enabled" {
+		t.Fatalf("unexpected inject annotations %v", mode.GetInjectAnnotations())
+	}
+	policies := mode.BuildAuthorizationPolicies(testMeshComponent())
+	if len(policies) != 7 {
+		t.Fatalf("expected 7 policies, got %d", len(policies))
+	}
+	if findPolicy(policies, "ServiceAccount", "rbd-mesh-svc-api") == nil {
+		t.Fatalf("expected a dedicated service account for the component")
+	}
+	server := findPolicy(policies, "Server", "api-8080")
+	if server == nil || server.GetNamespace() != "team" {
+		t.Fatalf("missing server for inner port: %v", server)
+	}
+	selector, _, _ := unstructured.NestedString(server.Object, "spec", "podSelector", "matchLabels", "service_id")
+	if selector != "svc-api" {
+		t.Fatalf("unexpected pod selector %q", selector)
+	}
+	inner := findPolicy(policies, "AuthorizationPolicy", "api-8080")
+	refs, _, _ := unstructured.NestedSlice(inner.Object, "spec", "requiredAuthenticationRefs")
+	if refs[0].(map[string]interface{})["kind"] != "MeshTLSAuthentication" {
+		t.Fatalf("inner port should require mesh tls, got %v", refs)
+	}
+	outer := findPolicy(policies, "AuthorizationPolicy", "api-80")
+	refs, _, _ = unstructured.NestedSlice(outer.Object, "spec", "requiredAuthenticationRefs")
+	if refs[0].(map[string]interface{})["kind"] != "NetworkAuthentication" {
+		t.Fatalf("outer port should allow all networks, got %v", refs)
+	}
+	authn := findPolicy(policies, "MeshTLSAuthentication", "api-consumers")
+	identities, _, _ := unstructured.NestedStringSlice(authn.Object, "spec", "identities")
+	want := []string{
+		"rbd-mesh-svc-web.team.serviceaccount.identity.linkerd.cluster.local",
+		"job.team.serviceaccount.identity.linkerd.cluster.local",
+	}
+	if len(identities) != len(want) || identities[0] != want[0] || identities[1] != want[1] {
+		t.Fatalf("unexpected identities %v", identities)
+	}
+	// a meshed pod that is not a consumer, with the default or its own service account, is denied
+	for _, other := range []string{
+		"default.team.serviceaccount.identity.linkerd.cluster.local",
+		"rbd-mesh-svc-other.team.serviceaccount.identity.linkerd.cluster.local",
+	} {
+		for _, identity := range identities {
+			if identity == other {
+				t.Fatalf("non consumer identity %s is authorized", other)
+			}
+		}
+	}
+
+	component := testMeshComponent()
+	component.ServiceAccount = "api"
+	if findPolicy(mode.BuildAuthorizationPolicies(component), "ServiceAccount", "rbd-mesh-svc-api") != nil {
+		t.Fatalf("component with its own service account should not get a dedicated one")
+	}
+}
+
+// capability_id: rainbond.app-governance.cilium.authorization-policy
+func TestCiliumBuildAuthorizationPolicies(t *testing.T) {
+	policies := NewCiliumGoveranceMode(nil).BuildAuthorizationPolicies(testMeshComponent())
+	if len(policies) != 1 || policies[0].GetKind() != "CiliumNetworkPolicy" {
+		t.Fatalf("expected one CiliumNetworkPolicy, got %v", policies)
+	}
+	ingress, _, _ := unstructured.NestedSlice(policies[0].Object, "spec", "ingress")
+	if len(ingress) != 2 {
+		t.Fatalf("expected consumer and outer rules, got %v", ingress)
+	}
+	endpoints := ingress[0].(map[string]interface{})["fromEndpoints"].([]interface{})
+	if len(endpoints) != 3 {
+		t.Fatalf("expected both consumers and the rbd namespace, got %v", endpoints)
+	}
+	namespace := endpoints[2].(map[string]interface{})["matchLabels"].(map[string]interface{})["k8s:io.kubernetes.pod.namespace"]
+	if namespace != "rbd-system" {
+		t.Fatalf("expected the rbd namespace allowed, got %v", endpoints[2])
+	}
+	if ingress[1].(map[string]interface{})["fromEntities"].([]interface{})[0] != "all" {
+		t.Fatalf("outer port should allow all entities, got %v", ingress[1])
+	}
+
+	component := testMeshComponent()
+	component.Ports = component.Ports[:1]
+	component.Consumers = nil
+	policies = NewCiliumGoveranceMode(nil).BuildAuthorizationPolicies(component)
+	ingress, _, _ = unstructured.NestedSlice(policies[0].Object, "spec", "ingress")
+	if len(ingress) != 1 || len(ingress[0].(map[string]interface{})["fromEndpoints"].([]interface{})) != 1 {
+		t.Fatalf("component without consumers should only allow the rbd namespace, got %v", ingress)
+	}
+}
@@ -1,5 +1,7 @@
 package adaptor
 
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
 type buildInServiceMeshMode struct{}
 
 // NewBuildInServiceMeshMode -
@@ -16,3 +18,13 @@ func (b *buildInServiceMeshMode) IsInstalledControlPlane() bool {
 func (b *buildInServiceMeshMode) GetInjectLabels() map[string]string {
 	return nil
 }
+
+// GetInjectAnnotations -
+func (b *buildInServiceMeshMode) GetInjectAnnotations() map[string]string {
+	return nil
+}
+
+// BuildAuthorizationPolicies -
+func (b *buildInServiceMeshMode) BuildAuthorizationPolicies(component *MeshComponent) []*unstructured.Unstructured {
+	return nil
+}
@@ -0,0 +1,99 @@
+package adaptor
+
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/util/constants"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+const ciliumAPIVersion = "cilium.io/v2"
+
+type ciliumServiceMeshMode struct {
+	kubeClient clientset.Interface
+}
+
+// NewCiliumGoveranceMode -
+func NewCiliumGoveranceMode(kubeClient clientset.Interface) AppGoveranceModeHandler {
+	return &ciliumServiceMeshMode{
+		kubeClient: kubeClient,
+	}
+}
+
+// IsInstalledControlPlane cilium has no injector, the CiliumNetworkPolicy resource is the sign of it
+func (c *ciliumServiceMeshMode) IsInstalledControlPlane() bool {
+	if c.kubeClient == nil {
+		return false
+	}
+	resources, err := c.kubeClient.Discovery().ServerResourcesForGroupVersion(ciliumAPIVersion)
+	if err != nil || resources == nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == "ciliumnetworkpolicies" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetInjectLabels -
+func (c *ciliumServiceMeshMode) GetInjectLabels() map[string]string {
+	return nil
+}
+
+// GetInjectAnnotations -
+func (c *ciliumServiceMeshMode) GetInjectAnnotations() map[string]string {
+	return nil
+}
+
+// BuildAuthorizationPolicies inner ports are only reachable from the consumers and the rainbond
+// system namespace, outer ports from everywhere.
+func (c *ciliumServiceMeshMode) BuildAuthorizationPolicies(component *MeshComponent) []*unstructured.Unstructured {
+	if component == nil || len(component.Ports) == 0 {
+		return nil
+	}
+	var innerPorts, outerPorts []interface{}
+	for _, port := range component.Ports {
+		p := map[string]interface{}{"port": fmt.Sprintf("%d", port.Port), "protocol": "ANY"}
+		if port.Outer {
+			outerPorts = append(outerPorts, p)
+		} else {
+			innerPorts = append(innerPorts, p)
+		}
+	}
+	var ingress []interface{}
+	if len(innerPorts) > 0 {
+		var endpoints []interface{}
+		for _, consumer := range component.Consumers {
+			endpoints = append(endpoints, map[string]interface{}{
+				"matchLabels": map[string]interface{}{"service_id": consumer.ServiceID},
+			})
+		}
+		// the rainbond system namespace keeps access for the gateway and monitoring
+		endpoints = append(endpoints, map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				"k8s:io.kubernetes.pod.namespace": util.GetenvDefault("RBD_NAMESPACE", constants.Namespace),
+			},
+		})
+		ingress = append(ingress, map[string]interface{}{
+			"fromEndpoints": endpoints,
+			"toPorts":       []interface{}{map[string]interface{}{"ports": innerPorts}},
+		})
+	}
+	if len(outerPorts) > 0 {
+		ingress = append(ingress, map[string]interface{}{
+			"fromEntities": []interface{}{"all"},
+			"toPorts":      []interface{}{map[string]interface{}{"ports": outerPorts}},
+		})
+	}
+	spec := map[string]interface{}{
+		"endpointSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"service_id": component.ServiceID},
+		},
+		"ingress": ingress,
+	}
+	return []*unstructured.Unstructured{newMeshObject(ciliumAPIVersion, "CiliumNetworkPolicy", component, component.Name, spec)}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	clientset "k8s.io/client-go/kubernetes"
 )
 
@@ -39,3 +40,13 @@ func (i *istioServiceMeshMode) IsInstalledControlPlane() bool {
 func (i *istioServiceMeshMode) GetInjectLabels() map[string]string {
 	return map[string]string{"sidecar.istio.io/inject": "true"}
 }
+
+// GetInjectAnnotations -
+func (i *istioServiceMeshMode) GetInjectAnnotations() map[string]string {
+	return nil
+}
+
+// BuildAuthorizationPolicies -
+func (i *istioServiceMeshMode) BuildAuthorizationPolicies(component *MeshComponent) []*unstructured.Unstructured {
+	return nil
+}
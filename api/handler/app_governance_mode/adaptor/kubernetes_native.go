@@ -1,5 +1,7 @@
 package adaptor
 
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
 type kubernetesNativeMode struct {
 }
 
@@ -17,3 +19,13 @@ func (k *kubernetesNativeMode) IsInstalledControlPlane() bool {
 func (k *kubernetesNativeMode) GetInjectLabels() map[string]string {
 	return nil
 }
+
+// GetInjectAnnotations -
+func (k *kubernetesNativeMode) GetInjectAnnotations() map[string]string {
+	return nil
+}
+
+// BuildAuthorizationPolicies -
+func (k *kubernetesNativeMode) BuildAuthorizationPolicies(component *MeshComponent) []*unstructured.Unstructured {
+	return nil
+}
@@ -0,0 +1,164 @@
+package adaptor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	linkerdServerAPIVersion         = "policy.linkerd.io/v1beta1"
+	linkerdAuthenticationAPIVersion = "policy.linkerd.io/v1alpha1"
+	linkerdIdentityDomain           = "cluster.local"
+)
+
+type linkerdServiceMeshMode struct {
+	kubeClient clientset.Interface
+}
+
+// NewLinkerdGoveranceMode -
+func NewLinkerdGoveranceMode(kubeClient clientset.Interface) AppGoveranceModeHandler {
+	return &linkerdServiceMeshMode{
+		kubeClient: kubeClient,
+	}
+}
+
+// IsInstalledControlPlane -
+func (l *linkerdServiceMeshMode) IsInstalledControlPlane() bool {
+	if l.kubeClient == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	name := os.Getenv("LINKERD_INJECTOR")
+	if name == "" {
+		name = "linkerd-proxy-injector-webhook-config"
+	}
+	_, err := l.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return true
+}
+
+// GetInjectLabels -
+func (l *linkerdServiceMeshMode) GetInjectLabels() map[string]string {
+	return nil
+}
+
+// GetInjectAnnotations linkerd only reads the inject switch from annotations
+func (l *linkerdServiceMeshMode) GetInjectAnnotations() map[string]string {
+	return map[string]string{"linkerd.io/inject": "enabled"}
+}
+
+// LinkerdServiceAccountName the dedicated service account of a component without one of its own.
+// linkerd identities are service accounts, sharing the default one would admit every meshed pod.
+func LinkerdServiceAccountName(serviceID string) string {
+	return "rbd-mesh-" + serviceID
+}
+
+// BuildAuthorizationPolicies every port becomes a Server, inner ports are only
+// authorized for the mTLS identities of the consumers, outer ports for any client.
+// A component without a service account gets its dedicated one.
+func (l *linkerdServiceMeshMode) BuildAuthorizationPolicies(component *MeshComponent) []*unstructured.Unstructured {
+	if component == nil {
+		return nil
+	}
+	var policies []*unstructured.Unstructured
+	if component.ServiceAccount == "" {
+		account := newMeshObject("v1", "ServiceAccount", component, LinkerdServiceAccountName(component.ServiceID), nil)
+		delete(account.Object, "spec")
+		policies = append(policies, account)
+	}
+	if len(component.Ports) == 0 {
+		return policies
+	}
+	meshTLSName := fmt.Sprintf("%s-consumers", component.Name)
+	networkName := fmt.Sprintf("%s-all-networks", component.Name)
+	var hasInner, hasOuter bool
+	for _, port := range component.Ports {
+		serverName := fmt.Sprintf("%s-%d", component.Name, port.Port)
+		policies = append(policies, newMeshObject(linkerdServerAPIVersion, "Server", component, serverName, map[string]interface{}{
+			"podSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"service_id": component.ServiceID},
+			},
+			"port":          int64(port.Port),
+			"proxyProtocol": "unknown",
+		}))
+		authn := map[string]interface{}{"group": "policy.linkerd.io", "kind": "MeshTLSAuthentication", "name": meshTLSName}
+		if port.Outer {
+			hasOuter = true
+			authn = map[string]interface{}{"group": "policy.linkerd.io", "kind": "NetworkAuthentication", "name": networkName}
+		} else {
+			hasInner = true
+		}
+		policies = append(policies, newMeshObject(linkerdAuthenticationAPIVersion, "AuthorizationPolicy", component, serverName, map[string]interface{}{
+			"targetRef": map[string]interface{}{
+				"group": "policy.linkerd.io",
+				"kind":  "Server",
+				"name":  serverName,
+			},
+			"requiredAuthenticationRefs": []interface{}{authn},
+		}))
+	}
+	if hasInner {
+		identities := make([]interface{}, 0, len(component.Consumers))
+		for _, consumer := range component.Consumers {
+			sa := consumer.ServiceAccount
+			if sa == "" {
+				sa = LinkerdServiceAccountName(consumer.ServiceID)
+			}
+			identity := fmt.Sprintf("%s.%s.serviceaccount.identity.linkerd.%s", sa, component.Namespace, linkerdIdentityDomain)
+			if !containsIdentity(identities, identity) {
+				identities = append(identities, identity)
+			}
+		}
+		// without consumers an empty list would be rejected, so deny all by an unused identity
+		if len(identities) == 0 {
+			identities = append(identities, fmt.Sprintf("%s-no-consumer.%s.serviceaccount.identity.linkerd.%s", component.Name, component.Namespace, linkerdIdentityDomain))
+		}
+		policies = append(policies, newMeshObject(linkerdAuthenticationAPIVersion, "MeshTLSAuthentication", component, meshTLSName, map[string]interface{}{
+			"identities": identities,
+		}))
+	}
+	if hasOuter {
+		policies = append(policies, newMeshObject(linkerdAuthenticationAPIVersion, "NetworkAuthentication", component, networkName, map[string]interface{}{
+			"networks": []interface{}{
+				map[string]interface{}{"cidr": "0.0.0.0/0"},
+				map[string]interface{}{"cidr": "::/0"},
+			},
+		}))
+	}
+	return policies
+}
+
+func containsIdentity(identities []interface{}, identity string) bool {
+	for _, i := range identities {
+		if i == identity {
+			return true
+		}
+	}
+	return false
+}
+
+func newMeshObject(apiVersion, kind string, component *MeshComponent, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": component.Namespace,
+			"labels": map[string]interface{}{
+				"service_id": component.ServiceID,
+				"creator":    "Rainbond",
+			},
+		},
+		"spec": spec,
+	}}
+	return obj
+}
@@ -0,0 +1,115 @@
+package adaptor
+
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/util/constants"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ZeroTrustPolicyName the name of the NetworkPolicy guarding the component
+func ZeroTrustPolicyName(name string) string {
+	return fmt.Sprintf("%s-zero-trust", name)
+}
+
+// NewMeshComponent loads the ports and the consumers of the component
+func NewMeshComponent(dbmanager db.Manager, namespace, name, serviceID string) (*MeshComponent, error) {
+	ports, err := dbmanager.TenantServicesPortDao().GetPortsByServiceID(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("get ports of component %s: %v", serviceID, err)
+	}
+	component := &MeshComponent{
+		Namespace: namespace,
+		Name:      name,
+		ServiceID: serviceID,
+	}
+	sa, err := dbmanager.ComponentK8sAttributeDao().GetByComponentIDAndName(serviceID, model.K8sAttributeNameServiceAccountName)
+	if err != nil {
+		return nil, fmt.Errorf("get service account of component %s: %v", serviceID, err)
+	}
+	if sa != nil {
+		component.ServiceAccount = sa.AttributeValue
+	}
+	for _, port := range ports {
+		component.Ports = append(component.Ports, MeshPort{
+			Port:  int32(port.ContainerPort),
+			Outer: util.BoolValue(port.IsOuterService),
+		})
+	}
+	if len(component.Ports) == 0 {
+		return component, nil
+	}
+	relations, err := dbmanager.TenantServiceRelationDao().GetTenantServiceRelationsByDependServiceID(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("get consumers of component %s: %v", serviceID, err)
+	}
+	for _, relation := range relations {
+		sa, err := dbmanager.ComponentK8sAttributeDao().GetByComponentIDAndName(relation.ServiceID, model.K8sAttributeNameServiceAccountName)
+		if err != nil {
+			return nil, fmt.Errorf("get service account of component %s: %v", relation.ServiceID, err)
+		}
+		consumer := MeshConsumer{ServiceID: relation.ServiceID}
+		if sa != nil {
+			consumer.ServiceAccount = sa.AttributeValue
+		}
+		component.Consumers = append(component.Consumers, consumer)
+	}
+	return component, nil
+}
+
+// BuildComponentPolicies the policies of the governance mode, plus the NetworkPolicy when zero trust is enabled
+func BuildComponentPolicies(governanceMode string, zeroTrust bool, component *MeshComponent) []*unstructured.Unstructured {
+	var policies []*unstructured.Unstructured
+	if mode, err := NewAppGoveranceModeHandler(governanceMode, nil); err == nil {
+		policies = append(policies, mode.BuildAuthorizationPolicies(component)...)
+	}
+	if zeroTrust {
+		policies = append(policies, BuildNetworkPolicy(component))
+	}
+	return policies
+}
+
+// BuildNetworkPolicy only the component itself and its declared consumers can reach the component,
+// the rainbond system namespace keeps access for the gateway, probes and monitoring.
+func BuildNetworkPolicy(component *MeshComponent) *unstructured.Unstructured {
+	sources := []interface{}{component.ServiceID}
+	for _, consumer := range component.Consumers {
+		if !containsIdentity(sources, consumer.ServiceID) {
+			sources = append(sources, consumer.ServiceID)
+		}
+	}
+	ingress := []interface{}{
+		map[string]interface{}{
+			"from": []interface{}{
+				map[string]interface{}{
+					"podSelector": map[string]interface{}{
+						"matchExpressions": []interface{}{
+							map[string]interface{}{"key": "service_id", "operator": "In", "values": sources},
+						},
+					},
+				},
+				map[string]interface{}{
+					"namespaceSelector": map[string]interface{}{
+						"matchLabels": map[string]interface{}{
+							"kubernetes.io/metadata.name": util.GetenvDefault("RBD_NAMESPACE", constants.Namespace),
+						},
+					},
+				},
+			},
+		},
+	}
+	policy := newMeshObject("networking.k8s.io/v1", "NetworkPolicy", component, ZeroTrustPolicyName(component.Name), map[string]interface{}{
+		"podSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"service_id": component.ServiceID},
+		},
+		"policyTypes": []interface{}{"Ingress"},
+		"ingress":     ingress,
+	})
+	labels := policy.GetLabels()
+	labels["zero_trust"] = "true"
+	policy.SetLabels(labels)
+	return policy
+}
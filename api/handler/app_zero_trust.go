@@ -0,0 +1,197 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goodrain/rainbond/api/handler/app_governance_mode/adaptor"
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var networkPolicyResource = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}
+
+// SyncZeroTrustPolicies 按应用当前的零信任开关和依赖关系同步所有组件的访问策略，关闭时删除 NetworkPolicy
+func (a *ApplicationAction) SyncZeroTrustPolicies(ctx context.Context, app *dbmodel.Application) error {
+	namespace, err := zeroTrustNamespace(app)
+	if err != nil {
+		return err
+	}
+	components, err := db.GetManager().TenantServiceDao().ListByAppID(app.AppID)
+	if err != nil {
+		return err
+	}
+	for _, component := range components {
+		if err := a.syncComponentPolicies(ctx, app, namespace, component); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncComponentZeroTrustPolicies 组件的依赖方变化后重新同步该组件的访问策略
+func (a *ApplicationAction) SyncComponentZeroTrustPolicies(ctx context.Context, serviceID string) error {
+	app, err := db.GetManager().ApplicationDao().GetByServiceID(serviceID)
+	if err != nil {
+		if err == bcode.ErrApplicationNotFound {
+			return nil
+		}
+		return err
+	}
+	namespace, err := zeroTrustNamespace(app)
+	if err != nil {
+		return err
+	}
+	component, err := db.GetManager().TenantServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		return err
+	}
+	return a.syncComponentPolicies(ctx, app, namespace, component)
+}
+
+// GetZeroTrustReport 列出应用各组件允许的访问方和被拒绝的组件间访问
+func (a *ApplicationAction) GetZeroTrustReport(app *dbmodel.Application) (*model.ZeroTrustReport, error) {
+	components, err := db.GetManager().TenantServiceDao().ListByAppID(app.AppID)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string, len(components))
+	for _, component := range components {
+		aliases[component.ServiceID] = component.ServiceAlias
+	}
+	aliasOf := func(serviceID string) string {
+		if alias, ok := aliases[serviceID]; ok {
+			return alias
+		}
+		return serviceID
+	}
+	report := &model.ZeroTrustReport{
+		AppID:           app.AppID,
+		Enabled:         app.ZeroTrust,
+		GovernanceMode:  app.GovernanceMode,
+		Components:      []*model.ZeroTrustComponent{},
+		BlockedFlows:    []*model.ZeroTrustFlow{},
+		WouldBlockFlows: []*model.ZeroTrustFlow{},
+	}
+	// 未开启零信任时没有访问被拒绝，只列出开启后将被拒绝的访问
+	flows := &report.BlockedFlows
+	if !app.ZeroTrust {
+		flows = &report.WouldBlockFlows
+	}
+	for _, target := range components {
+		name := zeroTrustWorkloadName(app, target)
+		mc, err := adaptor.NewMeshComponent(db.GetManager(), "", name, target.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+		item := &model.ZeroTrustComponent{
+			ServiceID:    target.ServiceID,
+			ServiceAlias: target.ServiceAlias,
+			PolicyName:   adaptor.ZeroTrustPolicyName(name),
+			Consumers:    []string{},
+			PublicPorts:  []int32{},
+		}
+		allowed := map[string]bool{target.ServiceID: true}
+		for _, consumer := range mc.Consumers {
+			allowed[consumer.ServiceID] = true
+			item.Consumers = append(item.Consumers, aliasOf(consumer.ServiceID))
+		}
+		var ports []int32
+		for _, port := range mc.Ports {
+			if port.Outer {
+				item.PublicPorts = append(item.PublicPorts, port.Port)
+			}
+			ports = append(ports, port.Port)
+		}
+		report.Components = append(report.Components, item)
+		if len(ports) == 0 {
+			continue
+		}
+		for _, source := range components {
+			if allowed[source.ServiceID] {
+				continue
+			}
+			*flows = append(*flows, &model.ZeroTrustFlow{
+				SourceServiceID:    source.ServiceID,
+				SourceServiceAlias: source.ServiceAlias,
+				TargetServiceID:    target.ServiceID,
+				TargetServiceAlias: target.ServiceAlias,
+				Ports:              ports,
+			})
+		}
+	}
+	return report, nil
+}
+
+func (a *ApplicationAction) syncComponentPolicies(ctx context.Context, app *dbmodel.Application, namespace string, component *dbmodel.TenantServices) error {
+	name := zeroTrustWorkloadName(app, component)
+	mc, err := adaptor.NewMeshComponent(db.GetManager(), namespace, name, component.ServiceID)
+	if err != nil {
+		return err
+	}
+	for _, policy := range adaptor.BuildComponentPolicies(app.GovernanceMode, app.ZeroTrust, mc) {
+		if err := a.applyPolicy(ctx, policy); err != nil {
+			return fmt.Errorf("apply %s %s: %v", policy.GetKind(), policy.GetName(), err)
+		}
+	}
+	if !app.ZeroTrust {
+		err := a.dynamicClient.Resource(networkPolicyResource).Namespace(namespace).Delete(ctx, adaptor.ZeroTrustPolicyName(name), metav1.DeleteOptions{})
+		if err != nil && !k8serror.IsNotFound(err) {
+			return fmt.Errorf("delete zero trust policy of %s: %v", component.ServiceAlias, err)
+		}
+	}
+	return nil
+}
+
+func (a *ApplicationAction) applyPolicy(ctx context.Context, policy *unstructured.Unstructured) error {
+	gvr, _ := meta.UnsafeGuessKindToResource(policy.GroupVersionKind())
+	client := a.dynamicClient.Resource(gvr).Namespace(policy.GetNamespace())
+	old, err := client.Get(ctx, policy.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8serror.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(ctx, policy, metav1.CreateOptions{})
+		return err
+	}
+	policy.SetResourceVersion(old.GetResourceVersion())
+	_, err = client.Update(ctx, policy, metav1.UpdateOptions{})
+	return err
+}
+
+func zeroTrustNamespace(app *dbmodel.Application) (string, error) {
+	tenant, err := db.GetManager().TenantDao().GetTenantByUUID(app.TenantID)
+	if err != nil {
+		return "", err
+	}
+	return tenant.Namespace, nil
+}
+
+func zeroTrustWorkloadName(app *dbmodel.Application, component *dbmodel.TenantServices) string {
+	return fmt.Sprintf("%s-%s", app.K8sApp, component.K8sComponentName)
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type zeroTrustTestManager struct {
+	db.Manager
+	services  []*dbmodel.TenantServices
+	ports     map[string][]*dbmodel.TenantServicesPort
+	relations []*dbmodel.TenantServiceRelation
+}
+
+func (m zeroTrustTestManager) TenantDao() dbdao.TenantDao {
+	return zeroTrustTestTenantDao{}
+}
+
+func (m zeroTrustTestManager) TenantServiceDao() dbdao.TenantServiceDao {
+	return zeroTrustTestServiceDao{services: m.services}
+}
+
+func (m zeroTrustTestManager) TenantServicesPortDao() dbdao.TenantServicesPortDao {
+	return zeroTrustTestPortDao{ports: m.ports}
+}
+
+func (m zeroTrustTestManager) TenantServiceRelationDao() dbdao.TenantServiceRelationDao {
+	return zeroTrustTestRelationDao{relations: m.relations}
+}
+
+func (m zeroTrustTestManager) ComponentK8sAttributeDao() dbdao.ComponentK8sAttributeDao {
+	return zeroTrustTestAttributeDao{}
+}
+
+type zeroTrustTestTenantDao struct {
+	dbdao.TenantDao
+}
+
+func (zeroTrustTestTenantDao) GetTenantByUUID(uuid string) (*dbmodel.Tenants, error) {
+	return &dbmodel.Tenants{UUID: uuid, Namespace: "team"}, nil
+}
+
+type zeroTrustTestServiceDao struct {
+	dbdao.TenantServiceDao
+	services []*dbmodel.TenantServices
+}
+
+func (d zeroTrustTestServiceDao) ListByAppID(string) ([]*dbmodel.TenantServices, error) {
+	return d.services, nil
+}
+
+type zeroTrustTestPortDao struct {
+	dbdao.TenantServicesPortDao
+	ports map[string][]*dbmodel.TenantServicesPort
+}
+
+func (d zeroTrustTestPortDao) GetPortsByServiceID(serviceID string) ([]*dbmodel.TenantServicesPort, error) {
+	return d.ports[serviceID], nil
+}
+
+type zeroTrustTestRelationDao struct {
+	dbdao.TenantServiceRelationDao
+	relations []*dbmodel.TenantServiceRelation
+}
+
+func (d zeroTrustTestRelationDao) GetTenantServiceRelationsByDependServiceID(dependServiceID string) ([]*dbmodel.TenantServiceRelation, error) {
+	var relations []*dbmodel.TenantServiceRelation
+	for _, relation := range d.relations {
+		if relation.DependServiceID == dependServiceID {
+			relations = append(relations, relation)
+		}
+	}
+	return relations, nil
+}
+
+type zeroTrustTestAttributeDao struct {
+	dbdao.ComponentK8sAttributeDao
+}
+
+func (zeroTrustTestAttributeDao) GetByComponentIDAndName(string, string) (*dbmodel.ComponentK8sAttributes, error) {
+	return nil, nil
+}
+
+func newZeroTrustTestManager() zeroTrustTestManager {
+	outer := true
+	return zeroTrustTestManager{
+		services: []*dbmodel.TenantServices{
+			{ServiceID: "api", ServiceAlias: "gr-api", K8sComponentName: "api"},
+			{ServiceID: "web", ServiceAlias: "gr-web", K8sComponentName: "web"},
+			{ServiceID: "job", ServiceAlias: "gr-job", K8sComponentName: "job"},
+		},
+		ports: map[string][]*dbmodel.TenantServicesPort{
+			"api": {{ContainerPort: 8080}},
+			"web": {{ContainerPort: 80, IsOuterService: &outer}},
+		},
+		relations: []*dbmodel.TenantServiceRelation{{ServiceID: "web", DependServiceID: "api"}},
+	}
+}
+
+// capability_id: rainbond.application.zero-trust.sync-network-policy
+func TestSyncZeroTrustPoliciesCreatesAndDeletesNetworkPolicies(t *testing.T) {
+	db.SetTestManager(newZeroTrustTestManager())
+	defer db.SetTestManager(nil)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		networkPolicyResource: "NetworkPolicyList",
+	})
+	action := &ApplicationAction{dynamicClient: dynamicClient}
+	app := &dbmodel.Application{AppID: "app", TenantID: "tenant", K8sApp: "demo", ZeroTrust: true,
+		GovernanceMode: dbmodel.GovernanceModeKubernetesNativeService}
+	if err := action.SyncZeroTrustPolicies(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	client := dynamicClient.Resource(networkPolicyResource).Namespace("team")
+	policy, err := client.Get(context.Background(), "demo-api-zero-trust", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected zero trust policy of api: %v", err)
+	}
+	ingress, _, _ := unstructured.NestedSlice(policy.Object, "spec", "ingress")
+	from := ingress[0].(map[string]interface{})["from"].([]interface{})
+	values := from[0].(map[string]interface{})["podSelector"].(map[string]interface{})["matchExpressions"].([]interface{})[0].(map[string]interface{})["values"].([]interface{})
+	if len(values) != 2 || values[0] != "api" || values[1] != "web" {
+		t.Fatalf("unexpected allowed sources %v", values)
+	}
+	web, err := client.Get(context.Background(), "demo-web-zero-trust", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected zero trust policy of web: %v", err)
+	}
+	ingress, _, _ = unstructured.NestedSlice(web.Object, "spec", "ingress")
+	for _, rule := range ingress {
+		if _, ok := rule.(map[string]interface{})["from"]; !ok {
+			t.Fatalf("public port of web should not be open to every pod, got %v", ingress)
+		}
+	}
+	// syncing again updates the existing policies
+	if err := action.SyncZeroTrustPolicies(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+
+	app.ZeroTrust = false
+	if err := action.SyncZeroTrustPolicies(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	list, err := client.List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 0 {
+		t.Fatalf("expected policies deleted after disabling zero trust, got %d", len(list.Items))
+	}
+}
+
+// capability_id: rainbond.application.zero-trust.report
+func TestGetZeroTrustReportListsBlockedFlows(t *testing.T) {
+	db.SetTestManager(newZeroTrustTestManager())
+	defer db.SetTestManager(nil)
+
+	report, err := (&ApplicationAction{}).GetZeroTrustReport(&dbmodel.Application{AppID: "app", K8sApp: "demo", ZeroTrust: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Enabled || len(report.Components) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	api := report.Components[0]
+	if api.PolicyName != "demo-api-zero-trust" || len(api.Consumers) != 1 || api.Consumers[0] != "gr-web" {
+		t.Fatalf("unexpected api entry %+v", api)
+	}
+	if len(report.Components[1].PublicPorts) != 1 {
+		t.Fatalf("web port 80 should be public, got %+v", report.Components[1])
+	}
+	// job -> api is blocked, the public port of web is only open to the gateway, not to api and job
+	if len(report.BlockedFlows) != 3 {
+		t.Fatalf("expected three blocked flows, got %d", len(report.BlockedFlows))
+	}
+	flow := report.BlockedFlows[0]
+	if flow.SourceServiceAlias != "gr-job" || flow.TargetServiceAlias != "gr-api" || flow.Ports[0] != 8080 {
+		t.Fatalf("unexpected blocked flow %+v", flow)
+	}
+	for _, flow := range report.BlockedFlows[1:] {
+		if flow.TargetServiceAlias != "gr-web" || flow.Ports[0] != 80 {
+			t.Fatalf("unexpected blocked flow %+v", flow)
+		}
+	}
+	if len(report.WouldBlockFlows) != 0 {
+		t.Fatalf("expected no hypothetical flows with zero trust enabled, got %d", len(report.WouldBlockFlows))
+	}
+
+	report, err = (&ApplicationAction{}).GetZeroTrustReport(&dbmodel.Application{AppID: "app", K8sApp: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Enabled || len(report.BlockedFlows) != 0 || len(report.WouldBlockFlows) != 3 {
+		t.Fatalf("without zero trust nothing is blocked, got %d blocked and %d would block", len(report.BlockedFlows), len(report.WouldBlockFlows))
+	}
+}
@@ -83,6 +83,11 @@ type ApplicationHandler interface {
 	UpdateServiceMeshCR(app *dbmodel.Application, governance string) (content string, err error)
 	DeleteServiceMeshCR(app *dbmodel.Application) error
 	ChangeVolumes(app *dbmodel.Application) error
+	ListGrayReleases(ctx context.Context, app *dbmodel.Application, namespace string) ([]*model.GrayReleaseModeRet, error)
+	GrayReleaseAction(ctx context.Context, app *dbmodel.Application, namespace, rolloutName, action string) error
+	SyncZeroTrustPolicies(ctx context.Context, app *dbmodel.Application) error
+	SyncComponentZeroTrustPolicies(ctx context.Context, serviceID string) error
+	GetZeroTrustReport(app *dbmodel.Application) (*model.ZeroTrustReport, error)
 }
 
 // NewApplicationHandler creates a new Tenant Application Handler.
@@ -215,8 +220,14 @@ func (a *ApplicationAction) UpdateApp(ctx context.Context, app *dbmodel.Applicat
 		if !adaptor.IsGovernanceModeValid(req.GovernanceMode, a.dynamicClient) {
 			return nil, bcode.ErrInvalidGovernanceMode
 		}
+		if err := adaptor.CheckControlPlane(req.GovernanceMode, a.kubeClient); err != nil {
+			return nil, err
+		}
 		app.GovernanceMode = req.GovernanceMode
 	}
+	if req.ZeroTrust != nil {
+		app.ZeroTrust = *req.ZeroTrust
+	}
 	app.K8sApp = req.K8sApp
 
 	err := db.GetManager().DB().Transaction(func(tx *gorm.DB) error {
@@ -234,8 +245,16 @@ func (a *ApplicationAction) UpdateApp(ctx context.Context, app *dbmodel.Applicat
 
 		return nil
 	})
-
-	return app, err
+	if err != nil {
+		return app, err
+	}
+	// 零信任开关变化后立即同步 NetworkPolicy，关闭时需要删除已有策略
+	if req.ZeroTrust != nil {
+		if err := a.SyncZeroTrustPolicies(ctx, app); err != nil {
+			return app, err
+		}
+	}
+	return app, nil
 }
 
 func (a *ApplicationAction) generateServiceMeshObj(app *dbmodel.Application, governance string, team *dbmodel.Tenants) *unstructured.Unstructured {
@@ -1085,6 +1104,15 @@ func (a *ApplicationAction) ListGovernanceMode() ([]model.GovernanceMode, error)
 			Description: dbmodel.GovernanceModeKubernetesNativeServiceDesc,
 		},
 	}
+	meshModes := []model.GovernanceMode{
+		{Name: dbmodel.GovernanceModeLinkerdServiceMesh, Description: dbmodel.GovernanceModeLinkerdServiceMeshDesc},
+		{Name: dbmodel.GovernanceModeCiliumServiceMesh, Description: dbmodel.GovernanceModeCiliumServiceMeshDesc},
+	}
+	for _, mode := range meshModes {
+		if adaptor.CheckControlPlane(mode.Name, a.kubeClient) == nil {
+			governanceModes = append(governanceModes, mode)
+		}
+	}
 
 	var serviceMeshClassesResource = schema.GroupVersionResource{Group: "rainbond.io", Version: "v1alpha1", Resource: "servicemeshclasses"}
 	list, err := a.dynamicClient.Resource(serviceMeshClassesResource).List(context.Background(), metav1.ListOptions{})
@@ -1107,7 +1135,7 @@ func (a *ApplicationAction) CheckGovernanceMode(ctx context.Context, governanceM
 	if !adaptor.IsGovernanceModeValid(governanceMode, a.dynamicClient) {
 		return bcode.ErrInvalidGovernanceMode
 	}
-	return nil
+	return adaptor.CheckControlPlane(governanceMode, a.kubeClient)
 }
 
 // GetAndHandleOperatorManaged get operator managed component
@@ -0,0 +1,163 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/pkg/errors"
+)
+
+// The export formats of the audit logs
+const (
+	AuditLogFormatJSONLines = "jsonl"
+	AuditLogFormatCSV       = "csv"
+)
+
+// auditLogBatchSize 导出与校验时每次读取的记录数
+const auditLogBatchSize = 500
+
+var auditLogCSVHeader = []string{"id", "create_time", "operator", "token_name", "client_ip", "tenant_id", "tenant_name",
+	"service_id", "service_alias", "method", "path", "request_body", "status_code", "result", "prev_hash", "hash"}
+
+// AuditLogHandler 审计日志的查询、导出与校验
+type AuditLogHandler interface {
+	ListAuditLogs(query *dbmodel.AuditLogQuery, page, pageSize int) ([]*dbmodel.AuditLog, int64, error)
+	ExportAuditLogs(w io.Writer, format string, query *dbmodel.AuditLogQuery) error
+	VerifyAuditLogs() (*apimodel.AuditLogVerifyResult, error)
+}
+
+var defaultAuditLogHandler AuditLogHandler
+
+// NewAuditLogHandler creates a new audit log handler
+func NewAuditLogHandler() AuditLogHandler {
+	return &AuditLogAction{}
+}
+
+// GetAuditLogHandler get audit log handler
+func GetAuditLogHandler() AuditLogHandler {
+	return defaultAuditLogHandler
+}
+
+// AuditLogAction audit log action
+type AuditLogAction struct {
+	dbmanager db.Manager
+}
+
+func (a *AuditLogAction) getDBManager() db.Manager {
+	if a.dbmanager != nil {
+		return a.dbmanager
+	}
+	return db.GetManager()
+}
+
+// ListAuditLogs 分页查询审计日志，最新的在前
+func (a *AuditLogAction) ListAuditLogs(query *dbmodel.AuditLogQuery, page, pageSize int) ([]*dbmodel.AuditLog, int64, error) {
+	logs, total, err := a.getDBManager().AuditLogDao().List(query, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list audit logs")
+	}
+	return logs, total, nil
+}
+
+// ExportAuditLogs 按写入顺序导出审计日志，支持 JSON lines 与 CSV
+func (a *AuditLogAction) ExportAuditLogs(w io.Writer, format string, query *dbmodel.AuditLogQuery) error {
+	var write func(log *dbmodel.AuditLog) error
+	var flush func() error
+	switch format {
+	case AuditLogFormatJSONLines:
+		encoder := json.NewEncoder(w)
+		write = func(log *dbmodel.AuditLog) error {
+			return encoder.Encode(log)
+		}
+		flush = func() error { return nil }
+	case AuditLogFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(auditLogCSVHeader); err != nil {
+			return err
+		}
+		write = func(log *dbmodel.AuditLog) error {
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(log.ID), 10), log.CreatedAt.Format(time.RFC3339), log.Operator, log.TokenName,
+				log.ClientIP, log.TenantID, log.TenantName, log.ServiceID, log.ServiceAlias, log.Method, log.Path,
+				log.RequestBody, strconv.Itoa(log.StatusCode), log.Result, log.PrevHash, log.Hash,
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		return fmt.Errorf("unsupported audit log format %q", format)
+	}
+	var afterID uint
+	for {
+		logs, err := a.getDBManager().AuditLogDao().ListAfter(query, afterID, auditLogBatchSize)
+		if err != nil {
+			return errors.Wrap(err, "list audit logs")
+		}
+		for _, log := range logs {
+			if err := write(log); err != nil {
+				return err
+			}
+			afterID = log.ID
+		}
+		if len(logs) < auditLogBatchSize {
+			return flush()
+		}
+	}
+}
+
+// VerifyAuditLogs 校验审计日志的哈希链，返回第一条被篡改或缺失前序记录的日志
+func (a *AuditLogAction) VerifyAuditLogs() (*apimodel.AuditLogVerifyResult, error) {
+	res := &apimodel.AuditLogVerifyResult{Valid: true}
+	var afterID uint
+	for {
+		logs, err := a.getDBManager().AuditLogDao().ListAfter(nil, afterID, auditLogBatchSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "list audit logs")
+		}
+		for _, log := range logs {
+			res.Checked++
+			if log.PrevHash != res.LastHash {
+				res.Valid, res.BrokenID = false, log.ID
+				res.Message = "the previous record is modified or deleted"
+				return res, nil
+			}
+			if log.Hash != log.ComputeHash() {
+				res.Valid, res.BrokenID = false, log.ID
+				res.Message = "the record is modified"
+				return res, nil
+			}
+			res.LastHash = log.Hash
+			afterID = log.ID
+		}
+		if len(logs) < auditLogBatchSize {
+			return res, nil
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditLogTestManager struct {
+	db.Manager
+	auditLogDao dbdao.AuditLogDao
+}
+
+func (m auditLogTestManager) AuditLogDao() dbdao.AuditLogDao {
+	return m.auditLogDao
+}
+
+type auditLogTestDao struct {
+	dbdao.AuditLogDao
+	logs []*dbmodel.AuditLog
+}
+
+func (d *auditLogTestDao) Append(log *dbmodel.AuditLog) error {
+	if len(d.logs) > 0 {
+		log.PrevHash = d.logs[len(d.logs)-1].Hash
+	}
+	log.ID = uint(len(d.logs) + 1)
+	log.Hash = log.ComputeHash()
+	d.logs = append(d.logs, log)
+	return nil
+}
+
+func (d *auditLogTestDao) ListAfter(query *dbmodel.AuditLogQuery, afterID uint, limit int) ([]*dbmodel.AuditLog, error) {
+	var res []*dbmodel.AuditLog
+	for _, log := range d.logs {
+		if log.ID > afterID && len(res) < limit && (query == nil || query.TenantID == "" || query.TenantID == log.TenantID) {
+			res = append(res, log)
+		}
+	}
+	return res, nil
+}
+
+func newAuditLogTestAction(t *testing.T, count int) (*AuditLogAction, *auditLogTestDao) {
+	dao := &auditLogTestDao{}
+	for i := 0; i < count; i++ {
+		tenantID := "tenant-a"
+		if i%2 == 1 {
+			tenantID = "tenant-b"
+		}
+		require.NoError(t, dao.Append(&dbmodel.AuditLog{TenantID: tenantID, Method: "POST", Path: "/v2/tenants/demo/services", RequestBody: `{"a":"b,c"}`}))
+	}
+	return &AuditLogAction{dbmanager: auditLogTestManager{auditLogDao: dao}}, dao
+}
+
+// capability_id: rainbond.audit-log.verify-chain
+func TestVerifyAuditLogsDetectsTampering(t *testing.T) {
+	action, dao := newAuditLogTestAction(t, auditLogBatchSize+3)
+
+	res, err := action.VerifyAuditLogs()
+	require.NoError(t, err)
+	assert.True(t, res.Valid)
+	assert.Equal(t, auditLogBatchSize+3, res.Checked)
+	assert.Equal(t, dao.logs[len(dao.logs)-1].Hash, res.LastHash)
+
+	dao.logs[10].StatusCode = 500
+	res, err = action.VerifyAuditLogs()
+	require.NoError(t, err)
+	assert.False(t, res.Valid)
+	assert.Equal(t, dao.logs[10].ID, res.BrokenID)
+
+	dao.logs[10].StatusCode = 0
+	deleted := dao.logs[20]
+	dao.logs = append(dao.logs[:20], dao.logs[21:]...)
+	res, err = action.VerifyAuditLogs()
+	require.NoError(t, err)
+	assert.False(t, res.Valid)
+	assert.Equal(t, deleted.ID+1, res.BrokenID)
+}
+
+// capability_id: rainbond.audit-log.export
+func TestExportAuditLogs(t *testing.T) {
+	action, _ := newAuditLogTestAction(t, 4)
+
+	var jsonl bytes.Buffer
+	require.NoError(t, action.ExportAuditLogs(&jsonl, AuditLogFormatJSONLines, &dbmodel.AuditLogQuery{TenantID: "tenant-b"}))
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"tenant_id":"tenant-b"`)
+
+	var out bytes.Buffer
+	require.NoError(t, action.ExportAuditLogs(&out, AuditLogFormatCSV, nil))
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, auditLogCSVHeader, records[0])
+	assert.Equal(t, `{"a":"b,c"}`, records[1][11])
+
+	assert.Error(t, action.ExportAuditLogs(&out, "xml", nil))
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/mq/client"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// BuildCacheHandler 组件源码构建缓存的查询、失效与预热
+type BuildCacheHandler interface {
+	GetBuildCache(serviceID string) (*model.BuildCacheStatus, error)
+	InvalidateBuildCache(tenantID, serviceID, eventID string) error
+	WarmBuildCache(tenantID, serviceID, eventID string, req *model.WarmBuildCacheReq) error
+}
+
+var defaultBuildCacheHandler BuildCacheHandler
+
+// NewBuildCacheHandler creates a new build cache handler
+func NewBuildCacheHandler(mqClient client.MQClient) BuildCacheHandler {
+	return &BuildCacheAction{mqClient: mqClient}
+}
+
+// GetBuildCacheHandler get build cache handler
+func GetBuildCacheHandler() BuildCacheHandler {
+	return defaultBuildCacheHandler
+}
+
+// BuildCacheAction build cache action
+type BuildCacheAction struct {
+	dbmanager db.Manager
+	mqClient  client.MQClient
+}
+
+func (b *BuildCacheAction) getDBManager() db.Manager {
+	if b.dbmanager != nil {
+		return b.dbmanager
+	}
+	return db.GetManager()
+}
+
+// GetBuildCache 查询组件构建缓存的大小与命中情况，组件未构建过时返回空的缓存
+func (b *BuildCacheAction) GetBuildCache(serviceID string) (*model.BuildCacheStatus, error) {
+	status := &model.BuildCacheStatus{ServiceID: serviceID}
+	cache, err := b.getDBManager().BuildCacheDao().GetByServiceID(serviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return status, nil
+		}
+		return nil, errors.Wrap(err, "get build cache")
+	}
+	status.SizeBytes = cache.SizeBytes
+	status.HitCount = cache.HitCount
+	status.MissCount = cache.MissCount
+	if lookups := cache.HitCount + cache.MissCount; lookups > 0 {
+		status.HitRate = float64(cache.HitCount) / float64(lookups)
+	}
+	if !cache.LastUsedTime.IsZero() {
+		status.LastUsedTime = &cache.LastUsedTime
+	}
+	return status, nil
+}
+
+// InvalidateBuildCache 清除组件的构建缓存，缓存位于 builder 的缓存卷，由 builder 异步执行
+func (b *BuildCacheAction) InvalidateBuildCache(tenantID, serviceID, eventID string) error {
+	return b.sendBuildCacheTask(map[string]interface{}{
+		"event_id":   eventID,
+		"action":     "invalidate",
+		"tenant_id":  tenantID,
+		"service_id": serviceID,
+	})
+}
+
+// WarmBuildCache 使用同一团队下另一个组件的构建缓存预热组件的缓存
+func (b *BuildCacheAction) WarmBuildCache(tenantID, serviceID, eventID string, req *model.WarmBuildCacheReq) error {
+	if req.SourceServiceID == serviceID {
+		return bcode.ErrBuildCacheSameSource
+	}
+	source, err := b.getDBManager().TenantServiceDao().GetServiceByID(req.SourceServiceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return bcode.ErrBuildCacheSourceNotFound
+		}
+		return errors.Wrap(err, "get source component")
+	}
+	if source.TenantID != tenantID {
+		return bcode.ErrBuildCacheSourceNotFound
+	}
+	return b.sendBuildCacheTask(map[string]interface{}{
+		"event_id":          eventID,
+		"action":            "warm",
+		"tenant_id":         tenantID,
+		"service_id":        serviceID,
+		"source_tenant_id":  source.TenantID,
+		"source_service_id": source.ServiceID,
+	})
+}
+
+func (b *BuildCacheAction) sendBuildCacheTask(body map[string]interface{}) error {
+	err := b.mqClient.SendBuilderTopic(client.TaskStruct{
+		Topic:    client.BuilderTopic,
+		TaskType: "build_cache",
+		TaskBody: body,
+	})
+	return errors.Wrap(err, "send build cache task")
+}
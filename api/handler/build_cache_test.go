@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+type buildCacheTestManager struct {
+	db.Manager
+	buildCacheDao dbdao.BuildCacheDao
+	serviceDao    dbdao.TenantServiceDao
+}
+
+func (m buildCacheTestManager) BuildCacheDao() dbdao.BuildCacheDao {
+	return m.buildCacheDao
+}
+
+func (m buildCacheTestManager) TenantServiceDao() dbdao.TenantServiceDao {
+	return m.serviceDao
+}
+
+type buildCacheTestDao struct {
+	dbdao.BuildCacheDao
+	caches map[string]*dbmodel.BuildCache
+}
+
+func (d *buildCacheTestDao) GetByServiceID(serviceID string) (*dbmodel.BuildCache, error) {
+	if cache, ok := d.caches[serviceID]; ok {
+		return cache, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type buildCacheTestServiceDao struct {
+	dbdao.TenantServiceDao
+	services map[string]*dbmodel.TenantServices
+}
+
+func (d *buildCacheTestServiceDao) GetServiceByID(serviceID string) (*dbmodel.TenantServices, error) {
+	if service, ok := d.services[serviceID]; ok {
+		return service, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func newBuildCacheTestAction() (*BuildCacheAction, *recordingMQClient) {
+	usedAt := time.Unix(1700000000, 0)
+	mq := &recordingMQClient{}
+	return &BuildCacheAction{
+		mqClient: mq,
+		dbmanager: buildCacheTestManager{
+			buildCacheDao: &buildCacheTestDao{caches: map[string]*dbmodel.BuildCache{
+				"svc": {ServiceID: "svc", SizeBytes: 4096, HitCount: 3, MissCount: 1, LastUsedTime: usedAt},
+			}},
+			serviceDao: &buildCacheTestServiceDao{services: map[string]*dbmodel.TenantServices{
+				"template": {TenantID: "tenant", ServiceID: "template"},
+				"other":    {TenantID: "other-tenant", ServiceID: "other"},
+			}},
+		},
+	}, mq
+}
+
+// capability_id: rainbond.builder.build-cache-hit-report
+func TestGetBuildCache(t *testing.T) {
+	action, _ := newBuildCacheTestAction()
+	status, err := action.GetBuildCache("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SizeBytes != 4096 || status.HitRate != 0.75 || status.LastUsedTime == nil {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	status, err = action.GetBuildCache("never-built")
+	if err != nil || status.SizeBytes != 0 || status.LastUsedTime != nil {
+		t.Fatalf("unexpected status of the component never built: %+v %v", status, err)
+	}
+}
+
+// capability_id: rainbond.builder.build-cache-invalidate
+func TestInvalidateAndWarmBuildCacheSendBuilderTask(t *testing.T) {
+	action, mq := newBuildCacheTestAction()
+	if err := action.InvalidateBuildCache("tenant", "svc", "event-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := action.WarmBuildCache("tenant", "svc", "event-2", &model.WarmBuildCacheReq{SourceServiceID: "template"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mq.tasks) != 2 {
+		t.Fatalf("expect 2 builder tasks, got %d", len(mq.tasks))
+	}
+	invalidate := mq.tasks[0].TaskBody.(map[string]interface{})
+	if mq.tasks[0].TaskType != "build_cache" || invalidate["action"] != "invalidate" || invalidate["event_id"] != "event-1" {
+		t.Fatalf("unexpected invalidate task %+v", mq.tasks[0])
+	}
+	warm := mq.tasks[1].TaskBody.(map[string]interface{})
+	if warm["action"] != "warm" || warm["source_service_id"] != "template" || warm["source_tenant_id"] != "tenant" {
+		t.Fatalf("unexpected warm task %+v", mq.tasks[1])
+	}
+
+	for source, want := range map[string]error{
+		"svc":     bcode.ErrBuildCacheSameSource,
+		"other":   bcode.ErrBuildCacheSourceNotFound,
+		"missing": bcode.ErrBuildCacheSourceNotFound,
+	} {
+		if err := action.WarmBuildCache("tenant", "svc", "event", &model.WarmBuildCacheReq{SourceServiceID: source}); err != want {
+			t.Fatalf("warm from %s: expect %v, got %v", source, want, err)
+		}
+	}
+	if len(mq.tasks) != 2 {
+		t.Fatal("rejected warm requests must not send builder tasks")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"compress/zlib"
 	"fmt"
 	eventdb "github.com/goodrain/rainbond/api/eventlog/db"
+	eventstore "github.com/goodrain/rainbond/api/eventlog/store"
 	"github.com/goodrain/rainbond/config/configs"
 	"io"
 	"io/ioutil"
@@ -38,14 +39,16 @@ import (
 
 // LogAction  log action struct
 type LogAction struct {
-	eventdb *eventdb.EventFilePlugin
+	eventdb  *eventdb.EventFilePlugin
+	searcher *eventstore.EventLogSearcher
 }
 
 // CreateLogManager get log manager
 func CreateLogManager() *LogAction {
 	config := configs.Default()
 	return &LogAction{
-		eventdb: eventdb.NewEventFilePlugin(config.LogConfig.LogPath),
+		eventdb:  eventdb.NewEventFilePlugin(config.LogConfig.LogPath),
+		searcher: eventstore.NewEventLogSearcher(path.Join(config.LogConfig.LogPath, "eventlog"), nil),
 	}
 }
 
@@ -19,6 +19,7 @@
 package handler
 
 import (
+	eventstore "github.com/goodrain/rainbond/api/eventlog/store"
 	"github.com/goodrain/rainbond/api/model"
 	apimodel "github.com/goodrain/rainbond/api/model"
 	dbmodel "github.com/goodrain/rainbond/db/model"
@@ -32,4 +33,5 @@ type EventHandler interface {
 	GetLogFile(serviceAlias, fileName string) (string, string, error)
 	GetEvents(target, targetID string, page, size int) ([]*dbmodel.ServiceEvent, int, error)
 	GetMyTeamsEvents(target string, targetIDs []string, page, size int) ([]*dbmodel.EventAndBuild, error)
+	SearchEventLogs(req *apimodel.EventLogSearchReq) (*eventstore.EventLogSearchResult, error)
 }
@@ -0,0 +1,84 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	eventstore "github.com/goodrain/rainbond/api/eventlog/store"
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/db"
+)
+
+// maxSearchEvents 一次检索最多扫描的事件数，超出的更早的事件需要缩小时间范围检索
+const maxSearchEvents = 500
+
+// maxSearchPageSize 每页返回的日志条数上限
+const maxSearchPageSize = 200
+
+// SearchEventLogs searches the logs of the latest events of the tenant or the target.
+// Only the logs kept in the local event log store are searched.
+func (l *LogAction) SearchEventLogs(req *apimodel.EventLogSearchReq) (*eventstore.EventLogSearchResult, error) {
+	if req.TenantID == "" && (req.Target == "" || req.TargetID == "") {
+		return nil, fmt.Errorf("tenant_id or target and target_id is required")
+	}
+	query := &eventstore.EventLogQuery{
+		Since:   req.Since,
+		Until:   req.Until,
+		Keyword: req.Keyword,
+		Step:    req.Step,
+		Level:   req.Level,
+	}
+	if req.Regex != "" {
+		re, err := regexp.Compile(req.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+		query.Regex = re
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 50
+	}
+	if req.PageSize > maxSearchPageSize {
+		req.PageSize = maxSearchPageSize
+	}
+	query.Offset = (req.Page - 1) * req.PageSize
+	query.Limit = req.PageSize
+
+	var since, until string
+	if req.Since != 0 {
+		since = time.Unix(req.Since, 0).Format(time.RFC3339)
+	}
+	if req.Until != 0 {
+		until = time.Unix(req.Until, 0).Format(time.RFC3339)
+	}
+	events, err := db.GetManager().ServiceEventDao().ListEventsForLogSearch(req.TenantID, req.Target, req.TargetID, since, until, maxSearchEvents)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		query.EventIDs = append(query.EventIDs, event.EventID)
+	}
+	return l.searcher.Search(query)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	eventdb "github.com/goodrain/rainbond/api/eventlog/db"
+	eventstore "github.com/goodrain/rainbond/api/eventlog/store"
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/sirupsen/logrus"
+)
+
+type eventLogSearchTestManager struct {
+	db.Manager
+	events *eventLogSearchTestEventDao
+}
+
+func (m eventLogSearchTestManager) ServiceEventDao() dbdao.EventDao { return m.events }
+
+type eventLogSearchTestEventDao struct {
+	dbdao.EventDao
+	since, until string
+}
+
+func (d *eventLogSearchTestEventDao) ListEventsForLogSearch(tenantID, target, targetID, since, until string, limit int) ([]*dbmodel.ServiceEvent, error) {
+	d.since, d.until = since, until
+	return []*dbmodel.ServiceEvent{{EventID: "build"}}, nil
+}
+
+// capability_id: rainbond.eventlog.search
+func TestSearchEventLogsClampsPageSizeAndFiltersEventsByTime(t *testing.T) {
+	dir := t.TempDir()
+	store, err := eventstore.NewJSONLinesFileStore(dir, logrus.WithField("test", "search"))
+	if err != nil {
+		t.Fatalf("create file store: %v", err)
+	}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 300; i++ {
+		msg := &eventdb.EventLogMessage{EventID: "build", Level: "info", Message: fmt.Sprintf("line %d", i), Time: base.Add(time.Duration(i) * time.Second).Format(time.RFC3339)}
+		if err := store.Append("build", msg); err != nil {
+			t.Fatalf("append message: %v", err)
+		}
+	}
+	events := &eventLogSearchTestEventDao{}
+	db.SetTestManager(eventLogSearchTestManager{events: events})
+	defer db.SetTestManager(nil)
+
+	action := &LogAction{searcher: eventstore.NewEventLogSearcher(dir, nil)}
+	result, err := action.SearchEventLogs(&apimodel.EventLogSearchReq{TenantID: "tenant-a", Since: base.Unix(), PageSize: 100000})
+	if err != nil {
+		t.Fatalf("SearchEventLogs returned error: %v", err)
+	}
+	if result.Total != 300 || len(result.Hits) != maxSearchPageSize {
+		t.Fatalf("expected %d of 300 hits, got %d of %d", maxSearchPageSize, len(result.Hits), result.Total)
+	}
+	if events.since != base.Local().Format(time.RFC3339) || events.until != "" {
+		t.Fatalf("expected since passed to the event query, got since=%q until=%q", events.since, events.until)
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goodrain/rainbond/api/model"
+	apiutil "github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// gitWebhookDeliveryLimit 查询 webhook 时返回的最近投递数
+const gitWebhookDeliveryLimit = 20
+
+// GitWebhookHandler 组件 git 推送 webhook 的配置与投递处理
+type GitWebhookHandler interface {
+	SetGitWebhook(tenantID, serviceID string, req *model.GitWebhookReq) (*model.GitWebhookStatus, error)
+	GetGitWebhook(serviceID string) (*model.GitWebhookStatus, error)
+	DeleteGitWebhook(serviceID string) error
+	HandleDelivery(provider string, header http.Header, body []byte) (*model.GitWebhookDeliveryResult, error)
+}
+
+var defaultGitWebhookHandler GitWebhookHandler
+
+// NewGitWebhookHandler creates a new git webhook handler
+func NewGitWebhookHandler(operation *OperationHandler) GitWebhookHandler {
+	return &GitWebhookAction{operation: operation}
+}
+
+// GetGitWebhookHandler get git webhook handler
+func GetGitWebhookHandler() GitWebhookHandler {
+	return defaultGitWebhookHandler
+}
+
+// GitWebhookAction git webhook action
+type GitWebhookAction struct {
+	operation *OperationHandler
+}
+
+// gitWebhookBuildTemplate 触发构建时使用的构建参数，分支与提交取自推送事件
+type gitWebhookBuildTemplate struct {
+	Action    string              `json:"action"`
+	Arch      string              `json:"arch"`
+	BuildENVs map[string]string   `json:"envs"`
+	CodeInfo  model.BuildCodeInfo `json:"code_info"`
+}
+
+// SetGitWebhook 创建或更新组件的 git 推送 webhook
+func (g *GitWebhookAction) SetGitWebhook(tenantID, serviceID string, req *model.GitWebhookReq) (*model.GitWebhookStatus, error) {
+	if !isGitProvider(req.Provider) {
+		return nil, bcode.ErrGitWebhookProvider
+	}
+	if req.Branch == "" && req.TagPattern == "" {
+		return nil, bcode.ErrGitWebhookRefRequired
+	}
+	if req.TagPattern != "" {
+		if _, err := path.Match(req.TagPattern, ""); err != nil {
+			return nil, bcode.ErrGitWebhookTagPattern
+		}
+	}
+	if req.CodeInfo.RepoURL == "" {
+		return nil, bcode.NewBadRequest("the repo_url of the code_info is required")
+	}
+	service, err := db.GetManager().TenantServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get component")
+	}
+	if dbmodel.ServiceKind(service.Kind) == dbmodel.ServiceKindThirdParty {
+		return nil, bcode.ErrGitWebhookThirdParty
+	}
+
+	hook, err := db.GetManager().GitWebhookDao().GetByServiceID(serviceID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(err, "get git webhook")
+	}
+	create := hook == nil
+	if create {
+		hook = &dbmodel.GitWebhook{TenantID: tenantID, ServiceID: serviceID, Enabled: true}
+	}
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	}
+	if hook.Secret == "" {
+		return nil, bcode.ErrGitWebhookSecretRequired
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	action := req.Action
+	if action == "" {
+		action = "upgrade"
+	}
+	template, err := json.Marshal(gitWebhookBuildTemplate{
+		Action:    action,
+		Arch:      req.Arch,
+		BuildENVs: req.BuildENVs,
+		CodeInfo:  req.CodeInfo,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal build template")
+	}
+	hook.Provider = req.Provider
+	hook.RepoURL = req.CodeInfo.RepoURL
+	hook.RepoKey = gitRepoKey(req.CodeInfo.RepoURL)
+	hook.Branch = req.Branch
+	hook.TagPattern = req.TagPattern
+	hook.BuildTemplate = string(template)
+	if create {
+		err = db.GetManager().GitWebhookDao().AddModel(hook)
+	} else {
+		err = db.GetManager().GitWebhookDao().UpdateModel(hook)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "save git webhook")
+	}
+	return gitWebhookStatus(hook, nil), nil
+}
+
+// GetGitWebhook 查询组件的 git 推送 webhook 及最近的投递
+func (g *GitWebhookAction) GetGitWebhook(serviceID string) (*model.GitWebhookStatus, error) {
+	hook, err := db.GetManager().GitWebhookDao().GetByServiceID(serviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, bcode.ErrGitWebhookNotFound
+		}
+		return nil, errors.Wrap(err, "get git webhook")
+	}
+	deliveries, err := db.GetManager().GitWebhookDeliveryDao().ListByServiceID(serviceID, gitWebhookDeliveryLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "list git webhook deliveries")
+	}
+	return gitWebhookStatus(hook, deliveries), nil
+}
+
+// DeleteGitWebhook 删除组件的 git 推送 webhook
+func (g *GitWebhookAction) DeleteGitWebhook(serviceID string) error {
+	return errors.Wrap(db.GetManager().GitWebhookDao().DeleteByServiceID(serviceID), "delete git webhook")
+}
+
+// HandleDelivery 处理 git 服务的推送投递：校验签名，匹配订阅了该仓库分支或标签的组件并触发固定提交的源码构建。
+// 同一投递在同一组件上只处理一次，重复投递返回之前的处理结果。
+func (g *GitWebhookAction) HandleDelivery(provider string, header http.Header, body []byte) (*model.GitWebhookDeliveryResult, error) {
+	provider = strings.ToLower(provider)
+	if !isGitProvider(provider) {
+		return nil, bcode.ErrGitWebhookProvider
+	}
+	result := &model.GitWebhookDeliveryResult{
+		DeliveryID: gitWebhookDeliveryID(provider, header, body),
+		Builds:     []*model.GitWebhookDeliveryBuild{},
+	}
+	if kind := gitWebhookEventKind(provider, header); kind != "push" {
+		result.Message = fmt.Sprintf("ignore %s event", kind)
+		if kind == "ping" {
+			result.Message = "pong"
+		}
+		return result, nil
+	}
+	push, err := parseGitPushEvent(provider, body)
+	if err != nil {
+		return nil, err
+	}
+	result.Ref = push.Ref
+	result.CommitSHA = push.Commit
+
+	hooks, err := g.listRepoWebhooks(provider, push.RepoURLs)
+	if err != nil {
+		return nil, err
+	}
+	var verified []*dbmodel.GitWebhook
+	for _, hook := range hooks {
+		if verifyGitWebhookSignature(provider, hook.Secret, header, body) {
+			verified = append(verified, hook)
+		}
+	}
+	if len(hooks) > 0 && len(verified) == 0 {
+		return nil, bcode.ErrGitWebhookSignature
+	}
+	if len(verified) == 0 {
+		result.Message = "no component subscribes to the repository"
+		return result, nil
+	}
+	if push.Deleted {
+		result.Message = "ignore deleted ref"
+		return result, nil
+	}
+	for _, hook := range verified {
+		build, err := g.handleHookDelivery(provider, result.DeliveryID, hook, push)
+		if err != nil {
+			return nil, err
+		}
+		result.Builds = append(result.Builds, build)
+	}
+	return result, nil
+}
+
+func (g *GitWebhookAction) listRepoWebhooks(provider string, repoURLs []string) ([]*dbmodel.GitWebhook, error) {
+	var hooks []*dbmodel.GitWebhook
+	seenRepos := make(map[string]bool)
+	seenServices := make(map[string]bool)
+	for _, repoURL := range repoURLs {
+		key := gitRepoKey(repoURL)
+		if seenRepos[key] {
+			continue
+		}
+		seenRepos[key] = true
+		list, err := db.GetManager().GitWebhookDao().ListEnabledByRepo(provider, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "list git webhooks")
+		}
+		for _, hook := range list {
+			if !seenServices[hook.ServiceID] {
+				seenServices[hook.ServiceID] = true
+				hooks = append(hooks, hook)
+			}
+		}
+	}
+	return hooks, nil
+}
+
+func (g *GitWebhookAction) handleHookDelivery(provider, deliveryID string, hook *dbmodel.GitWebhook, push *gitPushEvent) (*model.GitWebhookDeliveryBuild, error) {
+	build := &model.GitWebhookDeliveryBuild{ServiceID: hook.ServiceID}
+	deliveryDao := db.GetManager().GitWebhookDeliveryDao()
+	existing, err := deliveryDao.GetDelivery(provider, deliveryID, hook.ServiceID)
+	if err == nil {
+		return duplicateDeliveryBuild(existing), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(err, "get git webhook delivery")
+	}
+
+	delivery := &dbmodel.GitWebhookDelivery{
+		Provider:   provider,
+		DeliveryID: deliveryID,
+		ServiceID:  hook.ServiceID,
+		TenantID:   hook.TenantID,
+		Ref:        push.Ref,
+		CommitSHA:  push.Commit,
+		Status:     dbmodel.GitWebhookDeliveryTriggered,
+	}
+	branch, matched := matchGitRef(push.Ref, hook.Branch, hook.TagPattern)
+	if !matched {
+		delivery.Status = dbmodel.GitWebhookDeliverySkipped
+		delivery.Message = fmt.Sprintf("%s does not match the branch or tag pattern of the webhook", push.Ref)
+	}
+	// a commit delivered again under another delivery id, e.g. pushed to a second matching
+	// branch or replayed by hand, is built only once
+	if matched && push.Commit != "" {
+		built, err := deliveryDao.GetTriggeredByCommit(hook.ServiceID, push.Commit)
+		if err == nil {
+			return duplicateDeliveryBuild(built), nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrap(err, "get git webhook delivery of the commit")
+		}
+	}
+	// the unique index of the delivery guards against concurrent redeliveries
+	if err := deliveryDao.AddModel(delivery); err != nil {
+		if existing, gerr := deliveryDao.GetDelivery(provider, deliveryID, hook.ServiceID); gerr == nil {
+			return duplicateDeliveryBuild(existing), nil
+		}
+		return nil, errors.Wrap(err, "save git webhook delivery")
+	}
+	build.Status = delivery.Status
+	build.Message = delivery.Message
+	if !matched {
+		return build, nil
+	}
+
+	if err := g.triggerBuild(provider, deliveryID, hook, push, branch, delivery); err != nil {
+		logrus.Errorf("git webhook delivery %s trigger build of component %s failure: %v", deliveryID, hook.ServiceID, err)
+		delivery.Status = dbmodel.GitWebhookDeliveryFailed
+		delivery.Message = err.Error()
+	}
+	if err := deliveryDao.UpdateModel(delivery); err != nil {
+		logrus.Errorf("update git webhook delivery %s of component %s failure: %v", deliveryID, hook.ServiceID, err)
+	}
+	build.Status = delivery.Status
+	build.EventID = delivery.EventID
+	build.Message = delivery.Message
+	return build, nil
+}
+
+// triggerBuild 创建构建事件并下发固定提交的源码构建，事件日志记录触发构建的投递
+func (g *GitWebhookAction) triggerBuild(provider, deliveryID string, hook *dbmodel.GitWebhook, push *gitPushEvent, branch string, delivery *dbmodel.GitWebhookDelivery) error {
+	var template gitWebhookBuildTemplate
+	if err := json.Unmarshal([]byte(hook.BuildTemplate), &template); err != nil {
+		return errors.Wrap(err, "unmarshal build template")
+	}
+	tenant, err := db.GetManager().TenantDao().GetTenantByUUID(hook.TenantID)
+	if err != nil {
+		return errors.Wrap(err, "get tenant")
+	}
+	reqBody, _ := json.Marshal(map[string]string{
+		"provider":    provider,
+		"delivery_id": deliveryID,
+		"ref":         push.Ref,
+		"commit":      push.Commit,
+		"pusher":      push.Pusher,
+	})
+	sEvent, err := apiutil.CreateEvent(dbmodel.TargetTypeService, "build-service", hook.ServiceID, hook.TenantID,
+		string(reqBody), "git-webhook", "", "", dbmodel.ASYNEVENTTYPE)
+	if err != nil {
+		return errors.Wrap(err, "create build event")
+	}
+	delivery.EventID = sEvent.EventID
+
+	logger := event.GetManager().GetLogger(sEvent.EventID)
+	defer event.GetManager().ReleaseLogger(logger)
+	logger.Info(fmt.Sprintf("Build triggered by %s push delivery %s: %s at %s by %s", provider, deliveryID, push.Ref, push.Commit, push.Pusher),
+		map[string]string{"step": "git-webhook", "status": "starting"})
+
+	template.CodeInfo.Branch = branch
+	template.CodeInfo.Commit = push.Commit
+	req := &model.ComponentBuildReq{
+		ComponentOpGeneralReq: model.ComponentOpGeneralReq{
+			EventID:   sEvent.EventID,
+			ServiceID: hook.ServiceID,
+		},
+		BuildENVs:  template.BuildENVs,
+		Kind:       model.FromCodeBuildKing,
+		Arch:       template.Arch,
+		Action:     template.Action,
+		Operator:   "git-webhook",
+		CodeInfo:   template.CodeInfo,
+		TenantName: tenant.Name,
+	}
+	if err := g.operation.build(req); err != nil {
+		logger.Error(fmt.Sprintf("Trigger build failure: %v", err), map[string]string{"step": "last", "status": "failure"})
+		return err
+	}
+	return nil
+}
+
+func duplicateDeliveryBuild(delivery *dbmodel.GitWebhookDelivery) *model.GitWebhookDeliveryBuild {
+	return &model.GitWebhookDeliveryBuild{
+		ServiceID: delivery.ServiceID,
+		Status:    delivery.Status,
+		EventID:   delivery.EventID,
+		Message:   delivery.Message,
+		Duplicate: true,
+	}
+}
+
+func gitWebhookStatus(hook *dbmodel.GitWebhook, deliveries []*dbmodel.GitWebhookDelivery) *model.GitWebhookStatus {
+	if deliveries == nil {
+		deliveries = []*dbmodel.GitWebhookDelivery{}
+	}
+	return &model.GitWebhookStatus{
+		ServiceID:  hook.ServiceID,
+		Provider:   hook.Provider,
+		RepoURL:    hook.RepoURL,
+		Branch:     hook.Branch,
+		TagPattern: hook.TagPattern,
+		Enabled:    hook.Enabled,
+		HookPath:   "/v2/git-webhooks/" + hook.Provider,
+		Deliveries: deliveries,
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/tidwall/gjson"
+)
+
+// git providers which deliver push webhooks
+const (
+	GitProviderGitHub    = "github"
+	GitProviderGitLab    = "gitlab"
+	GitProviderGitea     = "gitea"
+	GitProviderBitbucket = "bitbucket"
+)
+
+const zeroCommitSHA = "0000000000000000000000000000000000000000"
+
+func isGitProvider(provider string) bool {
+	switch provider {
+	case GitProviderGitHub, GitProviderGitLab, GitProviderGitea, GitProviderBitbucket:
+		return true
+	}
+	return false
+}
+
+// gitPushEvent the fields of a push event shared by all the providers
+type gitPushEvent struct {
+	Ref      string
+	Commit   string
+	Message  string
+	Pusher   string
+	RepoURLs []string
+	Deleted  bool
+}
+
+// gitWebhookEventKind tells push deliveries from pings and the other events
+func gitWebhookEventKind(provider string, header http.Header) string {
+	switch provider {
+	case GitProviderGitHub:
+		return strings.ToLower(header.Get("X-GitHub-Event"))
+	case GitProviderGitea:
+		return strings.ToLower(header.Get("X-Gitea-Event"))
+	case GitProviderGitLab:
+		switch header.Get("X-Gitlab-Event") {
+		case "Push Hook", "Tag Push Hook":
+			return "push"
+		}
+		return header.Get("X-Gitlab-Event")
+	case GitProviderBitbucket:
+		switch key := header.Get("X-Event-Key"); key {
+		case "repo:push", "repo:refs_changed":
+			return "push"
+		case "diagnostics:ping":
+			return "ping"
+		default:
+			return key
+		}
+	}
+	return ""
+}
+
+// gitWebhookDeliveryID the id the provider gives to the delivery, redeliveries keep the id.
+// The digest of the payload is used when the provider sends none.
+func gitWebhookDeliveryID(provider string, header http.Header, body []byte) string {
+	var id string
+	switch provider {
+	case GitProviderGitHub:
+		id = header.Get("X-GitHub-Delivery")
+	case GitProviderGitea:
+		id = header.Get("X-Gitea-Delivery")
+	case GitProviderGitLab:
+		id = header.Get("X-Gitlab-Event-UUID")
+	case GitProviderBitbucket:
+		id = header.Get("X-Request-UUID")
+		if id == "" {
+			id = header.Get("X-Request-Id")
+		}
+	}
+	if id = strings.TrimSpace(id); id != "" && len(id) <= 128 {
+		return id
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// verifyGitWebhookSignature checks the delivery is signed with the secret of the webhook
+func verifyGitWebhookSignature(provider, secret string, header http.Header, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+	switch provider {
+	case GitProviderGitLab:
+		token := header.Get("X-Gitlab-Token")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	case GitProviderGitHub:
+		return verifyHMACSHA256(secret, strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256="), body)
+	case GitProviderGitea:
+		signature := header.Get("X-Gitea-Signature")
+		if signature == "" {
+			signature = strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		}
+		return verifyHMACSHA256(secret, signature, body)
+	case GitProviderBitbucket:
+		return verifyHMACSHA256(secret, strings.TrimPrefix(header.Get("X-Hub-Signature"), "sha256="), body)
+	}
+	return false
+}
+
+func verifyHMACSHA256(secret, signature string, body []byte) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// parseGitPushEvent parses the push payload of the provider
+func parseGitPushEvent(provider string, body []byte) (*gitPushEvent, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, bcode.ErrGitWebhookPayload
+	}
+	payload := gjson.ParseBytes(body)
+	push := &gitPushEvent{}
+	switch provider {
+	case GitProviderGitHub, GitProviderGitea:
+		push.Ref = payload.Get("ref").String()
+		push.Commit = payload.Get("after").String()
+		push.Deleted = payload.Get("deleted").Bool()
+		push.Message = payload.Get("head_commit.message").String()
+		push.Pusher = firstNonEmpty(payload.Get("pusher.name").String(), payload.Get("pusher.login").String(), payload.Get("pusher.username").String())
+		push.RepoURLs = gjsonStrings(payload, "repository.clone_url", "repository.ssh_url", "repository.html_url")
+	case GitProviderGitLab:
+		push.Ref = payload.Get("ref").String()
+		push.Commit = firstNonEmpty(payload.Get("checkout_sha").String(), payload.Get("after").String())
+		push.Message = payload.Get(`commits.#(id=="` + push.Commit + `").message`).String()
+		push.Pusher = firstNonEmpty(payload.Get("user_username").String(), payload.Get("user_name").String())
+		push.RepoURLs = gjsonStrings(payload, "project.git_http_url", "project.git_ssh_url", "project.web_url", "repository.git_http_url", "repository.git_ssh_url")
+	case GitProviderBitbucket:
+		if change := payload.Get("push.changes.0"); change.Exists() {
+			// bitbucket cloud
+			if next := change.Get("new"); next.Exists() && next.Type != gjson.Null {
+				push.Commit = next.Get("target.hash").String()
+				push.Message = next.Get("target.message").String()
+				if next.Get("type").String() == "tag" {
+					push.Ref = "refs/tags/" + next.Get("name").String()
+				} else {
+					push.Ref = "refs/heads/" + next.Get("name").String()
+				}
+			} else {
+				push.Deleted = true
+			}
+			push.Pusher = firstNonEmpty(payload.Get("actor.nickname").String(), payload.Get("actor.display_name").String())
+			push.RepoURLs = gjsonStrings(payload, "repository.links.html.href")
+			if fullName := payload.Get("repository.full_name").String(); fullName != "" {
+				push.RepoURLs = append(push.RepoURLs, "https://bitbucket.org/"+fullName)
+			}
+		} else {
+			// bitbucket server
+			change := payload.Get("changes.0")
+			push.Ref = change.Get("ref.id").String()
+			push.Commit = change.Get("toHash").String()
+			push.Deleted = change.Get("type").String() == "DELETE"
+			push.Pusher = payload.Get("actor.name").String()
+			for _, clone := range payload.Get("repository.links.clone.#.href").Array() {
+				push.RepoURLs = append(push.RepoURLs, clone.String())
+			}
+		}
+	default:
+		return nil, bcode.ErrGitWebhookProvider
+	}
+	if push.Commit == zeroCommitSHA {
+		push.Deleted = true
+	}
+	if push.Ref == "" || (!push.Deleted && push.Commit == "") || len(push.RepoURLs) == 0 {
+		return nil, bcode.ErrGitWebhookPayload
+	}
+	return push, nil
+}
+
+// gitRepoKey reduces the http, ssh and scp like addresses of a repository to host/owner/repo,
+// so the address configured on the component matches the ones in the push payloads
+func gitRepoKey(repoURL string) string {
+	key := strings.ToLower(strings.TrimSpace(repoURL))
+	if i := strings.Index(key, "://"); i >= 0 {
+		key = key[i+3:]
+	} else if i := strings.Index(key, ":"); i >= 0 && !strings.Contains(key[:i], "/") {
+		// scp like address, git@host:owner/repo.git
+		key = key[:i] + "/" + key[i+1:]
+	}
+	key = strings.TrimSuffix(strings.TrimSuffix(key, "/"), ".git")
+	host, repoPath := key, ""
+	if i := strings.Index(key, "/"); i >= 0 {
+		host, repoPath = key[:i], key[i:]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return host + repoPath
+}
+
+// matchGitRef matches the pushed ref against the branch or the tag pattern of the webhook,
+// the returned branch is in the form the builder clones, tags are prefixed with tag:
+func matchGitRef(ref, branch, tagPattern string) (string, bool) {
+	if name := strings.TrimPrefix(ref, "refs/heads/"); name != ref {
+		return name, branch != "" && name == branch
+	}
+	if name := strings.TrimPrefix(ref, "refs/tags/"); name != ref && tagPattern != "" {
+		if ok, _ := path.Match(tagPattern, name); ok {
+			return "tag:" + name, true
+		}
+	}
+	return "", false
+}
+
+func gjsonStrings(result gjson.Result, paths ...string) []string {
+	var values []string
+	for _, p := range paths {
+		if value := result.Get(p).String(); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
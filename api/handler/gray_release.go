@@ -0,0 +1,82 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	apisixversioned "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/client/clientset/versioned"
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/pkg/component/k8s"
+	"github.com/goodrain/rainbond/worker/grayrelease"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/typed/apis/v1beta1"
+)
+
+// ListGrayReleases 列出应用下的灰度发布及其指标分析状态
+func (a *ApplicationAction) ListGrayReleases(ctx context.Context, app *dbmodel.Application, namespace string) ([]*model.GrayReleaseModeRet, error) {
+	rollouts, err := k8s.Default().KruiseClient.RolloutsV1alpha1().Rollouts(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app_id=%s,%s", app.AppID, grayrelease.LabelGrayRelease),
+	})
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]*model.GrayReleaseModeRet, 0, len(rollouts.Items))
+	for i := range rollouts.Items {
+		rets = append(rets, grayrelease.RolloutStatus(&rollouts.Items[i]))
+	}
+	return rets, nil
+}
+
+// GrayReleaseAction 手动确认、暂停、恢复或回滚灰度发布，蓝绿发布通过确认一次性切换流量
+func (a *ApplicationAction) GrayReleaseAction(ctx context.Context, app *dbmodel.Application, namespace, rolloutName, action string) error {
+	switch action {
+	case grayrelease.ActionPromote, grayrelease.ActionPause, grayrelease.ActionResume, grayrelease.ActionRollback:
+	default:
+		return bcode.NewBadRequest(fmt.Sprintf("unsupported gray release action %q", action))
+	}
+	component := k8s.Default()
+	rollout, err := component.KruiseClient.RolloutsV1alpha1().Rollouts(namespace).Get(ctx, rolloutName, metav1.GetOptions{})
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return bcode.ErrGrayReleaseNotFound
+		}
+		return err
+	}
+	if rollout.Labels["app_id"] != app.AppID {
+		return bcode.ErrGrayReleaseNotFound
+	}
+	var apisix apisixversioned.Interface
+	if component.ApiSixClient != nil {
+		apisix = component.ApiSixClient
+	}
+	var gateway gatewayclient.GatewayV1beta1Interface
+	if component.GatewayClient != nil {
+		gateway = component.GatewayClient
+	}
+	err = grayrelease.NewOperator(component.KruiseClient, component.Clientset, apisix, gateway).Do(ctx, namespace, rolloutName, action)
+	if err == grayrelease.ErrNoCanary {
+		return bcode.NewBadRequest(err.Error())
+	}
+	return err
+}
@@ -19,6 +19,7 @@
 package group
 
 import (
+	"errors"
 	"fmt"
 	"github.com/goodrain/rainbond/pkg/component/grpc"
 	"github.com/goodrain/rainbond/pkg/component/mq"
@@ -34,6 +35,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/builder/exector"
 	"github.com/goodrain/rainbond/db"
 	"github.com/goodrain/rainbond/event"
 	"github.com/goodrain/rainbond/worker/client"
@@ -59,9 +61,15 @@ type Backup struct {
 		SourceDir  string   `json:"source_dir"`
 		BackupID   string   `json:"backup_id,omitempty"`
 
-		Mode     string `json:"mode" validate:"mode|required|in:full-online,full-offline"`
-		Force    bool   `json:"force"`
-		S3Config struct {
+		Mode  string `json:"mode" validate:"mode|required|in:full-online,full-offline"`
+		Force bool   `json:"force"`
+		//BackupType in full,incremental, an incremental backup only captures the volume
+		//files changed since the last successful backup of the group app
+		BackupType     string `json:"backup_type"`
+		ParentBackupID string `json:"parent_backup_id,omitempty"`
+		Since          int64  `json:"since,omitempty"`
+		ScheduleID     string `json:"schedule_id,omitempty"`
+		S3Config       struct {
 			Provider   string `json:"provider"`
 			Endpoint   string `json:"endpoint"`
 			AccessKey  string `json:"access_key"`
@@ -71,6 +79,13 @@ type Backup struct {
 	}
 }
 
+// incrementalClockSkew is subtracted from the time of the parent backup, so that
+// the files changed around it are captured again rather than lost.
+var incrementalClockSkew = 5 * time.Minute
+
+// ErrBackupHasChildren the backup can not be deleted, some incremental backups are based on it
+var ErrBackupHasChildren = errors.New("the backup can not be deleted, some incremental backups are based on it")
+
 // BackupHandle group app backup handle
 type BackupHandle struct {
 	mqcli     mqclient.MQClient
@@ -92,11 +107,21 @@ func (h *BackupHandle) NewBackup(b Backup) (*dbmodel.AppBackup, *util.APIHandleE
 		Status:     "starting",
 		Version:    b.Body.Version,
 		BackupMode: b.Body.Mode,
+		ScheduleID: b.Body.ScheduleID,
+		ServiceIDs: strings.Join(b.Body.ServiceIDs, ","),
 	}
 	//check last backup task whether complete or version whether exist
 	if db.GetManager().AppBackupDao().CheckHistory(b.Body.GroupID, b.Body.Version) {
 		return nil, util.CreateAPIHandleError(400, fmt.Errorf("last backup task do not complete or have restore backup or version is exist"))
 	}
+	if err := h.setBackupParent(&b); err != nil {
+		return nil, err
+	}
+	appBackup.BackupType = b.Body.BackupType
+	appBackup.ParentBackupID = b.Body.ParentBackupID
+	if appBackup.IsIncremental() {
+		logger.Info(fmt.Sprintf("make an incremental backup based on backup %s", b.Body.ParentBackupID), map[string]string{"step": "back-api"})
+	}
 	//check all service exist
 	if alias, err := db.GetManager().TenantServiceDao().GetServiceAliasByIDs(b.Body.ServiceIDs); len(alias) != len(b.Body.ServiceIDs) || err != nil {
 		return nil, util.CreateAPIHandleError(400, fmt.Errorf("some services do not exist in need backup services"))
@@ -174,6 +199,14 @@ func (h *BackupHandle) DeleteBackup(backupID string) error {
 		return err
 	}
 
+	children, err := db.GetManager().AppBackupDao().ListChildBackups(backupID)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrBackupHasChildren
+	}
+
 	tx := db.GetManager().Begin()
 	defer db.GetManager().EnsureEndTransactionFunc()(tx)
 
@@ -193,6 +226,52 @@ func (h *BackupHandle) DeleteBackup(backupID string) error {
 	return tx.Commit().Error
 }
 
+// setBackupParent finds the parent of an incremental backup, which is the
+// latest successful backup covering all the services to back up. A full
+// backup is made instead if there is no such backup.
+func (h *BackupHandle) setBackupParent(b *Backup) *util.APIHandleError {
+	switch b.Body.BackupType {
+	case "", dbmodel.BackupTypeFull:
+		b.Body.BackupType = dbmodel.BackupTypeFull
+		b.Body.ParentBackupID = ""
+		return nil
+	case dbmodel.BackupTypeIncremental:
+	default:
+		return util.CreateAPIHandleError(400, fmt.Errorf("backup type %s is not supported", b.Body.BackupType))
+	}
+	parent, err := db.GetManager().AppBackupDao().GetLatestSuccessBackup(b.Body.GroupID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return util.CreateAPIHandleErrorFromDBError("get the latest backup", err)
+	}
+	if parent == nil || !coversServices(parent, b.Body.ServiceIDs) {
+		logrus.Infof("group app %s has no backup to base on, make a full backup", b.Body.GroupID)
+		b.Body.BackupType = dbmodel.BackupTypeFull
+		b.Body.ParentBackupID = ""
+		return nil
+	}
+	b.Body.ParentBackupID = parent.BackupID
+	// the files are captured by the change time on the volumes, leave a margin for the clock skew
+	b.Body.Since = parent.CreatedAt.Add(-incrementalClockSkew).Unix()
+	return nil
+}
+
+// coversServices returns whether all the services are backed up by the backup.
+func coversServices(backup *dbmodel.AppBackup, serviceIDs []string) bool {
+	if backup.ServiceIDs == "" {
+		return false
+	}
+	backedUp := make(map[string]bool)
+	for _, id := range strings.Split(backup.ServiceIDs, ",") {
+		backedUp[id] = true
+	}
+	for _, id := range serviceIDs {
+		if !backedUp[id] {
+			return false
+		}
+	}
+	return true
+}
+
 // GetBackupByGroupID get some backup info by group id
 func (h *BackupHandle) GetBackupByGroupID(groupID string) ([]*dbmodel.AppBackup, *util.APIHandleError) {
 	backups, err := db.GetManager().AppBackupDao().GetAppBackups(groupID)
@@ -378,6 +457,8 @@ type BackupRestore struct {
 		//RestoreMode(cdot) current datacenter and other tenant
 		//RestoreMode(od)     other datacenter
 		RestoreMode string `json:"restore_mode"`
+		//DryRun validates metadata, images and volume sizes without restoring to the tenant
+		DryRun bool `json:"dry_run"`
 
 		S3Config struct {
 			Provider   string `json:"provider"`
@@ -391,16 +472,18 @@ type BackupRestore struct {
 
 // RestoreResult RestoreResult
 type RestoreResult struct {
-	Status        string           `json:"status"`
-	Message       string           `json:"message"`
-	CreateTime    time.Time        `json:"create_time"`
-	ServiceChange map[string]*Info `json:"service_change"`
-	BackupID      string           `json:"backup_id"`
-	RestoreMode   string           `json:"restore_mode"`
-	EventID       string           `json:"event_id"`
-	RestoreID     string           `json:"restore_id"`
-	Metadata      string           `json:"metadata"`
-	CacheDir      string           `json:"cache_dir"`
+	Status        string                       `json:"status"`
+	Message       string                       `json:"message"`
+	CreateTime    time.Time                    `json:"create_time"`
+	ServiceChange map[string]*Info             `json:"service_change"`
+	BackupID      string                       `json:"backup_id"`
+	RestoreMode   string                       `json:"restore_mode"`
+	EventID       string                       `json:"event_id"`
+	RestoreID     string                       `json:"restore_id"`
+	Metadata      string                       `json:"metadata"`
+	CacheDir      string                       `json:"cache_dir"`
+	DryRun        bool                         `json:"dry_run"`
+	DryRunReport  *exector.RestoreDryRunReport `json:"dry_run_report,omitempty"`
 }
 
 // Info service cache info
@@ -432,6 +515,7 @@ func (h *BackupHandle) RestoreBackup(br BackupRestore) (*RestoreResult, *util.AP
 		"tenant_id":    br.Body.TenantID,
 		"restore_id":   restoreID,
 		"restore_mode": br.Body.RestoreMode,
+		"dry_run":      br.Body.DryRun,
 		"s3_config":    br.Body.S3Config,
 	}
 	err := h.mqcli.SendBuilderTopic(mqclient.TaskStruct{
@@ -450,6 +534,7 @@ func (h *BackupHandle) RestoreBackup(br BackupRestore) (*RestoreResult, *util.AP
 		EventID:     br.Body.EventID,
 		RestoreMode: br.Body.RestoreMode,
 		RestoreID:   restoreID,
+		DryRun:      br.Body.DryRun,
 	}
 	body, _ := ffjson.Marshal(rr)
 	err = db.GetManager().KeyValueDao().Put("/rainbond/backup_restore/"+restoreID, string(body))
@@ -476,7 +561,7 @@ func (h *BackupHandle) RestoreBackupResult(restoreID string) (*RestoreResult, *u
 	if err := ffjson.Unmarshal([]byte(res.V), &rr); err != nil {
 		return nil, util.CreateAPIHandleError(500, err)
 	}
-	if rr.Status == "success" {
+	if rr.Status == "success" && !rr.DryRun {
 		// Download console_apps_metadata.json from S3 (uploaded by rbd-chaos during restore)
 		// In distributed architecture, rbd-api cannot access rbd-chaos's cache dir, so use S3 as intermediary
 		storageCli := storage.Default().StorageCli
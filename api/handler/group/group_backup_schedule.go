@@ -0,0 +1,419 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package group
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/pkg/component/storage"
+	core_util "github.com/goodrain/rainbond/util"
+)
+
+// defaultFullInterval is the max number of incremental backups between two full
+// backups when the schedule does not set it.
+const defaultFullInterval = 6
+
+// BackupSchedule the cron schedule and the retention policy of group app backups
+type BackupSchedule struct {
+	ScheduleID string `json:"schedule_id"`
+	Body       struct {
+		GroupID    string   `json:"group_id" validate:"group_id|required"`
+		Schedule   string   `json:"schedule" validate:"schedule|required"`
+		Timezone   string   `json:"timezone"`
+		Mode       string   `json:"mode" validate:"mode|required|in:full-online,full-offline"`
+		ServiceIDs []string `json:"service_ids" validate:"service_ids|required"`
+		Metadata   string   `json:"metadata" validate:"metadata|required"`
+		Force      bool     `json:"force"`
+		BucketName string   `json:"bucket_name"`
+		//Incremental backups only capture the volume files changed since the last backup,
+		//a full backup is made after every FullInterval incremental backups.
+		Incremental  bool `json:"incremental"`
+		FullInterval int  `json:"full_interval"`
+		//KeepLast keeps the last n backups, KeepDaily and KeepWeekly keep the last
+		//backup of each of the last n days and weeks. The backups created by the
+		//schedule and kept by no rule are pruned, nothing is pruned without rules.
+		KeepLast   int   `json:"keep_last"`
+		KeepDaily  int   `json:"keep_daily"`
+		KeepWeekly int   `json:"keep_weekly"`
+		Enable     *bool `json:"enable"`
+	}
+}
+
+// DbModel return database model
+func (s *BackupSchedule) DbModel() (*dbmodel.AppBackupSchedule, error) {
+	schedule := &dbmodel.AppBackupSchedule{
+		ScheduleID:   s.ScheduleID,
+		GroupID:      s.Body.GroupID,
+		Schedule:     strings.TrimSpace(s.Body.Schedule),
+		Timezone:     s.Body.Timezone,
+		Mode:         s.Body.Mode,
+		ServiceIDs:   strings.Join(s.Body.ServiceIDs, ","),
+		Metadata:     s.Body.Metadata,
+		Force:        s.Body.Force,
+		BucketName:   s.Body.BucketName,
+		Incremental:  s.Body.Incremental,
+		FullInterval: s.Body.FullInterval,
+		KeepLast:     s.Body.KeepLast,
+		KeepDaily:    s.Body.KeepDaily,
+		KeepWeekly:   s.Body.KeepWeekly,
+		Enable:       s.Body.Enable == nil || *s.Body.Enable,
+	}
+	if _, err := schedule.Parse(); err != nil {
+		return nil, fmt.Errorf("invalid schedule %s: %v", s.Body.Schedule, err)
+	}
+	if schedule.FullInterval < 0 || schedule.KeepLast < 0 || schedule.KeepDaily < 0 || schedule.KeepWeekly < 0 {
+		return nil, fmt.Errorf("full interval and retention rules can not be negative")
+	}
+	return schedule, nil
+}
+
+// CreateBackupSchedule creates a backup schedule for the group app
+func (h *BackupHandle) CreateBackupSchedule(s BackupSchedule) (*dbmodel.AppBackupSchedule, *util.APIHandleError) {
+	s.ScheduleID = core_util.NewUUID()
+	schedule, err := s.DbModel()
+	if err != nil {
+		return nil, util.CreateAPIHandleError(400, err)
+	}
+	if alias, err := db.GetManager().TenantServiceDao().GetServiceAliasByIDs(s.Body.ServiceIDs); len(alias) != len(s.Body.ServiceIDs) || err != nil {
+		return nil, util.CreateAPIHandleError(400, fmt.Errorf("some services do not exist in need backup services"))
+	}
+	if err := db.GetManager().AppBackupScheduleDao().AddModel(schedule); err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("create backup schedule", err)
+	}
+	return schedule, nil
+}
+
+// UpdateBackupSchedule replaces the cron schedule and the retention policy of a backup schedule
+func (h *BackupHandle) UpdateBackupSchedule(s BackupSchedule) (*dbmodel.AppBackupSchedule, *util.APIHandleError) {
+	old, err := db.GetManager().AppBackupScheduleDao().GetBackupSchedule(s.ScheduleID)
+	if err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("get backup schedule", err)
+	}
+	schedule, err := s.DbModel()
+	if err != nil {
+		return nil, util.CreateAPIHandleError(400, err)
+	}
+	if schedule.GroupID != old.GroupID {
+		return nil, util.CreateAPIHandleError(400, fmt.Errorf("the group of a backup schedule can not be changed"))
+	}
+	schedule.Model = old.Model
+	schedule.LastRunTime = old.LastRunTime
+	if err := db.GetManager().AppBackupScheduleDao().UpdateModel(schedule); err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("update backup schedule", err)
+	}
+	return schedule, nil
+}
+
+// GetBackupSchedules lists the backup schedules of the group app
+func (h *BackupHandle) GetBackupSchedules(groupID string) ([]*dbmodel.AppBackupSchedule, *util.APIHandleError) {
+	schedules, err := db.GetManager().AppBackupScheduleDao().ListByGroupID(groupID)
+	if err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("list backup schedules", err)
+	}
+	return schedules, nil
+}
+
+// DeleteBackupSchedule deletes the backup schedule, the backups created by it are kept
+func (h *BackupHandle) DeleteBackupSchedule(scheduleID string) *util.APIHandleError {
+	if _, err := db.GetManager().AppBackupScheduleDao().GetBackupSchedule(scheduleID); err != nil {
+		return util.CreateAPIHandleErrorFromDBError("get backup schedule", err)
+	}
+	if err := db.GetManager().AppBackupScheduleDao().DeleteBackupSchedule(scheduleID); err != nil {
+		return util.CreateAPIHandleErrorFromDBError("delete backup schedule", err)
+	}
+	return nil
+}
+
+// StartBackupScheduler runs the backup schedules and prunes the expired backups
+// until ctx is done. Every api instance runs it, a schedule is only run by the
+// instance which claims it.
+func (h *BackupHandle) StartBackupScheduler(ctx context.Context) {
+	scheduler := &backupScheduler{
+		newBackup:       h.NewBackup,
+		removeArtifacts: removeBackupArtifacts,
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			scheduler.run(now)
+		}
+	}
+}
+
+type backupScheduler struct {
+	newBackup       func(b Backup) (*dbmodel.AppBackup, *util.APIHandleError)
+	removeArtifacts func(backup *dbmodel.AppBackup, bucketName string) error
+}
+
+func (s *backupScheduler) run(now time.Time) {
+	schedules, err := db.GetManager().AppBackupScheduleDao().ListEnableOnes()
+	if err != nil {
+		logrus.Errorf("list backup schedules: %v", err)
+		return
+	}
+	for _, schedule := range schedules {
+		fired, err := s.runSchedule(schedule, now)
+		if err != nil {
+			logrus.Errorf("run backup schedule %s of group app %s: %v", schedule.ScheduleID, schedule.GroupID, err)
+		}
+		if fired {
+			s.prune(schedule)
+		}
+	}
+}
+
+// runSchedule starts a backup if the schedule is due, only one backup is
+// started for the runs missed while no api instance is running.
+func (s *backupScheduler) runSchedule(schedule *dbmodel.AppBackupSchedule, now time.Time) (bool, error) {
+	cronSchedule, err := schedule.Parse()
+	if err != nil {
+		return false, err
+	}
+	last := schedule.CreatedAt
+	if schedule.LastRunTime != nil {
+		last = *schedule.LastRunTime
+	}
+	next := cronSchedule.Next(last)
+	if next.After(now) {
+		return false, nil
+	}
+	claimed, err := db.GetManager().AppBackupScheduleDao().ClaimRun(schedule.ScheduleID, schedule.LastRunTime, now.Truncate(time.Second))
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	var b Backup
+	b.Body.EventID = core_util.NewUUID()
+	b.Body.GroupID = schedule.GroupID
+	b.Body.Metadata = schedule.Metadata
+	b.Body.ServiceIDs = strings.Split(schedule.ServiceIDs, ",")
+	// the version is unique for every run of the schedule
+	b.Body.Version = fmt.Sprintf("%s-%.8s", next.Format("20060102150405"), schedule.ScheduleID)
+	b.Body.Mode = schedule.Mode
+	b.Body.Force = schedule.Force
+	b.Body.ScheduleID = schedule.ScheduleID
+	b.Body.S3Config.BucketName = schedule.BucketName
+	b.Body.BackupType = dbmodel.BackupTypeFull
+	if schedule.Incremental {
+		fullInterval := schedule.FullInterval
+		if fullInterval == 0 {
+			fullInterval = defaultFullInterval
+		}
+		depth, err := incrementalDepth(schedule.GroupID)
+		if err != nil {
+			return true, err
+		}
+		if depth < fullInterval {
+			b.Body.BackupType = dbmodel.BackupTypeIncremental
+		}
+	}
+	backup, apiErr := s.newBackup(b)
+	if apiErr != nil {
+		return true, apiErr
+	}
+	logrus.Infof("backup schedule %s starts %s backup %s of group app %s", schedule.ScheduleID, backup.BackupType, backup.BackupID, schedule.GroupID)
+	return true, nil
+}
+
+// incrementalDepth returns the number of the incremental backups since the
+// last full one, counted on the parents of the latest successful backup.
+func incrementalDepth(groupID string) (int, error) {
+	backup, err := db.GetManager().AppBackupDao().GetLatestSuccessBackup(groupID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var depth int
+	for backup.IsIncremental() && backup.ParentBackupID != "" {
+		depth++
+		parent, err := db.GetManager().AppBackupDao().GetAppBackup(backup.ParentBackupID)
+		if err != nil {
+			// the chain is broken, start a new one with a full backup
+			return depth, nil
+		}
+		backup = parent
+	}
+	return depth, nil
+}
+
+// prune deletes the backups created by the schedule which are kept by none of
+// the retention rules, the parents of the kept incremental backups are kept.
+func (s *backupScheduler) prune(schedule *dbmodel.AppBackupSchedule) {
+	if !schedule.HasRetention() {
+		return
+	}
+	backups, err := db.GetManager().AppBackupDao().ListByScheduleID(schedule.ScheduleID)
+	if err != nil {
+		logrus.Errorf("list backups of schedule %s: %v", schedule.ScheduleID, err)
+		return
+	}
+	var succeeded []*dbmodel.AppBackup
+	for _, backup := range backups {
+		if backup.Status == "success" {
+			succeeded = append(succeeded, backup)
+		}
+	}
+	kept := retainedBackups(succeeded, schedule)
+	expired, err := expiredBackups(backups, kept)
+	if err != nil {
+		logrus.Errorf("find expired backups of schedule %s: %v", schedule.ScheduleID, err)
+		return
+	}
+	for _, backup := range expired {
+		if err := s.removeArtifacts(backup, schedule.BucketName); err != nil {
+			// the record is kept to remove the artifacts next time
+			logrus.Warningf("remove artifacts of expired backup %s: %v", backup.BackupID, err)
+			continue
+		}
+		if err := db.GetManager().AppBackupDao().DeleteAppBackup(backup.BackupID); err != nil {
+			logrus.Warningf("delete expired backup %s: %v", backup.BackupID, err)
+			continue
+		}
+		logrus.Infof("pruned expired backup %s(%s) of group app %s", backup.BackupID, backup.Version, backup.GroupID)
+	}
+}
+
+// retainedBackups returns the ids of the backups kept by the retention rules of
+// the schedule, backups are the successful ones sorted from newest to oldest.
+func retainedBackups(backups []*dbmodel.AppBackup, schedule *dbmodel.AppBackupSchedule) map[string]bool {
+	kept := make(map[string]bool)
+	for i := 0; i < schedule.KeepLast && i < len(backups); i++ {
+		kept[backups[i].BackupID] = true
+	}
+	loc := schedule.Location()
+	keepPeriods := func(n int, period func(t time.Time) string) {
+		seen := make(map[string]bool)
+		for _, backup := range backups {
+			if len(seen) >= n {
+				return
+			}
+			key := period(backup.CreatedAt.In(loc))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept[backup.BackupID] = true
+		}
+	}
+	keepPeriods(schedule.KeepDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepPeriods(schedule.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+	return kept
+}
+
+// expiredBackups returns the finished backups which are not kept, and are not
+// the parents of a backup that stays.
+func expiredBackups(backups []*dbmodel.AppBackup, kept map[string]bool) ([]*dbmodel.AppBackup, error) {
+	candidates := make(map[string]*dbmodel.AppBackup)
+	for _, backup := range backups {
+		if kept[backup.BackupID] || (backup.Status != "success" && backup.Status != "failed") {
+			continue
+		}
+		candidates[backup.BackupID] = backup
+	}
+	byID := make(map[string]*dbmodel.AppBackup)
+	for _, backup := range backups {
+		byID[backup.BackupID] = backup
+	}
+	var stay func(backup *dbmodel.AppBackup)
+	stay = func(backup *dbmodel.AppBackup) {
+		for backup != nil && backup.ParentBackupID != "" {
+			delete(candidates, backup.ParentBackupID)
+			backup = byID[backup.ParentBackupID]
+		}
+	}
+	for _, backup := range backups {
+		if candidates[backup.BackupID] == nil {
+			stay(backup)
+		}
+	}
+	// the incremental backups not created by the schedule may be based on its backups
+	for changed := true; changed; {
+		changed = false
+		for id, backup := range candidates {
+			children, err := db.GetManager().AppBackupDao().ListChildBackups(id)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if candidates[child.BackupID] == nil {
+					delete(candidates, id)
+					stay(backup)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	var res []*dbmodel.AppBackup
+	// the children are removed before the parents
+	for _, backup := range backups {
+		if candidates[backup.BackupID] != nil {
+			res = append(res, backup)
+		}
+	}
+	return res, nil
+}
+
+// removeBackupArtifacts removes the package and the metadata of the backup from
+// the storage, and the local copy of an offline backup.
+func removeBackupArtifacts(backup *dbmodel.AppBackup, bucketName string) error {
+	if bucketName == "" {
+		bucketName = "grdata"
+	}
+	storageCli := storage.Default().StorageCli
+	if strings.HasSuffix(backup.SourceDir, ".zip") {
+		_, filename := filepath.Split(backup.SourceDir)
+		if err := storageCli.RemoveAll(fmt.Sprintf("/%s/backup/%s/%s", bucketName, backup.GroupID, filename)); err != nil {
+			return err
+		}
+	}
+	if backup.Version != "" {
+		if err := storageCli.RemoveAll(fmt.Sprintf("/%s/backup/%s/%s", bucketName, backup.GroupID, backup.Version)); err != nil {
+			return err
+		}
+	}
+	if backup.BackupMode == "full-offline" && backup.SourceDir != "" {
+		if err := os.RemoveAll(backup.SourceDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package group
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+)
+
+type backupTestManager struct {
+	db.Manager
+	backupDao   *backupTestDao
+	scheduleDao *scheduleTestDao
+}
+
+func (m backupTestManager) AppBackupDao() dbdao.AppBackupDao {
+	return m.backupDao
+}
+
+func (m backupTestManager) AppBackupScheduleDao() dbdao.AppBackupScheduleDao {
+	return m.scheduleDao
+}
+
+type backupTestDao struct {
+	dbdao.AppBackupDao
+	backups []*dbmodel.AppBackup
+	deleted []string
+}
+
+func (d *backupTestDao) GetAppBackup(backupID string) (*dbmodel.AppBackup, error) {
+	for _, backup := range d.backups {
+		if backup.BackupID == backupID {
+			return backup, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *backupTestDao) GetLatestSuccessBackup(groupID string) (*dbmodel.AppBackup, error) {
+	var latest *dbmodel.AppBackup
+	for _, backup := range d.backups {
+		if backup.GroupID == groupID && backup.Status == "success" && (latest == nil || backup.CreatedAt.After(latest.CreatedAt)) {
+			latest = backup
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (d *backupTestDao) ListByScheduleID(scheduleID string) ([]*dbmodel.AppBackup, error) {
+	var res []*dbmodel.AppBackup
+	for _, backup := range d.backups {
+		if backup.ScheduleID == scheduleID {
+			res = append(res, backup)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.After(res[j].CreatedAt) })
+	return res, nil
+}
+
+func (d *backupTestDao) ListChildBackups(backupID string) ([]*dbmodel.AppBackup, error) {
+	var res []*dbmodel.AppBackup
+	for _, backup := range d.backups {
+		if backup.ParentBackupID == backupID {
+			res = append(res, backup)
+		}
+	}
+	return res, nil
+}
+
+func (d *backupTestDao) DeleteAppBackup(backupID string) error {
+	d.deleted = append(d.deleted, backupID)
+	for i, backup := range d.backups {
+		if backup.BackupID == backupID {
+			d.backups = append(d.backups[:i], d.backups[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+type scheduleTestDao struct {
+	dbdao.AppBackupScheduleDao
+	schedules []*dbmodel.AppBackupSchedule
+}
+
+func (d *scheduleTestDao) ListEnableOnes() ([]*dbmodel.AppBackupSchedule, error) {
+	return d.schedules, nil
+}
+
+func (d *scheduleTestDao) ClaimRun(scheduleID string, lastRunTime *time.Time, runTime time.Time) (bool, error) {
+	for _, schedule := range d.schedules {
+		if schedule.ScheduleID != scheduleID {
+			continue
+		}
+		if (schedule.LastRunTime == nil) != (lastRunTime == nil) || (lastRunTime != nil && !schedule.LastRunTime.Equal(*lastRunTime)) {
+			return false, nil
+		}
+		schedule.LastRunTime = &runTime
+		return true, nil
+	}
+	return false, nil
+}
+
+func newTestBackup(id, parent string, backupType string, created time.Time) *dbmodel.AppBackup {
+	backup := &dbmodel.AppBackup{
+		BackupID:       id,
+		GroupID:        "g1",
+		Status:         "success",
+		Version:        id,
+		BackupType:     backupType,
+		ParentBackupID: parent,
+		ScheduleID:     "s1",
+	}
+	backup.CreatedAt = created
+	return backup
+}
+
+func backupIDs(backups []*dbmodel.AppBackup) []string {
+	var ids []string
+	for _, backup := range backups {
+		ids = append(ids, backup.BackupID)
+	}
+	return ids
+}
+
+// capability_id: rainbond.app-backup.schedule-retention
+func TestRetainedBackups(t *testing.T) {
+	base := time.Date(2024, 3, 4, 3, 0, 0, 0, time.UTC) // a monday
+	var backups []*dbmodel.AppBackup
+	// two backups a day for 15 days, newest first
+	for day := 14; day >= 0; day-- {
+		for _, hour := range []int{12, 0} {
+			created := base.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+			backups = append(backups, newTestBackup(created.Format("0102-15"), "", dbmodel.BackupTypeFull, created))
+		}
+	}
+	schedule := &dbmodel.AppBackupSchedule{KeepLast: 3, KeepDaily: 3, KeepWeekly: 2, Timezone: "UTC"}
+	kept := retainedBackups(backups, schedule)
+	var ids []string
+	for id := range kept {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	// last 3: 0318-15 0318-03 0317-15, daily: 0318-15 0317-15 0316-15,
+	// weekly: 0318-15 (week of 03-18) 0317-15 (week of 03-11)
+	want := []string{"0316-15", "0317-15", "0318-03", "0318-15"}
+	if len(ids) != len(want) {
+		t.Fatalf("expected kept backups %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected kept backups %v, got %v", want, ids)
+		}
+	}
+}
+
+// capability_id: rainbond.app-backup.schedule-retention
+func TestExpiredBackupsKeepParents(t *testing.T) {
+	now := time.Now()
+	full1 := newTestBackup("full1", "", dbmodel.BackupTypeFull, now.Add(-6*time.Hour))
+	incr1 := newTestBackup("incr1", "full1", dbmodel.BackupTypeIncremental, now.Add(-5*time.Hour))
+	full2 := newTestBackup("full2", "", dbmodel.BackupTypeFull, now.Add(-4*time.Hour))
+	incr2 := newTestBackup("incr2", "full2", dbmodel.BackupTypeIncremental, now.Add(-3*time.Hour))
+	incr3 := newTestBackup("incr3", "incr2", dbmodel.BackupTypeIncremental, now.Add(-2*time.Hour))
+	running := newTestBackup("running", "incr3", dbmodel.BackupTypeIncremental, now.Add(-time.Hour))
+	running.Status = "starting"
+	failed := newTestBackup("failed", "", dbmodel.BackupTypeFull, now.Add(-7*time.Hour))
+	failed.Status = "failed"
+	// a manual incremental backup based on full1
+	manual := newTestBackup("manual", "full1", dbmodel.BackupTypeIncremental, now.Add(-30*time.Minute))
+	manual.ScheduleID = ""
+
+	dao := &backupTestDao{backups: []*dbmodel.AppBackup{full1, incr1, full2, incr2, incr3, running, failed, manual}}
+	db.SetTestManager(backupTestManager{backupDao: dao})
+	defer db.SetTestManager(nil)
+
+	scheduled, _ := dao.ListByScheduleID("s1")
+	expired, err := expiredBackups(scheduled, map[string]bool{"incr3": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := backupIDs(expired)
+	want := []string{"incr1", "failed"}
+	if len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] {
+		t.Fatalf("expected expired backups %v, got %v", want, ids)
+	}
+}
+
+// capability_id: rainbond.app-backup.schedule-run
+func TestBackupSchedulerRun(t *testing.T) {
+	now := time.Date(2024, 3, 18, 3, 0, 30, 0, time.UTC)
+	last := now.Add(-24 * time.Hour).Truncate(time.Second)
+	schedule := &dbmodel.AppBackupSchedule{
+		ScheduleID:   "s1abcdefgh",
+		GroupID:      "g1",
+		Schedule:     "0 3 * * *",
+		Timezone:     "UTC",
+		Mode:         "full-online",
+		ServiceIDs:   "svc1,svc2",
+		Metadata:     "{}",
+		Incremental:  true,
+		FullInterval: 2,
+		KeepLast:     1,
+		Enable:       true,
+		LastRunTime:  &last,
+	}
+	parent := newTestBackup("parent", "", dbmodel.BackupTypeFull, now.Add(-24*time.Hour))
+	old := newTestBackup("old", "", dbmodel.BackupTypeFull, now.Add(-48*time.Hour))
+	parent.ScheduleID, old.ScheduleID = "s1abcdefgh", "s1abcdefgh"
+	dao := &backupTestDao{backups: []*dbmodel.AppBackup{parent, old}}
+	db.SetTestManager(backupTestManager{backupDao: dao, scheduleDao: &scheduleTestDao{schedules: []*dbmodel.AppBackupSchedule{schedule}}})
+	defer db.SetTestManager(nil)
+
+	var started []Backup
+	var removed []string
+	scheduler := &backupScheduler{
+		newBackup: func(b Backup) (*dbmodel.AppBackup, *util.APIHandleError) {
+			started = append(started, b)
+			return &dbmodel.AppBackup{BackupID: "new", BackupType: b.Body.BackupType}, nil
+		},
+		removeArtifacts: func(backup *dbmodel.AppBackup, bucketName string) error {
+			removed = append(removed, backup.BackupID)
+			return nil
+		},
+	}
+	scheduler.run(now)
+	if len(started) != 1 {
+		t.Fatalf("expected one backup started, got %d", len(started))
+	}
+	body := started[0].Body
+	if body.Version != "20240318030000-s1abcdef" || body.BackupType != dbmodel.BackupTypeIncremental || body.ScheduleID != "s1abcdefgh" {
+		t.Fatalf("unexpected backup request %+v", body)
+	}
+	if len(body.ServiceIDs) != 2 || body.Metadata != "{}" {
+		t.Fatalf("unexpected backup services or metadata %+v", body)
+	}
+	if schedule.LastRunTime == nil || !schedule.LastRunTime.Equal(now.Truncate(time.Second)) {
+		t.Fatalf("expected the run claimed at %v, got %v", now, schedule.LastRunTime)
+	}
+	// keep last 1 keeps parent, old is pruned
+	if len(removed) != 1 || removed[0] != "old" || len(dao.deleted) != 1 || dao.deleted[0] != "old" {
+		t.Fatalf("expected backup old pruned, removed %v deleted %v", removed, dao.deleted)
+	}
+
+	// not due again in the same day
+	scheduler.run(now.Add(time.Minute))
+	if len(started) != 1 {
+		t.Fatalf("expected no more backup started, got %d", len(started))
+	}
+}
+
+// capability_id: rainbond.app-backup.schedule-run
+func TestIncrementalDepth(t *testing.T) {
+	now := time.Now()
+	dao := &backupTestDao{backups: []*dbmodel.AppBackup{
+		newTestBackup("full", "", dbmodel.BackupTypeFull, now.Add(-3*time.Hour)),
+		newTestBackup("incr1", "full", dbmodel.BackupTypeIncremental, now.Add(-2*time.Hour)),
+		newTestBackup("incr2", "incr1", dbmodel.BackupTypeIncremental, now.Add(-time.Hour)),
+	}}
+	db.SetTestManager(backupTestManager{backupDao: dao})
+	defer db.SetTestManager(nil)
+	depth, err := incrementalDepth("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth != 2 {
+		t.Fatalf("expected depth 2, got %d", depth)
+	}
+	if depth, _ := incrementalDepth("g2"); depth != 0 {
+		t.Fatalf("expected depth 0 without backups, got %d", depth)
+	}
+}
+
+// capability_id: rainbond.app-backup.incremental-parent
+func TestCoversServices(t *testing.T) {
+	backup := &dbmodel.AppBackup{ServiceIDs: "a,b,c"}
+	if !coversServices(backup, []string{"a", "c"}) {
+		t.Fatal("expected the services covered")
+	}
+	if coversServices(backup, []string{"a", "d"}) {
+		t.Fatal("expected service d not covered")
+	}
+	if coversServices(&dbmodel.AppBackup{}, []string{"a"}) {
+		t.Fatal("expected a backup without services covers nothing")
+	}
+}
+
+// capability_id: rainbond.app-backup.schedule-run
+func TestBackupScheduleDbModel(t *testing.T) {
+	var s BackupSchedule
+	s.Body.GroupID = "g1"
+	s.Body.Schedule = "0 3 * * *"
+	s.Body.Timezone = "Asia/Shanghai"
+	s.Body.ServiceIDs = []string{"a", "b"}
+	schedule, err := s.DbModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schedule.Enable || schedule.ServiceIDs != "a,b" {
+		t.Fatalf("unexpected schedule %+v", schedule)
+	}
+	s.Body.Schedule = "every day"
+	if _, err := s.DbModel(); err == nil {
+		t.Fatal("expected invalid schedule error")
+	}
+	s.Body.Schedule = "0 3 * * *"
+	s.Body.KeepDaily = -1
+	if _, err := s.DbModel(); err == nil {
+		t.Fatal("expected negative retention error")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package group
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/builder/exector"
+	"github.com/goodrain/rainbond/pkg/component/storage"
+	core_util "github.com/goodrain/rainbond/util"
+)
+
+// VerifyBackup re-reads the artifacts of the backup from the storage and reports the corrupted ones
+func (h *BackupHandle) VerifyBackup(backupID, bucketName string) (*exector.BackupVerifyResult, *util.APIHandleError) {
+	backup, Aerr := h.GetBackup(backupID)
+	if Aerr != nil {
+		return nil, Aerr
+	}
+	if backup.Status != "success" || backup.SourceDir == "" {
+		return nil, util.CreateAPIHandleErrorf(400, "backup %s is not finished successfully", backupID)
+	}
+	if bucketName == "" {
+		bucketName = "grdata"
+	}
+	tempDir, err := ioutil.TempDir("", "backup_verify_")
+	if err != nil {
+		return nil, util.CreateAPIHandleError(500, err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	_, filename := filepath.Split(backup.SourceDir)
+	pkg, err := fetchBackupArtifact(fmt.Sprintf("/%s/backup/%s/%s", bucketName, backup.GroupID, filename), tempDir)
+	if err != nil {
+		return &exector.BackupVerifyResult{
+			Package:  filename,
+			Problems: []exector.BackupVerifyProblem{{Path: filename, Reason: err.Error()}},
+		}, nil
+	}
+
+	files := make(map[string]string)
+	var missing []exector.BackupVerifyProblem
+	for _, name := range []string{"region_apps_metadata.json", "console_apps_metadata.json"} {
+		file, err := fetchBackupArtifact(fmt.Sprintf("/%s/backup/%s/%s/%s", bucketName, backup.GroupID, backup.Version, name), tempDir)
+		if err != nil {
+			missing = append(missing, exector.BackupVerifyProblem{Path: name + " (stored copy)", Reason: err.Error()})
+			continue
+		}
+		files[name] = file
+	}
+	result, err := exector.VerifyBackupPackage(pkg, backup.Checksum, files)
+	if err != nil {
+		return nil, util.CreateAPIHandleError(500, err)
+	}
+	if len(missing) > 0 {
+		result.Problems = append(result.Problems, missing...)
+		result.Valid = false
+	}
+	if !result.Valid {
+		logrus.Warningf("backup %s is corrupted: %+v", backupID, result.Problems)
+	}
+	return result, nil
+}
+
+// fetchBackupArtifact downloads the artifact from the storage to dir, and returns the local path of it.
+func fetchBackupArtifact(key, dir string) (string, error) {
+	if err := storage.Default().StorageCli.DownloadFileToDir(key, dir); err != nil {
+		return "", fmt.Errorf("download %s: %v", key, err)
+	}
+	file := filepath.Join(dir, filepath.Base(key))
+	if ok, _ := core_util.FileExists(file); ok {
+		return file, nil
+	}
+	// the local storage keeps the artifact at the key path
+	if ok, _ := core_util.FileExists(key); ok {
+		return key, nil
+	}
+	return "", fmt.Errorf("%s not found in the storage", key)
+}
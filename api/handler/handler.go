@@ -34,11 +34,14 @@ func InitAPIHandle() error {
 		logrus.Errorf("sync managed tenant namespace labels: %v", err)
 	}
 	defaultServieHandler = serviceManager
+	go serviceManager.StartVMSnapshotScheduler(context.Background())
 	defaultPluginHandler = CreatePluginManager()
 	defaultAppHandler = CreateAppManager()
 	defaultTenantHandler = CreateTenManager()
 	defaultHelmHandler = CreateHelmManager()
+	go GetHelmReleaseHandler().StartDriftDetector(context.Background())
 	defaultAPPBackupHandler = group.CreateBackupHandle()
+	go defaultAPPBackupHandler.StartBackupScheduler(context.Background())
 	defaultEventHandler = CreateLogManager()
 	defaultGatewayHandler = CreateGatewayManager()
 	defaultAPIGatewayHandler = CreateGatewayManager()
@@ -55,6 +58,13 @@ func InitAPIHandle() error {
 	defApplicationHandler = NewApplicationHandler()
 	defRegistryAuthSecretHandler = CreateRegistryAuthSecretManager()
 	defNodesHandler = NewNodesHandler()
+	defaultAPITokenHandler = NewAPITokenHandler()
+	defaultAuditLogHandler = NewAuditLogHandler()
+	defaultTerminalSessionHandler = NewTerminalSessionHandler()
+	defaultBuildCacheHandler = NewBuildCacheHandler(mq.Default().MqClient)
+	defaultGitWebhookHandler = NewGitWebhookHandler(operationHandler)
+	defaultAlertManagerHandler = NewAlertManagerHandler()
+	defaultNotificationHandler = NewNotificationHandler()
 
 	CreateLicenseV2Handler()
 
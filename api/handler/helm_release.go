@@ -110,6 +110,7 @@ type HelmReleaseDetail struct {
 	Services  []NsResourceInfo          `json:"services"`
 	Others    []NsResourceInfo          `json:"others"`
 	History   []*HelmReleaseHistoryItem `json:"history"`
+	Drift     *HelmReleaseDrift         `json:"drift"`
 }
 
 type HelmReleaseRollbackRequest struct {
@@ -291,6 +292,7 @@ func (h *HelmReleaseHandler) GetReleaseDetail(tenantName, releaseName, namespace
 		Services:  services,
 		Others:    others,
 		History:   summarizeHelmReleaseHistory(history),
+		Drift:     h.releaseDrift(release),
 	}, nil
 }
 
@@ -408,13 +410,12 @@ func (h *HelmReleaseHandler) UninstallRelease(tenantName, releaseName, namespace
 	return hc.Uninstall(releaseName)
 }
 
-var helmReleaseHandler *HelmReleaseHandler
+// helmReleaseHandler is created with the package, the drift detector and the api requests
+// read it from different goroutines.
+var helmReleaseHandler = &HelmReleaseHandler{}
 
 // GetHelmReleaseHandler returns the singleton HelmReleaseHandler.
 func GetHelmReleaseHandler() *HelmReleaseHandler {
-	if helmReleaseHandler == nil {
-		helmReleaseHandler = &HelmReleaseHandler{}
-	}
 	return helmReleaseHandler
 }
 
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goodrain/rainbond/pkg/component/k8s"
+	"github.com/goodrain/rainbond/pkg/helm"
+	utils "github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/util/constants"
+	"github.com/goodrain/rainbond/util/leader"
+	"github.com/sirupsen/logrus"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sapimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// helmDriftInterval is the interval of the periodic drift detection of the deployed releases.
+const helmDriftInterval = 10 * time.Minute
+
+// helmDriftLockName is the lease electing the api replica that runs the periodic drift detection.
+const helmDriftLockName = "rainbond-api-helm-drift-detector"
+
+// HelmReleaseUpgradeDiff is the structured diff of a dry-run upgrade against the live release.
+type HelmReleaseUpgradeDiff struct {
+	ReleaseName         string               `json:"release_name"`
+	Namespace           string               `json:"namespace"`
+	CurrentRevision     int                  `json:"current_revision"`
+	CurrentChart        string               `json:"current_chart"`
+	CurrentChartVersion string               `json:"current_chart_version"`
+	TargetChart         string               `json:"target_chart"`
+	TargetChartVersion  string               `json:"target_chart_version"`
+	Values              []*helm.FieldChange  `json:"values"`
+	Resources           []*helm.ResourceDiff `json:"resources"`
+	Added               int                  `json:"added"`
+	Modified            int                  `json:"modified"`
+	Removed             int                  `json:"removed"`
+}
+
+// HelmReleaseDrift is the result of comparing the release manifest with the live cluster objects.
+type HelmReleaseDrift struct {
+	Revision  int                  `json:"revision"`
+	CheckedAt string               `json:"checked_at"`
+	Drifted   bool                 `json:"drifted"`
+	Resources []*helm.ResourceDiff `json:"resources"`
+	// Unchecked lists the resources that could not be read from the cluster.
+	Unchecked []string `json:"unchecked,omitempty"`
+
+	checked time.Time
+}
+
+// liveObjectGetter reads the live object of a manifest resource, a NotFound error means it was deleted.
+type liveObjectGetter func(res *helm.ManifestResource) (map[string]interface{}, error)
+
+// helmDriftStore keeps the latest drift result of each release.
+type helmDriftStore struct {
+	lock    sync.RWMutex
+	results map[string]*HelmReleaseDrift
+}
+
+var helmReleaseDrifts = &helmDriftStore{results: make(map[string]*HelmReleaseDrift)}
+
+func helmDriftKey(namespace, releaseName string) string {
+	return namespace + "/" + releaseName
+}
+
+func (s *helmDriftStore) get(namespace, releaseName string) *HelmReleaseDrift {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.results[helmDriftKey(namespace, releaseName)]
+}
+
+func (s *helmDriftStore) set(namespace, releaseName string, drift *HelmReleaseDrift) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.results[helmDriftKey(namespace, releaseName)] = drift
+}
+
+// retain drops the results of the releases that are not deployed any more.
+func (s *helmDriftStore) retain(keys map[string]bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key := range s.results {
+		if !keys[key] {
+			delete(s.results, key)
+		}
+	}
+}
+
+// DiffUpgrade renders the upgrade without applying it and diffs the values and manifests
+// against the live release.
+func (h *HelmReleaseHandler) DiffUpgrade(tenantName, releaseName string, req HelmReleaseInstallRequest) (*HelmReleaseUpgradeDiff, error) {
+	req.Normalize()
+	req.ReleaseName = releaseName
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	hc, err := h.newHelm(tenantName, req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	currentRelease, err := hc.Status(releaseName)
+	if err != nil {
+		return nil, err
+	}
+	targetChart, chartPath, version, err := h.loadTargetChart(hc, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateUpgradeChartName(currentRelease, targetChart, req.AllowChartReplace); err != nil {
+		return nil, err
+	}
+	targetRelease, err := hc.DryRunUpgradeFromChartPath(chartPath, version, releaseName, req.Values)
+	if err != nil {
+		return nil, err
+	}
+	return diffHelmReleases(currentRelease, targetRelease)
+}
+
+func diffHelmReleases(current, target *helmrelease.Release) (*HelmReleaseUpgradeDiff, error) {
+	resources, err := helm.DiffManifests(current.Manifest, target.Manifest, current.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	diff := &HelmReleaseUpgradeDiff{
+		ReleaseName:     current.Name,
+		Namespace:       current.Namespace,
+		CurrentRevision: current.Version,
+		Values:          helm.DiffValues(current.Config, target.Config),
+		Resources:       resources,
+	}
+	if current.Chart != nil && current.Chart.Metadata != nil {
+		diff.CurrentChart = current.Chart.Metadata.Name
+		diff.CurrentChartVersion = current.Chart.Metadata.Version
+	}
+	if target.Chart != nil && target.Chart.Metadata != nil {
+		diff.TargetChart = target.Chart.Metadata.Name
+		diff.TargetChartVersion = target.Chart.Metadata.Version
+	}
+	for _, res := range resources {
+		switch res.Change {
+		case helm.DiffAdded:
+			diff.Added++
+		case helm.DiffRemoved:
+			diff.Removed++
+		default:
+			diff.Modified++
+		}
+	}
+	return diff, nil
+}
+
+// releaseDrift returns the drift of the release, it is detected now when the periodic
+// detection has not checked the current revision yet.
+func (h *HelmReleaseHandler) releaseDrift(release *helmrelease.Release) *HelmReleaseDrift {
+	// only the leader refreshes the results periodically, the other replicas detect again
+	// when their result is older than one detection interval
+	drift := helmReleaseDrifts.get(release.Namespace, release.Name)
+	if drift != nil && drift.Revision == release.Version && time.Since(drift.checked) < helmDriftInterval {
+		return drift
+	}
+	drift, err := detectHelmReleaseDrift(release, getHelmLiveObject)
+	if err != nil {
+		logrus.Warningf("detect drift of helm release %s/%s: %v", release.Namespace, release.Name, err)
+		return nil
+	}
+	helmReleaseDrifts.set(release.Namespace, release.Name, drift)
+	return drift
+}
+
+// StartDriftDetector periodically compares the manifests of the deployed releases with
+// the live cluster objects. The api runs with several replicas, the detection runs on the
+// one holding the helmDriftLockName lease.
+func (h *HelmReleaseHandler) StartDriftDetector(ctx context.Context) {
+	if k8s.Default() == nil || k8s.Default().Clientset == nil {
+		logrus.Warning("kubernetes client is not ready, helm release drift detection is disabled")
+		return
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		logrus.Errorf("get identity of helm drift detector: %v", err)
+		return
+	}
+	namespace := utils.GetenvDefault("RBD_NAMESPACE", constants.Namespace)
+	for ctx.Err() == nil {
+		// RunAsLeader returns when the leadership is lost, campaign again
+		leader.RunAsLeader(ctx, k8s.Default().Clientset, namespace, identity, helmDriftLockName, h.runDriftDetector, func() {})
+	}
+}
+
+func (h *HelmReleaseHandler) runDriftDetector(ctx context.Context) {
+	ticker := time.NewTicker(helmDriftInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.detectDrifts(ctx)
+		}
+	}
+}
+
+func (h *HelmReleaseHandler) detectDrifts(ctx context.Context) {
+	if k8s.Default() == nil || k8s.Default().Clientset == nil {
+		return
+	}
+	// helm stores the deployed revision of each release in a secret labeled with the release name
+	secrets, err := k8s.Default().Clientset.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: "owner=helm,status=deployed",
+	})
+	if err != nil {
+		logrus.Errorf("list helm release secrets: %v", err)
+		return
+	}
+	releases := make(map[string][]string)
+	for _, secret := range secrets.Items {
+		if name := secret.Labels["name"]; name != "" {
+			releases[secret.Namespace] = append(releases[secret.Namespace], name)
+		}
+	}
+	seen := make(map[string]bool)
+	for namespace, names := range releases {
+		hc, err := helm.NewHelm(namespace, repoFile, repoCache)
+		if err != nil {
+			logrus.Errorf("create helm client of namespace %s: %v", namespace, err)
+			continue
+		}
+		for _, name := range names {
+			seen[helmDriftKey(namespace, name)] = true
+			release, err := hc.Status(name)
+			if err != nil {
+				logrus.Warningf("get helm release %s/%s: %v", namespace, name, err)
+				continue
+			}
+			drift, err := detectHelmReleaseDrift(release, getHelmLiveObject)
+			if err != nil {
+				logrus.Warningf("detect drift of helm release %s/%s: %v", namespace, name, err)
+				continue
+			}
+			if drift.Drifted {
+				logrus.Infof("helm release %s/%s drifted from revision %d, %d resources changed out of band",
+					namespace, name, release.Version, len(drift.Resources))
+			}
+			helmReleaseDrifts.set(namespace, name, drift)
+		}
+	}
+	helmReleaseDrifts.retain(seen)
+}
+
+// detectHelmReleaseDrift compares each resource of the release manifest with its live object.
+func detectHelmReleaseDrift(release *helmrelease.Release, getLive liveObjectGetter) (*HelmReleaseDrift, error) {
+	resources, err := helm.ParseManifest(release.Manifest, release.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	drift := &HelmReleaseDrift{
+		Revision:  release.Version,
+		CheckedAt: now.UTC().Format(time.RFC3339),
+		checked:   now,
+		Resources: make([]*helm.ResourceDiff, 0),
+	}
+	for _, res := range resources {
+		live, err := getLive(res)
+		if err != nil {
+			if k8sapierrors.IsNotFound(err) {
+				drift.Resources = append(drift.Resources, &helm.ResourceDiff{
+					APIVersion: res.APIVersion,
+					Kind:       res.Kind,
+					Namespace:  res.Namespace,
+					Name:       res.Name,
+					Change:     helm.DiffMissing,
+				})
+				continue
+			}
+			logrus.Debugf("get live object of %s: %v", res.Key(), err)
+			drift.Unchecked = append(drift.Unchecked, fmt.Sprintf("%s/%s", res.Kind, res.Name))
+			continue
+		}
+		if diff := helm.DiffLive(res, live); diff != nil {
+			drift.Resources = append(drift.Resources, diff)
+		}
+	}
+	drift.Drifted = len(drift.Resources) > 0
+	return drift, nil
+}
+
+func getHelmLiveObject(res *helm.ManifestResource) (map[string]interface{}, error) {
+	gv, err := schema.ParseGroupVersion(res.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := k8s.Default().Mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: res.Kind}, gv.Version)
+	if err != nil {
+		return nil, err
+	}
+	var obj interface {
+		UnstructuredContent() map[string]interface{}
+	}
+	if mapping.Scope.Name() == k8sapimeta.RESTScopeNameNamespace {
+		obj, err = k8s.Default().DynamicClient.Resource(mapping.Resource).Namespace(res.Namespace).Get(context.Background(), res.Name, metav1.GetOptions{})
+	} else {
+		obj, err = k8s.Default().DynamicClient.Resource(mapping.Resource).Get(context.Background(), res.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.UnstructuredContent(), nil
+}
@@ -14,6 +14,8 @@ import (
 	helmchart "helm.sh/helm/v3/pkg/chart"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestGetHelmReleaseHandlerSingleton(t *testing.T) {
@@ -367,3 +369,82 @@ func TestValidateUpgradeChartNameAllowsMismatchWithExplicitConfirmation(t *testi
 
 	assert.NoError(t, err)
 }
+
+// capability_id: rainbond.helm-release.drift-detect
+func TestDetectHelmReleaseDrift(t *testing.T) {
+	release := &helmrelease.Release{
+		Name:      "demo",
+		Namespace: "ns",
+		Version:   3,
+		Manifest: `---
+# Source: demo/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+---
+# Source: demo/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: demo/templates/role.yaml
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+`,
+	}
+	drift, err := detectHelmReleaseDrift(release, func(res *helmcmd.ManifestResource) (map[string]interface{}, error) {
+		switch res.Kind {
+		case "Deployment":
+			return map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(5)}}, nil
+		case "Service":
+			return nil, k8sapierrors.NewNotFound(schema.GroupResource{Resource: "services"}, res.Name)
+		default:
+			return nil, fmt.Errorf("no matches for kind %s", res.Kind)
+		}
+	})
+	assert.NoError(t, err)
+	assert.True(t, drift.Drifted)
+	assert.Equal(t, 3, drift.Revision)
+	assert.Equal(t, []string{"Widget/w"}, drift.Unchecked)
+	if assert.Len(t, drift.Resources, 2) {
+		changes := map[string]*helmcmd.ResourceDiff{}
+		for _, res := range drift.Resources {
+			changes[res.Kind] = res
+		}
+		assert.Equal(t, helmcmd.DiffMissing, changes["Service"].Change)
+		assert.Equal(t, helmcmd.DiffModified, changes["Deployment"].Change)
+		assert.Equal(t, "spec.replicas", changes["Deployment"].Fields[0].Path)
+	}
+}
+
+// capability_id: rainbond.helm-release.upgrade-diff
+func TestDiffHelmReleases(t *testing.T) {
+	current := &helmrelease.Release{
+		Name: "demo", Namespace: "ns", Version: 2,
+		Chart:    &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "demo", Version: "1.0.0"}},
+		Config:   map[string]interface{}{"replicaCount": 1},
+		Manifest: "---\n# Source: demo/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\ndata:\n  a: \"1\"\n",
+	}
+	target := &helmrelease.Release{
+		Name: "demo", Namespace: "ns",
+		Chart:    &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "demo", Version: "1.1.0"}},
+		Config:   map[string]interface{}{"replicaCount": 2},
+		Manifest: "---\n# Source: demo/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\ndata:\n  a: \"2\"\n---\n# Source: demo/templates/sa.yaml\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: demo\n",
+	}
+	diff, err := diffHelmReleases(current, target)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", diff.CurrentChartVersion)
+	assert.Equal(t, "1.1.0", diff.TargetChartVersion)
+	assert.Equal(t, 1, diff.Added)
+	assert.Equal(t, 1, diff.Modified)
+	assert.Equal(t, 0, diff.Removed)
+	if assert.Len(t, diff.Values, 1) {
+		assert.Equal(t, "replicaCount", diff.Values[0].Path)
+	}
+}
@@ -0,0 +1,504 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
+	"github.com/goodrain/rainbond/pkg/component/k8s"
+	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/util/kubeblocks"
+	"github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	kubeBlocksBackupTypeLabel     = "dataprotection.kubeblocks.io/backup-type"
+	kubeBlocksBackupTypeContinous = "Continuous"
+	kubeBlocksInstanceLabel       = "app.kubernetes.io/instance"
+	kubeBlocksRestoreEventIDKey   = "rainbond.io/event-id"
+	kubeBlocksRestoreSourceKey    = "rainbond.io/source-service-id"
+)
+
+var (
+	kubeBlocksBackupResource  = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"}
+	kubeBlocksClusterResource = schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1alpha1", Resource: "clusters"}
+	// KubeBlocks 1.0 moved OpsRequest into operations.kubeblocks.io, 0.9 serves it in apps.kubeblocks.io
+	kubeBlocksOpsRequestResources = []schema.GroupVersionResource{
+		{Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+		{Group: "apps.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+	}
+	kubeBlocksRestorePollInterval = 10 * time.Second
+	kubeBlocksRestoreWaitTimeout  = 6 * time.Hour
+)
+
+// KubeBlocksRecoverableRange a time range the cluster can be restored to, covered by a
+// continuous log backup and a full backup finished inside it
+type KubeBlocksRecoverableRange struct {
+	BackupName string    `json:"backup_name"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+// KubeBlocksPITRRestoreRequest restores the cluster of the current component at restore_time
+// into a new cluster, which is managed by a new component created in the given tenant
+type KubeBlocksPITRRestoreRequest struct {
+	// RestoreTime RFC3339
+	RestoreTime string `json:"restore_time" validate:"required"`
+	// BackupName the continuous backup to restore from, optional
+	BackupName string `json:"backup_name"`
+	// TenantID the tenant of the new component, the current tenant by default.
+	// Restoring into another tenant needs a scoped api token granted both tenants.
+	TenantID     string `json:"tenant_id"`
+	AppID        string `json:"app_id" validate:"required"`
+	ServiceAlias string `json:"service_alias"`
+	ServiceCName string `json:"service_cname"`
+	// ClusterName the name of the new cluster, the alias of the new component by default
+	ClusterName string `json:"cluster_name"`
+}
+
+// KubeBlocksRestoreStatus the status of a point-in-time restore
+type KubeBlocksRestoreStatus struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ClusterName     string `json:"cluster_name"`
+	ServiceID       string `json:"service_id"`
+	SourceServiceID string `json:"source_service_id"`
+	BackupName      string `json:"backup_name"`
+	RestoreTime     string `json:"restore_time"`
+	Phase           string `json:"phase"`
+	Progress        string `json:"progress"`
+	EventID         string `json:"event_id"`
+	Message         string `json:"message,omitempty"`
+}
+
+type kubeBlocksCluster struct {
+	service       *dbmodel.TenantServices
+	namespace     string
+	clusterName   string
+	componentName string
+}
+
+// ListKubeBlocksRecoverableRanges lists the time ranges the kubeblocks component can be restored to
+func (s *ServiceAction) ListKubeBlocksRecoverableRanges(serviceID string) ([]*KubeBlocksRecoverableRange, error) {
+	cluster, err := s.getKubeBlocksCluster(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	backups, err := s.kubeBlocksDynamicClient().Resource(kubeBlocksBackupResource).Namespace(cluster.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", kubeBlocksInstanceLabel, cluster.clusterName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list backups of cluster %s: %v", cluster.clusterName, err)
+	}
+	return recoverableRanges(backups.Items), nil
+}
+
+// RestoreKubeBlocksPITR restores the cluster of the kubeblocks component serviceID at the given
+// time into a new cluster. A new component managing the restored cluster is created in the
+// current tenant, or in another tenant the caller is granted. The restore runs in the
+// background and its progress is written to the event log.
+func (s *ServiceAction) RestoreKubeBlocksPITR(ctx context.Context, tenantID, serviceID string, req *KubeBlocksPITRRestoreRequest) (*KubeBlocksRestoreStatus, error) {
+	restoreTime, err := time.Parse(time.RFC3339, req.RestoreTime)
+	if err != nil {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("invalid restore_time %q, RFC3339 expected", req.RestoreTime))
+	}
+	source, err := s.getKubeBlocksCluster(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.service.TenantID != tenantID {
+		return nil, bcode.ErrCrossTenantForbidden
+	}
+	targetTenantID := req.TenantID
+	if targetTenantID == "" {
+		targetTenantID = tenantID
+	}
+	if err := AuthorizeCrossTenant(ctx, tenantID, targetTenantID, dbmodel.APITokenVerbDeploy); err != nil {
+		return nil, err
+	}
+	app, err := s.getDBManager().ApplicationDao().GetAppByID(req.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if app.TenantID != targetTenantID {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("app %s does not belong to the tenant of the new component", req.AppID))
+	}
+	ranges, err := s.ListKubeBlocksRecoverableRanges(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	var backupName string
+	for _, r := range ranges {
+		if req.BackupName != "" && r.BackupName != req.BackupName {
+			continue
+		}
+		if !restoreTime.Before(r.Start) && !restoreTime.After(r.End) {
+			backupName = r.BackupName
+			break
+		}
+	}
+	if backupName == "" {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("restore time %s is not covered by the continuous backups of %s", req.RestoreTime, source.service.ServiceAlias))
+	}
+
+	// the restore opsrequest creates the cluster, which must not exist yet
+	newServiceID := util.NewUUID()
+	alias := strings.TrimSpace(req.ServiceAlias)
+	if alias == "" {
+		alias = "gr" + newServiceID[len(newServiceID)-6:]
+	}
+	clusterName := strings.TrimSpace(req.ClusterName)
+	if clusterName == "" {
+		clusterName = alias
+	}
+	if errs := validation.IsDNS1035Label(clusterName); len(errs) > 0 {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("invalid cluster name %q: %s", clusterName, strings.Join(errs, ", ")))
+	}
+	targetNamespace, err := s.kubeBlocksTenantNamespace(targetTenantID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.kubeBlocksDynamicClient().Resource(kubeBlocksClusterResource).Namespace(targetNamespace).Get(ctx, clusterName, metav1.GetOptions{})
+	if err == nil {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("cluster %s already exists", clusterName))
+	}
+	if !k8sErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get cluster %s: %v", clusterName, err)
+	}
+	sc, err := s.buildKubeBlocksRestoreService(source, targetTenantID, req.AppID, newServiceID, alias, req.ServiceCName, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.createKubeBlocksRestoreService(sc); err != nil {
+		return nil, fmt.Errorf("create restore component: %v", err)
+	}
+	target := &kubeBlocksCluster{
+		service:       &dbmodel.TenantServices{ServiceID: newServiceID, TenantID: targetTenantID, ServiceAlias: alias},
+		namespace:     targetNamespace,
+		clusterName:   clusterName,
+		componentName: source.componentName,
+	}
+
+	// 恢复在后台进行，事件不随请求结束
+	eventCtx := context.Background()
+	var eventID string
+	if ev, ok := ctx.Value(ctxutil.ContextKey("event")).(*dbmodel.ServiceEvent); ok && ev != nil {
+		eventCtx = context.WithValue(eventCtx, ctxutil.ContextKey("event"), ev)
+		eventID = ev.EventID
+	}
+	opsResource := s.kubeBlocksOpsRequestResource()
+	ops := buildKubeBlocksPITRRestore(opsResource, source, target, backupName, restoreTime, eventID, time.Now())
+	created, err := s.kubeBlocksDynamicClient().Resource(opsResource).Namespace(target.namespace).Create(ctx, ops, metav1.CreateOptions{})
+	if err != nil {
+		if delErr := s.delServiceMetadata(ctx, newServiceID); delErr != nil {
+			logrus.Errorf("delete restore component %s: %v", newServiceID, delErr)
+		}
+		return nil, fmt.Errorf("create restore opsrequest: %v", err)
+	}
+	go func() {
+		logger := operationLogger(eventID)
+		defer releaseOperationLogger(logger)
+		logger.Info(fmt.Sprintf("restoring %s at %s into cluster %s", source.service.ServiceAlias, req.RestoreTime, target.clusterName), map[string]string{"step": "kubeblocks-restore", "status": "running"})
+		if err := s.waitKubeBlocksRestore(opsResource, target.namespace, created.GetName(), logger); err != nil {
+			// the new component is kept on purpose: the opsrequest may still create the cluster after a
+			// timeout, so only the user can tell whether the component is still needed
+			logrus.Errorf("point-in-time restore %s of service %s: %v", created.GetName(), serviceID, err)
+			logger.Error(fmt.Sprintf("point-in-time restore failure: %v, component %s is kept for inspection", err, alias), map[string]string{"step": "last", "status": "failure"})
+			_ = markOperationEvent(eventCtx, dbmodel.EventStatusFailure)
+			return
+		}
+		logger.Info(fmt.Sprintf("cluster %s is restored to %s", target.clusterName, req.RestoreTime), event.GetLastLoggerOption())
+		_ = markOperationEvent(eventCtx, dbmodel.EventStatusSuccess)
+	}()
+	return kubeBlocksRestoreStatus(created), nil
+}
+
+// buildKubeBlocksRestoreService builds the component managing the restored cluster, the
+// resources and the ports are copied from the source component
+func (s *ServiceAction) buildKubeBlocksRestoreService(source *kubeBlocksCluster, tenantID, appID, serviceID, alias, cname, clusterName string) (*apimodel.ServiceStruct, error) {
+	if cname == "" {
+		cname = source.service.ServiceName + "-restore"
+	}
+	sc := &apimodel.ServiceStruct{
+		TenantID:         tenantID,
+		ServiceID:        serviceID,
+		ServiceAlias:     alias,
+		ServiceName:      cname,
+		ServiceType:      source.service.ServiceType,
+		Comment:          source.service.Comment,
+		ContainerCPU:     source.service.ContainerCPU,
+		ContainerMemory:  source.service.ContainerMemory,
+		ExtendMethod:     source.service.ExtendMethod,
+		Replicas:         source.service.Replicas,
+		Category:         source.service.Category,
+		ServiceOrigin:    source.service.ServiceOrigin,
+		Kind:             source.service.Kind,
+		AppID:            appID,
+		K8sComponentName: clusterName + "-" + source.componentName,
+	}
+	ports, err := s.getDBManager().TenantServicesPortDao().GetPortsByServiceID(source.service.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range ports {
+		copied := *port
+		copied.ID = 0
+		// the k8s service names are unique in the namespace, regenerated for the new component
+		copied.K8sServiceName = ""
+		sc.PortsInfo = append(sc.PortsInfo, copied)
+	}
+	return sc, nil
+}
+
+func (s *ServiceAction) createKubeBlocksRestoreService(sc *apimodel.ServiceStruct) error {
+	if s.createServiceHook != nil {
+		return s.createServiceHook(sc)
+	}
+	return s.ServiceCreate(sc)
+}
+
+// GetKubeBlocksRestore returns the status of a point-in-time restore into the component
+func (s *ServiceAction) GetKubeBlocksRestore(serviceID, name string) (*KubeBlocksRestoreStatus, error) {
+	target, err := s.getKubeBlocksCluster(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := s.kubeBlocksDynamicClient().Resource(s.kubeBlocksOpsRequestResource()).Namespace(target.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if ops.GetLabels()["service_id"] != serviceID {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("restore %s does not belong to the component", name))
+	}
+	return kubeBlocksRestoreStatus(ops), nil
+}
+
+func (s *ServiceAction) getKubeBlocksCluster(serviceID string) (*kubeBlocksCluster, error) {
+	service, err := db.GetManager().TenantServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if !service.IsKubeBlocksComponent() {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("service %s is not a kubeblocks component", service.ServiceAlias))
+	}
+	clusterName, componentName := kubeblocks.ParseComponentName(service.K8sComponentName)
+	if clusterName == "" {
+		return nil, bcode.NewBadRequest(fmt.Sprintf("invalid kubeblocks component name %q", service.K8sComponentName))
+	}
+	namespace, err := s.kubeBlocksTenantNamespace(service.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeBlocksCluster{service: service, namespace: namespace, clusterName: clusterName, componentName: componentName}, nil
+}
+
+func (s *ServiceAction) kubeBlocksTenantNamespace(tenantID string) (string, error) {
+	if s.resolveTenantNamespaceHook != nil {
+		return s.resolveTenantNamespaceHook(tenantID)
+	}
+	tenant, err := s.getDBManager().TenantDao().GetTenantByUUID(tenantID)
+	if err != nil {
+		return "", err
+	}
+	return tenant.Namespace, nil
+}
+
+func (s *ServiceAction) kubeBlocksDynamicClient() dynamic.Interface {
+	if s.dynamicClient != nil {
+		return s.dynamicClient
+	}
+	return k8s.Default().DynamicClient
+}
+
+func (s *ServiceAction) kubeBlocksOpsRequestResource() schema.GroupVersionResource {
+	if s.kubeClient != nil {
+		for _, gvr := range kubeBlocksOpsRequestResources {
+			resources, err := s.kubeClient.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+			if err != nil || resources == nil {
+				continue
+			}
+			for _, resource := range resources.APIResources {
+				if resource.Name == gvr.Resource {
+					return gvr
+				}
+			}
+		}
+	}
+	return kubeBlocksOpsRequestResources[len(kubeBlocksOpsRequestResources)-1]
+}
+
+func (s *ServiceAction) waitKubeBlocksRestore(gvr schema.GroupVersionResource, namespace, name string, logger event.Logger) error {
+	deadline := time.Now().Add(kubeBlocksRestoreWaitTimeout)
+	var lastPhase, lastProgress string
+	for {
+		ops, err := s.kubeBlocksDynamicClient().Resource(gvr).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		status := kubeBlocksRestoreStatus(ops)
+		if status.Phase != lastPhase || status.Progress != lastProgress {
+			lastPhase, lastProgress = status.Phase, status.Progress
+			logger.Info(fmt.Sprintf("restore %s, progress %s", status.Phase, status.Progress), map[string]string{"step": "kubeblocks-restore", "status": "running"})
+		}
+		switch status.Phase {
+		case "Succeed":
+			return nil
+		case "Failed", "Cancelled", "Aborted":
+			return fmt.Errorf("restore %s: %s", status.Phase, status.Message)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the restore %s", name)
+		}
+		time.Sleep(kubeBlocksRestorePollInterval)
+	}
+}
+
+func buildKubeBlocksPITRRestore(gvr schema.GroupVersionResource, source, target *kubeBlocksCluster, backupName string, restoreTime time.Time, eventID string, now time.Time) *unstructured.Unstructured {
+	ops := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gvr.GroupVersion().String(),
+		"kind":       "OpsRequest",
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("%s-pitr-%s", target.clusterName, now.Format("20060102150405")),
+			"namespace": target.namespace,
+			"labels": map[string]interface{}{
+				"service_id":            target.service.ServiceID,
+				kubeBlocksInstanceLabel: target.clusterName,
+			},
+			"annotations": map[string]interface{}{
+				kubeBlocksRestoreEventIDKey: eventID,
+				kubeBlocksRestoreSourceKey:  source.service.ServiceID,
+			},
+		},
+		"spec": map[string]interface{}{
+			"clusterName": target.clusterName,
+			"type":        "Restore",
+			"restore": map[string]interface{}{
+				"backupName":          backupName,
+				"backupNamespace":     source.namespace,
+				"restorePointInTime":  restoreTime.UTC().Format(time.RFC3339),
+				"volumeRestorePolicy": "Parallel",
+			},
+		},
+	}}
+	return ops
+}
+
+func kubeBlocksRestoreStatus(ops *unstructured.Unstructured) *KubeBlocksRestoreStatus {
+	status := &KubeBlocksRestoreStatus{
+		Name:            ops.GetName(),
+		Namespace:       ops.GetNamespace(),
+		ServiceID:       ops.GetLabels()["service_id"],
+		SourceServiceID: ops.GetAnnotations()[kubeBlocksRestoreSourceKey],
+		EventID:         ops.GetAnnotations()[kubeBlocksRestoreEventIDKey],
+	}
+	status.ClusterName, _, _ = unstructured.NestedString(ops.Object, "spec", "clusterName")
+	status.BackupName, _, _ = unstructured.NestedString(ops.Object, "spec", "restore", "backupName")
+	status.RestoreTime, _, _ = unstructured.NestedString(ops.Object, "spec", "restore", "restorePointInTime")
+	status.Phase, _, _ = unstructured.NestedString(ops.Object, "status", "phase")
+	status.Progress, _, _ = unstructured.NestedString(ops.Object, "status", "progress")
+	if status.Phase == "" {
+		status.Phase = "Pending"
+	}
+	conditions, _, _ := unstructured.NestedSlice(ops.Object, "status", "conditions")
+	if len(conditions) > 0 {
+		if condition, ok := conditions[len(conditions)-1].(map[string]interface{}); ok {
+			status.Message, _ = condition["message"].(string)
+		}
+	}
+	return status
+}
+
+// recoverableRanges pairs every continuous backup with the earliest full backup finished inside
+// it, the cluster can be restored to any time between the end of that full backup and the end
+// of the continuous backup.
+func recoverableRanges(backups []unstructured.Unstructured) []*KubeBlocksRecoverableRange {
+	var fullEnds []time.Time
+	type continuous struct {
+		name       string
+		start, end time.Time
+	}
+	var logs []continuous
+	for i := range backups {
+		backup := &backups[i]
+		phase, _, _ := unstructured.NestedString(backup.Object, "status", "phase")
+		start, startOK := nestedTime(backup.Object, "status", "timeRange", "start")
+		end, endOK := nestedTime(backup.Object, "status", "timeRange", "end")
+		if backup.GetLabels()[kubeBlocksBackupTypeLabel] == kubeBlocksBackupTypeContinous {
+			if (phase == "Running" || phase == "Completed") && startOK && endOK {
+				logs = append(logs, continuous{name: backup.GetName(), start: start, end: end})
+			}
+			continue
+		}
+		if phase != "Completed" {
+			continue
+		}
+		if !endOK {
+			if end, endOK = nestedTime(backup.Object, "status", "completionTimestamp"); !endOK {
+				continue
+			}
+		}
+		fullEnds = append(fullEnds, end)
+	}
+	ranges := []*KubeBlocksRecoverableRange{}
+	for _, cont := range logs {
+		var base time.Time
+		for _, end := range fullEnds {
+			if end.Before(cont.start) || end.After(cont.end) {
+				continue
+			}
+			if base.IsZero() || end.Before(base) {
+				base = end
+			}
+		}
+		if base.IsZero() {
+			continue
+		}
+		ranges = append(ranges, &KubeBlocksRecoverableRange{BackupName: cont.name, Start: base, End: cont.end})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start.Before(ranges[j].Start) })
+	return ranges
+}
+
+func nestedTime(obj map[string]interface{}, fields ...string) (time.Time, bool) {
+	value, found, _ := unstructured.NestedString(obj, fields...)
+	if !found || value == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
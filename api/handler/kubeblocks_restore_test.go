@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type kubeBlocksRestoreTestManager struct {
+	db.Manager
+	services map[string]*dbmodel.TenantServices
+}
+
+func (m kubeBlocksRestoreTestManager) TenantServiceDao() dbdao.TenantServiceDao {
+	return kubeBlocksRestoreTestServiceDao{services: m.services}
+}
+
+func (m kubeBlocksRestoreTestManager) ApplicationDao() dbdao.ApplicationDao {
+	return kubeBlocksRestoreTestAppDao{}
+}
+
+func (m kubeBlocksRestoreTestManager) TenantServicesPortDao() dbdao.TenantServicesPortDao {
+	return kubeBlocksRestoreTestPortDao{}
+}
+
+type kubeBlocksRestoreTestAppDao struct {
+	dbdao.ApplicationDao
+}
+
+func (kubeBlocksRestoreTestAppDao) GetAppByID(appID string) (*dbmodel.Application, error) {
+	return &dbmodel.Application{AppID: appID, TenantID: strings.TrimPrefix(appID, "app-")}, nil
+}
+
+type kubeBlocksRestoreTestPortDao struct {
+	dbdao.TenantServicesPortDao
+}
+
+func (kubeBlocksRestoreTestPortDao) GetPortsByServiceID(serviceID string) ([]*dbmodel.TenantServicesPort, error) {
+	port := &dbmodel.TenantServicesPort{ServiceID: serviceID, ContainerPort: 3306, Protocol: "tcp", K8sServiceName: "orders-mysql-3306"}
+	port.ID = 7
+	return []*dbmodel.TenantServicesPort{port}, nil
+}
+
+type kubeBlocksRestoreTestServiceDao struct {
+	dbdao.TenantServiceDao
+	services map[string]*dbmodel.TenantServices
+}
+
+func (d kubeBlocksRestoreTestServiceDao) GetServiceByID(serviceID string) (*dbmodel.TenantServices, error) {
+	return d.services[serviceID], nil
+}
+
+func kubeBlocksTestBackup(name, backupType, phase, start, end string) *unstructured.Unstructured {
+	backup := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "dataprotection.kubeblocks.io/v1alpha1",
+		"kind":       "Backup",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "prod",
+			"labels": map[string]interface{}{
+				kubeBlocksInstanceLabel:   "orders",
+				kubeBlocksBackupTypeLabel: backupType,
+			},
+		},
+		"status": map[string]interface{}{
+			"phase":     phase,
+			"timeRange": map[string]interface{}{"start": start, "end": end},
+		},
+	}}
+	return backup
+}
+
+func newKubeBlocksRestoreTestAction(objects ...runtime.Object) (*ServiceAction, *dynamicfake.FakeDynamicClient) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		kubeBlocksBackupResource:  "BackupList",
+		kubeBlocksClusterResource: "ClusterList",
+	}, objects...)
+	action := &ServiceAction{
+		dynamicClient: dynamicClient,
+		resolveTenantNamespaceHook: func(tenantID string) (string, error) {
+			return tenantID, nil
+		},
+	}
+	return action, dynamicClient
+}
+
+func setKubeBlocksRestoreTestManager() map[string]*dbmodel.TenantServices {
+	services := map[string]*dbmodel.TenantServices{
+		"source": {ServiceID: "source", TenantID: "prod", ServiceAlias: "orders", ServiceName: "orders", K8sComponentName: "orders-mysql", ExtendMethod: string(dbmodel.ServiceTypeKubeBlocks)},
+	}
+	db.SetTestManager(kubeBlocksRestoreTestManager{services: services})
+	return services
+}
+
+// capability_id: rainbond.api.kubeblocks.pitr-recoverable-ranges
+func TestListKubeBlocksRecoverableRanges(t *testing.T) {
+	setKubeBlocksRestoreTestManager()
+	defer db.SetTestManager(nil)
+	action, _ := newKubeBlocksRestoreTestAction(
+		kubeBlocksTestBackup("full-1", "Full", "Completed", "2026-10-01T00:00:00Z", "2026-10-01T00:10:00Z"),
+		kubeBlocksTestBackup("full-2", "Full", "Completed", "2026-10-02T00:00:00Z", "2026-10-02T00:10:00Z"),
+		kubeBlocksTestBackup("full-failed", "Full", "Failed", "2026-09-30T00:00:00Z", "2026-09-30T00:10:00Z"),
+		kubeBlocksTestBackup("binlog", kubeBlocksBackupTypeContinous, "Running", "2026-09-30T00:00:00Z", "2026-10-03T00:00:00Z"),
+		kubeBlocksTestBackup("binlog-old", kubeBlocksBackupTypeContinous, "Completed", "2026-09-01T00:00:00Z", "2026-09-02T00:00:00Z"),
+	)
+	ranges, err := action.ListKubeBlocksRecoverableRanges("source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// binlog-old has no full backup inside it
+	if len(ranges) != 1 {
+		t.Fatalf("expected one recoverable range, got %d", len(ranges))
+	}
+	want := time.Date(2026, 10, 1, 0, 10, 0, 0, time.UTC)
+	if ranges[0].BackupName != "binlog" || !ranges[0].Start.Equal(want) || !ranges[0].End.Equal(time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected range %+v", ranges[0])
+	}
+}
+
+// capability_id: rainbond.api.kubeblocks.pitr-restore-cross-tenant
+func TestRestoreKubeBlocksPITRCreatesNewComponent(t *testing.T) {
+	services := setKubeBlocksRestoreTestManager()
+	defer db.SetTestManager(nil)
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kubeblocks.io/v1alpha1",
+		"kind":       "Cluster",
+		"metadata":   map[string]interface{}{"name": "orders", "namespace": "prod"},
+	}}
+	action, dynamicClient := newKubeBlocksRestoreTestAction(
+		kubeBlocksTestBackup("full-1", "Full", "Completed", "2026-10-01T00:00:00Z", "2026-10-01T00:10:00Z"),
+		kubeBlocksTestBackup("binlog", kubeBlocksBackupTypeContinous, "Running", "2026-09-30T00:00:00Z", "2026-10-03T00:00:00Z"),
+		existing,
+	)
+	var created *apimodel.ServiceStruct
+	action.createServiceHook = func(sc *apimodel.ServiceStruct) error {
+		created = sc
+		services[sc.ServiceID] = &dbmodel.TenantServices{ServiceID: sc.ServiceID, TenantID: sc.TenantID, ServiceAlias: sc.ServiceAlias, K8sComponentName: sc.K8sComponentName, ExtendMethod: sc.ExtendMethod}
+		return nil
+	}
+	ctx := context.Background()
+	restoreTime := "2026-10-02T08:00:00+08:00"
+	if _, err := action.RestoreKubeBlocksPITR(ctx, "staging", "source", &KubeBlocksPITRRestoreRequest{RestoreTime: restoreTime, AppID: "app-staging"}); err != bcode.ErrCrossTenantForbidden {
+		t.Fatalf("expected the component of another tenant to be rejected, got %v", err)
+	}
+	if _, err := action.RestoreKubeBlocksPITR(ctx, "prod", "source", &KubeBlocksPITRRestoreRequest{RestoreTime: restoreTime, TenantID: "staging", AppID: "app-staging"}); err != bcode.ErrCrossTenantForbidden {
+		t.Fatalf("expected restoring into another tenant without grant to be rejected, got %v", err)
+	}
+	if _, err := action.RestoreKubeBlocksPITR(ctx, "prod", "source", &KubeBlocksPITRRestoreRequest{RestoreTime: "2026-10-01T00:05:00Z", AppID: "app-prod"}); err == nil {
+		t.Fatal("expected error for a time before the first full backup finished")
+	}
+	if _, err := action.RestoreKubeBlocksPITR(ctx, "prod", "source", &KubeBlocksPITRRestoreRequest{RestoreTime: restoreTime, AppID: "app-prod", ClusterName: "orders"}); err == nil {
+		t.Fatal("expected error restoring into the live cluster")
+	}
+	if created != nil {
+		t.Fatal("expected no component to be created by the rejected restores")
+	}
+
+	granted := context.WithValue(ctx, ctxutil.ContextKey("api_token"), &dbmodel.APIToken{TenantIDs: "prod,staging", Verbs: "deploy"})
+	status, err := action.RestoreKubeBlocksPITR(granted, "prod", "source", &KubeBlocksPITRRestoreRequest{RestoreTime: restoreTime, TenantID: "staging", AppID: "app-staging", ServiceAlias: "orders-copy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created == nil || created.TenantID != "staging" || created.K8sComponentName != "orders-copy-mysql" || created.ServiceName != "orders-restore" {
+		t.Fatalf("unexpected new component %+v", created)
+	}
+	if len(created.PortsInfo) != 1 || created.PortsInfo[0].ID != 0 || created.PortsInfo[0].K8sServiceName != "" {
+		t.Fatalf("expected the ports to be copied without the k8s service name, got %+v", created.PortsInfo)
+	}
+	if status.Namespace != "staging" || status.ClusterName != "orders-copy" || status.ServiceID != created.ServiceID || status.BackupName != "binlog" || status.Phase != "Pending" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	gvr := kubeBlocksOpsRequestResources[len(kubeBlocksOpsRequestResources)-1]
+	ops, err := dynamicClient.Resource(gvr).Namespace("staging").Get(ctx, status.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupNamespace, _, _ := unstructured.NestedString(ops.Object, "spec", "restore", "backupNamespace")
+	pointInTime, _, _ := unstructured.NestedString(ops.Object, "spec", "restore", "restorePointInTime")
+	if backupNamespace != "prod" || pointInTime != "2026-10-02T00:00:00Z" {
+		t.Fatalf("unexpected restore spec namespace %q time %q", backupNamespace, pointInTime)
+	}
+
+	_ = unstructured.SetNestedField(ops.Object, "Succeed", "status", "phase")
+	_ = unstructured.SetNestedField(ops.Object, "1/1", "status", "progress")
+	if _, err := dynamicClient.Resource(gvr).Namespace("staging").Update(ctx, ops, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := action.waitKubeBlocksRestore(gvr, "staging", status.Name, event.NewLogger("", nil)); err != nil {
+		t.Fatalf("expected restore to succeed: %v", err)
+	}
+	got, err := action.GetKubeBlocksRestore(created.ServiceID, status.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Phase != "Succeed" || got.Progress != "1/1" || got.SourceServiceID != "source" {
+		t.Fatalf("unexpected restore status %+v", got)
+	}
+}
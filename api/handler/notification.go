@@ -0,0 +1,315 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/worker/notification"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// NotificationHandler 团队通知渠道与订阅规则的管理
+type NotificationHandler interface {
+	ListChannels(tenantID string) ([]*model.NotificationChannelInfo, error)
+	CreateChannel(tenantID string, req *model.NotificationChannelReq) (*model.NotificationChannelInfo, error)
+	UpdateChannel(tenantID, channelID string, req *model.NotificationChannelReq) (*model.NotificationChannelInfo, error)
+	DeleteChannel(tenantID, channelID string) error
+	TestChannel(tenantID, channelID string) error
+	ListRules(tenantID string) ([]*model.NotificationRuleInfo, error)
+	CreateRule(tenantID string, req *model.NotificationRuleReq) (*model.NotificationRuleInfo, error)
+	UpdateRule(tenantID, ruleID string, req *model.NotificationRuleReq) (*model.NotificationRuleInfo, error)
+	DeleteRule(tenantID, ruleID string) error
+}
+
+var defaultNotificationHandler NotificationHandler
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler() NotificationHandler {
+	return &NotificationAction{}
+}
+
+// GetNotificationHandler get notification handler
+func GetNotificationHandler() NotificationHandler {
+	return defaultNotificationHandler
+}
+
+// NotificationAction notification action
+type NotificationAction struct{}
+
+// ListChannels 列出团队的通知渠道
+func (n *NotificationAction) ListChannels(tenantID string) ([]*model.NotificationChannelInfo, error) {
+	channels, err := db.GetManager().NotificationChannelDao().ListByTenantID(tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list notification channels")
+	}
+	infos := make([]*model.NotificationChannelInfo, 0, len(channels))
+	for _, channel := range channels {
+		infos = append(infos, channelInfo(channel))
+	}
+	return infos, nil
+}
+
+// CreateChannel 创建通知渠道
+func (n *NotificationAction) CreateChannel(tenantID string, req *model.NotificationChannelReq) (*model.NotificationChannelInfo, error) {
+	channel := &dbmodel.NotificationChannel{
+		ChannelID: util.NewUUID(),
+		TenantID:  tenantID,
+		Enabled:   true,
+	}
+	if err := applyChannelReq(channel, req, nil); err != nil {
+		return nil, err
+	}
+	if err := db.GetManager().NotificationChannelDao().AddModel(channel); err != nil {
+		return nil, errors.Wrap(err, "create notification channel")
+	}
+	return channelInfo(channel), nil
+}
+
+// UpdateChannel 更新通知渠道，请求中为空的地址与凭据保持不变
+func (n *NotificationAction) UpdateChannel(tenantID, channelID string, req *model.NotificationChannelReq) (*model.NotificationChannelInfo, error) {
+	channel, err := getTenantChannel(tenantID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := notification.ParseChannelConfig(channel)
+	if err != nil {
+		return nil, err
+	}
+	if channel.Type != req.Type {
+		// the credentials of another channel type are not reused
+		current = nil
+	}
+	if err := applyChannelReq(channel, req, current); err != nil {
+		return nil, err
+	}
+	if err := db.GetManager().NotificationChannelDao().UpdateModel(channel); err != nil {
+		return nil, errors.Wrap(err, "update notification channel")
+	}
+	return channelInfo(channel), nil
+}
+
+// DeleteChannel 删除通知渠道及发送到该渠道的订阅规则
+func (n *NotificationAction) DeleteChannel(tenantID, channelID string) error {
+	if _, err := getTenantChannel(tenantID, channelID); err != nil {
+		return err
+	}
+	if err := db.GetManager().NotificationRuleDao().DeleteByChannelID(channelID); err != nil {
+		return errors.Wrap(err, "delete notification rules of the channel")
+	}
+	if err := db.GetManager().NotificationChannelDao().DeleteByChannelID(channelID); err != nil {
+		return errors.Wrap(err, "delete notification channel")
+	}
+	return nil
+}
+
+// TestChannel 向通知渠道发送一条测试通知
+func (n *NotificationAction) TestChannel(tenantID, channelID string) error {
+	channel, err := getTenantChannel(tenantID, channelID)
+	if err != nil {
+		return err
+	}
+	if err := notification.SendTest(channel); err != nil {
+		return bcode.NewBadRequest(fmt.Sprintf("send test notification: %v", err))
+	}
+	return nil
+}
+
+// ListRules 列出团队的通知订阅规则
+func (n *NotificationAction) ListRules(tenantID string) ([]*model.NotificationRuleInfo, error) {
+	rules, err := db.GetManager().NotificationRuleDao().ListByTenantID(tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list notification rules")
+	}
+	infos := make([]*model.NotificationRuleInfo, 0, len(rules))
+	for _, rule := range rules {
+		infos = append(infos, ruleInfo(rule))
+	}
+	return infos, nil
+}
+
+// CreateRule 创建通知订阅规则
+func (n *NotificationAction) CreateRule(tenantID string, req *model.NotificationRuleReq) (*model.NotificationRuleInfo, error) {
+	rule := &dbmodel.NotificationRule{
+		RuleID:   util.NewUUID(),
+		TenantID: tenantID,
+		Enabled:  true,
+	}
+	if err := applyRuleReq(rule, req); err != nil {
+		return nil, err
+	}
+	if err := db.GetManager().NotificationRuleDao().AddModel(rule); err != nil {
+		return nil, errors.Wrap(err, "create notification rule")
+	}
+	return ruleInfo(rule), nil
+}
+
+// UpdateRule 更新通知订阅规则
+func (n *NotificationAction) UpdateRule(tenantID, ruleID string, req *model.NotificationRuleReq) (*model.NotificationRuleInfo, error) {
+	rule, err := getTenantRule(tenantID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyRuleReq(rule, req); err != nil {
+		return nil, err
+	}
+	if err := db.GetManager().NotificationRuleDao().UpdateModel(rule); err != nil {
+		return nil, errors.Wrap(err, "update notification rule")
+	}
+	return ruleInfo(rule), nil
+}
+
+// DeleteRule 删除通知订阅规则
+func (n *NotificationAction) DeleteRule(tenantID, ruleID string) error {
+	if _, err := getTenantRule(tenantID, ruleID); err != nil {
+		return err
+	}
+	if err := db.GetManager().NotificationRuleDao().DeleteByRuleID(ruleID); err != nil {
+		return errors.Wrap(err, "delete notification rule")
+	}
+	return nil
+}
+
+func getTenantChannel(tenantID, channelID string) (*dbmodel.NotificationChannel, error) {
+	channel, err := db.GetManager().NotificationChannelDao().GetByChannelID(channelID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, bcode.ErrNotificationChannelNotFound
+		}
+		return nil, errors.Wrap(err, "get notification channel")
+	}
+	if channel.TenantID != tenantID {
+		return nil, bcode.ErrNotificationChannelNotFound
+	}
+	return channel, nil
+}
+
+func getTenantRule(tenantID, ruleID string) (*dbmodel.NotificationRule, error) {
+	rule, err := db.GetManager().NotificationRuleDao().GetByRuleID(ruleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, bcode.ErrNotificationRuleNotFound
+		}
+		return nil, errors.Wrap(err, "get notification rule")
+	}
+	if rule.TenantID != tenantID {
+		return nil, bcode.ErrNotificationRuleNotFound
+	}
+	return rule, nil
+}
+
+// applyChannelReq 将请求写入渠道，current 为渠道当前的配置，用于保留请求中未填写的地址与凭据
+func applyChannelReq(channel *dbmodel.NotificationChannel, req *model.NotificationChannelReq, current *model.NotificationChannelConfig) error {
+	cfg := req.Config
+	if current != nil {
+		if cfg.URL == "" {
+			cfg.URL = current.URL
+		}
+		if cfg.Secret == "" {
+			cfg.Secret = current.Secret
+		}
+		if cfg.Password == "" {
+			cfg.Password = current.Password
+		}
+	}
+	if err := notification.ValidateChannelConfig(req.Type, &cfg); err != nil {
+		return bcode.NewBadRequest(err.Error())
+	}
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return errors.Wrap(err, "marshal notification channel config")
+	}
+	channel.Name = req.Name
+	channel.Type = req.Type
+	channel.Config = string(config)
+	if req.Enabled != nil {
+		channel.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+func applyRuleReq(rule *dbmodel.NotificationRule, req *model.NotificationRuleReq) error {
+	if req.ThrottleSeconds < 0 {
+		return bcode.ErrNotificationThrottle
+	}
+	if _, err := getTenantChannel(rule.TenantID, req.ChannelID); err != nil {
+		return err
+	}
+	rule.Name = req.Name
+	rule.ChannelID = req.ChannelID
+	rule.EventTypes = strings.Join(trimItems(req.EventTypes), ",")
+	rule.ServiceIDs = strings.Join(trimItems(req.ServiceIDs), ",")
+	rule.MinSeverity = req.MinSeverity
+	if rule.MinSeverity == "" {
+		rule.MinSeverity = dbmodel.NotificationSeverityInfo
+	}
+	rule.ThrottleSeconds = req.ThrottleSeconds
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+func trimItems(items []string) []string {
+	return notification.SplitList(strings.Join(items, ","))
+}
+
+func channelInfo(channel *dbmodel.NotificationChannel) *model.NotificationChannelInfo {
+	info := &model.NotificationChannelInfo{
+		ChannelID: channel.ChannelID,
+		TenantID:  channel.TenantID,
+		Name:      channel.Name,
+		Type:      channel.Type,
+		Enabled:   channel.Enabled,
+	}
+	cfg, err := notification.ParseChannelConfig(channel)
+	if err != nil {
+		return info
+	}
+	if channel.Type == dbmodel.NotificationChannelEmail {
+		info.Target = strings.Join(cfg.To, ",")
+	} else if u, err := url.Parse(cfg.URL); err == nil && u.Host != "" {
+		// the path and query of the robot webhooks carry the access tokens
+		info.Target = u.Scheme + "://" + u.Host + "/***"
+	}
+	return info
+}
+
+func ruleInfo(rule *dbmodel.NotificationRule) *model.NotificationRuleInfo {
+	return &model.NotificationRuleInfo{
+		RuleID:          rule.RuleID,
+		TenantID:        rule.TenantID,
+		ChannelID:       rule.ChannelID,
+		Name:            rule.Name,
+		EventTypes:      notification.SplitList(rule.EventTypes),
+		ServiceIDs:      notification.SplitList(rule.ServiceIDs),
+		MinSeverity:     rule.MinSeverity,
+		ThrottleSeconds: rule.ThrottleSeconds,
+		Enabled:         rule.Enabled,
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+type notifyTestManager struct {
+	db.Manager
+	channels map[string]*dbmodel.NotificationChannel
+	rules    map[string]*dbmodel.NotificationRule
+}
+
+func (m *notifyTestManager) NotificationChannelDao() dbdao.NotificationChannelDao {
+	return notifyTestChannelDao{m: m}
+}
+
+func (m *notifyTestManager) NotificationRuleDao() dbdao.NotificationRuleDao {
+	return notifyTestRuleDao{m: m}
+}
+
+type notifyTestChannelDao struct {
+	dbdao.NotificationChannelDao
+	m *notifyTestManager
+}
+
+func (d notifyTestChannelDao) AddModel(mo dbmodel.Interface) error {
+	channel := mo.(*dbmodel.NotificationChannel)
+	d.m.channels[channel.ChannelID] = channel
+	return nil
+}
+
+func (d notifyTestChannelDao) UpdateModel(mo dbmodel.Interface) error {
+	return d.AddModel(mo)
+}
+
+func (d notifyTestChannelDao) GetByChannelID(channelID string) (*dbmodel.NotificationChannel, error) {
+	if channel, ok := d.m.channels[channelID]; ok {
+		copied := *channel
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d notifyTestChannelDao) DeleteByChannelID(channelID string) error {
+	delete(d.m.channels, channelID)
+	return nil
+}
+
+type notifyTestRuleDao struct {
+	dbdao.NotificationRuleDao
+	m *notifyTestManager
+}
+
+func (d notifyTestRuleDao) AddModel(mo dbmodel.Interface) error {
+	rule := mo.(*dbmodel.NotificationRule)
+	d.m.rules[rule.RuleID] = rule
+	return nil
+}
+
+func (d notifyTestRuleDao) DeleteByChannelID(channelID string) error {
+	for id, rule := range d.m.rules {
+		if rule.ChannelID == channelID {
+			delete(d.m.rules, id)
+		}
+	}
+	return nil
+}
+
+// capability_id: rainbond.api.notification.channels-and-rules
+func TestNotificationChannelsAndRules(t *testing.T) {
+	fake := &notifyTestManager{
+		channels: map[string]*dbmodel.NotificationChannel{},
+		rules:    map[string]*dbmodel.NotificationRule{},
+	}
+	db.SetTestManager(fake)
+	defer db.SetTestManager(nil)
+	h := NewNotificationHandler()
+
+	if _, err := h.CreateChannel("tenant", &model.NotificationChannelReq{
+		Name: "mail", Type: dbmodel.NotificationChannelEmail, Config: model.NotificationChannelConfig{SMTPHost: "smtp.example.com"},
+	}); err == nil {
+		t.Fatal("expected an email channel without recipients to be rejected")
+	}
+	info, err := h.CreateChannel("tenant", &model.NotificationChannelReq{
+		Name: "robot", Type: dbmodel.NotificationChannelDingTalk,
+		Config: model.NotificationChannelConfig{URL: "https://oapi.dingtalk.com/robot/send?access_token=token", Secret: "SEC"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Enabled || info.Target != "https://oapi.dingtalk.com/***" {
+		t.Fatalf("expected the token to be masked, got %+v", info)
+	}
+
+	disabled := false
+	if _, err := h.UpdateChannel("tenant", info.ChannelID, &model.NotificationChannelReq{
+		Name: "robot", Type: dbmodel.NotificationChannelDingTalk, Enabled: &disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var cfg model.NotificationChannelConfig
+	stored := fake.channels[info.ChannelID]
+	if err := json.Unmarshal([]byte(stored.Config), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Enabled || cfg.URL == "" || cfg.Secret != "SEC" {
+		t.Fatalf("expected the url and secret to be kept on update, got %+v %+v", stored, cfg)
+	}
+	if _, err := h.UpdateChannel("other", info.ChannelID, &model.NotificationChannelReq{Name: "x", Type: dbmodel.NotificationChannelSlack}); err != bcode.ErrNotificationChannelNotFound {
+		t.Fatalf("expected the channel of another tenant not to be found, got %v", err)
+	}
+
+	if _, err := h.CreateRule("other", &model.NotificationRuleReq{Name: "r", ChannelID: info.ChannelID}); err != bcode.ErrNotificationChannelNotFound {
+		t.Fatalf("expected the rule to require a channel of the tenant, got %v", err)
+	}
+	if _, err := h.CreateRule("tenant", &model.NotificationRuleReq{Name: "r", ChannelID: info.ChannelID, ThrottleSeconds: -1}); err != bcode.ErrNotificationThrottle {
+		t.Fatalf("expected a negative throttle to be rejected, got %v", err)
+	}
+	rule, err := h.CreateRule("tenant", &model.NotificationRuleReq{
+		Name: "r", ChannelID: info.ChannelID, EventTypes: []string{" OOMKilled ", "", "LivenessRestart"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rule.EventTypes) != 2 || rule.MinSeverity != dbmodel.NotificationSeverityInfo || !rule.Enabled {
+		t.Fatalf("unexpected rule %+v", rule)
+	}
+	if fake.rules[rule.RuleID].EventTypes != "OOMKilled,LivenessRestart" {
+		t.Fatalf("unexpected stored event types %q", fake.rules[rule.RuleID].EventTypes)
+	}
+
+	if err := h.DeleteChannel("tenant", info.ChannelID); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.channels) != 0 || len(fake.rules) != 0 {
+		t.Fatal("expected the rules of the channel to be deleted with it")
+	}
+}
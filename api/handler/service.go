@@ -78,6 +78,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apiserver/pkg/util/flushwriter"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -95,6 +96,7 @@ type ServiceAction struct {
 	prometheusCli                            prometheus.Interface
 	rainbondClient                           versioned.Interface
 	kubeClient                               kubernetes.Interface
+	dynamicClient                            dynamic.Interface
 	kubevirtClient                           kubecli.KubevirtClient
 	dbmanager                                db.Manager
 	registryCli                              *registry.Registry
@@ -112,6 +114,7 @@ type ServiceAction struct {
 	loadVMRuntimeSpecExtensionSetHook        func(componentID string) (map[string]string, error)
 	hotplugVMDataDiskHook                    func(tenantID string, volume *dbmodel.TenantServiceVolume) error
 	hotunplugVMDataDiskHook                  func(volume *dbmodel.TenantServiceVolume) error
+	createServiceHook                        func(sc *apimodel.ServiceStruct) error
 }
 
 type dCfg struct {
@@ -446,7 +449,8 @@ func (s *ServiceAction) StartStopService(sss *apimodel.StartStopStruct) error {
 	return nil
 }
 
-func markDirectVMOperationEvent(ctx context.Context, status dbmodel.EventStatus) error {
+// markOperationEvent sets the status of the event in ctx when an operation finishes outside the worker
+func markOperationEvent(ctx context.Context, status dbmodel.EventStatus) error {
 	if ctx == nil {
 		return nil
 	}
@@ -455,7 +459,7 @@ func markDirectVMOperationEvent(ctx context.Context, status dbmodel.EventStatus)
 		return nil
 	}
 	if err := db.GetManager().ServiceEventDao().SetEventStatus(ctx, status); err != nil {
-		logrus.Errorf("update direct vm operation event status to %s failure: %v", status, err)
+		logrus.Errorf("update operation event status to %s failure: %v", status, err)
 		return err
 	}
 	return nil
@@ -526,22 +530,22 @@ func (s *ServiceAction) StartOrCreateVM(ctx context.Context, sss *apimodel.Start
 		return s.enqueueVMStartTask(sss, deployVersion)
 	}
 	if isVMStartRequestedOrRunning(vm.Status.PrintableStatus) {
-		return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+		return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 	}
 	if s.syncVirtualMachineSpecHook != nil || s.dbmanager != nil {
 		if err := s.syncVirtualMachineSpecAfterResourceUpdate(sss.ServiceID); err != nil {
 			if !isMissingVMRootImportSpecSyncError(err) {
-				_ = markDirectVMOperationEvent(ctx, dbmodel.EventStatusFailure)
+				_ = markOperationEvent(ctx, dbmodel.EventStatusFailure)
 				return err
 			}
 			logrus.Warnf("skip vm spec sync before start for existing vm with missing imported root disk: service_id=%s err=%v", sss.ServiceID, err)
 		}
 	}
 	if err := s.ensureVMStarted(sss, deployVersion, vm); err != nil {
-		_ = markDirectVMOperationEvent(ctx, dbmodel.EventStatusFailure)
+		_ = markOperationEvent(ctx, dbmodel.EventStatusFailure)
 		return err
 	}
-	return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+	return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 }
 
 func isVMStartRequestedOrRunning(status v1.VirtualMachinePrintableStatus) bool {
@@ -572,23 +576,23 @@ func (s *ServiceAction) RestartVM(ctx context.Context, sss *apimodel.StartStopSt
 		if s.syncVirtualMachineSpecHook != nil || s.dbmanager != nil {
 			if err := s.syncVirtualMachineSpecAfterResourceUpdate(sss.ServiceID); err != nil {
 				if !isMissingVMRootImportSpecSyncError(err) {
-					_ = markDirectVMOperationEvent(ctx, dbmodel.EventStatusFailure)
+					_ = markOperationEvent(ctx, dbmodel.EventStatusFailure)
 					return err
 				}
 				logrus.Warnf("skip vm spec sync before restart-start for existing vm with missing imported root disk: service_id=%s err=%v", sss.ServiceID, err)
 			}
 		}
 		if err := s.ensureVMStarted(sss, deployVersion, vm); err != nil {
-			_ = markDirectVMOperationEvent(ctx, dbmodel.EventStatusFailure)
+			_ = markOperationEvent(ctx, dbmodel.EventStatusFailure)
 			return err
 		}
-		return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+		return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 	}
 	if err := s.kubevirtClient.VirtualMachine(vm.Namespace).Restart(context.Background(), vm.Name, &v1.RestartOptions{}); err != nil {
-		_ = markDirectVMOperationEvent(ctx, dbmodel.EventStatusFailure)
+		_ = markOperationEvent(ctx, dbmodel.EventStatusFailure)
 		return err
 	}
-	return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+	return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 }
 
 func (s *ServiceAction) StopVM(ctx context.Context, serviceID string) error {
@@ -597,19 +601,19 @@ func (s *ServiceAction) StopVM(ctx context.Context, serviceID string) error {
 		return err
 	}
 	if vm == nil {
-		return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+		return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 	}
 	if vm.Status.PrintableStatus == v1.VirtualMachineStatusStopped {
-		return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+		return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 	}
 	if err := s.kubevirtClient.VirtualMachine(vm.Namespace).Stop(context.Background(), vm.Name, &v1.StopOptions{}); err != nil {
 		if fallbackErr := s.haltTransientVM(vm, err); fallbackErr == nil {
-			return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+			return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 		}
-		_ = markDirectVMOperationEvent(ctx, dbmodel.EventStatusFailure)
+		_ = markOperationEvent(ctx, dbmodel.EventStatusFailure)
 		return err
 	}
-	return markDirectVMOperationEvent(ctx, dbmodel.EventStatusSuccess)
+	return markOperationEvent(ctx, dbmodel.EventStatusSuccess)
 }
 
 func (s *ServiceAction) haltTransientVM(vm *v1.VirtualMachine, stopErr error) error {
@@ -1428,6 +1432,14 @@ func (s *ServiceAction) ServiceCreate(sc *apimodel.ServiceStruct) error {
 				return err
 			}
 		}
+		if sc.Endpoints.Kubernetes == nil && sc.Endpoints.Discovery != nil {
+			if err := db.GetManager().ThirdPartySvcDiscoveryCfgDaoTransactions(tx).
+				AddModel(sc.Endpoints.Discovery.DbModel(sc.ServiceID)); err != nil {
+				logrus.Errorf("error saving discover center configuration: %v", err)
+				tx.Rollback()
+				return err
+			}
+		}
 		if sc.Endpoints.Static != nil {
 			for _, o := range sc.Endpoints.Static {
 				ep := &dbmodel.Endpoint{
@@ -1876,6 +1888,12 @@ func (s *ServiceAction) ServiceDepend(action string, ds *apimodel.DependService)
 			return err
 		}
 	}
+	// the policies of the depended component list its consumers
+	if appHandler := GetApplicationHandler(); appHandler != nil {
+		if err := appHandler.SyncComponentZeroTrustPolicies(context.Background(), ds.DepServiceID); err != nil {
+			logrus.Warningf("sync zero trust policies of %s after dependency change: %v", ds.DepServiceID, err)
+		}
+	}
 	return nil
 }
 
@@ -3498,27 +3516,14 @@ func (s *ServiceAction) AddAutoscalerRule(req *apimodel.AutoscalerRuleReq) error
 	tx := db.GetManager().Begin()
 	defer db.GetManager().EnsureEndTransactionFunc()
 
-	r := &dbmodel.TenantServiceAutoscalerRules{
-		RuleID:      req.RuleID,
-		ServiceID:   req.ServiceID,
-		Enable:      req.Enable,
-		XPAType:     req.XPAType,
-		MinReplicas: req.MinReplicas,
-		MaxReplicas: req.MaxReplicas,
-	}
+	r := req.DbModel()
 	if err := db.GetManager().TenantServceAutoscalerRulesDaoTransactions(tx).AddModel(r); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	for _, metric := range req.Metrics {
-		m := &dbmodel.TenantServiceAutoscalerRuleMetrics{
-			RuleID:            req.RuleID,
-			MetricsType:       metric.MetricsType,
-			MetricsName:       metric.MetricsName,
-			MetricTargetType:  metric.MetricTargetType,
-			MetricTargetValue: metric.MetricTargetValue,
-		}
+		m := metric.DbModel(req.RuleID)
 		if err := db.GetManager().TenantServceAutoscalerRuleMetricsDaoTransactions(tx).AddModel(m); err != nil {
 			tx.Rollback()
 			return err
@@ -3553,6 +3558,9 @@ func (s *ServiceAction) UpdAutoscalerRule(req *apimodel.AutoscalerRuleReq) error
 	rule.XPAType = req.XPAType
 	rule.MinReplicas = req.MinReplicas
 	rule.MaxReplicas = req.MaxReplicas
+	newRule := req.DbModel()
+	rule.Behavior = newRule.Behavior
+	rule.Schedules = newRule.Schedules
 
 	tx := db.GetManager().Begin()
 	defer db.GetManager().EnsureEndTransactionFunc()
@@ -3569,13 +3577,7 @@ func (s *ServiceAction) UpdAutoscalerRule(req *apimodel.AutoscalerRuleReq) error
 	}
 
 	for _, metric := range req.Metrics {
-		m := &dbmodel.TenantServiceAutoscalerRuleMetrics{
-			RuleID:            req.RuleID,
-			MetricsType:       metric.MetricsType,
-			MetricsName:       metric.MetricsName,
-			MetricTargetType:  metric.MetricTargetType,
-			MetricTargetValue: metric.MetricTargetValue,
-		}
+		m := metric.DbModel(req.RuleID)
 		if err := db.GetManager().TenantServceAutoscalerRuleMetricsDaoTransactions(tx).AddModel(m); err != nil {
 			tx.Rollback()
 			return err
@@ -4041,7 +4043,7 @@ func (s *ServiceAction) SyncComponentEndpoints(tx *gorm.DB, components []*apimod
 			continue
 		}
 		componentIDs = append(componentIDs, component.ComponentBase.ComponentID)
-		if component.Endpoint.Kubernetes != nil {
+		if component.Endpoint.Kubernetes != nil || component.Endpoint.Discovery != nil {
 			thirdPartySvcDiscoveryCfgs = append(thirdPartySvcDiscoveryCfgs, component.Endpoint.DbModel(component.ComponentBase.ComponentID))
 		}
 	}
@@ -45,6 +45,16 @@ type ServiceHandler interface {
 	CreateVMExport(serviceID string, req *VMExportRequest) (*VMExportStatus, error)
 	GetVMExport(serviceID, exportName string) (*VMExportStatus, error)
 	CreateVMSnapshot(serviceID string, req *VMSnapshotRequest) (*VMSnapshotStatus, error)
+	ListVMSnapshots(serviceID string) ([]*VMSnapshotInfo, error)
+	DeleteVMSnapshot(serviceID, name string) error
+	RestoreVMSnapshot(ctx context.Context, tenantID, serviceID, name string, req *VMSnapshotRestoreRequest) (*VMSnapshotRestoreStatus, error)
+	GetVMSnapshotSchedule(serviceID string) (*dbmodel.VMSnapshotSchedule, error)
+	SetVMSnapshotSchedule(tenantID, serviceID string, req *VMSnapshotScheduleRequest) (*dbmodel.VMSnapshotSchedule, error)
+	DeleteVMSnapshotSchedule(serviceID string) error
+	ListKubeBlocksRecoverableRanges(serviceID string) ([]*KubeBlocksRecoverableRange, error)
+	RestoreKubeBlocksPITR(ctx context.Context, tenantID, serviceID string, req *KubeBlocksPITRRestoreRequest) (*KubeBlocksRestoreStatus, error)
+	GetKubeBlocksRestore(serviceID, name string) (*KubeBlocksRestoreStatus, error)
+	CloneVM(ctx context.Context, serviceID string, req *VMCloneRequest) (*VMCloneResult, error)
 	GetVMLiveUpdateCapability(serviceID string) VMLiveUpdateCapability
 	SetVMFixedPodIP(ctx context.Context, serviceID string, enabled bool) (*VMFixedPodIPResult, error)
 	ServiceVertical(ctx context.Context, v *model.VerticalScalingTaskBody) error
@@ -414,6 +414,9 @@ func (o *OperationHandler) buildFromSourceCode(r *model.ComponentBuildReq, servi
 	body["envs"] = r.BuildENVs
 	body["tenant_name"] = r.TenantName
 	body["branch"] = r.CodeInfo.Branch
+	if r.CodeInfo.Commit != "" {
+		body["commit"] = r.CodeInfo.Commit
+	}
 	body["server_type"] = r.CodeInfo.ServerType
 	body["service_alias"] = service.ServiceAlias
 	if r.CodeInfo.User != "" && r.CodeInfo.Password != "" {
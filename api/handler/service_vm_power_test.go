@@ -39,6 +39,14 @@ func (m *recordingMQClient) Dequeue(context.Context, *mqpb.DequeueRequest, ...gr
 	return nil, nil
 }
 
+func (m *recordingMQClient) Ack(context.Context, *mqpb.AckRequest, ...grpc.CallOption) (*mqpb.TaskReply, error) {
+	return nil, nil
+}
+
+func (m *recordingMQClient) Nack(context.Context, *mqpb.NackRequest, ...grpc.CallOption) (*mqpb.TaskReply, error) {
+	return nil, nil
+}
+
 func (m *recordingMQClient) Close() {}
 
 func (m *recordingMQClient) SendBuilderTopic(t mqclient.TaskStruct) error {
@@ -158,6 +158,15 @@ func (d *resourceSyncComponentK8sAttributeDao) CreateOrUpdateAttributesInBatch(a
 	return nil
 }
 
+func (d *resourceSyncComponentK8sAttributeDao) ListByComponentID(componentID string) ([]*dbmodel.ComponentK8sAttributes, error) {
+	var attrs []*dbmodel.ComponentK8sAttributes
+	for _, attr := range d.attributes {
+		copied := *attr
+		attrs = append(attrs, &copied)
+	}
+	return attrs, nil
+}
+
 func (d *resourceSyncComponentK8sAttributeDao) DeleteByComponentIDAndName(componentID, name string) error {
 	d.deleted = append(d.deleted, componentID+"/"+name)
 	if d.attributes != nil {
@@ -180,6 +189,14 @@ func (m *noopMQClient) Dequeue(context.Context, *mqpb.DequeueRequest, ...grpc.Ca
 	return nil, nil
 }
 
+func (m *noopMQClient) Ack(context.Context, *mqpb.AckRequest, ...grpc.CallOption) (*mqpb.TaskReply, error) {
+	return nil, nil
+}
+
+func (m *noopMQClient) Nack(context.Context, *mqpb.NackRequest, ...grpc.CallOption) (*mqpb.TaskReply, error) {
+	return nil, nil
+}
+
 func (m *noopMQClient) Close() {}
 
 func (m *noopMQClient) SendBuilderTopic(gclient.TaskStruct) error {
@@ -77,6 +77,14 @@ func (m *recordingMQClient) Dequeue(ctx context.Context, in *mqpb.DequeueRequest
 	return &mqpb.TaskMessage{}, nil
 }
 
+func (m *recordingMQClient) Ack(ctx context.Context, in *mqpb.AckRequest, opts ...grpc.CallOption) (*mqpb.TaskReply, error) {
+	return &mqpb.TaskReply{}, nil
+}
+
+func (m *recordingMQClient) Nack(ctx context.Context, in *mqpb.NackRequest, opts ...grpc.CallOption) (*mqpb.TaskReply, error) {
+	return &mqpb.TaskReply{}, nil
+}
+
 func (m *recordingMQClient) Close() {}
 
 func (m *recordingMQClient) SendBuilderTopic(t mqclient.TaskStruct) error {
@@ -0,0 +1,124 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"io"
+
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/pkg/component/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// TerminalSessionHandler web terminal 会话录制的开关、查询与回放
+type TerminalSessionHandler interface {
+	GetTerminalRecording(tenantID string) (bool, error)
+	SetTerminalRecording(tenantID string, enable bool) error
+	ListTerminalSessions(query *dbmodel.TerminalSessionQuery, page, pageSize int) ([]*dbmodel.TerminalSession, int64, error)
+	StreamTerminalRecording(w io.Writer, tenantID, sessionID string) error
+}
+
+var defaultTerminalSessionHandler TerminalSessionHandler
+
+// NewTerminalSessionHandler creates a new terminal session handler
+func NewTerminalSessionHandler() TerminalSessionHandler {
+	return &TerminalSessionAction{}
+}
+
+// GetTerminalSessionHandler get terminal session handler
+func GetTerminalSessionHandler() TerminalSessionHandler {
+	return defaultTerminalSessionHandler
+}
+
+// TerminalSessionAction terminal session action
+type TerminalSessionAction struct {
+	dbmanager db.Manager
+	storage   storage.InterfaceStorage
+}
+
+func (t *TerminalSessionAction) getDBManager() db.Manager {
+	if t.dbmanager != nil {
+		return t.dbmanager
+	}
+	return db.GetManager()
+}
+
+func (t *TerminalSessionAction) getStorage() storage.InterfaceStorage {
+	if t.storage != nil {
+		return t.storage
+	}
+	return storage.Default().StorageCli
+}
+
+// GetTerminalRecording 租户是否开启终端会话录制
+func (t *TerminalSessionAction) GetTerminalRecording(tenantID string) (bool, error) {
+	kv, err := t.getDBManager().KeyValueDao().Get(dbmodel.TerminalRecordingKey(tenantID))
+	if err != nil {
+		return false, errors.Wrap(err, "get terminal recording")
+	}
+	return kv != nil && kv.V == "true", nil
+}
+
+// SetTerminalRecording 开启或关闭租户的终端会话录制
+func (t *TerminalSessionAction) SetTerminalRecording(tenantID string, enable bool) error {
+	key := dbmodel.TerminalRecordingKey(tenantID)
+	if err := t.getDBManager().KeyValueDao().Delete(key); err != nil {
+		return errors.Wrap(err, "delete terminal recording")
+	}
+	if !enable {
+		return nil
+	}
+	return t.getDBManager().KeyValueDao().Put(key, "true")
+}
+
+// ListTerminalSessions 按组件、用户与时间查询终端会话
+func (t *TerminalSessionAction) ListTerminalSessions(query *dbmodel.TerminalSessionQuery, page, pageSize int) ([]*dbmodel.TerminalSession, int64, error) {
+	sessions, total, err := t.getDBManager().TerminalSessionDao().List(query, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list terminal sessions")
+	}
+	return sessions, total, nil
+}
+
+// StreamTerminalRecording 输出会话的 asciicast 录制文件
+func (t *TerminalSessionAction) StreamTerminalRecording(w io.Writer, tenantID, sessionID string) error {
+	session, err := t.getDBManager().TerminalSessionDao().GetBySessionID(sessionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bcode.ErrTerminalSessionNotFound
+		}
+		return errors.Wrap(err, "get terminal session")
+	}
+	if session.TenantID != tenantID {
+		return bcode.ErrTerminalSessionNotFound
+	}
+	if session.RecordingPath == "" {
+		return bcode.ErrTerminalRecordingNotReady
+	}
+	reader, err := t.getStorage().ReadFile(session.RecordingPath)
+	if err != nil {
+		return errors.Wrap(err, "read terminal recording")
+	}
+	defer reader.Close()
+	_, err = io.Copy(w, reader)
+	return err
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/pkg/component/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type terminalSessionTestManager struct {
+	db.Manager
+	keyValueDao        dbdao.KeyValueDao
+	terminalSessionDao dbdao.TerminalSessionDao
+}
+
+func (m terminalSessionTestManager) KeyValueDao() dbdao.KeyValueDao {
+	return m.keyValueDao
+}
+
+func (m terminalSessionTestManager) TerminalSessionDao() dbdao.TerminalSessionDao {
+	return m.terminalSessionDao
+}
+
+type terminalSessionTestKeyValueDao struct {
+	dbdao.KeyValueDao
+	values map[string]string
+}
+
+func (d *terminalSessionTestKeyValueDao) Get(key string) (*dbmodel.KeyValue, error) {
+	if v, ok := d.values[key]; ok {
+		return &dbmodel.KeyValue{K: key, V: v}, nil
+	}
+	return nil, nil
+}
+
+func (d *terminalSessionTestKeyValueDao) Put(key, value string) error {
+	d.values[key] = value
+	return nil
+}
+
+func (d *terminalSessionTestKeyValueDao) Delete(key string) error {
+	delete(d.values, key)
+	return nil
+}
+
+type terminalSessionTestDao struct {
+	dbdao.TerminalSessionDao
+	sessions []*dbmodel.TerminalSession
+}
+
+func (d *terminalSessionTestDao) GetBySessionID(sessionID string) (*dbmodel.TerminalSession, error) {
+	for _, session := range d.sessions {
+		if session.SessionID == sessionID {
+			return session, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type terminalSessionTestStorage struct {
+	storage.InterfaceStorage
+	files map[string]string
+}
+
+func (s *terminalSessionTestStorage) ReadFile(filePath string) (storage.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(s.files[filePath])), nil
+}
+
+// capability_id: rainbond.webcli.session-recording-switch
+func TestTerminalRecordingSwitch(t *testing.T) {
+	kv := &terminalSessionTestKeyValueDao{values: map[string]string{}}
+	action := &TerminalSessionAction{dbmanager: terminalSessionTestManager{keyValueDao: kv}}
+
+	require.NoError(t, action.SetTerminalRecording("tenant-a", true))
+	enable, err := action.GetTerminalRecording("tenant-a")
+	require.NoError(t, err)
+	assert.True(t, enable)
+	enable, _ = action.GetTerminalRecording("tenant-b")
+	assert.False(t, enable)
+
+	require.NoError(t, action.SetTerminalRecording("tenant-a", false))
+	enable, _ = action.GetTerminalRecording("tenant-a")
+	assert.False(t, enable)
+}
+
+// capability_id: rainbond.webcli.session-recording-playback
+func TestStreamTerminalRecording(t *testing.T) {
+	const cast = "{\"version\":2}\n[0.1,\"o\",\"$ \"]\n"
+	action := &TerminalSessionAction{
+		dbmanager: terminalSessionTestManager{terminalSessionDao: &terminalSessionTestDao{sessions: []*dbmodel.TerminalSession{
+			{SessionID: "done", TenantID: "tenant-a", RecordingPath: "/grdata/terminal-sessions/tenant-a/done.cast"},
+			{SessionID: "active", TenantID: "tenant-a"},
+		}}},
+		storage: &terminalSessionTestStorage{files: map[string]string{"/grdata/terminal-sessions/tenant-a/done.cast": cast}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, action.StreamTerminalRecording(&out, "tenant-a", "done"))
+	assert.Equal(t, cast, out.String())
+
+	assert.Equal(t, bcode.ErrTerminalSessionNotFound, action.StreamTerminalRecording(&out, "tenant-b", "done"))
+	assert.Equal(t, bcode.ErrTerminalSessionNotFound, action.StreamTerminalRecording(&out, "tenant-a", "missing"))
+	assert.Equal(t, bcode.ErrTerminalRecordingNotReady, action.StreamTerminalRecording(&out, "tenant-a", "active"))
+}
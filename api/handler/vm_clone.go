@@ -0,0 +1,420 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// VMCloneSourceSnapshot clones the disks from a VirtualMachineSnapshot
+	VMCloneSourceSnapshot = "snapshot"
+	// VMCloneSourceExport clones the root disk from a VirtualMachineExport
+	VMCloneSourceExport = "export"
+
+	vmExportTokenHeader = "x-kubevirt-export-token"
+)
+
+// vmCloneSkippedAttributes are bound to the source vm and never copied to the clone,
+// the clone starts without a fixed pod ip and gets its disks from vm_disk_imports.
+var vmCloneSkippedAttributes = map[string]bool{
+	vmFixedIPEnabledAttribute:             true,
+	vmFixedIPAttribute:                    true,
+	dbmodel.K8sAttributeNameVMDiskImports: true,
+}
+
+// VMCloneRequest creates a new vm component from a snapshot or an export of the vm
+type VMCloneRequest struct {
+	SourceType string `json:"source_type"`
+	SourceName string `json:"source_name"`
+	// TenantID the tenant of the new component, the tenant of the source component by default.
+	// Cloning into another tenant needs a scoped api token granted both tenants.
+	TenantID     string `json:"tenant_id"`
+	AppID        string `json:"app_id"`
+	ServiceCName string `json:"service_cname"`
+	ServiceAlias string `json:"service_alias"`
+}
+
+// VMCloneResult the component created by the clone
+type VMCloneResult struct {
+	TenantID      string   `json:"tenant_id"`
+	ServiceID     string   `json:"service_id"`
+	ServiceAlias  string   `json:"service_alias"`
+	DeployVersion string   `json:"deploy_version"`
+	ClonedVolumes []string `json:"cloned_volumes"`
+}
+
+// vmCloneDiskImport is stored in the vm_disk_imports attribute of the clone, the worker
+// creates the data volumes of the new vm from it.
+type vmCloneDiskImport struct {
+	VolumeName         string   `json:"volume_name"`
+	SourceType         string   `json:"source_type"`
+	SourceNamespace    string   `json:"source_namespace,omitempty"`
+	SourceName         string   `json:"source_name,omitempty"`
+	ImageURL           string   `json:"image_url,omitempty"`
+	Format             string   `json:"format,omitempty"`
+	CertConfigMap      string   `json:"cert_configmap,omitempty"`
+	SecretExtraHeaders []string `json:"secret_extra_headers,omitempty"`
+}
+
+// CloneVM creates a new vm component in the same or another tenant from a snapshot or an
+// export of the vm. Disks, resources, ports, envs and attributes are copied, the fixed pod
+// ip is dropped and the clone gets new mac addresses because they are not kept in the component.
+func (s *ServiceAction) CloneVM(ctx context.Context, serviceID string, req *VMCloneRequest) (*VMCloneResult, error) {
+	if req == nil || strings.TrimSpace(req.SourceName) == "" {
+		return nil, fmt.Errorf("clone source name is required")
+	}
+	if req.SourceType != VMCloneSourceSnapshot && req.SourceType != VMCloneSourceExport {
+		return nil, fmt.Errorf("unsupported clone source type %q", req.SourceType)
+	}
+	if strings.TrimSpace(req.AppID) == "" {
+		return nil, fmt.Errorf("app id is required")
+	}
+	dbmanager := s.getDBManager()
+	source, err := dbmanager.TenantServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if !source.IsVM() {
+		return nil, fmt.Errorf("component %s is not a vm", source.ServiceAlias)
+	}
+	tenantID := req.TenantID
+	if tenantID == "" {
+		tenantID = source.TenantID
+	}
+	if err := AuthorizeCrossTenant(ctx, source.TenantID, tenantID, dbmodel.APITokenVerbDeploy); err != nil {
+		return nil, err
+	}
+	tenant, err := dbmanager.TenantDao().GetTenantByUUID(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("get tenant %s: %v", tenantID, err)
+	}
+	app, err := dbmanager.ApplicationDao().GetAppByID(req.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if app.TenantID != tenant.UUID {
+		return nil, fmt.Errorf("app %s does not belong to tenant %s", req.AppID, tenant.Name)
+	}
+	vm, err := s.getVirtualMachineByServiceID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("service id is %v vm is not exist", serviceID)
+	}
+
+	newServiceID := util.NewUUID()
+	alias := strings.TrimSpace(req.ServiceAlias)
+	if alias == "" {
+		alias = "gr" + newServiceID[len(newServiceID)-6:]
+	}
+	var imports []vmCloneDiskImport
+	if req.SourceType == VMCloneSourceSnapshot {
+		imports, err = s.vmSnapshotDiskImports(vm, serviceID, req.SourceName)
+	} else {
+		imports, err = s.vmExportDiskImports(vm, serviceID, req.SourceName, tenant.Namespace, alias)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(imports) == 0 {
+		return nil, fmt.Errorf("no disk found in the %s %s", req.SourceType, req.SourceName)
+	}
+
+	sc, err := s.buildVMCloneService(source, tenantID, req.AppID, newServiceID, alias, req.ServiceCName, imports)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ServiceCreate(sc); err != nil {
+		return nil, fmt.Errorf("create clone component: %v", err)
+	}
+	deployVersion, err := s.copyVMCloneVersion(source, newServiceID)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("[vm-clone] service %s cloned from %s %s of service %s", newServiceID, req.SourceType, req.SourceName, serviceID)
+	result := &VMCloneResult{
+		TenantID:      tenantID,
+		ServiceID:     newServiceID,
+		ServiceAlias:  alias,
+		DeployVersion: deployVersion,
+	}
+	for _, item := range imports {
+		result.ClonedVolumes = append(result.ClonedVolumes, item.VolumeName)
+	}
+	return result, nil
+}
+
+// vmSnapshotDiskImports clones every disk backed up by the snapshot from its VolumeSnapshot
+func (s *ServiceAction) vmSnapshotDiskImports(vm *kubevirtv1.VirtualMachine, serviceID, name string) ([]vmCloneDiskImport, error) {
+	snapshot, err := s.getVMSnapshot(vm, serviceID, name)
+	if err != nil {
+		return nil, err
+	}
+	if !isVMSnapshotReady(snapshot) || snapshot.Status.VirtualMachineSnapshotContentName == nil {
+		return nil, fmt.Errorf("vm snapshot %s is not ready to use", name)
+	}
+	content, err := s.kubevirtClient.VirtualMachineSnapshotContent(vm.Namespace).Get(context.Background(), *snapshot.Status.VirtualMachineSnapshotContentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get vm snapshot content: %v", err)
+	}
+	volumeNames := vmClaimVolumeNames(&vm.Spec)
+	if source := content.Spec.Source.VirtualMachine; source != nil {
+		volumeNames = vmClaimVolumeNames(&source.Spec)
+	}
+	var imports []vmCloneDiskImport
+	for _, backup := range content.Spec.VolumeBackups {
+		if backup.VolumeSnapshotName == nil {
+			continue
+		}
+		volumeName := volumeNames[backup.PersistentVolumeClaim.Name]
+		if volumeName == "" {
+			volumeName = backup.PersistentVolumeClaim.Annotations["volume_name"]
+		}
+		if volumeName == "" {
+			logrus.Warnf("[vm-clone] skip volume %s of snapshot %s, no component volume found", backup.VolumeName, name)
+			continue
+		}
+		imports = append(imports, vmCloneDiskImport{
+			VolumeName:      volumeName,
+			SourceType:      VMCloneSourceSnapshot,
+			SourceNamespace: vm.Namespace,
+			SourceName:      *backup.VolumeSnapshotName,
+		})
+	}
+	return imports, nil
+}
+
+// vmExportDiskImports imports the exported root disk over http. The export token and the
+// certificate of the export proxy are copied to the namespace of the clone for the importer.
+func (s *ServiceAction) vmExportDiskImports(vm *kubevirtv1.VirtualMachine, serviceID, name, namespace, alias string) ([]vmCloneDiskImport, error) {
+	dynamicClient := vmExportDynamicClient()
+	if dynamicClient == nil {
+		return nil, fmt.Errorf("dynamic client is not initialized")
+	}
+	if s.kubeClient == nil {
+		return nil, fmt.Errorf("kube client is not initialized")
+	}
+	export, err := dynamicClient.Resource(vmExportGVR).Namespace(vm.Namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("vm export %s not found", name)
+		}
+		return nil, err
+	}
+	if export.GetLabels()["service_id"] != serviceID {
+		return nil, fmt.Errorf("vm export %s not found", name)
+	}
+	if phase := nestedString(export.Object, "status", "phase"); phase != "Ready" {
+		return nil, fmt.Errorf("vm export %s is %s, not ready", name, phase)
+	}
+	url := extractVMExportDownloadURL(export.Object)
+	if url == "" {
+		return nil, fmt.Errorf("vm export %s has no download url", name)
+	}
+	token, err := readVMExportDownloadToken(s.kubeClient, vm.Namespace, nestedString(export.Object, "spec", "tokenSecretRef"))
+	if err != nil {
+		return nil, err
+	}
+	volumeName := vmClaimVolumeNames(&vm.Spec)[nestedString(export.Object, "spec", "source", "name")]
+	if volumeName == "" {
+		volumeName = "disk"
+	}
+
+	tokenSecret := alias + "-clone-token"
+	if err := applyVMCloneSecret(s.kubeClient, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: tokenSecret, Namespace: namespace},
+		StringData: map[string]string{"header": vmExportTokenHeader + ":" + token},
+	}); err != nil {
+		return nil, fmt.Errorf("copy export token: %v", err)
+	}
+	item := vmCloneDiskImport{
+		VolumeName:         volumeName,
+		SourceType:         "http",
+		ImageURL:           url,
+		SecretExtraHeaders: []string{tokenSecret},
+	}
+	if cert := nestedString(export.Object, "status", "links", "internal", "cert"); cert != "" {
+		item.CertConfigMap = alias + "-clone-ca"
+		if err := applyVMCloneConfigMap(s.kubeClient, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: item.CertConfigMap, Namespace: namespace},
+			Data:       map[string]string{"ca.pem": cert},
+		}); err != nil {
+			return nil, fmt.Errorf("copy export certificate: %v", err)
+		}
+	}
+	return []vmCloneDiskImport{item}, nil
+}
+
+// vmClaimVolumeNames maps the claim names of the vm to the component volume names
+func vmClaimVolumeNames(spec *kubevirtv1.VirtualMachineSpec) map[string]string {
+	names := make(map[string]string, len(spec.DataVolumeTemplates))
+	for _, template := range spec.DataVolumeTemplates {
+		if volumeName := strings.TrimSpace(template.Annotations["volume_name"]); volumeName != "" {
+			names[template.Name] = volumeName
+		}
+	}
+	return names
+}
+
+// buildVMCloneService copies the component settings to the clone
+func (s *ServiceAction) buildVMCloneService(source *dbmodel.TenantServices, tenantID, appID, serviceID, alias, cname string, imports []vmCloneDiskImport) (*apimodel.ServiceStruct, error) {
+	dbmanager := s.getDBManager()
+	if cname == "" {
+		cname = source.ServiceName + "-clone"
+	}
+	sc := &apimodel.ServiceStruct{
+		TenantID:         tenantID,
+		ServiceID:        serviceID,
+		ServiceAlias:     alias,
+		ServiceName:      cname,
+		ServiceType:      source.ServiceType,
+		Comment:          source.Comment,
+		ContainerCPU:     source.ContainerCPU,
+		ContainerMemory:  source.ContainerMemory,
+		ContainerGPU:     source.ContainerGPU,
+		ExtendMethod:     source.ExtendMethod,
+		Replicas:         source.Replicas,
+		Category:         source.Category,
+		Namespace:        source.Namespace,
+		ServiceOrigin:    source.ServiceOrigin,
+		Kind:             source.Kind,
+		AppID:            appID,
+		K8sComponentName: alias,
+		JobStrategy:      source.JobStrategy,
+	}
+	ports, err := dbmanager.TenantServicesPortDao().GetPortsByServiceID(source.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range ports {
+		copied := *port
+		copied.ID = 0
+		// the k8s service names are unique in the namespace, regenerated for the clone
+		copied.K8sServiceName = ""
+		sc.PortsInfo = append(sc.PortsInfo, copied)
+	}
+	envs, err := dbmanager.TenantServiceEnvVarDao().GetServiceEnvs(source.ServiceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range envs {
+		copied := *env
+		copied.ID = 0
+		sc.EnvsInfo = append(sc.EnvsInfo, copied)
+	}
+	volumes, err := dbmanager.TenantServiceVolumeDao().GetTenantServiceVolumesByServiceID(source.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range volumes {
+		sc.VolumesInfo = append(sc.VolumesInfo, apimodel.TenantServiceVolumeStruct{
+			Category:           volume.Category,
+			VolumeType:         volume.VolumeType,
+			VolumeName:         volume.VolumeName,
+			VolumePath:         volume.VolumePath,
+			IsReadOnly:         volume.IsReadOnly,
+			VolumeCapacity:     volume.VolumeCapacity,
+			AccessMode:         volume.AccessMode,
+			SharePolicy:        volume.SharePolicy,
+			BackupPolicy:       volume.BackupPolicy,
+			ReclaimPolicy:      volume.ReclaimPolicy,
+			AllowExpansion:     volume.AllowExpansion,
+			VolumeProviderName: volume.VolumeProviderName,
+			Mode:               volume.Mode,
+		})
+	}
+	attributes, err := dbmanager.ComponentK8sAttributeDao().ListByComponentID(source.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attributes {
+		if vmCloneSkippedAttributes[attr.Name] {
+			continue
+		}
+		sc.ComponentK8sAttributes = append(sc.ComponentK8sAttributes, apimodel.ComponentK8sAttribute{
+			Name:           attr.Name,
+			SaveType:       attr.SaveType,
+			AttributeValue: attr.AttributeValue,
+		})
+	}
+	diskImports := make(map[string]vmCloneDiskImport, len(imports))
+	for _, item := range imports {
+		diskImports[item.VolumeName] = item
+	}
+	value, err := json.Marshal(diskImports)
+	if err != nil {
+		return nil, err
+	}
+	sc.ComponentK8sAttributes = append(sc.ComponentK8sAttributes, apimodel.ComponentK8sAttribute{
+		Name:           dbmodel.K8sAttributeNameVMDiskImports,
+		SaveType:       "json",
+		AttributeValue: string(value),
+	})
+	return sc, nil
+}
+
+// copyVMCloneVersion copies the deployed version of the source, the boot image of the vm
+// is defined by the version.
+func (s *ServiceAction) copyVMCloneVersion(source *dbmodel.TenantServices, serviceID string) (string, error) {
+	if source.DeployVersion == "" {
+		return "", nil
+	}
+	dbmanager := s.getDBManager()
+	version, err := dbmanager.VersionInfoDao().GetVersionByDeployVersion(source.DeployVersion, source.ServiceID)
+	if err != nil {
+		return "", fmt.Errorf("get deploy version of the source: %v", err)
+	}
+	copied := *version
+	copied.ID = 0
+	copied.ServiceID = serviceID
+	copied.EventID = util.NewUUID()
+	if err := dbmanager.VersionInfoDao().AddModel(&copied); err != nil {
+		return "", fmt.Errorf("copy deploy version: %v", err)
+	}
+	service, err := dbmanager.TenantServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		return "", err
+	}
+	service.DeployVersion = copied.BuildVersion
+	if err := dbmanager.TenantServiceDao().UpdateModel(service); err != nil {
+		return "", err
+	}
+	return copied.BuildVersion, nil
+}
+
+func applyVMCloneSecret(kubeClient kubernetes.Interface, secret *corev1.Secret) error {
+	secrets := kubeClient.CoreV1().Secrets(secret.Namespace)
+	if _, err := secrets.Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+		return err
+	}
+	return nil
+}
+
+func applyVMCloneConfigMap(kubeClient kubernetes.Interface, configMap *corev1.ConfigMap) error {
+	configMaps := kubeClient.CoreV1().ConfigMaps(configMap.Namespace)
+	if _, err := configMaps.Create(context.Background(), configMap, metav1.CreateOptions{}); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+		_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/api/util/bcode"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	snapshotv1 "kubevirt.io/api/snapshot/v1beta1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+type vmCloneTestManager struct {
+	resourceSyncTestManager
+	portDao   dbdao.TenantServicesPortDao
+	envDao    dbdao.TenantServiceEnvVarDao
+	volumeDao dbdao.TenantServiceVolumeDao
+}
+
+func (m vmCloneTestManager) TenantServicesPortDao() dbdao.TenantServicesPortDao {
+	return m.portDao
+}
+
+func (m vmCloneTestManager) TenantServiceEnvVarDao() dbdao.TenantServiceEnvVarDao {
+	return m.envDao
+}
+
+func (m vmCloneTestManager) TenantServiceVolumeDao() dbdao.TenantServiceVolumeDao {
+	return m.volumeDao
+}
+
+type vmClonePortDao struct {
+	dbdao.TenantServicesPortDao
+	ports []*dbmodel.TenantServicesPort
+}
+
+func (d *vmClonePortDao) GetPortsByServiceID(serviceID string) ([]*dbmodel.TenantServicesPort, error) {
+	return d.ports, nil
+}
+
+type vmCloneEnvDao struct {
+	dbdao.TenantServiceEnvVarDao
+	envs []*dbmodel.TenantServiceEnvVar
+}
+
+func (d *vmCloneEnvDao) GetServiceEnvs(serviceID string, scopes []string) ([]*dbmodel.TenantServiceEnvVar, error) {
+	return d.envs, nil
+}
+
+type vmCloneVolumeDao struct {
+	dbdao.TenantServiceVolumeDao
+	volumes []*dbmodel.TenantServiceVolume
+}
+
+func (d *vmCloneVolumeDao) GetTenantServiceVolumesByServiceID(serviceID string) ([]*dbmodel.TenantServiceVolume, error) {
+	return d.volumes, nil
+}
+
+// capability_id: rainbond.vm.clone.snapshot-disks
+func TestVMSnapshotDiskImports(t *testing.T) {
+	snapshot := testVMSnapshot("snap-1", time.Now(), false, snapshotv1.Succeeded)
+	contentName := "vmsnapshot-content-1"
+	snapshot.Status.VirtualMachineSnapshotContentName = &contentName
+	rootSnapshot, dataSnapshot := "vmsnapshot-root", "vmsnapshot-data"
+	content := &snapshotv1.VirtualMachineSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: contentName, Namespace: "demo-ns"},
+		Spec: snapshotv1.VirtualMachineSnapshotContentSpec{
+			Source: snapshotv1.SourceSpec{VirtualMachine: &snapshotv1.VirtualMachine{
+				Spec: kubevirtv1.VirtualMachineSpec{DataVolumeTemplates: []kubevirtv1.DataVolumeTemplateSpec{
+					{ObjectMeta: metav1.ObjectMeta{Name: "manual-1", Annotations: map[string]string{"volume_name": "disk"}}, Spec: cdiv1.DataVolumeSpec{}},
+				}},
+			}},
+			VolumeBackups: []snapshotv1.VolumeBackup{
+				{VolumeName: "manual-1", PersistentVolumeClaim: snapshotv1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "manual-1"}}, VolumeSnapshotName: &rootSnapshot},
+				{VolumeName: "manual-2", PersistentVolumeClaim: snapshotv1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "manual-2", Annotations: map[string]string{"volume_name": "data-1"}}}, VolumeSnapshotName: &dataSnapshot},
+				{VolumeName: "cdrom", PersistentVolumeClaim: snapshotv1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "cdrom"}}},
+			},
+		},
+	}
+	action, _ := newVMSnapshotTestAction(t, snapshot, content)
+	vm, _ := action.getVirtualMachineByServiceID("service-1")
+
+	imports, err := action.vmSnapshotDiskImports(vm, "service-1", "snap-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imports) != 2 {
+		t.Fatalf("expected 2 disk imports, got %+v", imports)
+	}
+	if imports[0].VolumeName != "disk" || imports[0].SourceName != rootSnapshot || imports[0].SourceNamespace != "demo-ns" || imports[0].SourceType != VMCloneSourceSnapshot {
+		t.Fatalf("unexpected root disk import %+v", imports[0])
+	}
+	if imports[1].VolumeName != "data-1" || imports[1].SourceName != dataSnapshot {
+		t.Fatalf("unexpected data disk import %+v", imports[1])
+	}
+}
+
+// capability_id: rainbond.vm.clone.copy-settings
+func TestBuildVMCloneServiceDropsFixedIP(t *testing.T) {
+	portDao := &vmClonePortDao{ports: []*dbmodel.TenantServicesPort{
+		{ServiceID: "service-1", ContainerPort: 22, Protocol: "tcp", PortAlias: "SSH", K8sServiceName: "demo-22"},
+	}}
+	envDao := &vmCloneEnvDao{envs: []*dbmodel.TenantServiceEnvVar{
+		{ServiceID: "service-1", AttrName: "MODE", AttrValue: "test"},
+	}}
+	volumeDao := &vmCloneVolumeDao{volumes: []*dbmodel.TenantServiceVolume{
+		{ServiceID: "service-1", VolumeName: "disk", VolumePath: "/disk", VolumeType: "vm-file", VolumeCapacity: 20, HostPath: "/grdata/old"},
+	}}
+	action := &ServiceAction{dbmanager: vmCloneTestManager{
+		resourceSyncTestManager: resourceSyncTestManager{attributeDao: &resourceSyncComponentK8sAttributeDao{attributes: map[string]*dbmodel.ComponentK8sAttributes{
+			"vm_os_name":                          {Name: "vm_os_name", SaveType: "string", AttributeValue: "ubuntu"},
+			vmFixedIPEnabledAttribute:             {Name: vmFixedIPEnabledAttribute, SaveType: "string", AttributeValue: "true"},
+			vmFixedIPAttribute:                    {Name: vmFixedIPAttribute, SaveType: "string", AttributeValue: "10.0.0.8"},
+			dbmodel.K8sAttributeNameVMDiskImports: {Name: dbmodel.K8sAttributeNameVMDiskImports, SaveType: "json", AttributeValue: `{"disk":{"image_url":"https://old"}}`},
+		}}},
+		portDao:   portDao,
+		envDao:    envDao,
+		volumeDao: volumeDao,
+	}}
+	source := &dbmodel.TenantServices{ServiceID: "service-1", TenantID: "tenant-1", ServiceName: "demo", ExtendMethod: "vm", ContainerCPU: 2000, ContainerMemory: 4096, Replicas: 1}
+
+	sc, err := action.buildVMCloneService(source, "tenant-2", "app-2", "service-2", "gr000002", "", []vmCloneDiskImport{
+		{VolumeName: "disk", SourceType: VMCloneSourceSnapshot, SourceNamespace: "demo-ns", SourceName: "vmsnapshot-root"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TenantID != "tenant-2" || sc.ServiceName != "demo-clone" || sc.ContainerCPU != 2000 || sc.ContainerMemory != 4096 || sc.ExtendMethod != "vm" {
+		t.Fatalf("unexpected clone service %+v", sc)
+	}
+	if len(sc.PortsInfo) != 1 || sc.PortsInfo[0].K8sServiceName != "" || sc.PortsInfo[0].ContainerPort != 22 {
+		t.Fatalf("unexpected ports %+v", sc.PortsInfo)
+	}
+	if len(sc.VolumesInfo) != 1 || sc.VolumesInfo[0].HostPath != "" || sc.VolumesInfo[0].VolumeCapacity != 20 {
+		t.Fatalf("unexpected volumes %+v", sc.VolumesInfo)
+	}
+	attributes := map[string]string{}
+	for _, attr := range sc.ComponentK8sAttributes {
+		attributes[attr.Name] = attr.AttributeValue
+	}
+	if _, ok := attributes[vmFixedIPAttribute]; ok {
+		t.Fatal("expected the fixed ip not to be copied")
+	}
+	if _, ok := attributes[vmFixedIPEnabledAttribute]; ok {
+		t.Fatal("expected the fixed ip switch not to be copied")
+	}
+	if attributes["vm_os_name"] != "ubuntu" {
+		t.Fatalf("expected vm attributes to be copied, got %v", attributes)
+	}
+	var imports map[string]vmCloneDiskImport
+	if err := json.Unmarshal([]byte(attributes[dbmodel.K8sAttributeNameVMDiskImports]), &imports); err != nil {
+		t.Fatal(err)
+	}
+	if imports["disk"].SourceName != "vmsnapshot-root" || imports["disk"].SourceType != VMCloneSourceSnapshot {
+		t.Fatalf("unexpected disk imports %+v", imports)
+	}
+}
+
+// capability_id: rainbond.vm.clone.claim-volume-names
+func TestVMClaimVolumeNames(t *testing.T) {
+	names := vmClaimVolumeNames(&kubevirtv1.VirtualMachineSpec{DataVolumeTemplates: []kubevirtv1.DataVolumeTemplateSpec{
+		{ObjectMeta: metav1.ObjectMeta{Name: "manual-1", Annotations: map[string]string{"volume_name": "disk"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "manual-2"}},
+	}})
+	if len(names) != 1 || names["manual-1"] != "disk" {
+		t.Fatalf("unexpected claim volume names %v", names)
+	}
+}
+
+// capability_id: rainbond.vm.clone.cross-tenant-grant
+func TestCloneVMRejectsOtherTenantWithoutGrant(t *testing.T) {
+	action := &ServiceAction{dbmanager: vmCloneTestManager{
+		resourceSyncTestManager: resourceSyncTestManager{serviceDao: &resourceSyncTenantServiceDao{
+			service: &dbmodel.TenantServices{ServiceID: "service-1", TenantID: "tenant-1", ServiceAlias: "demo", ExtendMethod: "vm"},
+		}},
+	}}
+	req := &VMCloneRequest{SourceType: VMCloneSourceSnapshot, SourceName: "snap-1", TenantID: "tenant-2", AppID: "app-2"}
+	if _, err := action.CloneVM(context.Background(), "service-1", req); err != bcode.ErrCrossTenantForbidden {
+		t.Fatalf("expected cross tenant clone to be forbidden, got %v", err)
+	}
+}
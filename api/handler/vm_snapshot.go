@@ -3,8 +3,17 @@ package handler
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	apimodel "github.com/goodrain/rainbond/api/model"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,6 +21,15 @@ import (
 	snapshotv1 "kubevirt.io/api/snapshot/v1beta1"
 )
 
+const (
+	// vmSnapshotScheduleLabel marks the snapshots taken by the schedule, only they are pruned
+	vmSnapshotScheduleLabel = "rainbond.io/vm-snapshot-schedule"
+	vmSnapshotWaitTimeout   = 10 * time.Minute
+)
+
+// vmSnapshotPollInterval the interval of waiting for the vm power state and the restore
+var vmSnapshotPollInterval = 3 * time.Second
+
 type VMSnapshotRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
@@ -21,6 +39,37 @@ type VMSnapshotStatus struct {
 	SnapshotName string `json:"snapshot_name"`
 }
 
+// VMSnapshotInfo a snapshot of the vm component
+type VMSnapshotInfo struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Phase        string `json:"phase"`
+	ReadyToUse   bool   `json:"ready_to_use"`
+	Scheduled    bool   `json:"scheduled"`
+	CreationTime string `json:"creation_time"`
+	Error        string `json:"error,omitempty"`
+}
+
+// VMSnapshotRestoreRequest restores the vm to a snapshot
+type VMSnapshotRestoreRequest struct {
+	// Start whether to start the vm after the restore, the vm is started only if it was running by default
+	Start *bool `json:"start,omitempty"`
+}
+
+// VMSnapshotRestoreStatus the restore started for the snapshot
+type VMSnapshotRestoreStatus struct {
+	RestoreName  string `json:"restore_name"`
+	SnapshotName string `json:"snapshot_name"`
+}
+
+// VMSnapshotScheduleRequest takes the snapshots on a cron schedule
+type VMSnapshotScheduleRequest struct {
+	Schedule string `json:"schedule"`
+	Timezone string `json:"timezone"`
+	KeepLast int    `json:"keep_last"`
+	Enable   bool   `json:"enable"`
+}
+
 func (s *ServiceAction) CreateVMSnapshot(serviceID string, req *VMSnapshotRequest) (*VMSnapshotStatus, error) {
 	if req == nil || strings.TrimSpace(req.Name) == "" {
 		return nil, fmt.Errorf("snapshot name is required")
@@ -69,3 +118,386 @@ func buildVMSnapshot(vm *kubevirtv1.VirtualMachine, snapshotName, description, s
 		},
 	}
 }
+
+// ListVMSnapshots lists the snapshots of the vm component, newest first
+func (s *ServiceAction) ListVMSnapshots(serviceID string) ([]*VMSnapshotInfo, error) {
+	vm, err := s.getVirtualMachineByServiceID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("service id is %v vm is not exist", serviceID)
+	}
+	snapshots, err := s.listVMSnapshots(vm.Namespace, "service_id="+serviceID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*VMSnapshotInfo, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		info := &VMSnapshotInfo{
+			Name:         snapshot.Name,
+			Description:  snapshot.Annotations["description"],
+			Phase:        string(snapshot.Status.Phase),
+			ReadyToUse:   isVMSnapshotReady(&snapshot),
+			Scheduled:    snapshot.Labels[vmSnapshotScheduleLabel] == "true",
+			CreationTime: snapshot.CreationTimestamp.Format(time.RFC3339),
+		}
+		if snapshot.Status.Error != nil && snapshot.Status.Error.Message != nil {
+			info.Error = *snapshot.Status.Error.Message
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// listVMSnapshots lists the snapshots matching the selector, newest first
+func (s *ServiceAction) listVMSnapshots(namespace, selector string) ([]snapshotv1.VirtualMachineSnapshot, error) {
+	list, err := s.kubevirtClient.VirtualMachineSnapshot(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshots := list.Items
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[j].CreationTimestamp.Before(&snapshots[i].CreationTimestamp)
+	})
+	return snapshots, nil
+}
+
+func isVMSnapshotReady(snapshot *snapshotv1.VirtualMachineSnapshot) bool {
+	return snapshot.Status != nil && snapshot.Status.ReadyToUse != nil && *snapshot.Status.ReadyToUse
+}
+
+// getVMSnapshot gets the snapshot of the vm component
+func (s *ServiceAction) getVMSnapshot(vm *kubevirtv1.VirtualMachine, serviceID, name string) (*snapshotv1.VirtualMachineSnapshot, error) {
+	snapshot, err := s.kubevirtClient.VirtualMachineSnapshot(vm.Namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("vm snapshot %s not found", name)
+		}
+		return nil, err
+	}
+	if snapshot.Labels["service_id"] != serviceID {
+		return nil, fmt.Errorf("vm snapshot %s not found", name)
+	}
+	return snapshot, nil
+}
+
+// DeleteVMSnapshot deletes the snapshot of the vm component
+func (s *ServiceAction) DeleteVMSnapshot(serviceID, name string) error {
+	vm, err := s.getVirtualMachineByServiceID(serviceID)
+	if err != nil {
+		return err
+	}
+	if vm == nil {
+		return fmt.Errorf("service id is %v vm is not exist", serviceID)
+	}
+	if _, err := s.getVMSnapshot(vm, serviceID, name); err != nil {
+		return err
+	}
+	err = s.kubevirtClient.VirtualMachineSnapshot(vm.Namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// RestoreVMSnapshot restores the vm component to the snapshot. The vm is stopped,
+// restored and started again in the background, the progress is written to the event log.
+func (s *ServiceAction) RestoreVMSnapshot(ctx context.Context, tenantID, serviceID, name string, req *VMSnapshotRestoreRequest) (*VMSnapshotRestoreStatus, error) {
+	vm, err := s.getVirtualMachineByServiceID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("service id is %v vm is not exist", serviceID)
+	}
+	snapshot, err := s.getVMSnapshot(vm, serviceID, name)
+	if err != nil {
+		return nil, err
+	}
+	if !isVMSnapshotReady(snapshot) {
+		return nil, fmt.Errorf("vm snapshot %s is not ready to use", name)
+	}
+	restore := buildVMRestore(vm, snapshot.Name, serviceID, time.Now())
+	// 恢复在后台进行，事件不随请求结束
+	eventCtx := context.Background()
+	var eventID string
+	if ev, ok := ctx.Value(ctxutil.ContextKey("event")).(*dbmodel.ServiceEvent); ok && ev != nil {
+		eventCtx = context.WithValue(eventCtx, ctxutil.ContextKey("event"), ev)
+		eventID = ev.EventID
+	}
+	start := isVMStartRequestedOrRunning(vm.Status.PrintableStatus)
+	if req != nil && req.Start != nil {
+		start = *req.Start
+	}
+	go func() {
+		logger := operationLogger(eventID)
+		defer releaseOperationLogger(logger)
+		err := s.restoreVMSnapshot(tenantID, serviceID, eventID, vm, restore, start, logger)
+		if err != nil {
+			logrus.Errorf("restore vm snapshot %s of service %s: %v", name, serviceID, err)
+			logger.Error(fmt.Sprintf("restore vm snapshot %s failure: %v", name, err), map[string]string{"step": "last", "status": "failure"})
+			_ = markOperationEvent(eventCtx, dbmodel.EventStatusFailure)
+			return
+		}
+		logger.Info(fmt.Sprintf("restore vm snapshot %s success", name), event.GetLastLoggerOption())
+		_ = markOperationEvent(eventCtx, dbmodel.EventStatusSuccess)
+	}()
+	return &VMSnapshotRestoreStatus{RestoreName: restore.Name, SnapshotName: snapshot.Name}, nil
+}
+
+// restoreVMSnapshot stops the vm, restores it and starts it again when start is true
+func (s *ServiceAction) restoreVMSnapshot(tenantID, serviceID, eventID string, vm *kubevirtv1.VirtualMachine, restore *snapshotv1.VirtualMachineRestore, start bool, logger event.Logger) error {
+	if vm.Status.PrintableStatus != kubevirtv1.VirtualMachineStatusStopped {
+		logger.Info("stopping the vm before the restore", map[string]string{"step": "vm-stop", "status": "running"})
+		if err := s.StopVM(nil, serviceID); err != nil {
+			return fmt.Errorf("stop vm: %v", err)
+		}
+		if err := s.waitVMStopped(serviceID); err != nil {
+			return err
+		}
+		logger.Info("the vm is stopped", map[string]string{"step": "vm-stop", "status": "success"})
+	}
+
+	logger.Info(fmt.Sprintf("restoring the vm to snapshot %s", restore.Spec.VirtualMachineSnapshotName), map[string]string{"step": "vm-restore", "status": "running"})
+	if _, err := s.kubevirtClient.VirtualMachineRestore(vm.Namespace).Create(context.Background(), restore, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("create vm restore: %v", err)
+	}
+	if err := s.waitVMRestoreComplete(vm.Namespace, restore.Name); err != nil {
+		return err
+	}
+	logger.Info("the vm is restored", map[string]string{"step": "vm-restore", "status": "success"})
+
+	if !start {
+		return nil
+	}
+	logger.Info("starting the vm", map[string]string{"step": "vm-start", "status": "running"})
+	var deployVersion string
+	if service, err := db.GetManager().TenantServiceDao().GetServiceByID(serviceID); err == nil {
+		deployVersion = service.DeployVersion
+	}
+	if err := s.StartOrCreateVM(nil, &apimodel.StartStopStruct{
+		TenantID:  tenantID,
+		ServiceID: serviceID,
+		EventID:   eventID,
+		TaskType:  "start",
+	}, deployVersion); err != nil {
+		return fmt.Errorf("start vm: %v", err)
+	}
+	logger.Info("the vm is started", map[string]string{"step": "vm-start", "status": "success"})
+	return nil
+}
+
+func (s *ServiceAction) waitVMStopped(serviceID string) error {
+	deadline := time.Now().Add(vmSnapshotWaitTimeout)
+	for {
+		vm, err := s.getVirtualMachineByServiceID(serviceID)
+		if err != nil {
+			return err
+		}
+		if vm == nil || vm.Status.PrintableStatus == kubevirtv1.VirtualMachineStatusStopped {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the vm to stop, status %s", vm.Status.PrintableStatus)
+		}
+		time.Sleep(vmSnapshotPollInterval)
+	}
+}
+
+func (s *ServiceAction) waitVMRestoreComplete(namespace, name string) error {
+	deadline := time.Now().Add(vmSnapshotWaitTimeout)
+	for {
+		restore, err := s.kubevirtClient.VirtualMachineRestore(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if restore.Status != nil {
+			if restore.Status.Complete != nil && *restore.Status.Complete {
+				return nil
+			}
+			for _, condition := range restore.Status.Conditions {
+				if condition.Type == snapshotv1.ConditionFailure && condition.Status == corev1.ConditionTrue {
+					return fmt.Errorf("vm restore failed: %s", condition.Reason)
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the vm restore %s", name)
+		}
+		time.Sleep(vmSnapshotPollInterval)
+	}
+}
+
+func buildVMRestore(vm *kubevirtv1.VirtualMachine, snapshotName, serviceID string, now time.Time) *snapshotv1.VirtualMachineRestore {
+	apiGroup := kubevirtv1.VirtualMachineGroupVersionKind.Group
+	return &snapshotv1.VirtualMachineRestore{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: snapshotv1.SchemeGroupVersion.String(),
+			Kind:       "VirtualMachineRestore",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-restore-%s", snapshotName, now.Format("20060102150405")),
+			Namespace: vm.Namespace,
+			Labels:    map[string]string{"service_id": serviceID},
+		},
+		Spec: snapshotv1.VirtualMachineRestoreSpec{
+			Target: corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VirtualMachine",
+				Name:     vm.Name,
+			},
+			VirtualMachineSnapshotName: snapshotName,
+		},
+	}
+}
+
+func operationLogger(eventID string) event.Logger {
+	if manager := event.GetManager(); manager != nil && eventID != "" {
+		return manager.GetLogger(eventID)
+	}
+	return event.NewLogger(eventID, nil)
+}
+
+func releaseOperationLogger(logger event.Logger) {
+	if manager := event.GetManager(); manager != nil {
+		manager.ReleaseLogger(logger)
+	}
+}
+
+// GetVMSnapshotSchedule returns the snapshot schedule of the vm component, nil if not set
+func (s *ServiceAction) GetVMSnapshotSchedule(serviceID string) (*dbmodel.VMSnapshotSchedule, error) {
+	schedule, err := db.GetManager().VMSnapshotScheduleDao().GetByServiceID(serviceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// SetVMSnapshotSchedule creates or updates the snapshot schedule of the vm component
+func (s *ServiceAction) SetVMSnapshotSchedule(tenantID, serviceID string, req *VMSnapshotScheduleRequest) (*dbmodel.VMSnapshotSchedule, error) {
+	if req == nil || strings.TrimSpace(req.Schedule) == "" {
+		return nil, fmt.Errorf("snapshot schedule is required")
+	}
+	if req.KeepLast < 0 {
+		return nil, fmt.Errorf("keep_last must not be negative")
+	}
+	schedule := &dbmodel.VMSnapshotSchedule{
+		ServiceID: serviceID,
+		TenantID:  tenantID,
+		Schedule:  strings.TrimSpace(req.Schedule),
+		Timezone:  req.Timezone,
+		KeepLast:  req.KeepLast,
+		Enable:    req.Enable,
+	}
+	if _, err := schedule.Parse(); err != nil {
+		return nil, fmt.Errorf("invalid snapshot schedule %q: %v", req.Schedule, err)
+	}
+	old, err := s.GetVMSnapshotSchedule(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if old == nil {
+		if err := db.GetManager().VMSnapshotScheduleDao().AddModel(schedule); err != nil {
+			return nil, err
+		}
+		return schedule, nil
+	}
+	old.Schedule, old.Timezone, old.KeepLast, old.Enable = schedule.Schedule, schedule.Timezone, schedule.KeepLast, schedule.Enable
+	if err := db.GetManager().VMSnapshotScheduleDao().UpdateModel(old); err != nil {
+		return nil, err
+	}
+	return old, nil
+}
+
+// DeleteVMSnapshotSchedule deletes the snapshot schedule, the taken snapshots are kept
+func (s *ServiceAction) DeleteVMSnapshotSchedule(serviceID string) error {
+	return db.GetManager().VMSnapshotScheduleDao().DeleteByServiceID(serviceID)
+}
+
+// StartVMSnapshotScheduler takes the scheduled vm snapshots every minute until ctx is done
+func (s *ServiceAction) StartVMSnapshotScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runVMSnapshotSchedules(now)
+		}
+	}
+}
+
+func (s *ServiceAction) runVMSnapshotSchedules(now time.Time) {
+	schedules, err := db.GetManager().VMSnapshotScheduleDao().ListEnableOnes()
+	if err != nil {
+		logrus.Errorf("list vm snapshot schedules: %v", err)
+		return
+	}
+	for _, schedule := range schedules {
+		if err := s.runVMSnapshotSchedule(schedule, now); err != nil {
+			logrus.Errorf("run vm snapshot schedule of service %s: %v", schedule.ServiceID, err)
+		}
+	}
+}
+
+func (s *ServiceAction) runVMSnapshotSchedule(schedule *dbmodel.VMSnapshotSchedule, now time.Time) error {
+	cronSchedule, err := schedule.Parse()
+	if err != nil {
+		return err
+	}
+	last := schedule.CreatedAt
+	if schedule.LastRunTime != nil {
+		last = *schedule.LastRunTime
+	}
+	if cronSchedule.Next(last.In(schedule.Location())).After(now) {
+		return nil
+	}
+	claimed, err := db.GetManager().VMSnapshotScheduleDao().ClaimRun(schedule.ServiceID, schedule.LastRunTime, now)
+	if err != nil || !claimed {
+		return err
+	}
+	vm, err := s.getVirtualMachineByServiceID(schedule.ServiceID)
+	if err != nil {
+		return err
+	}
+	if vm == nil {
+		return nil
+	}
+	snapshot := buildVMSnapshot(vm, fmt.Sprintf("%s-auto-%s", vm.Name, now.In(schedule.Location()).Format("20060102150405")), "scheduled snapshot", schedule.ServiceID)
+	snapshot.Labels[vmSnapshotScheduleLabel] = "true"
+	if _, err := s.kubevirtClient.VirtualMachineSnapshot(vm.Namespace).Create(context.Background(), snapshot, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return s.pruneVMSnapshots(vm.Namespace, schedule)
+}
+
+// pruneVMSnapshots deletes the scheduled snapshots beyond the retention count,
+// the manual snapshots and the ones in progress are never deleted.
+func (s *ServiceAction) pruneVMSnapshots(namespace string, schedule *dbmodel.VMSnapshotSchedule) error {
+	if schedule.KeepLast <= 0 {
+		return nil
+	}
+	snapshots, err := s.listVMSnapshots(namespace, fmt.Sprintf("service_id=%s,%s=true", schedule.ServiceID, vmSnapshotScheduleLabel))
+	if err != nil {
+		return err
+	}
+	for i := schedule.KeepLast; i < len(snapshots); i++ {
+		snapshot := snapshots[i]
+		if snapshot.Status != nil && snapshot.Status.Phase == snapshotv1.InProgress {
+			continue
+		}
+		err := s.kubevirtClient.VirtualMachineSnapshot(namespace).Delete(context.Background(), snapshot.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		logrus.Infof("pruned vm snapshot %s/%s of service %s", namespace, snapshot.Name, schedule.ServiceID)
+	}
+	return nil
+}
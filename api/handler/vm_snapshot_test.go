@@ -3,13 +3,19 @@ package handler
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
 	kubecli "kubevirt.io/client-go/kubecli"
+	kubevirtfake "kubevirt.io/client-go/kubevirt/fake"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	snapshotv1 "kubevirt.io/api/snapshot/v1beta1"
 )
@@ -109,3 +115,136 @@ func TestCreateVMSnapshot(t *testing.T) {
 		t.Fatalf("unexpected created snapshot source %#v", snapshotClient.created.Spec.Source)
 	}
 }
+
+func newVMSnapshotTestAction(t *testing.T, objects ...runtime.Object) (*ServiceAction, *kubevirtfake.Clientset) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	clientset := kubevirtfake.NewSimpleClientset(objects...)
+	mockClient := kubecli.NewMockKubevirtClient(ctrl)
+	mockClient.EXPECT().VirtualMachineSnapshot("demo-ns").Return(clientset.SnapshotV1beta1().VirtualMachineSnapshots("demo-ns")).AnyTimes()
+	mockClient.EXPECT().VirtualMachineRestore("demo-ns").Return(clientset.SnapshotV1beta1().VirtualMachineRestores("demo-ns")).AnyTimes()
+	mockClient.EXPECT().VirtualMachineSnapshotContent("demo-ns").Return(clientset.SnapshotV1beta1().VirtualMachineSnapshotContents("demo-ns")).AnyTimes()
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-vm", Namespace: "demo-ns"},
+		Status:     kubevirtv1.VirtualMachineStatus{PrintableStatus: kubevirtv1.VirtualMachineStatusStopped},
+	}
+	return &ServiceAction{
+		kubevirtClient: mockClient,
+		getVirtualMachineByServiceIDHook: func(serviceID string) (*kubevirtv1.VirtualMachine, error) {
+			return vm, nil
+		},
+	}, clientset
+}
+
+func testVMSnapshot(name string, created time.Time, scheduled bool, phase snapshotv1.VirtualMachineSnapshotPhase) *snapshotv1.VirtualMachineSnapshot {
+	ready := phase == snapshotv1.Succeeded
+	snapshot := &snapshotv1.VirtualMachineSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "demo-ns",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            map[string]string{"service_id": "service-1"},
+		},
+		Status: &snapshotv1.VirtualMachineSnapshotStatus{Phase: phase, ReadyToUse: &ready},
+	}
+	if scheduled {
+		snapshot.Labels[vmSnapshotScheduleLabel] = "true"
+	}
+	return snapshot
+}
+
+// capability_id: rainbond.vm.snapshot.list
+func TestListVMSnapshotsNewestFirst(t *testing.T) {
+	now := time.Now()
+	action, _ := newVMSnapshotTestAction(t,
+		testVMSnapshot("snap-old", now.Add(-2*time.Hour), false, snapshotv1.Succeeded),
+		testVMSnapshot("snap-new", now, true, snapshotv1.InProgress),
+	)
+
+	snapshots, err := action.ListVMSnapshots("service-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "snap-new" || snapshots[1].Name != "snap-old" {
+		t.Fatalf("unexpected snapshots %+v", snapshots)
+	}
+	if !snapshots[0].Scheduled || snapshots[0].ReadyToUse || !snapshots[1].ReadyToUse {
+		t.Fatalf("unexpected snapshot state %+v %+v", snapshots[0], snapshots[1])
+	}
+}
+
+// capability_id: rainbond.vm.snapshot.prune
+func TestPruneVMSnapshotsKeepsLatestScheduled(t *testing.T) {
+	now := time.Now()
+	action, clientset := newVMSnapshotTestAction(t,
+		testVMSnapshot("auto-1", now.Add(-3*time.Hour), true, snapshotv1.Succeeded),
+		testVMSnapshot("auto-2", now.Add(-2*time.Hour), true, snapshotv1.Succeeded),
+		testVMSnapshot("auto-3", now.Add(-time.Hour), true, snapshotv1.Succeeded),
+		testVMSnapshot("manual", now.Add(-4*time.Hour), false, snapshotv1.Succeeded),
+	)
+
+	err := action.pruneVMSnapshots("demo-ns", &dbmodel.VMSnapshotSchedule{ServiceID: "service-1", KeepLast: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := clientset.SnapshotV1beta1().VirtualMachineSnapshots("demo-ns").List(context.Background(), metav1.ListOptions{})
+	names := map[string]bool{}
+	for _, snapshot := range list.Items {
+		names[snapshot.Name] = true
+	}
+	if len(names) != 3 || names["auto-1"] || !names["manual"] {
+		t.Fatalf("unexpected snapshots after prune %v", names)
+	}
+}
+
+// capability_id: rainbond.vm.snapshot.restore
+func TestRestoreVMSnapshotWaitsForCompletion(t *testing.T) {
+	defer func(interval time.Duration) { vmSnapshotPollInterval = interval }(vmSnapshotPollInterval)
+	vmSnapshotPollInterval = time.Millisecond
+
+	action, clientset := newVMSnapshotTestAction(t)
+	clientset.PrependReactor("create", "virtualmachinerestores", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		restore := a.(k8stesting.CreateAction).GetObject().(*snapshotv1.VirtualMachineRestore)
+		complete := true
+		restore.Status = &snapshotv1.VirtualMachineRestoreStatus{Complete: &complete}
+		return false, nil, nil
+	})
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-vm", Namespace: "demo-ns"},
+		Status:     kubevirtv1.VirtualMachineStatus{PrintableStatus: kubevirtv1.VirtualMachineStatusStopped},
+	}
+	restore := buildVMRestore(vm, "snap-1", "service-1", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if restore.Name != "snap-1-restore-20240102030405" || restore.Spec.Target.Name != "demo-vm" || restore.Spec.Target.Kind != "VirtualMachine" {
+		t.Fatalf("unexpected restore %+v", restore)
+	}
+
+	if err := action.restoreVMSnapshot("tenant-1", "service-1", "", vm, restore, false, event.NewLogger("", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientset.SnapshotV1beta1().VirtualMachineRestores("demo-ns").Get(context.Background(), restore.Name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected restore to be created: %v", err)
+	}
+}
+
+// capability_id: rainbond.vm.snapshot.restore-not-ready
+func TestRestoreVMSnapshotRequiresReadySnapshot(t *testing.T) {
+	action, _ := newVMSnapshotTestAction(t, testVMSnapshot("snap-1", time.Now(), false, snapshotv1.InProgress))
+
+	if _, err := action.RestoreVMSnapshot(context.Background(), "tenant-1", "service-1", "snap-1", nil); err == nil {
+		t.Fatal("expected restore of a snapshot in progress to fail")
+	}
+}
+
+// capability_id: rainbond.vm.snapshot.schedule-validate
+func TestSetVMSnapshotScheduleValidates(t *testing.T) {
+	action := &ServiceAction{}
+	for _, req := range []*VMSnapshotScheduleRequest{
+		{Schedule: "not a cron"},
+		{Schedule: "0 2 * * *", Timezone: "Nowhere/City"},
+		{Schedule: "0 2 * * *", KeepLast: -1},
+	} {
+		if _, err := action.SetVMSnapshotSchedule("tenant-1", "service-1", req); err == nil {
+			t.Fatalf("expected %+v to be rejected", req)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/middleware"
+	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/sirupsen/logrus"
+)
+
+// auditBodyLimit 审计记录保存的请求体上限，超出时不保存请求体
+const auditBodyLimit = 64 * 1024
+
+// auditRedacted 敏感字段的值在审计记录中被替换
+const auditRedacted = "******"
+
+var auditSensitiveKeys = []string{"password", "passwd", "secret", "token", "private_key", "credential"}
+
+// auditValueKeys 环境变量、组件属性、配置组等键值对的值字段，名称无法判断是否敏感，总是脱敏
+var auditValueKeys = map[string]bool{"env_value": true, "attr_value": true, "item_value": true, "config_value": true, "value": true}
+
+type auditRecorder interface {
+	Record(log *dbmodel.AuditLog)
+}
+
+// defaultAuditRecorder 按请求顺序在后台写入审计记录
+var defaultAuditRecorder auditRecorder = &asyncAuditRecorder{queue: make(chan *dbmodel.AuditLog, 1024)}
+
+type asyncAuditRecorder struct {
+	once  sync.Once
+	queue chan *dbmodel.AuditLog
+}
+
+// Record queues the audit log, it blocks when the queue is full so that no record is dropped
+func (a *asyncAuditRecorder) Record(log *dbmodel.AuditLog) {
+	a.once.Do(func() {
+		go a.run()
+	})
+	a.queue <- log
+}
+
+func (a *asyncAuditRecorder) run() {
+	for log := range a.queue {
+		if err := db.GetManager().AuditLogDao().Append(log); err != nil {
+			logrus.Errorf("append audit log of %s %s: %v", log.Method, log.Path, err)
+		}
+	}
+}
+
+// Audit 记录所有非 GET 请求的操作者、租户、组件、请求内容与结果
+func Audit(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log := &dbmodel.AuditLog{
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			ClientIP: auditClientIP(r.RemoteAddr),
+		}
+		log.CreatedAt = time.Now()
+		log.Operator, log.RequestBody = readAuditBody(r)
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			p := recover()
+			log.StatusCode = ww.Status()
+			if p != nil {
+				log.StatusCode = http.StatusInternalServerError
+			} else if log.StatusCode == 0 {
+				log.StatusCode = http.StatusOK
+			}
+			log.Result = dbmodel.AuditResultSuccess
+			if log.StatusCode >= http.StatusBadRequest {
+				log.Result = dbmodel.AuditResultFailure
+			}
+			defaultAuditRecorder.Record(log)
+			if p != nil {
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), ctxutil.ContextKey("audit_log"), log)))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// auditLogFrom returns the audit log of the request, nil if the request is not audited
+func auditLogFrom(r *http.Request) *dbmodel.AuditLog {
+	log, _ := r.Context().Value(ctxutil.ContextKey("audit_log")).(*dbmodel.AuditLog)
+	return log
+}
+
+func auditClientIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// readAuditBody reads the operator and the redacted body, the request body is restored for the handlers
+func readAuditBody(r *http.Request) (string, string) {
+	if r.Body == nil {
+		return "", ""
+	}
+	if contentType := r.Header.Get("Content-Type"); strings.HasPrefix(contentType, "multipart/") || strings.HasPrefix(contentType, "application/octet-stream") {
+		return "", ""
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, auditBodyLimit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || len(body) > auditBodyLimit {
+		return "", ""
+	}
+	if contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); contentType == "application/x-www-form-urlencoded" {
+		return readAuditForm(body)
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return "", auditBodyDigest(body)
+	}
+	var operator string
+	if m, ok := data.(map[string]interface{}); ok {
+		operator, _ = m["operator"].(string)
+	}
+	redacted, _ := json.Marshal(redactAuditValue(data))
+	return operator, string(redacted)
+}
+
+// readAuditForm 脱敏表单请求体中的敏感字段
+func readAuditForm(body []byte) (string, string) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", auditBodyDigest(body)
+	}
+	for key := range form {
+		if isAuditSensitiveKey(key) || auditValueKeys[strings.ToLower(key)] {
+			form[key] = []string{auditRedacted}
+		}
+	}
+	return form.Get("operator"), form.Encode()
+}
+
+// auditBodyDigest 无法识别结构的请求体只记录其摘要，避免保存其中的敏感信息
+func auditBodyDigest(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+}
+
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		sensitiveName := hasAuditSensitiveName(v)
+		for key, item := range v {
+			if isAuditSensitiveKey(key) || isAuditValueKey(key, sensitiveName) {
+				v[key] = auditRedacted
+				continue
+			}
+			v[key] = redactAuditValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactAuditValue(item)
+		}
+	}
+	return value
+}
+
+// hasAuditSensitiveName 键值对的名称字段（如 attr_name、item_key）是否为敏感名称
+func hasAuditSensitiveName(m map[string]interface{}) bool {
+	for key, item := range m {
+		key = strings.ToLower(key)
+		if name, ok := item.(string); ok && (strings.HasSuffix(key, "name") || strings.HasSuffix(key, "key")) && isAuditSensitiveKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuditValueKey 值字段总是脱敏，其他 *_value 字段在名称敏感时脱敏
+func isAuditValueKey(key string, sensitiveName bool) bool {
+	key = strings.ToLower(key)
+	return auditValueKeys[key] || (sensitiveName && strings.HasSuffix(key, "value"))
+}
+
+func isAuditSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range auditSensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+)
+
+type auditTestRecorder struct {
+	logs []*dbmodel.AuditLog
+}
+
+func (a *auditTestRecorder) Record(log *dbmodel.AuditLog) {
+	a.logs = append(a.logs, log)
+}
+
+// capability_id: rainbond.audit-log.capture-mutating-requests
+func TestAuditRecordsMutatingRequests(t *testing.T) {
+	t.Setenv("TOKEN", "cluster-token")
+	recorder := &auditTestRecorder{}
+	defer func(old auditRecorder) { defaultAuditRecorder = old }(defaultAuditRecorder)
+	defaultAuditRecorder = recorder
+	db.SetTestManager(apiTokenTestManager{tenantDao: &apiTokenTestTenantDao{}, apiTokenDao: &apiTokenTestDao{}})
+	defer db.SetTestManager(nil)
+
+	var handlerBody string
+	r := chi.NewRouter()
+	r.Use(Audit)
+	r.Use(FullToken)
+	r.Route("/v2/tenants/{tenant_name}", func(r chi.Router) {
+		r.Use(InitTenant)
+		r.Get("/envs", func(w http.ResponseWriter, _ *http.Request) {})
+		r.Put("/envs", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			handlerBody = string(body)
+			w.WriteHeader(http.StatusConflict)
+		})
+	})
+
+	body := `{"operator":"alice","env_name":"DB_PASSWORD","attr_value":"x","auth":{"password":"p","user":"u"}}`
+	req := httptest.NewRequest(http.MethodPut, "/v2/tenants/team-a/envs", strings.NewReader(body))
+	req.Header.Set("Authorization", "Token cluster-token")
+	req.RemoteAddr = "10.0.0.1:34567"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	get := httptest.NewRequest(http.MethodGet, "/v2/tenants/team-a/envs", nil)
+	get.Header.Set("Authorization", "Token cluster-token")
+	r.ServeHTTP(httptest.NewRecorder(), get)
+
+	if handlerBody != body {
+		t.Fatalf("expected the handler to read the original body, got %q", handlerBody)
+	}
+	if len(recorder.logs) != 1 {
+		t.Fatalf("expected only the PUT request audited, got %d records", len(recorder.logs))
+	}
+	log := recorder.logs[0]
+	if log.Operator != "alice" || log.TokenName != "admin" || log.ClientIP != "10.0.0.1" {
+		t.Fatalf("unexpected actor of the audit log: %#v", log)
+	}
+	if log.TenantID != "team-a-id" || log.TenantName != "team-a" || log.Path != "/v2/tenants/team-a/envs" {
+		t.Fatalf("unexpected target of the audit log: %#v", log)
+	}
+	if log.StatusCode != http.StatusConflict || log.Result != dbmodel.AuditResultFailure {
+		t.Fatalf("expected a failed result with status 409, got %d %s", log.StatusCode, log.Result)
+	}
+	if strings.Contains(log.RequestBody, `"p"`) || !strings.Contains(log.RequestBody, `"password":"******"`) || !strings.Contains(log.RequestBody, `"user":"u"`) {
+		t.Fatalf("expected the password redacted in the request body, got %s", log.RequestBody)
+	}
+}
+
+// capability_id: rainbond.audit-log.redact-request-body
+func TestReadAuditBodyRedactsNonJSONBodies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v2/tenants/team-a/envs", strings.NewReader("operator=alice&password=p&user=u"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	operator, body := readAuditBody(req)
+	if operator != "alice" || body != "operator=alice&password=%2A%2A%2A%2A%2A%2A&user=u" {
+		t.Fatalf("expected the password redacted in the form body, got %q %q", operator, body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v2/tenants/team-a/envs", strings.NewReader("token: t"))
+	req.Header.Set("Content-Type", "text/plain")
+	operator, body = readAuditBody(req)
+	if operator != "" || !strings.HasPrefix(body, "sha256:") || strings.Contains(body, "token") {
+		t.Fatalf("expected only a digest of the plain body, got %q %q", operator, body)
+	}
+	if raw, _ := io.ReadAll(req.Body); string(raw) != "token: t" {
+		t.Fatalf("expected the body restored for the handler, got %q", raw)
+	}
+}
+
+// capability_id: rainbond.audit-log.redact-key-value-body
+func TestReadAuditBodyRedactsKeyValuePairs(t *testing.T) {
+	body := `{"operator":"alice","env_name":"DB_HOST","env_value":"s3cr3t","attrs":[{"attr_name":"SERVICE_TOKEN","attribute_value":"t0ken","label_value":"web"}],"items":[{"item_key":"user","item_value":"p4ss"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v2/tenants/team-a/services/web/env", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	operator, redacted := readAuditBody(req)
+	if operator != "alice" {
+		t.Fatalf("unexpected operator %q", operator)
+	}
+	for _, secret := range []string{"s3cr3t", "t0ken", "p4ss"} {
+		if strings.Contains(redacted, secret) {
+			t.Fatalf("expected %s redacted, got %s", secret, redacted)
+		}
+	}
+	for _, kept := range []string{"DB_HOST", "SERVICE_TOKEN", `"label_value":"******"`} {
+		if !strings.Contains(redacted, kept) {
+			t.Fatalf("expected %s in the audit body, got %s", kept, redacted)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v2/tenants/team-a/services/web/env", strings.NewReader("env_name=DB_HOST&env_value=s3cr3t"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, redacted = readAuditBody(req); redacted != "env_name=DB_HOST&env_value=%2A%2A%2A%2A%2A%2A" {
+		t.Fatalf("expected the env value redacted in the form body, got %q", redacted)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/goodrain/rainbond/api/handler"
 	"github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/api/util/bcode"
 	ctxutil "github.com/goodrain/rainbond/api/util/ctx"
 	"github.com/goodrain/rainbond/db"
 	dbmodel "github.com/goodrain/rainbond/db/model"
@@ -71,6 +72,12 @@ func InitTenant(next http.Handler) http.Handler {
 			httputil.ReturnError(r, w, 500, "get assign tenant uuid failed")
 			return
 		}
+		if log := auditLogFrom(r); log != nil {
+			log.TenantID, log.TenantName = tenant.UUID, tenantName
+		}
+		if !authorizeAPIToken(w, r, tenant.UUID) {
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), ctxutil.ContextKey("tenant_name"), tenantName)
 		ctx = context.WithValue(ctx, ctxutil.ContextKey("tenant_id"), tenant.UUID)
@@ -108,6 +115,12 @@ func InitService(next http.Handler) http.Handler {
 			httputil.ReturnError(r, w, 500, "get service id error")
 			return
 		}
+		if log := auditLogFrom(r); log != nil {
+			log.ServiceID, log.ServiceAlias = service.ServiceID, serviceAlias
+		}
+		if !authorizeAPIToken(w, r, service.TenantID) {
+			return
+		}
 		serviceID := service.ServiceID
 		ctx := context.WithValue(r.Context(), ctxutil.ContextKey("service_alias"), serviceAlias)
 		ctx = context.WithValue(ctx, ctxutil.ContextKey("service_id"), serviceID)
@@ -198,15 +211,19 @@ func cleanupServices(namespace, serviceAlias string) error {
 	return nil
 }
 
-// InitApplication -
+// InitApplication 加载应用，应用不属于当前租户时按不存在处理
 func InitApplication(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		appID := chi.URLParam(r, "app_id")
-		tenantApp, err := handler.GetApplicationHandler().GetAppByID(appID)
+		tenantApp, err := db.GetManager().ApplicationDao().GetAppByID(appID)
 		if err != nil {
 			httputil.ReturnBcodeError(r, w, err)
 			return
 		}
+		if tenantID, ok := r.Context().Value(ctxutil.ContextKey("tenant_id")).(string); ok && tenantApp.TenantID != tenantID {
+			httputil.ReturnBcodeError(r, w, bcode.ErrApplicationNotFound)
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), ctxutil.ContextKey("app_id"), tenantApp.AppID)
 		ctx = context.WithValue(ctx, ctxutil.ContextKey("application"), tenantApp)
@@ -5,6 +5,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/goodrain/rainbond/api/handler"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
 )
 
 type tokenTestBody struct {
@@ -112,3 +121,194 @@ func TestFullTokenAllowsOnlyConfiguredRegionToken(t *testing.T) {
 		})
 	}
 }
+
+// capability_id: rainbond.region-api.git-webhook-no-token
+func TestFullTokenLetsGitWebhookDeliveriesThrough(t *testing.T) {
+	t.Setenv("TOKEN", "cluster-token")
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/git-webhooks/github", nil)
+	resp := httptest.NewRecorder()
+	FullToken(next).ServeHTTP(resp, req)
+	if !called || resp.Code != http.StatusNoContent {
+		t.Fatalf("expected the git webhook delivery to reach the handler, got status %d", resp.Code)
+	}
+
+	called = false
+	req = httptest.NewRequest(http.MethodPost, "/v2/git-webhooks-admin", nil)
+	resp = httptest.NewRecorder()
+	FullToken(next).ServeHTTP(resp, req)
+	if called || resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected other paths to require the token, got status %d", resp.Code)
+	}
+}
+
+type apiTokenTestManager struct {
+	db.Manager
+	tenantDao   dbdao.TenantDao
+	apiTokenDao dbdao.APITokenDao
+	appDao      dbdao.ApplicationDao
+}
+
+func (m apiTokenTestManager) TenantDao() dbdao.TenantDao {
+	return m.tenantDao
+}
+
+func (m apiTokenTestManager) APITokenDao() dbdao.APITokenDao {
+	return m.apiTokenDao
+}
+
+func (m apiTokenTestManager) ApplicationDao() dbdao.ApplicationDao {
+	return m.appDao
+}
+
+type apiTokenTestAppDao struct {
+	dbdao.ApplicationDao
+	apps map[string]*dbmodel.Application
+}
+
+func (d *apiTokenTestAppDao) GetAppByID(appID string) (*dbmodel.Application, error) {
+	if app, ok := d.apps[appID]; ok {
+		return app, nil
+	}
+	return nil, bcode.ErrApplicationNotFound
+}
+
+type apiTokenTestTenantDao struct {
+	dbdao.TenantDao
+}
+
+func (d *apiTokenTestTenantDao) GetTenantIDByName(name string) (*dbmodel.Tenants, error) {
+	return &dbmodel.Tenants{Name: name, UUID: name + "-id"}, nil
+}
+
+type apiTokenTestDao struct {
+	dbdao.APITokenDao
+	tokens   map[string]*dbmodel.APIToken
+	lastUsed int
+}
+
+func (d *apiTokenTestDao) GetByTokenHash(tokenHash string) (*dbmodel.APIToken, error) {
+	if token, ok := d.tokens[tokenHash]; ok {
+		return token, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *apiTokenTestDao) UpdateLastUsedTime(id uint, lastUsedTime time.Time) error {
+	d.lastUsed++
+	return nil
+}
+
+// capability_id: rainbond.region-api.scoped-token-rbac
+func TestFullTokenEnforcesScopedAPITokens(t *testing.T) {
+	t.Setenv("TOKEN", "cluster-token")
+	expired := time.Now().Add(-time.Minute)
+	tokenDao := &apiTokenTestDao{tokens: map[string]*dbmodel.APIToken{
+		handler.HashAPIToken("rbd_reader"):   {Name: "reader", TenantIDs: "team-a-id", Verbs: "read"},
+		handler.HashAPIToken("rbd_deployer"): {Name: "deployer", TenantIDs: "team-a-id,team-b-id", Verbs: "read,deploy"},
+		handler.HashAPIToken("rbd_expired"):  {Name: "expired", TenantIDs: "team-a-id", Verbs: "read", ExpireTime: &expired},
+	}}
+	db.SetTestManager(apiTokenTestManager{tenantDao: &apiTokenTestTenantDao{}, apiTokenDao: tokenDao})
+	defer db.SetTestManager(nil)
+
+	ok := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	r := chi.NewRouter()
+	r.Use(FullToken)
+	r.Get("/v2/cluster", ok)
+	r.Route("/v2/tenants/{tenant_name}", func(r chi.Router) {
+		r.Use(InitTenant)
+		r.Get("/services", ok)
+		r.Post("/services", ok)
+		r.Delete("/services/demo", ok)
+		r.Post("/services/demo/pods/demo-0/exec", ok)
+	})
+
+	testCases := []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "admin token", token: "cluster-token", method: http.MethodDelete, path: "/v2/tenants/team-c/services/demo", wantStatus: http.StatusNoContent},
+		{name: "read granted tenant", token: "rbd_reader", method: http.MethodGet, path: "/v2/tenants/team-a/services", wantStatus: http.StatusNoContent},
+		{name: "read other tenant", token: "rbd_reader", method: http.MethodGet, path: "/v2/tenants/team-b/services", wantStatus: http.StatusForbidden},
+		{name: "deploy without verb", token: "rbd_reader", method: http.MethodPost, path: "/v2/tenants/team-a/services", wantStatus: http.StatusForbidden},
+		{name: "deploy granted", token: "rbd_deployer", method: http.MethodPost, path: "/v2/tenants/team-b/services", wantStatus: http.StatusNoContent},
+		{name: "delete without verb", token: "rbd_deployer", method: http.MethodDelete, path: "/v2/tenants/team-a/services/demo", wantStatus: http.StatusForbidden},
+		{name: "exec without verb", token: "rbd_deployer", method: http.MethodPost, path: "/v2/tenants/team-a/services/demo/pods/demo-0/exec", wantStatus: http.StatusForbidden},
+		{name: "cluster api", token: "rbd_deployer", method: http.MethodGet, path: "/v2/cluster", wantStatus: http.StatusForbidden},
+		{name: "expired token", token: "rbd_expired", method: http.MethodGet, path: "/v2/tenants/team-a/services", wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", token: "rbd_unknown", method: http.MethodGet, path: "/v2/tenants/team-a/services", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			resp := httptest.NewRecorder()
+
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, resp.Code)
+			}
+		})
+	}
+	if tokenDao.lastUsed != 2 {
+		t.Fatalf("expected last used time updated once per valid token, got %d", tokenDao.lastUsed)
+	}
+}
+
+// capability_id: rainbond.region-api.scoped-token-app-tenant
+func TestInitApplicationRejectsAppOfOtherTenant(t *testing.T) {
+	t.Setenv("TOKEN", "cluster-token")
+	tokenDao := &apiTokenTestDao{tokens: map[string]*dbmodel.APIToken{
+		handler.HashAPIToken("rbd_reader"): {Name: "reader", TenantIDs: "team-a-id", Verbs: "read"},
+	}}
+	appDao := &apiTokenTestAppDao{apps: map[string]*dbmodel.Application{
+		"app-a": {AppID: "app-a", TenantID: "team-a-id"},
+		"app-b": {AppID: "app-b", TenantID: "team-b-id"},
+	}}
+	db.SetTestManager(apiTokenTestManager{tenantDao: &apiTokenTestTenantDao{}, apiTokenDao: tokenDao, appDao: appDao})
+	defer db.SetTestManager(nil)
+
+	r := chi.NewRouter()
+	r.Use(FullToken)
+	r.Route("/v2/tenants/{tenant_name}/apps/{app_id}", func(r chi.Router) {
+		r.Use(InitTenant)
+		r.Use(InitApplication)
+		r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "app of granted tenant", path: "/v2/tenants/team-a/apps/app-a/", wantStatus: http.StatusNoContent},
+		{name: "app of other tenant", path: "/v2/tenants/team-a/apps/app-b/", wantStatus: http.StatusNotFound},
+		{name: "unknown app", path: "/v2/tenants/team-a/apps/app-c/", wantStatus: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer rbd_reader")
+			resp := httptest.NewRecorder()
+
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, resp.Code)
+			}
+		})
+	}
+}
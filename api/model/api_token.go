@@ -0,0 +1,47 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+// CreateAPITokenReq the request to issue a scoped api token
+type CreateAPITokenReq struct {
+	Name string `json:"name" validate:"name|required"`
+	// Tenants the names of the tenants the token can access
+	Tenants []string `json:"tenants" validate:"tenants|required"`
+	// Verbs the granted verbs: read, deploy, delete, exec
+	Verbs      []string   `json:"verbs" validate:"verbs|required"`
+	ExpireTime *time.Time `json:"expire_time"`
+}
+
+// APITokenInfo the scoped api token without the secret
+type APITokenInfo struct {
+	Name         string     `json:"name"`
+	Tenants      []string   `json:"tenants"`
+	Verbs        []string   `json:"verbs"`
+	ExpireTime   *time.Time `json:"expire_time"`
+	LastUsedTime *time.Time `json:"last_used_time"`
+	CreateTime   time.Time  `json:"create_time"`
+}
+
+// CreateAPITokenResp the issued token, the plaintext is only returned once
+type CreateAPITokenResp struct {
+	APITokenInfo
+	Token string `json:"token"`
+}
@@ -0,0 +1,31 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// AuditLogVerifyResult the result of the audit log hash chain verification
+type AuditLogVerifyResult struct {
+	Valid bool `json:"valid"`
+	// Checked the number of the checked records
+	Checked int `json:"checked"`
+	// BrokenID the id of the first record breaking the chain
+	BrokenID uint   `json:"broken_id,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// LastHash the hash of the last record, it can be kept outside of the region to detect truncation
+	LastHash string `json:"last_hash"`
+}
@@ -18,22 +18,103 @@
 
 package model
 
-import dbmodel "github.com/goodrain/rainbond/db/model"
+import (
+	"encoding/json"
+	"fmt"
+
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
 
 // AutoscalerRuleReq -
 type AutoscalerRuleReq struct {
 	RuleID      string `json:"rule_id" validate:"rule_id|required"`
 	ServiceID   string
-	Enable      bool   `json:"enable" validate:"enable|required"`
-	XPAType     string `json:"xpa_type" validate:"xpa_type|required"`
-	MinReplicas int    `json:"min_replicas" validate:"min_replicas|required"`
-	MaxReplicas int    `json:"max_replicas" validate:"min_replicas|required"`
-	Metrics     []struct {
-		MetricsType       string `json:"metric_type"`
-		MetricsName       string `json:"metric_name"`
-		MetricTargetType  string `json:"metric_target_type"`
-		MetricTargetValue int    `json:"metric_target_value"`
-	} `json:"metrics"`
+	Enable      bool         `json:"enable" validate:"enable|required"`
+	XPAType     string       `json:"xpa_type" validate:"xpa_type|required"`
+	MinReplicas int          `json:"min_replicas" validate:"min_replicas|required"`
+	MaxReplicas int          `json:"max_replicas" validate:"min_replicas|required"`
+	Metrics     []RuleMetric `json:"metrics"`
+	// Behavior the scale-up and scale-down policies of hpa
+	Behavior *dbmodel.AutoscalerBehavior `json:"behavior"`
+	// Schedules the cron schedules of the min replicas
+	Schedules []dbmodel.AutoscalerSchedule `json:"schedules"`
+}
+
+// Validate checks the metrics, behavior and schedules of the rule
+func (a *AutoscalerRuleReq) Validate() error {
+	if a.MaxReplicas < a.MinReplicas {
+		return fmt.Errorf("max_replicas can not be less than min_replicas")
+	}
+	for _, metric := range a.Metrics {
+		if err := metric.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateBehavior(a.Behavior); err != nil {
+		return err
+	}
+	for _, schedule := range a.Schedules {
+		if _, err := schedule.Parse(); err != nil {
+			return fmt.Errorf("wrong schedule %s: %v", schedule.Schedule, err)
+		}
+		if schedule.MinReplicas < 1 || schedule.MinReplicas > a.MaxReplicas {
+			return fmt.Errorf("min_replicas of schedule %s must be between 1 and max_replicas", schedule.Name)
+		}
+	}
+	return nil
+}
+
+// DbModel return database model
+func (a *AutoscalerRuleReq) DbModel() *dbmodel.TenantServiceAutoscalerRules {
+	rule := &dbmodel.TenantServiceAutoscalerRules{
+		RuleID:      a.RuleID,
+		ServiceID:   a.ServiceID,
+		Enable:      a.Enable,
+		XPAType:     a.XPAType,
+		MinReplicas: a.MinReplicas,
+		MaxReplicas: a.MaxReplicas,
+	}
+	if a.Behavior != nil {
+		behavior, _ := json.Marshal(a.Behavior)
+		rule.Behavior = string(behavior)
+	}
+	if len(a.Schedules) > 0 {
+		schedules, _ := json.Marshal(a.Schedules)
+		rule.Schedules = string(schedules)
+	}
+	return rule
+}
+
+func validateBehavior(behavior *dbmodel.AutoscalerBehavior) error {
+	if behavior == nil {
+		return nil
+	}
+	for _, rules := range []*dbmodel.AutoscalerScalingRules{behavior.ScaleUp, behavior.ScaleDown} {
+		if rules == nil {
+			continue
+		}
+		if rules.StabilizationWindowSeconds != nil && (*rules.StabilizationWindowSeconds < 0 || *rules.StabilizationWindowSeconds > 3600) {
+			return fmt.Errorf("stabilization_window_seconds must be between 0 and 3600")
+		}
+		switch rules.SelectPolicy {
+		case "", "Max", "Min", "Disabled":
+		default:
+			return fmt.Errorf("unsupported select_policy: %s", rules.SelectPolicy)
+		}
+		for _, policy := range rules.Policies {
+			if policy.Type != "Pods" && policy.Type != "Percent" {
+				return fmt.Errorf("unsupported scaling policy type: %s", policy.Type)
+			}
+			if policy.Value <= 0 {
+				return fmt.Errorf("the value of scaling policy must be greater than 0")
+			}
+			if policy.PeriodSeconds <= 0 || policy.PeriodSeconds > 1800 {
+				return fmt.Errorf("period_seconds of scaling policy must be between 1 and 1800")
+			}
+		}
+	}
+	return nil
 }
 
 // AutoscalerRuleResp -
@@ -60,11 +141,15 @@ type AutoScalerRule struct {
 	MinReplicas int          `json:"min_replicas"`
 	MaxReplicas int          `json:"max_replicas"`
 	RuleMetrics []RuleMetric `json:"metrics"`
+	// Behavior the scale-up and scale-down policies of hpa
+	Behavior *dbmodel.AutoscalerBehavior `json:"behavior,omitempty"`
+	// Schedules the cron schedules of the min replicas
+	Schedules []dbmodel.AutoscalerSchedule `json:"schedules,omitempty"`
 }
 
 // DbModel return database model
 func (a AutoScalerRule) DbModel(componentID string) *dbmodel.TenantServiceAutoscalerRules {
-	return &dbmodel.TenantServiceAutoscalerRules{
+	rule := &dbmodel.TenantServiceAutoscalerRules{
 		RuleID:      a.RuleID,
 		ServiceID:   componentID,
 		MinReplicas: a.MinReplicas,
@@ -72,6 +157,15 @@ func (a AutoScalerRule) DbModel(componentID string) *dbmodel.TenantServiceAutosc
 		Enable:      a.Enable,
 		XPAType:     a.XPAType,
 	}
+	if a.Behavior != nil {
+		behavior, _ := json.Marshal(a.Behavior)
+		rule.Behavior = string(behavior)
+	}
+	if len(a.Schedules) > 0 {
+		schedules, _ := json.Marshal(a.Schedules)
+		rule.Schedules = string(schedules)
+	}
+	return rule
 }
 
 // RuleMetric -
@@ -80,15 +174,69 @@ type RuleMetric struct {
 	MetricsName       string `json:"metric_name"`
 	MetricTargetType  string `json:"metric_target_type"`
 	MetricTargetValue int    `json:"metric_target_value"`
+	// MetricTargetQuantity the quantity target of pods, object and external metrics, such as 500m
+	MetricTargetQuantity string `json:"metric_target_quantity,omitempty"`
+	// MetricSelector the label selector of pods, object and external metrics
+	MetricSelector map[string]string `json:"metric_selector,omitempty"`
+	// DescribedObject the object described by object metrics
+	DescribedObject *dbmodel.AutoscalerDescribedObject `json:"described_object,omitempty"`
+}
+
+// Validate checks the metric type and the target of the metric
+func (r RuleMetric) Validate() error {
+	switch r.MetricsType {
+	case dbmodel.MetricsTypeResource:
+		if r.MetricsName != "cpu" && r.MetricsName != "memory" {
+			return fmt.Errorf("unsupported resource metric: %s", r.MetricsName)
+		}
+		if r.MetricTargetType != dbmodel.MetricTargetTypeUtilization && r.MetricTargetType != dbmodel.MetricTargetTypeAverageValue {
+			return fmt.Errorf("unsupported target type %s of resource metric", r.MetricTargetType)
+		}
+		return nil
+	case dbmodel.MetricsTypePods:
+		if r.MetricTargetType != dbmodel.MetricTargetTypeAverageValue {
+			return fmt.Errorf("the target type of pods metric must be %s", dbmodel.MetricTargetTypeAverageValue)
+		}
+	case dbmodel.MetricsTypeObject, dbmodel.MetricsTypeExternal:
+		if r.MetricTargetType != dbmodel.MetricTargetTypeAverageValue && r.MetricTargetType != dbmodel.MetricTargetTypeValue {
+			return fmt.Errorf("unsupported target type %s of %s", r.MetricTargetType, r.MetricsType)
+		}
+		if r.MetricsType == dbmodel.MetricsTypeObject && (r.DescribedObject == nil || r.DescribedObject.Kind == "" || r.DescribedObject.Name == "") {
+			return fmt.Errorf("object metric %s requires the described object", r.MetricsName)
+		}
+	default:
+		return fmt.Errorf("unsupported metric type: %s", r.MetricsType)
+	}
+	if r.MetricsName == "" {
+		return fmt.Errorf("metric name can not be empty")
+	}
+	if r.MetricTargetQuantity != "" {
+		if _, err := resource.ParseQuantity(r.MetricTargetQuantity); err != nil {
+			return fmt.Errorf("wrong target quantity %s: %v", r.MetricTargetQuantity, err)
+		}
+	} else if r.MetricTargetValue <= 0 {
+		return fmt.Errorf("the target of metric %s must be greater than 0", r.MetricsName)
+	}
+	return nil
 }
 
 // DbModel return database model
 func (r RuleMetric) DbModel(ruleID string) *dbmodel.TenantServiceAutoscalerRuleMetrics {
-	return &dbmodel.TenantServiceAutoscalerRuleMetrics{
-		RuleID:            ruleID,
-		MetricsType:       r.MetricsType,
-		MetricsName:       r.MetricsName,
-		MetricTargetType:  r.MetricTargetType,
-		MetricTargetValue: r.MetricTargetValue,
+	metric := &dbmodel.TenantServiceAutoscalerRuleMetrics{
+		RuleID:               ruleID,
+		MetricsType:          r.MetricsType,
+		MetricsName:          r.MetricsName,
+		MetricTargetType:     r.MetricTargetType,
+		MetricTargetValue:    r.MetricTargetValue,
+		MetricTargetQuantity: r.MetricTargetQuantity,
+	}
+	if len(r.MetricSelector) > 0 {
+		selector, _ := json.Marshal(r.MetricSelector)
+		metric.MetricSelector = string(selector)
+	}
+	if r.DescribedObject != nil {
+		object, _ := json.Marshal(r.DescribedObject)
+		metric.DescribedObject = string(object)
 	}
+	return metric
 }
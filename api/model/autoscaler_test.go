@@ -0,0 +1,57 @@
+package model
+
+import (
+	"testing"
+
+	dbmodel "github.com/goodrain/rainbond/db/model"
+)
+
+// capability_id: rainbond.api.autoscaler.validate-rule
+func TestAutoscalerRuleReqValidate(t *testing.T) {
+	window := int32(300)
+	valid := AutoscalerRuleReq{
+		MinReplicas: 1,
+		MaxReplicas: 5,
+		Metrics: []RuleMetric{
+			{MetricsType: "resource_metrics", MetricsName: "cpu", MetricTargetType: "utilization", MetricTargetValue: 60},
+			{MetricsType: "external_metrics", MetricsName: "queue_depth", MetricTargetType: "average_value", MetricTargetQuantity: "30"},
+		},
+		Behavior: &dbmodel.AutoscalerBehavior{ScaleDown: &dbmodel.AutoscalerScalingRules{
+			StabilizationWindowSeconds: &window,
+			Policies:                   []dbmodel.AutoscalerScalingPolicy{{Type: "Percent", Value: 10, PeriodSeconds: 60}},
+		}},
+		Schedules: []dbmodel.AutoscalerSchedule{{Name: "day", Schedule: "0 8 * * *", MinReplicas: 3}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid rule, got %v", err)
+	}
+	rule := valid.DbModel()
+	if rule.Behavior == "" || rule.Schedules == "" {
+		t.Fatalf("expected behavior and schedules stored, got %+v", rule)
+	}
+
+	invalid := []func(r *AutoscalerRuleReq){
+		func(r *AutoscalerRuleReq) {
+			r.Metrics = []RuleMetric{{MetricsType: "pods_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetValue: 1}}
+		},
+		func(r *AutoscalerRuleReq) {
+			r.Metrics = []RuleMetric{{MetricsType: "object_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetValue: 1}}
+		},
+		func(r *AutoscalerRuleReq) {
+			r.Schedules = []dbmodel.AutoscalerSchedule{{Name: "bad", Schedule: "every day", MinReplicas: 1}}
+		},
+		func(r *AutoscalerRuleReq) {
+			r.Schedules = []dbmodel.AutoscalerSchedule{{Name: "too-many", Schedule: "0 8 * * *", MinReplicas: 6}}
+		},
+		func(r *AutoscalerRuleReq) {
+			r.Behavior = &dbmodel.AutoscalerBehavior{ScaleUp: &dbmodel.AutoscalerScalingRules{SelectPolicy: "Random"}}
+		},
+	}
+	for i, mutate := range invalid {
+		req := valid
+		mutate(&req)
+		if err := req.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+// BuildCacheStatus the source build cache of a component
+type BuildCacheStatus struct {
+	ServiceID string `json:"service_id"`
+	SizeBytes int64  `json:"size_bytes"`
+	HitCount  int    `json:"hit_count"`
+	MissCount int    `json:"miss_count"`
+	// HitRate hits of all the build steps which used the cache
+	HitRate      float64    `json:"hit_rate"`
+	LastUsedTime *time.Time `json:"last_used_time,omitempty"`
+}
+
+// WarmBuildCacheReq seed the build cache of the component from another component
+type WarmBuildCacheReq struct {
+	SourceServiceID string `json:"source_service_id" validate:"required"`
+}
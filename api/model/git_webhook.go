@@ -0,0 +1,72 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import dbmodel "github.com/goodrain/rainbond/db/model"
+
+// GitWebhookReq set the git push webhook of a source built component
+type GitWebhookReq struct {
+	// Provider github, gitlab, gitea or bitbucket
+	Provider string `json:"provider" validate:"provider|required|in:github,gitlab,gitea,bitbucket"`
+	// Secret the webhook secret, the current one is kept when it is empty on update
+	Secret string `json:"secret"`
+	// Branch pushes to the branch trigger builds
+	Branch string `json:"branch"`
+	// TagPattern pushed tags matching the glob pattern trigger builds, e.g. v*
+	TagPattern string `json:"tag_pattern"`
+	Enabled    *bool  `json:"enabled"`
+	// Action the action after the build, upgrade by default
+	Action    string            `json:"action"`
+	Arch      string            `json:"arch"`
+	BuildENVs map[string]string `json:"envs"`
+	// CodeInfo the source of the component, the branch is taken from the pushed ref
+	CodeInfo BuildCodeInfo `json:"code_info"`
+}
+
+// GitWebhookStatus the git push webhook of a component
+type GitWebhookStatus struct {
+	ServiceID  string `json:"service_id"`
+	Provider   string `json:"provider"`
+	RepoURL    string `json:"repo_url"`
+	Branch     string `json:"branch"`
+	TagPattern string `json:"tag_pattern"`
+	Enabled    bool   `json:"enabled"`
+	// HookPath the path the git server delivers the push events to
+	HookPath   string                        `json:"hook_path"`
+	Deliveries []*dbmodel.GitWebhookDelivery `json:"deliveries"`
+}
+
+// GitWebhookDeliveryResult the result of a webhook delivery
+type GitWebhookDeliveryResult struct {
+	DeliveryID string                     `json:"delivery_id"`
+	Ref        string                     `json:"ref,omitempty"`
+	CommitSHA  string                     `json:"commit_sha,omitempty"`
+	Message    string                     `json:"message,omitempty"`
+	Builds     []*GitWebhookDeliveryBuild `json:"builds"`
+}
+
+// GitWebhookDeliveryBuild the handling of a delivery on a component
+type GitWebhookDeliveryBuild struct {
+	ServiceID string `json:"service_id"`
+	Status    string `json:"status"`
+	EventID   string `json:"event_id,omitempty"`
+	Message   string `json:"message,omitempty"`
+	// Duplicate the delivery has been handled before
+	Duplicate bool `json:"duplicate,omitempty"`
+}
@@ -138,3 +138,21 @@ type LastLinesStruct struct {
 		Lines int `json:"lines" validate:"lines"`
 	}
 }
+
+// EventLogSearchReq 事件日志检索条件
+type EventLogSearchReq struct {
+	// TenantID 检索租户下所有事件的日志
+	TenantID string
+	// Target TargetID 检索指定对象（如 service）的事件的日志
+	Target   string
+	TargetID string
+	// Since Until 时间范围（unix 秒），0 表示不限制
+	Since    int64
+	Until    int64
+	Keyword  string
+	Regex    string
+	Step     string
+	Level    string
+	Page     int
+	PageSize int
+}
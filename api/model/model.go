@@ -19,8 +19,10 @@
 package model
 
 import (
+	"encoding/json"
 	corev1 "k8s.io/api/core/v1"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/goodrain/rainbond/util"
@@ -392,10 +394,14 @@ type ServiceStruct struct {
 type Endpoints struct {
 	Static     []string            `json:"static" validate:"static"`
 	Kubernetes *EndpointKubernetes `json:"kubernetes" validate:"kubernetes"`
+	Discovery  *EndpointDiscovery  `json:"discovery" validate:"discovery"`
 }
 
 // DbModel -
 func (e *Endpoints) DbModel(componentID string) *dbmodel.ThirdPartySvcDiscoveryCfg {
+	if e.Kubernetes == nil && e.Discovery != nil {
+		return e.Discovery.DbModel(componentID)
+	}
 	return &dbmodel.ThirdPartySvcDiscoveryCfg{
 		ServiceID:   componentID,
 		Type:        string(dbmodel.DiscorveryTypeKubernetes),
@@ -410,6 +416,36 @@ type EndpointKubernetes struct {
 	ServiceName string `json:"serviceName"`
 }
 
+// EndpointDiscovery service registry(consul, nacos or dns) to get endpoints.
+type EndpointDiscovery struct {
+	Type     string   `json:"type"`
+	Servers  []string `json:"servers"`
+	Key      string   `json:"key"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	// Namespace is the namespace id of nacos
+	Namespace string `json:"namespace"`
+	// Group is the group of nacos
+	Group string `json:"group"`
+	// Datacenter is the datacenter of consul
+	Datacenter string `json:"datacenter"`
+}
+
+// DbModel -
+func (e *EndpointDiscovery) DbModel(componentID string) *dbmodel.ThirdPartySvcDiscoveryCfg {
+	return &dbmodel.ThirdPartySvcDiscoveryCfg{
+		ServiceID:  componentID,
+		Type:       strings.ToLower(e.Type),
+		Servers:    strings.Join(e.Servers, ","),
+		Key:        e.Key,
+		Username:   e.Username,
+		Password:   e.Password,
+		Namespace:  e.Namespace,
+		Group:      e.Group,
+		Datacenter: e.Datacenter,
+	}
+}
+
 // TenantServiceVolumeStruct -
 type TenantServiceVolumeStruct struct {
 	ServiceID string ` json:"service_id"`
@@ -1714,8 +1750,14 @@ type ExportAppStruct struct {
 		EventID       string `json:"event_id"`
 		GroupKey      string `json:"group_key"` // TODO 考虑去掉
 		Version       string `json:"version"`   // TODO 考虑去掉
-		Format        string `json:"format"`    // only rainbond-app/docker-compose/slug
+		Format        string `json:"format"`    // only rainbond-app/docker-compose/slug/helm-chart/kustomize/k8s-manifests
 		GroupMetadata string `json:"group_metadata"`
+		// kustomize 导出的环境 overlay，默认为 dev 和 prod
+		Environments []string `json:"environments"`
+		// kustomize 和 k8s-manifests 导出的资源所在的命名空间，为空则不设置
+		Namespace string `json:"namespace"`
+		// kustomize 和 k8s-manifests 导出时是否同时打包镜像
+		WithImages bool `json:"with_images"`
 	}
 }
 
@@ -1784,6 +1826,10 @@ type BuildCodeInfo struct {
 	// in: body
 	// required: false
 	CNBVersionPolicy map[string]interface{} `json:"cnb_version_policy"`
+	// 固定构建的提交，为空时构建分支最新提交
+	// in: body
+	// required: false
+	Commit string `json:"commit"`
 }
 
 // BuildSlugInfo -
@@ -1995,21 +2041,27 @@ type RollbackInfoRequestStruct struct {
 // BuildMQBodyFrom -
 func BuildMQBodyFrom(app *ExportAppStruct) *MQBody {
 	return &MQBody{
-		EventID:   app.Body.EventID,
-		GroupKey:  app.Body.GroupKey,
-		Version:   app.Body.Version,
-		Format:    app.Body.Format,
-		SourceDir: app.SourceDir,
+		EventID:      app.Body.EventID,
+		GroupKey:     app.Body.GroupKey,
+		Version:      app.Body.Version,
+		Format:       app.Body.Format,
+		SourceDir:    app.SourceDir,
+		Environments: app.Body.Environments,
+		Namespace:    app.Body.Namespace,
+		WithImages:   app.Body.WithImages,
 	}
 }
 
 // MQBody -
 type MQBody struct {
-	EventID   string `json:"event_id"`
-	GroupKey  string `json:"group_key"`
-	Version   string `json:"version"`
-	Format    string `json:"format"` // only rainbond-app/docker-compose/slug
-	SourceDir string `json:"source_dir"`
+	EventID      string   `json:"event_id"`
+	GroupKey     string   `json:"group_key"`
+	Version      string   `json:"version"`
+	Format       string   `json:"format"` // only rainbond-app/docker-compose/slug/helm-chart/kustomize/k8s-manifests
+	SourceDir    string   `json:"source_dir"`
+	Environments []string `json:"environments,omitempty"`
+	Namespace    string   `json:"namespace,omitempty"`
+	WithImages   bool     `json:"with_images,omitempty"`
 }
 
 // NewAppStatusFromExport -
@@ -2121,6 +2173,8 @@ type UpdateAppRequest struct {
 	Version        string   `json:"version"`
 	Revision       int      `json:"revision"`
 	K8sApp         string   `json:"k8s_app"`
+	// ZeroTrust 为空时不修改
+	ZeroTrust *bool `json:"zero_trust,omitempty"`
 }
 
 // NeedUpdateHelmApp check if necessary to update the helm app.
@@ -2354,17 +2408,20 @@ type FileInfo struct {
 }
 
 type GrayReleaseModeRet struct {
-	ComponentID         string `json:"component_id"`
-	Hostname            string `json:"hostname"`
-	IstioNamespace      string `json:"istio_namespace"`
-	CanaryReadyReplicas int32  `json:"canary_ready_replicas"`
-	CanaryReplicas      int32  `json:"canary_replicas"`
-	CurrentStepIndex    int    `json:"current_step_index"`
-	CurrentStepState    string `json:"current_step_state"`
-	Message             string `json:"message"`
-	Step                int    `json:"step"`
-	NewVersion          string `json:"new_version"`
-	OldVersion          string `json:"old_version"`
+	RolloutName         string              `json:"rollout_name,omitempty"`
+	ComponentID         string              `json:"component_id"`
+	Hostname            string              `json:"hostname"`
+	IstioNamespace      string              `json:"istio_namespace"`
+	CanaryReadyReplicas int32               `json:"canary_ready_replicas"`
+	CanaryReplicas      int32               `json:"canary_replicas"`
+	CurrentStepIndex    int                 `json:"current_step_index"`
+	CurrentStepState    string              `json:"current_step_state"`
+	Message             string              `json:"message"`
+	Step                int                 `json:"step"`
+	NewVersion          string              `json:"new_version"`
+	OldVersion          string              `json:"old_version"`
+	Strategy            string              `json:"strategy,omitempty"` // canary 或 blue-green
+	Analysis            *GrayAnalysisStatus `json:"analysis,omitempty"`
 }
 
 type GrayReleaseModeReq struct {
@@ -2372,19 +2429,124 @@ type GrayReleaseModeReq struct {
 	Namespace        string             `json:"namespace"`
 	EntryComponentID string             `json:"entry_component_id"`
 	EntryHttpRoute   string             `json:"entry_http_route"`
+	EntryRouteKind   string             `json:"entry_route_kind,omitempty"` // HTTPRoute（默认）或 ApisixRoute
 	FlowEntryRule    [][]*FlowEntryRule `json:"flow_entry_rule"`
 	GrayStrategyType string             `json:"gray_strategy_type"`
-	GrayStrategy     []int              `json:"gray_strategy"`
+	GrayStrategy     json.RawMessage    `json:"gray_strategy"` // 权重数组或 GrayStep 数组
 	Status           bool               `json:"status"`
 	TraceType        string             `json:"trace_type"`
 }
 
 type FlowEntryRule struct {
+	// MatchType header（默认）或 cookie，cookie 时 HeaderKey 为 cookie 名称
+	MatchType   string `json:"match_type,omitempty"`
 	HeaderKey   string `json:"header_key"`
 	HeaderType  string `json:"header_type"`
 	HeaderValue string `json:"header_value"`
 }
 
+const (
+	// FlowEntryMatchHeader 按请求头匹配
+	FlowEntryMatchHeader = "header"
+	// FlowEntryMatchCookie 按 cookie 匹配
+	FlowEntryMatchCookie = "cookie"
+)
+
+const (
+	// GrayStrategyCanary 按步骤逐步放量
+	GrayStrategyCanary = "canary"
+	// GrayStrategyBlueGreen 新版本全量部署后一次性切换流量
+	GrayStrategyBlueGreen = "blue-green"
+)
+
+// GrayStep 灰度发布步骤，gray_strategy 也兼容只包含权重的数组 [10, 50, 100]
+type GrayStep struct {
+	Weight int32 `json:"weight"`
+	// Matches 本步骤中命中 flow_entry_rule 的请求全部进入灰度版本
+	Matches bool `json:"matches,omitempty"`
+	// Pause 本步骤完成后等待的秒数，0 表示等待指标分析通过或手动确认
+	Pause    int32         `json:"pause,omitempty"`
+	Analysis *GrayAnalysis `json:"analysis,omitempty"`
+}
+
+// GrayAnalysis 灰度步骤的指标分析，任一阈值超出即视为失败
+type GrayAnalysis struct {
+	// Interval 检查间隔，默认 30s
+	Interval string `json:"interval,omitempty"`
+	// Window 指标统计窗口，默认 1m
+	Window string `json:"window,omitempty"`
+	// MaxErrorRate 5xx 比例上限（百分比），0 表示不检查
+	MaxErrorRate float64 `json:"max_error_rate,omitempty"`
+	// MaxLatency P99 延迟上限（毫秒），0 表示不检查
+	MaxLatency float64 `json:"max_latency,omitempty"`
+	// MinRequests 窗口内请求数少于该值时不做判断
+	MinRequests int `json:"min_requests,omitempty"`
+	// SuccessCount 连续通过多少次后进入下一步，默认 3
+	SuccessCount int `json:"success_count,omitempty"`
+	// FailureLimit 连续失败多少次后执行 OnFailure，默认 1
+	FailureLimit int `json:"failure_limit,omitempty"`
+	// OnFailure pause 或 rollback，默认 pause
+	OnFailure string `json:"on_failure,omitempty"`
+	// 自定义 PromQL，可使用 {{.Namespace}} {{.PodIPs}} {{.Window}} 等变量
+	ErrorRateQuery string `json:"error_rate_query,omitempty"`
+	LatencyQuery   string `json:"latency_query,omitempty"`
+	RequestQuery   string `json:"request_query,omitempty"`
+}
+
+// GrayAnalysisStatus 灰度步骤的分析状态
+type GrayAnalysisStatus struct {
+	// Revision 灰度版本的 pod-template-hash
+	Revision  string  `json:"revision,omitempty"`
+	Step      int32   `json:"step"`
+	Phase     string  `json:"phase"`
+	Successes int     `json:"successes"`
+	Failures  int     `json:"failures"`
+	ErrorRate float64 `json:"error_rate"`
+	Latency   float64 `json:"latency"`
+	Requests  float64 `json:"requests"`
+	Action    string  `json:"action,omitempty"`
+	Message   string  `json:"message,omitempty"`
+	LastCheck string  `json:"last_check,omitempty"`
+}
+
+// GrayReleaseActionReq 手动操作灰度发布
+type GrayReleaseActionReq struct {
+	// Action promote/pause/resume/rollback
+	Action string `json:"action" validate:"required"`
+}
+
+// ZeroTrustReport 应用零信任策略报告
+type ZeroTrustReport struct {
+	AppID          string                `json:"app_id"`
+	Enabled        bool                  `json:"enabled"`
+	GovernanceMode string                `json:"governance_mode"`
+	Components     []*ZeroTrustComponent `json:"components"`
+	// BlockedFlows 应用内未声明依赖、已被拒绝的访问，未开启零信任时为空
+	BlockedFlows []*ZeroTrustFlow `json:"blocked_flows"`
+	// WouldBlockFlows 未开启零信任时，开启后将被拒绝的访问
+	WouldBlockFlows []*ZeroTrustFlow `json:"would_block_flows"`
+}
+
+// ZeroTrustComponent 组件的访问策略
+type ZeroTrustComponent struct {
+	ServiceID    string `json:"service_id"`
+	ServiceAlias string `json:"service_alias"`
+	PolicyName   string `json:"policy_name"`
+	// Consumers 声明依赖该组件的组件
+	Consumers []string `json:"consumers"`
+	// PublicPorts 通过网关对外开放的端口，应用内其他组件仍需声明依赖才能访问
+	PublicPorts []int32 `json:"public_ports"`
+}
+
+// ZeroTrustFlow 被拒绝的组件间访问
+type ZeroTrustFlow struct {
+	SourceServiceID    string  `json:"source_service_id"`
+	SourceServiceAlias string  `json:"source_service_alias"`
+	TargetServiceID    string  `json:"target_service_id"`
+	TargetServiceAlias string  `json:"target_service_alias"`
+	Ports              []int32 `json:"ports"`
+}
+
 type AppPeerAuthentications struct {
 	Name        string `json:"name"`
 	Namespace   string `json:"namespace"`
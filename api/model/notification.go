@@ -0,0 +1,85 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// NotificationChannelConfig the delivery config of a notification channel
+type NotificationChannelConfig struct {
+	// URL the webhook address of webhook, slack, dingtalk, feishu and wecom channels
+	URL string `json:"url,omitempty"`
+	// Method the http method of the webhook channel, POST by default
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// BodyTemplate a go text/template rendering the body of the webhook channel, {{json .Text}}
+	// renders a field as a json string. The notification is posted as json when it is empty
+	BodyTemplate string `json:"body_template,omitempty"`
+	// Secret the signing secret of dingtalk and feishu robots
+	Secret   string   `json:"secret,omitempty"`
+	SMTPHost string   `json:"smtp_host,omitempty"`
+	SMTPPort int      `json:"smtp_port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// NotificationChannelReq create or update a notification channel
+type NotificationChannelReq struct {
+	Name    string                    `json:"name" validate:"name|required"`
+	Type    string                    `json:"type" validate:"type|required|in:webhook,email,slack,dingtalk,feishu,wecom"`
+	Enabled *bool                     `json:"enabled"`
+	Config  NotificationChannelConfig `json:"config"`
+}
+
+// NotificationChannelInfo a notification channel, the credentials are not returned
+type NotificationChannelInfo struct {
+	ChannelID string `json:"channel_id"`
+	TenantID  string `json:"tenant_id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Enabled   bool   `json:"enabled"`
+	// Target the masked webhook address or the mail recipients
+	Target string `json:"target"`
+}
+
+// NotificationRuleReq create or update a notification rule
+type NotificationRuleReq struct {
+	Name      string `json:"name" validate:"name|required"`
+	ChannelID string `json:"channel_id" validate:"channel_id|required"`
+	// EventTypes the subscribed event types, e.g. OOMKilled, all types are subscribed when it is empty
+	EventTypes []string `json:"event_types"`
+	// ServiceIDs the subscribed components, all components of the tenant when it is empty
+	ServiceIDs  []string `json:"service_ids"`
+	MinSeverity string   `json:"min_severity" validate:"min_severity|in:info,warning,critical"`
+	// ThrottleSeconds the min interval of the notifications of the same component and event type
+	ThrottleSeconds int   `json:"throttle_seconds"`
+	Enabled         *bool `json:"enabled"`
+}
+
+// NotificationRuleInfo a notification rule
+type NotificationRuleInfo struct {
+	RuleID          string   `json:"rule_id"`
+	TenantID        string   `json:"tenant_id"`
+	ChannelID       string   `json:"channel_id"`
+	Name            string   `json:"name"`
+	EventTypes      []string `json:"event_types"`
+	ServiceIDs      []string `json:"service_ids"`
+	MinSeverity     string   `json:"min_severity"`
+	ThrottleSeconds int      `json:"throttle_seconds"`
+	Enabled         bool     `json:"enabled"`
+}
@@ -112,6 +112,8 @@ func (m *Manager) SetMiddleware() {
 	r.Use(interceptors.Recoverer)
 	//request time out
 	r.Use(interceptors.Timeout(time.Second * 5))
+	//audit the mutating requests
+	r.Use(apimiddleware.Audit)
 	//simple authz
 	if os.Getenv("TOKEN") != "" {
 		r.Use(apimiddleware.FullToken)
@@ -0,0 +1,9 @@
+package bcode
+
+// alertmanager 11800~11899
+var (
+	// ErrAlertManagerPayload -
+	ErrAlertManagerPayload = newByMessage(400, 11800, "invalid alertmanager webhook payload")
+	// ErrAlertManagerVersion -
+	ErrAlertManagerVersion = newByMessage(400, 11801, "unsupported alertmanager webhook version, only version 4 is supported")
+)
@@ -0,0 +1,15 @@
+package bcode
+
+// api token 11400~11499
+var (
+	// ErrAPITokenExists -
+	ErrAPITokenExists = newByMessage(400, 11400, "api token already exists")
+	// ErrAPITokenNotFound -
+	ErrAPITokenNotFound = newByMessage(404, 11401, "api token not found")
+	// ErrAPITokenInvalidVerb -
+	ErrAPITokenInvalidVerb = newByMessage(400, 11402, "invalid api token verb")
+	// ErrAPITokenExpired -
+	ErrAPITokenExpired = newByMessage(400, 11403, "api token expire time must be in the future")
+	// ErrCrossTenantForbidden -
+	ErrCrossTenantForbidden = newByMessage(403, 11404, "no grant to access the other tenant")
+)
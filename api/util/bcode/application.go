@@ -36,4 +36,6 @@ var (
 	ErrConfigItemExist = newByMessage(400, 11103, "config item under this config group already exist")
 	//ErrServiceNotFound -
 	ErrServiceNotFound = newByMessage(404, 11104, "this service ID cannot be found under this application")
+	//ErrGrayReleaseNotFound -
+	ErrGrayReleaseNotFound = newByMessage(404, 11105, "gray release not found under this application")
 )
@@ -0,0 +1,9 @@
+package bcode
+
+// build cache 11600~11699
+var (
+	// ErrBuildCacheSourceNotFound -
+	ErrBuildCacheSourceNotFound = newByMessage(404, 11600, "the component to warm the build cache from is not found")
+	// ErrBuildCacheSameSource -
+	ErrBuildCacheSameSource = newByMessage(400, 11601, "can not warm the build cache from the component itself")
+)
@@ -0,0 +1,21 @@
+package bcode
+
+// git webhook 11700~11799
+var (
+	// ErrGitWebhookNotFound -
+	ErrGitWebhookNotFound = newByMessage(404, 11700, "git webhook not found")
+	// ErrGitWebhookProvider -
+	ErrGitWebhookProvider = newByMessage(400, 11701, "unsupported git provider, must be one of github, gitlab, gitea, bitbucket")
+	// ErrGitWebhookSecretRequired -
+	ErrGitWebhookSecretRequired = newByMessage(400, 11702, "the secret of the git webhook is required")
+	// ErrGitWebhookRefRequired -
+	ErrGitWebhookRefRequired = newByMessage(400, 11703, "either branch or tag_pattern is required")
+	// ErrGitWebhookTagPattern -
+	ErrGitWebhookTagPattern = newByMessage(400, 11704, "invalid tag pattern")
+	// ErrGitWebhookSignature -
+	ErrGitWebhookSignature = newByMessage(401, 11705, "invalid git webhook signature")
+	// ErrGitWebhookPayload -
+	ErrGitWebhookPayload = newByMessage(400, 11706, "invalid git webhook payload")
+	// ErrGitWebhookThirdParty -
+	ErrGitWebhookThirdParty = newByMessage(400, 11707, "third party components can not be built from git webhooks")
+)
@@ -0,0 +1,11 @@
+package bcode
+
+// notification 11900~11999
+var (
+	// ErrNotificationChannelNotFound -
+	ErrNotificationChannelNotFound = newByMessage(404, 11900, "notification channel not found")
+	// ErrNotificationRuleNotFound -
+	ErrNotificationRuleNotFound = newByMessage(404, 11901, "notification rule not found")
+	// ErrNotificationThrottle -
+	ErrNotificationThrottle = newByMessage(400, 11902, "throttle_seconds must not be negative")
+)
@@ -0,0 +1,9 @@
+package bcode
+
+// terminal session 11500~11599
+var (
+	// ErrTerminalSessionNotFound -
+	ErrTerminalSessionNotFound = newByMessage(404, 11500, "terminal session not found")
+	// ErrTerminalRecordingNotReady -
+	ErrTerminalRecordingNotReady = newByMessage(404, 11501, "the recording of the terminal session is not available")
+)
@@ -27,10 +27,12 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/barnettZQG/gotty/server"
 	"github.com/barnettZQG/gotty/webtty"
+	"github.com/goodrain/rainbond/util"
 	k8sutil "github.com/goodrain/rainbond/util/k8s"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
@@ -66,6 +68,9 @@ type App struct {
 	restClient *restclient.RESTClient
 	coreClient kubernetes.Interface
 	config     *restclient.Config
+
+	sessionsOnce sync.Once
+	sessions     *sessionRegistry
 }
 
 // Options options
@@ -107,6 +112,12 @@ type InitMessage struct {
 	Md5           string `json:"Md5"`
 	Namespace     string `json:"namespace"`
 	Mode          string `json:"mode"`
+	// User the console user of the session, it is only kept for display and in the session
+	// recording, the session is not bound to it.
+	User string `json:"user"`
+	// SessionID the terminal session to reattach, as listed by the terminal session api. New
+	// sessions always get an id from the server. The session keeps running after the websocket is closed.
+	SessionID string `json:"session_id"`
 }
 
 // SetUpgrader -
@@ -163,8 +174,76 @@ func (app *App) HandleWS(w http.ResponseWriter, r *http.Request) {
 		init.Namespace = init.TenantID
 	}
 
+	// the session belongs to the tenant owning the namespace, the tenant id sent by the client is not trusted
+	tenantID := namespaceTenantID(init.Namespace)
+	if tenantID == "" {
+		conn.WriteMessage(websocket.TextMessage, []byte("Tenant of the namespace is not found!"))
+		conn.Close()
+		return
+	}
+
+	var session *persistentSession
+	if init.SessionID != "" {
+		session, err = app.sessionRegistry().get(init.SessionID, tenantID, init)
+		if err != nil {
+			logrus.Warningf("reattach terminal session %s of pod %s: %v", init.SessionID, init.PodName, err)
+			message := "Session is not found!"
+			if err == ErrSessionForbidden {
+				message = "Session is not allowed!"
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(message))
+			conn.Close()
+			return
+		}
+		logrus.Infof("reattach terminal session %s of pod %s", init.SessionID, init.PodName)
+	} else {
+		session = app.openSession(conn, init, tenantID)
+		if session == nil {
+			return
+		}
+	}
+	view, err := session.Attach()
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte("Session is closed!"))
+		conn.Close()
+		return
+	}
+	// closing the view only detaches the client, the exec keeps running until it exits or idles out
+	defer view.Close()
+	opts := []webtty.Option{
+		webtty.WithWindowTitle([]byte(session.title)),
+		webtty.WithReconnect(60),
+		webtty.WithPermitWrite(),
+	}
+	// create web tty and run
+	tty, err := webtty.New(&WsWrapper{conn}, view, opts...)
+	if err != nil {
+		logrus.Errorf("open web tty context failure %s", err.Error())
+		conn.WriteMessage(websocket.TextMessage, []byte("open tty failure!"))
+		ExecuteCommandFailed++
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	err = tty.Run(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), "master closed") {
+			logrus.Infof("client close connection, terminal session %s detached", session.Info().SessionID)
+			return
+		}
+		logrus.Errorf("run web tty failure %s", err.Error())
+		conn.WriteMessage(websocket.TextMessage, []byte("run tty failure!"))
+		ExecuteCommandFailed++
+		return
+	}
+}
+
+// openSession creates the exec of the container and registers it as a terminal session of the tenant.
+// The exec is refused when the tenant records the sessions and the recording cannot start.
+func (app *App) openSession(conn *websocket.Conn, init InitMessage, tenantID string) *persistentSession {
 	// 等待容器就绪，最多重试 30 次（30秒）
 	var containerName, ip string
+	var err error
 	var args []string
 	maxRetries := 30
 	retryInterval := time.Second
@@ -193,7 +272,7 @@ func (app *App) HandleWS(w http.ResponseWriter, r *http.Request) {
 		logrus.Errorf("get default container failure %s", err.Error())
 		conn.WriteMessage(websocket.TextMessage, []byte("Get default container name failure!"))
 		ExecuteCommandFailed++
-		return
+		return nil
 	}
 
 	// 超过重试次数仍未就绪
@@ -201,7 +280,7 @@ func (app *App) HandleWS(w http.ResponseWriter, r *http.Request) {
 		logrus.Errorf("container not ready after %d retries: %s", maxRetries, err.Error())
 		conn.WriteMessage(websocket.TextMessage, []byte("Container is not ready, please try again later!"))
 		ExecuteCommandFailed++
-		return
+		return nil
 	}
 	request := app.NewRequest(init.PodName, init.Namespace, containerName, args)
 	var slave server.Slave
@@ -210,35 +289,51 @@ func (app *App) HandleWS(w http.ResponseWriter, r *http.Request) {
 		logrus.Errorf("open exec context failure %s", err.Error())
 		conn.WriteMessage(websocket.TextMessage, []byte("open tty failure!"))
 		ExecuteCommandFailed++
-		return
-	}
-	defer slave.Close()
-	opts := []webtty.Option{
-		webtty.WithWindowTitle([]byte(ip)),
-		webtty.WithReconnect(60),
-		webtty.WithPermitWrite(),
+		return nil
 	}
-	// create web tty and run
-	tty, err := webtty.New(&WsWrapper{conn}, slave, opts...)
+	sessionID := util.NewUUID()
+	recording, err := startSessionRecording(sessionID, tenantID, init, containerName)
 	if err != nil {
-		logrus.Errorf("open web tty context failure %s", err.Error())
-		conn.WriteMessage(websocket.TextMessage, []byte("open tty failure!"))
+		logrus.Errorf("start recording of terminal session %s: %v", sessionID, err)
+		slave.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("Start session recording failure!"))
 		ExecuteCommandFailed++
-		return
-	}
-	ctx, cancel := context.WithCancel(r.Context())
-	defer cancel()
-	err = tty.Run(ctx)
-	if err != nil {
-		if strings.Contains(err.Error(), "master closed") {
-			logrus.Infof("client close connection")
-			return
-		}
-		logrus.Errorf("run web tty failure %s", err.Error())
-		conn.WriteMessage(websocket.TextMessage, []byte("run tty failure!"))
-		ExecuteCommandFailed++
-		return
-	}
+		return nil
+	}
+	if recording != nil {
+		slave = recording.Wrap(slave)
+	}
+	mode := init.Mode
+	if mode != webcliModeDebug {
+		mode = webcliModeExec
+	}
+	return app.sessionRegistry().open(SessionInfo{
+		SessionID:     sessionID,
+		TenantID:      tenantID,
+		ServiceID:     init.ServiceID,
+		PodName:       init.PodName,
+		ContainerName: containerName,
+		Mode:          mode,
+		User:          init.User,
+	}, ip, slave, recording)
+}
+
+func (app *App) sessionRegistry() *sessionRegistry {
+	app.sessionsOnce.Do(func() {
+		app.sessions = newSessionRegistry(SessionIdleTimeout())
+		go app.sessions.runReaper()
+	})
+	return app.sessions
+}
+
+// ListSessions returns the open terminal sessions of the tenant, filtered by component and user when given.
+func (app *App) ListSessions(tenantID, serviceID, user string) []SessionInfo {
+	return app.sessionRegistry().list(tenantID, serviceID, user)
+}
+
+// CloseSession terminates the terminal session of the tenant.
+func (app *App) CloseSession(tenantID, sessionID string) error {
+	return app.sessionRegistry().close(tenantID, sessionID)
 }
 
 // DebugToolboxImage returns the configured toolbox image for debug terminals.
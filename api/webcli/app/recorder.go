@@ -0,0 +1,272 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/barnettZQG/gotty/server"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/pkg/component/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// TerminalSessionDir the directory of the terminal recordings in the storage
+const TerminalSessionDir = "/grdata/terminal-sessions"
+
+const (
+	webcliModeExec = "exec"
+	// the size of the terminal before the client reports its size
+	defaultTerminalWidth  = 80
+	defaultTerminalHeight = 24
+)
+
+// castHeader the header line of an asciicast v2 recording
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// castWriter writes the terminal events in asciicast v2 format
+type castWriter struct {
+	mu    sync.Mutex
+	w     *bufio.Writer
+	start time.Time
+	now   func() time.Time
+	// pending the trailing bytes of an incomplete utf-8 sequence of the output
+	pending []byte
+	err     error
+}
+
+func newCastWriter(w io.Writer, title string, start time.Time) (*castWriter, error) {
+	c := &castWriter{w: bufio.NewWriter(w), start: start, now: time.Now}
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     defaultTerminalWidth,
+		Height:    defaultTerminalHeight,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.w.Write(append(header, '\n')); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Output records the output of the terminal
+func (c *castWriter) Output(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data := append(c.pending, p...)
+	cut := len(data)
+	// keep an incomplete utf-8 sequence at the end for the next output
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	c.pending = append([]byte(nil), data[cut:]...)
+	c.event("o", string(data[:cut]))
+}
+
+// Input records the input of the operator
+func (c *castWriter) Input(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.event("i", string(p))
+}
+
+// Resize records the new size of the terminal
+func (c *castWriter) Resize(columns, rows int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.event("r", fmt.Sprintf("%dx%d", columns, rows))
+}
+
+func (c *castWriter) event(kind, data string) {
+	if c.err != nil || data == "" {
+		return
+	}
+	elapsed := strconv.FormatFloat(c.now().Sub(c.start).Seconds(), 'f', 6, 64)
+	line, _ := json.Marshal([]interface{}{json.RawMessage(elapsed), kind, data})
+	_, c.err = c.w.Write(append(line, '\n'))
+}
+
+// Close flushes the recording
+func (c *castWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) > 0 {
+		c.event("o", string(c.pending))
+		c.pending = nil
+	}
+	if c.err != nil {
+		return c.err
+	}
+	return c.w.Flush()
+}
+
+// recordingSlave records the terminal traffic of the slave
+type recordingSlave struct {
+	server.Slave
+	cast *castWriter
+}
+
+func (s *recordingSlave) Read(p []byte) (int, error) {
+	n, err := s.Slave.Read(p)
+	if n > 0 {
+		s.cast.Output(p[:n])
+	}
+	return n, err
+}
+
+func (s *recordingSlave) Write(p []byte) (int, error) {
+	s.cast.Input(p)
+	return s.Slave.Write(p)
+}
+
+func (s *recordingSlave) ResizeTerminal(columns, rows int) error {
+	s.cast.Resize(columns, rows)
+	return s.Slave.ResizeTerminal(columns, rows)
+}
+
+// sessionRecording a web terminal session being recorded to a local file,
+// the file is moved to the storage when the session ends.
+type sessionRecording struct {
+	session *dbmodel.TerminalSession
+	file    *os.File
+	cast    *castWriter
+}
+
+// recordingEnabled reports whether the terminal sessions of the tenant are recorded
+func recordingEnabled(tenantID string) bool {
+	kv, err := db.GetManager().KeyValueDao().Get(dbmodel.TerminalRecordingKey(tenantID))
+	if err != nil {
+		logrus.Warningf("get terminal recording switch of tenant %s: %v", tenantID, err)
+		return false
+	}
+	return kv != nil && kv.V == "true"
+}
+
+// namespaceTenantID returns the tenant owning the namespace, the namespace of the
+// tenants created before the namespace column is the tenant id.
+func namespaceTenantID(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	tenant, err := db.GetManager().TenantDao().GetTenantByNamespace(namespace)
+	if err != nil {
+		tenant, err = db.GetManager().TenantDao().GetTenantByUUID(namespace)
+	}
+	if err != nil {
+		logrus.Warningf("get tenant of namespace %s: %v", namespace, err)
+		return ""
+	}
+	return tenant.UUID
+}
+
+// startSessionRecording starts to record the session if the tenant enables the recording,
+// it returns nil when the session is not recorded. tenantID is resolved from the namespace
+// of the pod by namespaceTenantID, the tenant id sent by the client is not trusted.
+func startSessionRecording(sessionID, tenantID string, init InitMessage, containerName string) (*sessionRecording, error) {
+	if tenantID == "" || !recordingEnabled(tenantID) {
+		return nil, nil
+	}
+	mode := init.Mode
+	if mode != webcliModeDebug {
+		mode = webcliModeExec
+	}
+	session := &dbmodel.TerminalSession{
+		SessionID:     sessionID,
+		TenantID:      tenantID,
+		ServiceID:     init.ServiceID,
+		PodName:       init.PodName,
+		ContainerName: containerName,
+		Mode:          mode,
+		User:          init.User,
+	}
+	session.CreatedAt = time.Now()
+	file, err := os.CreateTemp("", "terminal-"+session.SessionID+"-*.cast")
+	if err != nil {
+		return nil, fmt.Errorf("create terminal recording file: %v", err)
+	}
+	cast, err := newCastWriter(file, init.PodName+"/"+containerName, session.CreatedAt)
+	if err == nil {
+		err = db.GetManager().TerminalSessionDao().AddModel(session)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("start terminal recording of pod %s: %v", init.PodName, err)
+	}
+	return &sessionRecording{session: session, file: file, cast: cast}, nil
+}
+
+// Wrap records the traffic of the slave
+func (s *sessionRecording) Wrap(slave server.Slave) server.Slave {
+	return &recordingSlave{Slave: slave, cast: s.cast}
+}
+
+// Finish uploads the recording to the storage and closes the session
+func (s *sessionRecording) Finish() {
+	defer os.Remove(s.file.Name())
+	err := s.cast.Close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	now := time.Now()
+	s.session.EndTime = &now
+	if err == nil {
+		if info, statErr := os.Stat(s.file.Name()); statErr == nil {
+			s.session.Size = info.Size()
+		}
+		dst := path.Join(TerminalSessionDir, s.session.TenantID, s.session.SessionID+".cast")
+		if err = storage.Default().StorageCli.UploadFileToFile(s.file.Name(), dst, nil); err == nil {
+			s.session.RecordingPath = dst
+		}
+	}
+	if err != nil {
+		logrus.Errorf("save terminal recording %s: %v", filepath.Base(s.file.Name()), err)
+	}
+	if err := db.GetManager().TerminalSessionDao().UpdateModel(s.session); err != nil {
+		logrus.Errorf("update terminal session %s: %v", s.session.SessionID, err)
+	}
+}
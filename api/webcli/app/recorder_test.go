@@ -0,0 +1,216 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+type recorderTestSlave struct {
+	output  []byte
+	input   bytes.Buffer
+	columns int
+}
+
+func (s *recorderTestSlave) Read(p []byte) (int, error) {
+	n := copy(p, s.output)
+	s.output = s.output[n:]
+	return n, nil
+}
+
+func (s *recorderTestSlave) Write(p []byte) (int, error) {
+	return s.input.Write(p)
+}
+
+func (s *recorderTestSlave) WindowTitleVariables() map[string]interface{} {
+	return nil
+}
+
+func (s *recorderTestSlave) ResizeTerminal(columns, rows int) error {
+	s.columns = columns
+	return nil
+}
+
+func (s *recorderTestSlave) Close() error {
+	return nil
+}
+
+// capability_id: rainbond.webcli.session-recording-asciicast
+func TestRecordingSlaveWritesAsciicast(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	var out bytes.Buffer
+	cast, err := newCastWriter(&out, "demo-0/app", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := []time.Duration{time.Second, 1500 * time.Millisecond, 2 * time.Second, 3 * time.Second, 4 * time.Second}
+	cast.now = func() time.Time {
+		d := elapsed[0]
+		elapsed = elapsed[1:]
+		return start.Add(d)
+	}
+	// "中" is split across two reads of the output
+	inner := &recorderTestSlave{output: []byte("ls\r\n\xe4\xb8")}
+	slave := &recordingSlave{Slave: inner, cast: cast}
+
+	if err := slave.ResizeTerminal(120, 40); err != nil || inner.columns != 120 {
+		t.Fatalf("expected the resize passed to the slave, got %d %v", inner.columns, err)
+	}
+	if _, err := slave.Write([]byte("ls\r")); err != nil || inner.input.String() != "ls\r" {
+		t.Fatalf("expected the input passed to the slave, got %q %v", inner.input.String(), err)
+	}
+	buf := make([]byte, 64)
+	if n, _ := slave.Read(buf); string(buf[:n]) != "ls\r\n\xe4\xb8" {
+		t.Fatalf("expected the output returned unchanged, got %q", buf[:n])
+	}
+	inner.output = []byte("\xad\r\n")
+	slave.Read(buf)
+	if err := cast.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(&out)
+	scanner.Scan()
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	if header.Version != 2 || header.Timestamp != start.Unix() || header.Width != defaultTerminalWidth {
+		t.Fatalf("unexpected asciicast header %+v", header)
+	}
+	var events [][]interface{}
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("decode event %s: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	want := [][]interface{}{
+		{1.0, "r", "120x40"},
+		{1.5, "i", "ls\r"},
+		{2.0, "o", "ls\r\n"},
+		{3.0, "o", "中\r\n"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %v", len(want), events)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if events[i][j] != want[i][j] {
+				t.Fatalf("expected event %d to be %v, got %v", i, want[i], events[i])
+			}
+		}
+	}
+}
+
+type recorderTestManager struct {
+	db.Manager
+	sessions *recorderTestSessionDao
+}
+
+func (m recorderTestManager) TenantDao() dbdao.TenantDao { return recorderTestTenantDao{} }
+
+func (m recorderTestManager) KeyValueDao() dbdao.KeyValueDao { return recorderTestKeyValueDao{} }
+
+func (m recorderTestManager) TerminalSessionDao() dbdao.TerminalSessionDao { return m.sessions }
+
+type recorderTestTenantDao struct {
+	dbdao.TenantDao
+}
+
+func (recorderTestTenantDao) GetTenantByNamespace(namespace string) (*dbmodel.Tenants, error) {
+	if namespace == "team-a" {
+		return &dbmodel.Tenants{UUID: "tenant-a", Namespace: namespace}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (recorderTestTenantDao) GetTenantByUUID(uuid string) (*dbmodel.Tenants, error) {
+	if uuid == "tenant-b" {
+		return &dbmodel.Tenants{UUID: uuid}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// only tenant-a records the terminal sessions
+type recorderTestKeyValueDao struct {
+	dbdao.KeyValueDao
+}
+
+func (recorderTestKeyValueDao) Get(key string) (*dbmodel.KeyValue, error) {
+	if key == dbmodel.TerminalRecordingKey("tenant-a") {
+		return &dbmodel.KeyValue{K: key, V: "true"}, nil
+	}
+	return nil, nil
+}
+
+type recorderTestSessionDao struct {
+	dbdao.TerminalSessionDao
+	sessions []*dbmodel.TerminalSession
+	err      error
+}
+
+func (d *recorderTestSessionDao) AddModel(mo dbmodel.Interface) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.sessions = append(d.sessions, mo.(*dbmodel.TerminalSession))
+	return nil
+}
+
+// capability_id: rainbond.webcli.session-recording-tenant
+func TestStartSessionRecordingResolvesTenantFromNamespace(t *testing.T) {
+	sessions := &recorderTestSessionDao{}
+	db.SetTestManager(recorderTestManager{sessions: sessions})
+	defer db.SetTestManager(nil)
+
+	// the client claims a tenant without recording to skip the recording of team-a
+	recording, err := startSessionRecording("s1", namespaceTenantID("team-a"), InitMessage{TenantID: "tenant-b", Namespace: "team-a", PodName: "pod"}, "main")
+	if err != nil || recording == nil {
+		t.Fatal("expected the session in the namespace of tenant-a recorded")
+	}
+	recording.file.Close()
+	os.Remove(recording.file.Name())
+	if len(sessions.sessions) != 1 || sessions.sessions[0].TenantID != "tenant-a" {
+		t.Fatalf("expected the session recorded for tenant-a, got %+v", sessions.sessions)
+	}
+
+	// the client claims tenant-a in the namespace of tenant-b
+	if recording, err := startSessionRecording("s2", namespaceTenantID("tenant-b"), InitMessage{TenantID: "tenant-a", Namespace: "tenant-b", PodName: "pod"}, "main"); err != nil || recording != nil {
+		t.Fatal("expected no recording for the namespace of tenant-b")
+	}
+
+	// a session that must be recorded is refused when the recording cannot start
+	sessions.err = errors.New("duplicate session id")
+	if recording, err := startSessionRecording("s1", "tenant-a", InitMessage{Namespace: "team-a", PodName: "pod"}, "main"); err == nil || recording != nil {
+		t.Fatalf("expected the recording failure returned, got %v", err)
+	}
+}
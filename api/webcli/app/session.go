@@ -0,0 +1,354 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/barnettZQG/gotty/server"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvSessionIdleTimeout configures how long a detached terminal session is kept without any activity.
+	EnvSessionIdleTimeout = "WEBCLI_SESSION_IDLE_TIMEOUT"
+	// DefaultSessionIdleTimeout is used when EnvSessionIdleTimeout is not set.
+	DefaultSessionIdleTimeout = 30 * time.Minute
+	// the output kept for the client reattaching the session
+	sessionScrollbackSize = 64 * 1024
+)
+
+var (
+	// ErrSessionNotFound the terminal session does not exist or is closed
+	ErrSessionNotFound = errors.New("terminal session not found")
+	// ErrSessionForbidden the terminal session belongs to another pod
+	ErrSessionForbidden = errors.New("terminal session belongs to another pod")
+	errSessionDetached  = errors.New("terminal session detached")
+)
+
+// SessionInfo an open terminal session
+type SessionInfo struct {
+	SessionID     string    `json:"session_id"`
+	TenantID      string    `json:"tenant_id"`
+	ServiceID     string    `json:"service_id"`
+	PodName       string    `json:"pod_name"`
+	ContainerName string    `json:"container_name"`
+	Mode          string    `json:"mode"`
+	User          string    `json:"user"`
+	Attached      bool      `json:"attached"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastActive    time.Time `json:"last_active"`
+}
+
+// SessionIdleTimeout returns the configured idle timeout of the detached sessions.
+func SessionIdleTimeout() time.Duration {
+	if value := os.Getenv(EnvSessionIdleTimeout); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err == nil && timeout > 0 {
+			return timeout
+		}
+		logrus.Warningf("invalid %s %q, use %s", EnvSessionIdleTimeout, value, DefaultSessionIdleTimeout)
+	}
+	return DefaultSessionIdleTimeout
+}
+
+// sessionRegistry keeps the terminal sessions alive across websocket connections
+type sessionRegistry struct {
+	mu          sync.Mutex
+	sessions    map[string]*persistentSession
+	idleTimeout time.Duration
+	now         func() time.Time
+}
+
+func newSessionRegistry(idleTimeout time.Duration) *sessionRegistry {
+	return &sessionRegistry{
+		sessions:    make(map[string]*persistentSession),
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+	}
+}
+
+// open registers the exec slave as a session and starts to pump its output
+func (r *sessionRegistry) open(info SessionInfo, title string, slave server.Slave, recording *sessionRecording) *persistentSession {
+	now := r.now()
+	info.CreatedAt, info.LastActive = now, now
+	s := &persistentSession{info: info, title: title, slave: slave, recording: recording, registry: r}
+	r.mu.Lock()
+	r.sessions[info.SessionID] = s
+	r.mu.Unlock()
+	go s.pump()
+	return s
+}
+
+// get returns the session of the tenant, it must be opened on the same pod. The user sent by
+// the client is not checked, it cannot be verified by the webcli.
+func (r *sessionRegistry) get(sessionID, tenantID string, init InitMessage) (*persistentSession, error) {
+	r.mu.Lock()
+	s, ok := r.sessions[sessionID]
+	r.mu.Unlock()
+	if !ok || s.Info().TenantID != tenantID {
+		return nil, ErrSessionNotFound
+	}
+	info := s.Info()
+	if info.ServiceID != init.ServiceID || info.PodName != init.PodName {
+		return nil, ErrSessionForbidden
+	}
+	return s, nil
+}
+
+func (r *sessionRegistry) remove(sessionID string) {
+	r.mu.Lock()
+	delete(r.sessions, sessionID)
+	r.mu.Unlock()
+}
+
+// list returns the open sessions of the tenant, filtered by component and user when given,
+// the user is a display filter and not an access check
+func (r *sessionRegistry) list(tenantID, serviceID, user string) []SessionInfo {
+	r.mu.Lock()
+	sessions := make([]*persistentSession, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+	infos := []SessionInfo{}
+	for _, s := range sessions {
+		info := s.Info()
+		if info.TenantID != tenantID || (serviceID != "" && info.ServiceID != serviceID) || (user != "" && info.User != user) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// close terminates the session of the tenant
+func (r *sessionRegistry) close(tenantID, sessionID string) error {
+	r.mu.Lock()
+	s, ok := r.sessions[sessionID]
+	r.mu.Unlock()
+	if !ok || s.Info().TenantID != tenantID {
+		return ErrSessionNotFound
+	}
+	s.Close()
+	return nil
+}
+
+// reap closes the detached sessions without any activity for longer than the idle timeout
+func (r *sessionRegistry) reap() {
+	r.mu.Lock()
+	sessions := make([]*persistentSession, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+	now := r.now()
+	for _, s := range sessions {
+		info := s.Info()
+		if !info.Attached && now.Sub(info.LastActive) > r.idleTimeout {
+			logrus.Infof("close idle terminal session %s of pod %s", info.SessionID, info.PodName)
+			s.Close()
+		}
+	}
+}
+
+func (r *sessionRegistry) runReaper() {
+	interval := r.idleTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.reap()
+	}
+}
+
+// persistentSession an exec session the websocket clients attach to and detach from,
+// the exec keeps running while no client is attached.
+type persistentSession struct {
+	mu         sync.Mutex
+	info       SessionInfo
+	title      string
+	slave      server.Slave
+	recording  *sessionRecording
+	scrollback []byte
+	view       *sessionView
+	closed     bool
+	closeOnce  sync.Once
+	registry   *sessionRegistry
+}
+
+// Info returns a snapshot of the session info
+func (s *persistentSession) Info() SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.info
+}
+
+// Attach attaches a client to the session, the client attached before is detached.
+// The recent output is replayed to the new client first.
+func (s *persistentSession) Attach() (server.Slave, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrSessionNotFound
+	}
+	if s.view != nil {
+		s.view.detach()
+	}
+	view := &sessionView{session: s, out: make(chan []byte, 64), done: make(chan struct{})}
+	if len(s.scrollback) > 0 {
+		view.out <- append([]byte(nil), s.scrollback...)
+	}
+	s.view = view
+	s.info.Attached = true
+	s.info.LastActive = s.registry.now()
+	return view, nil
+}
+
+// Close terminates the exec and finishes the recording
+func (s *persistentSession) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		if s.view != nil {
+			s.view.detach()
+			s.view = nil
+		}
+		s.mu.Unlock()
+		s.registry.remove(s.info.SessionID)
+		s.slave.Close()
+		if s.recording != nil {
+			s.recording.Finish()
+		}
+	})
+}
+
+func (s *persistentSession) pump() {
+	defer s.Close()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.slave.Read(buf)
+		if n > 0 {
+			data := append([]byte(nil), buf[:n]...)
+			s.mu.Lock()
+			s.scrollback = append(s.scrollback, data...)
+			if over := len(s.scrollback) - sessionScrollbackSize; over > 0 {
+				s.scrollback = append([]byte(nil), s.scrollback[over:]...)
+			}
+			s.info.LastActive = s.registry.now()
+			view := s.view
+			s.mu.Unlock()
+			if view != nil {
+				view.push(data)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				logrus.Debugf("terminal session %s exec closed: %v", s.info.SessionID, err)
+			}
+			return
+		}
+	}
+}
+
+func (s *persistentSession) touch() {
+	s.mu.Lock()
+	s.info.LastActive = s.registry.now()
+	s.mu.Unlock()
+}
+
+func (s *persistentSession) detachView(view *sessionView) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.view == view {
+		s.view = nil
+		s.info.Attached = false
+		s.info.LastActive = s.registry.now()
+	}
+}
+
+// sessionView the slave a websocket client works on, closing it only detaches the client
+type sessionView struct {
+	session  *persistentSession
+	out      chan []byte
+	pending  []byte
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func (v *sessionView) push(data []byte) {
+	select {
+	case v.out <- data:
+	case <-v.done:
+	}
+}
+
+func (v *sessionView) detach() {
+	v.doneOnce.Do(func() { close(v.done) })
+}
+
+func (v *sessionView) Read(p []byte) (int, error) {
+	if len(v.pending) == 0 {
+		select {
+		case data := <-v.out:
+			v.pending = data
+		case <-v.done:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, v.pending)
+	v.pending = v.pending[n:]
+	return n, nil
+}
+
+func (v *sessionView) Write(p []byte) (int, error) {
+	select {
+	case <-v.done:
+		return 0, errSessionDetached
+	default:
+	}
+	v.session.touch()
+	return v.session.slave.Write(p)
+}
+
+func (v *sessionView) ResizeTerminal(columns, rows int) error {
+	return v.session.slave.ResizeTerminal(columns, rows)
+}
+
+func (v *sessionView) WindowTitleVariables() map[string]interface{} {
+	return v.session.slave.WindowTitleVariables()
+}
+
+// Close detaches the client, the session keeps running
+func (v *sessionView) Close() error {
+	v.detach()
+	v.session.detachView(v)
+	return nil
+}
@@ -0,0 +1,263 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sessionTestSlave an exec whose output is fed by the test
+type sessionTestSlave struct {
+	output chan []byte
+	mu     sync.Mutex
+	input  []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newSessionTestSlave() *sessionTestSlave {
+	return &sessionTestSlave{output: make(chan []byte, 16), closed: make(chan struct{})}
+}
+
+func (s *sessionTestSlave) Read(p []byte) (int, error) {
+	select {
+	case data := <-s.output:
+		return copy(p, data), nil
+	case <-s.closed:
+		return 0, io.EOF
+	}
+}
+
+func (s *sessionTestSlave) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.input = append(s.input, p...)
+	return len(p), nil
+}
+
+func (s *sessionTestSlave) WindowTitleVariables() map[string]interface{} {
+	return nil
+}
+
+func (s *sessionTestSlave) ResizeTerminal(columns, rows int) error {
+	return nil
+}
+
+func (s *sessionTestSlave) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func readWithTimeout(t *testing.T, r io.Reader) string {
+	t.Helper()
+	result := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 1024)
+		n, _ := r.Read(buf)
+		result <- string(buf[:n])
+	}()
+	select {
+	case out := <-result:
+		return out
+	case <-time.After(2 * time.Second):
+		t.Fatal("read terminal output timeout")
+		return ""
+	}
+}
+
+func waitSessionOutput(t *testing.T, s *persistentSession, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := string(s.scrollback)
+		s.mu.Unlock()
+		if got == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("session output never became %q", want)
+}
+
+var sessionTestInit = InitMessage{TenantID: "tenant", ServiceID: "service", PodName: "pod", User: "alice"}
+
+func openTestSession(r *sessionRegistry, slave *sessionTestSlave) *persistentSession {
+	return r.open(SessionInfo{
+		SessionID: "s1",
+		TenantID:  sessionTestInit.TenantID,
+		ServiceID: sessionTestInit.ServiceID,
+		PodName:   sessionTestInit.PodName,
+		User:      sessionTestInit.User,
+	}, "10.0.0.1", slave, nil)
+}
+
+// capability_id: rainbond.webcli.session-reattach
+func TestSessionSurvivesDetachAndReplaysScrollback(t *testing.T) {
+	registry := newSessionRegistry(time.Minute)
+	slave := newSessionTestSlave()
+	session := openTestSession(registry, slave)
+
+	view, err := session.Attach()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slave.output <- []byte("step 1\r\n")
+	if out := readWithTimeout(t, view); out != "step 1\r\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := view.Write([]byte("ls\n")); err != nil {
+		t.Fatal(err)
+	}
+	view.Close()
+	if info := session.Info(); info.Attached {
+		t.Fatal("session should be detached after the websocket closed")
+	}
+
+	// the command keeps producing output while no client is attached
+	slave.output <- []byte("step 2\r\n")
+	waitSessionOutput(t, session, "step 1\r\nstep 2\r\n")
+	select {
+	case <-slave.closed:
+		t.Fatal("exec must keep running after detach")
+	default:
+	}
+
+	reattached, err := registry.get("s1", "tenant", sessionTestInit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err = reattached.Attach()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := readWithTimeout(t, view); out != "step 1\r\nstep 2\r\n" {
+		t.Fatalf("scrollback not replayed, got %q", out)
+	}
+	if string(slave.input) != "ls\n" {
+		t.Fatalf("unexpected input %q", slave.input)
+	}
+}
+
+// capability_id: rainbond.webcli.session-reattach
+func TestSessionAttachTakesOverAndChecksOwner(t *testing.T) {
+	registry := newSessionRegistry(time.Minute)
+	session := openTestSession(registry, newSessionTestSlave())
+	first, _ := session.Attach()
+	if _, err := session.Attach(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Write([]byte("x")); err == nil {
+		t.Fatal("the replaced client should be detached")
+	}
+	other := sessionTestInit
+	other.PodName = "pod-2"
+	if _, err := registry.get("s1", "tenant", other); err != ErrSessionForbidden {
+		t.Fatalf("expect forbidden, got %v", err)
+	}
+	// the tenant resolved from the namespace owns the session, not the tenant claimed by the client
+	if _, err := registry.get("s1", "other-tenant", sessionTestInit); err != ErrSessionNotFound {
+		t.Fatalf("expect not found for another tenant, got %v", err)
+	}
+	if _, err := registry.get("missing", "tenant", sessionTestInit); err != ErrSessionNotFound {
+		t.Fatalf("expect not found, got %v", err)
+	}
+}
+
+// capability_id: rainbond.webcli.session-idle-reap
+func TestSessionReapIdleDetached(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	registry := newSessionRegistry(10 * time.Minute)
+	registry.now = func() time.Time { return now }
+	slave := newSessionTestSlave()
+	session := openTestSession(registry, slave)
+	view, _ := session.Attach()
+
+	now = now.Add(time.Hour)
+	registry.reap()
+	if len(registry.list("tenant", "", "")) != 1 {
+		t.Fatal("attached session must not be reaped")
+	}
+
+	view.Close()
+	now = now.Add(5 * time.Minute)
+	registry.reap()
+	if len(registry.list("tenant", "", "")) != 1 {
+		t.Fatal("session idle shorter than the timeout must not be reaped")
+	}
+	now = now.Add(6 * time.Minute)
+	registry.reap()
+	if len(registry.list("tenant", "", "")) != 0 {
+		t.Fatal("idle session should be reaped")
+	}
+	select {
+	case <-slave.closed:
+	case <-time.After(time.Second):
+		t.Fatal("exec of the reaped session should be closed")
+	}
+}
+
+// capability_id: rainbond.webcli.session-list
+func TestSessionListAndClose(t *testing.T) {
+	registry := newSessionRegistry(time.Minute)
+	slave := newSessionTestSlave()
+	openTestSession(registry, slave)
+
+	if sessions := registry.list("tenant", "service", "alice"); len(sessions) != 1 || sessions[0].SessionID != "s1" {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+	if sessions := registry.list("tenant", "", "bob"); len(sessions) != 0 {
+		t.Fatalf("sessions of other users listed: %+v", sessions)
+	}
+	if err := registry.close("other-tenant", "s1"); err != ErrSessionNotFound {
+		t.Fatalf("closing session of another tenant should fail, got %v", err)
+	}
+	if err := registry.close("tenant", "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(registry.list("tenant", "", "")) != 0 {
+		t.Fatal("closed session still listed")
+	}
+
+	// the session is removed when the exec exits
+	slave = newSessionTestSlave()
+	openTestSession(registry, slave)
+	slave.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(registry.list("tenant", "", "")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("exited session still listed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionIdleTimeoutEnv(t *testing.T) {
+	t.Setenv(EnvSessionIdleTimeout, "5m")
+	if SessionIdleTimeout() != 5*time.Minute {
+		t.Fatal("idle timeout env not applied")
+	}
+	t.Setenv(EnvSessionIdleTimeout, "bad")
+	if SessionIdleTimeout() != DefaultSessionIdleTimeout {
+		t.Fatal("invalid idle timeout should fall back to default")
+	}
+}
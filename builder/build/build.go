@@ -194,7 +194,7 @@ func GetBuildByType(lang code.Lang, buildType string) (Build, error) {
 		if lang == code.Python || lang == code.PHP || lang == code.Golang {
 			return cnbCreater()
 		}
-		if lang == code.NetCore {
+		if lang == code.NetCore || lang == code.Ruby || lang == code.Rust {
 			return cnbCreater()
 		}
 		// Other languages fall back to default builder
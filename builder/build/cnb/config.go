@@ -14,9 +14,9 @@ const (
 	DefaultCNBBuilder = "registry.cn-hangzhou.aliyuncs.com/goodrain/ubuntu-noble-builder:0.0.98"
 	// DefaultCNBRunImage is the default online CNB run image
 	DefaultCNBRunImage = "registry.cn-hangzhou.aliyuncs.com/goodrain/ubuntu-noble-run:0.0.73"
-	// DefaultPHPCNBBuilder is the default Jammy Full builder image for PHP CNB builds.
+	// DefaultPHPCNBBuilder is the default Jammy Full builder image for PHP, Ruby and Rust CNB builds.
 	DefaultPHPCNBBuilder = "registry.cn-hangzhou.aliyuncs.com/goodrain/builder-jammy-full:0.3.613"
-	// DefaultPHPCNBRunImage is the default Jammy Full run image for PHP CNB builds.
+	// DefaultPHPCNBRunImage is the default Jammy Full run image for PHP, Ruby and Rust CNB builds.
 	DefaultPHPCNBRunImage = "registry.cn-hangzhou.aliyuncs.com/goodrain/run-jammy-full:0.1.141"
 	// CNBLifecycleCreatorPath is the path to the lifecycle creator binary
 	CNBLifecycleCreatorPath = "/lifecycle/creator"
@@ -63,14 +63,22 @@ func GetCNBRunImage() string {
 	return DefaultCNBRunImage
 }
 
+// usesJammyFullBuilder reports whether the language needs the Jammy Full builder,
+// which ships the PHP and Ruby buildpacks and the native libraries their
+// extensions and crates link against. Rust selects paketo-community/rust
+// through its custom order.
+func usesJammyFullBuilder(lang code.Lang) bool {
+	return lang == code.PHP || lang == code.Ruby || lang == code.Rust
+}
+
 func GetCNBBuilderImageForLanguage(lang code.Lang) string {
-	if lang == code.PHP {
+	if usesJammyFullBuilder(lang) {
 		if v := os.Getenv("CNB_BUILDER_IMAGE"); v != "" {
 			return v
 		}
 		if isOfflineMode() {
 			img := path.Join(builder.REGISTRYDOMAIN, phpBuilderShortName)
-			logrus.Infof("Offline mode: using PHP CNB builder image from internal registry: %s", img)
+			logrus.Infof("Offline mode: using %s CNB builder image from internal registry: %s", lang, img)
 			return img
 		}
 		return DefaultPHPCNBBuilder
@@ -79,13 +87,13 @@ func GetCNBBuilderImageForLanguage(lang code.Lang) string {
 }
 
 func GetCNBRunImageForLanguage(lang code.Lang) string {
-	if lang == code.PHP {
+	if usesJammyFullBuilder(lang) {
 		if v := os.Getenv("CNB_RUN_IMAGE"); v != "" {
 			return v
 		}
 		if isOfflineMode() {
 			img := path.Join(builder.REGISTRYDOMAIN, phpRunShortName)
-			logrus.Infof("Offline mode: using PHP CNB run image from internal registry: %s", img)
+			logrus.Infof("Offline mode: using %s CNB run image from internal registry: %s", lang, img)
 			return img
 		}
 		return DefaultPHPCNBRunImage
@@ -4,12 +4,21 @@ import (
 	"os"
 	"strings"
 
+	"github.com/goodrain/rainbond/util"
 	"github.com/sirupsen/logrus"
 )
 
 // defaultOnlineMirror is the public object storage URL for CNB dependencies.
 const defaultOnlineMirror = "https://buildpack.rainbond.com/cnb"
 
+// Default China mirrors for the package registries of the languages whose
+// buildpacks fetch packages outside the dependency mirror.
+const (
+	DefaultRubyGemsMirror   = "https://gems.ruby-china.com"
+	DefaultRustupDistServer = "https://rsproxy.cn"
+	DefaultRustupUpdateRoot = "https://rsproxy.cn/rustup"
+)
+
 // offlineMirrorMarker is the file path inside the build pod (grdata mount)
 // that an offline provisioning tool writes to switch to local file:// mirror.
 var offlineMirrorMarker = "/grdata/cnb/BP_DEPENDENCY_MIRROR"
@@ -40,3 +49,20 @@ func getDependencyMirror() string {
 	}
 	return defaultOnlineMirror
 }
+
+// getPackageMirror returns the package registry mirror for a language.
+// Priority: build env keys > platform env platformKey > China mirror default.
+// The China default is skipped when ENABLE_CHINA_MIRROR is off, and in offline
+// mode, where only mirrors configured explicitly are reachable.
+func getPackageMirror(envs map[string]string, platformKey, chinaDefault string, keys ...string) string {
+	if mirror := firstNonEmptyEnv(envs, keys...); mirror != "" {
+		return mirror
+	}
+	if v := strings.TrimSpace(os.Getenv(platformKey)); v != "" {
+		return v
+	}
+	if util.GetenvDefault("ENABLE_CHINA_MIRROR", "true") != "true" || isOfflineMode() {
+		return ""
+	}
+	return chinaDefault
+}
@@ -89,6 +89,22 @@ func dependencyMirrorAnnotationCases(t *testing.T) []dependencyMirrorAnnotationC
 				BuildEnvs: map[string]string{},
 			},
 		},
+		{
+			name: "ruby",
+			request: &build.Request{
+				Lang:      code.Ruby,
+				SourceDir: t.TempDir(),
+				BuildEnvs: map[string]string{},
+			},
+		},
+		{
+			name: "rust",
+			request: &build.Request{
+				Lang:      code.Rust,
+				SourceDir: t.TempDir(),
+				BuildEnvs: map[string]string{},
+			},
+		},
 		{
 			name: "python",
 			request: &build.Request{
@@ -35,6 +35,10 @@ func getLanguageConfig(re *build.Request) LanguageConfig {
 		return &phpConfig{}
 	case code.NetCore:
 		return &dotnetConfig{}
+	case code.Ruby:
+		return &rubyConfig{}
+	case code.Rust:
+		return &rustConfig{}
 	case code.Static:
 		return &staticConfig{}
 	case code.Nodejs:
@@ -0,0 +1,57 @@
+package cnb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/goodrain/rainbond/builder/build"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type rubyConfig struct{}
+
+func (r *rubyConfig) BuildAnnotations(re *build.Request, annotations map[string]string) {
+	applyDependencyMirrorAnnotation(annotations)
+	setAnnotationValue(annotations, "cnb-bp-mri-version", firstNonEmptyEnv(re.BuildEnvs, "BP_MRI_VERSION", "BUILD_RUNTIMES", "RUNTIMES"))
+	setAnnotationValue(annotations, "cnb-bp-bundler-version", firstNonEmptyEnv(re.BuildEnvs, "BP_BUNDLER_VERSION", "BUILD_BUNDLER_VERSION"))
+	setAnnotationValue(annotations, bpEnvToAnnotationKey("BUNDLE_WITHOUT"), firstNonEmptyEnv(re.BuildEnvs, "BUNDLE_WITHOUT", "BUILD_BUNDLE_WITHOUT"))
+	setAnnotationValue(annotations, bpEnvToAnnotationKey("RAILS_ENV"), firstNonEmptyEnv(re.BuildEnvs, "RAILS_ENV", "BUILD_RAILS_ENV"))
+}
+
+func (r *rubyConfig) BuildEnvVars(re *build.Request) []corev1.EnvVar {
+	return nil
+}
+
+// InjectMirrorConfig writes .bundle/config so that bundler installs gems from the
+// RubyGems mirror. Bundler only reads mirrors from its config, the key contains
+// characters which are not allowed in an environment variable name.
+func (r *rubyConfig) InjectMirrorConfig(re *build.Request) error {
+	if _, err := os.Stat(filepath.Join(re.SourceDir, "Gemfile")); os.IsNotExist(err) {
+		logrus.Info("No Gemfile found, skipping bundler config injection")
+		return nil
+	}
+	configPath := filepath.Join(re.SourceDir, ".bundle", "config")
+	if _, err := os.Stat(configPath); err == nil {
+		logrus.Info("Using project bundler config .bundle/config")
+		return nil
+	}
+	mirror := getPackageMirror(re.BuildEnvs, "DEFAULT_RUBYGEMS_MIRROR", DefaultRubyGemsMirror, "BUILD_RUBYGEMS_MIRROR", "CNB_RUBYGEMS_MIRROR")
+	if mirror == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("inject .bundle/config: %w", err)
+	}
+	content := fmt.Sprintf("---\nBUNDLE_MIRROR__HTTPS://RUBYGEMS__ORG/: %q\n", mirror)
+	logrus.Infof("Creating .bundle/config with RubyGems mirror %s", mirror)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("inject .bundle/config: %w", err)
+	}
+	return nil
+}
+
+func (r *rubyConfig) CustomOrder(re *build.Request) []orderBuildpack {
+	return nil
+}
@@ -0,0 +1,92 @@
+package cnb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/builder/build"
+	"github.com/goodrain/rainbond/builder/parser/code"
+)
+
+func TestRubyLanguageConfigAnnotations(t *testing.T) {
+	re := &build.Request{
+		Lang:      code.Ruby,
+		SourceDir: t.TempDir(),
+		BuildEnvs: map[string]string{
+			"BUILD_RUNTIMES":        "3.3",
+			"BUILD_BUNDLER_VERSION": "2.5.22",
+			"BUILD_BUNDLE_WITHOUT":  "development:test",
+			"BUILD_RAILS_ENV":       "production",
+		},
+	}
+
+	if _, ok := getLanguageConfig(re).(*rubyConfig); !ok {
+		t.Fatal("expected rubyConfig for ruby build")
+	}
+
+	annotations := (&Builder{}).buildPlatformAnnotations(re)
+	if annotations["cnb-bp-mri-version"] != "3.3" {
+		t.Fatalf("expected cnb-bp-mri-version=3.3, got %q", annotations["cnb-bp-mri-version"])
+	}
+	if annotations["cnb-bp-bundler-version"] != "2.5.22" {
+		t.Fatalf("expected cnb-bp-bundler-version=2.5.22, got %q", annotations["cnb-bp-bundler-version"])
+	}
+	if annotations["cnb-bundle-without"] != "development:test" {
+		t.Fatalf("expected cnb-bundle-without, got %q", annotations["cnb-bundle-without"])
+	}
+	if annotations["cnb-rails-env"] != "production" {
+		t.Fatalf("expected cnb-rails-env=production, got %q", annotations["cnb-rails-env"])
+	}
+	if annotations["rainbond.io/cnb-language"] != "ruby" {
+		t.Fatalf("expected ruby debug annotation, got %q", annotations["rainbond.io/cnb-language"])
+	}
+}
+
+func TestRubyInjectMirrorConfig(t *testing.T) {
+	t.Setenv("ENABLE_CHINA_MIRROR", "true")
+	t.Setenv("DEFAULT_RUBYGEMS_MIRROR", "")
+	origMarker := offlineMirrorMarker
+	offlineMirrorMarker = filepath.Join(t.TempDir(), "missing-marker")
+	defer func() { offlineMirrorMarker = origMarker }()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Gemfile"), []byte("source \"https://rubygems.org\"\n"), 0644); err != nil {
+		t.Fatalf("write Gemfile: %v", err)
+	}
+	re := &build.Request{Lang: code.Ruby, SourceDir: dir, BuildEnvs: map[string]string{}}
+	if err := (&rubyConfig{}).InjectMirrorConfig(re); err != nil {
+		t.Fatalf("InjectMirrorConfig returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".bundle", "config"))
+	if err != nil {
+		t.Fatalf("read .bundle/config: %v", err)
+	}
+	if !strings.Contains(string(data), `BUNDLE_MIRROR__HTTPS://RUBYGEMS__ORG/: "`+DefaultRubyGemsMirror+`"`) {
+		t.Fatalf("expected default rubygems mirror, got %q", string(data))
+	}
+
+	// a project config is kept
+	if err := os.WriteFile(filepath.Join(dir, ".bundle", "config"), []byte("---\n"), 0644); err != nil {
+		t.Fatalf("write .bundle/config: %v", err)
+	}
+	re.BuildEnvs["BUILD_RUBYGEMS_MIRROR"] = "https://gems.example.com"
+	if err := (&rubyConfig{}).InjectMirrorConfig(re); err != nil {
+		t.Fatalf("InjectMirrorConfig returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".bundle", "config")); string(data) != "---\n" {
+		t.Fatalf("expected project bundler config untouched, got %q", string(data))
+	}
+}
+
+func TestRubyAndRustBuilderRouting(t *testing.T) {
+	for _, lang := range []code.Lang{code.Ruby, code.Rust} {
+		if got := GetCNBBuilderImageForLanguage(lang); got != GetCNBBuilderImageForLanguage(code.PHP) {
+			t.Fatalf("expected %s to use the jammy full builder, got %q", lang, got)
+		}
+		if got := GetCNBRunImageForLanguage(lang); got != GetCNBRunImageForLanguage(code.PHP) {
+			t.Fatalf("expected %s to use the jammy full run image, got %q", lang, got)
+		}
+	}
+}
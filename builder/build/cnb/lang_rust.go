@@ -0,0 +1,90 @@
+package cnb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goodrain/rainbond/builder/build"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type rustConfig struct{}
+
+func (r *rustConfig) BuildAnnotations(re *build.Request, annotations map[string]string) {
+	applyDependencyMirrorAnnotation(annotations)
+	setAnnotationValue(annotations, "cnb-bp-rust-toolchain", firstNonEmptyEnv(re.BuildEnvs, "BP_RUST_TOOLCHAIN", "BUILD_RUNTIMES", "RUNTIMES"))
+	setAnnotationValue(annotations, "cnb-bp-rust-profile", firstNonEmptyEnv(re.BuildEnvs, "BP_RUST_PROFILE", "BUILD_RUST_PROFILE"))
+	setAnnotationValue(annotations, "cnb-bp-cargo-install-args", firstNonEmptyEnv(re.BuildEnvs, "BP_CARGO_INSTALL_ARGS", "BUILD_CARGO_INSTALL_ARGS"))
+	setAnnotationValue(annotations, "cnb-bp-cargo-workspace-members", firstNonEmptyEnv(re.BuildEnvs, "BP_CARGO_WORKSPACE_MEMBERS", "BUILD_CARGO_WORKSPACE_MEMBERS"))
+	setAnnotationValue(annotations, bpEnvToAnnotationKey("RUSTUP_DIST_SERVER"), rustupDistServer(re))
+	setAnnotationValue(annotations, bpEnvToAnnotationKey("RUSTUP_UPDATE_ROOT"), rustupUpdateRoot(re))
+}
+
+func (r *rustConfig) BuildEnvVars(re *build.Request) []corev1.EnvVar {
+	var envs []corev1.EnvVar
+	envs = appendEnvVar(envs, "RUSTUP_DIST_SERVER", rustupDistServer(re))
+	envs = appendEnvVar(envs, "RUSTUP_UPDATE_ROOT", rustupUpdateRoot(re))
+	return envs
+}
+
+// InjectMirrorConfig writes .cargo/config.toml which replaces crates.io with the
+// crates mirror. Nothing is written unless a mirror is configured for the build
+// or the platform, and a cargo config committed in the project is left untouched.
+func (r *rustConfig) InjectMirrorConfig(re *build.Request) error {
+	mirror := firstNonEmptyEnv(re.BuildEnvs, "BUILD_CARGO_REGISTRY_MIRROR", "CNB_CARGO_REGISTRY_MIRROR")
+	if mirror == "" {
+		mirror = strings.TrimSpace(os.Getenv("DEFAULT_CRATES_MIRROR"))
+	}
+	if mirror == "" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(re.SourceDir, "Cargo.toml")); os.IsNotExist(err) {
+		logrus.Info("No Cargo.toml found, skipping cargo config injection")
+		return nil
+	}
+	for _, file := range []string{"config.toml", "config"} {
+		if _, err := os.Stat(filepath.Join(re.SourceDir, ".cargo", file)); err == nil {
+			logrus.Infof("Using project cargo config .cargo/%s", file)
+			return nil
+		}
+	}
+	configPath := filepath.Join(re.SourceDir, ".cargo", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("inject .cargo/config.toml: %w", err)
+	}
+	content := fmt.Sprintf("[source.crates-io]\nreplace-with = \"rainbond-mirror\"\n\n[source.rainbond-mirror]\nregistry = %q\n", mirror)
+	logrus.Infof("Creating .cargo/config.toml with crates mirror %s", mirror)
+	f, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return fmt.Errorf("inject .cargo/config.toml: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return fmt.Errorf("inject .cargo/config.toml: %w", err)
+	}
+	return nil
+}
+
+// CustomOrder returns the paketo-community/rust order for Rust projects; the
+// Jammy Full builder has no Rust buildpack in its default order.
+func (r *rustConfig) CustomOrder(re *build.Request) []orderBuildpack {
+	return []orderBuildpack{
+		{ID: "paketo-buildpacks/ca-certificates", Optional: true},
+		{ID: "paketo-community/rust", Version: os.Getenv("CNB_RUST_BUILDPACK_VERSION")},
+		{ID: "paketo-buildpacks/procfile", Optional: true},
+	}
+}
+
+func rustupDistServer(re *build.Request) string {
+	return getPackageMirror(re.BuildEnvs, "DEFAULT_RUSTUP_DIST_SERVER", DefaultRustupDistServer, "RUSTUP_DIST_SERVER", "BUILD_RUSTUP_DIST_SERVER")
+}
+
+func rustupUpdateRoot(re *build.Request) string {
+	return getPackageMirror(re.BuildEnvs, "DEFAULT_RUSTUP_UPDATE_ROOT", DefaultRustupUpdateRoot, "RUSTUP_UPDATE_ROOT", "BUILD_RUSTUP_UPDATE_ROOT")
+}
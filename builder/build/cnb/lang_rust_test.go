@@ -0,0 +1,130 @@
+package cnb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/builder/build"
+	"github.com/goodrain/rainbond/builder/parser/code"
+)
+
+func TestRustLanguageConfigAnnotationsAndEnv(t *testing.T) {
+	t.Setenv("ENABLE_CHINA_MIRROR", "true")
+	re := &build.Request{
+		Lang:      code.Rust,
+		SourceDir: t.TempDir(),
+		BuildEnvs: map[string]string{
+			"BUILD_RUNTIMES":                "1.88",
+			"BUILD_CARGO_INSTALL_ARGS":      "--locked",
+			"BUILD_CARGO_WORKSPACE_MEMBERS": "api,worker",
+			"RUSTUP_DIST_SERVER":            "https://rustup.example.com",
+		},
+	}
+
+	if _, ok := getLanguageConfig(re).(*rustConfig); !ok {
+		t.Fatal("expected rustConfig for rust build")
+	}
+
+	annotations := (&Builder{}).buildPlatformAnnotations(re)
+	if annotations["cnb-bp-rust-toolchain"] != "1.88" {
+		t.Fatalf("expected cnb-bp-rust-toolchain=1.88, got %q", annotations["cnb-bp-rust-toolchain"])
+	}
+	if annotations["cnb-bp-cargo-install-args"] != "--locked" {
+		t.Fatalf("expected cnb-bp-cargo-install-args, got %q", annotations["cnb-bp-cargo-install-args"])
+	}
+	if annotations["cnb-bp-cargo-workspace-members"] != "api,worker" {
+		t.Fatalf("expected cnb-bp-cargo-workspace-members, got %q", annotations["cnb-bp-cargo-workspace-members"])
+	}
+	if annotations["cnb-rustup-dist-server"] != "https://rustup.example.com" {
+		t.Fatalf("expected cnb-rustup-dist-server, got %q", annotations["cnb-rustup-dist-server"])
+	}
+	if annotations["rainbond.io/cnb-language"] != "rust" {
+		t.Fatalf("expected rust debug annotation, got %q", annotations["rainbond.io/cnb-language"])
+	}
+
+	envs := (&Builder{}).buildEnvVars(re)
+	found := false
+	for _, env := range envs {
+		if env.Name == "RUSTUP_DIST_SERVER" && env.Value == "https://rustup.example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected RUSTUP_DIST_SERVER in the build container env")
+	}
+}
+
+func TestRustInjectMirrorConfig(t *testing.T) {
+	t.Setenv("ENABLE_CHINA_MIRROR", "true")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\nname = \"demo\"\n"), 0644); err != nil {
+		t.Fatalf("write Cargo.toml: %v", err)
+	}
+	re := &build.Request{
+		Lang:      code.Rust,
+		SourceDir: dir,
+		BuildEnvs: map[string]string{"BUILD_CARGO_REGISTRY_MIRROR": "sparse+https://crates.example.com/index/"},
+	}
+	if err := (&rustConfig{}).InjectMirrorConfig(re); err != nil {
+		t.Fatalf("InjectMirrorConfig returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".cargo", "config.toml"))
+	if err != nil {
+		t.Fatalf("read .cargo/config.toml: %v", err)
+	}
+	if !strings.Contains(string(data), `replace-with = "rainbond-mirror"`) || !strings.Contains(string(data), `registry = "sparse+https://crates.example.com/index/"`) {
+		t.Fatalf("unexpected cargo config %q", string(data))
+	}
+
+	// nothing is injected without an explicitly configured mirror
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\nname = \"demo\"\n"), 0644); err != nil {
+		t.Fatalf("write Cargo.toml: %v", err)
+	}
+	re = &build.Request{Lang: code.Rust, SourceDir: dir, BuildEnvs: map[string]string{}}
+	if err := (&rustConfig{}).InjectMirrorConfig(re); err != nil {
+		t.Fatalf("InjectMirrorConfig returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".cargo", "config.toml")); !os.IsNotExist(err) {
+		t.Fatalf("expected no cargo config, got %v", err)
+	}
+
+	// a project cargo config is never overwritten
+	if err := os.MkdirAll(filepath.Join(dir, ".cargo"), 0755); err != nil {
+		t.Fatalf("mkdir .cargo: %v", err)
+	}
+	userConfig := "[net]\ngit-fetch-with-cli = true\n"
+	if err := os.WriteFile(filepath.Join(dir, ".cargo", "config.toml"), []byte(userConfig), 0644); err != nil {
+		t.Fatalf("write .cargo/config.toml: %v", err)
+	}
+	re.BuildEnvs["BUILD_CARGO_REGISTRY_MIRROR"] = "sparse+https://crates.example.com/index/"
+	if err := (&rustConfig{}).InjectMirrorConfig(re); err != nil {
+		t.Fatalf("InjectMirrorConfig returned error: %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, ".cargo", "config.toml"))
+	if err != nil || string(data) != userConfig {
+		t.Fatalf("expected project cargo config to be kept, got %q, %v", string(data), err)
+	}
+}
+
+func TestRustCustomOrder(t *testing.T) {
+	t.Setenv("CNB_RUST_BUILDPACK_VERSION", "5.1.0")
+	re := &build.Request{Lang: code.Rust, SourceDir: t.TempDir(), BuildEnvs: map[string]string{}}
+	bps := (&rustConfig{}).CustomOrder(re)
+	if flag := (&Builder{}).writeCustomOrder(re, bps, "rust"); flag != "-order=/workspace/.cnb-order.toml" {
+		t.Fatalf("unexpected order flag %q", flag)
+	}
+	data, err := os.ReadFile(filepath.Join(re.SourceDir, ".cnb-order.toml"))
+	if err != nil {
+		t.Fatalf("read order.toml: %v", err)
+	}
+	want := "[[order]]\n" +
+		"  [[order.group]]\n    id = \"paketo-buildpacks/ca-certificates\"\n    optional = true\n" +
+		"  [[order.group]]\n    id = \"paketo-community/rust\"\n    version = \"5.1.0\"\n" +
+		"  [[order.group]]\n    id = \"paketo-buildpacks/procfile\"\n    optional = true\n"
+	if string(data) != want {
+		t.Fatalf("unexpected order.toml:\n%s", data)
+	}
+}
@@ -93,6 +93,10 @@ func cnbDebugLanguage(re *build.Request) string {
 		return "php"
 	case code.NetCore:
 		return "dotnet"
+	case code.Ruby:
+		return "ruby"
+	case code.Rust:
+		return "rust"
 	case code.Static:
 		return "static"
 	default:
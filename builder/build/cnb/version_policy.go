@@ -25,6 +25,8 @@ var versionPolicyRules = map[code.Lang]versionPolicyRule{
 	code.Golang:    {policyKey: "golang", explicitKeys: []string{"BP_GO_VERSION", "BUILD_GOVERSION", "GOVERSION"}, bpKey: "BP_GO_VERSION", setKeys: []string{"BUILD_GOVERSION", "BP_GO_VERSION"}, ossDefault: "1.25"},
 	code.NetCore:   {policyKey: "dotnet", explicitKeys: []string{"BP_DOTNET_FRAMEWORK_VERSION"}, bpKey: "BP_DOTNET_FRAMEWORK_VERSION", setKeys: []string{"BP_DOTNET_FRAMEWORK_VERSION"}, ossDefault: "8.0"},
 	code.PHP:       {policyKey: "php", explicitKeys: []string{"BP_PHP_VERSION", "BUILD_RUNTIMES", "RUNTIMES"}, bpKey: "BP_PHP_VERSION", setKeys: []string{"BUILD_RUNTIMES", "BP_PHP_VERSION"}, ossDefault: "8.3"},
+	code.Ruby:      {policyKey: "ruby", explicitKeys: []string{"BP_MRI_VERSION", "BUILD_RUNTIMES", "RUNTIMES"}, bpKey: "BP_MRI_VERSION", setKeys: []string{"BUILD_RUNTIMES", "BP_MRI_VERSION"}, ossDefault: "3.4"},
+	code.Rust:      {policyKey: "rust", explicitKeys: []string{"BP_RUST_TOOLCHAIN", "BUILD_RUNTIMES", "RUNTIMES"}, bpKey: "BP_RUST_TOOLCHAIN", setKeys: []string{"BUILD_RUNTIMES", "BP_RUST_TOOLCHAIN"}, ossDefault: "stable"},
 	code.Nodejs:    {policyKey: "nodejs", explicitKeys: []string{"CNB_NODE_VERSION", "BUILD_RUNTIMES", "RUNTIMES", "BP_NODE_VERSION"}, bpKey: "BP_NODE_VERSION", setKeys: []string{"CNB_NODE_VERSION", "BP_NODE_VERSION"}, ossDefault: "24.13.0"},
 }
 
@@ -185,6 +187,24 @@ func normalizeVersionByLanguage(lang code.Lang, version string) (string, error)
 			return "", fmt.Errorf("invalid dotnet cnb version %q", version)
 		}
 		return strings.Join(parts[:2], "."), nil
+	case code.Ruby:
+		version = strings.TrimPrefix(version, "ruby-")
+		parts := strings.Split(version, ".")
+		if len(parts) < 2 {
+			return "", fmt.Errorf("invalid ruby cnb version %q", version)
+		}
+		return strings.Join(parts[:2], "."), nil
+	case code.Rust:
+		for _, channel := range []string{"stable", "beta", "nightly"} {
+			if version == channel || strings.HasPrefix(version, channel+"-") {
+				return version, nil
+			}
+		}
+		parts := strings.Split(version, ".")
+		if len(parts) < 2 {
+			return "", fmt.Errorf("invalid rust cnb version %q", version)
+		}
+		return strings.Join(parts[:2], "."), nil
 	case code.Nodejs:
 		return normalizeNodeVersion(version)
 	default:
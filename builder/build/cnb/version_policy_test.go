@@ -223,3 +223,60 @@ func TestResolvePHPOssFallbackVersionWithoutPolicy(t *testing.T) {
 		t.Fatalf("expected BP_PHP_VERSION=8.3, got %q", got)
 	}
 }
+
+func TestResolveRubyVersionFromSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Gemfile"), []byte("source \"https://rubygems.org\"\n"), 0644); err != nil {
+		t.Fatalf("write Gemfile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".ruby-version"), []byte("3.3.6\n"), 0644); err != nil {
+		t.Fatalf("write .ruby-version: %v", err)
+	}
+
+	re := &build.Request{
+		Lang:          code.Ruby,
+		BuildStrategy: "cnb",
+		SourceDir:     dir,
+		BuildEnvs:     map[string]string{},
+	}
+
+	if err := applyVersionPolicy(re); err != nil {
+		t.Fatalf("applyVersionPolicy returned error: %v", err)
+	}
+	if got := re.BuildEnvs["BP_MRI_VERSION"]; got != "3.3" {
+		t.Fatalf("expected BP_MRI_VERSION=3.3, got %q", got)
+	}
+}
+
+func TestResolveRustToolchainFromSourceAndFallback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"1.88.0\"\n"), 0644); err != nil {
+		t.Fatalf("write rust-toolchain.toml: %v", err)
+	}
+
+	re := &build.Request{
+		Lang:          code.Rust,
+		BuildStrategy: "cnb",
+		SourceDir:     dir,
+		BuildEnvs:     map[string]string{},
+	}
+	if err := applyVersionPolicy(re); err != nil {
+		t.Fatalf("applyVersionPolicy returned error: %v", err)
+	}
+	if got := re.BuildEnvs["BP_RUST_TOOLCHAIN"]; got != "1.88" {
+		t.Fatalf("expected BP_RUST_TOOLCHAIN=1.88, got %q", got)
+	}
+
+	re = &build.Request{
+		Lang:          code.Rust,
+		BuildStrategy: "cnb",
+		SourceDir:     t.TempDir(),
+		BuildEnvs:     map[string]string{},
+	}
+	if err := applyVersionPolicy(re); err != nil {
+		t.Fatalf("applyVersionPolicy returned error: %v", err)
+	}
+	if got := re.BuildEnvs["BP_RUST_TOOLCHAIN"]; got != "stable" {
+		t.Fatalf("expected BP_RUST_TOOLCHAIN=stable, got %q", got)
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package buildcache keeps the accounting of the source build caches on the builder cache
+// volume: the size and hit rate of every component, invalidate and warm, and the LRU
+// eviction under the cluster wide quota.
+package buildcache
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
+	"github.com/goodrain/rainbond/util"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// StepSourceBuild the cache of the buildpack (slug) builds, e.g. the maven repository
+	StepSourceBuild = "source-build"
+	// StepDockerfileBuild the local layer cache of the buildkit builds
+	StepDockerfileBuild = "dockerfile-build"
+
+	// ResultHit the cache of the step is restored
+	ResultHit = "hit"
+	// ResultMiss the step runs without cache
+	ResultMiss = "miss"
+)
+
+// Steps the build steps whose cache is managed
+var Steps = []string{StepSourceBuild, StepDockerfileBuild}
+
+// Root the cache volume mounted into the builder, it is /opt/rainbond/cache on the build nodes
+var Root = "/cache"
+
+// minEvictIdle the caches used in this duration are never evicted, the build may still be running
+var minEvictIdle = time.Hour
+
+// removeRegistryCache removes the cnb cache image of the component, it is replaced in tests
+var removeRegistryCache = removeCNBCacheImage
+
+// Dir returns the cache directory of the build step of the component
+func Dir(step, tenantID, serviceID string) string {
+	if step == StepDockerfileBuild {
+		return path.Join(Root, "buildkit", serviceID)
+	}
+	return path.Join(Root, "build", tenantID, "cache", serviceID)
+}
+
+// ParseQuota parses the quota of the build caches, e.g. 100Gi, empty means no limit
+func ParseQuota(quota string) (int64, error) {
+	if quota == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(quota)
+	if err != nil {
+		return 0, fmt.Errorf("invalid build cache quota %q: %v", quota, err)
+	}
+	return q.Value(), nil
+}
+
+var (
+	lookupLock  sync.Mutex
+	lookupStats = map[[2]string]float64{}
+)
+
+// LookupStats returns the number of the cache hits and misses by step and result
+func LookupStats() map[[2]string]float64 {
+	lookupLock.Lock()
+	defer lookupLock.Unlock()
+	stats := make(map[[2]string]float64, len(Steps)*2)
+	for _, step := range Steps {
+		for _, result := range []string{ResultHit, ResultMiss} {
+			key := [2]string{step, result}
+			stats[key] = lookupStats[key]
+		}
+	}
+	return stats
+}
+
+func countLookup(step, result string) {
+	lookupLock.Lock()
+	lookupStats[[2]string{step, result}]++
+	lookupLock.Unlock()
+}
+
+// StepReport the cache usage of a build step
+type StepReport struct {
+	Step       string `json:"step"`
+	Dir        string `json:"-"`
+	SizeBefore int64  `json:"size_before"`
+	SizeAfter  int64  `json:"size_after"`
+}
+
+// Used whether the step is run by the build, the steps of other build types leave their cache untouched
+func (s StepReport) Used() bool {
+	return s.SizeBefore > 0 || s.SizeAfter > 0
+}
+
+// Result hit when the cache of the step exists before the build
+func (s StepReport) Result() string {
+	if s.SizeBefore > 0 {
+		return ResultHit
+	}
+	return ResultMiss
+}
+
+// Report the cache usage of a build
+type Report struct {
+	TenantID  string
+	ServiceID string
+	NoCache   bool
+	Steps     []StepReport
+	start     time.Time
+}
+
+// Begin measures the caches of the component before the build
+func Begin(tenantID, serviceID string, noCache bool) *Report {
+	report := &Report{TenantID: tenantID, ServiceID: serviceID, NoCache: noCache, start: time.Now()}
+	for _, step := range Steps {
+		dir := Dir(step, tenantID, serviceID)
+		report.Steps = append(report.Steps, StepReport{Step: step, Dir: dir, SizeBefore: dirSize(dir)})
+	}
+	return report
+}
+
+// Finish measures the caches after the build, reports the hits and misses of the steps into
+// the build event log and saves the accounting of the component.
+func (r *Report) Finish(logger event.Logger) {
+	var total int64
+	var hits, misses int
+	for i := range r.Steps {
+		step := &r.Steps[i]
+		step.SizeAfter = dirSize(step.Dir)
+		total += step.SizeAfter
+		if r.NoCache || !step.Used() {
+			continue
+		}
+		result := step.Result()
+		countLookup(step.Step, result)
+		if result == ResultHit {
+			hits++
+		} else {
+			misses++
+		}
+		if logger != nil {
+			logger.Info(fmt.Sprintf("build cache %s of step %s, size %s (%s)", result, step.Step,
+				formatSize(step.SizeAfter), formatDelta(step.SizeAfter-step.SizeBefore)), map[string]string{"step": "build-cache"})
+		}
+	}
+	if r.NoCache && logger != nil {
+		logger.Info("build cache is disabled by NO_CACHE, the cache is rebuilt", map[string]string{"step": "build-cache"})
+	}
+	if err := record(r.TenantID, r.ServiceID, total, hits, misses, time.Now()); err != nil {
+		logrus.Warningf("save build cache of component %s: %v", r.ServiceID, err)
+	}
+}
+
+func record(tenantID, serviceID string, size int64, hits, misses int, usedAt time.Time) error {
+	dao := db.GetManager().BuildCacheDao()
+	cache, err := dao.GetByServiceID(serviceID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if cache == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return dao.AddModel(&dbmodel.BuildCache{TenantID: tenantID, ServiceID: serviceID, SizeBytes: size,
+			HitCount: hits, MissCount: misses, LastUsedTime: usedAt})
+	}
+	cache.SizeBytes = size
+	cache.HitCount += hits
+	cache.MissCount += misses
+	cache.LastUsedTime = usedAt
+	return dao.UpdateModel(cache)
+}
+
+// Invalidate removes all the caches of the component including the cnb cache image,
+// the next build runs without cache
+func Invalidate(tenantID, serviceID string) error {
+	if err := removeLocal(tenantID, serviceID); err != nil {
+		return err
+	}
+	if err := removeRegistryCache(serviceID); err != nil {
+		logrus.Warningf("remove cnb cache image of component %s: %v", serviceID, err)
+	}
+	return nil
+}
+
+// removeLocal removes the cache directories of the component on the cache volume
+func removeLocal(tenantID, serviceID string) error {
+	for _, step := range Steps {
+		if err := os.RemoveAll(Dir(step, tenantID, serviceID)); err != nil {
+			return errors.Wrapf(err, "remove %s cache", step)
+		}
+	}
+	cache, err := db.GetManager().BuildCacheDao().GetByServiceID(serviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	cache.SizeBytes = 0
+	return db.GetManager().BuildCacheDao().UpdateModel(cache)
+}
+
+// Warm seeds the caches of the component with the caches of another component, e.g. a fork of
+// the same repository, so that its first build does not download all the dependencies.
+func Warm(tenantID, serviceID, sourceTenantID, sourceServiceID string) (int64, error) {
+	var total int64
+	for _, step := range Steps {
+		src := Dir(step, sourceTenantID, sourceServiceID)
+		if dirSize(src) == 0 {
+			continue
+		}
+		dst := Dir(step, tenantID, serviceID)
+		if err := util.CheckAndCreateDir(dst); err != nil {
+			return 0, err
+		}
+		// keep the owner of the files, the buildpack builds run as the slug user
+		if out, err := exec.Command("cp", "-a", src+"/.", dst).CombinedOutput(); err != nil {
+			return 0, errors.Wrapf(err, "copy %s cache: %s", step, out)
+		}
+	}
+	for _, step := range Steps {
+		total += dirSize(Dir(step, tenantID, serviceID))
+	}
+	return total, record(tenantID, serviceID, total, 0, 0, time.Now())
+}
+
+// Evict removes the least recently used caches on the cache volume until the total size is
+// within the quota.
+// It returns the components whose cache is evicted.
+func Evict(quota int64) ([]string, error) {
+	if quota <= 0 {
+		return nil, nil
+	}
+	caches, err := db.GetManager().BuildCacheDao().ListLeastRecentlyUsed()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, cache := range caches {
+		total += cache.SizeBytes
+	}
+	var evicted []string
+	for _, cache := range caches {
+		if total <= quota {
+			break
+		}
+		if cache.SizeBytes == 0 || time.Since(cache.LastUsedTime) < minEvictIdle {
+			continue
+		}
+		if err := removeLocal(cache.TenantID, cache.ServiceID); err != nil {
+			logrus.Errorf("evict build cache of component %s: %v", cache.ServiceID, err)
+			continue
+		}
+		logrus.Infof("evicted build cache of component %s, size %s, last used at %s", cache.ServiceID,
+			formatSize(cache.SizeBytes), cache.LastUsedTime.Format(time.RFC3339))
+		total -= cache.SizeBytes
+		evicted = append(evicted, cache.ServiceID)
+	}
+	if total > quota {
+		logrus.Warningf("build caches use %s after eviction, more than the quota %s", formatSize(total), formatSize(quota))
+	}
+	return evicted, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func formatSize(size int64) string {
+	return resource.NewQuantity(size, resource.BinarySI).String()
+}
+
+func formatDelta(delta int64) string {
+	if delta >= 0 {
+		return "+" + formatSize(delta)
+	}
+	return "-" + formatSize(-delta)
+}
@@ -0,0 +1,234 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package buildcache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/event"
+	"github.com/jinzhu/gorm"
+)
+
+type fakeBuildCacheDao struct {
+	dao.BuildCacheDao
+	caches map[string]*dbmodel.BuildCache
+}
+
+func (f *fakeBuildCacheDao) AddModel(mo dbmodel.Interface) error {
+	cache := mo.(*dbmodel.BuildCache)
+	f.caches[cache.ServiceID] = cache
+	return nil
+}
+
+func (f *fakeBuildCacheDao) UpdateModel(mo dbmodel.Interface) error {
+	return f.AddModel(mo)
+}
+
+func (f *fakeBuildCacheDao) GetByServiceID(serviceID string) (*dbmodel.BuildCache, error) {
+	cache, ok := f.caches[serviceID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *cache
+	return &copied, nil
+}
+
+func (f *fakeBuildCacheDao) ListLeastRecentlyUsed() ([]*dbmodel.BuildCache, error) {
+	var caches []*dbmodel.BuildCache
+	for _, cache := range f.caches {
+		copied := *cache
+		caches = append(caches, &copied)
+	}
+	for i := range caches {
+		for j := i + 1; j < len(caches); j++ {
+			if caches[j].LastUsedTime.Before(caches[i].LastUsedTime) {
+				caches[i], caches[j] = caches[j], caches[i]
+			}
+		}
+	}
+	return caches, nil
+}
+
+type fakeBuildCacheManager struct {
+	db.Manager
+	dao *fakeBuildCacheDao
+}
+
+func (f fakeBuildCacheManager) BuildCacheDao() dao.BuildCacheDao {
+	return f.dao
+}
+
+func setupBuildCacheTest(t *testing.T) *fakeBuildCacheDao {
+	t.Helper()
+	oldRoot, oldRemove := Root, removeRegistryCache
+	Root = t.TempDir()
+	removeRegistryCache = func(string) error { return nil }
+	fake := &fakeBuildCacheDao{caches: map[string]*dbmodel.BuildCache{}}
+	db.SetTestManager(fakeBuildCacheManager{dao: fake})
+	t.Cleanup(func() {
+		Root, removeRegistryCache = oldRoot, oldRemove
+		db.SetTestManager(nil)
+	})
+	return fake
+}
+
+func writeCacheFile(t *testing.T, step, tenantID, serviceID string, size int) {
+	t.Helper()
+	dir := Dir(step, tenantID, serviceID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cache.bin"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func cacheMessages(ch chan []byte) []string {
+	var messages []string
+	for {
+		select {
+		case data := <-ch:
+			var msg map[string]string
+			json.Unmarshal(data, &msg)
+			messages = append(messages, msg["message"])
+		default:
+			return messages
+		}
+	}
+}
+
+// capability_id: rainbond.builder.build-cache-hit-report
+func TestBuildCacheReportHitsAndMisses(t *testing.T) {
+	fake := setupBuildCacheTest(t)
+	ch := make(chan []byte, 10)
+	logger := event.NewLogger("event", ch)
+
+	// first build: the source build populates the cache, the dockerfile step is not used
+	report := Begin("tenant", "svc", false)
+	writeCacheFile(t, StepSourceBuild, "tenant", "svc", 1024)
+	report.Finish(logger)
+	messages := cacheMessages(ch)
+	if len(messages) != 1 || !strings.Contains(messages[0], "build cache miss of step source-build") {
+		t.Fatalf("unexpected messages %v", messages)
+	}
+	cache := fake.caches["svc"]
+	if cache.SizeBytes != 1024 || cache.HitCount != 0 || cache.MissCount != 1 {
+		t.Fatalf("unexpected cache record %+v", cache)
+	}
+
+	// second build restores the cache
+	report = Begin("tenant", "svc", false)
+	report.Finish(logger)
+	messages = cacheMessages(ch)
+	if len(messages) != 1 || !strings.Contains(messages[0], "build cache hit of step source-build") {
+		t.Fatalf("unexpected messages %v", messages)
+	}
+	if cache := fake.caches["svc"]; cache.HitCount != 1 || cache.MissCount != 1 {
+		t.Fatalf("unexpected cache record %+v", cache)
+	}
+	if stats := LookupStats(); stats[[2]string{StepSourceBuild, ResultHit}] < 1 || stats[[2]string{StepSourceBuild, ResultMiss}] < 1 {
+		t.Fatalf("lookups not counted: %v", stats)
+	}
+}
+
+// capability_id: rainbond.builder.build-cache-invalidate
+func TestBuildCacheInvalidateAndWarm(t *testing.T) {
+	fake := setupBuildCacheTest(t)
+	var removedImage string
+	removeRegistryCache = func(serviceID string) error {
+		removedImage = serviceID
+		return nil
+	}
+	writeCacheFile(t, StepSourceBuild, "tenant", "template", 2048)
+	writeCacheFile(t, StepDockerfileBuild, "tenant", "template", 512)
+
+	size, err := Warm("tenant", "svc", "tenant", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 2560 || fake.caches["svc"].SizeBytes != 2560 {
+		t.Fatalf("unexpected warmed size %d, record %+v", size, fake.caches["svc"])
+	}
+	if _, err := os.Stat(filepath.Join(Dir(StepSourceBuild, "tenant", "svc"), "cache.bin")); err != nil {
+		t.Fatalf("cache not copied: %v", err)
+	}
+
+	if err := Invalidate("tenant", "svc"); err != nil {
+		t.Fatal(err)
+	}
+	if dirSize(Dir(StepSourceBuild, "tenant", "svc")) != 0 || dirSize(Dir(StepDockerfileBuild, "tenant", "svc")) != 0 {
+		t.Fatal("cache directories not removed")
+	}
+	if fake.caches["svc"].SizeBytes != 0 || removedImage != "svc" {
+		t.Fatalf("invalidate not recorded: %+v, removed image %q", fake.caches["svc"], removedImage)
+	}
+	if dirSize(Dir(StepSourceBuild, "tenant", "template")) != 2048 {
+		t.Fatal("the source component cache must be kept")
+	}
+}
+
+// capability_id: rainbond.builder.build-cache-lru-eviction
+func TestBuildCacheEvictLeastRecentlyUsed(t *testing.T) {
+	fake := setupBuildCacheTest(t)
+	now := time.Now()
+	for i, serviceID := range []string{"old", "middle", "recent", "building"} {
+		writeCacheFile(t, StepSourceBuild, "tenant", serviceID, 1000)
+		fake.caches[serviceID] = &dbmodel.BuildCache{TenantID: "tenant", ServiceID: serviceID, SizeBytes: 1000,
+			LastUsedTime: now.Add(time.Duration(i-4) * 24 * time.Hour)}
+	}
+	// used a moment ago, the build may be still running
+	fake.caches["building"].LastUsedTime = now
+	fake.caches["old"].LastUsedTime = now.Add(-10 * 24 * time.Hour)
+
+	evicted, err := Evict(2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(evicted, ",") != "old,middle" {
+		t.Fatalf("unexpected evicted caches %v", evicted)
+	}
+	for _, serviceID := range []string{"recent", "building"} {
+		if dirSize(Dir(StepSourceBuild, "tenant", serviceID)) != 1000 {
+			t.Fatalf("cache of %s should be kept", serviceID)
+		}
+	}
+	if evicted, _ := Evict(0); evicted != nil {
+		t.Fatal("no eviction without quota")
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	if quota, err := ParseQuota("1Gi"); err != nil || quota != 1<<30 {
+		t.Fatalf("unexpected quota %d %v", quota, err)
+	}
+	if quota, err := ParseQuota(""); err != nil || quota != 0 {
+		t.Fatalf("empty quota means no limit, got %d %v", quota, err)
+	}
+	if _, err := ParseQuota("lots"); err == nil {
+		t.Fatal("invalid quota should fail")
+	}
+}
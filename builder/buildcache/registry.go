@@ -0,0 +1,50 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package buildcache
+
+import (
+	"strings"
+
+	"github.com/goodrain/rainbond/builder"
+	"github.com/goodrain/rainbond/builder/build"
+	"github.com/goodrain/rainbond/builder/sources"
+	"github.com/goodrain/rainbond/builder/sources/registry"
+	"github.com/pkg/errors"
+)
+
+// removeCNBCacheImage deletes the cnb-cache tag of the component in rbd-hub, the next cnb build
+// does not restore the layers from it.
+func removeCNBCacheImage(serviceID string) error {
+	imageInfo := sources.ImageNameHandle(build.CreateImageName(serviceID, "cnb-cache"))
+	if !strings.Contains(imageInfo.Host, "goodrain.me") {
+		return nil
+	}
+	reg, err := registry.NewInsecure(imageInfo.Host, builder.REGISTRYUSER, builder.REGISTRYPASS)
+	if err != nil {
+		return err
+	}
+	digest, err := reg.ManifestDigestV2(imageInfo.Name, imageInfo.Tag)
+	if err != nil {
+		if errors.Is(err, registry.ErrManifestNotFound) {
+			return nil
+		}
+		return err
+	}
+	return reg.DeleteManifest(imageInfo.Name, digest)
+}
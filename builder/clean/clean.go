@@ -26,6 +26,7 @@ import (
 	dockercli "github.com/docker/docker/client"
 	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond/builder"
+	"github.com/goodrain/rainbond/builder/buildcache"
 	"github.com/goodrain/rainbond/builder/sources"
 	"github.com/goodrain/rainbond/builder/sources/registry"
 	"github.com/goodrain/rainbond/config/configs"
@@ -55,10 +56,15 @@ type Manager struct {
 	keepCount     uint
 	clientset     *kubernetes.Clientset
 	cleanInterval int
+	cacheQuota    int64
 }
 
 // CreateCleanManager create clean manager
 func CreateCleanManager(imageClient sources.ImageClient) (*Manager, error) {
+	cacheQuota, err := buildcache.ParseQuota(configs.Default().ChaosConfig.BuildCacheQuota)
+	if err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &Manager{
 		imageClient:   imageClient,
@@ -68,6 +74,7 @@ func CreateCleanManager(imageClient sources.ImageClient) (*Manager, error) {
 		keepCount:     uint(configs.Default().ChaosConfig.KeepCount),
 		clientset:     k8s.Default().Clientset,
 		cleanInterval: configs.Default().ChaosConfig.CleanInterval,
+		cacheQuota:    cacheQuota,
 	}
 	return c, nil
 }
@@ -162,6 +169,14 @@ func (t *Manager) Start(errchan chan error) error {
 					}
 				}
 			}
+			if t.cacheQuota > 0 {
+				evicted, err := buildcache.Evict(t.cacheQuota)
+				if err != nil {
+					logrus.Errorf("[clean] failed to evict build caches: %v", err)
+				} else {
+					logrus.Infof("[clean] evicted %d build caches to keep them within the quota", len(evicted))
+				}
+			}
 			// only registry garbage-collect
 			logrus.Info("[clean] running rbd-hub registry garbage-collect")
 			cmd := []string{"registry", "garbage-collect", "/etc/docker/registry/config.yml"}
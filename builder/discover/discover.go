@@ -21,6 +21,7 @@ package discover
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/goodrain/rainbond/config/configs"
 	"github.com/goodrain/rainbond/pkg/component/mq"
 	"os"
@@ -66,6 +67,7 @@ func NewChaosTaskManager(exec exector.Manager) *TaskManager {
 		callbackChan:   callbackChan,
 	}
 	exec.SetReturnTaskChan(taskManager.callback)
+	exec.SetTaskDoneFunc(taskManager.taskDone)
 	return taskManager
 }
 
@@ -115,6 +117,18 @@ func (t *TaskManager) Start(errChan chan error) error {
 func (t *TaskManager) callback(task *pb.TaskMessage) {
 	ctx, cancel := context.WithCancel(t.ctx)
 	defer cancel()
+	if task.Receipt != "" {
+		// give the lease back, the task is redelivered to another builder
+		_, err := t.client.Nack(ctx, &pb.NackRequest{
+			Topic:   configs.Default().ChaosConfig.Topic,
+			Receipt: task.Receipt,
+		})
+		if err != nil {
+			logrus.Errorf("nack task %s to mq failure %s", task.TaskId, err.Error())
+		}
+		logrus.Infof("The build controller returns an indigestible task(%s) to the messaging system", task.TaskId)
+		return
+	}
 	_, err := t.client.Enqueue(ctx, &pb.EnqueueRequest{
 		Topic:   client.BuilderTopic,
 		Message: task,
@@ -125,6 +139,57 @@ func (t *TaskManager) callback(task *pb.TaskMessage) {
 	logrus.Infof("The build controller returns an indigestible task(%s) to the messaging system", task.TaskId)
 }
 
+// taskDone ack the task message after the task has been executed. A retryable failed task is
+// nacked and retried with backoff until mq moves it to the dead-letter topic, the other
+// failures are moved to the dead-letter topic at once.
+func (t *TaskManager) taskDone(task *pb.TaskMessage, err error) {
+	if task.Receipt == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(t.ctx, time.Second*5)
+	defer cancel()
+	if err != nil {
+		_, nackErr := t.client.Nack(ctx, failedTaskNack(configs.Default().ChaosConfig.Topic, task, err))
+		if nackErr != nil {
+			logrus.Errorf("nack failed task %s to mq failure %s", task.TaskId, nackErr.Error())
+		}
+		return
+	}
+	_, ackErr := t.client.Ack(ctx, &pb.AckRequest{
+		Topic:   configs.Default().ChaosConfig.Topic,
+		Receipt: task.Receipt,
+	})
+	if ackErr != nil {
+		logrus.Errorf("ack task %s to mq failure %s", task.TaskId, ackErr.Error())
+	}
+}
+
+func failedTaskNack(topic string, task *pb.TaskMessage, err error) *pb.NackRequest {
+	req := &pb.NackRequest{
+		Topic:   topic,
+		Receipt: task.Receipt,
+		Reason:  err.Error(),
+	}
+	var taskErr *exector.TaskError
+	if errors.As(err, &taskErr) && taskErr.Retryable {
+		req.RequeueDelay = retryDelay(task.DeliveryCount)
+	} else {
+		req.DeadLetter = true
+	}
+	return req
+}
+
+// retryDelay the seconds before a failed task is redelivered, doubled for every delivery
+func retryDelay(deliveries int32) int64 {
+	if deliveries < 1 {
+		deliveries = 1
+	}
+	if deliveries > 6 {
+		deliveries = 6
+	}
+	return 30 << uint(deliveries-1)
+}
+
 // Do do
 func (t *TaskManager) Do(errChan chan error) {
 	hostName, _ := os.Hostname()
@@ -134,8 +199,13 @@ func (t *TaskManager) Do(errChan chan error) {
 			return
 		default:
 			ctx, cancel := context.WithCancel(t.discoverCtx)
-			topic := configs.Default().ChaosConfig.Topic
-			data, err := t.client.Dequeue(ctx, &pb.DequeueRequest{Topic: topic, ClientHost: hostName + "-builder"})
+			chaosConfig := configs.Default().ChaosConfig
+			data, err := t.client.Dequeue(ctx, &pb.DequeueRequest{
+				Topic:             chaosConfig.Topic,
+				ClientHost:        hostName + "-builder",
+				ManualAck:         true,
+				VisibilityTimeout: int64(chaosConfig.TaskVisibilityTimeout.Seconds()),
+			})
 			cancel()
 			if err != nil {
 				if grpc1.ErrorDesc(err) == context.DeadlineExceeded.Error() {
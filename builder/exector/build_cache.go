@@ -0,0 +1,108 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goodrain/rainbond/builder/buildcache"
+	"github.com/goodrain/rainbond/event"
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// BuildCacheInvalidate remove the build caches of the component
+	BuildCacheInvalidate = "invalidate"
+	// BuildCacheWarm seed the build caches of the component from another component
+	BuildCacheWarm = "warm"
+)
+
+// BuildCacheItem invalidate or warm the build caches of a component
+type BuildCacheItem struct {
+	EventID         string `json:"event_id"`
+	Action          string `json:"action"`
+	TenantID        string `json:"tenant_id"`
+	ServiceID       string `json:"service_id"`
+	SourceTenantID  string `json:"source_tenant_id"`
+	SourceServiceID string `json:"source_service_id"`
+	Logger          event.Logger
+}
+
+func init() {
+	RegisterRetryableWorker("build_cache", NewBuildCacheItem)
+}
+
+// NewBuildCacheItem create
+func NewBuildCacheItem(in []byte, m *exectorManager) (TaskWorker, error) {
+	eventID := gjson.GetBytes(in, "event_id").String()
+	item := &BuildCacheItem{
+		EventID: eventID,
+		Logger:  event.GetManager().GetLogger(eventID),
+	}
+	if err := ffjson.Unmarshal(in, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Run Run
+func (i *BuildCacheItem) Run(timeout time.Duration) error {
+	switch i.Action {
+	case BuildCacheInvalidate:
+		if err := buildcache.Invalidate(i.TenantID, i.ServiceID); err != nil {
+			i.Logger.Error(fmt.Sprintf("invalidate build cache failure: %v", err), map[string]string{"step": "callback", "status": "failure"})
+			return err
+		}
+		i.Logger.Info("build cache is invalidated, the next build runs without cache", map[string]string{"step": "last", "status": "success"})
+	case BuildCacheWarm:
+		size, err := buildcache.Warm(i.TenantID, i.ServiceID, i.SourceTenantID, i.SourceServiceID)
+		if err != nil {
+			i.Logger.Error(fmt.Sprintf("warm build cache failure: %v", err), map[string]string{"step": "callback", "status": "failure"})
+			return err
+		}
+		i.Logger.Info(fmt.Sprintf("build cache is warmed from component %s, cache size %d bytes", i.SourceServiceID, size), map[string]string{"step": "last", "status": "success"})
+	default:
+		logrus.Warningf("unknown build cache action %q of component %s", i.Action, i.ServiceID)
+		i.Logger.Error(fmt.Sprintf("unknown build cache action %q", i.Action), map[string]string{"step": "callback", "status": "failure"})
+	}
+	return nil
+}
+
+// Stop -
+func (i *BuildCacheItem) Stop() error {
+	return nil
+}
+
+// Name return worker name
+func (i *BuildCacheItem) Name() string {
+	return "build_cache"
+}
+
+// GetLogger GetLogger
+func (i *BuildCacheItem) GetLogger() event.Logger {
+	return i.Logger
+}
+
+// ErrorCallBack if run error will callback
+func (i *BuildCacheItem) ErrorCallBack(err error) {
+	logrus.Errorf("build cache %s of component %s: %v", i.Action, i.ServiceID, err)
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/goodrain/rainbond/builder"
 	"github.com/goodrain/rainbond/builder/build"
 	_ "github.com/goodrain/rainbond/builder/build/cnb" // register CNB builder
+	"github.com/goodrain/rainbond/builder/buildcache"
 	"github.com/goodrain/rainbond/builder/parser/code"
 	"github.com/goodrain/rainbond/builder/sources"
 	"github.com/goodrain/rainbond/db"
@@ -115,6 +116,7 @@ func NewSouceCodeBuildItem(in []byte) *SourceCodeBuildItem {
 		ServiceID:      gjson.GetBytes(in, "service_id").String(),
 		Configs:        gjson.GetBytes(in, "configs").Map(),
 		DockerfilePath: gjson.GetBytes(in, "dockerfile_path").String(),
+		Commit:         gjson.GetBytes(in, "commit").String(),
 	}
 	envs := gjson.GetBytes(in, "envs").String()
 	be := make(map[string]string)
@@ -148,7 +150,7 @@ func NewSouceCodeBuildItem(in []byte) *SourceCodeBuildItem {
 		BuildEnvs:        be,
 		CNBVersionPolicy: parseCNBVersionPolicy(in),
 	}
-	scb.CacheDir = fmt.Sprintf("/cache/build/%s/cache/%s", scb.TenantID, scb.ServiceID)
+	scb.CacheDir = buildcache.Dir(buildcache.StepSourceBuild, scb.TenantID, scb.ServiceID)
 	//scb.SourceDir = scb.CodeSouceInfo.GetCodeSourceDir()
 	scb.TGZDir = fmt.Sprintf("/grdata/build/tenant/%s/slug/%s", scb.TenantID, scb.ServiceID)
 	return scb
@@ -367,6 +369,13 @@ func (i *SourceCodeBuildItem) Run(timeout time.Duration) error {
 			}
 			return err
 		}
+		if err := sources.GitCheckoutCommit(rs, i.CodeSouceInfo, i.Logger, 5); err != nil {
+			logrus.Errorf("checkout commit %s error: %s", i.CodeSouceInfo.Commit, err.Error())
+			failCause := fmt.Sprintf("%s: %s", util.Translation("Checkout code commit failed"), i.CodeSouceInfo.Commit)
+			i.FailCause = failCause
+			i.Logger.Error(failCause, map[string]string{"step": "builder-exector", "status": "failure"})
+			return err
+		}
 		//get last commit
 		commit, err := sources.GetLastCommit(rs)
 		if err != nil || commit == nil {
@@ -410,7 +419,9 @@ func (i *SourceCodeBuildItem) Run(timeout time.Duration) error {
 	}
 
 	i.Logger.Info("pull or clone code successfully, start code build", map[string]string{"step": "codee-version"})
+	cacheReport := buildcache.Begin(i.TenantID, i.ServiceID, sourceBuildNoCacheEnabled(i.BuildEnvs))
 	res, err := i.codeBuild()
+	cacheReport.Finish(i.Logger)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			failCause := util.Translation("Build timeout, exceeded maximum build time of 60 minutes, please check build logs")
@@ -68,6 +68,7 @@ type Manager interface {
 	GetCurrentConcurrentTask() float64
 	AddTask(*pb.TaskMessage) error
 	SetReturnTaskChan(func(*pb.TaskMessage))
+	SetTaskDoneFunc(func(*pb.TaskMessage, error))
 	Start() error
 	Stop() error
 	GetImageClient() sources.ImageClient
@@ -135,6 +136,7 @@ type exectorManager struct {
 	RainbondClient    versioned.Interface
 	tasks             chan *pb.TaskMessage
 	callback          func(*pb.TaskMessage)
+	taskDone          func(*pb.TaskMessage, error)
 	maxConcurrentTask int
 	mqClient          mqclient.MQClient
 	ctx               context.Context
@@ -155,11 +157,37 @@ type TaskWorker interface {
 
 var workerCreaterList = make(map[string]func([]byte, *exectorManager) (TaskWorker, error))
 
+// retryableWorkers the idempotent task types, which are redelivered after a failure
+var retryableWorkers = make(map[string]bool)
+
 // RegisterWorker register worker creator
 func RegisterWorker(name string, fun func([]byte, *exectorManager) (TaskWorker, error)) {
 	workerCreaterList[name] = fun
 }
 
+// RegisterRetryableWorker register the creator of an idempotent worker, the failed tasks of
+// it are redelivered with backoff until they are moved to the dead-letter topic
+func RegisterRetryableWorker(name string, fun func([]byte, *exectorManager) (TaskWorker, error)) {
+	RegisterWorker(name, fun)
+	retryableWorkers[name] = true
+}
+
+// TaskError the failure of a task the task message is nacked with, a retryable task is
+// redelivered and the others are moved to the dead-letter topic at once
+type TaskError struct {
+	Err       error
+	Retryable bool
+}
+
+func (e *TaskError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause of the failure
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
 // ErrCallback do not handle this task
 var ErrCallback = fmt.Errorf("callback task to mq")
 
@@ -167,6 +195,17 @@ func (e *exectorManager) SetReturnTaskChan(re func(*pb.TaskMessage)) {
 	e.callback = re
 }
 
+// SetTaskDoneFunc set the func called after a task has been executed, used to ack the task message
+func (e *exectorManager) SetTaskDoneFunc(done func(*pb.TaskMessage, error)) {
+	e.taskDone = done
+}
+
+func (e *exectorManager) doneTask(task *pb.TaskMessage, err error) {
+	if e.taskDone != nil {
+		e.taskDone(task, err)
+	}
+}
+
 // TaskType:
 // build_from_image build app from docker image
 // build_from_source_code build app from source code
@@ -179,6 +218,7 @@ func (e *exectorManager) SetReturnTaskChan(re func(*pb.TaskMessage)) {
 // build_from_kubeblocks build app from kubeblocks, actually no build action, workload was managed by block-mechanica
 func (e *exectorManager) AddTask(task *pb.TaskMessage) error {
 	if task.TaskType == "" {
+		e.doneTask(task, nil)
 		return nil
 	}
 	if e.callback != nil && len(e.tasks) > e.maxConcurrentTask {
@@ -225,11 +265,13 @@ func (e *exectorManager) runTask(f func(task *pb.TaskMessage), task *pb.TaskMess
 	}
 	f(task)
 	e.runningTask.Delete(task.TaskId)
+	e.doneTask(task, nil)
 	logrus.Infof("Build task %s is completed", task.TaskId)
 }
 
 func (e *exectorManager) runTaskWithErr(f func(task *pb.TaskMessage) error, task *pb.TaskMessage, concurrencyControl bool) {
 	if task.TaskType == "" || task.TaskId == "" {
+		e.doneTask(task, nil)
 		return
 	}
 	logrus.Infof("Build task %s in progress", task.TaskId)
@@ -241,10 +283,12 @@ func (e *exectorManager) runTaskWithErr(f func(task *pb.TaskMessage) error, task
 	} else {
 		defer func() { <-e.tasks }()
 	}
-	if err := f(task); err != nil {
+	err := f(task)
+	if err != nil {
 		logrus.Errorf("[runTask] Task execution failed: task_id=%s, error=%s", task.TaskId, err.Error())
 	}
 	e.runningTask.Delete(task.TaskId)
+	e.doneTask(task, err)
 	logrus.Infof("[runTask] Task completed: task_id=%s", task.TaskId)
 }
 func (e *exectorManager) RunTask(task *pb.TaskMessage) {
@@ -283,6 +327,7 @@ func (e *exectorManager) RunTask(task *pb.TaskMessage) {
 		// 直接忽略，从任务队列中移除即可
 		logrus.Info("[RunTask] Received warmup task, consumer loop is active")
 		<-e.tasks // 从队列中移除
+		e.doneTask(task, nil)
 	default:
 		if _, ok := workerCreaterList[task.TaskType]; ok {
 			go e.runTaskWithErr(e.exec, task, false)
@@ -293,15 +338,18 @@ func (e *exectorManager) RunTask(task *pb.TaskMessage) {
 	}
 }
 
-func (e *exectorManager) exec(task *pb.TaskMessage) error {
+// exec run the registered worker of the task. A task that can never run, e.g. an unknown task
+// type or a broken task body, is dead-lettered. A failed run has been handled by the error
+// callback of the worker and is acked, unless the task type is retryable.
+func (e *exectorManager) exec(task *pb.TaskMessage) (err error) {
 	creator, ok := workerCreaterList[task.TaskType]
 	if !ok {
-		return fmt.Errorf("`%s` tasktype can't support", task.TaskType)
+		return &TaskError{Err: fmt.Errorf("`%s` tasktype can't support", task.TaskType)}
 	}
 	worker, err := creator(task.TaskBody, e)
 	if err != nil {
 		logrus.Errorf("create worker for builder error.%s", err)
-		return err
+		return &TaskError{Err: fmt.Errorf("create worker: %v", err)}
 	}
 	defer event.GetManager().ReleaseLogger(worker.GetLogger())
 	defer func() {
@@ -309,17 +357,28 @@ func (e *exectorManager) exec(task *pb.TaskMessage) error {
 			fmt.Println(r)
 			debug.PrintStack()
 			worker.GetLogger().Error(util.Translation("Please try again or contact customer service"), map[string]string{"step": "callback", "status": "failure"})
-			worker.ErrorCallBack(fmt.Errorf("%s", r))
+			panicErr := fmt.Errorf("task panic: %v", r)
+			worker.ErrorCallBack(panicErr)
+			err = runTaskError(task.TaskType, panicErr)
 		}
 	}()
 	if err := worker.Run(time.Minute * 10); err != nil {
 		logrus.Errorf("task type: %s; body: %s; run task: %+v", task.TaskType, task.TaskBody, err)
 		MetricErrorTaskNum++
 		worker.ErrorCallBack(err)
+		return runTaskError(task.TaskType, err)
 	}
 	return nil
 }
 
+// runTaskError the failed runs are only redelivered for the retryable task types
+func runTaskError(taskType string, err error) error {
+	if !retryableWorkers[taskType] {
+		return nil
+	}
+	return &TaskError{Err: err, Retryable: true}
+}
+
 // buildFromImage build app from docker image
 func (e *exectorManager) buildFromImage(task *pb.TaskMessage) {
 	i := NewImageBuildItem(task.TaskBody)
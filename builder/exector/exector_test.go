@@ -2,6 +2,7 @@ package exector
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 	"time"
 
@@ -97,3 +98,54 @@ func TestRunTaskDoesNotWarnForRegisteredWorker(t *testing.T) {
 		t.Fatalf("expected registered worker to avoid unknown task warning, got logs: %s", got)
 	}
 }
+
+type failingTaskWorker struct {
+	stubTaskWorker
+	callbacks int
+}
+
+func (f *failingTaskWorker) Run(timeout time.Duration) error {
+	return errors.New("run failure")
+}
+
+func (f *failingTaskWorker) ErrorCallBack(err error) {
+	f.callbacks++
+}
+
+// capability_id: rainbond.builder.task-failure-dead-letter
+func TestExecTaskErrors(t *testing.T) {
+	event.NewTestManager(&stubEventManager{})
+	defer event.NewTestManager(nil)
+	worker := &failingTaskWorker{stubTaskWorker: stubTaskWorker{logger: event.NewLogger("test-failing-worker", make(chan []byte, 1))}}
+	creators := map[string]func([]byte, *exectorManager) (TaskWorker, error){
+		"test-failing-worker": func(in []byte, m *exectorManager) (TaskWorker, error) { return worker, nil },
+		"test-broken-worker": func(in []byte, m *exectorManager) (TaskWorker, error) {
+			return nil, errors.New("invalid task body")
+		},
+	}
+	for name, creator := range creators {
+		RegisterWorker(name, creator)
+	}
+	RegisterRetryableWorker("test-retryable-worker", creators["test-failing-worker"])
+	defer func() {
+		for _, name := range []string{"test-failing-worker", "test-broken-worker", "test-retryable-worker"} {
+			delete(workerCreaterList, name)
+			delete(retryableWorkers, name)
+		}
+	}()
+	manager := &exectorManager{}
+
+	// the failure is handled by the error callback of the worker, the task is acked
+	if err := manager.exec(&pb.TaskMessage{TaskType: "test-failing-worker"}); err != nil || worker.callbacks != 1 {
+		t.Fatalf("expected the handled failure to be acked, got %v with %d callbacks", err, worker.callbacks)
+	}
+	var taskErr *TaskError
+	if err := manager.exec(&pb.TaskMessage{TaskType: "test-retryable-worker"}); !errors.As(err, &taskErr) || !taskErr.Retryable {
+		t.Fatalf("expected the idempotent task to be retried, got %v", err)
+	}
+	for _, taskType := range []string{"test-broken-worker", "test-unknown-worker"} {
+		if err := manager.exec(&pb.TaskMessage{TaskType: taskType}); !errors.As(err, &taskErr) || taskErr.Retryable {
+			t.Fatalf("expected task %s to be dead-lettered, got %v", taskType, err)
+		}
+	}
+}
@@ -28,14 +28,17 @@ import (
 	"os/exec"
 	"path"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/goodrain/rainbond-oam/pkg/export"
 	"github.com/goodrain/rainbond-oam/pkg/ram/v1alpha1"
 	ramv1alpha1 "github.com/goodrain/rainbond-oam/pkg/ram/v1alpha1"
 	"github.com/goodrain/rainbond/builder"
+	"github.com/goodrain/rainbond/builder/kustomize"
 	"github.com/goodrain/rainbond/db"
 	"github.com/goodrain/rainbond/event"
+	"github.com/goodrain/rainbond/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
@@ -43,11 +46,17 @@ import (
 
 var re = regexp.MustCompile(`\s`)
 
-// ExportApp Export app to specified format(rainbond-app or dockercompose or slug)
+// ExportApp Export app to specified format(rainbond-app, docker-compose, slug, helm-chart, kustomize or k8s-manifests)
 type ExportApp struct {
-	EventID     string `json:"event_id"`
-	Format      string `json:"format"`
-	SourceDir   string `json:"source_dir"`
+	EventID   string `json:"event_id"`
+	Format    string `json:"format"`
+	SourceDir string `json:"source_dir"`
+	// Environments the overlays of the kustomize format
+	Environments []string `json:"environments"`
+	// Namespace the namespace of the kustomize and k8s-manifests formats
+	Namespace string `json:"namespace"`
+	// WithImages bundles the images of the kustomize and k8s-manifests formats
+	WithImages  bool `json:"with_images"`
 	Logger      event.Logger
 	ImageClient sources.ImageClient
 }
@@ -60,12 +69,19 @@ func init() {
 func NewExportApp(in []byte, m *exectorManager) (TaskWorker, error) {
 	eventID := gjson.GetBytes(in, "event_id").String()
 	logger := event.GetManager().GetLogger(eventID)
+	var envs []string
+	for _, env := range gjson.GetBytes(in, "environments").Array() {
+		envs = append(envs, env.String())
+	}
 	return &ExportApp{
-		Format:      gjson.GetBytes(in, "format").String(),
-		SourceDir:   gjson.GetBytes(in, "source_dir").String(),
-		Logger:      logger,
-		EventID:     eventID,
-		ImageClient: m.imageClient,
+		Format:       gjson.GetBytes(in, "format").String(),
+		SourceDir:    gjson.GetBytes(in, "source_dir").String(),
+		Environments: envs,
+		Namespace:    gjson.GetBytes(in, "namespace").String(),
+		WithImages:   gjson.GetBytes(in, "with_images").Bool(),
+		Logger:       logger,
+		EventID:      eventID,
+		ImageClient:  m.imageClient,
 	}, nil
 }
 
@@ -122,6 +138,13 @@ func (i *ExportApp) Run(timeout time.Duration) error {
 			i.updateStatus("failed", "")
 			return err
 		}
+	} else if i.Format == "kustomize" || i.Format == "k8s-manifests" {
+		re, err = i.exportManifests()
+		if err != nil {
+			logrus.Errorf("export %s package failure %s", i.Format, err.Error())
+			i.updateStatus("failed", "")
+			return err
+		}
 	} else {
 		return errors.New("Unsupported the format: " + i.Format)
 	}
@@ -207,6 +230,72 @@ func (i *ExportApp) exportHelmChart(ram v1alpha1.RainbondApplicationConfig) (*ex
 	return helmExporter.Export()
 }
 
+// exportManifests export app to a kustomize base with overlays or a flat kubernetes manifest bundle,
+// the images are saved into the images dir of the package if required
+func (i *ExportApp) exportManifests() (*export.Result, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("%s/metadata.json", i.SourceDir))
+	if err != nil {
+		return nil, err
+	}
+	app, err := kustomize.ParseApp(data)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%s", path.Base(i.SourceDir), i.Format)
+	packageDir := path.Join(i.SourceDir, name)
+	opts := kustomize.Options{Environments: i.Environments, Namespace: i.Namespace}
+	if i.Format == "kustomize" {
+		err = kustomize.WriteKustomize(packageDir, app, opts)
+	} else {
+		err = kustomize.WriteManifests(packageDir, app, opts)
+	}
+	if err != nil {
+		i.Logger.Error(fmt.Sprintf("生成 %s 资源清单失败: %v", i.Format, err), map[string]string{"step": "export-manifests", "status": "failure"})
+		return nil, err
+	}
+	if i.WithImages {
+		if err := i.saveManifestImages(app, path.Join(packageDir, "images")); err != nil {
+			return nil, err
+		}
+	}
+	packagePath := path.Join(i.SourceDir, name+".zip")
+	if err := util.Zip(packageDir, packagePath); err != nil {
+		return nil, fmt.Errorf("zip %s package: %v", i.Format, err)
+	}
+	return &export.Result{PackagePath: packagePath, PackageName: name + ".zip", PackageVersion: app.AppVersion}, nil
+}
+
+// saveManifestImages pulls the images of the components and saves each of them as an archive
+func (i *ExportApp) saveManifestImages(app *kustomize.App, dir string) error {
+	if err := util.CheckAndCreateDir(dir); err != nil {
+		return err
+	}
+	saved := make(map[string]bool)
+	for _, com := range app.Components {
+		image := com.ShareImage
+		if image == "" {
+			image = com.Image
+		}
+		if image == "" || saved[image] {
+			continue
+		}
+		saved[image] = true
+		user, pass := builder.GetImageUserInfoV2(image, com.AppImage.HubUser, com.AppImage.HubPassword)
+		if _, err := i.ImageClient.ImagePull(image, user, pass, i.Logger, 20); err != nil {
+			i.Logger.Error(fmt.Sprintf("拉取镜像 %s 失败", image), map[string]string{"step": "export-manifests", "status": "failure"})
+			logrus.Errorf("pull image %s failure: %v", image, err)
+			return err
+		}
+		fileName := strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image) + ".tar"
+		if err := i.ImageClient.ImageSave(image, path.Join(dir, fileName)); err != nil {
+			i.Logger.Error(fmt.Sprintf("保存镜像 %s 失败", image), map[string]string{"step": "export-manifests", "status": "failure"})
+			logrus.Errorf("save image %s failure: %v", image, err)
+			return err
+		}
+	}
+	return nil
+}
+
 // Stop stop
 func (i *ExportApp) Stop() error {
 	return nil
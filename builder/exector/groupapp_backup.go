@@ -62,10 +62,15 @@ type BackupAPPNew struct {
 	SourceType  string `json:"source_type"`
 	BackupID    string `json:"backup_id"`
 	BackupSize  int64
+	Checksum    string
 	Logger      event.Logger
 	ImageClient sources.ImageClient
 	//full-online,full-offline
-	Mode     string `json:"mode"`
+	Mode string `json:"mode"`
+	//full,incremental
+	BackupType string `json:"backup_type"`
+	//Since the unix time since which the changed volume files are captured by an incremental backup
+	Since    int64 `json:"since"`
 	S3Config struct {
 		Provider   string `json:"provider"`
 		Endpoint   string `json:"endpoint"`
@@ -173,11 +178,20 @@ func (b *BackupAPPNew) Run(timeout time.Duration) error {
 	if strings.HasSuffix(b.SourceDir, "/") {
 		b.SourceDir = b.SourceDir[:len(b.SourceDir)-2]
 	}
+	if _, err := writeBackupChecksums(b.SourceDir); err != nil {
+		b.Logger.Error("Failed to compute the checksums of backup artifacts", map[string]string{"step": "backup_builder", "status": "failure"})
+		return err
+	}
 	if err := util.Zip(b.SourceDir, fmt.Sprintf("%s.zip", b.SourceDir)); err != nil {
 		b.Logger.Info(fmt.Sprintf("Compressed backup metadata failed"), map[string]string{"step": "backup_builder", "status": "starting"})
 		return err
 	}
 	b.BackupSize += util.GetFileSize(fmt.Sprintf("%s.zip", b.SourceDir))
+	checksum, _, err := util.FileSHA256(fmt.Sprintf("%s.zip", b.SourceDir))
+	if err != nil {
+		return fmt.Errorf("compute the checksum of backup package: %v", err)
+	}
+	b.Checksum = checksum
 	if err := os.RemoveAll(b.SourceDir); err != nil {
 		logrus.Warningf("error removing temporary direcotry: %v", err)
 	}
@@ -285,7 +299,7 @@ func (b *BackupAPPNew) backupServiceInfo(serviceInfos []*RegionServiceSnapshot)
 			_, sharepath := GetVolumeDir()
 			serviceVolumeData := path.Join(sharepath, "tenant", app.Service.TenantID, "service", app.Service.ServiceID)
 			if !util.DirIsEmpty(serviceVolumeData) {
-				if err := util.Zip(serviceVolumeData, dstDir); err != nil {
+				if err := b.zipVolumeData(serviceVolumeData, dstDir); err != nil {
 					logrus.Errorf("backup service(%s) volume data error.%s", app.ServiceID, err.Error())
 					return err
 				}
@@ -295,7 +309,7 @@ func (b *BackupAPPNew) backupServiceInfo(serviceInfos []*RegionServiceSnapshot)
 			dstDir := fmt.Sprintf("%s/data_%s/%s.zip", b.SourceDir, app.ServiceID, strings.Replace(volume.VolumeName, "/", "", -1))
 			hostPath := volume.HostPath
 			if hostPath != "" && !util.DirIsEmpty(hostPath) {
-				if err := util.Zip(hostPath, dstDir); err != nil {
+				if err := b.zipVolumeData(hostPath, dstDir); err != nil {
 					logrus.Errorf("backup service(%s) volume(%s) data error.%s", app.ServiceID, volume.VolumeName, err.Error())
 					return err
 				}
@@ -417,6 +431,7 @@ func (b *BackupAPPNew) updateBackupStatu(status string) error {
 	backupstatus.SourceDir = b.SourceDir
 	backupstatus.SourceType = b.SourceType
 	backupstatus.BuckupSize = b.BackupSize
+	backupstatus.Checksum = b.Checksum
 	return db.GetManager().AppBackupDao().UpdateModel(backupstatus)
 }
 
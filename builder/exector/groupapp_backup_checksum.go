@@ -0,0 +1,233 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goodrain/rainbond/util"
+)
+
+// BackupChecksumsFile is the file in the backup package which records the
+// checksums of all the artifacts of the backup.
+const BackupChecksumsFile = "checksums.json"
+
+// BackupArtifactChecksum the checksum of an artifact in the backup package
+type BackupArtifactChecksum struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupChecksums the checksums of all the artifacts in the backup package
+type BackupChecksums struct {
+	Algorithm string                   `json:"algorithm"`
+	Artifacts []BackupArtifactChecksum `json:"artifacts"`
+}
+
+// writeBackupChecksums computes the checksums of the metadata, slug packages,
+// images and volume data in sourceDir, and writes them to the checksums file.
+func writeBackupChecksums(sourceDir string) (*BackupChecksums, error) {
+	checksums := &BackupChecksums{Algorithm: "sha256"}
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == BackupChecksumsFile {
+			return nil
+		}
+		sum, size, err := util.FileSHA256(path)
+		if err != nil {
+			return fmt.Errorf("compute checksum of %s: %v", rel, err)
+		}
+		checksums.Artifacts = append(checksums.Artifacts, BackupArtifactChecksum{Path: rel, Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(checksums.Artifacts, func(i, j int) bool {
+		return checksums.Artifacts[i].Path < checksums.Artifacts[j].Path
+	})
+	body, err := json.MarshalIndent(checksums, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(sourceDir, BackupChecksumsFile), body, 0644); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// BackupVerifyProblem a corrupted or missing artifact of the backup
+type BackupVerifyProblem struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// BackupVerifyResult the result of verifying a backup package
+type BackupVerifyResult struct {
+	Package  string `json:"package"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+	// Checksummed is false for the backups made before the checksums are recorded,
+	// only the integrity of the zip entries is verified for them.
+	Checksummed bool                  `json:"checksummed"`
+	Artifacts   int                   `json:"artifacts"`
+	Valid       bool                  `json:"valid"`
+	Problems    []BackupVerifyProblem `json:"problems"`
+}
+
+func (r *BackupVerifyResult) addProblem(path, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, BackupVerifyProblem{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// VerifyBackupPackage re-reads the backup package pkg and checks every artifact
+// against the recorded checksums. checksum is the recorded checksum of the whole
+// package, and files maps the path of an artifact to a copy of it stored out of
+// the package, e.g. the metadata files, which are verified as well.
+func VerifyBackupPackage(pkg, checksum string, files map[string]string) (*BackupVerifyResult, error) {
+	sum, size, err := util.FileSHA256(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("read backup package: %v", err)
+	}
+	result := &BackupVerifyResult{Package: filepath.Base(pkg), Size: size, Checksum: sum}
+	defer func() {
+		result.Valid = len(result.Problems) == 0
+	}()
+	if checksum != "" && checksum != sum {
+		result.addProblem(result.Package, "package checksum mismatch, expected %s, got %s", checksum, sum)
+	}
+	reader, err := zip.OpenReader(pkg)
+	if err != nil {
+		result.addProblem(result.Package, "invalid backup package: %v", err)
+		return result, nil
+	}
+	defer reader.Close()
+
+	entries := make(map[string]*zip.File, len(reader.File))
+	var checksumsEntry *zip.File
+	for _, file := range reader.File {
+		entries[file.Name] = file
+		if file.Name != BackupChecksumsFile && !strings.HasSuffix(file.Name, "/"+BackupChecksumsFile) {
+			continue
+		}
+		// the one in the top directory
+		if checksumsEntry == nil || len(file.Name) < len(checksumsEntry.Name) {
+			checksumsEntry = file
+		}
+	}
+	if checksumsEntry == nil {
+		for _, file := range reader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			if _, _, err := readEntrySHA256(file); err != nil {
+				result.addProblem(file.Name, "unreadable: %v", err)
+			}
+			result.Artifacts++
+		}
+		return result, nil
+	}
+
+	var checksums BackupChecksums
+	rc, err := checksumsEntry.Open()
+	if err != nil {
+		result.addProblem(checksumsEntry.Name, "unreadable: %v", err)
+		return result, nil
+	}
+	err = json.NewDecoder(rc).Decode(&checksums)
+	rc.Close()
+	if err != nil {
+		result.addProblem(checksumsEntry.Name, "invalid checksums: %v", err)
+		return result, nil
+	}
+	result.Checksummed = true
+	prefix := strings.TrimSuffix(checksumsEntry.Name, BackupChecksumsFile)
+	recorded := make(map[string]BackupArtifactChecksum, len(checksums.Artifacts))
+	for _, artifact := range checksums.Artifacts {
+		recorded[artifact.Path] = artifact
+		result.Artifacts++
+		file, ok := entries[prefix+artifact.Path]
+		if !ok {
+			result.addProblem(artifact.Path, "missing in the backup package")
+			continue
+		}
+		sum, size, err := readEntrySHA256(file)
+		if err != nil {
+			result.addProblem(artifact.Path, "unreadable: %v", err)
+			continue
+		}
+		checkArtifact(result, artifact.Path, artifact, sum, size)
+	}
+
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		artifact, ok := recorded[path]
+		if !ok {
+			continue
+		}
+		sum, size, err := util.FileSHA256(files[path])
+		if err != nil {
+			result.addProblem(path+" (stored copy)", "unreadable: %v", err)
+			continue
+		}
+		checkArtifact(result, path+" (stored copy)", artifact, sum, size)
+	}
+	return result, nil
+}
+
+func checkArtifact(result *BackupVerifyResult, path string, artifact BackupArtifactChecksum, sum string, size int64) {
+	if size != artifact.Size {
+		result.addProblem(path, "size mismatch, expected %d, got %d", artifact.Size, size)
+		return
+	}
+	if sum != artifact.SHA256 {
+		result.addProblem(path, "checksum mismatch, expected %s, got %s", artifact.SHA256, sum)
+	}
+}
+
+// readEntrySHA256 reads the whole entry, so that the crc32 of it is checked as well.
+func readEntrySHA256(file *zip.File) (string, int64, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer rc.Close()
+	return util.ReaderSHA256(rc)
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/util"
+)
+
+func writeBackupDir(t *testing.T, root string) string {
+	t.Helper()
+	sourceDir := filepath.Join(root, "group_v1")
+	files := map[string]string{
+		"region_apps_metadata.json":  `{"Services":[]}`,
+		"console_apps_metadata.json": `{}`,
+		"app_sid/slug_v1.tgz":        "slug",
+		"app_sid/image_v2.tar":       "image",
+		"data_sid/__all_data.zip":    "data",
+	}
+	for name, body := range files {
+		file := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sourceDir
+}
+
+// capability_id: rainbond.app-backup.integrity-verify
+func TestWriteBackupChecksums(t *testing.T) {
+	sourceDir := writeBackupDir(t, t.TempDir())
+	checksums, err := writeBackupChecksums(sourceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksums.Artifacts) != 5 {
+		t.Fatalf("expected 5 artifacts, got %d", len(checksums.Artifacts))
+	}
+	if checksums.Artifacts[0].Path != "app_sid/image_v2.tar" || checksums.Artifacts[0].Size != 5 {
+		t.Fatalf("unexpected first artifact %+v", checksums.Artifacts[0])
+	}
+	// writing again does not checksum the checksums file itself
+	checksums, err = writeBackupChecksums(sourceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksums.Artifacts) != 5 {
+		t.Fatalf("expected 5 artifacts again, got %d", len(checksums.Artifacts))
+	}
+}
+
+// capability_id: rainbond.app-backup.integrity-verify
+func TestVerifyBackupPackage(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := writeBackupDir(t, root)
+	if _, err := writeBackupChecksums(sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	pkg := sourceDir + ".zip"
+	if err := util.Zip(sourceDir, pkg); err != nil {
+		t.Fatal(err)
+	}
+	checksum, _, err := util.FileSHA256(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := filepath.Join(root, "region_apps_metadata.json")
+	if err := os.WriteFile(stored, []byte(`{"Services":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := VerifyBackupPackage(pkg, checksum, map[string]string{"region_apps_metadata.json": stored})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || !result.Checksummed || result.Artifacts != 5 {
+		t.Fatalf("expected a valid package, got %+v", result)
+	}
+
+	// the stored metadata is modified
+	if err := os.WriteFile(stored, []byte(`{"Services":[{}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = VerifyBackupPackage(pkg, "bad", map[string]string{"region_apps_metadata.json": stored})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Problems) != 2 {
+		t.Fatalf("expected package checksum and stored copy problems, got %+v", result.Problems)
+	}
+	if !strings.Contains(result.Problems[0].Reason, "package checksum mismatch") || result.Problems[1].Path != "region_apps_metadata.json (stored copy)" {
+		t.Fatalf("unexpected problems %+v", result.Problems)
+	}
+}
+
+// capability_id: rainbond.app-backup.integrity-verify
+func TestVerifyBackupPackageCorruptedArtifact(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := writeBackupDir(t, root)
+	if _, err := writeBackupChecksums(sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "app_sid", "image_v2.tar"), []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "app_sid", "slug_v1.tgz"), []byte("glus"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(sourceDir, "data_sid", "__all_data.zip")); err != nil {
+		t.Fatal(err)
+	}
+	pkg := sourceDir + ".zip"
+	if err := util.Zip(sourceDir, pkg); err != nil {
+		t.Fatal(err)
+	}
+	result, err := VerifyBackupPackage(pkg, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %+v", result.Problems)
+	}
+	want := map[string]string{
+		"app_sid/image_v2.tar":    "size mismatch",
+		"app_sid/slug_v1.tgz":     "checksum mismatch",
+		"data_sid/__all_data.zip": "missing",
+	}
+	for _, problem := range result.Problems {
+		if !strings.Contains(problem.Reason, want[problem.Path]) || want[problem.Path] == "" {
+			t.Fatalf("unexpected problem %+v", problem)
+		}
+	}
+}
+
+// capability_id: rainbond.app-backup.integrity-verify
+func TestVerifyBackupPackageWithoutChecksums(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := writeBackupDir(t, root)
+	pkg := sourceDir + ".zip"
+	if err := util.Zip(sourceDir, pkg); err != nil {
+		t.Fatal(err)
+	}
+	result, err := VerifyBackupPackage(pkg, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checksummed || result.Artifacts != 5 {
+		t.Fatalf("expected a valid package without checksums, got %+v", result)
+	}
+
+	if err := os.WriteFile(pkg, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = VerifyBackupPackage(pkg, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || !strings.Contains(result.Problems[0].Reason, "invalid backup package") {
+		t.Fatalf("expected invalid package, got %+v", result.Problems)
+	}
+}
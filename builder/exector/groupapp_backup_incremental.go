@@ -0,0 +1,239 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/pkg/component/storage"
+	"github.com/goodrain/rainbond/util"
+	"github.com/sirupsen/logrus"
+)
+
+// manifestSuffix is the suffix of the file which lists all the entries of the
+// volume data when an incremental backup is made, it is beside the data archive.
+const manifestSuffix = ".manifest.json"
+
+// maxBackupChainLength stops walking the parents of a broken backup chain.
+const maxBackupChainLength = 1000
+
+// zipVolumeData compresses the volume data to target. Only the files changed
+// since the parent backup are compressed for an incremental backup, and the
+// names of all the entries are written to the manifest beside target.
+func (b *BackupAPPNew) zipVolumeData(source, target string) error {
+	if b.BackupType != dbmodel.BackupTypeIncremental {
+		return util.Zip(source, target)
+	}
+	names, err := util.ZipModifiedSince(source, target, time.Unix(b.Since, 0))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(target+manifestSuffix, body, 0644)
+}
+
+// rebuildIncrementalData turns the incremental volume data archives of the
+// backup into full ones, the archives of the parent backups are applied in
+// order, so that the data can be restored the same as a full backup.
+func (b *BackupAPPRestore) rebuildIncrementalData(backup *dbmodel.AppBackup) error {
+	if !backup.IsIncremental() {
+		return nil
+	}
+	manifests, err := filepath.Glob(filepath.Join(b.cacheDir, "data_*", "*"+manifestSuffix))
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return nil
+	}
+	parents, err := backupParents(backup)
+	if err != nil {
+		return err
+	}
+	layerDir := filepath.Join("/grdata/cache/tmp", b.BackupID, "layers_"+util.NewUUID())
+	defer os.RemoveAll(layerDir)
+	var packages []string
+	for _, parent := range parents {
+		pkg, err := b.downloadParentPackage(parent, filepath.Join(layerDir, parent.BackupID))
+		if err != nil {
+			return fmt.Errorf("download the package of backup %s: %v", parent.BackupID, err)
+		}
+		packages = append(packages, pkg)
+	}
+	for _, manifest := range manifests {
+		archive := strings.TrimSuffix(manifest, manifestSuffix)
+		rel, err := filepath.Rel(b.cacheDir, archive)
+		if err != nil {
+			return err
+		}
+		var names []string
+		body, err := ioutil.ReadFile(manifest)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, &names); err != nil {
+			return fmt.Errorf("read manifest %s: %v", rel, err)
+		}
+		var layers []string
+		for i, pkg := range packages {
+			layer := filepath.Join(layerDir, parents[i].BackupID, rel)
+			if err := extractPackageEntry(pkg, filepath.ToSlash(rel), layer); err != nil {
+				if os.IsNotExist(err) {
+					// the volume was empty when the parent backup was made
+					continue
+				}
+				return err
+			}
+			layers = append(layers, layer)
+		}
+		layers = append(layers, archive)
+		if err := mergeDataArchives(layers, names, archive+".full"); err != nil {
+			return fmt.Errorf("rebuild volume data %s: %v", rel, err)
+		}
+		if err := os.Rename(archive+".full", archive); err != nil {
+			return err
+		}
+		logrus.Infof("rebuild volume data %s from %d incremental layers", rel, len(layers))
+	}
+	return nil
+}
+
+// backupParents returns the parents of an incremental backup, the full backup comes first.
+func backupParents(backup *dbmodel.AppBackup) ([]*dbmodel.AppBackup, error) {
+	var parents []*dbmodel.AppBackup
+	current := backup
+	for current.IsIncremental() {
+		if current.ParentBackupID == "" || len(parents) >= maxBackupChainLength {
+			return nil, fmt.Errorf("the backup chain of %s is broken", backup.BackupID)
+		}
+		parent, err := db.GetManager().AppBackupDao().GetAppBackup(current.ParentBackupID)
+		if err != nil {
+			return nil, fmt.Errorf("the parent backup %s of %s: %v", current.ParentBackupID, current.BackupID, err)
+		}
+		if parent.Status != "success" {
+			return nil, fmt.Errorf("the parent backup %s of %s is not successful", parent.BackupID, current.BackupID)
+		}
+		parents = append([]*dbmodel.AppBackup{parent}, parents...)
+		current = parent
+	}
+	return parents, nil
+}
+
+// downloadParentPackage downloads the package of the parent backup to dir.
+func (b *BackupAPPRestore) downloadParentPackage(parent *dbmodel.AppBackup, dir string) (string, error) {
+	bucketName := b.S3Config.BucketName
+	if bucketName == "" {
+		bucketName = "grdata"
+	}
+	_, filename := filepath.Split(parent.SourceDir)
+	s3Key := fmt.Sprintf("/%s/backup/%s/%s", bucketName, parent.GroupID, filename)
+	if err := util.CheckAndCreateDir(dir); err != nil {
+		return "", err
+	}
+	if err := storage.Default().StorageCli.DownloadFileToDir(s3Key, dir); err != nil {
+		return "", err
+	}
+	pkg := filepath.Join(dir, filename)
+	if ok, _ := util.FileExists(pkg); ok {
+		return pkg, nil
+	}
+	// the local storage keeps the package at the key path
+	if ok, _ := util.FileExists(s3Key); ok {
+		return s3Key, nil
+	}
+	return "", fmt.Errorf("backup package %s not found", s3Key)
+}
+
+// extractPackageEntry extracts the entry whose name ends with name from the
+// backup package to target, an error satisfying os.IsNotExist is returned if
+// there is no such entry.
+func extractPackageEntry(pkg, name, target string) error {
+	reader, err := zip.OpenReader(pkg)
+	if err != nil {
+		return fmt.Errorf("open backup package %s: %v", pkg, err)
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.Name != name && !strings.HasSuffix(file.Name, "/"+name) {
+			continue
+		}
+		if err := util.CheckAndCreateDir(filepath.Dir(target)); err != nil {
+			return err
+		}
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+		_, err = io.Copy(dst, src)
+		return err
+	}
+	return os.ErrNotExist
+}
+
+// mergeDataArchives writes the entries listed in names to target, an entry of
+// a later archive overrides the same one of the earlier archives, and the
+// entries not listed are deleted files.
+func mergeDataArchives(archives, names []string, target string) error {
+	entries := make(map[string]*zip.File)
+	for _, archive := range archives {
+		reader, err := zip.OpenReader(archive)
+		if err != nil {
+			return fmt.Errorf("open data archive %s: %v", archive, err)
+		}
+		defer reader.Close()
+		for _, file := range reader.File {
+			entries[file.Name] = file
+		}
+	}
+	out, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := zip.NewWriter(out)
+	for _, name := range names {
+		file, ok := entries[name]
+		if !ok {
+			return fmt.Errorf("file %s is missing in the backup chain", name)
+		}
+		if err := writer.Copy(file); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
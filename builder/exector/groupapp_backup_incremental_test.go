@@ -0,0 +1,175 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+)
+
+func readArchive(t *testing.T, archive string) map[string]string {
+	t.Helper()
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	res := make(map[string]string)
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			res[file.Name] = ""
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res[file.Name] = string(body)
+	}
+	return res
+}
+
+// capability_id: rainbond.app-backup.incremental-volume-data
+func TestIncrementalVolumeDataRebuild(t *testing.T) {
+	root := t.TempDir()
+	volume := filepath.Join(root, "volume")
+	if err := os.MkdirAll(filepath.Join(volume, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{"keep.txt": "keep", "change.txt": "v1", "sub/delete.txt": "delete"} {
+		if err := os.WriteFile(filepath.Join(volume, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	full := &BackupAPPNew{BackupType: dbmodel.BackupTypeFull}
+	fullArchive := filepath.Join(root, "full", "data_sid", "vol.zip")
+	if err := full.zipVolumeData(volume, fullArchive); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := util.FileExists(fullArchive + manifestSuffix); ok {
+		t.Fatal("expected no manifest for a full backup")
+	}
+
+	// since is in seconds, start from the next second
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	since := time.Now()
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(volume, "change.txt"), []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(volume, "sub", "add.txt"), []byte("add"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(volume, "sub", "delete.txt")); err != nil {
+		t.Fatal(err)
+	}
+	incremental := &BackupAPPNew{BackupType: dbmodel.BackupTypeIncremental, Since: since.Unix()}
+	incrArchive := filepath.Join(root, "incr", "data_sid", "vol.zip")
+	if err := incremental.zipVolumeData(volume, incrArchive); err != nil {
+		t.Fatal(err)
+	}
+	layer := readArchive(t, incrArchive)
+	if _, ok := layer["volume/keep.txt"]; ok {
+		t.Fatal("expected the unchanged file not in the incremental archive")
+	}
+	if layer["volume/change.txt"] != "v2" || layer["volume/sub/add.txt"] != "add" {
+		t.Fatalf("expected the changed files in the incremental archive, got %v", layer)
+	}
+
+	body, err := ioutil.ReadFile(incrArchive + manifestSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err := json.Unmarshal(body, &names); err != nil {
+		t.Fatal(err)
+	}
+	merged := filepath.Join(root, "merged.zip")
+	if err := mergeDataArchives([]string{fullArchive, incrArchive}, names, merged); err != nil {
+		t.Fatal(err)
+	}
+	result := readArchive(t, merged)
+	var got []string
+	for name, body := range result {
+		got = append(got, name+"="+body)
+	}
+	sort.Strings(got)
+	want := []string{"volume/=", "volume/change.txt=v2", "volume/keep.txt=keep", "volume/sub/=", "volume/sub/add.txt=add"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected rebuilt entries %v, got %v", want, got)
+	}
+}
+
+// capability_id: rainbond.app-backup.incremental-volume-data
+func TestMergeDataArchivesMissingEntry(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "volume")
+	if err := os.MkdirAll(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(root, "vol.zip")
+	if err := util.Zip(source, archive); err != nil {
+		t.Fatal(err)
+	}
+	err := mergeDataArchives([]string{archive}, []string{"volume/", "volume/lost.txt"}, filepath.Join(root, "merged.zip"))
+	if err == nil || !strings.Contains(err.Error(), "missing in the backup chain") {
+		t.Fatalf("expected missing entry error, got %v", err)
+	}
+}
+
+// capability_id: rainbond.app-backup.incremental-volume-data
+func TestExtractPackageEntry(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "group_version")
+	if err := os.MkdirAll(filepath.Join(pkgDir, "data_sid"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "data_sid", "vol.zip"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg := filepath.Join(root, "group_version.zip")
+	if err := util.Zip(pkgDir, pkg); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(root, "layer", "data_sid", "vol.zip")
+	if err := extractPackageEntry(pkg, "data_sid/vol.zip", target); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadFile(target); string(body) != "data" {
+		t.Fatalf("expected extracted data, got %q", body)
+	}
+	if err := extractPackageEntry(pkg, "data_sid/other.zip", target); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+}
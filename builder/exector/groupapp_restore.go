@@ -54,11 +54,15 @@ type BackupAPPRestore struct {
 	//RestoreMode(od)     other datacenter
 	RestoreMode string `json:"restore_mode"`
 	RestoreID   string `json:"restore_id"`
+	//DryRun validates the backup could be restored without touching the target tenant
+	DryRun      bool `json:"dry_run"`
 	ImageClient sources.ImageClient
 	cacheDir    string
 	//serviceChange  key: oldServiceID
 	serviceChange map[string]*Info
 	volumeIDMap   map[uint]uint
+	packagePath   string
+	dryRunReport  *RestoreDryRunReport
 
 	S3Config struct {
 		Provider   string `json:"provider"`
@@ -121,6 +125,10 @@ func (b *BackupAPPRestore) Run(timeout time.Duration) error {
 	if err := b.downloadBackupPackage(backup); err != nil {
 		return fmt.Errorf("error downloading backup package: %v", err)
 	}
+	if err := b.rebuildIncrementalData(backup); err != nil {
+		b.Logger.Error("重建增量备份数据失败", map[string]string{"step": "restore_builder", "status": "failure"})
+		return fmt.Errorf("error rebuilding incremental backup data: %v", err)
+	}
 
 	// Download metadata files from S3 (always download fresh metadata from S3, not relying on zip contents)
 	if err := b.downloadMetadataFromS3(backup); err != nil {
@@ -159,6 +167,9 @@ func (b *BackupAPPRestore) Run(timeout time.Duration) error {
 
 	b.Logger.Info("读取备份元数据完成", map[string]string{"step": "restore_builder", "status": "running"})
 	logrus.Infof("backup id: %s; successfully read metadata.", b.BackupID)
+	if b.DryRun {
+		return b.dryRun(backup, metaVersion, &appSnapshot)
+	}
 	//modify the metadata
 	if err := b.modify(&appSnapshot); err != nil {
 		return err
@@ -731,6 +742,7 @@ func (b *BackupAPPRestore) downloadBackupPackage(backup *dbmodel.AppBackup) erro
 
 	// Download from S3 to cache dir
 	zipPath := path.Join(b.cacheDir, filename)
+	b.packagePath = zipPath
 	logrus.Infof("Downloading backup package from S3: %s -> %s", s3Key, zipPath)
 	if err := storageCli.DownloadFileToDir(s3Key, b.cacheDir); err != nil {
 		b.Logger.Error("下载备份包失败", map[string]string{"step": "restore_builder", "status": "failure"})
@@ -845,22 +857,26 @@ func (b *BackupAPPRestore) ErrorCallBack(err error) {
 	if err != nil {
 		logrus.Errorf("restore backup group app failure %s", err)
 		b.Logger.Error(util.Translation("restore backup group app failure"), map[string]string{"step": "callback", "status": "failure"})
-		b.clear()
+		if !b.DryRun {
+			b.clear()
+		}
 		b.saveResult("failed", err.Error())
 	}
 }
 
 // RestoreResult RestoreResult
 type RestoreResult struct {
-	Status        string           `json:"status"`
-	Message       string           `json:"message"`
-	CreateTime    time.Time        `json:"create_time"`
-	ServiceChange map[string]*Info `json:"service_change"`
-	BackupID      string           `json:"backup_id"`
-	RestoreMode   string           `json:"restore_mode"`
-	EventID       string           `json:"event_id"`
-	RestoreID     string           `json:"restore_id"`
-	CacheDir      string           `json:"cache_dir"`
+	Status        string               `json:"status"`
+	Message       string               `json:"message"`
+	CreateTime    time.Time            `json:"create_time"`
+	ServiceChange map[string]*Info     `json:"service_change"`
+	BackupID      string               `json:"backup_id"`
+	RestoreMode   string               `json:"restore_mode"`
+	EventID       string               `json:"event_id"`
+	RestoreID     string               `json:"restore_id"`
+	CacheDir      string               `json:"cache_dir"`
+	DryRun        bool                 `json:"dry_run"`
+	DryRunReport  *RestoreDryRunReport `json:"dry_run_report,omitempty"`
 }
 
 func (b *BackupAPPRestore) saveResult(status, message string) {
@@ -874,6 +890,8 @@ func (b *BackupAPPRestore) saveResult(status, message string) {
 		EventID:       b.EventID,
 		RestoreID:     b.RestoreID,
 		CacheDir:      b.cacheDir,
+		DryRun:        b.DryRun,
+		DryRunReport:  b.dryRunReport,
 	}
 	body, _ := ffjson.Marshal(rr)
 	key := "/rainbond/backup_restore/" + rr.RestoreID
@@ -0,0 +1,274 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/goodrain/rainbond/builder"
+	"github.com/goodrain/rainbond/builder/sources"
+	"github.com/goodrain/rainbond/builder/sources/registry"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/sirupsen/logrus"
+)
+
+// RestoreDryRunReport the result of a dry-run restore, nothing is written to the target tenant
+type RestoreDryRunReport struct {
+	MetadataVersion string                `json:"metadata_version"`
+	Services        int                   `json:"services"`
+	Integrity       *BackupVerifyResult   `json:"integrity,omitempty"`
+	Images          []RestoreDryRunImage  `json:"images"`
+	Volumes         []RestoreDryRunVolume `json:"volumes"`
+	// RequiredSize the size of the volume data after decompressing
+	RequiredSize  int64    `json:"required_size"`
+	AvailableSize int64    `json:"available_size"`
+	Problems      []string `json:"problems"`
+}
+
+// RestoreDryRunImage the image or slug package of a build version or plugin
+type RestoreDryRunImage struct {
+	ServiceAlias string `json:"service_alias,omitempty"`
+	PluginID     string `json:"plugin_id,omitempty"`
+	Version      string `json:"version"`
+	Image        string `json:"image"`
+	// InPackage whether the image or slug is in the backup package
+	InPackage bool `json:"in_package"`
+	// InRegistry whether the image is still in the registry, it is only checked
+	// if the image is missing in the backup package
+	InRegistry bool `json:"in_registry"`
+}
+
+// RestoreDryRunVolume the volume data of a component
+type RestoreDryRunVolume struct {
+	ServiceAlias string `json:"service_alias"`
+	VolumeName   string `json:"volume_name"`
+	Size         int64  `json:"size"`
+}
+
+func (r *RestoreDryRunReport) addProblem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// imageInRegistry checks whether the image exists in its registry
+var imageInRegistry = func(image string) (bool, error) {
+	imageInfo := sources.ImageNameHandle(image)
+	reg, err := registry.NewInsecure(imageInfo.Host, builder.REGISTRYUSER, builder.REGISTRYPASS)
+	if err != nil {
+		return false, err
+	}
+	return reg.ManifestExists(imageInfo.Name, imageInfo.Tag)
+}
+
+// availableDiskSize returns the free space of the file system which path is on
+var availableDiskSize = func(path string) (int64, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(usage.Free), nil
+}
+
+// dryRun validates the backup could be restored without touching the target
+// tenant, the report is saved as the restore result.
+func (b *BackupAPPRestore) dryRun(backup *dbmodel.AppBackup, metaVersion string, appSnapshot *AppSnapshot) error {
+	report := &RestoreDryRunReport{MetadataVersion: metaVersion, Services: len(appSnapshot.Services)}
+	b.Logger.Info("开始校验备份数据完整性", map[string]string{"step": "restore_builder", "status": "running"})
+	integrity, err := VerifyBackupPackage(b.packagePath, backup.Checksum, map[string]string{
+		"region_apps_metadata.json":  path.Join(b.cacheDir, "region_apps_metadata.json"),
+		"console_apps_metadata.json": path.Join(b.cacheDir, "console_apps_metadata.json"),
+	})
+	if err != nil {
+		return err
+	}
+	report.Integrity = integrity
+	for _, problem := range integrity.Problems {
+		report.addProblem("%s: %s", problem.Path, problem.Reason)
+	}
+	b.checkMetadata(report, appSnapshot)
+	b.checkImages(report, appSnapshot)
+	b.checkVolumes(report, appSnapshot)
+
+	b.dryRunReport = report
+	if len(report.Problems) > 0 {
+		b.Logger.Error(fmt.Sprintf("模拟恢复发现 %d 个问题", len(report.Problems)), map[string]string{"step": "restore_builder", "status": "failure"})
+		b.saveResult("failed", strings.Join(report.Problems, "; "))
+		return nil
+	}
+	b.Logger.Info("模拟恢复校验通过", map[string]string{"step": "restore_builder", "status": "success"})
+	b.saveResult("success", "")
+	return nil
+}
+
+func (b *BackupAPPRestore) checkMetadata(report *RestoreDryRunReport, appSnapshot *AppSnapshot) {
+	if len(appSnapshot.Services) == 0 {
+		report.addProblem("no component in the backup metadata")
+	}
+	for i, app := range appSnapshot.Services {
+		if app == nil || app.Service == nil {
+			report.addProblem("component %d has no metadata", i)
+			continue
+		}
+		if app.ServiceID == "" || app.ServiceID != app.Service.ServiceID {
+			report.addProblem("component %s has inconsistent service id", app.Service.ServiceAlias)
+		}
+		for _, volume := range app.ServiceVolume {
+			if volume.VolumeName == "" || volume.VolumePath == "" {
+				report.addProblem("component %s has an invalid volume %q", app.Service.ServiceAlias, volume.VolumeName)
+			}
+		}
+	}
+	for _, pv := range appSnapshot.PluginBuildVersions {
+		if pv.BuildLocalImage == "" {
+			report.addProblem("plugin %s version %s has no image", pv.PluginID, pv.DeployVersion)
+		}
+	}
+}
+
+func (b *BackupAPPRestore) checkImages(report *RestoreDryRunReport, appSnapshot *AppSnapshot) {
+	for _, app := range appSnapshot.Services {
+		if app == nil || app.Service == nil {
+			continue
+		}
+		for _, version := range app.Versions {
+			if version.FinalStatus != "success" {
+				continue
+			}
+			image := RestoreDryRunImage{ServiceAlias: app.Service.ServiceAlias, Version: version.BuildVersion, Image: version.DeliveredPath}
+			var pkg string
+			switch version.DeliveredType {
+			case "slug":
+				pkg = fmt.Sprintf("%s/app_%s/slug_%s.tgz", b.cacheDir, app.ServiceID, version.BuildVersion)
+				image.InPackage, _ = util.FileExists(pkg)
+			case "image":
+				pkg = fmt.Sprintf("%s/app_%s/image_%s.tar", b.cacheDir, app.ServiceID, version.BuildVersion)
+				image.InPackage = b.checkImagePackage(report, pkg)
+			default:
+				continue
+			}
+			if !image.InPackage {
+				if version.DeliveredType == "image" {
+					exists, err := imageInRegistry(version.DeliveredPath)
+					if err != nil {
+						logrus.Warningf("check image %s in registry: %v", version.DeliveredPath, err)
+					}
+					image.InRegistry = exists
+				}
+				report.addProblem("component %s version %s: %s is missing in the backup package", app.Service.ServiceAlias, version.BuildVersion, path.Base(pkg))
+			}
+			report.Images = append(report.Images, image)
+		}
+	}
+	for _, pv := range appSnapshot.PluginBuildVersions {
+		pkg := fmt.Sprintf("%s/plugin_%s/image_%s.tar", b.cacheDir, pv.PluginID, pv.DeployVersion)
+		image := RestoreDryRunImage{PluginID: pv.PluginID, Version: pv.DeployVersion, Image: pv.BuildLocalImage}
+		image.InPackage = b.checkImagePackage(report, pkg)
+		if !image.InPackage {
+			exists, err := imageInRegistry(pv.BuildLocalImage)
+			if err != nil {
+				logrus.Warningf("check image %s in registry: %v", pv.BuildLocalImage, err)
+			}
+			image.InRegistry = exists
+			report.addProblem("plugin %s version %s: %s is missing in the backup package", pv.PluginID, pv.DeployVersion, path.Base(pkg))
+		}
+		report.Images = append(report.Images, image)
+	}
+}
+
+// checkImagePackage checks the image package is a tarball saved by docker
+func (b *BackupAPPRestore) checkImagePackage(report *RestoreDryRunReport, pkg string) bool {
+	file, err := os.Open(pkg)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.addProblem("%s is not a valid image package: %v", strings.TrimPrefix(pkg, b.cacheDir+"/"), err)
+			return true
+		}
+		if header.Name == "manifest.json" {
+			return true
+		}
+	}
+	report.addProblem("%s is not a valid image package: manifest.json not found", strings.TrimPrefix(pkg, b.cacheDir+"/"))
+	return true
+}
+
+func (b *BackupAPPRestore) checkVolumes(report *RestoreDryRunReport, appSnapshot *AppSnapshot) {
+	for _, app := range appSnapshot.Services {
+		if app == nil || app.Service == nil {
+			continue
+		}
+		// the data of all the volumes is restored from one archive if it exists
+		allData := fmt.Sprintf("%s/data_%s/%s.zip", b.cacheDir, app.ServiceID, "__all_data")
+		if ok, _ := util.FileExists(allData); ok {
+			b.checkVolumeArchive(report, app.Service.ServiceAlias, "__all_data", allData)
+			continue
+		}
+		for _, volume := range app.ServiceVolume {
+			if volume.HostPath == "" {
+				continue
+			}
+			archive := fmt.Sprintf("%s/data_%s/%s.zip", b.cacheDir, app.ServiceID, strings.Replace(volume.VolumeName, "/", "", -1))
+			if ok, _ := util.FileExists(archive); !ok {
+				// the volume was empty when backing up
+				continue
+			}
+			b.checkVolumeArchive(report, app.Service.ServiceAlias, volume.VolumeName, archive)
+		}
+	}
+	_, sharePath := GetVolumeDir()
+	available, err := availableDiskSize(sharePath)
+	if err != nil {
+		logrus.Warningf("get the available size of %s: %v", sharePath, err)
+		return
+	}
+	report.AvailableSize = available
+	if report.RequiredSize > available {
+		report.addProblem("volume data needs %d bytes, but only %d bytes are available in %s", report.RequiredSize, available, sharePath)
+	}
+}
+
+func (b *BackupAPPRestore) checkVolumeArchive(report *RestoreDryRunReport, serviceAlias, volumeName, archive string) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		report.addProblem("component %s volume %s: invalid data archive: %v", serviceAlias, volumeName, err)
+		return
+	}
+	defer reader.Close()
+	var size int64
+	for _, file := range reader.File {
+		size += int64(file.UncompressedSize64)
+	}
+	report.RequiredSize += size
+	report.Volumes = append(report.Volumes, RestoreDryRunVolume{ServiceAlias: serviceAlias, VolumeName: volumeName, Size: size})
+}
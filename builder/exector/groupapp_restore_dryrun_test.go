@@ -0,0 +1,163 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+)
+
+func writeImageTar(t *testing.T, file string, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	writer := tar.NewWriter(f)
+	for _, name := range names {
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dryRunSnapshot() *AppSnapshot {
+	return &AppSnapshot{
+		Services: []*RegionServiceSnapshot{{
+			ServiceID: "sid",
+			Service:   &dbmodel.TenantServices{ServiceID: "sid", ServiceAlias: "gr123456"},
+			ServiceVolume: []*dbmodel.TenantServiceVolume{
+				{VolumeName: "data", VolumePath: "/data", HostPath: "/grdata/tenant/t/service/sid/data"},
+			},
+			Versions: []*dbmodel.VersionInfo{
+				{BuildVersion: "v1", DeliveredType: "image", DeliveredPath: "goodrain.me/app:v1", FinalStatus: "success"},
+				{BuildVersion: "v2", DeliveredType: "slug", DeliveredPath: "/grdata/build/v2.tgz", FinalStatus: "success"},
+				{BuildVersion: "v3", DeliveredType: "image", DeliveredPath: "goodrain.me/app:v3", FinalStatus: "failure"},
+			},
+		}},
+		PluginBuildVersions: []*dbmodel.TenantPluginBuildVersion{
+			{PluginID: "pid", DeployVersion: "p1", BuildLocalImage: "goodrain.me/plugin:p1"},
+		},
+	}
+}
+
+// capability_id: rainbond.app-backup.restore-dry-run
+func TestRestoreDryRunChecks(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeImageTar(t, filepath.Join(cacheDir, "app_sid", "image_v1.tar"), "layer.tar", "manifest.json")
+	if err := os.WriteFile(filepath.Join(cacheDir, "app_sid", "slug_v2.tgz"), []byte("slug"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeImageTar(t, filepath.Join(cacheDir, "plugin_pid", "image_p1.tar"), "manifest.json")
+	volume := filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(volume, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(volume, "file"), []byte(strings.Repeat("a", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.Zip(volume, filepath.Join(cacheDir, "data_sid", "data.zip")); err != nil {
+		t.Fatal(err)
+	}
+
+	oldAvailable := availableDiskSize
+	defer func() { availableDiskSize = oldAvailable }()
+	availableDiskSize = func(path string) (int64, error) { return 1 << 20, nil }
+
+	b := &BackupAPPRestore{cacheDir: cacheDir}
+	report := &RestoreDryRunReport{}
+	snapshot := dryRunSnapshot()
+	b.checkMetadata(report, snapshot)
+	b.checkImages(report, snapshot)
+	b.checkVolumes(report, snapshot)
+	if len(report.Problems) != 0 {
+		t.Fatalf("expected no problems, got %v", report.Problems)
+	}
+	if len(report.Images) != 3 {
+		t.Fatalf("expected 3 images, got %+v", report.Images)
+	}
+	if len(report.Volumes) != 1 || report.Volumes[0].Size != 1000 || report.RequiredSize != 1000 {
+		t.Fatalf("unexpected volumes %+v, required %d", report.Volumes, report.RequiredSize)
+	}
+
+	// not enough space for the volume data
+	availableDiskSize = func(path string) (int64, error) { return 10, nil }
+	report = &RestoreDryRunReport{}
+	b.checkVolumes(report, snapshot)
+	if len(report.Problems) != 1 || !strings.Contains(report.Problems[0], "only 10 bytes are available") {
+		t.Fatalf("expected disk space problem, got %v", report.Problems)
+	}
+}
+
+// capability_id: rainbond.app-backup.restore-dry-run
+func TestRestoreDryRunMissingImages(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeImageTar(t, filepath.Join(cacheDir, "plugin_pid", "image_p1.tar"), "layer.tar")
+
+	oldInRegistry := imageInRegistry
+	defer func() { imageInRegistry = oldInRegistry }()
+	var checked []string
+	imageInRegistry = func(image string) (bool, error) {
+		checked = append(checked, image)
+		return image == "goodrain.me/app:v1", nil
+	}
+
+	b := &BackupAPPRestore{cacheDir: cacheDir}
+	report := &RestoreDryRunReport{}
+	snapshot := dryRunSnapshot()
+	snapshot.Services[0].Service.ServiceID = "other"
+	b.checkMetadata(report, snapshot)
+	b.checkImages(report, snapshot)
+	if len(checked) != 1 || checked[0] != "goodrain.me/app:v1" {
+		t.Fatalf("expected only the missing app image checked in registry, got %v", checked)
+	}
+	if !report.Images[0].InRegistry || report.Images[0].InPackage {
+		t.Fatalf("unexpected image report %+v", report.Images[0])
+	}
+	want := []string{
+		"inconsistent service id",
+		"image_v1.tar is missing",
+		"slug_v2.tgz is missing",
+		"manifest.json not found",
+	}
+	if len(report.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), report.Problems)
+	}
+	for i := range want {
+		if !strings.Contains(report.Problems[i], want[i]) {
+			t.Fatalf("expected problem %q, got %q", want[i], report.Problems[i])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kustomize renders the rainbond application templates into a kustomize base
+// with per-environment overlays, or into a flat kubernetes manifest bundle.
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// App the parts of the rainbond application template (the metadata.json of the
+// export) the kubernetes manifests are rendered from
+type App struct {
+	AppName      string         `json:"group_name"`
+	AppVersion   string         `json:"group_version"`
+	Components   []*Component   `json:"apps"`
+	ConfigGroups []*ConfigGroup `json:"app_config_groups"`
+	HTTPRoutes   []*HTTPRoute   `json:"ingress_http_routes"`
+}
+
+// Component a component of the application template
+type Component struct {
+	ServiceKey       string `json:"service_key"`
+	ServiceShareID   string `json:"service_share_uuid"`
+	ServiceCname     string `json:"service_cname"`
+	K8sComponentName string `json:"k8s_component_name"`
+	ShareImage       string `json:"share_image"`
+	Image            string `json:"image"`
+	AppImage         struct {
+		HubUser     string `json:"hub_user"`
+		HubPassword string `json:"hub_password"`
+	} `json:"service_image"`
+	Cmd string `json:"cmd"`
+	// DeployType stateless_multiple, stateless_singleton, state_multiple, state_singleton, job or cronjob
+	DeployType       string `json:"extend_method"`
+	ExtendMethodRule struct {
+		MinNode int `json:"min_node"`
+	} `json:"extend_method_map"`
+	// JobStrategy the json encoded strategy of job and cronjob components, e.g. {"schedule":"*/5 * * * *"}
+	JobStrategy string `json:"job_strategy"`
+	// Memory in Mi and CPU in millicores, 0 means unlimited
+	Memory       int       `json:"memory"`
+	CPU          int       `json:"cpu"`
+	Ports        []*Port   `json:"port_map_list"`
+	Envs         []*Env    `json:"service_env_map_list"`
+	ConnectInfos []*Env    `json:"service_connect_info_map_list"`
+	Volumes      []*Volume `json:"service_volume_map_list"`
+	Probes       []*Probe  `json:"probes"`
+	Deps         []struct {
+		DepServiceKey string `json:"dep_service_key"`
+	} `json:"dep_service_map_list"`
+}
+
+// Port a port of a component
+type Port struct {
+	PortAlias      string `json:"port_alias"`
+	Protocol       string `json:"protocol"`
+	ContainerPort  int    `json:"container_port"`
+	IsInnerService bool   `json:"is_inner_service"`
+	IsOuterService bool   `json:"is_outer_service"`
+}
+
+// Env an environment variable of a component
+type Env struct {
+	AttrName  string `json:"attr_name"`
+	AttrValue string `json:"attr_value"`
+}
+
+// Volume a volume or a config file of a component
+type Volume struct {
+	VolumeName string `json:"volume_name"`
+	// VolumeType config-file, memoryfs or a storage type, e.g. share-file, local
+	VolumeType  string `json:"volume_type"`
+	VolumePath  string `json:"volume_path"`
+	FileContent string `json:"file_content"`
+	// VolumeCapacity in Gi, 0 means the default capacity
+	VolumeCapacity int64  `json:"volume_capacity"`
+	AccessMode     string `json:"access_mode"`
+}
+
+// Probe a health check of a component
+type Probe struct {
+	// Mode readiness, liveness or ignore
+	Mode string `json:"mode"`
+	// Scheme tcp, http or cmd
+	Scheme             string `json:"scheme"`
+	Port               int    `json:"port"`
+	Path               string `json:"path"`
+	HTTPHeader         string `json:"http_header"`
+	Cmd                string `json:"cmd"`
+	InitialDelaySecond int    `json:"initial_delay_second"`
+	PeriodSecond       int    `json:"period_second"`
+	TimeoutSecond      int    `json:"timeout_second"`
+	SuccessThreshold   int    `json:"success_threshold"`
+	FailureThreshold   int    `json:"failure_threshold"`
+	// IsUsed is a bool or an int in the templates of different versions
+	IsUsed interface{} `json:"is_used"`
+}
+
+// ConfigGroup an application config group injected into its components as env vars
+type ConfigGroup struct {
+	Name          string            `json:"name"`
+	ConfigItems   map[string]string `json:"config_items"`
+	ComponentKeys []string          `json:"component_keys"`
+}
+
+// HTTPRoute a gateway route of a component port
+type HTTPRoute struct {
+	ComponentKey string `json:"component_key"`
+	Port         int    `json:"port"`
+	DomainName   string `json:"domain_name"`
+	Location     string `json:"location"`
+	SSL          bool   `json:"ssl"`
+}
+
+// ParseApp parses the application template
+func ParseApp(metadata []byte) (*App, error) {
+	var app App
+	if err := json.Unmarshal(metadata, &app); err != nil {
+		return nil, fmt.Errorf("parse application template: %v", err)
+	}
+	if len(app.Components) == 0 {
+		return nil, fmt.Errorf("the application template has no components")
+	}
+	return &app, nil
+}
+
+// key the key config groups, routes and dependencies refer to the component with
+func (c *Component) key() string {
+	if c.ServiceShareID != "" {
+		return c.ServiceShareID
+	}
+	return c.ServiceKey
+}
+
+func (c *Component) image() string {
+	if c.ShareImage != "" {
+		return c.ShareImage
+	}
+	return c.Image
+}
+
+func (c *Component) stateful() bool {
+	return strings.HasPrefix(c.DeployType, "state_")
+}
+
+func (p *Probe) used() bool {
+	switch v := p.IsUsed.(type) {
+	case nil:
+		return true
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	default:
+		return true
+	}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// dnsName converts the name into a dns-1123 label, it returns empty when nothing is left
+func dnsName(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 63 {
+		name = strings.Trim(name[:63], "-")
+	}
+	return name
+}
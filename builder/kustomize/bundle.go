@@ -0,0 +1,144 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultEnvironments the overlays generated when no environment is specified
+var DefaultEnvironments = []string{"dev", "prod"}
+
+// Options the options of the exported manifests
+type Options struct {
+	// Environments the overlays of the kustomize export
+	Environments []string
+	// Namespace the namespace the manifests are deployed in, empty means unset
+	Namespace string
+}
+
+type kustomization struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Namespace  string               `json:"namespace,omitempty"`
+	Resources  []string             `json:"resources"`
+	Labels     []kustomizationLabel `json:"labels,omitempty"`
+}
+
+type kustomizationLabel struct {
+	Pairs map[string]string `json:"pairs"`
+}
+
+func newKustomization(resources []string) *kustomization {
+	return &kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	}
+}
+
+// WriteKustomize writes the kustomize base of the application into dir/base, and an
+// overlay of each environment into dir/overlays/<env>. The overlays only set the namespace
+// and the environment label, the images are overridden per environment by adding an images
+// entry to the overlay.
+func WriteKustomize(dir string, app *App, opts Options) error {
+	resources, err := Render(app)
+	if err != nil {
+		return err
+	}
+	baseDir := filepath.Join(dir, "base")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return err
+	}
+	var files []string
+	for _, res := range resources {
+		data, err := yaml.Marshal(res.Object)
+		if err != nil {
+			return fmt.Errorf("encode %s %s: %v", res.Kind, res.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(baseDir, res.FileName()), data, 0644); err != nil {
+			return err
+		}
+		files = append(files, res.FileName())
+	}
+	if err := writeKustomization(baseDir, newKustomization(files)); err != nil {
+		return err
+	}
+
+	envs := opts.Environments
+	if len(envs) == 0 {
+		envs = DefaultEnvironments
+	}
+	for _, env := range envs {
+		env = dnsName(env)
+		if env == "" {
+			continue
+		}
+		overlay := newKustomization([]string{"../../base"})
+		overlay.Namespace = opts.Namespace
+		overlay.Labels = []kustomizationLabel{{Pairs: map[string]string{"environment": env}}}
+		overlayDir := filepath.Join(dir, "overlays", env)
+		if err := os.MkdirAll(overlayDir, 0755); err != nil {
+			return err
+		}
+		if err := writeKustomization(overlayDir, overlay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKustomization(dir string, k *kustomization) error {
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "kustomization.yaml"), data, 0644)
+}
+
+// WriteManifests writes all the objects of the application into dir/manifests.yaml
+func WriteManifests(dir string, app *App, opts Options) error {
+	resources, err := Render(app)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, res := range resources {
+		if opts.Namespace != "" {
+			res.Object["metadata"].(map[string]interface{})["namespace"] = opts.Namespace
+		}
+		data, err := yaml.Marshal(res.Object)
+		if err != nil {
+			return fmt.Errorf("encode %s %s: %v", res.Kind, res.Name, err)
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifests.yaml"), buf.Bytes(), 0644)
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const testMetadata = `{
+  "group_name": "Demo App",
+  "group_version": "1.0",
+  "apps": [
+    {
+      "service_share_uuid": "web-key",
+      "service_cname": "Web",
+      "k8s_component_name": "web",
+      "share_image": "hub.example.com/demo/web:1.2",
+      "extend_method": "stateless_multiple",
+      "extend_method_map": {"min_node": 2},
+      "memory": 512,
+      "cpu": 250,
+      "port_map_list": [{"port_alias": "WEB", "protocol": "http", "container_port": 8080, "is_inner_service": true, "is_outer_service": true}],
+      "service_env_map_list": [{"attr_name": "MODE", "attr_value": "prod"}],
+      "service_volume_map_list": [
+        {"volume_name": "conf", "volume_type": "config-file", "volume_path": "/app/conf/app.ini", "file_content": "debug=false"},
+        {"volume_name": "data", "volume_type": "share-file", "volume_path": "/data", "volume_capacity": 5}
+      ],
+      "dep_service_map_list": [{"dep_service_key": "db-key"}],
+      "probes": [{"mode": "readiness", "scheme": "http", "port": 8080, "path": "/healthz", "http_header": "X-Check=1", "is_used": 1}]
+    },
+    {
+      "service_share_uuid": "db-key",
+      "service_cname": "MySQL",
+      "k8s_component_name": "mysql",
+      "share_image": "mysql:8",
+      "extend_method": "state_singleton",
+      "port_map_list": [{"port_alias": "MYSQL", "protocol": "mysql", "container_port": 3306, "is_inner_service": true}],
+      "service_connect_info_map_list": [{"attr_name": "MYSQL_HOST", "attr_value": "127.0.0.1"}],
+      "service_volume_map_list": [{"volume_name": "data", "volume_type": "local", "volume_path": "/var/lib/mysql"}],
+      "probes": [{"mode": "liveness", "scheme": "tcp", "port": 3306, "is_used": false}]
+    },
+    {
+      "service_share_uuid": "clean-key",
+      "service_cname": "Clean",
+      "share_image": "busybox",
+      "extend_method": "cronjob",
+      "job_strategy": "{\"schedule\":\"*/5 * * * *\"}",
+      "cmd": "sh clean.sh"
+    }
+  ],
+  "app_config_groups": [{"name": "common", "config_items": {"LOG_LEVEL": "info"}, "component_keys": ["web-key"]}],
+  "ingress_http_routes": [{"component_key": "web-key", "port": 8080, "domain_name": "demo.example.com", "location": "/", "ssl": true}]
+}`
+
+func renderTestApp(t *testing.T) map[string]*Resource {
+	app, err := ParseApp([]byte(testMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := Render(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byFile := make(map[string]*Resource)
+	for _, res := range resources {
+		byFile[res.FileName()] = res
+	}
+	return byFile
+}
+
+func field(obj interface{}, path ...interface{}) interface{} {
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			m, ok := obj.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			obj = m[key]
+		case int:
+			list, ok := obj.([]interface{})
+			if !ok || key >= len(list) {
+				return nil
+			}
+			obj = list[key]
+		}
+	}
+	return obj
+}
+
+// capability_id: rainbond.app-export.kustomize-render
+func TestRender(t *testing.T) {
+	resources := renderTestApp(t)
+	for _, file := range []string{
+		"common-configmap.yaml", "web-conf-configmap.yaml", "web-data-persistentvolumeclaim.yaml",
+		"web-deployment.yaml", "web-service.yaml", "mysql-statefulset.yaml", "mysql-service.yaml",
+		"clean-cronjob.yaml", "web-8080-ingress.yaml",
+	} {
+		if resources[file] == nil {
+			t.Fatalf("expected %s to be rendered", file)
+		}
+	}
+	if len(resources) != 9 {
+		t.Fatalf("expected 9 resources, got %d", len(resources))
+	}
+
+	web := resources["web-deployment.yaml"].Object
+	if _, ok := web["status"]; ok {
+		t.Fatal("expected the status to be pruned")
+	}
+	if got := field(web, "spec", "replicas"); got != float64(2) {
+		t.Fatalf("expected 2 replicas, got %v", got)
+	}
+	container := field(web, "spec", "template", "spec", "containers", 0)
+	if got := field(container, "image"); got != "hub.example.com/demo/web:1.2" {
+		t.Fatalf("unexpected image %v", got)
+	}
+	if got := field(container, "resources", "limits", "memory"); got != "512Mi" {
+		t.Fatalf("unexpected memory limit %v", got)
+	}
+	if got := field(container, "envFrom", 0, "configMapRef", "name"); got != "common" {
+		t.Fatalf("expected the config group to be injected, got %v", got)
+	}
+	if got := field(container, "env", 1, "name"); got != "MYSQL_HOST" {
+		t.Fatalf("expected the connection info of the dependency to be injected, got %v", got)
+	}
+	if got := field(container, "readinessProbe", "httpGet", "httpHeaders", 0, "name"); got != "X-Check" {
+		t.Fatalf("unexpected readiness probe %v", field(container, "readinessProbe"))
+	}
+	if got := field(container, "volumeMounts", 0, "subPath"); got != "app.ini" {
+		t.Fatalf("expected the config file to be mounted with sub path, got %v", got)
+	}
+	if got := field(resources["web-data-persistentvolumeclaim.yaml"].Object, "spec", "accessModes", 0); got != "ReadWriteMany" {
+		t.Fatalf("expected the share volume to be ReadWriteMany, got %v", got)
+	}
+
+	mysql := resources["mysql-statefulset.yaml"].Object
+	if got := field(mysql, "spec", "volumeClaimTemplates", 0, "metadata", "name"); got != "data" {
+		t.Fatalf("expected the volume claim template of the stateful component, got %v", got)
+	}
+	if field(mysql, "spec", "template", "spec", "containers", 0, "livenessProbe") != nil {
+		t.Fatal("expected the unused probe to be skipped")
+	}
+
+	cron := resources["clean-cronjob.yaml"].Object
+	if got := field(cron, "spec", "schedule"); got != "*/5 * * * *" {
+		t.Fatalf("unexpected schedule %v", got)
+	}
+	ingress := resources["web-8080-ingress.yaml"].Object
+	if got := field(ingress, "spec", "tls", 0, "secretName"); got != "web-8080-tls" {
+		t.Fatalf("unexpected tls %v", got)
+	}
+}
+
+// capability_id: rainbond.app-export.kustomize-render
+func TestRenderCronJobWithoutSchedule(t *testing.T) {
+	app, err := ParseApp([]byte(`{"group_name":"demo","apps":[{"service_cname":"job","extend_method":"cronjob","share_image":"busybox"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Render(app); err == nil {
+		t.Fatal("expected the cronjob without schedule to fail")
+	}
+}
+
+// capability_id: rainbond.app-export.kustomize-layout
+func TestWriteKustomize(t *testing.T) {
+	app, err := ParseApp([]byte(testMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteKustomize(dir, app, Options{Environments: []string{"staging"}, Namespace: "demo"}); err != nil {
+		t.Fatal(err)
+	}
+	var base kustomization
+	readYAML(t, filepath.Join(dir, "base", "kustomization.yaml"), &base)
+	if len(base.Resources) != 9 || base.Namespace != "" {
+		t.Fatalf("unexpected base kustomization %+v", base)
+	}
+	for _, file := range base.Resources {
+		if _, err := os.Stat(filepath.Join(dir, "base", file)); err != nil {
+			t.Fatalf("expected the base resource %s: %v", file, err)
+		}
+	}
+	var overlay kustomization
+	readYAML(t, filepath.Join(dir, "overlays", "staging", "kustomization.yaml"), &overlay)
+	if overlay.Namespace != "demo" || overlay.Resources[0] != "../../base" || overlay.Labels[0].Pairs["environment"] != "staging" {
+		t.Fatalf("unexpected overlay kustomization %+v", overlay)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "overlays", "staging", "kustomization.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "images:") {
+		t.Fatalf("expected the overlay to keep the images of the base:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "overlays", "dev")); !os.IsNotExist(err) {
+		t.Fatal("expected only the specified environments")
+	}
+}
+
+// capability_id: rainbond.app-export.k8s-manifests
+func TestWriteManifests(t *testing.T) {
+	app, err := ParseApp([]byte(testMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteManifests(dir, app, Options{Namespace: "demo"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "manifests.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(string(data), "\n---\n")
+	if len(docs) != 9 {
+		t.Fatalf("expected 9 documents, got %d", len(docs))
+	}
+	for _, doc := range docs {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			t.Fatal(err)
+		}
+		if got := field(obj, "metadata", "namespace"); got != "demo" {
+			t.Fatalf("expected the namespace to be set, got %v", got)
+		}
+	}
+}
+
+func readYAML(t *testing.T, file string, out interface{}) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
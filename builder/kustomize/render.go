@@ -0,0 +1,486 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	labelName   = "app.kubernetes.io/name"
+	labelPartOf = "app.kubernetes.io/part-of"
+	// defaultVolumeCapacity the capacity in Gi of the volumes without capacity
+	defaultVolumeCapacity = 1
+)
+
+// Resource a rendered kubernetes object
+type Resource struct {
+	Kind   string
+	Name   string
+	Object map[string]interface{}
+}
+
+// FileName the file name of the resource in the kustomize base
+func (r *Resource) FileName() string {
+	return fmt.Sprintf("%s-%s.yaml", r.Name, strings.ToLower(r.Kind))
+}
+
+type renderer struct {
+	app       *App
+	appName   string
+	names     map[string]string
+	byKey     map[string]*Component
+	used      map[string]bool
+	resources []*Resource
+}
+
+// Render renders the kubernetes objects of the application, including the workloads,
+// services, config groups, config files, volumes and gateway routes of the components.
+func Render(app *App) ([]*Resource, error) {
+	r := &renderer{
+		app:     app,
+		appName: dnsName(app.AppName),
+		names:   make(map[string]string),
+		byKey:   make(map[string]*Component),
+		used:    make(map[string]bool),
+	}
+	if r.appName == "" {
+		r.appName = "rainbond-app"
+	}
+	for _, com := range app.Components {
+		r.byKey[com.key()] = com
+		r.names[com.key()] = r.uniqueName(componentName(com))
+	}
+	for _, group := range app.ConfigGroups {
+		r.renderConfigGroup(group)
+	}
+	for _, com := range app.Components {
+		if err := r.renderComponent(com); err != nil {
+			return nil, err
+		}
+	}
+	for _, route := range app.HTTPRoutes {
+		r.renderHTTPRoute(route)
+	}
+	return r.resources, nil
+}
+
+func componentName(com *Component) string {
+	for _, name := range []string{com.K8sComponentName, com.ServiceCname, com.key()} {
+		if name = dnsName(name); name != "" {
+			return name
+		}
+	}
+	return "component"
+}
+
+// uniqueName the names of the components are unique in the application
+func (r *renderer) uniqueName(name string) string {
+	unique := name
+	for i := 1; r.used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	r.used[unique] = true
+	return unique
+}
+
+func (r *renderer) labels(name string) map[string]string {
+	return map[string]string{labelName: name, labelPartOf: r.appName}
+}
+
+// add encodes the typed object, which never fails for the api types, into the generic
+// form the nil fields and empty status are pruned from
+func (r *renderer) add(kind, name string, obj interface{}) {
+	data, _ := json.Marshal(obj)
+	var content map[string]interface{}
+	json.Unmarshal(data, &content)
+	prune(content)
+	r.resources = append(r.resources, &Resource{Kind: kind, Name: name, Object: content})
+}
+
+// prune removes the null fields and the empty status the typed objects are encoded with
+func prune(content map[string]interface{}) {
+	for key, value := range content {
+		switch v := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			prune(v)
+			if key == "status" && len(v) == 0 {
+				delete(content, key)
+			}
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					prune(m)
+				}
+			}
+		}
+	}
+}
+
+func (r *renderer) renderConfigGroup(group *ConfigGroup) {
+	name := dnsName(group.Name)
+	if name == "" || len(group.ComponentKeys) == 0 {
+		return
+	}
+	r.add("ConfigMap", name, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{labelPartOf: r.appName}},
+		Data:       group.ConfigItems,
+	})
+}
+
+func (r *renderer) renderComponent(com *Component) error {
+	name := r.names[com.key()]
+	labels := r.labels(name)
+	container := corev1.Container{
+		Name:  name,
+		Image: com.image(),
+		Args:  strings.Fields(com.Cmd),
+		Env:   r.envs(com),
+	}
+	for _, group := range r.app.ConfigGroups {
+		if groupName := dnsName(group.Name); groupName != "" && contains(group.ComponentKeys, com.key()) {
+			container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: groupName}},
+			})
+		}
+	}
+	for _, port := range com.Ports {
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			ContainerPort: int32(port.ContainerPort),
+			Protocol:      protocol(port.Protocol),
+		})
+	}
+	if com.Memory > 0 || com.CPU > 0 {
+		container.Resources.Limits = corev1.ResourceList{}
+		if com.Memory > 0 {
+			container.Resources.Limits[corev1.ResourceMemory] = resource.MustParse(fmt.Sprintf("%dMi", com.Memory))
+		}
+		if com.CPU > 0 {
+			container.Resources.Limits[corev1.ResourceCPU] = resource.MustParse(fmt.Sprintf("%dm", com.CPU))
+		}
+	}
+	for _, probe := range com.Probes {
+		if !probe.used() {
+			continue
+		}
+		switch probe.Mode {
+		case "readiness":
+			container.ReadinessProbe = buildProbe(probe)
+		case "liveness":
+			container.LivenessProbe = buildProbe(probe)
+		}
+	}
+
+	var volumes []corev1.Volume
+	var claims []corev1.PersistentVolumeClaim
+	for _, vol := range com.Volumes {
+		volName := dnsName(vol.VolumeName)
+		if volName == "" || vol.VolumePath == "" {
+			continue
+		}
+		mount := corev1.VolumeMount{Name: volName, MountPath: vol.VolumePath}
+		switch vol.VolumeType {
+		case "config-file":
+			cmName := fmt.Sprintf("%s-%s", name, volName)
+			fileName := path.Base(vol.VolumePath)
+			r.add("ConfigMap", cmName, &corev1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: cmName, Labels: labels},
+				Data:       map[string]string{fileName: vol.FileContent},
+			})
+			mount.SubPath = fileName
+			volumes = append(volumes, corev1.Volume{Name: volName, VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: cmName}},
+			}})
+		case "memoryfs":
+			volumes = append(volumes, corev1.Volume{Name: volName, VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			}})
+		default:
+			if com.stateful() {
+				// the stateful components get a volume of each instance
+				claims = append(claims, r.claim(volName, labels, vol))
+				break
+			}
+			claimName := fmt.Sprintf("%s-%s", name, volName)
+			claim := r.claim(claimName, labels, vol)
+			r.add("PersistentVolumeClaim", claimName, &claim)
+			volumes = append(volumes, corev1.Volume{Name: volName, VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			}})
+		}
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{container},
+			Volumes:    volumes,
+		},
+	}
+	meta := metav1.ObjectMeta{Name: name, Labels: labels}
+	replicas := int32(com.ExtendMethodRule.MinNode)
+	if replicas < 1 {
+		replicas = 1
+	}
+	switch com.DeployType {
+	case "job":
+		template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+		r.add("Job", name, &batchv1.Job{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+			ObjectMeta: meta,
+			Spec:       batchv1.JobSpec{Template: template},
+		})
+	case "cronjob":
+		schedule := jobSchedule(com.JobStrategy)
+		if schedule == "" {
+			return fmt.Errorf("the cronjob component %s has no schedule", com.ServiceCname)
+		}
+		template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+		r.add("CronJob", name, &batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+			ObjectMeta: meta,
+			Spec: batchv1.CronJobSpec{
+				Schedule:    schedule,
+				JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}},
+			},
+		})
+	default:
+		if com.stateful() {
+			r.add("StatefulSet", name, &appsv1.StatefulSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+				ObjectMeta: meta,
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:             &replicas,
+					ServiceName:          name,
+					Selector:             &metav1.LabelSelector{MatchLabels: labels},
+					Template:             template,
+					VolumeClaimTemplates: claims,
+				},
+			})
+		} else {
+			r.add("Deployment", name, &appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				ObjectMeta: meta,
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: template,
+				},
+			})
+		}
+	}
+	r.renderService(com, name, labels)
+	return nil
+}
+
+// envs the env vars of the component and the connection infos of the components it depends on
+func (r *renderer) envs(com *Component) []corev1.EnvVar {
+	var envs []corev1.EnvVar
+	seen := make(map[string]bool)
+	add := func(list []*Env) {
+		for _, env := range list {
+			if env.AttrName == "" || seen[env.AttrName] {
+				continue
+			}
+			seen[env.AttrName] = true
+			envs = append(envs, corev1.EnvVar{Name: env.AttrName, Value: env.AttrValue})
+		}
+	}
+	add(com.Envs)
+	add(com.ConnectInfos)
+	for _, dep := range com.Deps {
+		if depCom := r.byKey[dep.DepServiceKey]; depCom != nil {
+			add(depCom.ConnectInfos)
+		}
+	}
+	return envs
+}
+
+func (r *renderer) claim(name string, labels map[string]string, vol *Volume) corev1.PersistentVolumeClaim {
+	capacity := vol.VolumeCapacity
+	if capacity <= 0 {
+		capacity = defaultVolumeCapacity
+	}
+	return corev1.PersistentVolumeClaim{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode(vol)},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(fmt.Sprintf("%dGi", capacity))},
+			},
+		},
+	}
+}
+
+func accessMode(vol *Volume) corev1.PersistentVolumeAccessMode {
+	switch strings.ToUpper(vol.AccessMode) {
+	case "RWX":
+		return corev1.ReadWriteMany
+	case "ROX":
+		return corev1.ReadOnlyMany
+	case "RWO":
+		return corev1.ReadWriteOnce
+	}
+	if vol.VolumeType == "share-file" {
+		return corev1.ReadWriteMany
+	}
+	return corev1.ReadWriteOnce
+}
+
+func (r *renderer) renderService(com *Component, name string, labels map[string]string) {
+	var ports []corev1.ServicePort
+	for _, port := range com.Ports {
+		if !port.IsInnerService && !port.IsOuterService {
+			continue
+		}
+		ports = append(ports, corev1.ServicePort{
+			Name:       portName(port),
+			Port:       int32(port.ContainerPort),
+			TargetPort: intstr.FromInt(port.ContainerPort),
+			Protocol:   protocol(port.Protocol),
+		})
+	}
+	if len(ports) == 0 {
+		return
+	}
+	r.add("Service", name, &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    ports,
+		},
+	})
+}
+
+func (r *renderer) renderHTTPRoute(route *HTTPRoute) {
+	com := r.byKey[route.ComponentKey]
+	if com == nil {
+		return
+	}
+	name := r.names[com.key()]
+	location := route.Location
+	if location == "" {
+		location = "/"
+	}
+	pathType := networkingv1.PathTypePrefix
+	ingressName := fmt.Sprintf("%s-%d", name, route.Port)
+	ingress := &networkingv1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{Name: ingressName, Labels: r.labels(name)},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: route.DomainName,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     location,
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: name,
+							Port: networkingv1.ServiceBackendPort{Number: int32(route.Port)},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	if route.SSL && route.DomainName != "" {
+		// the certificate is not exported, the secret is expected to be created in the cluster
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{route.DomainName}, SecretName: ingressName + "-tls"}}
+	}
+	r.add("Ingress", ingressName, ingress)
+}
+
+func buildProbe(p *Probe) *corev1.Probe {
+	probe := &corev1.Probe{
+		InitialDelaySeconds: int32(p.InitialDelaySecond),
+		PeriodSeconds:       int32(p.PeriodSecond),
+		TimeoutSeconds:      int32(p.TimeoutSecond),
+		SuccessThreshold:    int32(p.SuccessThreshold),
+		FailureThreshold:    int32(p.FailureThreshold),
+	}
+	switch strings.ToLower(p.Scheme) {
+	case "http":
+		get := &corev1.HTTPGetAction{Path: p.Path, Port: intstr.FromInt(p.Port)}
+		// the headers are saved as name=value pairs separated by commas
+		for _, header := range strings.Split(p.HTTPHeader, ",") {
+			kv := strings.SplitN(strings.TrimSpace(header), "=", 2)
+			if len(kv) == 2 && kv[0] != "" {
+				get.HTTPHeaders = append(get.HTTPHeaders, corev1.HTTPHeader{Name: kv[0], Value: kv[1]})
+			}
+		}
+		probe.HTTPGet = get
+	case "cmd":
+		probe.Exec = &corev1.ExecAction{Command: []string{"/bin/sh", "-c", p.Cmd}}
+	default:
+		probe.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt(p.Port)}
+	}
+	return probe
+}
+
+func jobSchedule(strategy string) string {
+	var s struct {
+		Schedule string `json:"schedule"`
+	}
+	if strategy == "" || json.Unmarshal([]byte(strategy), &s) != nil {
+		return ""
+	}
+	return s.Schedule
+}
+
+func protocol(proto string) corev1.Protocol {
+	if strings.EqualFold(proto, "udp") {
+		return corev1.ProtocolUDP
+	}
+	return corev1.ProtocolTCP
+}
+
+func portName(port *Port) string {
+	proto := dnsName(port.Protocol)
+	if proto == "" {
+		proto = "tcp"
+	}
+	return fmt.Sprintf("%s-%d", proto, port.ContainerPort)
+}
+
+func contains(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"github.com/goodrain/rainbond/builder/buildcache"
 	"github.com/goodrain/rainbond/builder/discover"
 	"github.com/goodrain/rainbond/builder/exector"
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,6 +25,12 @@ type Exporter struct {
 	exec                        exector.Manager
 }
 
+var buildCacheDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, exporter, "build_cache_lookups"),
+	"builder number of source build cache hits and misses by build step.",
+	[]string{"step", "result"}, nil,
+)
+
 var healthDesc = prometheus.NewDesc(
 	prometheus.BuildFQName(namespace, exporter, "health_status"),
 	"builder service health status.",
@@ -104,4 +111,7 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(e.taskBackMetric.Desc(), prometheus.CounterValue, exector.MetricBackTaskNum)
 	ch <- prometheus.MustNewConstMetric(e.maxConcurrentTaskMetric.Desc(), prometheus.GaugeValue, e.exec.GetMaxConcurrentTask())
 	ch <- prometheus.MustNewConstMetric(e.currentConcurrentTaskMetric.Desc(), prometheus.GaugeValue, e.exec.GetCurrentConcurrentTask())
+	for key, count := range buildcache.LookupStats() {
+		ch <- prometheus.MustNewConstMetric(buildCacheDesc, prometheus.CounterValue, count, key[0], key[1])
+	}
 }
@@ -47,6 +47,21 @@ var cnbPythonVersions = []CNBVersion{
 	{Version: "3.14", Default: true},
 }
 
+// cnbRubyVersions defines the supported Ruby major.minor versions for CNB builds.
+var cnbRubyVersions = []CNBVersion{
+	{Version: "3.2", Default: false},
+	{Version: "3.3", Default: false},
+	{Version: "3.4", Default: true},
+}
+
+// cnbRustVersions defines the supported Rust toolchains for CNB builds.
+var cnbRustVersions = []CNBVersion{
+	{Version: "1.85", Default: false},
+	{Version: "1.88", Default: false},
+	{Version: "1.90", Default: false},
+	{Version: "stable", Default: true},
+}
+
 // GetCNBVersions returns the supported CNB versions for a given language.
 // Supports composite languages like "dockerfile,Node.js" by checking each part.
 func GetCNBVersions(lang string) []CNBVersion {
@@ -61,6 +76,10 @@ func GetCNBVersions(lang string) []CNBVersion {
 			return cnbGolangVersions
 		case "python":
 			return cnbPythonVersions
+		case "ruby":
+			return cnbRubyVersions
+		case "rust":
+			return cnbRustVersions
 		}
 	}
 	return []CNBVersion{}
@@ -137,6 +156,12 @@ func normalizeCNBVersionSpec(lang, spec string) (string, bool) {
 	case "go", "golang":
 		normalized, err := normalizeGolangRuntimeVersion(trimVersionSpecPrefixes(spec))
 		return normalized, err == nil
+	case "ruby":
+		normalized, err := normalizeRubyRuntimeVersion(spec)
+		return normalized, err == nil
+	case "rust":
+		normalized, err := normalizeRustRuntimeVersion(trimVersionSpecPrefixes(spec))
+		return normalized, err == nil
 	default:
 		return "", false
 	}
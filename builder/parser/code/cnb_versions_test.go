@@ -20,6 +20,8 @@ func TestGetCNBVersions(t *testing.T) {
 		{"golang returns versions", "golang", len(cnbGolangVersions)},
 		{"python returns versions", "python", len(cnbPythonVersions)},
 		{"Go returns versions", "Go", len(cnbGolangVersions)},
+		{"Ruby returns versions", "Ruby", len(cnbRubyVersions)},
+		{"Rust returns versions", "Rust", len(cnbRustVersions)},
 		{"Node.js returns versions", "Node.js", len(cnbNodeVersions)},
 		{"node returns versions", "node", len(cnbNodeVersions)},
 		{"NODEJS returns versions (case-insensitive)", "NODEJS", len(cnbNodeVersions)},
@@ -133,6 +135,32 @@ func TestMatchCNBVersion_Golang(t *testing.T) {
 	}
 }
 
+// capability_id: rainbond.cnb-version.match-ruby-rust
+func TestMatchCNBVersion_RubyAndRust(t *testing.T) {
+	tests := []struct {
+		name        string
+		lang        string
+		versionSpec string
+		want        string
+	}{
+		{"ruby empty spec returns default", "ruby", "", "3.4"},
+		{"ruby patch version normalizes to major.minor", "Ruby", "3.3.6", "3.3"},
+		{"ruby pessimistic constraint", "ruby", "~> 3.2", "3.2"},
+		{"rust empty spec returns stable", "rust", "", "stable"},
+		{"rust channel", "Rust", "stable", "stable"},
+		{"rust patch version normalizes to major.minor", "rust", "1.88.0", "1.88"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchCNBVersion(tt.lang, tt.versionSpec)
+			if got != tt.want {
+				t.Errorf("MatchCNBVersion(%q, %q) = %q, want %q", tt.lang, tt.versionSpec, got, tt.want)
+			}
+		})
+	}
+}
+
 // capability_id: rainbond.cnb-version.extract-major
 func TestExtractMajorFromSpec(t *testing.T) {
 	tests := []struct {
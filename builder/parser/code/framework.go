@@ -406,6 +406,24 @@ var extraNodeFrameworks = []Framework{
 	{Name: "other-server", DisplayName: "Other", RuntimeType: "dynamic", OutputDir: "", BuildCmd: "", StartCmd: "start"},
 }
 
+// rubyFrameworks defines the Ruby web frameworks offered by the frontend framework selector.
+// StartCmd is the process command, Ruby has no script names like package.json.
+var rubyFrameworks = []Framework{
+	{Name: "rails", DisplayName: "Ruby on Rails", RuntimeType: "dynamic", OutputDir: "public", BuildCmd: "bundle exec rails assets:precompile", StartCmd: "bundle exec rails server -b 0.0.0.0 -p $PORT"},
+	{Name: "sinatra", DisplayName: "Sinatra", RuntimeType: "dynamic", StartCmd: "bundle exec rackup -o 0.0.0.0 -p $PORT"},
+	{Name: "rack", DisplayName: "Rack", RuntimeType: "dynamic", StartCmd: "bundle exec rackup -o 0.0.0.0 -p $PORT"},
+	{Name: "other-server", DisplayName: "Other", RuntimeType: "dynamic"},
+}
+
+// rustFrameworks defines the Rust web frameworks offered by the frontend framework selector.
+// Cargo builds the release binaries, so only the start command differs.
+var rustFrameworks = []Framework{
+	{Name: "actix-web", DisplayName: "Actix Web", RuntimeType: "dynamic", BuildCmd: "cargo build --release"},
+	{Name: "axum", DisplayName: "Axum", RuntimeType: "dynamic", BuildCmd: "cargo build --release"},
+	{Name: "rocket", DisplayName: "Rocket", RuntimeType: "dynamic", BuildCmd: "cargo build --release"},
+	{Name: "other-server", DisplayName: "Other", RuntimeType: "dynamic", BuildCmd: "cargo build --release"},
+}
+
 // GetSupportedFrameworks returns list of all supported frameworks for a given language.
 // If lang is empty, defaults to "nodejs".
 func GetSupportedFrameworks(lang string) []Framework {
@@ -427,6 +445,10 @@ func GetSupportedFrameworks(lang string) []Framework {
 		}
 		frameworks = append(frameworks, extraNodeFrameworks...)
 		return frameworks
+	case "ruby":
+		return append([]Framework{}, rubyFrameworks...)
+	case "rust":
+		return append([]Framework{}, rustFrameworks...)
 	default:
 		return []Framework{}
 	}
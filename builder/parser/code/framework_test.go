@@ -648,6 +648,28 @@ func TestGetSupportedFrameworks(t *testing.T) {
 	}
 }
 
+// capability_id: rainbond.framework-detect.supported-list
+func TestGetSupportedFrameworks_RubyAndRust(t *testing.T) {
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{"ruby", []string{"rails", "sinatra", "rack", "other-server"}},
+		{"Rust", []string{"actix-web", "axum", "rocket", "other-server"}},
+	}
+	for _, tt := range tests {
+		frameworks := GetSupportedFrameworks(tt.lang)
+		if len(frameworks) != len(tt.want) {
+			t.Fatalf("GetSupportedFrameworks(%q) returned %d frameworks, want %d", tt.lang, len(frameworks), len(tt.want))
+		}
+		for i, f := range frameworks {
+			if f.Name != tt.want[i] || f.RuntimeType != "dynamic" {
+				t.Errorf("GetSupportedFrameworks(%q)[%d] = %+v, want dynamic %s", tt.lang, i, f, tt.want[i])
+			}
+		}
+	}
+}
+
 // capability_id: rainbond.framework-detect.display-name
 func TestGetDisplayName(t *testing.T) {
 	tests := []struct {
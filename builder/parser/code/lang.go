@@ -46,6 +46,7 @@ func init() {
 	checkFuncList = append(checkFuncList, grails)
 	checkFuncList = append(checkFuncList, scala)
 	checkFuncList = append(checkFuncList, netcore)
+	checkFuncList = append(checkFuncList, rust)
 }
 
 // ErrCodeNotExist 代码为空错误
@@ -125,6 +126,9 @@ var Grails Lang = "Grails"
 // NetCore Lang
 var NetCore Lang = ".NetCore"
 
+// Rust Lang
+var Rust Lang = "Rust"
+
 // OSS Lang
 var OSS Lang = "OSS"
 
@@ -345,6 +349,14 @@ func netcore(homepath string) Lang {
 	return NO
 }
 
+// rust cargo project
+func rust(homepath string) Lang {
+	if ok, _ := util.FileExists(path.Join(homepath, "Cargo.toml")); ok {
+		return Rust
+	}
+	return NO
+}
+
 // 暂时不支持
 func scala(homepath string) Lang {
 	return NO
@@ -37,6 +37,8 @@ func TestGetLangType_SupportedSourceBuildLanguages(t *testing.T) {
 		{name: "nodejs", files: map[string]string{"package.json": "{\"name\":\"demo\"}\n"}, want: Nodejs},
 		{name: "static", files: map[string]string{"index.html": "<html></html>\n"}, want: Static},
 		{name: "netcore", files: map[string]string{"demo.csproj": "<Project />\n"}, want: NetCore},
+		{name: "ruby", files: map[string]string{"Gemfile": "source \"https://rubygems.org\"\n"}, want: Ruby},
+		{name: "rust", files: map[string]string{"Cargo.toml": "[package]\nname = \"demo\"\n"}, want: Rust},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,43 @@
+package code
+
+import (
+	"testing"
+)
+
+func TestCheckRuntimeByStrategyRubyDetectsRubyVersionFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"Gemfile":       "source \"https://rubygems.org\"\nruby \"3.2.4\"\n",
+		".ruby-version": "ruby-3.3.6\n",
+		"Gemfile.lock":  "GEM\n  specs:\n\nBUNDLED WITH\n   2.5.22\n",
+	})
+
+	runtimeInfo, err := CheckRuntimeByStrategy(dir, Ruby, "cnb")
+	if err != nil {
+		t.Fatalf("CheckRuntimeByStrategy returned error: %v", err)
+	}
+	if got := runtimeInfo["RUNTIMES"]; got != "3.3" {
+		t.Fatalf("expected RUNTIMES=3.3, got %q", got)
+	}
+	if got := runtimeInfo["BUNDLER_VERSION"]; got != "2.5.22" {
+		t.Fatalf("expected BUNDLER_VERSION=2.5.22, got %q", got)
+	}
+}
+
+func TestCheckRuntimeByStrategyRubyDetectsGemfileRuby(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"Gemfile": "source 'https://rubygems.org'\n\nruby '~> 3.2.0'\ngem 'rails'\n",
+	})
+
+	runtimeInfo, err := CheckRuntimeByStrategy(dir, Ruby, "cnb")
+	if err != nil {
+		t.Fatalf("CheckRuntimeByStrategy returned error: %v", err)
+	}
+	if got := runtimeInfo["RUNTIMES"]; got != "3.2" {
+		t.Fatalf("expected RUNTIMES=3.2, got %q", got)
+	}
+	if _, ok := runtimeInfo["BUNDLER_VERSION"]; ok {
+		t.Fatalf("expected no bundler version without Gemfile.lock, got %v", runtimeInfo)
+	}
+}
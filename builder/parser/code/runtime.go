@@ -23,6 +23,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/goodrain/rainbond/db"
@@ -85,6 +86,16 @@ func checkRuntime(buildPath string, lang Lang, buildStrategy string) (map[string
 			return readDotnetRuntimeInfoForCNB(buildPath)
 		}
 		return nil, nil
+	case Ruby:
+		if buildStrategy == "cnb" {
+			return readRubyRuntimeInfoForCNB(buildPath)
+		}
+		return nil, nil
+	case Rust:
+		if buildStrategy == "cnb" {
+			return readRustRuntimeInfoForCNB(buildPath)
+		}
+		return nil, nil
 	case Nodejs:
 		return readNodeRuntimeInfo(buildPath)
 	case Static:
@@ -223,6 +234,77 @@ func readDotnetRuntimeInfoForCNB(buildPath string) (map[string]string, error) {
 	return runtimeInfo, nil
 }
 
+// readRubyRuntimeInfoForCNB reads the ruby version pinned by .ruby-version or the
+// ruby directive of the Gemfile, and the bundler version locked in Gemfile.lock.
+func readRubyRuntimeInfoForCNB(buildPath string) (map[string]string, error) {
+	var runtimeInfo = make(map[string]string, 2)
+	version := ""
+	if body, err := os.ReadFile(path.Join(buildPath, ".ruby-version")); err == nil {
+		version = strings.TrimSpace(strings.SplitN(string(body), "\n", 2)[0])
+	}
+	if version == "" {
+		if body, err := os.ReadFile(path.Join(buildPath, "Gemfile")); err == nil {
+			if match := gemfileRubyVersion.FindStringSubmatch(string(body)); len(match) == 2 {
+				version = match[1]
+			}
+		}
+	}
+	if version != "" {
+		normalized, err := normalizeRubyRuntimeVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		runtimeInfo["RUNTIMES"] = normalized
+	}
+	if body, err := os.ReadFile(path.Join(buildPath, "Gemfile.lock")); err == nil {
+		lines := strings.Split(string(body), "\n")
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "BUNDLED WITH" && i+1 < len(lines) {
+				if bundler := strings.TrimSpace(lines[i+1]); bundler != "" {
+					runtimeInfo["BUNDLER_VERSION"] = bundler
+				}
+				break
+			}
+		}
+	}
+	return runtimeInfo, nil
+}
+
+// readRustRuntimeInfoForCNB reads the toolchain channel pinned by rust-toolchain.toml
+// or the legacy rust-toolchain file.
+func readRustRuntimeInfoForCNB(buildPath string) (map[string]string, error) {
+	var runtimeInfo = make(map[string]string, 1)
+	channel := ""
+	for _, name := range []string{"rust-toolchain.toml", "rust-toolchain"} {
+		body, err := os.ReadFile(path.Join(buildPath, name))
+		if err != nil {
+			continue
+		}
+		content := strings.TrimSpace(string(body))
+		if match := rustToolchainChannel.FindStringSubmatch(content); len(match) == 2 {
+			channel = match[1]
+		} else if !strings.Contains(content, "[") && !strings.Contains(content, "=") {
+			// the legacy file only contains the channel name
+			channel = strings.TrimSpace(strings.SplitN(content, "\n", 2)[0])
+		}
+		break
+	}
+	if channel == "" {
+		return runtimeInfo, nil
+	}
+	normalized, err := normalizeRustRuntimeVersion(channel)
+	if err != nil {
+		return nil, err
+	}
+	runtimeInfo["RUNTIMES"] = normalized
+	return runtimeInfo, nil
+}
+
+var (
+	gemfileRubyVersion   = regexp.MustCompile(`(?m)^\s*ruby\s+['"]([^'"]+)['"]`)
+	rustToolchainChannel = regexp.MustCompile(`(?m)^\s*channel\s*=\s*['"]([^'"]+)['"]`)
+)
+
 func extractXMLTag(content, tag string) string {
 	openTag := "<" + tag + ">"
 	closeTag := "</" + tag + ">"
@@ -342,6 +424,38 @@ func normalizePHPRuntimeVersion(version string) (string, error) {
 	return strings.Join(parts[:2], "."), nil
 }
 
+func normalizeRubyRuntimeVersion(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	version = strings.TrimPrefix(version, "ruby-")
+	for _, prefix := range []string{"~>", ">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(version, prefix) {
+			version = strings.TrimSpace(strings.TrimPrefix(version, prefix))
+			break
+		}
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || parts[0] == "" {
+		return "", ErrRuntimeNotSupport
+	}
+	return strings.Join(parts[:2], "."), nil
+}
+
+// normalizeRustRuntimeVersion keeps the release channels (stable, beta, nightly and
+// dated nightlies) as they are, and reduces numbered releases to major.minor.
+func normalizeRustRuntimeVersion(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	for _, channel := range []string{"stable", "beta", "nightly"} {
+		if version == channel || strings.HasPrefix(version, channel+"-") {
+			return version, nil
+		}
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || parts[0] == "" {
+		return "", ErrRuntimeNotSupport
+	}
+	return strings.Join(parts[:2], "."), nil
+}
+
 func readPHPRuntimeInfo(buildPath string) (map[string]string, error) {
 	var phpRuntimeInfo = make(map[string]string, 1)
 	if ok, _ := util.FileExists(path.Join(buildPath, "composer.json")); !ok {
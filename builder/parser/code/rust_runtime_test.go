@@ -0,0 +1,34 @@
+package code
+
+import (
+	"testing"
+)
+
+func TestCheckRuntimeByStrategyRustDetectsToolchainFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{name: "toolchain toml", files: map[string]string{"rust-toolchain.toml": "[toolchain]\nchannel = \"1.88.0\"\ncomponents = [\"rustfmt\"]\n"}, want: "1.88"},
+		{name: "toolchain toml channel", files: map[string]string{"rust-toolchain.toml": "[toolchain]\nchannel = 'nightly-2025-01-15'\n"}, want: "nightly-2025-01-15"},
+		{name: "legacy toolchain file", files: map[string]string{"rust-toolchain": "stable\n"}, want: "stable"},
+		{name: "no toolchain file", files: map[string]string{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.files["Cargo.toml"] = "[package]\nname = \"demo\"\nrust-version = \"1.70\"\n"
+			writeTestFiles(t, dir, tt.files)
+
+			runtimeInfo, err := CheckRuntimeByStrategy(dir, Rust, "cnb")
+			if err != nil {
+				t.Fatalf("CheckRuntimeByStrategy returned error: %v", err)
+			}
+			if got := runtimeInfo["RUNTIMES"]; got != tt.want {
+				t.Fatalf("expected RUNTIMES=%q, got %q", tt.want, got)
+			}
+		})
+	}
+}
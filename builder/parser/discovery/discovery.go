@@ -34,6 +34,8 @@ func NewDiscoverier(info *Info) Discoverier {
 	switch strings.ToUpper(info.Type) {
 	case "ETCD":
 		return NewEtcd(info)
+	case "CONSUL", "NACOS", "DNS":
+		return NewRegistry(info)
 	}
 	return nil
 }
@@ -45,6 +47,12 @@ type Info struct {
 	Key      string   `json:"key"`
 	Username string   `json:"username"`
 	Password string   `json:"password"`
+	// Namespace is the namespace id of nacos
+	Namespace string `json:"namespace"`
+	// Group is the group of nacos
+	Group string `json:"group"`
+	// Datacenter is the datacenter of consul
+	Datacenter string `json:"datacenter"`
 }
 
 // Endpoint holds endpoint and endpoint status(online or offline).
@@ -4,7 +4,7 @@ import "testing"
 
 // capability_id: rainbond.source-discovery.unsupported-type
 func TestNewDiscoverierUnsupportedType(t *testing.T) {
-	info := &Info{Type: "consul"}
+	info := &Info{Type: "zookeeper"}
 	if got := NewDiscoverier(info); got != nil {
 		t.Fatalf("expected nil discoverier for unsupported type, got %T", got)
 	}
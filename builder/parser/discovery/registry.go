@@ -0,0 +1,90 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/worker/appm/thirdparty/discovery"
+)
+
+// registry implements Discoverier with the third-party discoverier of worker,
+// so the endpoints checked here are the same as the ones worker finds.
+type registry struct {
+	cfg *model.ThirdPartySvcDiscoveryCfg
+	d   discovery.Discoverier
+}
+
+// NewRegistry creates a new Discoverier for consul, nacos or dns srv records.
+func NewRegistry(info *Info) Discoverier {
+	return &registry{
+		cfg: &model.ThirdPartySvcDiscoveryCfg{
+			Type:       strings.ToLower(info.Type),
+			Servers:    strings.Join(info.Servers, ","),
+			Key:        info.Key,
+			Username:   info.Username,
+			Password:   info.Password,
+			Namespace:  info.Namespace,
+			Group:      info.Group,
+			Datacenter: info.Datacenter,
+		},
+	}
+}
+
+// Connect connects the service registry.
+func (r *registry) Connect() error {
+	d, err := discovery.NewDiscoverier(r.cfg, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := d.Connect(); err != nil {
+		return err
+	}
+	r.d = d
+	return nil
+}
+
+// Fetch fetches the instances of the service from the service registry.
+func (r *registry) Fetch() ([]*Endpoint, error) {
+	if r.d == nil {
+		return nil, fmt.Errorf("can't fetching data from %s without connecting", r.cfg.Type)
+	}
+	eps, err := r.d.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	var res []*Endpoint
+	for _, ep := range eps {
+		res = append(res, &Endpoint{
+			Ep:       fmt.Sprintf("%s:%d", ep.IP, ep.Port),
+			IsOnline: ep.IsOnline,
+		})
+	}
+	return res, nil
+}
+
+// Close closes the connection of the service registry.
+func (r *registry) Close() error {
+	if r.d == nil {
+		return nil
+	}
+	return r.d.Close()
+}
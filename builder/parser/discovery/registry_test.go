@@ -0,0 +1,36 @@
+package discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// capability_id: rainbond.source-discovery.registry
+func TestRegistryFetchFromConsul(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Node":{"Node":"node1","Address":"10.0.0.1"},` +
+			`"Service":{"ID":"web-1","Address":"10.0.1.1","Port":8080},` +
+			`"Checks":[{"Status":"critical"}]}]`))
+	}))
+	defer server.Close()
+
+	d := NewDiscoverier(&Info{Type: "consul", Servers: []string{server.URL}, Key: "web"})
+	if d == nil {
+		t.Fatal("expected consul discoverier")
+	}
+	if _, err := d.Fetch(); err == nil {
+		t.Fatal("expected fetch guard error without connecting")
+	}
+	if err := d.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+	eps, err := d.Fetch()
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(eps) != 1 || eps[0].Ep != "10.0.1.1:8080" || eps[0].IsOnline {
+		t.Fatalf("unexpected endpoints %+v", eps)
+	}
+}
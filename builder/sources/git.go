@@ -42,6 +42,7 @@ import (
 	"encoding/pem"
 
 	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/client"
@@ -92,6 +93,8 @@ type CodeSourceInfo struct {
 	BuildStrategy  string                  `json:"build_strategy"`
 	Configs        map[string]gjson.Result `json:"configs"`
 	DockerfilePath string                  `json:"dockerfile_path"` // Dockerfile路径，用于指定子目录中的Dockerfile
+	// Commit 固定构建的提交，为空时构建分支最新提交
+	Commit string `json:"commit"`
 	//避免项目之间冲突，代码缓存目录提高到租户
 	TenantID  string `json:"tenant_id"`
 	ServiceID string `json:"service_id"`
@@ -638,6 +641,57 @@ func GitCheckout(sourceDir, branch string) error {
 	// }
 	return nil
 }
+
+// GitCheckoutCommit checkout the commit pinned by csi.Commit.
+// The code is cloned with depth 1, so the commit is fetched by hash when the
+// branch has moved on since it was pushed.
+func GitCheckoutCommit(re *git.Repository, csi CodeSourceInfo, logger event.Logger, timeout int) error {
+	if csi.Commit == "" {
+		return nil
+	}
+	hash := plumbing.NewHash(csi.Commit)
+	if hash.IsZero() || hash.String() != strings.ToLower(csi.Commit) {
+		return fmt.Errorf("invalid commit %s", csi.Commit)
+	}
+	if logger != nil {
+		logger.Info(fmt.Sprintf("Checkout commit %s", csi.Commit), map[string]string{"step": "clone_code"})
+	}
+	if _, err := re.CommitObject(hash); err != nil {
+		if timeout < 1 {
+			timeout = 1
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute*time.Duration(timeout))
+		defer cancel()
+		opts := &git.FetchOptions{
+			RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:refs/rainbond/pinned", hash.String()))},
+			Depth:    1,
+			Tags:     git.NoTags,
+		}
+		ep, err := transport.NewEndpoint(csi.RepositoryURL)
+		if err != nil {
+			return err
+		}
+		if ep.Protocol == "ssh" {
+			sshAuth, err := ssh.NewPublicKeysFromFile("git", GetPrivateFile(csi.TenantID), "")
+			if err != nil {
+				return err
+			}
+			sshAuth.HostKeyCallbackHelper.HostKeyCallback = netssh.InsecureIgnoreHostKey()
+			opts.Auth = sshAuth
+		} else if csi.User != "" && csi.Password != "" {
+			opts.Auth = &githttp.BasicAuth{Username: csi.User, Password: csi.Password}
+		}
+		if err := re.FetchContext(ctx, opts); err != nil && err != git.NoErrAlreadyUpToDate {
+			return fmt.Errorf("fetch commit %s failure: %v", csi.Commit, err)
+		}
+	}
+	tree, err := re.Worktree()
+	if err != nil {
+		return err
+	}
+	return tree.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+}
+
 func getBranch(branch string) plumbing.ReferenceName {
 	if strings.HasPrefix(branch, "tag:") {
 		return plumbing.ReferenceName(fmt.Sprintf("refs/tags/%s", branch[4:]))
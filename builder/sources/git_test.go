@@ -22,11 +22,14 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/goodrain/rainbond/event"
 )
 
@@ -196,3 +199,49 @@ func TestGetBranch(t *testing.T) {
 		t.Fatalf("unexpected tag ref: %q", got)
 	}
 }
+
+// capability_id: rainbond.source-repo.pinned-commit
+func TestGitCheckoutCommit(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile := func(content string) plumbing.Hash {
+		if err := os.WriteFile(filepath.Join(dir, "version"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tree.Add("version"); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := tree.Commit(content, &git.CommitOptions{Author: &object.Signature{Name: "test", When: time.Now()}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	pushed := commitFile("v1")
+	commitFile("v2")
+
+	if err := GitCheckoutCommit(repo, CodeSourceInfo{Commit: "not-a-sha"}, event.GetTestLogger(), 1); err == nil {
+		t.Fatal("expected invalid commit error")
+	}
+	if err := GitCheckoutCommit(repo, CodeSourceInfo{Commit: pushed.String()}, event.GetTestLogger(), 1); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := GetLastCommit(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Hash != pushed {
+		t.Fatalf("expected HEAD at %s, got %s", pushed, commit.Hash)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "version"))
+	if err != nil || string(content) != "v1" {
+		t.Fatalf("expected the work tree of the pinned commit, got %q, %v", content, err)
+	}
+}
@@ -58,6 +58,8 @@ func (f *fakeStorage) CleanupChunks(sessionID string) error { return nil }
 
 func (f *fakeStorage) GetChunkDir(sessionID string) string { return "" }
 
+func (f *fakeStorage) RemoveAll(path string) error { return nil }
+
 func TestReadLocalPackageDirFallsBackToStorageDownload(t *testing.T) {
 	sourcePath := filepath.Join(t.TempDir(), "vm-image")
 	originalPrefixes := localPackageSourcePrefixes
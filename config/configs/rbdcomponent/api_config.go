@@ -142,4 +142,8 @@ func AddEventLogFlags(fs *pflag.FlagSet, elc *EventLogConfig) {
 	fs.StringVar(&elc.Conf.Entry.NewMonitorMessageServerConf.ListenerHost, "monitor.udp.host", "0.0.0.0", "receive new monitor udp server host")
 	fs.IntVar(&elc.Conf.Entry.NewMonitorMessageServerConf.ListenerPort, "monitor.udp.port", 6166, "receive new monitor udp server port")
 	fs.StringVar(&elc.Conf.EventStore.StorageHomePath, "docker.log.homepath", "/grdata/logs/", "container log persistent home path")
+	fs.StringVar(&elc.Conf.EventStore.Sink.ConfigFile, "eventlog.sink.config", "", "the json file defining external sinks (syslog, webhook, loki, elasticsearch) for event and container logs")
+	fs.StringVar(&elc.Conf.EventStore.Sink.BufferPath, "eventlog.sink.buffer.path", "", "the directory buffering logs for slow sinks, default is {docker.log.homepath}/sink-buffer")
+	fs.IntVar(&elc.Conf.EventStore.Sink.QueueSize, "eventlog.sink.queue.size", 4096, "the in-memory queue size of each sink, logs are buffered on disk when it is full")
+	fs.Int64Var(&elc.Conf.EventStore.Sink.BufferMaxBytes, "eventlog.sink.buffer.max.bytes", 512*1024*1024, "the max disk buffer size of each sink, the oldest logs are dropped when exceeded")
 }
@@ -1,6 +1,8 @@
 package rbdcomponent
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 )
 
@@ -26,6 +28,10 @@ type ChaosConfig struct {
 	BRVersion        string
 	SourceScanURL    string
 	RegistryMirrors  string
+	// BuildCacheQuota the cluster wide quota of the source build caches, e.g. 100Gi
+	BuildCacheQuota string
+	// TaskVisibilityTimeout how long a dequeued task stays invisible in mq before it is redelivered if not acked
+	TaskVisibilityTimeout time.Duration
 }
 
 func AddChaosFlags(fs *pflag.FlagSet, cc *ChaosConfig) {
@@ -49,4 +55,6 @@ func AddChaosFlags(fs *pflag.FlagSet, cc *ChaosConfig) {
 	fs.StringVar(&cc.BRVersion, "br-version", "stable", "builder and runner version")
 	fs.StringVar(&cc.SourceScanURL, "source-scan-url", "", "rainbond source scan service URL, eg: http://rainbond-sourcescan:8080")
 	fs.StringVar(&cc.RegistryMirrors, "registry-mirrors", "", "comma-separated registry mirrors for docker.io base-image pulls in dockerfile builds, empty means no mirror; can be overridden by env REGISTRY_MIRRORS. prefix a value with http:// to mark it as a plain-HTTP mirror endpoint")
+	fs.StringVar(&cc.BuildCacheQuota, "build-cache-quota", "", "cluster wide quota of the source build caches on the cache volume, e.g. 100Gi; the least recently used caches are evicted by the clean task when it is exceeded, empty means no limit")
+	fs.DurationVar(&cc.TaskVisibilityTimeout, "task-visibility-timeout", 2*time.Hour, "how long a build task stays invisible in mq until it is acked, the task is redelivered when the builder crashed before finishing it")
 }
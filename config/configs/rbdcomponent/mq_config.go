@@ -1,12 +1,22 @@
 package rbdcomponent
 
-import "github.com/spf13/pflag"
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
 
 type MQConfig struct {
-	KeyPrefix string
-	RunMode   string //http grpc
-	HostName  string
-	APIPort   int
+	KeyPrefix         string
+	RunMode           string //http grpc
+	HostName          string
+	APIPort           int
+	StorageMode       string //memory wal
+	DataDir           string
+	SyncWrite         bool
+	VisibilityTimeout time.Duration
+	MaxDeliveries     int
+	AdminToken        string
 }
 
 func AddMQFlags(fs *pflag.FlagSet, mqc *MQConfig) {
@@ -14,4 +24,10 @@ func AddMQFlags(fs *pflag.FlagSet, mqc *MQConfig) {
 	fs.StringVar(&mqc.RunMode, "mode", "grpc", "the api server run mode grpc or http")
 	fs.StringVar(&mqc.HostName, "hostName", "", "Current node host name")
 	fs.IntVar(&mqc.APIPort, "api-port", 6300, "the api server listen port")
+	fs.StringVar(&mqc.StorageMode, "mq-storage-mode", "wal", "the message storage mode, memory or wal(write-ahead log on local disk)")
+	fs.StringVar(&mqc.DataDir, "mq-data-dir", "/data/mq", "the directory of the message write-ahead log")
+	fs.BoolVar(&mqc.SyncWrite, "mq-sync-write", true, "fsync the write-ahead log before a message operation returns")
+	fs.DurationVar(&mqc.VisibilityTimeout, "mq-visibility-timeout", 10*time.Minute, "default time an un-acked message stays invisible before it is redelivered")
+	fs.IntVar(&mqc.MaxDeliveries, "mq-max-deliveries", 5, "the failed deliveries before a message is moved to the dead-letter topic <topic>.dlq, 0 means never")
+	fs.StringVar(&mqc.AdminToken, "mq-admin-token", "", "the bearer token of the dead-letter queue api on the metrics port, the api is disabled when it is empty")
 }
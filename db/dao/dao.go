@@ -51,6 +51,7 @@ type EnterpriseDao interface {
 type TenantDao interface {
 	Dao
 	GetTenantByUUID(uuid string) (*model.Tenants, error)
+	GetTenantByNamespace(namespace string) (*model.Tenants, error)
 	GetTenantIDByName(tenantName string) (*model.Tenants, error)
 	GetALLTenants(query string) ([]*model.Tenants, error)
 	GetTenantsByTenantIDs(tenantIDs []string) ([]*model.Tenants, error)
@@ -448,6 +449,7 @@ type EventDao interface {
 	GetEventsByTarget(target, targetID string, offset, liimt int) ([]*model.ServiceEvent, int, error)
 	GetEventsByTenantID(tenantID string, offset, limit int) ([]*model.ServiceEvent, int, error)
 	GetEventsByTenantIDs(tenantID []string, offset, limit int) ([]*model.EventAndBuild, error)
+	ListEventsForLogSearch(tenantID, target, targetID, since, until string, limit int) ([]*model.ServiceEvent, error)
 	GetLastASyncEvent(target, targetID string) (*model.ServiceEvent, error)
 	UnfinishedEvents(target, targetID string, optTypes ...string) ([]*model.ServiceEvent, error)
 	LatestFailurePodEvent(podName string) (*model.ServiceEvent, error)
@@ -487,6 +489,7 @@ type NotificationEventDao interface {
 	GetNotificationEventByKind(kind, kindID string) ([]*model.NotificationEvent, error)
 	GetNotificationEventByTime(start, end time.Time) ([]*model.NotificationEvent, error)
 	GetNotificationEventNotHandle() ([]*model.NotificationEvent, error)
+	ListNotificationEvents(query *model.NotificationEventQuery) ([]*model.NotificationEvent, error)
 }
 
 // AppBackupDao group app backup history
@@ -498,6 +501,94 @@ type AppBackupDao interface {
 	GetAppBackup(backupID string) (*model.AppBackup, error)
 	GetDeleteAppBackup(backupID string) (*model.AppBackup, error)
 	GetDeleteAppBackups() ([]*model.AppBackup, error)
+	GetLatestSuccessBackup(groupID string) (*model.AppBackup, error)
+	ListByScheduleID(scheduleID string) ([]*model.AppBackup, error)
+	ListChildBackups(backupID string) ([]*model.AppBackup, error)
+}
+
+// AppBackupScheduleDao group app backup schedule
+type AppBackupScheduleDao interface {
+	Dao
+	GetBackupSchedule(scheduleID string) (*model.AppBackupSchedule, error)
+	ListByGroupID(groupID string) ([]*model.AppBackupSchedule, error)
+	ListEnableOnes() ([]*model.AppBackupSchedule, error)
+	DeleteBackupSchedule(scheduleID string) error
+	ClaimRun(scheduleID string, lastRunTime *time.Time, runTime time.Time) (bool, error)
+}
+
+// VMSnapshotScheduleDao vm component snapshot schedule
+type VMSnapshotScheduleDao interface {
+	Dao
+	GetByServiceID(serviceID string) (*model.VMSnapshotSchedule, error)
+	ListEnableOnes() ([]*model.VMSnapshotSchedule, error)
+	DeleteByServiceID(serviceID string) error
+	ClaimRun(serviceID string, lastRunTime *time.Time, runTime time.Time) (bool, error)
+}
+
+// APITokenDao scoped api token
+type APITokenDao interface {
+	Dao
+	GetByName(name string) (*model.APIToken, error)
+	GetByTokenHash(tokenHash string) (*model.APIToken, error)
+	List() ([]*model.APIToken, error)
+	DeleteByName(name string) error
+	UpdateLastUsedTime(id uint, lastUsedTime time.Time) error
+}
+
+// AuditLogDao the append only audit log
+type AuditLogDao interface {
+	Append(log *model.AuditLog) error
+	List(query *model.AuditLogQuery, page, pageSize int) ([]*model.AuditLog, int64, error)
+	ListAfter(query *model.AuditLogQuery, afterID uint, limit int) ([]*model.AuditLog, error)
+}
+
+// TerminalSessionDao recorded web terminal sessions
+type TerminalSessionDao interface {
+	Dao
+	GetBySessionID(sessionID string) (*model.TerminalSession, error)
+	List(query *model.TerminalSessionQuery, page, pageSize int) ([]*model.TerminalSession, int64, error)
+}
+
+// BuildCacheDao source build caches of the components
+type BuildCacheDao interface {
+	Dao
+	GetByServiceID(serviceID string) (*model.BuildCache, error)
+	ListLeastRecentlyUsed() ([]*model.BuildCache, error)
+	DeleteByServiceID(serviceID string) error
+}
+
+// GitWebhookDao git push webhook subscriptions of the components
+type GitWebhookDao interface {
+	Dao
+	GetByServiceID(serviceID string) (*model.GitWebhook, error)
+	ListEnabledByRepo(provider, repoKey string) ([]*model.GitWebhook, error)
+	DeleteByServiceID(serviceID string) error
+}
+
+// GitWebhookDeliveryDao git webhook deliveries handled for the components
+type GitWebhookDeliveryDao interface {
+	Dao
+	GetDelivery(provider, deliveryID, serviceID string) (*model.GitWebhookDelivery, error)
+	GetTriggeredByCommit(serviceID, commitSHA string) (*model.GitWebhookDelivery, error)
+	ListByServiceID(serviceID string, limit int) ([]*model.GitWebhookDelivery, error)
+}
+
+// NotificationChannelDao outbound notification channels of the tenants
+type NotificationChannelDao interface {
+	Dao
+	GetByChannelID(channelID string) (*model.NotificationChannel, error)
+	ListByTenantID(tenantID string) ([]*model.NotificationChannel, error)
+	DeleteByChannelID(channelID string) error
+}
+
+// NotificationRuleDao notification subscription rules of the tenants
+type NotificationRuleDao interface {
+	Dao
+	GetByRuleID(ruleID string) (*model.NotificationRule, error)
+	ListByTenantID(tenantID string) ([]*model.NotificationRule, error)
+	ListEnabledByTenantID(tenantID string) ([]*model.NotificationRule, error)
+	DeleteByRuleID(ruleID string) error
+	DeleteByChannelID(channelID string) error
 }
 
 // ServiceSourceDao service source dao
@@ -600,6 +691,7 @@ type TenantServceAutoscalerRulesDao interface {
 	GetByRuleID(ruleID string) (*model.TenantServiceAutoscalerRules, error)
 	ListByServiceID(serviceID string) ([]*model.TenantServiceAutoscalerRules, error)
 	ListEnableOnesByServiceID(serviceID string) ([]*model.TenantServiceAutoscalerRules, error)
+	ListEnableScheduledOnes() ([]*model.TenantServiceAutoscalerRules, error)
 	ListByComponentIDs(componentIDs []string) ([]*model.TenantServiceAutoscalerRules, error)
 	DeleteByComponentIDs(componentIDs []string) error
 	CreateOrUpdateScaleRulesInBatch(rules []*model.TenantServiceAutoscalerRules) error
@@ -638,6 +730,7 @@ type TenantServiceMonitorDao interface {
 type ComponentK8sAttributeDao interface {
 	Dao
 	GetByComponentIDAndName(componentID, name string) (*model.ComponentK8sAttributes, error)
+	ListByComponentID(componentID string) ([]*model.ComponentK8sAttributes, error)
 	CreateOrUpdateAttributesInBatch(attributes []*model.ComponentK8sAttributes) error
 	DeleteByComponentIDAndName(componentID, name string) error
 	DeleteByComponentIDs(componentIDs []string) error
@@ -194,6 +194,19 @@ func (mr *MockTenantDaoMockRecorder) GetTenantByUUID(uuid interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTenantByUUID", reflect.TypeOf((*MockTenantDao)(nil).GetTenantByUUID), uuid)
 }
 
+// GetTenantByNamespace mocks base method
+func (m *MockTenantDao) GetTenantByNamespace(namespace string) (*model.Tenants, error) {
+	ret := m.ctrl.Call(m, "GetTenantByNamespace", namespace)
+	ret0, _ := ret[0].(*model.Tenants)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTenantByNamespace indicates an expected call of GetTenantByNamespace
+func (mr *MockTenantDaoMockRecorder) GetTenantByNamespace(namespace interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTenantByNamespace", reflect.TypeOf((*MockTenantDao)(nil).GetTenantByNamespace), namespace)
+}
+
 // GetTenantIDByName mocks base method
 func (m *MockTenantDao) GetTenantIDByName(tenantName string) (*model.Tenants, error) {
 	ret := m.ctrl.Call(m, "GetTenantIDByName", tenantName)
@@ -3781,6 +3794,19 @@ func (mr *MockEventDaoMockRecorder) GetEventsByTarget(target, targetID, offset,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventsByTarget", reflect.TypeOf((*MockEventDao)(nil).GetEventsByTarget), target, targetID, offset, liimt)
 }
 
+// ListEventsForLogSearch mocks base method
+func (m *MockEventDao) ListEventsForLogSearch(tenantID, target, targetID, since, until string, limit int) ([]*model.ServiceEvent, error) {
+	ret := m.ctrl.Call(m, "ListEventsForLogSearch", tenantID, target, targetID, since, until, limit)
+	ret0, _ := ret[0].([]*model.ServiceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEventsForLogSearch indicates an expected call of ListEventsForLogSearch
+func (mr *MockEventDaoMockRecorder) ListEventsForLogSearch(tenantID, target, targetID, since, until, limit interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEventsForLogSearch", reflect.TypeOf((*MockEventDao)(nil).ListEventsForLogSearch), tenantID, target, targetID, since, until, limit)
+}
+
 // GetEventsByTenantID mocks base method
 func (m *MockEventDao) GetEventsByTenantID(tenantID string, offset, limit int) ([]*model.ServiceEvent, int, error) {
 	ret := m.ctrl.Call(m, "GetEventsByTenantID", tenantID, offset, limit)
@@ -4137,6 +4163,19 @@ func (mr *MockNotificationEventDaoMockRecorder) GetNotificationEventNotHandle()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationEventNotHandle", reflect.TypeOf((*MockNotificationEventDao)(nil).GetNotificationEventNotHandle))
 }
 
+// ListNotificationEvents mocks base method
+func (m *MockNotificationEventDao) ListNotificationEvents(query *model.NotificationEventQuery) ([]*model.NotificationEvent, error) {
+	ret := m.ctrl.Call(m, "ListNotificationEvents", query)
+	ret0, _ := ret[0].([]*model.NotificationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNotificationEvents indicates an expected call of ListNotificationEvents
+func (mr *MockNotificationEventDaoMockRecorder) ListNotificationEvents(query interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNotificationEvents", reflect.TypeOf((*MockNotificationEventDao)(nil).ListNotificationEvents), query)
+}
+
 // MockAppBackupDao is a mock of AppBackupDao interface
 type MockAppBackupDao struct {
 	ctrl     *gomock.Controller
@@ -4260,354 +4299,933 @@ func (mr *MockAppBackupDaoMockRecorder) GetDeleteAppBackups() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeleteAppBackups", reflect.TypeOf((*MockAppBackupDao)(nil).GetDeleteAppBackups))
 }
 
-// MockServiceSourceDao is a mock of ServiceSourceDao interface
-type MockServiceSourceDao struct {
-	ctrl     *gomock.Controller
-	recorder *MockServiceSourceDaoMockRecorder
-}
-
-// MockServiceSourceDaoMockRecorder is the mock recorder for MockServiceSourceDao
-type MockServiceSourceDaoMockRecorder struct {
-	mock *MockServiceSourceDao
-}
-
-// NewMockServiceSourceDao creates a new mock instance
-func NewMockServiceSourceDao(ctrl *gomock.Controller) *MockServiceSourceDao {
-	mock := &MockServiceSourceDao{ctrl: ctrl}
-	mock.recorder = &MockServiceSourceDaoMockRecorder{mock}
-	return mock
-}
-
-// EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockServiceSourceDao) EXPECT() *MockServiceSourceDaoMockRecorder {
-	return m.recorder
-}
-
-// AddModel mocks base method
-func (m *MockServiceSourceDao) AddModel(arg0 model.Interface) error {
-	ret := m.ctrl.Call(m, "AddModel", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+// GetLatestSuccessBackup mocks base method
+func (m *MockAppBackupDao) GetLatestSuccessBackup(groupID string) (*model.AppBackup, error) {
+	ret := m.ctrl.Call(m, "GetLatestSuccessBackup", groupID)
+	ret0, _ := ret[0].(*model.AppBackup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// AddModel indicates an expected call of AddModel
-func (mr *MockServiceSourceDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockServiceSourceDao)(nil).AddModel), arg0)
+// GetLatestSuccessBackup indicates an expected call of GetLatestSuccessBackup
+func (mr *MockAppBackupDaoMockRecorder) GetLatestSuccessBackup(groupID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestSuccessBackup", reflect.TypeOf((*MockAppBackupDao)(nil).GetLatestSuccessBackup), groupID)
 }
 
-// UpdateModel mocks base method
-func (m *MockServiceSourceDao) UpdateModel(arg0 model.Interface) error {
-	ret := m.ctrl.Call(m, "UpdateModel", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+// ListByScheduleID mocks base method
+func (m *MockAppBackupDao) ListByScheduleID(scheduleID string) ([]*model.AppBackup, error) {
+	ret := m.ctrl.Call(m, "ListByScheduleID", scheduleID)
+	ret0, _ := ret[0].([]*model.AppBackup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// UpdateModel indicates an expected call of UpdateModel
-func (mr *MockServiceSourceDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockServiceSourceDao)(nil).UpdateModel), arg0)
+// ListByScheduleID indicates an expected call of ListByScheduleID
+func (mr *MockAppBackupDaoMockRecorder) ListByScheduleID(scheduleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByScheduleID", reflect.TypeOf((*MockAppBackupDao)(nil).ListByScheduleID), scheduleID)
 }
 
-// GetServiceSource mocks base method
-func (m *MockServiceSourceDao) GetServiceSource(serviceID string) ([]*model.ServiceSourceConfig, error) {
-	ret := m.ctrl.Call(m, "GetServiceSource", serviceID)
-	ret0, _ := ret[0].([]*model.ServiceSourceConfig)
+// ListChildBackups mocks base method
+func (m *MockAppBackupDao) ListChildBackups(backupID string) ([]*model.AppBackup, error) {
+	ret := m.ctrl.Call(m, "ListChildBackups", backupID)
+	ret0, _ := ret[0].([]*model.AppBackup)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetServiceSource indicates an expected call of GetServiceSource
-func (mr *MockServiceSourceDaoMockRecorder) GetServiceSource(serviceID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceSource", reflect.TypeOf((*MockServiceSourceDao)(nil).GetServiceSource), serviceID)
+// ListChildBackups indicates an expected call of ListChildBackups
+func (mr *MockAppBackupDaoMockRecorder) ListChildBackups(backupID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChildBackups", reflect.TypeOf((*MockAppBackupDao)(nil).ListChildBackups), backupID)
 }
 
-// MockCertificateDao is a mock of CertificateDao interface
-type MockCertificateDao struct {
+// MockAppBackupScheduleDao is a mock of AppBackupScheduleDao interface
+type MockAppBackupScheduleDao struct {
 	ctrl     *gomock.Controller
-	recorder *MockCertificateDaoMockRecorder
+	recorder *MockAppBackupScheduleDaoMockRecorder
 }
 
-// MockCertificateDaoMockRecorder is the mock recorder for MockCertificateDao
-type MockCertificateDaoMockRecorder struct {
-	mock *MockCertificateDao
+// MockAppBackupScheduleDaoMockRecorder is the mock recorder for MockAppBackupScheduleDao
+type MockAppBackupScheduleDaoMockRecorder struct {
+	mock *MockAppBackupScheduleDao
 }
 
-// NewMockCertificateDao creates a new mock instance
-func NewMockCertificateDao(ctrl *gomock.Controller) *MockCertificateDao {
-	mock := &MockCertificateDao{ctrl: ctrl}
-	mock.recorder = &MockCertificateDaoMockRecorder{mock}
+// NewMockAppBackupScheduleDao creates a new mock instance
+func NewMockAppBackupScheduleDao(ctrl *gomock.Controller) *MockAppBackupScheduleDao {
+	mock := &MockAppBackupScheduleDao{ctrl: ctrl}
+	mock.recorder = &MockAppBackupScheduleDaoMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockCertificateDao) EXPECT() *MockCertificateDaoMockRecorder {
+func (m *MockAppBackupScheduleDao) EXPECT() *MockAppBackupScheduleDaoMockRecorder {
 	return m.recorder
 }
 
 // AddModel mocks base method
-func (m *MockCertificateDao) AddModel(arg0 model.Interface) error {
+func (m *MockAppBackupScheduleDao) AddModel(arg0 model.Interface) error {
 	ret := m.ctrl.Call(m, "AddModel", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddModel indicates an expected call of AddModel
-func (mr *MockCertificateDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockCertificateDao)(nil).AddModel), arg0)
+func (mr *MockAppBackupScheduleDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).AddModel), arg0)
 }
 
 // UpdateModel mocks base method
-func (m *MockCertificateDao) UpdateModel(arg0 model.Interface) error {
+func (m *MockAppBackupScheduleDao) UpdateModel(arg0 model.Interface) error {
 	ret := m.ctrl.Call(m, "UpdateModel", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateModel indicates an expected call of UpdateModel
-func (mr *MockCertificateDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockCertificateDao)(nil).UpdateModel), arg0)
+func (mr *MockAppBackupScheduleDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).UpdateModel), arg0)
 }
 
-// AddOrUpdate mocks base method
-func (m *MockCertificateDao) AddOrUpdate(mo model.Interface) error {
-	ret := m.ctrl.Call(m, "AddOrUpdate", mo)
+// GetBackupSchedule mocks base method
+func (m *MockAppBackupScheduleDao) GetBackupSchedule(scheduleID string) (*model.AppBackupSchedule, error) {
+	ret := m.ctrl.Call(m, "GetBackupSchedule", scheduleID)
+	ret0, _ := ret[0].(*model.AppBackupSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBackupSchedule indicates an expected call of GetBackupSchedule
+func (mr *MockAppBackupScheduleDaoMockRecorder) GetBackupSchedule(scheduleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBackupSchedule", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).GetBackupSchedule), scheduleID)
+}
+
+// ListByGroupID mocks base method
+func (m *MockAppBackupScheduleDao) ListByGroupID(groupID string) ([]*model.AppBackupSchedule, error) {
+	ret := m.ctrl.Call(m, "ListByGroupID", groupID)
+	ret0, _ := ret[0].([]*model.AppBackupSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByGroupID indicates an expected call of ListByGroupID
+func (mr *MockAppBackupScheduleDaoMockRecorder) ListByGroupID(groupID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByGroupID", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).ListByGroupID), groupID)
+}
+
+// ListEnableOnes mocks base method
+func (m *MockAppBackupScheduleDao) ListEnableOnes() ([]*model.AppBackupSchedule, error) {
+	ret := m.ctrl.Call(m, "ListEnableOnes")
+	ret0, _ := ret[0].([]*model.AppBackupSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnableOnes indicates an expected call of ListEnableOnes
+func (mr *MockAppBackupScheduleDaoMockRecorder) ListEnableOnes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnableOnes", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).ListEnableOnes))
+}
+
+// DeleteBackupSchedule mocks base method
+func (m *MockAppBackupScheduleDao) DeleteBackupSchedule(scheduleID string) error {
+	ret := m.ctrl.Call(m, "DeleteBackupSchedule", scheduleID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// AddOrUpdate indicates an expected call of AddOrUpdate
-func (mr *MockCertificateDaoMockRecorder) AddOrUpdate(mo interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOrUpdate", reflect.TypeOf((*MockCertificateDao)(nil).AddOrUpdate), mo)
+// DeleteBackupSchedule indicates an expected call of DeleteBackupSchedule
+func (mr *MockAppBackupScheduleDaoMockRecorder) DeleteBackupSchedule(scheduleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBackupSchedule", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).DeleteBackupSchedule), scheduleID)
 }
 
-// GetCertificateByID mocks base method
-func (m *MockCertificateDao) GetCertificateByID(certificateID string) (*model.Certificate, error) {
-	ret := m.ctrl.Call(m, "GetCertificateByID", certificateID)
-	ret0, _ := ret[0].(*model.Certificate)
+// ClaimRun mocks base method
+func (m *MockAppBackupScheduleDao) ClaimRun(scheduleID string, lastRunTime *time.Time, runTime time.Time) (bool, error) {
+	ret := m.ctrl.Call(m, "ClaimRun", scheduleID, lastRunTime, runTime)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetCertificateByID indicates an expected call of GetCertificateByID
-func (mr *MockCertificateDaoMockRecorder) GetCertificateByID(certificateID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificateByID", reflect.TypeOf((*MockCertificateDao)(nil).GetCertificateByID), certificateID)
+// ClaimRun indicates an expected call of ClaimRun
+func (mr *MockAppBackupScheduleDaoMockRecorder) ClaimRun(scheduleID interface{}, lastRunTime interface{}, runTime interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimRun", reflect.TypeOf((*MockAppBackupScheduleDao)(nil).ClaimRun), scheduleID, lastRunTime, runTime)
 }
 
-// MockRuleExtensionDao is a mock of RuleExtensionDao interface
-type MockRuleExtensionDao struct {
+// MockVMSnapshotScheduleDao is a mock of VMSnapshotScheduleDao interface
+type MockVMSnapshotScheduleDao struct {
 	ctrl     *gomock.Controller
-	recorder *MockRuleExtensionDaoMockRecorder
+	recorder *MockVMSnapshotScheduleDaoMockRecorder
 }
 
-// MockRuleExtensionDaoMockRecorder is the mock recorder for MockRuleExtensionDao
-type MockRuleExtensionDaoMockRecorder struct {
-	mock *MockRuleExtensionDao
+// MockVMSnapshotScheduleDaoMockRecorder is the mock recorder for MockVMSnapshotScheduleDao
+type MockVMSnapshotScheduleDaoMockRecorder struct {
+	mock *MockVMSnapshotScheduleDao
 }
 
-// NewMockRuleExtensionDao creates a new mock instance
-func NewMockRuleExtensionDao(ctrl *gomock.Controller) *MockRuleExtensionDao {
-	mock := &MockRuleExtensionDao{ctrl: ctrl}
-	mock.recorder = &MockRuleExtensionDaoMockRecorder{mock}
+// NewMockVMSnapshotScheduleDao creates a new mock instance
+func NewMockVMSnapshotScheduleDao(ctrl *gomock.Controller) *MockVMSnapshotScheduleDao {
+	mock := &MockVMSnapshotScheduleDao{ctrl: ctrl}
+	mock.recorder = &MockVMSnapshotScheduleDaoMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockRuleExtensionDao) EXPECT() *MockRuleExtensionDaoMockRecorder {
+func (m *MockVMSnapshotScheduleDao) EXPECT() *MockVMSnapshotScheduleDaoMockRecorder {
 	return m.recorder
 }
 
 // AddModel mocks base method
-func (m *MockRuleExtensionDao) AddModel(arg0 model.Interface) error {
+func (m *MockVMSnapshotScheduleDao) AddModel(arg0 model.Interface) error {
 	ret := m.ctrl.Call(m, "AddModel", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddModel indicates an expected call of AddModel
-func (mr *MockRuleExtensionDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockRuleExtensionDao)(nil).AddModel), arg0)
+func (mr *MockVMSnapshotScheduleDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockVMSnapshotScheduleDao)(nil).AddModel), arg0)
 }
 
 // UpdateModel mocks base method
-func (m *MockRuleExtensionDao) UpdateModel(arg0 model.Interface) error {
+func (m *MockVMSnapshotScheduleDao) UpdateModel(arg0 model.Interface) error {
 	ret := m.ctrl.Call(m, "UpdateModel", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateModel indicates an expected call of UpdateModel
-func (mr *MockRuleExtensionDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockRuleExtensionDao)(nil).UpdateModel), arg0)
+func (mr *MockVMSnapshotScheduleDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockVMSnapshotScheduleDao)(nil).UpdateModel), arg0)
 }
 
-// GetRuleExtensionByRuleID mocks base method
-func (m *MockRuleExtensionDao) GetRuleExtensionByRuleID(ruleID string) ([]*model.RuleExtension, error) {
-	ret := m.ctrl.Call(m, "GetRuleExtensionByRuleID", ruleID)
-	ret0, _ := ret[0].([]*model.RuleExtension)
+// GetByServiceID mocks base method
+func (m *MockVMSnapshotScheduleDao) GetByServiceID(serviceID string) (*model.VMSnapshotSchedule, error) {
+	ret := m.ctrl.Call(m, "GetByServiceID", serviceID)
+	ret0, _ := ret[0].(*model.VMSnapshotSchedule)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetRuleExtensionByRuleID indicates an expected call of GetRuleExtensionByRuleID
-func (mr *MockRuleExtensionDaoMockRecorder) GetRuleExtensionByRuleID(ruleID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRuleExtensionByRuleID", reflect.TypeOf((*MockRuleExtensionDao)(nil).GetRuleExtensionByRuleID), ruleID)
+// GetByServiceID indicates an expected call of GetByServiceID
+func (mr *MockVMSnapshotScheduleDaoMockRecorder) GetByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByServiceID", reflect.TypeOf((*MockVMSnapshotScheduleDao)(nil).GetByServiceID), serviceID)
 }
 
-// DeleteRuleExtensionByRuleID mocks base method
-func (m *MockRuleExtensionDao) DeleteRuleExtensionByRuleID(ruleID string) error {
-	ret := m.ctrl.Call(m, "DeleteRuleExtensionByRuleID", ruleID)
+// ListEnableOnes mocks base method
+func (m *MockVMSnapshotScheduleDao) ListEnableOnes() ([]*model.VMSnapshotSchedule, error) {
+	ret := m.ctrl.Call(m, "ListEnableOnes")
+	ret0, _ := ret[0].([]*model.VMSnapshotSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnableOnes indicates an expected call of ListEnableOnes
+func (mr *MockVMSnapshotScheduleDaoMockRecorder) ListEnableOnes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnableOnes", reflect.TypeOf((*MockVMSnapshotScheduleDao)(nil).ListEnableOnes))
+}
+
+// DeleteByServiceID mocks base method
+func (m *MockVMSnapshotScheduleDao) DeleteByServiceID(serviceID string) error {
+	ret := m.ctrl.Call(m, "DeleteByServiceID", serviceID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteRuleExtensionByRuleID indicates an expected call of DeleteRuleExtensionByRuleID
-func (mr *MockRuleExtensionDaoMockRecorder) DeleteRuleExtensionByRuleID(ruleID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRuleExtensionByRuleID", reflect.TypeOf((*MockRuleExtensionDao)(nil).DeleteRuleExtensionByRuleID), ruleID)
+// DeleteByServiceID indicates an expected call of DeleteByServiceID
+func (mr *MockVMSnapshotScheduleDaoMockRecorder) DeleteByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByServiceID", reflect.TypeOf((*MockVMSnapshotScheduleDao)(nil).DeleteByServiceID), serviceID)
 }
 
-// MockHTTPRuleDao is a mock of HTTPRuleDao interface
-type MockHTTPRuleDao struct {
+// ClaimRun mocks base method
+func (m *MockVMSnapshotScheduleDao) ClaimRun(serviceID string, lastRunTime *time.Time, runTime time.Time) (bool, error) {
+	ret := m.ctrl.Call(m, "ClaimRun", serviceID, lastRunTime, runTime)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimRun indicates an expected call of ClaimRun
+func (mr *MockVMSnapshotScheduleDaoMockRecorder) ClaimRun(serviceID interface{}, lastRunTime interface{}, runTime interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimRun", reflect.TypeOf((*MockVMSnapshotScheduleDao)(nil).ClaimRun), serviceID, lastRunTime, runTime)
+}
+
+// MockAPITokenDao is a mock of APITokenDao interface
+type MockAPITokenDao struct {
 	ctrl     *gomock.Controller
-	recorder *MockHTTPRuleDaoMockRecorder
+	recorder *MockAPITokenDaoMockRecorder
 }
 
-// MockHTTPRuleDaoMockRecorder is the mock recorder for MockHTTPRuleDao
-type MockHTTPRuleDaoMockRecorder struct {
-	mock *MockHTTPRuleDao
+// MockAPITokenDaoMockRecorder is the mock recorder for MockAPITokenDao
+type MockAPITokenDaoMockRecorder struct {
+	mock *MockAPITokenDao
 }
 
-// NewMockHTTPRuleDao creates a new mock instance
-func NewMockHTTPRuleDao(ctrl *gomock.Controller) *MockHTTPRuleDao {
-	mock := &MockHTTPRuleDao{ctrl: ctrl}
-	mock.recorder = &MockHTTPRuleDaoMockRecorder{mock}
+// NewMockAPITokenDao creates a new mock instance
+func NewMockAPITokenDao(ctrl *gomock.Controller) *MockAPITokenDao {
+	mock := &MockAPITokenDao{ctrl: ctrl}
+	mock.recorder = &MockAPITokenDaoMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockHTTPRuleDao) EXPECT() *MockHTTPRuleDaoMockRecorder {
+func (m *MockAPITokenDao) EXPECT() *MockAPITokenDaoMockRecorder {
 	return m.recorder
 }
 
 // AddModel mocks base method
-func (m *MockHTTPRuleDao) AddModel(arg0 model.Interface) error {
+func (m *MockAPITokenDao) AddModel(arg0 model.Interface) error {
 	ret := m.ctrl.Call(m, "AddModel", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddModel indicates an expected call of AddModel
-func (mr *MockHTTPRuleDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockHTTPRuleDao)(nil).AddModel), arg0)
+func (mr *MockAPITokenDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockAPITokenDao)(nil).AddModel), arg0)
 }
 
 // UpdateModel mocks base method
-func (m *MockHTTPRuleDao) UpdateModel(arg0 model.Interface) error {
+func (m *MockAPITokenDao) UpdateModel(arg0 model.Interface) error {
 	ret := m.ctrl.Call(m, "UpdateModel", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateModel indicates an expected call of UpdateModel
-func (mr *MockHTTPRuleDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockHTTPRuleDao)(nil).UpdateModel), arg0)
+func (mr *MockAPITokenDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockAPITokenDao)(nil).UpdateModel), arg0)
 }
 
-// GetHTTPRuleByID mocks base method
-func (m *MockHTTPRuleDao) GetHTTPRuleByID(id string) (*model.HTTPRule, error) {
-	ret := m.ctrl.Call(m, "GetHTTPRuleByID", id)
-	ret0, _ := ret[0].(*model.HTTPRule)
+// GetByName mocks base method
+func (m *MockAPITokenDao) GetByName(name string) (*model.APIToken, error) {
+	ret := m.ctrl.Call(m, "GetByName", name)
+	ret0, _ := ret[0].(*model.APIToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetHTTPRuleByID indicates an expected call of GetHTTPRuleByID
-func (mr *MockHTTPRuleDaoMockRecorder) GetHTTPRuleByID(id interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHTTPRuleByID", reflect.TypeOf((*MockHTTPRuleDao)(nil).GetHTTPRuleByID), id)
+// GetByName indicates an expected call of GetByName
+func (mr *MockAPITokenDaoMockRecorder) GetByName(name interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockAPITokenDao)(nil).GetByName), name)
 }
 
-// GetHTTPRuleByServiceIDAndContainerPort mocks base method
-func (m *MockHTTPRuleDao) GetHTTPRuleByServiceIDAndContainerPort(serviceID string, containerPort int) ([]*model.HTTPRule, error) {
-	ret := m.ctrl.Call(m, "GetHTTPRuleByServiceIDAndContainerPort", serviceID, containerPort)
-	ret0, _ := ret[0].([]*model.HTTPRule)
+// GetByTokenHash mocks base method
+func (m *MockAPITokenDao) GetByTokenHash(tokenHash string) (*model.APIToken, error) {
+	ret := m.ctrl.Call(m, "GetByTokenHash", tokenHash)
+	ret0, _ := ret[0].(*model.APIToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetHTTPRuleByServiceIDAndContainerPort indicates an expected call of GetHTTPRuleByServiceIDAndContainerPort
-func (mr *MockHTTPRuleDaoMockRecorder) GetHTTPRuleByServiceIDAndContainerPort(serviceID, containerPort interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHTTPRuleByServiceIDAndContainerPort", reflect.TypeOf((*MockHTTPRuleDao)(nil).GetHTTPRuleByServiceIDAndContainerPort), serviceID, containerPort)
+// GetByTokenHash indicates an expected call of GetByTokenHash
+func (mr *MockAPITokenDaoMockRecorder) GetByTokenHash(tokenHash interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByTokenHash", reflect.TypeOf((*MockAPITokenDao)(nil).GetByTokenHash), tokenHash)
 }
 
-// GetHTTPRulesByCertificateID mocks base method
-func (m *MockHTTPRuleDao) GetHTTPRulesByCertificateID(certificateID string) ([]*model.HTTPRule, error) {
-	ret := m.ctrl.Call(m, "GetHTTPRulesByCertificateID", certificateID)
-	ret0, _ := ret[0].([]*model.HTTPRule)
+// List mocks base method
+func (m *MockAPITokenDao) List() ([]*model.APIToken, error) {
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*model.APIToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetHTTPRulesByCertificateID indicates an expected call of GetHTTPRulesByCertificateID
-func (mr *MockHTTPRuleDaoMockRecorder) GetHTTPRulesByCertificateID(certificateID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHTTPRulesByCertificateID", reflect.TypeOf((*MockHTTPRuleDao)(nil).GetHTTPRulesByCertificateID), certificateID)
+// List indicates an expected call of List
+func (mr *MockAPITokenDaoMockRecorder) List() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAPITokenDao)(nil).List))
 }
 
-// DeleteHTTPRuleByID mocks base method
-func (m *MockHTTPRuleDao) DeleteHTTPRuleByID(id string) error {
-	ret := m.ctrl.Call(m, "DeleteHTTPRuleByID", id)
+// DeleteByName mocks base method
+func (m *MockAPITokenDao) DeleteByName(name string) error {
+	ret := m.ctrl.Call(m, "DeleteByName", name)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteHTTPRuleByID indicates an expected call of DeleteHTTPRuleByID
-func (mr *MockHTTPRuleDaoMockRecorder) DeleteHTTPRuleByID(id interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHTTPRuleByID", reflect.TypeOf((*MockHTTPRuleDao)(nil).DeleteHTTPRuleByID), id)
+// DeleteByName indicates an expected call of DeleteByName
+func (mr *MockAPITokenDaoMockRecorder) DeleteByName(name interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByName", reflect.TypeOf((*MockAPITokenDao)(nil).DeleteByName), name)
 }
 
-// DeleteHTTPRuleByServiceID mocks base method
-func (m *MockHTTPRuleDao) DeleteHTTPRuleByServiceID(serviceID string) error {
-	ret := m.ctrl.Call(m, "DeleteHTTPRuleByServiceID", serviceID)
+// UpdateLastUsedTime mocks base method
+func (m *MockAPITokenDao) UpdateLastUsedTime(id uint, lastUsedTime time.Time) error {
+	ret := m.ctrl.Call(m, "UpdateLastUsedTime", id, lastUsedTime)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteHTTPRuleByServiceID indicates an expected call of DeleteHTTPRuleByServiceID
-func (mr *MockHTTPRuleDaoMockRecorder) DeleteHTTPRuleByServiceID(serviceID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHTTPRuleByServiceID", reflect.TypeOf((*MockHTTPRuleDao)(nil).DeleteHTTPRuleByServiceID), serviceID)
+// UpdateLastUsedTime indicates an expected call of UpdateLastUsedTime
+func (mr *MockAPITokenDaoMockRecorder) UpdateLastUsedTime(id interface{}, lastUsedTime interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsedTime", reflect.TypeOf((*MockAPITokenDao)(nil).UpdateLastUsedTime), id, lastUsedTime)
 }
 
-// ListByServiceID mocks base method
-func (m *MockHTTPRuleDao) ListByServiceID(serviceID string) ([]*model.HTTPRule, error) {
-	ret := m.ctrl.Call(m, "ListByServiceID", serviceID)
-	ret0, _ := ret[0].([]*model.HTTPRule)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// MockAuditLogDao is a mock of AuditLogDao interface
+type MockAuditLogDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogDaoMockRecorder
 }
 
-// ListByServiceID indicates an expected call of ListByServiceID
-func (mr *MockHTTPRuleDaoMockRecorder) ListByServiceID(serviceID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByServiceID", reflect.TypeOf((*MockHTTPRuleDao)(nil).ListByServiceID), serviceID)
+// MockAuditLogDaoMockRecorder is the mock recorder for MockAuditLogDao
+type MockAuditLogDaoMockRecorder struct {
+	mock *MockAuditLogDao
 }
 
-// ListByCertID mocks base method
-func (m *MockHTTPRuleDao) ListByCertID(certID string) ([]*model.HTTPRule, error) {
-	ret := m.ctrl.Call(m, "ListByCertID", certID)
-	ret0, _ := ret[0].([]*model.HTTPRule)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// NewMockAuditLogDao creates a new mock instance
+func NewMockAuditLogDao(ctrl *gomock.Controller) *MockAuditLogDao {
+	mock := &MockAuditLogDao{ctrl: ctrl}
+	mock.recorder = &MockAuditLogDaoMockRecorder{mock}
+	return mock
 }
 
-// ListByCertID indicates an expected call of ListByCertID
-func (mr *MockHTTPRuleDaoMockRecorder) ListByCertID(certID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByCertID", reflect.TypeOf((*MockHTTPRuleDao)(nil).ListByCertID), certID)
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAuditLogDao) EXPECT() *MockAuditLogDaoMockRecorder {
+	return m.recorder
 }
 
-// MockTCPRuleDao is a mock of TCPRuleDao interface
-type MockTCPRuleDao struct {
-	ctrl     *gomock.Controller
-	recorder *MockTCPRuleDaoMockRecorder
+// Append mocks base method
+func (m *MockAuditLogDao) Append(log *model.AuditLog) error {
+	ret := m.ctrl.Call(m, "Append", log)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// MockTCPRuleDaoMockRecorder is the mock recorder for MockTCPRuleDao
-type MockTCPRuleDaoMockRecorder struct {
+// Append indicates an expected call of Append
+func (mr *MockAuditLogDaoMockRecorder) Append(log interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockAuditLogDao)(nil).Append), log)
+}
+
+// List mocks base method
+func (m *MockAuditLogDao) List(query *model.AuditLogQuery, page int, pageSize int) ([]*model.AuditLog, int64, error) {
+	ret := m.ctrl.Call(m, "List", query, page, pageSize)
+	ret0, _ := ret[0].([]*model.AuditLog)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List
+func (mr *MockAuditLogDaoMockRecorder) List(query interface{}, page interface{}, pageSize interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAuditLogDao)(nil).List), query, page, pageSize)
+}
+
+// ListAfter mocks base method
+func (m *MockAuditLogDao) ListAfter(query *model.AuditLogQuery, afterID uint, limit int) ([]*model.AuditLog, error) {
+	ret := m.ctrl.Call(m, "ListAfter", query, afterID, limit)
+	ret0, _ := ret[0].([]*model.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAfter indicates an expected call of ListAfter
+func (mr *MockAuditLogDaoMockRecorder) ListAfter(query interface{}, afterID interface{}, limit interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAfter", reflect.TypeOf((*MockAuditLogDao)(nil).ListAfter), query, afterID, limit)
+}
+
+// MockTerminalSessionDao is a mock of TerminalSessionDao interface
+type MockTerminalSessionDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockTerminalSessionDaoMockRecorder
+}
+
+// MockTerminalSessionDaoMockRecorder is the mock recorder for MockTerminalSessionDao
+type MockTerminalSessionDaoMockRecorder struct {
+	mock *MockTerminalSessionDao
+}
+
+// NewMockTerminalSessionDao creates a new mock instance
+func NewMockTerminalSessionDao(ctrl *gomock.Controller) *MockTerminalSessionDao {
+	mock := &MockTerminalSessionDao{ctrl: ctrl}
+	mock.recorder = &MockTerminalSessionDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTerminalSessionDao) EXPECT() *MockTerminalSessionDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockTerminalSessionDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockTerminalSessionDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockTerminalSessionDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockTerminalSessionDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockTerminalSessionDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockTerminalSessionDao)(nil).UpdateModel), arg0)
+}
+
+// GetBySessionID mocks base method
+func (m *MockTerminalSessionDao) GetBySessionID(sessionID string) (*model.TerminalSession, error) {
+	ret := m.ctrl.Call(m, "GetBySessionID", sessionID)
+	ret0, _ := ret[0].(*model.TerminalSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBySessionID indicates an expected call of GetBySessionID
+func (mr *MockTerminalSessionDaoMockRecorder) GetBySessionID(sessionID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySessionID", reflect.TypeOf((*MockTerminalSessionDao)(nil).GetBySessionID), sessionID)
+}
+
+// List mocks base method
+func (m *MockTerminalSessionDao) List(query *model.TerminalSessionQuery, page int, pageSize int) ([]*model.TerminalSession, int64, error) {
+	ret := m.ctrl.Call(m, "List", query, page, pageSize)
+	ret0, _ := ret[0].([]*model.TerminalSession)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List
+func (mr *MockTerminalSessionDaoMockRecorder) List(query interface{}, page interface{}, pageSize interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTerminalSessionDao)(nil).List), query, page, pageSize)
+}
+
+// MockBuildCacheDao is a mock of BuildCacheDao interface
+type MockBuildCacheDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockBuildCacheDaoMockRecorder
+}
+
+// MockBuildCacheDaoMockRecorder is the mock recorder for MockBuildCacheDao
+type MockBuildCacheDaoMockRecorder struct {
+	mock *MockBuildCacheDao
+}
+
+// NewMockBuildCacheDao creates a new mock instance
+func NewMockBuildCacheDao(ctrl *gomock.Controller) *MockBuildCacheDao {
+	mock := &MockBuildCacheDao{ctrl: ctrl}
+	mock.recorder = &MockBuildCacheDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockBuildCacheDao) EXPECT() *MockBuildCacheDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockBuildCacheDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockBuildCacheDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockBuildCacheDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockBuildCacheDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockBuildCacheDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockBuildCacheDao)(nil).UpdateModel), arg0)
+}
+
+// GetByServiceID mocks base method
+func (m *MockBuildCacheDao) GetByServiceID(serviceID string) (*model.BuildCache, error) {
+	ret := m.ctrl.Call(m, "GetByServiceID", serviceID)
+	ret0, _ := ret[0].(*model.BuildCache)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByServiceID indicates an expected call of GetByServiceID
+func (mr *MockBuildCacheDaoMockRecorder) GetByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByServiceID", reflect.TypeOf((*MockBuildCacheDao)(nil).GetByServiceID), serviceID)
+}
+
+// ListLeastRecentlyUsed mocks base method
+func (m *MockBuildCacheDao) ListLeastRecentlyUsed() ([]*model.BuildCache, error) {
+	ret := m.ctrl.Call(m, "ListLeastRecentlyUsed")
+	ret0, _ := ret[0].([]*model.BuildCache)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLeastRecentlyUsed indicates an expected call of ListLeastRecentlyUsed
+func (mr *MockBuildCacheDaoMockRecorder) ListLeastRecentlyUsed() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLeastRecentlyUsed", reflect.TypeOf((*MockBuildCacheDao)(nil).ListLeastRecentlyUsed))
+}
+
+// DeleteByServiceID mocks base method
+func (m *MockBuildCacheDao) DeleteByServiceID(serviceID string) error {
+	ret := m.ctrl.Call(m, "DeleteByServiceID", serviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByServiceID indicates an expected call of DeleteByServiceID
+func (mr *MockBuildCacheDaoMockRecorder) DeleteByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByServiceID", reflect.TypeOf((*MockBuildCacheDao)(nil).DeleteByServiceID), serviceID)
+}
+
+// MockServiceSourceDao is a mock of ServiceSourceDao interface
+type MockServiceSourceDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceSourceDaoMockRecorder
+}
+
+// MockServiceSourceDaoMockRecorder is the mock recorder for MockServiceSourceDao
+type MockServiceSourceDaoMockRecorder struct {
+	mock *MockServiceSourceDao
+}
+
+// NewMockServiceSourceDao creates a new mock instance
+func NewMockServiceSourceDao(ctrl *gomock.Controller) *MockServiceSourceDao {
+	mock := &MockServiceSourceDao{ctrl: ctrl}
+	mock.recorder = &MockServiceSourceDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockServiceSourceDao) EXPECT() *MockServiceSourceDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockServiceSourceDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockServiceSourceDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockServiceSourceDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockServiceSourceDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockServiceSourceDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockServiceSourceDao)(nil).UpdateModel), arg0)
+}
+
+// GetServiceSource mocks base method
+func (m *MockServiceSourceDao) GetServiceSource(serviceID string) ([]*model.ServiceSourceConfig, error) {
+	ret := m.ctrl.Call(m, "GetServiceSource", serviceID)
+	ret0, _ := ret[0].([]*model.ServiceSourceConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceSource indicates an expected call of GetServiceSource
+func (mr *MockServiceSourceDaoMockRecorder) GetServiceSource(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceSource", reflect.TypeOf((*MockServiceSourceDao)(nil).GetServiceSource), serviceID)
+}
+
+// MockCertificateDao is a mock of CertificateDao interface
+type MockCertificateDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockCertificateDaoMockRecorder
+}
+
+// MockCertificateDaoMockRecorder is the mock recorder for MockCertificateDao
+type MockCertificateDaoMockRecorder struct {
+	mock *MockCertificateDao
+}
+
+// NewMockCertificateDao creates a new mock instance
+func NewMockCertificateDao(ctrl *gomock.Controller) *MockCertificateDao {
+	mock := &MockCertificateDao{ctrl: ctrl}
+	mock.recorder = &MockCertificateDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCertificateDao) EXPECT() *MockCertificateDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockCertificateDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockCertificateDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockCertificateDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockCertificateDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockCertificateDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockCertificateDao)(nil).UpdateModel), arg0)
+}
+
+// AddOrUpdate mocks base method
+func (m *MockCertificateDao) AddOrUpdate(mo model.Interface) error {
+	ret := m.ctrl.Call(m, "AddOrUpdate", mo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddOrUpdate indicates an expected call of AddOrUpdate
+func (mr *MockCertificateDaoMockRecorder) AddOrUpdate(mo interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOrUpdate", reflect.TypeOf((*MockCertificateDao)(nil).AddOrUpdate), mo)
+}
+
+// GetCertificateByID mocks base method
+func (m *MockCertificateDao) GetCertificateByID(certificateID string) (*model.Certificate, error) {
+	ret := m.ctrl.Call(m, "GetCertificateByID", certificateID)
+	ret0, _ := ret[0].(*model.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCertificateByID indicates an expected call of GetCertificateByID
+func (mr *MockCertificateDaoMockRecorder) GetCertificateByID(certificateID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificateByID", reflect.TypeOf((*MockCertificateDao)(nil).GetCertificateByID), certificateID)
+}
+
+// MockRuleExtensionDao is a mock of RuleExtensionDao interface
+type MockRuleExtensionDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockRuleExtensionDaoMockRecorder
+}
+
+// MockRuleExtensionDaoMockRecorder is the mock recorder for MockRuleExtensionDao
+type MockRuleExtensionDaoMockRecorder struct {
+	mock *MockRuleExtensionDao
+}
+
+// NewMockRuleExtensionDao creates a new mock instance
+func NewMockRuleExtensionDao(ctrl *gomock.Controller) *MockRuleExtensionDao {
+	mock := &MockRuleExtensionDao{ctrl: ctrl}
+	mock.recorder = &MockRuleExtensionDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRuleExtensionDao) EXPECT() *MockRuleExtensionDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockRuleExtensionDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockRuleExtensionDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockRuleExtensionDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockRuleExtensionDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockRuleExtensionDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockRuleExtensionDao)(nil).UpdateModel), arg0)
+}
+
+// GetRuleExtensionByRuleID mocks base method
+func (m *MockRuleExtensionDao) GetRuleExtensionByRuleID(ruleID string) ([]*model.RuleExtension, error) {
+	ret := m.ctrl.Call(m, "GetRuleExtensionByRuleID", ruleID)
+	ret0, _ := ret[0].([]*model.RuleExtension)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRuleExtensionByRuleID indicates an expected call of GetRuleExtensionByRuleID
+func (mr *MockRuleExtensionDaoMockRecorder) GetRuleExtensionByRuleID(ruleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRuleExtensionByRuleID", reflect.TypeOf((*MockRuleExtensionDao)(nil).GetRuleExtensionByRuleID), ruleID)
+}
+
+// DeleteRuleExtensionByRuleID mocks base method
+func (m *MockRuleExtensionDao) DeleteRuleExtensionByRuleID(ruleID string) error {
+	ret := m.ctrl.Call(m, "DeleteRuleExtensionByRuleID", ruleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRuleExtensionByRuleID indicates an expected call of DeleteRuleExtensionByRuleID
+func (mr *MockRuleExtensionDaoMockRecorder) DeleteRuleExtensionByRuleID(ruleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRuleExtensionByRuleID", reflect.TypeOf((*MockRuleExtensionDao)(nil).DeleteRuleExtensionByRuleID), ruleID)
+}
+
+// MockHTTPRuleDao is a mock of HTTPRuleDao interface
+type MockHTTPRuleDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockHTTPRuleDaoMockRecorder
+}
+
+// MockHTTPRuleDaoMockRecorder is the mock recorder for MockHTTPRuleDao
+type MockHTTPRuleDaoMockRecorder struct {
+	mock *MockHTTPRuleDao
+}
+
+// NewMockHTTPRuleDao creates a new mock instance
+func NewMockHTTPRuleDao(ctrl *gomock.Controller) *MockHTTPRuleDao {
+	mock := &MockHTTPRuleDao{ctrl: ctrl}
+	mock.recorder = &MockHTTPRuleDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHTTPRuleDao) EXPECT() *MockHTTPRuleDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockHTTPRuleDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockHTTPRuleDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockHTTPRuleDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockHTTPRuleDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockHTTPRuleDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockHTTPRuleDao)(nil).UpdateModel), arg0)
+}
+
+// GetHTTPRuleByID mocks base method
+func (m *MockHTTPRuleDao) GetHTTPRuleByID(id string) (*model.HTTPRule, error) {
+	ret := m.ctrl.Call(m, "GetHTTPRuleByID", id)
+	ret0, _ := ret[0].(*model.HTTPRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHTTPRuleByID indicates an expected call of GetHTTPRuleByID
+func (mr *MockHTTPRuleDaoMockRecorder) GetHTTPRuleByID(id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHTTPRuleByID", reflect.TypeOf((*MockHTTPRuleDao)(nil).GetHTTPRuleByID), id)
+}
+
+// GetHTTPRuleByServiceIDAndContainerPort mocks base method
+func (m *MockHTTPRuleDao) GetHTTPRuleByServiceIDAndContainerPort(serviceID string, containerPort int) ([]*model.HTTPRule, error) {
+	ret := m.ctrl.Call(m, "GetHTTPRuleByServiceIDAndContainerPort", serviceID, containerPort)
+	ret0, _ := ret[0].([]*model.HTTPRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHTTPRuleByServiceIDAndContainerPort indicates an expected call of GetHTTPRuleByServiceIDAndContainerPort
+func (mr *MockHTTPRuleDaoMockRecorder) GetHTTPRuleByServiceIDAndContainerPort(serviceID, containerPort interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHTTPRuleByServiceIDAndContainerPort", reflect.TypeOf((*MockHTTPRuleDao)(nil).GetHTTPRuleByServiceIDAndContainerPort), serviceID, containerPort)
+}
+
+// GetHTTPRulesByCertificateID mocks base method
+func (m *MockHTTPRuleDao) GetHTTPRulesByCertificateID(certificateID string) ([]*model.HTTPRule, error) {
+	ret := m.ctrl.Call(m, "GetHTTPRulesByCertificateID", certificateID)
+	ret0, _ := ret[0].([]*model.HTTPRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHTTPRulesByCertificateID indicates an expected call of GetHTTPRulesByCertificateID
+func (mr *MockHTTPRuleDaoMockRecorder) GetHTTPRulesByCertificateID(certificateID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHTTPRulesByCertificateID", reflect.TypeOf((*MockHTTPRuleDao)(nil).GetHTTPRulesByCertificateID), certificateID)
+}
+
+// DeleteHTTPRuleByID mocks base method
+func (m *MockHTTPRuleDao) DeleteHTTPRuleByID(id string) error {
+	ret := m.ctrl.Call(m, "DeleteHTTPRuleByID", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteHTTPRuleByID indicates an expected call of DeleteHTTPRuleByID
+func (mr *MockHTTPRuleDaoMockRecorder) DeleteHTTPRuleByID(id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHTTPRuleByID", reflect.TypeOf((*MockHTTPRuleDao)(nil).DeleteHTTPRuleByID), id)
+}
+
+// DeleteHTTPRuleByServiceID mocks base method
+func (m *MockHTTPRuleDao) DeleteHTTPRuleByServiceID(serviceID string) error {
+	ret := m.ctrl.Call(m, "DeleteHTTPRuleByServiceID", serviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteHTTPRuleByServiceID indicates an expected call of DeleteHTTPRuleByServiceID
+func (mr *MockHTTPRuleDaoMockRecorder) DeleteHTTPRuleByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHTTPRuleByServiceID", reflect.TypeOf((*MockHTTPRuleDao)(nil).DeleteHTTPRuleByServiceID), serviceID)
+}
+
+// ListByServiceID mocks base method
+func (m *MockHTTPRuleDao) ListByServiceID(serviceID string) ([]*model.HTTPRule, error) {
+	ret := m.ctrl.Call(m, "ListByServiceID", serviceID)
+	ret0, _ := ret[0].([]*model.HTTPRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByServiceID indicates an expected call of ListByServiceID
+func (mr *MockHTTPRuleDaoMockRecorder) ListByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByServiceID", reflect.TypeOf((*MockHTTPRuleDao)(nil).ListByServiceID), serviceID)
+}
+
+// ListByCertID mocks base method
+func (m *MockHTTPRuleDao) ListByCertID(certID string) ([]*model.HTTPRule, error) {
+	ret := m.ctrl.Call(m, "ListByCertID", certID)
+	ret0, _ := ret[0].([]*model.HTTPRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByCertID indicates an expected call of ListByCertID
+func (mr *MockHTTPRuleDaoMockRecorder) ListByCertID(certID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByCertID", reflect.TypeOf((*MockHTTPRuleDao)(nil).ListByCertID), certID)
+}
+
+// MockTCPRuleDao is a mock of TCPRuleDao interface
+type MockTCPRuleDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockTCPRuleDaoMockRecorder
+}
+
+// MockTCPRuleDaoMockRecorder is the mock recorder for MockTCPRuleDao
+type MockTCPRuleDaoMockRecorder struct {
 	mock *MockTCPRuleDao
 }
 
@@ -5076,6 +5694,19 @@ func (mr *MockTenantServceAutoscalerRulesDaoMockRecorder) ListEnableOnesByServic
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnableOnesByServiceID", reflect.TypeOf((*MockTenantServceAutoscalerRulesDao)(nil).ListEnableOnesByServiceID), serviceID)
 }
 
+// ListEnableScheduledOnes mocks base method
+func (m *MockTenantServceAutoscalerRulesDao) ListEnableScheduledOnes() ([]*model.TenantServiceAutoscalerRules, error) {
+	ret := m.ctrl.Call(m, "ListEnableScheduledOnes")
+	ret0, _ := ret[0].([]*model.TenantServiceAutoscalerRules)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnableScheduledOnes indicates an expected call of ListEnableScheduledOnes
+func (mr *MockTenantServceAutoscalerRulesDaoMockRecorder) ListEnableScheduledOnes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnableScheduledOnes", reflect.TypeOf((*MockTenantServceAutoscalerRulesDao)(nil).ListEnableScheduledOnes))
+}
+
 // MockTenantServceAutoscalerRuleMetricsDao is a mock of TenantServceAutoscalerRuleMetricsDao interface
 type MockTenantServceAutoscalerRuleMetricsDao struct {
 	ctrl     *gomock.Controller
@@ -5341,3 +5972,369 @@ func (m *MockTenantServiceMonitorDao) DeleteServiceMonitorByServiceID(serviceID
 func (mr *MockTenantServiceMonitorDaoMockRecorder) DeleteServiceMonitorByServiceID(serviceID interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServiceMonitorByServiceID", reflect.TypeOf((*MockTenantServiceMonitorDao)(nil).DeleteServiceMonitorByServiceID), serviceID)
 }
+
+// MockGitWebhookDao is a mock of GitWebhookDao interface
+type MockGitWebhookDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockGitWebhookDaoMockRecorder
+}
+
+// MockGitWebhookDaoMockRecorder is the mock recorder for MockGitWebhookDao
+type MockGitWebhookDaoMockRecorder struct {
+	mock *MockGitWebhookDao
+}
+
+// NewMockGitWebhookDao creates a new mock instance
+func NewMockGitWebhookDao(ctrl *gomock.Controller) *MockGitWebhookDao {
+	mock := &MockGitWebhookDao{ctrl: ctrl}
+	mock.recorder = &MockGitWebhookDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockGitWebhookDao) EXPECT() *MockGitWebhookDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockGitWebhookDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockGitWebhookDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockGitWebhookDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockGitWebhookDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockGitWebhookDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockGitWebhookDao)(nil).UpdateModel), arg0)
+}
+
+// GetByServiceID mocks base method
+func (m *MockGitWebhookDao) GetByServiceID(serviceID string) (*model.GitWebhook, error) {
+	ret := m.ctrl.Call(m, "GetByServiceID", serviceID)
+	ret0, _ := ret[0].(*model.GitWebhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByServiceID indicates an expected call of GetByServiceID
+func (mr *MockGitWebhookDaoMockRecorder) GetByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByServiceID", reflect.TypeOf((*MockGitWebhookDao)(nil).GetByServiceID), serviceID)
+}
+
+// ListEnabledByRepo mocks base method
+func (m *MockGitWebhookDao) ListEnabledByRepo(provider string, repoKey string) ([]*model.GitWebhook, error) {
+	ret := m.ctrl.Call(m, "ListEnabledByRepo", provider, repoKey)
+	ret0, _ := ret[0].([]*model.GitWebhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnabledByRepo indicates an expected call of ListEnabledByRepo
+func (mr *MockGitWebhookDaoMockRecorder) ListEnabledByRepo(provider interface{}, repoKey interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnabledByRepo", reflect.TypeOf((*MockGitWebhookDao)(nil).ListEnabledByRepo), provider, repoKey)
+}
+
+// DeleteByServiceID mocks base method
+func (m *MockGitWebhookDao) DeleteByServiceID(serviceID string) error {
+	ret := m.ctrl.Call(m, "DeleteByServiceID", serviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByServiceID indicates an expected call of DeleteByServiceID
+func (mr *MockGitWebhookDaoMockRecorder) DeleteByServiceID(serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByServiceID", reflect.TypeOf((*MockGitWebhookDao)(nil).DeleteByServiceID), serviceID)
+}
+
+// MockGitWebhookDeliveryDao is a mock of GitWebhookDeliveryDao interface
+type MockGitWebhookDeliveryDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockGitWebhookDeliveryDaoMockRecorder
+}
+
+// MockGitWebhookDeliveryDaoMockRecorder is the mock recorder for MockGitWebhookDeliveryDao
+type MockGitWebhookDeliveryDaoMockRecorder struct {
+	mock *MockGitWebhookDeliveryDao
+}
+
+// NewMockGitWebhookDeliveryDao creates a new mock instance
+func NewMockGitWebhookDeliveryDao(ctrl *gomock.Controller) *MockGitWebhookDeliveryDao {
+	mock := &MockGitWebhookDeliveryDao{ctrl: ctrl}
+	mock.recorder = &MockGitWebhookDeliveryDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockGitWebhookDeliveryDao) EXPECT() *MockGitWebhookDeliveryDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockGitWebhookDeliveryDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockGitWebhookDeliveryDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockGitWebhookDeliveryDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockGitWebhookDeliveryDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockGitWebhookDeliveryDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockGitWebhookDeliveryDao)(nil).UpdateModel), arg0)
+}
+
+// GetDelivery mocks base method
+func (m *MockGitWebhookDeliveryDao) GetDelivery(provider string, deliveryID string, serviceID string) (*model.GitWebhookDelivery, error) {
+	ret := m.ctrl.Call(m, "GetDelivery", provider, deliveryID, serviceID)
+	ret0, _ := ret[0].(*model.GitWebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelivery indicates an expected call of GetDelivery
+func (mr *MockGitWebhookDeliveryDaoMockRecorder) GetDelivery(provider interface{}, deliveryID interface{}, serviceID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelivery", reflect.TypeOf((*MockGitWebhookDeliveryDao)(nil).GetDelivery), provider, deliveryID, serviceID)
+}
+
+// GetTriggeredByCommit mocks base method
+func (m *MockGitWebhookDeliveryDao) GetTriggeredByCommit(serviceID string, commitSHA string) (*model.GitWebhookDelivery, error) {
+	ret := m.ctrl.Call(m, "GetTriggeredByCommit", serviceID, commitSHA)
+	ret0, _ := ret[0].(*model.GitWebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTriggeredByCommit indicates an expected call of GetTriggeredByCommit
+func (mr *MockGitWebhookDeliveryDaoMockRecorder) GetTriggeredByCommit(serviceID interface{}, commitSHA interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTriggeredByCommit", reflect.TypeOf((*MockGitWebhookDeliveryDao)(nil).GetTriggeredByCommit), serviceID, commitSHA)
+}
+
+// ListByServiceID mocks base method
+func (m *MockGitWebhookDeliveryDao) ListByServiceID(serviceID string, limit int) ([]*model.GitWebhookDelivery, error) {
+	ret := m.ctrl.Call(m, "ListByServiceID", serviceID, limit)
+	ret0, _ := ret[0].([]*model.GitWebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByServiceID indicates an expected call of ListByServiceID
+func (mr *MockGitWebhookDeliveryDaoMockRecorder) ListByServiceID(serviceID interface{}, limit interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByServiceID", reflect.TypeOf((*MockGitWebhookDeliveryDao)(nil).ListByServiceID), serviceID, limit)
+}
+
+// MockNotificationChannelDao is a mock of NotificationChannelDao interface
+type MockNotificationChannelDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationChannelDaoMockRecorder
+}
+
+// MockNotificationChannelDaoMockRecorder is the mock recorder for MockNotificationChannelDao
+type MockNotificationChannelDaoMockRecorder struct {
+	mock *MockNotificationChannelDao
+}
+
+// NewMockNotificationChannelDao creates a new mock instance
+func NewMockNotificationChannelDao(ctrl *gomock.Controller) *MockNotificationChannelDao {
+	mock := &MockNotificationChannelDao{ctrl: ctrl}
+	mock.recorder = &MockNotificationChannelDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockNotificationChannelDao) EXPECT() *MockNotificationChannelDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockNotificationChannelDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockNotificationChannelDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockNotificationChannelDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockNotificationChannelDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockNotificationChannelDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockNotificationChannelDao)(nil).UpdateModel), arg0)
+}
+
+// GetByChannelID mocks base method
+func (m *MockNotificationChannelDao) GetByChannelID(channelID string) (*model.NotificationChannel, error) {
+	ret := m.ctrl.Call(m, "GetByChannelID", channelID)
+	ret0, _ := ret[0].(*model.NotificationChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByChannelID indicates an expected call of GetByChannelID
+func (mr *MockNotificationChannelDaoMockRecorder) GetByChannelID(channelID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByChannelID", reflect.TypeOf((*MockNotificationChannelDao)(nil).GetByChannelID), channelID)
+}
+
+// ListByTenantID mocks base method
+func (m *MockNotificationChannelDao) ListByTenantID(tenantID string) ([]*model.NotificationChannel, error) {
+	ret := m.ctrl.Call(m, "ListByTenantID", tenantID)
+	ret0, _ := ret[0].([]*model.NotificationChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByTenantID indicates an expected call of ListByTenantID
+func (mr *MockNotificationChannelDaoMockRecorder) ListByTenantID(tenantID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByTenantID", reflect.TypeOf((*MockNotificationChannelDao)(nil).ListByTenantID), tenantID)
+}
+
+// DeleteByChannelID mocks base method
+func (m *MockNotificationChannelDao) DeleteByChannelID(channelID string) error {
+	ret := m.ctrl.Call(m, "DeleteByChannelID", channelID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByChannelID indicates an expected call of DeleteByChannelID
+func (mr *MockNotificationChannelDaoMockRecorder) DeleteByChannelID(channelID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByChannelID", reflect.TypeOf((*MockNotificationChannelDao)(nil).DeleteByChannelID), channelID)
+}
+
+// MockNotificationRuleDao is a mock of NotificationRuleDao interface
+type MockNotificationRuleDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationRuleDaoMockRecorder
+}
+
+// MockNotificationRuleDaoMockRecorder is the mock recorder for MockNotificationRuleDao
+type MockNotificationRuleDaoMockRecorder struct {
+	mock *MockNotificationRuleDao
+}
+
+// NewMockNotificationRuleDao creates a new mock instance
+func NewMockNotificationRuleDao(ctrl *gomock.Controller) *MockNotificationRuleDao {
+	mock := &MockNotificationRuleDao{ctrl: ctrl}
+	mock.recorder = &MockNotificationRuleDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockNotificationRuleDao) EXPECT() *MockNotificationRuleDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method
+func (m *MockNotificationRuleDao) AddModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel
+func (mr *MockNotificationRuleDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockNotificationRuleDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method
+func (m *MockNotificationRuleDao) UpdateModel(arg0 model.Interface) error {
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel
+func (mr *MockNotificationRuleDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockNotificationRuleDao)(nil).UpdateModel), arg0)
+}
+
+// GetByRuleID mocks base method
+func (m *MockNotificationRuleDao) GetByRuleID(ruleID string) (*model.NotificationRule, error) {
+	ret := m.ctrl.Call(m, "GetByRuleID", ruleID)
+	ret0, _ := ret[0].(*model.NotificationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByRuleID indicates an expected call of GetByRuleID
+func (mr *MockNotificationRuleDaoMockRecorder) GetByRuleID(ruleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByRuleID", reflect.TypeOf((*MockNotificationRuleDao)(nil).GetByRuleID), ruleID)
+}
+
+// ListByTenantID mocks base method
+func (m *MockNotificationRuleDao) ListByTenantID(tenantID string) ([]*model.NotificationRule, error) {
+	ret := m.ctrl.Call(m, "ListByTenantID", tenantID)
+	ret0, _ := ret[0].([]*model.NotificationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByTenantID indicates an expected call of ListByTenantID
+func (mr *MockNotificationRuleDaoMockRecorder) ListByTenantID(tenantID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByTenantID", reflect.TypeOf((*MockNotificationRuleDao)(nil).ListByTenantID), tenantID)
+}
+
+// ListEnabledByTenantID mocks base method
+func (m *MockNotificationRuleDao) ListEnabledByTenantID(tenantID string) ([]*model.NotificationRule, error) {
+	ret := m.ctrl.Call(m, "ListEnabledByTenantID", tenantID)
+	ret0, _ := ret[0].([]*model.NotificationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnabledByTenantID indicates an expected call of ListEnabledByTenantID
+func (mr *MockNotificationRuleDaoMockRecorder) ListEnabledByTenantID(tenantID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnabledByTenantID", reflect.TypeOf((*MockNotificationRuleDao)(nil).ListEnabledByTenantID), tenantID)
+}
+
+// DeleteByRuleID mocks base method
+func (m *MockNotificationRuleDao) DeleteByRuleID(ruleID string) error {
+	ret := m.ctrl.Call(m, "DeleteByRuleID", ruleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByRuleID indicates an expected call of DeleteByRuleID
+func (mr *MockNotificationRuleDaoMockRecorder) DeleteByRuleID(ruleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByRuleID", reflect.TypeOf((*MockNotificationRuleDao)(nil).DeleteByRuleID), ruleID)
+}
+
+// DeleteByChannelID mocks base method
+func (m *MockNotificationRuleDao) DeleteByChannelID(channelID string) error {
+	ret := m.ctrl.Call(m, "DeleteByChannelID", channelID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByChannelID indicates an expected call of DeleteByChannelID
+func (mr *MockNotificationRuleDaoMockRecorder) DeleteByChannelID(channelID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByChannelID", reflect.TypeOf((*MockNotificationRuleDao)(nil).DeleteByChannelID), channelID)
+}
@@ -108,6 +108,16 @@ type Manager interface {
 	NotificationEventDao() dao.NotificationEventDao
 	AppBackupDao() dao.AppBackupDao
 	AppBackupDaoTransactions(db *gorm.DB) dao.AppBackupDao
+	AppBackupScheduleDao() dao.AppBackupScheduleDao
+	VMSnapshotScheduleDao() dao.VMSnapshotScheduleDao
+	APITokenDao() dao.APITokenDao
+	AuditLogDao() dao.AuditLogDao
+	TerminalSessionDao() dao.TerminalSessionDao
+	BuildCacheDao() dao.BuildCacheDao
+	GitWebhookDao() dao.GitWebhookDao
+	GitWebhookDeliveryDao() dao.GitWebhookDeliveryDao
+	NotificationChannelDao() dao.NotificationChannelDao
+	NotificationRuleDao() dao.NotificationRuleDao
 	ServiceSourceDao() dao.ServiceSourceDao
 
 	// gateway
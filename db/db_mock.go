@@ -928,6 +928,146 @@ func (mr *MockManagerMockRecorder) AppBackupDaoTransactions(db interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppBackupDaoTransactions", reflect.TypeOf((*MockManager)(nil).AppBackupDaoTransactions), db)
 }
 
+// AppBackupScheduleDao mocks base method
+func (m *MockManager) AppBackupScheduleDao() dao.AppBackupScheduleDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppBackupScheduleDao")
+	ret0, _ := ret[0].(dao.AppBackupScheduleDao)
+	return ret0
+}
+
+// AppBackupScheduleDao indicates an expected call of AppBackupScheduleDao
+func (mr *MockManagerMockRecorder) AppBackupScheduleDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppBackupScheduleDao", reflect.TypeOf((*MockManager)(nil).AppBackupScheduleDao))
+}
+
+// VMSnapshotScheduleDao mocks base method
+func (m *MockManager) VMSnapshotScheduleDao() dao.VMSnapshotScheduleDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VMSnapshotScheduleDao")
+	ret0, _ := ret[0].(dao.VMSnapshotScheduleDao)
+	return ret0
+}
+
+// VMSnapshotScheduleDao indicates an expected call of VMSnapshotScheduleDao
+func (mr *MockManagerMockRecorder) VMSnapshotScheduleDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VMSnapshotScheduleDao", reflect.TypeOf((*MockManager)(nil).VMSnapshotScheduleDao))
+}
+
+// APITokenDao mocks base method
+func (m *MockManager) APITokenDao() dao.APITokenDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "APITokenDao")
+	ret0, _ := ret[0].(dao.APITokenDao)
+	return ret0
+}
+
+// APITokenDao indicates an expected call of APITokenDao
+func (mr *MockManagerMockRecorder) APITokenDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APITokenDao", reflect.TypeOf((*MockManager)(nil).APITokenDao))
+}
+
+// AuditLogDao mocks base method
+func (m *MockManager) AuditLogDao() dao.AuditLogDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditLogDao")
+	ret0, _ := ret[0].(dao.AuditLogDao)
+	return ret0
+}
+
+// AuditLogDao indicates an expected call of AuditLogDao
+func (mr *MockManagerMockRecorder) AuditLogDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditLogDao", reflect.TypeOf((*MockManager)(nil).AuditLogDao))
+}
+
+// TerminalSessionDao mocks base method
+func (m *MockManager) TerminalSessionDao() dao.TerminalSessionDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TerminalSessionDao")
+	ret0, _ := ret[0].(dao.TerminalSessionDao)
+	return ret0
+}
+
+// TerminalSessionDao indicates an expected call of TerminalSessionDao
+func (mr *MockManagerMockRecorder) TerminalSessionDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminalSessionDao", reflect.TypeOf((*MockManager)(nil).TerminalSessionDao))
+}
+
+// BuildCacheDao mocks base method
+func (m *MockManager) BuildCacheDao() dao.BuildCacheDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BuildCacheDao")
+	ret0, _ := ret[0].(dao.BuildCacheDao)
+	return ret0
+}
+
+// BuildCacheDao indicates an expected call of BuildCacheDao
+func (mr *MockManagerMockRecorder) BuildCacheDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildCacheDao", reflect.TypeOf((*MockManager)(nil).BuildCacheDao))
+}
+
+// GitWebhookDao mocks base method
+func (m *MockManager) GitWebhookDao() dao.GitWebhookDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GitWebhookDao")
+	ret0, _ := ret[0].(dao.GitWebhookDao)
+	return ret0
+}
+
+// GitWebhookDao indicates an expected call of GitWebhookDao
+func (mr *MockManagerMockRecorder) GitWebhookDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GitWebhookDao", reflect.TypeOf((*MockManager)(nil).GitWebhookDao))
+}
+
+// GitWebhookDeliveryDao mocks base method
+func (m *MockManager) GitWebhookDeliveryDao() dao.GitWebhookDeliveryDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GitWebhookDeliveryDao")
+	ret0, _ := ret[0].(dao.GitWebhookDeliveryDao)
+	return ret0
+}
+
+// GitWebhookDeliveryDao indicates an expected call of GitWebhookDeliveryDao
+func (mr *MockManagerMockRecorder) GitWebhookDeliveryDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GitWebhookDeliveryDao", reflect.TypeOf((*MockManager)(nil).GitWebhookDeliveryDao))
+}
+
+// NotificationChannelDao mocks base method
+func (m *MockManager) NotificationChannelDao() dao.NotificationChannelDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotificationChannelDao")
+	ret0, _ := ret[0].(dao.NotificationChannelDao)
+	return ret0
+}
+
+// NotificationChannelDao indicates an expected call of NotificationChannelDao
+func (mr *MockManagerMockRecorder) NotificationChannelDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotificationChannelDao", reflect.TypeOf((*MockManager)(nil).NotificationChannelDao))
+}
+
+// NotificationRuleDao mocks base method
+func (m *MockManager) NotificationRuleDao() dao.NotificationRuleDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotificationRuleDao")
+	ret0, _ := ret[0].(dao.NotificationRuleDao)
+	return ret0
+}
+
+// NotificationRuleDao indicates an expected call of NotificationRuleDao
+func (mr *MockManagerMockRecorder) NotificationRuleDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotificationRuleDao", reflect.TypeOf((*MockManager)(nil).NotificationRuleDao))
+}
+
 // ServiceSourceDao mocks base method
 func (m *MockManager) ServiceSourceDao() dao.ServiceSourceDao {
 	m.ctrl.T.Helper()
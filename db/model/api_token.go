@@ -0,0 +1,79 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// The verbs a scoped api token may be granted
+const (
+	APITokenVerbRead   = "read"
+	APITokenVerbDeploy = "deploy"
+	APITokenVerbDelete = "delete"
+	APITokenVerbExec   = "exec"
+)
+
+// APITokenVerbs all the verbs of the api tokens
+var APITokenVerbs = []string{APITokenVerbRead, APITokenVerbDeploy, APITokenVerbDelete, APITokenVerbExec}
+
+// APIToken a named api token bound to a set of tenants and verbs, only the sha256 of the token is stored
+type APIToken struct {
+	Model
+	Name      string `gorm:"column:name;size:64;unique_index" json:"name"`
+	TokenHash string `gorm:"column:token_hash;size:64;unique_index" json:"-"`
+	// TenantIDs the comma separated ids of the tenants the token can access
+	TenantIDs    string     `gorm:"column:tenant_ids;type:text" json:"tenant_ids"`
+	Verbs        string     `gorm:"column:verbs;size:64" json:"verbs"`
+	ExpireTime   *time.Time `gorm:"column:expire_time" json:"expire_time"`
+	LastUsedTime *time.Time `gorm:"column:last_used_time" json:"last_used_time"`
+}
+
+// TableName 表名
+func (t *APIToken) TableName() string {
+	return "region_api_token"
+}
+
+// Expired reports whether the token is expired at now
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpireTime != nil && !now.Before(*t.ExpireTime)
+}
+
+// AllowTenant reports whether the token can access the tenant
+func (t *APIToken) AllowTenant(tenantID string) bool {
+	return containsCommaItem(t.TenantIDs, tenantID)
+}
+
+// AllowVerb reports whether the token is granted the verb
+func (t *APIToken) AllowVerb(verb string) bool {
+	return containsCommaItem(t.Verbs, verb)
+}
+
+func containsCommaItem(list, item string) bool {
+	if item == "" {
+		return false
+	}
+	for _, value := range strings.Split(list, ",") {
+		if strings.TrimSpace(value) == item {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,17 @@
 package model
 
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// group app backup types
+const (
+	BackupTypeFull        = "full"
+	BackupTypeIncremental = "incremental"
+)
+
 // AppStatus app status
 type AppStatus struct {
 	EventID     string `gorm:"column:event_id;size:32;primary_key" json:"event_id"`
@@ -30,9 +42,91 @@ type AppBackup struct {
 	BackupMode string `gorm:"column:backup_mode;size:32" json:"backup_mode"`
 	BuckupSize int64  `gorm:"column:backup_size;type:bigint" json:"backup_size"`
 	Deleted    bool   `gorm:"column:deleted" json:"deleted"`
+	//BackupType in full,incremental
+	BackupType string `gorm:"column:backup_type;size:32;default:'full'" json:"backup_type"`
+	//ParentBackupID the backup which an incremental backup is based on
+	ParentBackupID string `gorm:"column:parent_backup_id;size:32" json:"parent_backup_id"`
+	//ScheduleID the schedule which creates the backup, empty for the manual ones
+	ScheduleID string `gorm:"column:schedule_id;size:32" json:"schedule_id"`
+	//ServiceIDs the comma separated ids of the backed up services
+	ServiceIDs string `gorm:"column:service_ids;type:text" json:"-"`
+	//Checksum the sha256 checksum of the backup package
+	Checksum string `gorm:"column:checksum;size:64" json:"checksum"`
+}
+
+// IsIncremental returns whether the backup only holds the changed volume data
+func (t *AppBackup) IsIncremental() bool {
+	return t.BackupType == BackupTypeIncremental
 }
 
 //TableName 表名
 func (t *AppBackup) TableName() string {
 	return "region_app_backup"
 }
+
+// AppBackupSchedule the cron schedule and the retention policy of group app backups
+type AppBackupSchedule struct {
+	Model
+	ScheduleID string `gorm:"column:schedule_id;size:32;unique_index" json:"schedule_id"`
+	GroupID    string `gorm:"column:group_id;size:32;index" json:"group_id"`
+	Schedule   string `gorm:"column:schedule;size:64" json:"schedule"`
+	Timezone   string `gorm:"column:timezone;size:64" json:"timezone"`
+	//Mode in full-online,full-offline
+	Mode       string `gorm:"column:mode;size:32" json:"mode"`
+	ServiceIDs string `gorm:"column:service_ids;type:text" json:"service_ids"`
+	//Metadata the console level metadata written into every backup
+	Metadata   string `gorm:"column:metadata;type:longtext" json:"metadata"`
+	Force      bool   `gorm:"column:force" json:"force"`
+	BucketName string `gorm:"column:bucket_name;size:255" json:"bucket_name"`
+	//Incremental backups only capture the volume files changed since the last backup
+	Incremental bool `gorm:"column:incremental" json:"incremental"`
+	//FullInterval the max number of incremental backups between two full backups
+	FullInterval int `gorm:"column:full_interval" json:"full_interval"`
+	//KeepLast, KeepDaily and KeepWeekly are the retention rules, the backups
+	//which are not kept by any rule are pruned, zero disables the rule.
+	KeepLast    int        `gorm:"column:keep_last" json:"keep_last"`
+	KeepDaily   int        `gorm:"column:keep_daily" json:"keep_daily"`
+	KeepWeekly  int        `gorm:"column:keep_weekly" json:"keep_weekly"`
+	Enable      bool       `gorm:"column:enable" json:"enable"`
+	LastRunTime *time.Time `gorm:"column:last_run_time" json:"last_run_time,omitempty"`
+}
+
+// TableName 表名
+func (t *AppBackupSchedule) TableName() string {
+	return "region_app_backup_schedule"
+}
+
+// Parse parses the cron expression of the schedule in its timezone
+func (t *AppBackupSchedule) Parse() (cron.Schedule, error) {
+	return parseSchedule(t.Schedule, t.Timezone)
+}
+
+// parseSchedule parses a standard cron expression in the timezone
+func parseSchedule(schedule, timezone string) (cron.Schedule, error) {
+	spec := schedule
+	if timezone != "" {
+		spec = "CRON_TZ=" + timezone + " " + spec
+	}
+	return cron.ParseStandard(spec)
+}
+
+// HasRetention returns whether any retention rule is set
+func (t *AppBackupSchedule) HasRetention() bool {
+	return t.KeepLast > 0 || t.KeepDaily > 0 || t.KeepWeekly > 0
+}
+
+// Location returns the timezone of the schedule, local time by default
+func (t *AppBackupSchedule) Location() *time.Location {
+	return scheduleLocation(t.Timezone)
+}
+
+func scheduleLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
@@ -25,11 +25,19 @@ const (
 	GovernanceModeKubernetesNativeService = "KUBERNETES_NATIVE_SERVICE"
 	// GovernanceModeIstioServiceMesh means the governance mode is ISTIO_SERVICE_MESH
 	GovernanceModeIstioServiceMesh = "ISTIO_SERVICE_MESH"
+	// GovernanceModeLinkerdServiceMesh means the governance mode is LINKERD_SERVICE_MESH
+	GovernanceModeLinkerdServiceMesh = "LINKERD_SERVICE_MESH"
+	// GovernanceModeCiliumServiceMesh means the governance mode is CILIUM_SERVICE_MESH
+	GovernanceModeCiliumServiceMesh = "CILIUM_SERVICE_MESH"
 )
 
 const (
 	// GovernanceModeKubernetesNativeServiceDesc -
 	GovernanceModeKubernetesNativeServiceDesc = "该模式组件间使用Kubernetes service名称域名进行通信，用户需要配置每个组件端口注册的service名称，治理能力有限"
+	// GovernanceModeLinkerdServiceMeshDesc -
+	GovernanceModeLinkerdServiceMeshDesc = "该模式使用 Linkerd 注入代理，组件间通信使用 mTLS，组件依赖关系转换为 Linkerd 授权策略"
+	// GovernanceModeCiliumServiceMeshDesc -
+	GovernanceModeCiliumServiceMeshDesc = "该模式使用 Cilium 服务网格，无需注入代理，组件依赖关系转换为 CiliumNetworkPolicy"
 )
 
 // app type
@@ -52,6 +60,8 @@ type Application struct {
 	Version         string `gorm:"column:version" json:"version"`
 	GovernanceMode  string `gorm:"column:governance_mode;default:'KUBERNETES_NATIVE_SERVICE'" json:"governance_mode"`
 	K8sApp          string `gorm:"column:k8s_app" json:"k8s_app"`
+	// ZeroTrust 开启后组件只允许被声明依赖它的组件访问
+	ZeroTrust bool `gorm:"column:zero_trust;default:false" json:"zero_trust"`
 }
 
 // TableName return tableName "application"
@@ -127,6 +137,7 @@ type AppGrayRelease struct {
 	AppID            string `gorm:"column:app_id" json:"app_id"`
 	EntryComponentID string `gorm:"column:entry_component_id" json:"entry_component_id"`
 	EntryHTTPRoute   string `gorm:"column:entry_http_route" json:"entry_http_route"`
+	EntryRouteKind   string `gorm:"column:entry_route_kind" json:"entry_route_kind"` // HTTPRoute（默认）或 ApisixRoute
 	FlowEntryRule    string `gorm:"column:flow_entry_rule;type:longtext" json:"flow_entry_rule"`
 	GrayStrategyType string `gorm:"column:gray_strategy_type" json:"gray_strategy_type"`
 	GrayStrategy     string `gorm:"column:gray_strategy;type:longtext" json:"gray_strategy"`
@@ -0,0 +1,82 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// AuditLog a mutating region api call, the records are chained by the hash of the
+// previous record so that a modified or deleted record breaks the chain.
+type AuditLog struct {
+	Model
+	// Operator the console user passed in the request body
+	Operator string `gorm:"column:operator;size:64" json:"operator"`
+	// TokenName the api token of the request, admin for the cluster token
+	TokenName    string `gorm:"column:token_name;size:64" json:"token_name"`
+	ClientIP     string `gorm:"column:client_ip;size:64" json:"client_ip"`
+	TenantID     string `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	TenantName   string `gorm:"column:tenant_name;size:64" json:"tenant_name"`
+	ServiceID    string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	ServiceAlias string `gorm:"column:service_alias;size:64" json:"service_alias"`
+	Method       string `gorm:"column:method;size:16" json:"method"`
+	Path         string `gorm:"column:path;size:1024" json:"path"`
+	// RequestBody the request payload with the secrets redacted
+	RequestBody string `gorm:"column:request_body;type:longtext" json:"request_body"`
+	StatusCode  int    `gorm:"column:status_code" json:"status_code"`
+	Result      string `gorm:"column:result;size:16" json:"result"`
+	PrevHash    string `gorm:"column:prev_hash;size:64;unique_index" json:"prev_hash"`
+	Hash        string `gorm:"column:hash;size:64" json:"hash"`
+}
+
+// TableName 表名
+func (a *AuditLog) TableName() string {
+	return "region_audit_log"
+}
+
+// The results of the audit logs
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// ComputeHash computes the hash of the record chained to PrevHash, the create time
+// is hashed in seconds as the database does not keep the sub seconds.
+func (a *AuditLog) ComputeHash() string {
+	data, _ := json.Marshal([]interface{}{
+		a.PrevHash, a.CreatedAt.Unix(), a.Operator, a.TokenName, a.ClientIP,
+		a.TenantID, a.TenantName, a.ServiceID, a.ServiceAlias,
+		a.Method, a.Path, a.RequestBody, a.StatusCode, a.Result,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditLogQuery the conditions of the audit logs query
+type AuditLogQuery struct {
+	TenantID  string
+	ServiceID string
+	Operator  string
+	TokenName string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
@@ -0,0 +1,135 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/robfig/cron/v3"
+)
+
+// autoscaler metric types
+const (
+	MetricsTypeResource = "resource_metrics"
+	MetricsTypePods     = "pods_metrics"
+	MetricsTypeObject   = "object_metrics"
+	MetricsTypeExternal = "external_metrics"
+)
+
+// autoscaler metric target types
+const (
+	MetricTargetTypeUtilization  = "utilization"
+	MetricTargetTypeAverageValue = "average_value"
+	MetricTargetTypeValue        = "value"
+)
+
+// AutoscalerBehavior the scale-up and scale-down behavior of hpa
+type AutoscalerBehavior struct {
+	ScaleUp   *AutoscalerScalingRules `json:"scale_up,omitempty"`
+	ScaleDown *AutoscalerScalingRules `json:"scale_down,omitempty"`
+}
+
+// AutoscalerScalingRules the scaling rules of one direction
+type AutoscalerScalingRules struct {
+	StabilizationWindowSeconds *int32 `json:"stabilization_window_seconds,omitempty"`
+	// SelectPolicy is Max, Min or Disabled
+	SelectPolicy string                    `json:"select_policy,omitempty"`
+	Policies     []AutoscalerScalingPolicy `json:"policies,omitempty"`
+}
+
+// AutoscalerScalingPolicy a single scaling policy, Type is Pods or Percent
+type AutoscalerScalingPolicy struct {
+	Type          string `json:"type"`
+	Value         int32  `json:"value"`
+	PeriodSeconds int32  `json:"period_seconds"`
+}
+
+// AutoscalerSchedule sets the min replicas of the rule from the time the cron
+// schedule fires until another schedule of the rule fires.
+type AutoscalerSchedule struct {
+	Name string `json:"name"`
+	// Schedule is a standard cron expression, such as 0 8 * * 1-5
+	Schedule    string `json:"schedule"`
+	Timezone    string `json:"timezone,omitempty"`
+	MinReplicas int    `json:"min_replicas"`
+}
+
+// Parse parses the cron expression of the schedule in its timezone
+func (s AutoscalerSchedule) Parse() (cron.Schedule, error) {
+	spec := s.Schedule
+	if s.Timezone != "" {
+		spec = "CRON_TZ=" + s.Timezone + " " + spec
+	}
+	return cron.ParseStandard(spec)
+}
+
+// AutoscalerDescribedObject the object described by object metrics
+type AutoscalerDescribedObject struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	APIVersion string `json:"api_version"`
+}
+
+// GetBehavior returns the behavior of the rule, nil if it is not set
+func (t *TenantServiceAutoscalerRules) GetBehavior() (*AutoscalerBehavior, error) {
+	if t.Behavior == "" {
+		return nil, nil
+	}
+	var behavior AutoscalerBehavior
+	if err := json.Unmarshal([]byte(t.Behavior), &behavior); err != nil {
+		return nil, err
+	}
+	return &behavior, nil
+}
+
+// GetSchedules returns the min replicas schedules of the rule
+func (t *TenantServiceAutoscalerRules) GetSchedules() ([]AutoscalerSchedule, error) {
+	if t.Schedules == "" {
+		return nil, nil
+	}
+	var schedules []AutoscalerSchedule
+	if err := json.Unmarshal([]byte(t.Schedules), &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// GetSelector returns the label selector of the metric
+func (t *TenantServiceAutoscalerRuleMetrics) GetSelector() (map[string]string, error) {
+	if t.MetricSelector == "" {
+		return nil, nil
+	}
+	var selector map[string]string
+	if err := json.Unmarshal([]byte(t.MetricSelector), &selector); err != nil {
+		return nil, err
+	}
+	return selector, nil
+}
+
+// GetDescribedObject returns the described object of object metrics
+func (t *TenantServiceAutoscalerRuleMetrics) GetDescribedObject() (*AutoscalerDescribedObject, error) {
+	if t.DescribedObject == "" {
+		return nil, nil
+	}
+	var object AutoscalerDescribedObject
+	if err := json.Unmarshal([]byte(t.DescribedObject), &object); err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
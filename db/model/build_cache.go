@@ -0,0 +1,38 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+// BuildCache the source build cache of a component on the builder cache volume
+type BuildCache struct {
+	Model
+	TenantID  string `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	ServiceID string `gorm:"column:service_id;size:32;unique_index" json:"service_id"`
+	// SizeBytes the size of all the cache directories of the component
+	SizeBytes    int64     `gorm:"column:size_bytes" json:"size_bytes"`
+	HitCount     int       `gorm:"column:hit_count" json:"hit_count"`
+	MissCount    int       `gorm:"column:miss_count" json:"miss_count"`
+	LastUsedTime time.Time `gorm:"column:last_used_time" json:"last_used_time"`
+}
+
+// TableName 表名
+func (t *BuildCache) TableName() string {
+	return "region_build_cache"
+}
@@ -123,6 +123,30 @@ type NotificationEvent struct {
 	HandleMessage string    `gorm:"column:handle_message;"`
 	ServiceName   string    `gorm:"column:service_name;size:40"`
 	TenantName    string    `gorm:"column:tenant_name;size:40"`
+	//Source alertmanager for the alerts ingested from alertmanager
+	Source string `gorm:"column:source;size:40"`
+	//State firing or resolved of the alerts
+	State    string `gorm:"column:state;size:20"`
+	Severity string `gorm:"column:severity;size:20"`
+}
+
+// notification event source and alert states
+const (
+	NotificationEventSourceAlertManager = "alertmanager"
+	AlertStateFiring                    = "firing"
+	AlertStateResolved                  = "resolved"
+)
+
+// NotificationEventQuery the filters of the notification events
+type NotificationEventQuery struct {
+	Kind   string
+	KindID string
+	Source string
+	State  string
+	Start  time.Time
+	End    time.Time
+	// WithHandled include the handled events
+	WithHandled bool
 }
 
 // TableName table name
@@ -0,0 +1,69 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// GitWebhook git 推送 webhook 订阅，推送匹配时直接在 region 触发组件源码构建
+type GitWebhook struct {
+	Model
+	TenantID  string `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	ServiceID string `gorm:"column:service_id;size:32;unique_index" json:"service_id"`
+	// Provider github, gitlab, gitea or bitbucket
+	Provider string `gorm:"column:provider;size:16" json:"provider"`
+	Secret   string `gorm:"column:secret;size:255" json:"-"`
+	RepoURL  string `gorm:"column:repo_url;size:1024" json:"repo_url"`
+	// RepoKey 去掉协议、账号与 .git 后缀的仓库地址，用于匹配推送事件
+	RepoKey    string `gorm:"column:repo_key;size:255;index" json:"-"`
+	Branch     string `gorm:"column:branch;size:255" json:"branch"`
+	TagPattern string `gorm:"column:tag_pattern;size:255" json:"tag_pattern"`
+	Enabled    bool   `gorm:"column:enabled;default:true" json:"enabled"`
+	// BuildTemplate 触发构建时使用的构建参数(json)
+	BuildTemplate string `gorm:"column:build_template;type:text" json:"-"`
+}
+
+// TableName 表名
+func (t *GitWebhook) TableName() string {
+	return "region_git_webhook"
+}
+
+// git webhook delivery status
+const (
+	GitWebhookDeliveryTriggered = "triggered"
+	GitWebhookDeliverySkipped   = "skipped"
+	GitWebhookDeliveryFailed    = "failed"
+)
+
+// GitWebhookDelivery 一次 webhook 投递在某个组件上的处理结果，用于投递去重
+type GitWebhookDelivery struct {
+	Model
+	Provider   string `gorm:"column:provider;size:16;unique_index:unique_delivery_service" json:"provider"`
+	DeliveryID string `gorm:"column:delivery_id;size:128;unique_index:unique_delivery_service" json:"delivery_id"`
+	ServiceID  string `gorm:"column:service_id;size:32;unique_index:unique_delivery_service;index:idx_delivery_service_commit" json:"service_id"`
+	TenantID   string `gorm:"column:tenant_id;size:32" json:"tenant_id"`
+	Ref        string `gorm:"column:ref;size:255" json:"ref"`
+	CommitSHA  string `gorm:"column:commit_sha;size:64;index:idx_delivery_service_commit" json:"commit_sha"`
+	// EventID 触发的构建事件
+	EventID string `gorm:"column:event_id;size:32" json:"event_id"`
+	Status  string `gorm:"column:status;size:16" json:"status"`
+	Message string `gorm:"column:message;size:1024" json:"message"`
+}
+
+// TableName 表名
+func (t *GitWebhookDelivery) TableName() string {
+	return "region_git_webhook_delivery"
+}
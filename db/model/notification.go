@@ -0,0 +1,76 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// notification channel types
+const (
+	NotificationChannelWebhook  = "webhook"
+	NotificationChannelEmail    = "email"
+	NotificationChannelSlack    = "slack"
+	NotificationChannelDingTalk = "dingtalk"
+	NotificationChannelFeishu   = "feishu"
+	NotificationChannelWeCom    = "wecom"
+)
+
+// notification severities, from low to high
+const (
+	NotificationSeverityInfo     = "info"
+	NotificationSeverityWarning  = "warning"
+	NotificationSeverityCritical = "critical"
+)
+
+// NotificationChannel 团队的外发通知渠道
+type NotificationChannel struct {
+	Model
+	ChannelID string `gorm:"column:channel_id;size:32;unique_index" json:"channel_id"`
+	TenantID  string `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	Name      string `gorm:"column:name;size:64" json:"name"`
+	// Type webhook, email, slack, dingtalk, feishu or wecom
+	Type string `gorm:"column:type;size:16" json:"type"`
+	// Config 渠道配置(json)，包含地址与凭据
+	Config  string `gorm:"column:config;type:text" json:"-"`
+	Enabled bool   `gorm:"column:enabled" json:"enabled"`
+}
+
+// TableName 表名
+func (t *NotificationChannel) TableName() string {
+	return "region_notification_channel"
+}
+
+// NotificationRule 团队的通知订阅规则，按事件类型、级别与组件匹配事件并发送到渠道
+type NotificationRule struct {
+	Model
+	RuleID    string `gorm:"column:rule_id;size:32;unique_index" json:"rule_id"`
+	TenantID  string `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	ChannelID string `gorm:"column:channel_id;size:32;index" json:"channel_id"`
+	Name      string `gorm:"column:name;size:64" json:"name"`
+	// EventTypes 逗号分隔的事件类型，为空时匹配所有类型
+	EventTypes string `gorm:"column:event_types;size:1024" json:"event_types"`
+	// ServiceIDs 逗号分隔的组件，为空时匹配团队下所有组件
+	ServiceIDs  string `gorm:"column:service_ids;type:text" json:"service_ids"`
+	MinSeverity string `gorm:"column:min_severity;size:16" json:"min_severity"`
+	// ThrottleSeconds 同一组件同一类型事件的最小通知间隔，期间的事件合并计数
+	ThrottleSeconds int  `gorm:"column:throttle_seconds" json:"throttle_seconds"`
+	Enabled         bool `gorm:"column:enabled" json:"enabled"`
+}
+
+// TableName 表名
+func (t *NotificationRule) TableName() string {
+	return "region_notification_rule"
+}
@@ -705,6 +705,10 @@ type TenantServiceAutoscalerRules struct {
 	XPAType     string `gorm:"column:xpa_type;size:3"`
 	MinReplicas int    `gorm:"colume:min_replicas"`
 	MaxReplicas int    `gorm:"colume:max_replicas"`
+	// Behavior is the json of AutoscalerBehavior
+	Behavior string `gorm:"column:behavior;type:text"`
+	// Schedules is the json of []AutoscalerSchedule
+	Schedules string `gorm:"column:schedules;type:text"`
 }
 
 // TableName -
@@ -720,6 +724,13 @@ type TenantServiceAutoscalerRuleMetrics struct {
 	MetricsName       string `gorm:"column:metric_name;not null"`
 	MetricTargetType  string `gorm:"column:metric_target_type;not null"`
 	MetricTargetValue int    `gorm:"column:metric_target_value;not null"`
+	// MetricTargetQuantity is the quantity target of pods, object and external metrics, such as 500m.
+	// MetricTargetValue is used if it is empty.
+	MetricTargetQuantity string `gorm:"column:metric_target_quantity"`
+	// MetricSelector is the json of the label selector of the metric
+	MetricSelector string `gorm:"column:metric_selector;size:1023"`
+	// DescribedObject is the json of the AutoscalerDescribedObject of object metrics
+	DescribedObject string `gorm:"column:described_object;size:1023"`
 }
 
 // TableName -
@@ -0,0 +1,56 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+// TerminalSession a recorded web terminal session, the recording is an asciicast v2 file in the storage
+type TerminalSession struct {
+	Model
+	SessionID     string     `gorm:"column:session_id;size:32;unique_index" json:"session_id"`
+	TenantID      string     `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	ServiceID     string     `gorm:"column:service_id;size:32;index" json:"service_id"`
+	PodName       string     `gorm:"column:pod_name;size:255" json:"pod_name"`
+	ContainerName string     `gorm:"column:container_name;size:255" json:"container_name"`
+	Mode          string     `gorm:"column:mode;size:16" json:"mode"`
+	User          string     `gorm:"column:user;size:64;index" json:"user"`
+	EndTime       *time.Time `gorm:"column:end_time" json:"end_time"`
+	// Size the size of the recording in bytes
+	Size          int64  `gorm:"column:size" json:"size"`
+	RecordingPath string `gorm:"column:recording_path;size:512" json:"-"`
+}
+
+// TableName 表名
+func (t *TerminalSession) TableName() string {
+	return "region_terminal_session"
+}
+
+// TerminalSessionQuery the conditions of the terminal sessions query
+type TerminalSessionQuery struct {
+	TenantID  string
+	ServiceID string
+	User      string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// TerminalRecordingKey the key of the terminal recording switch of the tenant in the key value store
+func TerminalRecordingKey(tenantID string) string {
+	return "/rainbond/terminal_recording/" + tenantID
+}
@@ -53,6 +53,15 @@ var DiscorveryTypeEtcd DiscorveryType = "etcd"
 // DiscorveryTypeKubernetes kubernetes service
 var DiscorveryTypeKubernetes DiscorveryType = "kubernetes"
 
+// DiscorveryTypeConsul consul catalog
+var DiscorveryTypeConsul DiscorveryType = "consul"
+
+// DiscorveryTypeNacos nacos naming
+var DiscorveryTypeNacos DiscorveryType = "nacos"
+
+// DiscorveryTypeDNS dns srv records
+var DiscorveryTypeDNS DiscorveryType = "dns"
+
 func (d DiscorveryType) String() string {
 	return string(d)
 }
@@ -71,6 +80,10 @@ type ThirdPartySvcDiscoveryCfg struct {
 	//for kubernetes service
 	Namespace   string `gorm:"namespace"`
 	ServiceName string `gorm:"serviceName"`
+	//for nacos, the group of the service
+	Group string `gorm:"column:group_name"`
+	//for consul, the datacenter of the service
+	Datacenter string `gorm:"column:datacenter"`
 }
 
 // TableName returns table name of ThirdPartySvcDiscoveryCfg.
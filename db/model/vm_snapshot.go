@@ -0,0 +1,53 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// VMSnapshotSchedule the cron schedule and the retention count of the snapshots of a vm component
+type VMSnapshotSchedule struct {
+	Model
+	ServiceID string `gorm:"column:service_id;size:32;unique_index" json:"service_id"`
+	TenantID  string `gorm:"column:tenant_id;size:32" json:"tenant_id"`
+	Schedule  string `gorm:"column:schedule;size:64" json:"schedule"`
+	Timezone  string `gorm:"column:timezone;size:64" json:"timezone"`
+	//KeepLast the number of scheduled snapshots kept, the older ones are deleted, zero keeps all
+	KeepLast    int        `gorm:"column:keep_last" json:"keep_last"`
+	Enable      bool       `gorm:"column:enable" json:"enable"`
+	LastRunTime *time.Time `gorm:"column:last_run_time" json:"last_run_time,omitempty"`
+}
+
+// TableName 表名
+func (t *VMSnapshotSchedule) TableName() string {
+	return "tenant_service_vm_snapshot_schedule"
+}
+
+// Parse parses the cron expression of the schedule in its timezone
+func (t *VMSnapshotSchedule) Parse() (cron.Schedule, error) {
+	return parseSchedule(t.Schedule, t.Timezone)
+}
+
+// Location returns the timezone of the schedule, local time by default
+func (t *VMSnapshotSchedule) Location() *time.Location {
+	return scheduleLocation(t.Timezone)
+}
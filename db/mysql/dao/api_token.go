@@ -0,0 +1,92 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// APITokenDaoImpl api token store mysql impl
+type APITokenDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (a *APITokenDaoImpl) AddModel(mo model.Interface) error {
+	token, ok := mo.(*model.APIToken)
+	if !ok {
+		return errors.New("Failed to convert interface to APIToken")
+	}
+	var old model.APIToken
+	if ok := a.DB.Where("name = ?", token.Name).Find(&old).RecordNotFound(); ok {
+		return a.DB.Create(token).Error
+	}
+	return fmt.Errorf("api token %s exist", token.Name)
+}
+
+// UpdateModel UpdateModel
+func (a *APITokenDaoImpl) UpdateModel(mo model.Interface) error {
+	token, ok := mo.(*model.APIToken)
+	if !ok {
+		return errors.New("Failed to convert interface to APIToken")
+	}
+	return a.DB.Save(token).Error
+}
+
+// GetByName GetByName
+func (a *APITokenDaoImpl) GetByName(name string) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := a.DB.Where("name = ?", name).Find(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByTokenHash GetByTokenHash
+func (a *APITokenDaoImpl) GetByTokenHash(tokenHash string) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := a.DB.Where("token_hash = ?", tokenHash).Find(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// List lists all the api tokens
+func (a *APITokenDaoImpl) List() ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+	if err := a.DB.Order("create_time desc").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteByName DeleteByName
+func (a *APITokenDaoImpl) DeleteByName(name string) error {
+	return a.DB.Where("name = ?", name).Delete(&model.APIToken{}).Error
+}
+
+// UpdateLastUsedTime UpdateLastUsedTime
+func (a *APITokenDaoImpl) UpdateLastUsedTime(id uint, lastUsedTime time.Time) error {
+	return a.DB.Model(&model.APIToken{}).Where("ID = ?", id).Update("last_used_time", lastUsedTime).Error
+}
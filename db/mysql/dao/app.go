@@ -2,6 +2,7 @@ package dao
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/goodrain/rainbond/db/model"
 	"github.com/jinzhu/gorm"
@@ -145,3 +146,105 @@ func (a *AppBackupDaoImpl) GetDeleteAppBackups() ([]*model.AppBackup, error) {
 	}
 	return apps, nil
 }
+
+//GetLatestSuccessBackup returns the latest successful backup of the group app
+func (a *AppBackupDaoImpl) GetLatestSuccessBackup(groupID string) (*model.AppBackup, error) {
+	var app model.AppBackup
+	if err := a.DB.Where("group_id = ? and status = ? and deleted=?", groupID, "success", false).Order("create_time desc, ID desc").First(&app).Error; err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+//ListByScheduleID lists the backups created by the schedule, newest first
+func (a *AppBackupDaoImpl) ListByScheduleID(scheduleID string) ([]*model.AppBackup, error) {
+	var apps []*model.AppBackup
+	if err := a.DB.Where("schedule_id = ? and deleted=?", scheduleID, false).Order("create_time desc, ID desc").Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+//ListChildBackups lists the incremental backups which are based on the backup
+func (a *AppBackupDaoImpl) ListChildBackups(backupID string) ([]*model.AppBackup, error) {
+	var apps []*model.AppBackup
+	if err := a.DB.Where("parent_backup_id = ? and deleted=?", backupID, false).Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+//AppBackupScheduleDaoImpl group app backup schedule store mysql impl
+type AppBackupScheduleDaoImpl struct {
+	DB *gorm.DB
+}
+
+//AddModel AddModel
+func (a *AppBackupScheduleDaoImpl) AddModel(mo model.Interface) error {
+	schedule, ok := mo.(*model.AppBackupSchedule)
+	if !ok {
+		return errors.New("Failed to convert interface to AppBackupSchedule")
+	}
+	var old model.AppBackupSchedule
+	if ok := a.DB.Where("schedule_id = ?", schedule.ScheduleID).Find(&old).RecordNotFound(); ok {
+		return a.DB.Create(schedule).Error
+	}
+	return fmt.Errorf("backup schedule exist with id %s", schedule.ScheduleID)
+}
+
+//UpdateModel UpdateModel
+func (a *AppBackupScheduleDaoImpl) UpdateModel(mo model.Interface) error {
+	schedule, ok := mo.(*model.AppBackupSchedule)
+	if !ok {
+		return errors.New("Failed to convert interface to AppBackupSchedule")
+	}
+	return a.DB.Save(schedule).Error
+}
+
+//GetBackupSchedule GetBackupSchedule
+func (a *AppBackupScheduleDaoImpl) GetBackupSchedule(scheduleID string) (*model.AppBackupSchedule, error) {
+	var schedule model.AppBackupSchedule
+	if err := a.DB.Where("schedule_id = ?", scheduleID).Find(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+//ListByGroupID ListByGroupID
+func (a *AppBackupScheduleDaoImpl) ListByGroupID(groupID string) ([]*model.AppBackupSchedule, error) {
+	var schedules []*model.AppBackupSchedule
+	if err := a.DB.Where("group_id = ?", groupID).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+//ListEnableOnes ListEnableOnes
+func (a *AppBackupScheduleDaoImpl) ListEnableOnes() ([]*model.AppBackupSchedule, error) {
+	var schedules []*model.AppBackupSchedule
+	if err := a.DB.Where("enable = ?", true).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+//DeleteBackupSchedule DeleteBackupSchedule
+func (a *AppBackupScheduleDaoImpl) DeleteBackupSchedule(scheduleID string) error {
+	return a.DB.Where("schedule_id = ?", scheduleID).Delete(&model.AppBackupSchedule{}).Error
+}
+
+//ClaimRun moves the last run time of the backup schedule to runTime when it still equals
+//lastRunTime, false means another api instance already started this backup run.
+func (a *AppBackupScheduleDaoImpl) ClaimRun(scheduleID string, lastRunTime *time.Time, runTime time.Time) (bool, error) {
+	query := a.DB.Model(&model.AppBackupSchedule{}).Where("schedule_id = ?", scheduleID)
+	if lastRunTime == nil {
+		query = query.Where("last_run_time is null")
+	} else {
+		query = query.Where("last_run_time = ?", *lastRunTime)
+	}
+	res := query.Update("last_run_time", runTime)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
@@ -0,0 +1,108 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// auditLogAppendRetries the times to retry when another instance appends concurrently
+const auditLogAppendRetries = 10
+
+// AuditLogDaoImpl audit log store mysql impl, the records can only be appended
+type AuditLogDaoImpl struct {
+	DB *gorm.DB
+}
+
+// Append chains the record to the last record and saves it. prev_hash is unique,
+// so a concurrent append to the same record fails and is retried on the new last record.
+func (a *AuditLogDaoImpl) Append(log *model.AuditLog) error {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	log.CreatedAt = log.CreatedAt.Truncate(time.Second)
+	var err error
+	for i := 0; i < auditLogAppendRetries; i++ {
+		var last model.AuditLog
+		log.PrevHash = ""
+		if err = a.DB.Order("ID desc").Limit(1).Find(&last).Error; err == nil {
+			log.PrevHash = last.Hash
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		log.ID = 0
+		log.Hash = log.ComputeHash()
+		if err = a.DB.Create(log).Error; err == nil {
+			return nil
+		}
+	}
+	return errors.Wrap(err, "append audit log")
+}
+
+func (a *AuditLogDaoImpl) where(query *model.AuditLogQuery) *gorm.DB {
+	db := a.DB.Model(&model.AuditLog{})
+	if query == nil {
+		return db
+	}
+	if query.TenantID != "" {
+		db = db.Where("tenant_id = ?", query.TenantID)
+	}
+	if query.ServiceID != "" {
+		db = db.Where("service_id = ?", query.ServiceID)
+	}
+	if query.Operator != "" {
+		db = db.Where("operator = ?", query.Operator)
+	}
+	if query.TokenName != "" {
+		db = db.Where("token_name = ?", query.TokenName)
+	}
+	if query.StartTime != nil {
+		db = db.Where("create_time >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("create_time < ?", *query.EndTime)
+	}
+	return db
+}
+
+// List lists the audit logs newest first
+func (a *AuditLogDaoImpl) List(query *model.AuditLogQuery, page, pageSize int) ([]*model.AuditLog, int64, error) {
+	var total int64
+	if err := a.where(query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var logs []*model.AuditLog
+	if err := a.where(query).Order("ID desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// ListAfter lists at most limit audit logs with an id greater than afterID in the append order
+func (a *AuditLogDaoImpl) ListAfter(query *model.AuditLogQuery, afterID uint, limit int) ([]*model.AuditLog, error) {
+	var logs []*model.AuditLog
+	if err := a.where(query).Where("ID > ?", afterID).Order("ID asc").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
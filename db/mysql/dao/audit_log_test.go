@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// capability_id: rainbond.audit-log.hash-chain
+func TestAuditLogDaoAppendChainsRecords(t *testing.T) {
+	database, err := gorm.Open("sqlite3", filepath.Join(t.TempDir(), "audit-log.db"))
+	if err != nil {
+		t.Fatalf("open sqlite db: %v", err)
+	}
+	defer database.Close()
+	database.LogMode(false)
+	if err := database.AutoMigrate(&model.AuditLog{}).Error; err != nil {
+		t.Fatalf("migrate audit log table: %v", err)
+	}
+
+	dao := &AuditLogDaoImpl{DB: database}
+	for _, tenantID := range []string{"tenant-a", "tenant-b", "tenant-a"} {
+		if err := dao.Append(&model.AuditLog{TenantID: tenantID, Method: "POST", Path: "/v2/tenants/" + tenantID + "/services"}); err != nil {
+			t.Fatalf("append audit log: %v", err)
+		}
+	}
+
+	logs, err := dao.ListAfter(nil, 0, 10)
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("expected 3 audit logs, got %d", len(logs))
+	}
+	prevHash := ""
+	for _, log := range logs {
+		if log.PrevHash != prevHash {
+			t.Fatalf("expected audit log %d chained to %q, got %q", log.ID, prevHash, log.PrevHash)
+		}
+		if log.Hash != log.ComputeHash() {
+			t.Fatalf("expected the stored hash of audit log %d to match its content", log.ID)
+		}
+		prevHash = log.Hash
+	}
+
+	// a stale append to an already chained record is rejected by the unique prev_hash
+	stale := &model.AuditLog{PrevHash: logs[0].PrevHash, Hash: "stale"}
+	if err := database.Create(stale).Error; err == nil {
+		t.Fatal("expected a second record chained to the same record to be rejected")
+	}
+
+	tenantLogs, total, err := dao.List(&model.AuditLogQuery{TenantID: "tenant-a"}, 1, 1)
+	if err != nil {
+		t.Fatalf("list tenant audit logs: %v", err)
+	}
+	if total != 2 || len(tenantLogs) != 1 || tenantLogs[0].ID != logs[2].ID {
+		t.Fatalf("expected the newest of 2 tenant audit logs, got total %d %#v", total, tenantLogs)
+	}
+}
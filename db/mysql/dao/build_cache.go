@@ -0,0 +1,71 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// BuildCacheDaoImpl build cache store mysql impl
+type BuildCacheDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (b *BuildCacheDaoImpl) AddModel(mo model.Interface) error {
+	cache, ok := mo.(*model.BuildCache)
+	if !ok {
+		return errors.New("Failed to convert interface to BuildCache")
+	}
+	return b.DB.Create(cache).Error
+}
+
+// UpdateModel UpdateModel
+func (b *BuildCacheDaoImpl) UpdateModel(mo model.Interface) error {
+	cache, ok := mo.(*model.BuildCache)
+	if !ok {
+		return errors.New("Failed to convert interface to BuildCache")
+	}
+	return b.DB.Save(cache).Error
+}
+
+// GetByServiceID GetByServiceID
+func (b *BuildCacheDaoImpl) GetByServiceID(serviceID string) (*model.BuildCache, error) {
+	var cache model.BuildCache
+	if err := b.DB.Where("service_id = ?", serviceID).Find(&cache).Error; err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// ListLeastRecentlyUsed lists the build caches, the least recently used first
+func (b *BuildCacheDaoImpl) ListLeastRecentlyUsed() ([]*model.BuildCache, error) {
+	var caches []*model.BuildCache
+	if err := b.DB.Order("last_used_time asc").Find(&caches).Error; err != nil {
+		return nil, err
+	}
+	return caches, nil
+}
+
+// DeleteByServiceID DeleteByServiceID
+func (b *BuildCacheDaoImpl) DeleteByServiceID(serviceID string) error {
+	return b.DB.Where("service_id = ?", serviceID).Delete(&model.BuildCache{}).Error
+}
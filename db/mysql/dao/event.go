@@ -227,6 +227,36 @@ func (c *EventDaoImpl) GetEventsByTenantID(tenantID string, offset, limit int) (
 	return result, total, nil
 }
 
+// ListEventsForLogSearch lists the latest events of the tenant or the target which started before until
+// and did not end before since
+func (c *EventDaoImpl) ListEventsForLogSearch(tenantID, target, targetID, since, until string, limit int) ([]*model.ServiceEvent, error) {
+	db := c.DB
+	if tenantID != "" {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	if target != "" && targetID != "" {
+		if strings.TrimSpace(target) == "service" {
+			db = db.Where("service_id=? or (target=? and target_id=?)", strings.TrimSpace(targetID), strings.TrimSpace(target), strings.TrimSpace(targetID))
+		} else {
+			db = db.Where("target=? and target_id=?", strings.TrimSpace(target), strings.TrimSpace(targetID))
+		}
+	}
+	if since != "" {
+		db = db.Where("end_time is null or end_time='' or end_time>=?", since)
+	}
+	if until != "" {
+		db = db.Where("start_time<=?", until)
+	}
+	var result []*model.ServiceEvent
+	if err := db.Order("start_time DESC, ID DESC").Limit(limit).Find(&result).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return result, nil
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
 // GetEventsByTenantIDs get my teams all event by tenantIDs
 func (c *EventDaoImpl) GetEventsByTenantIDs(tenantIDs []string, offset, limit int) ([]*model.EventAndBuild, error) {
 	var events []*model.EventAndBuild
@@ -417,6 +447,37 @@ func (c *NotificationEventDaoImpl) GetNotificationEventNotHandle() ([]*model.Not
 	return result, nil
 }
 
+// ListNotificationEvents lists the notification events matching the query, the latest first
+func (c *NotificationEventDaoImpl) ListNotificationEvents(query *model.NotificationEventQuery) ([]*model.NotificationEvent, error) {
+	db := c.DB
+	if query.Kind != "" {
+		db = db.Where("kind = ?", query.Kind)
+	}
+	if query.KindID != "" {
+		db = db.Where("kind_id = ?", query.KindID)
+	}
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+	}
+	if query.State != "" {
+		db = db.Where("state = ?", query.State)
+	}
+	if !query.Start.IsZero() {
+		db = db.Where("last_time > ?", query.Start)
+	}
+	if !query.End.IsZero() {
+		db = db.Where("last_time < ?", query.End)
+	}
+	if !query.WithHandled {
+		db = db.Where("is_handle = ?", false)
+	}
+	var result []*model.NotificationEvent
+	if err := db.Order("last_time DESC").Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // GetNotificationEventByHash GetNotificationEventByHash
 func (c *NotificationEventDaoImpl) GetNotificationEventByHash(hash string) (*model.NotificationEvent, error) {
 	var result model.NotificationEvent
@@ -0,0 +1,122 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// GitWebhookDaoImpl git webhook store mysql impl
+type GitWebhookDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (g *GitWebhookDaoImpl) AddModel(mo model.Interface) error {
+	hook, ok := mo.(*model.GitWebhook)
+	if !ok {
+		return errors.New("Failed to convert interface to GitWebhook")
+	}
+	return g.DB.Create(hook).Error
+}
+
+// UpdateModel UpdateModel
+func (g *GitWebhookDaoImpl) UpdateModel(mo model.Interface) error {
+	hook, ok := mo.(*model.GitWebhook)
+	if !ok {
+		return errors.New("Failed to convert interface to GitWebhook")
+	}
+	return g.DB.Save(hook).Error
+}
+
+// GetByServiceID GetByServiceID
+func (g *GitWebhookDaoImpl) GetByServiceID(serviceID string) (*model.GitWebhook, error) {
+	var hook model.GitWebhook
+	if err := g.DB.Where("service_id = ?", serviceID).Find(&hook).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// ListEnabledByRepo lists the enabled webhooks of the repository
+func (g *GitWebhookDaoImpl) ListEnabledByRepo(provider, repoKey string) ([]*model.GitWebhook, error) {
+	var hooks []*model.GitWebhook
+	if err := g.DB.Where("provider = ? and repo_key = ? and enabled = ?", provider, repoKey, true).Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// DeleteByServiceID DeleteByServiceID
+func (g *GitWebhookDaoImpl) DeleteByServiceID(serviceID string) error {
+	return g.DB.Where("service_id = ?", serviceID).Delete(&model.GitWebhook{}).Error
+}
+
+// GitWebhookDeliveryDaoImpl git webhook delivery store mysql impl
+type GitWebhookDeliveryDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (g *GitWebhookDeliveryDaoImpl) AddModel(mo model.Interface) error {
+	delivery, ok := mo.(*model.GitWebhookDelivery)
+	if !ok {
+		return errors.New("Failed to convert interface to GitWebhookDelivery")
+	}
+	return g.DB.Create(delivery).Error
+}
+
+// UpdateModel UpdateModel
+func (g *GitWebhookDeliveryDaoImpl) UpdateModel(mo model.Interface) error {
+	delivery, ok := mo.(*model.GitWebhookDelivery)
+	if !ok {
+		return errors.New("Failed to convert interface to GitWebhookDelivery")
+	}
+	return g.DB.Save(delivery).Error
+}
+
+// GetDelivery GetDelivery
+func (g *GitWebhookDeliveryDaoImpl) GetDelivery(provider, deliveryID, serviceID string) (*model.GitWebhookDelivery, error) {
+	var delivery model.GitWebhookDelivery
+	if err := g.DB.Where("provider = ? and delivery_id = ? and service_id = ?", provider, deliveryID, serviceID).Find(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetTriggeredByCommit returns the latest delivery that triggered a build of the commit on the component
+func (g *GitWebhookDeliveryDaoImpl) GetTriggeredByCommit(serviceID, commitSHA string) (*model.GitWebhookDelivery, error) {
+	var delivery model.GitWebhookDelivery
+	if err := g.DB.Where("service_id = ? and commit_sha = ? and status = ?", serviceID, commitSHA, model.GitWebhookDeliveryTriggered).
+		Order("ID desc").Limit(1).Find(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListByServiceID lists the latest deliveries of the component
+func (g *GitWebhookDeliveryDaoImpl) ListByServiceID(serviceID string, limit int) ([]*model.GitWebhookDelivery, error) {
+	var deliveries []*model.GitWebhookDelivery
+	if err := g.DB.Where("service_id = ?", serviceID).Order("ID desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,131 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// NotificationChannelDaoImpl notification channel store mysql impl
+type NotificationChannelDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (n *NotificationChannelDaoImpl) AddModel(mo model.Interface) error {
+	channel, ok := mo.(*model.NotificationChannel)
+	if !ok {
+		return errors.New("Failed to convert interface to NotificationChannel")
+	}
+	return n.DB.Create(channel).Error
+}
+
+// UpdateModel UpdateModel
+func (n *NotificationChannelDaoImpl) UpdateModel(mo model.Interface) error {
+	channel, ok := mo.(*model.NotificationChannel)
+	if !ok {
+		return errors.New("Failed to convert interface to NotificationChannel")
+	}
+	return n.DB.Save(channel).Error
+}
+
+// GetByChannelID GetByChannelID
+func (n *NotificationChannelDaoImpl) GetByChannelID(channelID string) (*model.NotificationChannel, error) {
+	var channel model.NotificationChannel
+	if err := n.DB.Where("channel_id = ?", channelID).Find(&channel).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// ListByTenantID ListByTenantID
+func (n *NotificationChannelDaoImpl) ListByTenantID(tenantID string) ([]*model.NotificationChannel, error) {
+	var channels []*model.NotificationChannel
+	if err := n.DB.Where("tenant_id = ?", tenantID).Order("ID").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// DeleteByChannelID DeleteByChannelID
+func (n *NotificationChannelDaoImpl) DeleteByChannelID(channelID string) error {
+	return n.DB.Where("channel_id = ?", channelID).Delete(&model.NotificationChannel{}).Error
+}
+
+// NotificationRuleDaoImpl notification rule store mysql impl
+type NotificationRuleDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (n *NotificationRuleDaoImpl) AddModel(mo model.Interface) error {
+	rule, ok := mo.(*model.NotificationRule)
+	if !ok {
+		return errors.New("Failed to convert interface to NotificationRule")
+	}
+	return n.DB.Create(rule).Error
+}
+
+// UpdateModel UpdateModel
+func (n *NotificationRuleDaoImpl) UpdateModel(mo model.Interface) error {
+	rule, ok := mo.(*model.NotificationRule)
+	if !ok {
+		return errors.New("Failed to convert interface to NotificationRule")
+	}
+	return n.DB.Save(rule).Error
+}
+
+// GetByRuleID GetByRuleID
+func (n *NotificationRuleDaoImpl) GetByRuleID(ruleID string) (*model.NotificationRule, error) {
+	var rule model.NotificationRule
+	if err := n.DB.Where("rule_id = ?", ruleID).Find(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListByTenantID ListByTenantID
+func (n *NotificationRuleDaoImpl) ListByTenantID(tenantID string) ([]*model.NotificationRule, error) {
+	var rules []*model.NotificationRule
+	if err := n.DB.Where("tenant_id = ?", tenantID).Order("ID").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListEnabledByTenantID ListEnabledByTenantID
+func (n *NotificationRuleDaoImpl) ListEnabledByTenantID(tenantID string) ([]*model.NotificationRule, error) {
+	var rules []*model.NotificationRule
+	if err := n.DB.Where("tenant_id = ? and enabled = ?", tenantID, true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeleteByRuleID DeleteByRuleID
+func (n *NotificationRuleDaoImpl) DeleteByRuleID(ruleID string) error {
+	return n.DB.Where("rule_id = ?", ruleID).Delete(&model.NotificationRule{}).Error
+}
+
+// DeleteByChannelID DeleteByChannelID
+func (n *NotificationRuleDaoImpl) DeleteByChannelID(channelID string) error {
+	return n.DB.Where("channel_id = ?", channelID).Delete(&model.NotificationRule{}).Error
+}
@@ -74,6 +74,15 @@ func (t *TenantDaoImpl) GetTenantByUUID(uuid string) (*model.Tenants, error) {
 	return &tenant, nil
 }
 
+// GetTenantByNamespace 根据命名空间获取租户
+func (t *TenantDaoImpl) GetTenantByNamespace(namespace string) (*model.Tenants, error) {
+	var tenant model.Tenants
+	if err := t.DB.Where("namespace = ?", namespace).Find(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
 // GetTenantByUUIDIsExist 获取租户
 func (t *TenantDaoImpl) GetTenantByUUIDIsExist(uuid string) bool {
 	var tenant model.Tenants
@@ -2105,6 +2114,15 @@ func (t *TenantServceAutoscalerRulesDaoImpl) ListEnableOnesByServiceID(serviceID
 	return rules, nil
 }
 
+// ListEnableScheduledOnes lists the enabled rules which have min replicas schedules
+func (t *TenantServceAutoscalerRulesDaoImpl) ListEnableScheduledOnes() ([]*model.TenantServiceAutoscalerRules, error) {
+	var rules []*model.TenantServiceAutoscalerRules
+	if err := t.DB.Where("enable=? and schedules is not null and schedules != ''", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
 // ListByComponentIDs -
 func (t *TenantServceAutoscalerRulesDaoImpl) ListByComponentIDs(componentIDs []string) ([]*model.TenantServiceAutoscalerRules, error) {
 	var rules []*model.TenantServiceAutoscalerRules
@@ -2340,6 +2358,15 @@ func (t *ComponentK8sAttributeDaoImpl) GetByComponentIDAndName(componentID, name
 	return &record, nil
 }
 
+// ListByComponentID lists the attributes of the component
+func (t *ComponentK8sAttributeDaoImpl) ListByComponentID(componentID string) ([]*model.ComponentK8sAttributes, error) {
+	var records []*model.ComponentK8sAttributes
+	if err := t.DB.Where("component_id=?", componentID).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
 // CreateOrUpdateAttributesInBatch Batch insert or update component attributes
 func (t *ComponentK8sAttributeDaoImpl) CreateOrUpdateAttributesInBatch(attributes []*model.ComponentK8sAttributes) error {
 	dbType := t.DB.Dialect().GetName()
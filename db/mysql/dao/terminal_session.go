@@ -0,0 +1,86 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// TerminalSessionDaoImpl terminal session store mysql impl
+type TerminalSessionDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (t *TerminalSessionDaoImpl) AddModel(mo model.Interface) error {
+	session, ok := mo.(*model.TerminalSession)
+	if !ok {
+		return errors.New("Failed to convert interface to TerminalSession")
+	}
+	return t.DB.Create(session).Error
+}
+
+// UpdateModel UpdateModel
+func (t *TerminalSessionDaoImpl) UpdateModel(mo model.Interface) error {
+	session, ok := mo.(*model.TerminalSession)
+	if !ok {
+		return errors.New("Failed to convert interface to TerminalSession")
+	}
+	return t.DB.Save(session).Error
+}
+
+// GetBySessionID GetBySessionID
+func (t *TerminalSessionDaoImpl) GetBySessionID(sessionID string) (*model.TerminalSession, error) {
+	var session model.TerminalSession
+	if err := t.DB.Where("session_id = ?", sessionID).Find(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// List lists the terminal sessions newest first
+func (t *TerminalSessionDaoImpl) List(query *model.TerminalSessionQuery, page, pageSize int) ([]*model.TerminalSession, int64, error) {
+	db := t.DB.Model(&model.TerminalSession{})
+	if query.TenantID != "" {
+		db = db.Where("tenant_id = ?", query.TenantID)
+	}
+	if query.ServiceID != "" {
+		db = db.Where("service_id = ?", query.ServiceID)
+	}
+	if query.User != "" {
+		db = db.Where("user = ?", query.User)
+	}
+	if query.StartTime != nil {
+		db = db.Where("create_time >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("create_time < ?", *query.EndTime)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var sessions []*model.TerminalSession
+	if err := db.Order("create_time desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
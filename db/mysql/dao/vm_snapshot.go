@@ -0,0 +1,94 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// VMSnapshotScheduleDaoImpl vm component snapshot schedule store mysql impl
+type VMSnapshotScheduleDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel AddModel
+func (a *VMSnapshotScheduleDaoImpl) AddModel(mo model.Interface) error {
+	schedule, ok := mo.(*model.VMSnapshotSchedule)
+	if !ok {
+		return errors.New("Failed to convert interface to VMSnapshotSchedule")
+	}
+	var old model.VMSnapshotSchedule
+	if ok := a.DB.Where("service_id = ?", schedule.ServiceID).Find(&old).RecordNotFound(); ok {
+		return a.DB.Create(schedule).Error
+	}
+	return fmt.Errorf("vm snapshot schedule exist with service id %s", schedule.ServiceID)
+}
+
+// UpdateModel UpdateModel
+func (a *VMSnapshotScheduleDaoImpl) UpdateModel(mo model.Interface) error {
+	schedule, ok := mo.(*model.VMSnapshotSchedule)
+	if !ok {
+		return errors.New("Failed to convert interface to VMSnapshotSchedule")
+	}
+	return a.DB.Save(schedule).Error
+}
+
+// GetByServiceID GetByServiceID
+func (a *VMSnapshotScheduleDaoImpl) GetByServiceID(serviceID string) (*model.VMSnapshotSchedule, error) {
+	var schedule model.VMSnapshotSchedule
+	if err := a.DB.Where("service_id = ?", serviceID).Find(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListEnableOnes ListEnableOnes
+func (a *VMSnapshotScheduleDaoImpl) ListEnableOnes() ([]*model.VMSnapshotSchedule, error) {
+	var schedules []*model.VMSnapshotSchedule
+	if err := a.DB.Where("enable = ?", true).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// DeleteByServiceID DeleteByServiceID
+func (a *VMSnapshotScheduleDaoImpl) DeleteByServiceID(serviceID string) error {
+	return a.DB.Where("service_id = ?", serviceID).Delete(&model.VMSnapshotSchedule{}).Error
+}
+
+// ClaimRun marks the scheduled snapshot of the vm component as taken at runTime. The
+// update is conditioned on lastRunTime, a false result means the snapshot was taken elsewhere.
+func (a *VMSnapshotScheduleDaoImpl) ClaimRun(serviceID string, lastRunTime *time.Time, runTime time.Time) (bool, error) {
+	query := a.DB.Model(&model.VMSnapshotSchedule{}).Where("service_id = ?", serviceID)
+	if lastRunTime == nil {
+		query = query.Where("last_run_time is null")
+	} else {
+		query = query.Where("last_run_time = ?", *lastRunTime)
+	}
+	res := query.Update("last_run_time", runTime)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
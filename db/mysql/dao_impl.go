@@ -458,6 +458,76 @@ func (m *Manager) AppBackupDaoTransactions(db *gorm.DB) dao.AppBackupDao {
 	}
 }
 
+// AppBackupScheduleDao group app backup schedule
+func (m *Manager) AppBackupScheduleDao() dao.AppBackupScheduleDao {
+	return &mysqldao.AppBackupScheduleDaoImpl{
+		DB: m.db,
+	}
+}
+
+// VMSnapshotScheduleDao vm component snapshot schedule
+func (m *Manager) VMSnapshotScheduleDao() dao.VMSnapshotScheduleDao {
+	return &mysqldao.VMSnapshotScheduleDaoImpl{
+		DB: m.db,
+	}
+}
+
+// APITokenDao scoped api token
+func (m *Manager) APITokenDao() dao.APITokenDao {
+	return &mysqldao.APITokenDaoImpl{
+		DB: m.db,
+	}
+}
+
+// AuditLogDao audit log
+func (m *Manager) AuditLogDao() dao.AuditLogDao {
+	return &mysqldao.AuditLogDaoImpl{
+		DB: m.db,
+	}
+}
+
+// TerminalSessionDao terminal session
+func (m *Manager) TerminalSessionDao() dao.TerminalSessionDao {
+	return &mysqldao.TerminalSessionDaoImpl{
+		DB: m.db,
+	}
+}
+
+// BuildCacheDao build cache
+func (m *Manager) BuildCacheDao() dao.BuildCacheDao {
+	return &mysqldao.BuildCacheDaoImpl{
+		DB: m.db,
+	}
+}
+
+// GitWebhookDao git webhook
+func (m *Manager) GitWebhookDao() dao.GitWebhookDao {
+	return &mysqldao.GitWebhookDaoImpl{
+		DB: m.db,
+	}
+}
+
+// GitWebhookDeliveryDao git webhook delivery
+func (m *Manager) GitWebhookDeliveryDao() dao.GitWebhookDeliveryDao {
+	return &mysqldao.GitWebhookDeliveryDaoImpl{
+		DB: m.db,
+	}
+}
+
+// NotificationChannelDao notification channel
+func (m *Manager) NotificationChannelDao() dao.NotificationChannelDao {
+	return &mysqldao.NotificationChannelDaoImpl{
+		DB: m.db,
+	}
+}
+
+// NotificationRuleDao notification rule
+func (m *Manager) NotificationRuleDao() dao.NotificationRuleDao {
+	return &mysqldao.NotificationRuleDaoImpl{
+		DB: m.db,
+	}
+}
+
 // ServiceSourceDao service source db impl
 func (m *Manager) ServiceSourceDao() dao.ServiceSourceDao {
 	return &mysqldao.ServiceSourceImpl{
@@ -210,6 +210,16 @@ func (m *Manager) RegisterTableModel() {
 	m.models = append(m.models, &model.NotificationEvent{})
 	m.models = append(m.models, &model.AppStatus{})
 	m.models = append(m.models, &model.AppBackup{})
+	m.models = append(m.models, &model.AppBackupSchedule{})
+	m.models = append(m.models, &model.VMSnapshotSchedule{})
+	m.models = append(m.models, &model.APIToken{})
+	m.models = append(m.models, &model.AuditLog{})
+	m.models = append(m.models, &model.TerminalSession{})
+	m.models = append(m.models, &model.BuildCache{})
+	m.models = append(m.models, &model.GitWebhook{})
+	m.models = append(m.models, &model.GitWebhookDelivery{})
+	m.models = append(m.models, &model.NotificationChannel{})
+	m.models = append(m.models, &model.NotificationRule{})
 	m.models = append(m.models, &model.UploadSession{})
 	m.models = append(m.models, &model.ServiceSourceConfig{})
 	m.models = append(m.models, &model.Application{})
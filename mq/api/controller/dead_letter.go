@@ -0,0 +1,244 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"strings"
+
+	"github.com/goodrain/rainbond/mq/api/grpc/pb"
+	"github.com/goodrain/rainbond/mq/api/mq"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+
+	restful "github.com/emicklei/go-restful"
+)
+
+// RegisterDeadLetter 注册死信队列接口，接口可以重放和清除任务，必须配置 token 才会开启
+func RegisterDeadLetter(container *restful.Container, mq mq.ActionMQ, token string) {
+	if token == "" {
+		logrus.Warning("the mq admin token is not set, the dead-letter queue api is disabled")
+		return
+	}
+	DeadLetterSource{mq: mq, token: token}.Register(container)
+}
+
+// DeadLetterSource 死信队列接口
+type DeadLetterSource struct {
+	mq    mq.ActionMQ
+	token string
+}
+
+// DeadLetterMessage dead-lettered message with the decoded task
+type DeadLetterMessage struct {
+	mq.DeadLetter
+	TaskID   string `json:"task_id,omitempty"`
+	TaskType string `json:"task_type,omitempty"`
+	TaskBody string `json:"task_body,omitempty"`
+}
+
+// PurgeRequest purge dead-lettered messages
+type PurgeRequest struct {
+	MessageIDs []string `json:"message_ids"`
+}
+
+// Register 注册
+func (d DeadLetterSource) Register(container *restful.Container) {
+	ws := new(restful.WebService)
+	ws.Path("/dlq").
+		Doc("dead-letter queue interface").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON).
+		Filter(d.authenticate)
+
+	ws.Route(ws.GET("/{topic}").To(d.list).
+		Doc("list the dead-lettered messages of the topic").
+		Operation("listDeadLetters").
+		Param(ws.PathParameter("topic", "queue topic name").DataType("string")).
+		Writes(ResponseType{}))
+	ws.Route(ws.GET("/{topic}/stats").To(d.stats).
+		Doc("get the delivery counters of the topic").
+		Operation("topicStats").
+		Param(ws.PathParameter("topic", "queue topic name").DataType("string")).
+		Writes(ResponseType{}))
+	ws.Route(ws.GET("/{topic}/{message_id}").To(d.get).
+		Doc("inspect a dead-lettered message").
+		Operation("getDeadLetter").
+		Param(ws.PathParameter("topic", "queue topic name").DataType("string")).
+		Param(ws.PathParameter("message_id", "message id").DataType("string")).
+		Writes(ResponseType{}))
+	ws.Route(ws.POST("/{topic}/{message_id}/requeue").To(d.requeue).
+		Doc("move a dead-lettered message back to the topic").
+		Operation("requeueDeadLetter").
+		Param(ws.PathParameter("topic", "queue topic name").DataType("string")).
+		Param(ws.PathParameter("message_id", "message id").DataType("string")).
+		Writes(ResponseType{}))
+	ws.Route(ws.DELETE("/{topic}/{message_id}").To(d.purgeOne).
+		Doc("purge a dead-lettered message").
+		Operation("purgeDeadLetter").
+		Param(ws.PathParameter("topic", "queue topic name").DataType("string")).
+		Param(ws.PathParameter("message_id", "message id").DataType("string")).
+		Writes(ResponseType{}))
+	ws.Route(ws.DELETE("/{topic}").To(d.purge).
+		Doc("purge the dead-lettered messages of the topic, all of them when no message id is given").
+		Operation("purgeDeadLetters").
+		Param(ws.PathParameter("topic", "queue topic name").DataType("string")).
+		Reads(PurgeRequest{}).
+		Writes(ResponseType{}))
+	container.Add(ws)
+}
+
+// authenticate 校验请求携带的 Bearer token
+func (d DeadLetterSource) authenticate(request *restful.Request, response *restful.Response, chain *restful.FilterChain) {
+	auth := strings.SplitN(request.HeaderParameter("Authorization"), " ", 2)
+	if len(auth) != 2 || !strings.EqualFold(auth[0], "Bearer") ||
+		subtle.ConstantTimeCompare([]byte(auth[1]), []byte(d.token)) != 1 {
+		NewFaliResponse(401, "unauthorized", "未授权", response)
+		return
+	}
+	chain.ProcessFilter(request, response)
+}
+
+func newDeadLetterMessage(letter mq.DeadLetter) DeadLetterMessage {
+	message := DeadLetterMessage{DeadLetter: letter}
+	var task pb.TaskMessage
+	if err := proto.Unmarshal([]byte(letter.Body), &task); err == nil && task.TaskType != "" {
+		message.TaskID = task.TaskId
+		message.TaskType = task.TaskType
+		message.TaskBody = string(task.TaskBody)
+		return message
+	}
+	message.TaskBody = letter.Body
+	return message
+}
+
+func (d *DeadLetterSource) checkTopic(topic string, response *restful.Response) bool {
+	if topic == "" || mq.IsDeadLetterTopic(topic) || !d.mq.TopicIsExist(topic) {
+		NewFaliResponse(400, "topic can not be empty or topic is not define", "主题不能为空或者当前主题未注册", response)
+		return false
+	}
+	return true
+}
+
+func (d *DeadLetterSource) list(request *restful.Request, response *restful.Response) {
+	topic := request.PathParameter("topic")
+	if !d.checkTopic(topic, response) {
+		return
+	}
+	letters, err := d.mq.ListDeadLetters(topic)
+	if err != nil {
+		NewFaliResponse(500, "list dead letters error."+err.Error(), "查询死信消息错误", response)
+		return
+	}
+	var list []interface{}
+	for _, letter := range letters {
+		list = append(list, newDeadLetterMessage(letter))
+	}
+	NewSuccessResponse(nil, list, response)
+}
+
+func (d *DeadLetterSource) stats(request *restful.Request, response *restful.Response) {
+	topic := request.PathParameter("topic")
+	if !d.checkTopic(topic, response) {
+		return
+	}
+	NewSuccessResponse(map[string]interface{}{
+		"topic":       d.mq.TopicStats(topic),
+		"dead_letter": d.mq.TopicStats(mq.DeadLetterTopic(topic)),
+	}, nil, response)
+}
+
+func (d *DeadLetterSource) get(request *restful.Request, response *restful.Response) {
+	topic := request.PathParameter("topic")
+	if !d.checkTopic(topic, response) {
+		return
+	}
+	letter, err := d.mq.GetDeadLetter(topic, request.PathParameter("message_id"))
+	if err != nil {
+		if err == mq.ErrMessageNotFound {
+			NewFaliResponse(404, "dead letter not found", "死信消息不存在", response)
+			return
+		}
+		NewFaliResponse(500, "get dead letter error."+err.Error(), "查询死信消息错误", response)
+		return
+	}
+	NewSuccessResponse(newDeadLetterMessage(*letter), nil, response)
+}
+
+func (d *DeadLetterSource) requeue(request *restful.Request, response *restful.Response) {
+	topic := request.PathParameter("topic")
+	if !d.checkTopic(topic, response) {
+		return
+	}
+	messageID := request.PathParameter("message_id")
+	ctx, cancel := context.WithCancel(request.Request.Context())
+	defer cancel()
+	if err := d.mq.RequeueDeadLetter(ctx, topic, messageID); err != nil {
+		if err == mq.ErrMessageNotFound {
+			NewFaliResponse(404, "dead letter not found", "死信消息不存在", response)
+			return
+		}
+		NewFaliResponse(500, "requeue dead letter error."+err.Error(), "死信消息重新入队列错误", response)
+		return
+	}
+	logrus.Infof("dead-lettered message %s is requeued to topic %s", messageID, topic)
+	NewSuccessResponse(nil, nil, response)
+}
+
+func (d *DeadLetterSource) purgeOne(request *restful.Request, response *restful.Response) {
+	topic := request.PathParameter("topic")
+	if !d.checkTopic(topic, response) {
+		return
+	}
+	messageID := request.PathParameter("message_id")
+	count, err := d.mq.PurgeDeadLetters(topic, []string{messageID})
+	if err != nil {
+		NewFaliResponse(500, "purge dead letter error."+err.Error(), "清除死信消息错误", response)
+		return
+	}
+	if count == 0 {
+		NewFaliResponse(404, "dead letter not found", "死信消息不存在", response)
+		return
+	}
+	NewSuccessResponse(map[string]int{"purged": count}, nil, response)
+}
+
+func (d *DeadLetterSource) purge(request *restful.Request, response *restful.Response) {
+	topic := request.PathParameter("topic")
+	if !d.checkTopic(topic, response) {
+		return
+	}
+	var req PurgeRequest
+	if request.Request.ContentLength > 0 {
+		if err := json.NewDecoder(request.Request.Body).Decode(&req); err != nil {
+			NewFaliResponse(400, "request body error."+err.Error(), "读取数据错误，数据不合法", response)
+			return
+		}
+	}
+	count, err := d.mq.PurgeDeadLetters(topic, req.MessageIDs)
+	if err != nil {
+		NewFaliResponse(500, "purge dead letters error."+err.Error(), "清除死信消息错误", response)
+		return
+	}
+	logrus.Infof("purge %d dead-lettered messages of topic %s", count, topic)
+	NewSuccessResponse(map[string]int{"purged": count}, nil, response)
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restful "github.com/emicklei/go-restful"
+	"github.com/goodrain/rainbond/mq/api/mq"
+)
+
+type deadLetterTestMQ struct {
+	mq.ActionMQ
+}
+
+func (deadLetterTestMQ) TopicIsExist(topic string) bool {
+	return topic == "builder"
+}
+
+func (deadLetterTestMQ) TopicStats(topic string) mq.TopicStats {
+	return mq.TopicStats{}
+}
+
+// capability_id: rainbond.mq.dead-letter-api-auth
+func TestDeadLetterAPIRequiresToken(t *testing.T) {
+	serve := func(container *restful.Container, auth string) int {
+		req := httptest.NewRequest(http.MethodGet, "/dlq/builder/stats", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		recorder := httptest.NewRecorder()
+		container.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	disabled := restful.NewContainer()
+	RegisterDeadLetter(disabled, deadLetterTestMQ{}, "")
+	if code := serve(disabled, "Bearer "); code != http.StatusNotFound {
+		t.Fatalf("expected the api to be disabled without token, got %d", code)
+	}
+
+	container := restful.NewContainer()
+	RegisterDeadLetter(container, deadLetterTestMQ{}, "secret")
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		if code := serve(container, auth); code != http.StatusUnauthorized {
+			t.Fatalf("expected %q to be unauthorized, got %d", auth, code)
+		}
+	}
+	if code := serve(container, "Bearer secret"); code != http.StatusOK {
+		t.Fatalf("expected the token to be accepted, got %d", code)
+	}
+}
@@ -20,6 +20,7 @@ package server
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/goodrain/rainbond/util"
 
@@ -73,6 +74,9 @@ func (s *mqServer) Dequeue(ctx context.Context, in *pb.DequeueRequest) (*pb.Task
 	}
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	if in.ManualAck {
+		return s.receive(ctx, in)
+	}
 	message, err := s.actionMQ.Dequeue(ctx, in.Topic)
 	if err != nil {
 		return nil, err
@@ -86,6 +90,53 @@ func (s *mqServer) Dequeue(ctx context.Context, in *pb.DequeueRequest) (*pb.Task
 	return &task, nil
 }
 
+// receive lease a message, the consumer must ack or nack it with the returned receipt
+func (s *mqServer) receive(ctx context.Context, in *pb.DequeueRequest) (*pb.TaskMessage, error) {
+	delivery, err := s.actionMQ.Receive(ctx, in.Topic, time.Duration(in.VisibilityTimeout)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	var task pb.TaskMessage
+	if err := proto.Unmarshal([]byte(delivery.Body), &task); err != nil {
+		// the message can never be consumed, move it to the dead-letter topic instead of redelivering forever
+		logrus.Errorf("unmarshal message of topic %s failure %s, dead-letter it", in.Topic, err.Error())
+		reason := "unmarshal task message failure: " + err.Error()
+		if nackErr := s.actionMQ.Nack(ctx, in.Topic, delivery.Receipt, 0, reason, true); nackErr != nil {
+			logrus.Errorf("dead-letter broken message failure %s", nackErr.Error())
+		}
+		return nil, err
+	}
+	task.Receipt = delivery.Receipt
+	task.DeliveryCount = int32(delivery.Deliveries)
+	logrus.Debugf("task (%s) leased by (%s), delivery count %d.", task.GetTaskType(), in.ClientHost, delivery.Deliveries)
+	return &task, nil
+}
+
+func (s *mqServer) Ack(ctx context.Context, in *pb.AckRequest) (*pb.TaskReply, error) {
+	if in.Topic == "" || !s.actionMQ.TopicIsExist(in.Topic) {
+		return nil, fmt.Errorf("topic %s is not support", in.Topic)
+	}
+	if err := s.actionMQ.Ack(ctx, in.Topic, in.Receipt); err != nil {
+		return nil, err
+	}
+	return &pb.TaskReply{
+		Status: "success",
+	}, nil
+}
+
+func (s *mqServer) Nack(ctx context.Context, in *pb.NackRequest) (*pb.TaskReply, error) {
+	if in.Topic == "" || !s.actionMQ.TopicIsExist(in.Topic) {
+		return nil, fmt.Errorf("topic %s is not support", in.Topic)
+	}
+	err := s.actionMQ.Nack(ctx, in.Topic, in.Receipt, time.Duration(in.RequeueDelay)*time.Second, in.Reason, in.DeadLetter)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TaskReply{
+		Status: "success",
+	}, nil
+}
+
 //RegisterServer 注册服务
 func RegisterServer(server *grpc1.Server, actionMQ mq.ActionMQ) {
 	pb.RegisterTaskQueueServer(server, &mqServer{actionMQ})
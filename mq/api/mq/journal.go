@@ -0,0 +1,218 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// journal record operations
+const (
+	OpEnqueue = "enqueue"
+	OpLease   = "lease"
+	OpNack    = "nack"
+	OpAck     = "ack"
+)
+
+// maxRecordSize the max size of one journal line
+const maxRecordSize = 64 * 1024 * 1024
+
+// Record a journal entry of a topic queue
+type Record struct {
+	Op         string `json:"op"`
+	ID         string `json:"id"`
+	Body       []byte `json:"body,omitempty"`
+	Deliveries int    `json:"deliveries,omitempty"`
+	Failures   int    `json:"failures,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Time       int64  `json:"time"`
+}
+
+// Journal persists the operations applied to a topic queue, so that the
+// queue can be rebuilt after rbd-mq restarts
+type Journal interface {
+	// Append writes records of the topic, records must be durable when it returns
+	Append(topic string, records ...Record) error
+	// Replay calls fn with every record of the topic in write order
+	Replay(topic string, fn func(Record)) error
+	// Compact replaces all records of the topic with the given live records
+	Compact(topic string, records []Record) error
+	Close() error
+}
+
+// NewJournal create the journal of the storage mode
+func NewJournal(mode, dataDir string, syncWrite bool) (Journal, error) {
+	switch mode {
+	case "", "memory":
+		return &memoryJournal{}, nil
+	case "wal":
+		return newFileJournal(dataDir, syncWrite)
+	default:
+		return nil, fmt.Errorf("storage mode %s is not support", mode)
+	}
+}
+
+// memoryJournal keeps nothing, messages are lost when rbd-mq restarts
+type memoryJournal struct{}
+
+func (m *memoryJournal) Append(topic string, records ...Record) error { return nil }
+func (m *memoryJournal) Replay(topic string, fn func(Record)) error   { return nil }
+func (m *memoryJournal) Compact(topic string, records []Record) error { return nil }
+func (m *memoryJournal) Close() error                                 { return nil }
+
+// fileJournal is a write-ahead log on local disk, one json lines file per topic
+type fileJournal struct {
+	dataDir   string
+	syncWrite bool
+	files     map[string]*os.File
+	lock      sync.Mutex
+}
+
+func newFileJournal(dataDir string, syncWrite bool) (*fileJournal, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("create mq data dir %s failure %s", dataDir, err.Error())
+	}
+	return &fileJournal{
+		dataDir:   dataDir,
+		syncWrite: syncWrite,
+		files:     make(map[string]*os.File),
+	}, nil
+}
+
+func (f *fileJournal) topicFile(topic string) string {
+	return path.Join(f.dataDir, url.PathEscape(topic)+".wal")
+}
+
+func (f *fileJournal) open(topic string) (*os.File, error) {
+	if file, ok := f.files[topic]; ok {
+		return file, nil
+	}
+	file, err := os.OpenFile(f.topicFile(topic), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.files[topic] = file
+	return file, nil
+}
+
+func encodeRecords(records []Record) ([]byte, error) {
+	var buf []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
+
+func (f *fileJournal) Append(topic string, records ...Record) error {
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	file, err := f.open(topic)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf); err != nil {
+		return err
+	}
+	if f.syncWrite {
+		return file.Sync()
+	}
+	return nil
+}
+
+func (f *fileJournal) Replay(topic string, fn func(Record)) error {
+	file, err := os.Open(f.topicFile(topic))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// the last line may be partially written when rbd-mq crashed
+			logrus.Warningf("skip broken record of topic %s journal: %s", topic, err.Error())
+			continue
+		}
+		fn(r)
+	}
+	return scanner.Err()
+}
+
+func (f *fileJournal) Compact(topic string, records []Record) error {
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	tmpFile := f.topicFile(topic) + ".tmp"
+	tmp, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if file, ok := f.files[topic]; ok {
+		file.Close()
+		delete(f.files, topic)
+	}
+	return os.Rename(tmpFile, f.topicFile(topic))
+}
+
+func (f *fileJournal) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for topic, file := range f.files {
+		if err := file.Close(); err != nil {
+			logrus.Errorf("close topic %s journal failure %s", topic, err.Error())
+		}
+		delete(f.files, topic)
+	}
+	return nil
+}
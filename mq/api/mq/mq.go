@@ -19,12 +19,15 @@
 package mq
 
 import (
-	"github.com/goodrain/rainbond/config/configs"
-	"github.com/goodrain/rainbond/config/configs/rbdcomponent"
-	"github.com/goodrain/rainbond/mq/client"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/goodrain/rainbond/config/configs"
+	"github.com/goodrain/rainbond/config/configs/rbdcomponent"
+	"github.com/goodrain/rainbond/mq/client"
 
 	"golang.org/x/net/context"
 
@@ -34,12 +37,26 @@ import (
 // ActionMQ 队列操作
 type ActionMQ interface {
 	Enqueue(context.Context, string, string) error
+	// Dequeue remove the first message of the topic, the message is acked at once
 	Dequeue(context.Context, string) (string, error)
+	// Receive lease the first message of the topic, it is redelivered if not acked before visibility timeout
+	Receive(ctx context.Context, topic string, visibilityTimeout time.Duration) (*Delivery, error)
+	Ack(ctx context.Context, topic, receipt string) error
+	// Nack give up the lease, the message is visible again after delay. A non-empty reason counts a
+	// failed delivery, the message is moved to the dead-letter topic once it failed too many times
+	Nack(ctx context.Context, topic, receipt string, delay time.Duration, reason string, deadLetter bool) error
 	TopicIsExist(string) bool
 	GetAllTopics() []string
 	Start() error
 	Stop() error
 	MessageQueueSize(topic string) int64
+	TopicStats(topic string) TopicStats
+	ListDeadLetters(topic string) ([]DeadLetter, error)
+	GetDeadLetter(topic, messageID string) (*DeadLetter, error)
+	// RequeueDeadLetter move the dead-lettered message back to its topic with the failures reset
+	RequeueDeadLetter(ctx context.Context, topic, messageID string) error
+	// PurgeDeadLetters remove the dead-lettered messages, all of them when messageIDs is empty
+	PurgeDeadLetters(topic string, messageIDs []string) (int, error)
 }
 
 // EnqueueNumber enqueue number
@@ -48,59 +65,103 @@ var EnqueueNumber float64 = 0
 // DequeueNumber dequeue number
 var DequeueNumber float64 = 0
 
-// NewActionMQ new etcd mq
+// dequeueWaitTime the max time a dequeue request waits for a message
+var dequeueWaitTime = 5 * time.Second
+
+// NewActionMQ new durable mq
 func NewActionMQ(ctx context.Context) ActionMQ {
-	etcdQueue := etcdQueue{
+	ctx, cancel := context.WithCancel(ctx)
+	return &durableQueue{
 		mqConfig: configs.Default().MQConfig,
 		ctx:      ctx,
-		queues:   make(map[string]string),
+		cancel:   cancel,
+		queues:   make(map[string]*topicQueue),
 	}
-	return &etcdQueue
 }
 
-type etcdQueue struct {
-	client     *KeyValueStore
+// durableQueue keeps messages in memory and records every operation in a journal
+type durableQueue struct {
+	journal    Journal
 	mqConfig   *rbdcomponent.MQConfig
 	ctx        context.Context
-	queues     map[string]string
+	cancel     context.CancelFunc
+	queues     map[string]*topicQueue
 	queuesLock sync.Mutex
 }
 
-func (e *etcdQueue) Start() error {
-	logrus.Debug("etcd message queue client starting")
-
-	e.client = NewKeyValueStore()
-	topics := os.Getenv("topics")
-	if topics != "" {
-		ts := strings.Split(topics, ",")
-		for _, t := range ts {
-			e.registerTopic(t)
+func (e *durableQueue) Start() error {
+	logrus.Debugf("message queue starting with storage mode %s", e.mqConfig.StorageMode)
+	journal, err := NewJournal(e.mqConfig.StorageMode, e.mqConfig.DataDir, e.mqConfig.SyncWrite)
+	if err != nil {
+		return err
+	}
+	e.journal = journal
+	topics := []string{
+		client.BuilderTopic,
+		client.WindowsBuilderTopic,
+		client.WorkerTopic,
+		client.WorkerHealth,
+		client.BuilderHealth,
+		client.SourceScanTopic,
+	}
+	if envTopics := os.Getenv("topics"); envTopics != "" {
+		topics = append(strings.Split(envTopics, ","), topics...)
+	}
+	for _, t := range topics {
+		if IsDeadLetterTopic(t) {
+			continue
 		}
+		if err := e.registerTopic(t); err != nil {
+			return fmt.Errorf("register topic %s failure %s", t, err.Error())
+		}
+	}
+	go e.redeliverLoop()
+	logrus.Info("message queue started success")
+	return nil
+}
+
+// registerTopic 注册消息队列主题, the dead-letter topic of it is registered at the same time
+func (e *durableQueue) registerTopic(topic string) error {
+	e.queuesLock.Lock()
+	defer e.queuesLock.Unlock()
+	if _, ok := e.queues[topic]; ok {
+		return nil
+	}
+	deadLetters := newTopicQueue(DeadLetterTopic(topic), e.journal)
+	if err := deadLetters.restore(); err != nil {
+		return err
+	}
+	queue := newTopicQueue(topic, e.journal)
+	queue.maxDeliveries = e.mqConfig.MaxDeliveries
+	queue.deadLetters = deadLetters
+	if err := queue.restore(); err != nil {
+		return err
 	}
-	e.registerTopic(client.BuilderTopic)
-	e.registerTopic(client.WindowsBuilderTopic)
-	e.registerTopic(client.WorkerTopic)
-	e.registerTopic(client.WorkerHealth)
-	e.registerTopic(client.BuilderHealth)
-	e.registerTopic(client.SourceScanTopic)
-	logrus.Info("etcd message queue client started success")
+	e.queues[deadLetters.name] = deadLetters
+	e.queues[topic] = queue
 	return nil
 }
 
-// registerTopic 注册消息队列主题
-func (e *etcdQueue) registerTopic(topic string) {
+func (e *durableQueue) getQueue(topic string) (*topicQueue, error) {
 	e.queuesLock.Lock()
 	defer e.queuesLock.Unlock()
-	e.queues[topic] = topic
+	queue, ok := e.queues[topic]
+	if !ok {
+		return nil, fmt.Errorf("topic %s is not support", topic)
+	}
+	return queue, nil
 }
 
-func (e *etcdQueue) TopicIsExist(topic string) bool {
+func (e *durableQueue) TopicIsExist(topic string) bool {
 	e.queuesLock.Lock()
 	defer e.queuesLock.Unlock()
 	_, ok := e.queues[topic]
 	return ok
 }
-func (e *etcdQueue) GetAllTopics() []string {
+
+func (e *durableQueue) GetAllTopics() []string {
+	e.queuesLock.Lock()
+	defer e.queuesLock.Unlock()
 	var topics []string
 	for k := range e.queues {
 		topics = append(topics, k)
@@ -108,28 +169,163 @@ func (e *etcdQueue) GetAllTopics() []string {
 	return topics
 }
 
-func (e *etcdQueue) Stop() error {
-
+func (e *durableQueue) Stop() error {
+	e.cancel()
+	if e.journal != nil {
+		return e.journal.Close()
+	}
 	return nil
 }
-func (e *etcdQueue) queueKey(topic string) string {
-	return e.mqConfig.KeyPrefix + "/" + topic
+
+// redeliverLoop requeue the messages whose visibility timeout expired
+func (e *durableQueue) redeliverLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.queuesLock.Lock()
+			queues := make([]*topicQueue, 0, len(e.queues))
+			for _, q := range e.queues {
+				queues = append(queues, q)
+			}
+			e.queuesLock.Unlock()
+			for _, q := range queues {
+				q.requeueExpired(now)
+			}
+		}
+	}
 }
-func (e *etcdQueue) Enqueue(ctx context.Context, topic, value string) error {
+
+func (e *durableQueue) Enqueue(ctx context.Context, topic, value string) error {
+	queue, err := e.getQueue(topic)
+	if err != nil {
+		return err
+	}
 	EnqueueNumber++
-	e.client.Put(e.queueKey(topic), value)
-	return nil
+	return queue.push(value)
 }
 
-func (e *etcdQueue) Dequeue(ctx context.Context, topic string) (string, error) {
-	DequeueNumber++
-	res, ok := e.client.Get(e.queueKey(topic))
-	if !ok {
-		return "", context.DeadlineExceeded
+func (e *durableQueue) Dequeue(ctx context.Context, topic string) (string, error) {
+	delivery, err := e.Receive(ctx, topic, e.mqConfig.VisibilityTimeout)
+	if err != nil {
+		return "", err
+	}
+	if err := e.Ack(ctx, topic, delivery.Receipt); err != nil {
+		return "", err
 	}
-	return res, nil
+	return delivery.Body, nil
 }
 
-func (e *etcdQueue) MessageQueueSize(topic string) int64 {
-	return e.client.Size(topic)
+func (e *durableQueue) Receive(ctx context.Context, topic string, visibilityTimeout time.Duration) (*Delivery, error) {
+	queue, err := e.getQueue(topic)
+	if err != nil {
+		return nil, err
+	}
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = e.mqConfig.VisibilityTimeout
+	}
+	timer := time.NewTimer(dequeueWaitTime)
+	defer timer.Stop()
+	for {
+		notify := queue.wait()
+		delivery, err := queue.lease(visibilityTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if delivery != nil {
+			DequeueNumber++
+			return delivery, nil
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, context.DeadlineExceeded
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (e *durableQueue) Ack(ctx context.Context, topic, receipt string) error {
+	queue, err := e.getQueue(topic)
+	if err != nil {
+		return err
+	}
+	return queue.ack(receipt)
+}
+
+func (e *durableQueue) Nack(ctx context.Context, topic, receipt string, delay time.Duration, reason string, deadLetter bool) error {
+	queue, err := e.getQueue(topic)
+	if err != nil {
+		return err
+	}
+	return queue.nack(receipt, delay, reason, deadLetter)
+}
+
+func (e *durableQueue) MessageQueueSize(topic string) int64 {
+	queue, err := e.getQueue(topic)
+	if err != nil {
+		return 0
+	}
+	return queue.size()
+}
+
+func (e *durableQueue) TopicStats(topic string) TopicStats {
+	queue, err := e.getQueue(topic)
+	if err != nil {
+		return TopicStats{}
+	}
+	return queue.stats()
+}
+
+// getDeadLetterQueue return the dead-letter queue of a source topic
+func (e *durableQueue) getDeadLetterQueue(topic string) (*topicQueue, error) {
+	if IsDeadLetterTopic(topic) {
+		return nil, fmt.Errorf("topic %s is a dead-letter topic", topic)
+	}
+	return e.getQueue(DeadLetterTopic(topic))
+}
+
+func (e *durableQueue) ListDeadLetters(topic string) ([]DeadLetter, error) {
+	queue, err := e.getDeadLetterQueue(topic)
+	if err != nil {
+		return nil, err
+	}
+	return queue.list(topic), nil
+}
+
+func (e *durableQueue) GetDeadLetter(topic, messageID string) (*DeadLetter, error) {
+	queue, err := e.getDeadLetterQueue(topic)
+	if err != nil {
+		return nil, err
+	}
+	return queue.get(topic, messageID)
+}
+
+func (e *durableQueue) RequeueDeadLetter(ctx context.Context, topic, messageID string) error {
+	deadLetters, err := e.getDeadLetterQueue(topic)
+	if err != nil {
+		return err
+	}
+	letter, err := deadLetters.get(topic, messageID)
+	if err != nil {
+		return err
+	}
+	// enqueue before removing, a crash in between duplicates the message instead of losing it
+	if err := e.Enqueue(ctx, topic, letter.Body); err != nil {
+		return err
+	}
+	_, err = deadLetters.purge([]string{messageID})
+	return err
+}
+
+func (e *durableQueue) PurgeDeadLetters(topic string, messageIDs []string) (int, error) {
+	queue, err := e.getDeadLetterQueue(topic)
+	if err != nil {
+		return 0, err
+	}
+	return queue.purge(messageIDs)
 }
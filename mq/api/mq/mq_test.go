@@ -0,0 +1,303 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/config/configs/rbdcomponent"
+	"golang.org/x/net/context"
+)
+
+func newTestQueue(t *testing.T, dataDir string) *durableQueue {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &durableQueue{
+		mqConfig: &rbdcomponent.MQConfig{
+			StorageMode:       "wal",
+			DataDir:           dataDir,
+			SyncWrite:         true,
+			VisibilityTimeout: time.Minute,
+			MaxDeliveries:     2,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		queues: make(map[string]*topicQueue),
+	}
+	if err := q.Start(); err != nil {
+		t.Fatalf("start queue: %v", err)
+	}
+	t.Cleanup(func() { q.Stop() })
+	return q
+}
+
+func TestDurableQueueRestoresUnackedMessagesAfterRestart(t *testing.T) {
+	dataDir := t.TempDir()
+	q := newTestQueue(t, dataDir)
+	ctx := context.Background()
+	for _, body := range []string{"task-1", "task-2", "task-3"} {
+		if err := q.Enqueue(ctx, "builder", body); err != nil {
+			t.Fatalf("enqueue %s: %v", body, err)
+		}
+	}
+	first, err := q.Receive(ctx, "builder", time.Minute)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if err := q.Ack(ctx, "builder", first.Receipt); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	// leased but never acked, the consumer crashed
+	if _, err := q.Receive(ctx, "builder", time.Minute); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	q.Stop()
+
+	restarted := newTestQueue(t, dataDir)
+	if size := restarted.MessageQueueSize("builder"); size != 2 {
+		t.Fatalf("expected 2 messages restored, got %d", size)
+	}
+	delivery, err := restarted.Receive(ctx, "builder", time.Minute)
+	if err != nil {
+		t.Fatalf("receive after restart: %v", err)
+	}
+	if delivery.Body != "task-2" || delivery.Deliveries != 2 {
+		t.Fatalf("expected task-2 redelivered the second time, got %s with %d deliveries", delivery.Body, delivery.Deliveries)
+	}
+}
+
+func TestDurableQueueRedeliversAfterVisibilityTimeout(t *testing.T) {
+	q := newTestQueue(t, t.TempDir())
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, "worker", "task"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	delivery, err := q.Receive(ctx, "worker", time.Millisecond)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	q.queues["worker"].requeueExpired(time.Now().Add(time.Second))
+	if err := q.Ack(ctx, "worker", delivery.Receipt); err != ErrReceiptNotFound {
+		t.Fatalf("expected expired receipt to be rejected, got %v", err)
+	}
+	redelivery, err := q.Receive(ctx, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("receive redelivery: %v", err)
+	}
+	if redelivery.Deliveries != 2 || redelivery.Receipt == delivery.Receipt {
+		t.Fatalf("expected a new lease, got %+v", redelivery)
+	}
+}
+
+func TestDurableQueueNackRequeuesMessage(t *testing.T) {
+	q := newTestQueue(t, t.TempDir())
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, "worker", "task"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	delivery, err := q.Receive(ctx, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if err := q.Nack(ctx, "worker", delivery.Receipt, 0, "", false); err != nil {
+		t.Fatalf("nack: %v", err)
+	}
+	if size := q.MessageQueueSize("worker"); size != 1 {
+		t.Fatalf("expected nacked message pending, got %d", size)
+	}
+	body, err := q.Dequeue(ctx, "worker")
+	if err != nil || body != "task" {
+		t.Fatalf("expected task dequeued, got %q %v", body, err)
+	}
+	if size := q.MessageQueueSize("worker"); size != 0 {
+		t.Fatalf("expected empty queue after dequeue, got %d", size)
+	}
+}
+
+func TestTopicQueueCompactsJournal(t *testing.T) {
+	journal, err := NewJournal("wal", t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("new journal: %v", err)
+	}
+	queue := newTopicQueue("builder", journal)
+	for i := 0; i < compactThreshold; i++ {
+		if err := queue.push("task"); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+		delivery, err := queue.lease(time.Minute)
+		if err != nil {
+			t.Fatalf("lease: %v", err)
+		}
+		if err := queue.ack(delivery.Receipt); err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+	if err := queue.push("live"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if queue.writes >= compactThreshold {
+		t.Fatalf("expected journal compacted, %d records written", queue.writes)
+	}
+	restored := newTopicQueue("builder", journal)
+	if err := restored.restore(); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.size() != 1 || restored.pending[0].body != "live" {
+		t.Fatalf("expected only the live message restored, got %d", restored.size())
+	}
+}
+
+func TestTopicQueueCompactionKeepsEnqueueTime(t *testing.T) {
+	journal, err := NewJournal("wal", t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("new journal: %v", err)
+	}
+	queue := newTopicQueue("builder", journal)
+	if err := queue.push("live"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	enqueuedAt := queue.pending[0].enqueuedAt.Unix()
+	if _, err := queue.lease(time.Minute); err != nil {
+		t.Fatalf("lease: %v", err)
+	}
+	for i := 0; i < compactThreshold; i++ {
+		if err := queue.push("task"); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+		delivery, err := queue.lease(time.Minute)
+		if err != nil {
+			t.Fatalf("lease: %v", err)
+		}
+		if err := queue.ack(delivery.Receipt); err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+	if queue.writes >= compactThreshold {
+		t.Fatalf("expected journal compacted, %d records written", queue.writes)
+	}
+	restored := newTopicQueue("builder", journal)
+	if err := restored.restore(); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.size() != 1 || restored.pending[0].body != "live" {
+		t.Fatalf("expected only the in-flight message restored, got %d", restored.size())
+	}
+	if got := restored.pending[0].enqueuedAt.Unix(); got != enqueuedAt {
+		t.Fatalf("expected enqueue time %d kept by compaction, got %d", enqueuedAt, got)
+	}
+}
+
+func TestDurableQueueDeadLettersExhaustedMessage(t *testing.T) {
+	dataDir := t.TempDir()
+	q := newTestQueue(t, dataDir)
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, "builder", "poison"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		delivery, err := q.Receive(ctx, "builder", time.Minute)
+		if err != nil {
+			t.Fatalf("receive %d: %v", i, err)
+		}
+		if err := q.Nack(ctx, "builder", delivery.Receipt, 0, "run task failure", false); err != nil {
+			t.Fatalf("nack %d: %v", i, err)
+		}
+	}
+	if size := q.MessageQueueSize("builder"); size != 0 {
+		t.Fatalf("expected poison message removed from topic, got %d", size)
+	}
+	letters, err := q.ListDeadLetters("builder")
+	if err != nil {
+		t.Fatalf("list dead letters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Failures != 2 || letters[0].Reason != "run task failure" {
+		t.Fatalf("unexpected dead letters %+v", letters)
+	}
+	if stats := q.TopicStats("builder"); stats.DeadLettered != 1 || stats.Redelivered != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	q.Stop()
+
+	restarted := newTestQueue(t, dataDir)
+	letters, err = restarted.ListDeadLetters("builder")
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected dead letter restored, got %+v %v", letters, err)
+	}
+	if err := restarted.RequeueDeadLetter(ctx, "builder", letters[0].ID); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	body, err := restarted.Dequeue(ctx, "builder")
+	if err != nil || body != "poison" {
+		t.Fatalf("expected requeued message, got %q %v", body, err)
+	}
+	if letters, _ := restarted.ListDeadLetters("builder"); len(letters) != 0 {
+		t.Fatalf("expected dead-letter topic empty after requeue, got %d", len(letters))
+	}
+}
+
+func TestDurableQueueNackWithoutReasonIsNotAFailure(t *testing.T) {
+	q := newTestQueue(t, t.TempDir())
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, "builder", "task"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		delivery, err := q.Receive(ctx, "builder", time.Minute)
+		if err != nil {
+			t.Fatalf("receive %d: %v", i, err)
+		}
+		if err := q.Nack(ctx, "builder", delivery.Receipt, 0, "", false); err != nil {
+			t.Fatalf("nack %d: %v", i, err)
+		}
+	}
+	if size := q.MessageQueueSize("builder"); size != 1 {
+		t.Fatalf("expected message kept in topic, got %d", size)
+	}
+}
+
+func TestDurableQueuePurgeDeadLetters(t *testing.T) {
+	q := newTestQueue(t, t.TempDir())
+	ctx := context.Background()
+	for _, body := range []string{"a", "b"} {
+		if err := q.Enqueue(ctx, "worker", body); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		delivery, err := q.Receive(ctx, "worker", time.Minute)
+		if err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if err := q.Nack(ctx, "worker", delivery.Receipt, 0, "broken", true); err != nil {
+			t.Fatalf("nack: %v", err)
+		}
+	}
+	letters, _ := q.ListDeadLetters("worker")
+	if len(letters) != 2 {
+		t.Fatalf("expected 2 dead letters, got %d", len(letters))
+	}
+	if count, err := q.PurgeDeadLetters("worker", []string{letters[0].ID}); err != nil || count != 1 {
+		t.Fatalf("purge one: %d %v", count, err)
+	}
+	if count, err := q.PurgeDeadLetters("worker", nil); err != nil || count != 1 {
+		t.Fatalf("purge all: %d %v", count, err)
+	}
+	if _, err := q.GetDeadLetter("worker", letters[0].ID); err != ErrMessageNotFound {
+		t.Fatalf("expected purged message not found, got %v", err)
+	}
+}
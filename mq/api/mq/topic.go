@@ -0,0 +1,486 @@
+// Copyright (C) 2014-2018 Goodrain Co., Ltd.
+// RAINBOND, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goodrain/rainbond/util"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrReceiptNotFound the receipt is unknown or its visibility timeout has expired
+var ErrReceiptNotFound = errors.New("receipt not found or lease expired")
+
+// ErrMessageNotFound the message is not in the queue
+var ErrMessageNotFound = errors.New("message not found")
+
+// compactThreshold the journal records written before trying to compact it
+const compactThreshold = 1024
+
+// deadLetterSuffix the suffix of the dead-letter topic of a topic
+const deadLetterSuffix = ".dlq"
+
+// DeadLetterTopic return the dead-letter topic name of the topic
+func DeadLetterTopic(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+// IsDeadLetterTopic whether the topic is a dead-letter topic
+func IsDeadLetterTopic(topic string) bool {
+	return strings.HasSuffix(topic, deadLetterSuffix)
+}
+
+// Delivery a message leased to a consumer
+type Delivery struct {
+	Receipt    string
+	Body       string
+	Deliveries int
+}
+
+// DeadLetter a message routed to the dead-letter topic
+type DeadLetter struct {
+	ID         string    `json:"message_id"`
+	Topic      string    `json:"topic"`
+	Body       string    `json:"-"`
+	Deliveries int       `json:"deliveries"`
+	Failures   int       `json:"failures"`
+	Reason     string    `json:"reason"`
+	DeadAt     time.Time `json:"dead_at"`
+}
+
+// TopicStats the counters of a topic
+type TopicStats struct {
+	Pending      int64 `json:"pending"`
+	InFlight     int64 `json:"in_flight"`
+	Redelivered  int64 `json:"redelivered"`
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+type message struct {
+	id         string
+	body       string
+	deliveries int
+	failures   int
+	reason     string
+	receipt    string
+	// visibleAt the time an in-flight message is redelivered
+	visibleAt time.Time
+	// enqueuedAt the time the message entered the topic, for a dead letter the time it was dead-lettered
+	enqueuedAt time.Time
+}
+
+// topicQueue the messages of one topic, pending messages are visible to consumers,
+// in-flight messages are leased and wait for ack
+type topicQueue struct {
+	name     string
+	journal  Journal
+	lock     sync.Mutex
+	pending  []*message
+	inflight map[string]*message
+	notify   chan struct{}
+	writes   int
+	// maxDeliveries the failed deliveries before a message is dead-lettered, zero means never
+	maxDeliveries int
+	deadLetters   *topicQueue
+	redelivered   int64
+	deadLettered  int64
+}
+
+func newTopicQueue(name string, journal Journal) *topicQueue {
+	return &topicQueue{
+		name:     name,
+		journal:  journal,
+		inflight: make(map[string]*message),
+		notify:   make(chan struct{}),
+	}
+}
+
+// restore rebuild the queue from journal, messages in-flight before restart are pending again
+func (t *topicQueue) restore() error {
+	messages := make(map[string]*message)
+	var order []string
+	err := t.journal.Replay(t.name, func(r Record) {
+		t.writes++
+		switch r.Op {
+		case OpEnqueue:
+			if _, ok := messages[r.ID]; !ok {
+				order = append(order, r.ID)
+			}
+			messages[r.ID] = &message{
+				id:         r.ID,
+				body:       string(r.Body),
+				deliveries: r.Deliveries,
+				failures:   r.Failures,
+				reason:     r.Reason,
+				enqueuedAt: time.Unix(r.Time, 0),
+			}
+		case OpLease:
+			if m, ok := messages[r.ID]; ok {
+				m.deliveries++
+			}
+		case OpNack:
+			if m, ok := messages[r.ID]; ok {
+				m.failures++
+				m.reason = r.Reason
+			}
+		case OpAck:
+			delete(messages, r.ID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, id := range order {
+		m, ok := messages[id]
+		if !ok {
+			continue
+		}
+		if t.exhausted(m) {
+			t.deadLetter(m)
+			continue
+		}
+		t.pending = append(t.pending, m)
+	}
+	if len(t.pending) > 0 {
+		logrus.Infof("restore %d messages of topic %s", len(t.pending), t.name)
+	}
+	return nil
+}
+
+// signal wake up the consumers waiting for messages, must be called with lock held
+func (t *topicQueue) signal() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+func (t *topicQueue) wait() <-chan struct{} {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.notify
+}
+
+func (t *topicQueue) push(body string) error {
+	m := &message{id: util.NewUUID(), body: body, enqueuedAt: time.Now()}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if err := t.journal.Append(t.name, Record{Op: OpEnqueue, ID: m.id, Body: []byte(body), Time: m.enqueuedAt.Unix()}); err != nil {
+		return err
+	}
+	t.writes++
+	t.pending = append(t.pending, m)
+	t.signal()
+	return nil
+}
+
+// lease take the first pending message and keep it in-flight until visibility timeout
+func (t *topicQueue) lease(visibility time.Duration) (*Delivery, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.pending) == 0 {
+		return nil, nil
+	}
+	m := t.pending[0]
+	if err := t.journal.Append(t.name, Record{Op: OpLease, ID: m.id, Time: time.Now().Unix()}); err != nil {
+		return nil, err
+	}
+	t.writes++
+	t.pending = t.pending[1:]
+	m.deliveries++
+	m.receipt = fmt.Sprintf("%s.%d", m.id, m.deliveries)
+	m.visibleAt = time.Now().Add(visibility)
+	t.inflight[m.id] = m
+	return &Delivery{Receipt: m.receipt, Body: m.body, Deliveries: m.deliveries}, nil
+}
+
+func (t *topicQueue) leased(receipt string) (*message, error) {
+	id := receipt
+	if i := strings.LastIndex(receipt, "."); i > 0 {
+		id = receipt[:i]
+	}
+	m, ok := t.inflight[id]
+	if !ok || m.receipt == "" || m.receipt != receipt {
+		return nil, ErrReceiptNotFound
+	}
+	return m, nil
+}
+
+func (t *topicQueue) ack(receipt string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	m, err := t.leased(receipt)
+	if err != nil {
+		return err
+	}
+	if err := t.remove(m); err != nil {
+		return err
+	}
+	t.compact()
+	return nil
+}
+
+// remove ack the message in journal and drop it from the queue, must be called with lock held
+func (t *topicQueue) remove(m *message) error {
+	if err := t.journal.Append(t.name, Record{Op: OpAck, ID: m.id, Time: time.Now().Unix()}); err != nil {
+		return err
+	}
+	t.writes++
+	delete(t.inflight, m.id)
+	for i := range t.pending {
+		if t.pending[i] == m {
+			t.pending = append(t.pending[:i], t.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// fail record a failed delivery of the message, must be called with lock held
+func (t *topicQueue) fail(m *message, reason string) error {
+	if err := t.journal.Append(t.name, Record{Op: OpNack, ID: m.id, Reason: reason, Time: time.Now().Unix()}); err != nil {
+		return err
+	}
+	t.writes++
+	m.failures++
+	m.reason = reason
+	return nil
+}
+
+// exhausted whether the message has failed too many times
+func (t *topicQueue) exhausted(m *message) bool {
+	return t.deadLetters != nil && t.maxDeliveries > 0 && m.failures >= t.maxDeliveries
+}
+
+// deadLetter move the message to the dead-letter topic, must be called with lock held
+func (t *topicQueue) deadLetter(m *message) {
+	if t.deadLetters == nil {
+		return
+	}
+	if err := t.deadLetters.pushDeadLetter(m); err != nil {
+		logrus.Errorf("move message %s of topic %s to dead-letter topic failure %s", m.id, t.name, err.Error())
+		return
+	}
+	if err := t.remove(m); err != nil {
+		logrus.Errorf("remove dead-lettered message %s of topic %s failure %s", m.id, t.name, err.Error())
+		return
+	}
+	t.deadLettered++
+	logrus.Warningf("message %s of topic %s failed %d times and was moved to %s, reason: %s", m.id, t.name, m.failures, t.deadLetters.name, m.reason)
+}
+
+// nack give up a lease, a failed delivery is counted when reason is not empty,
+// the message is visible again after delay unless it is dead-lettered
+func (t *topicQueue) nack(receipt string, delay time.Duration, reason string, deadLetter bool) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	m, err := t.leased(receipt)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		if err := t.fail(m, reason); err != nil {
+			return err
+		}
+	}
+	m.receipt = ""
+	if deadLetter || t.exhausted(m) {
+		if t.deadLetters != nil {
+			t.deadLetter(m)
+			return nil
+		}
+	}
+	t.redelivered++
+	if delay <= 0 {
+		delete(t.inflight, m.id)
+		t.pending = append(t.pending, m)
+		t.signal()
+		return nil
+	}
+	m.visibleAt = time.Now().Add(delay)
+	return nil
+}
+
+// requeueExpired move the in-flight messages whose visibility timeout expired back to pending
+func (t *topicQueue) requeueExpired(now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var requeued int
+	for id, m := range t.inflight {
+		if m.visibleAt.After(now) {
+			continue
+		}
+		if m.receipt != "" {
+			// the consumer did not finish the message in time, it may crash on it
+			logrus.Warningf("message %s of topic %s is not acked before visibility timeout, redeliver it", id, t.name)
+			if err := t.fail(m, "visibility timeout expired"); err != nil {
+				logrus.Errorf("record failed delivery of message %s failure %s", id, err.Error())
+			}
+			m.receipt = ""
+			t.redelivered++
+		}
+		if t.exhausted(m) {
+			t.deadLetter(m)
+			continue
+		}
+		delete(t.inflight, id)
+		t.pending = append(t.pending, m)
+		requeued++
+	}
+	if requeued > 0 {
+		t.signal()
+	}
+}
+
+// pushDeadLetter enqueue a message of the source topic with its failure state
+func (t *topicQueue) pushDeadLetter(src *message) error {
+	now := time.Now()
+	m := &message{
+		id:         src.id,
+		body:       src.body,
+		deliveries: src.deliveries,
+		failures:   src.failures,
+		reason:     src.reason,
+		enqueuedAt: now,
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	record := Record{Op: OpEnqueue, ID: m.id, Body: []byte(m.body), Deliveries: m.deliveries, Failures: m.failures, Reason: m.reason, Time: now.Unix()}
+	if err := t.journal.Append(t.name, record); err != nil {
+		return err
+	}
+	t.writes++
+	t.pending = append(t.pending, m)
+	return nil
+}
+
+func (m *message) deadLetterView(topic string) DeadLetter {
+	return DeadLetter{
+		ID:         m.id,
+		Topic:      topic,
+		Body:       m.body,
+		Deliveries: m.deliveries,
+		Failures:   m.failures,
+		Reason:     m.reason,
+		DeadAt:     m.enqueuedAt,
+	}
+}
+
+// list return the pending messages as dead letters of the source topic
+func (t *topicQueue) list(topic string) []DeadLetter {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	letters := make([]DeadLetter, 0, len(t.pending))
+	for _, m := range t.pending {
+		letters = append(letters, m.deadLetterView(topic))
+	}
+	return letters
+}
+
+func (t *topicQueue) get(topic, id string) (*DeadLetter, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, m := range t.pending {
+		if m.id == id {
+			letter := m.deadLetterView(topic)
+			return &letter, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// purge remove the pending messages with the given ids, all pending messages when ids is empty
+func (t *topicQueue) purge(ids []string) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var targets []*message
+	if len(ids) == 0 {
+		targets = append(targets, t.pending...)
+	} else {
+		wanted := make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			wanted[id] = struct{}{}
+		}
+		for _, m := range t.pending {
+			if _, ok := wanted[m.id]; ok {
+				targets = append(targets, m)
+			}
+		}
+	}
+	for i, m := range targets {
+		if err := t.remove(m); err != nil {
+			return i, err
+		}
+	}
+	t.compact()
+	return len(targets), nil
+}
+
+// compact rewrite the journal with live messages once enough records were written, must be called with lock held
+func (t *topicQueue) compact() {
+	live := len(t.pending) + len(t.inflight)
+	if t.writes < compactThreshold || live*2 > t.writes {
+		return
+	}
+	records := make([]Record, 0, live)
+	add := func(m *message) {
+		records = append(records, Record{
+			Op:         OpEnqueue,
+			ID:         m.id,
+			Body:       []byte(m.body),
+			Deliveries: m.deliveries,
+			Failures:   m.failures,
+			Reason:     m.reason,
+			Time:       m.enqueuedAt.Unix(),
+		})
+	}
+	for _, m := range t.pending {
+		add(m)
+	}
+	for _, m := range t.inflight {
+		add(m)
+	}
+	if err := t.journal.Compact(t.name, records); err != nil {
+		logrus.Errorf("compact topic %s journal failure %s", t.name, err.Error())
+		return
+	}
+	t.writes = len(records)
+}
+
+func (t *topicQueue) size() int64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return int64(len(t.pending))
+}
+
+func (t *topicQueue) stats() TopicStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return TopicStats{
+		Pending:      int64(len(t.pending)),
+		InFlight:     int64(len(t.inflight)),
+		Redelivered:  t.redelivered,
+		DeadLettered: t.deadLettered,
+	}
+}
@@ -47,6 +47,9 @@ type Exporter struct {
 	scrapeErrors       *prometheus.CounterVec
 	lbPluginUp         prometheus.Gauge
 	queueMessageNumber *prometheus.GaugeVec
+	inFlightNumber     *prometheus.GaugeVec
+	redeliveredTotal   *prometheus.Desc
+	deadLetteredTotal  *prometheus.Desc
 	mqm                mq.ActionMQ
 }
 
@@ -88,6 +91,22 @@ func NewExporter(mqm mq.ActionMQ) *Exporter {
 			Name:      "queue_message_number",
 			Help:      "Message queue enqueue total.",
 		}, []string{"topic"}),
+		inFlightNumber: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_in_flight_number",
+			Help:      "Messages leased by consumers and not acked yet.",
+		}, []string{"topic"}),
+		// the counters are kept by the topics, exported as const metrics on each scrape
+		redeliveredTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "queue_redelivered_total"),
+			"Messages redelivered after nack or visibility timeout since mq started.",
+			[]string{"topic"}, nil,
+		),
+		deadLetteredTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "queue_dead_lettered_total"),
+			"Messages moved to the dead-letter topic since mq started.",
+			[]string{"topic"}, nil,
+		),
 	}
 }
 
@@ -106,6 +125,8 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	e.Collect(metricCh)
 	close(metricCh)
 	<-doneCh
+	ch <- e.redeliveredTotal
+	ch <- e.deadLetteredTotal
 }
 
 // Collect implements prometheus.Collector.
@@ -115,9 +136,14 @@ func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 	ch <- e.error
 	e.scrapeErrors.Collect(ch)
 	for _, topic := range e.mqm.GetAllTopics() {
-		e.queueMessageNumber.WithLabelValues(topic).Set(float64(e.mqm.MessageQueueSize(topic)))
+		stats := e.mqm.TopicStats(topic)
+		e.queueMessageNumber.WithLabelValues(topic).Set(float64(stats.Pending))
+		e.inFlightNumber.WithLabelValues(topic).Set(float64(stats.InFlight))
+		ch <- prometheus.MustNewConstMetric(e.redeliveredTotal, prometheus.CounterValue, float64(stats.Redelivered), topic)
+		ch <- prometheus.MustNewConstMetric(e.deadLetteredTotal, prometheus.CounterValue, float64(stats.DeadLettered), topic)
 	}
 	e.queueMessageNumber.Collect(ch)
+	e.inFlightNumber.Collect(ch)
 }
 
 func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
@@ -3,6 +3,9 @@ package metrics
 import (
 	"context"
 
+	restful "github.com/emicklei/go-restful"
+	"github.com/goodrain/rainbond/config/configs"
+	"github.com/goodrain/rainbond/mq/api/controller"
 	"github.com/goodrain/rainbond/mq/monitor"
 	"github.com/goodrain/rainbond/mq/mqcomponent/mqclient"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -48,6 +51,7 @@ func (s *Server) StartCancel(ctx context.Context, cancel context.CancelFunc) err
 			"status": "healthy",
 		})
 	})
+	controller.RegisterDeadLetter(restful.DefaultContainer, mqclient.Default().ActionMQ(), configs.Default().MQConfig.AdminToken)
 	logrus.Infof("metrics health route registered successfully")
 	return gogo.Go(func(ctx context.Context) error {
 		logrus.Infof("starting metrics server on :6301")
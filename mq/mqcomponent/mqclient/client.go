@@ -30,6 +30,9 @@ func (m *Component) Start(ctx context.Context) error {
 
 // CloseHandle -
 func (m *Component) CloseHandle() {
+	if m.actionMQ != nil {
+		m.actionMQ.Stop()
+	}
 }
 
 // New -
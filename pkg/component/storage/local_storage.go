@@ -333,3 +333,8 @@ func (l *LocalStorage) ReadFile(filePath string) (ReadCloser, error) {
 	}
 	return file, nil
 }
+
+// RemoveAll removes the file or the directory from local storage
+func (l *LocalStorage) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
@@ -1034,6 +1034,21 @@ func (s3s *S3Storage) ReadFile(filePath string) (ReadCloser, error) {
 	return result.Body, nil
 }
 
+// RemoveAll removes the object, or all the objects under the directory from S3
+func (s3s *S3Storage) RemoveAll(path string) error {
+	bucketName, key, err := s3s.ParseDirPath(path, true)
+	if err != nil {
+		return fmt.Errorf("failed to parse path: %w", err)
+	}
+	if _, err := s3s.s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+	return s3s.ClearDirectory(bucketName, strings.TrimSuffix(key, "/")+"/")
+}
+
 // InitBucketLifecycle 在 API 启动时主动初始化默认 bucket 的生命周期策略
 func (s3s *S3Storage) InitBucketLifecycle() error {
 	// 默认初始化 grdata bucket 的生命周期策略
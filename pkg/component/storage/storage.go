@@ -86,6 +86,8 @@ type InterfaceStorage interface {
 	DownloadFileToDir(srcFile, dstDir string) error
 	// ReadFile reads a file directly from storage and returns a reader
 	ReadFile(filePath string) (ReadCloser, error)
+	// RemoveAll removes the file, or the directory and everything it contains
+	RemoveAll(path string) error
 
 	// 分片上传相关方法
 	SaveChunk(sessionID string, chunkIndex int, reader multipart.File) (string, error)
@@ -0,0 +1,432 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// change types of the resources and fields in a diff
+const (
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+	DiffChanged  = "changed"
+	DiffModified = "modified"
+	// DiffMissing the resource of the release manifest is deleted from the cluster
+	DiffMissing = "missing"
+)
+
+// sensitiveValue replaces the values of the secret data in the diffs
+const sensitiveValue = "(sensitive)"
+
+// ManifestResource a kubernetes object rendered in a release manifest
+type ManifestResource struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Object     map[string]interface{}
+}
+
+// Key identifies the resource in a release
+func (m *ManifestResource) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", m.APIVersion, m.Kind, m.Namespace, m.Name)
+}
+
+// FieldChange a changed field of a resource or the values, the path looks like
+// spec.template.spec.containers[name=web].image
+type FieldChange struct {
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ResourceDiff the changes of a resource
+type ResourceDiff struct {
+	APIVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	// Change added, removed, modified or missing
+	Change string         `json:"change"`
+	Fields []*FieldChange `json:"fields,omitempty"`
+}
+
+// ParseManifest splits a release manifest into resources, the resources without a
+// namespace are put into the default namespace, use an empty one for cluster resources
+func ParseManifest(manifest, defaultNamespace string) ([]*ManifestResource, error) {
+	docs := releaseutil.SplitManifests(manifest)
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(names))
+	var resources []*ManifestResource
+	for _, name := range names {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(docs[name]), &obj); err != nil {
+			return nil, fmt.Errorf("parse manifest %s: %v", name, err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		res := &ManifestResource{Object: obj}
+		res.APIVersion, _ = obj["apiVersion"].(string)
+		res.Kind, _ = obj["kind"].(string)
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			res.Name, _ = metadata["name"].(string)
+			res.Namespace, _ = metadata["namespace"].(string)
+		}
+		if res.Kind == "" || res.Name == "" {
+			continue
+		}
+		if res.Namespace == "" {
+			res.Namespace = defaultNamespace
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// DiffManifests compares the resources of two release manifests field by field
+func DiffManifests(oldManifest, newManifest, namespace string) ([]*ResourceDiff, error) {
+	olds, err := ParseManifest(oldManifest, namespace)
+	if err != nil {
+		return nil, err
+	}
+	news, err := ParseManifest(newManifest, namespace)
+	if err != nil {
+		return nil, err
+	}
+	oldByKey := make(map[string]*ManifestResource, len(olds))
+	for _, res := range olds {
+		oldByKey[res.Key()] = res
+	}
+	var diffs []*ResourceDiff
+	for _, res := range news {
+		old, ok := oldByKey[res.Key()]
+		if !ok {
+			diffs = append(diffs, newResourceDiff(res, DiffAdded, nil))
+			continue
+		}
+		delete(oldByKey, res.Key())
+		var fields []*FieldChange
+		diffValue("", old.Object, res.Object, &fields)
+		if len(fields) > 0 {
+			diffs = append(diffs, newResourceDiff(res, DiffModified, fields))
+		}
+	}
+	for _, res := range olds {
+		if _, ok := oldByKey[res.Key()]; ok {
+			diffs = append(diffs, newResourceDiff(res, DiffRemoved, nil))
+		}
+	}
+	return diffs, nil
+}
+
+// DiffValues compares the user supplied values of two revisions
+func DiffValues(oldValues, newValues map[string]interface{}) []*FieldChange {
+	var fields []*FieldChange
+	diffValue("", normalize(oldValues), normalize(newValues), &fields)
+	return fields
+}
+
+// DiffLive compares a resource of the release manifest with the live object, only the
+// fields set in the manifest are compared because the cluster fills defaults and status.
+// The items added to the named lists of the live object are reported too, e.g. a container
+// or an env var added by kubectl edit.
+func DiffLive(desired *ManifestResource, live map[string]interface{}) *ResourceDiff {
+	var fields []*FieldChange
+	for key, value := range desired.Object {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			meta, _ := value.(map[string]interface{})
+			liveMeta, _ := live["metadata"].(map[string]interface{})
+			for _, field := range []string{"labels", "annotations"} {
+				if v, ok := meta[field]; ok {
+					diffDesired("metadata."+field, normalizeValue(v), normalizeValue(liveMeta[field]), &fields)
+				}
+			}
+		case "stringData":
+			// written into data by the api server
+			continue
+		default:
+			diffDesired(key, normalizeValue(value), normalizeValue(live[key]), &fields)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return newResourceDiff(desired, DiffModified, fields)
+}
+
+func newResourceDiff(res *ManifestResource, change string, fields []*FieldChange) *ResourceDiff {
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	if res.Kind == "Secret" {
+		for _, field := range fields {
+			if strings.HasPrefix(field.Path, "data") || strings.HasPrefix(field.Path, "stringData") {
+				if field.Old != nil {
+					field.Old = sensitiveValue
+				}
+				if field.New != nil {
+					field.New = sensitiveValue
+				}
+			}
+		}
+	}
+	return &ResourceDiff{
+		APIVersion: res.APIVersion,
+		Kind:       res.Kind,
+		Namespace:  res.Namespace,
+		Name:       res.Name,
+		Change:     change,
+		Fields:     fields,
+	}
+}
+
+// diffValue compares two values in both directions
+func diffValue(path string, old, next interface{}, out *[]*FieldChange) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := next.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		for key, ov := range oldMap {
+			nv, ok := newMap[key]
+			if !ok {
+				if !isEmpty(ov) {
+					*out = append(*out, &FieldChange{Path: joinPath(path, key), Type: DiffRemoved, Old: ov})
+				}
+				continue
+			}
+			diffValue(joinPath(path, key), ov, nv, out)
+		}
+		for key, nv := range newMap {
+			if _, ok := oldMap[key]; !ok && !isEmpty(nv) {
+				*out = append(*out, &FieldChange{Path: joinPath(path, key), Type: DiffAdded, New: nv})
+			}
+		}
+		return
+	}
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := next.([]interface{})
+	if oldIsList && newIsList {
+		diffList(path, oldList, newList, true, out)
+		return
+	}
+	if old == nil && next == nil {
+		return
+	}
+	if old == nil && !isEmpty(next) {
+		*out = append(*out, &FieldChange{Path: path, Type: DiffAdded, New: next})
+		return
+	}
+	if next == nil && !isEmpty(old) {
+		*out = append(*out, &FieldChange{Path: path, Type: DiffRemoved, Old: old})
+		return
+	}
+	if !scalarEqual(old, next) {
+		*out = append(*out, &FieldChange{Path: path, Type: DiffChanged, Old: old, New: next})
+	}
+}
+
+// diffDesired compares the desired value with the live one, the fields only in live are ignored
+func diffDesired(path string, desired, live interface{}, out *[]*FieldChange) {
+	if desired == nil {
+		return
+	}
+	if desiredMap, ok := desired.(map[string]interface{}); ok {
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			if !isEmpty(desired) {
+				*out = append(*out, &FieldChange{Path: path, Type: DiffChanged, Old: desired, New: live})
+			}
+			return
+		}
+		for key, dv := range desiredMap {
+			lv, ok := liveMap[key]
+			if !ok {
+				if !isEmpty(dv) {
+					*out = append(*out, &FieldChange{Path: joinPath(path, key), Type: DiffRemoved, Old: dv})
+				}
+				continue
+			}
+			diffDesired(joinPath(path, key), dv, lv, out)
+		}
+		return
+	}
+	if desiredList, ok := desired.([]interface{}); ok {
+		liveList, ok := live.([]interface{})
+		if !ok {
+			if !isEmpty(desired) {
+				*out = append(*out, &FieldChange{Path: path, Type: DiffChanged, Old: desired, New: live})
+			}
+			return
+		}
+		diffList(path, desiredList, liveList, false, out)
+		return
+	}
+	if live == nil {
+		*out = append(*out, &FieldChange{Path: path, Type: DiffRemoved, Old: desired})
+		return
+	}
+	if !scalarEqual(desired, live) {
+		*out = append(*out, &FieldChange{Path: path, Type: DiffChanged, Old: desired, New: live})
+	}
+}
+
+// diffList matches the items of lists of named objects, e.g. containers, env and ports,
+// by name and the others by index. full compares the items in both directions, otherwise
+// the new items are compared as the live state of the old ones.
+func diffList(path string, old, next []interface{}, full bool, out *[]*FieldChange) {
+	compare := func(p string, o, n interface{}) {
+		if full {
+			diffValue(p, o, n, out)
+		} else {
+			diffDesired(p, o, n, out)
+		}
+	}
+	if isNamedList(old) && isNamedList(next) {
+		newByName := make(map[string]interface{}, len(next))
+		for _, item := range next {
+			newByName[itemName(item)] = item
+		}
+		oldNames := make(map[string]bool, len(old))
+		for _, item := range old {
+			name := itemName(item)
+			oldNames[name] = true
+			p := fmt.Sprintf("%s[name=%s]", path, name)
+			if n, ok := newByName[name]; ok {
+				compare(p, item, n)
+			} else {
+				*out = append(*out, &FieldChange{Path: p, Type: DiffRemoved, Old: item})
+			}
+		}
+		for _, item := range next {
+			if name := itemName(item); !oldNames[name] {
+				*out = append(*out, &FieldChange{Path: fmt.Sprintf("%s[name=%s]", path, name), Type: DiffAdded, New: item})
+			}
+		}
+		return
+	}
+	for i := 0; i < len(old) || i < len(next); i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(next):
+			*out = append(*out, &FieldChange{Path: p, Type: DiffRemoved, Old: old[i]})
+		case i >= len(old):
+			*out = append(*out, &FieldChange{Path: p, Type: DiffAdded, New: next[i]})
+		default:
+			compare(p, old[i], next[i])
+		}
+	}
+}
+
+func isNamedList(items []interface{}) bool {
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if itemName(item) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func itemName(item interface{}) string {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := m["name"].(string)
+	return name
+}
+
+// scalarEqual compares scalars, numbers and quantities of the same amount are equal, e.g. 0.5 and 500m
+func scalarEqual(a, b interface{}) bool {
+	if a == b {
+		return true
+	}
+	as, aok := scalarString(a)
+	bs, bok := scalarString(b)
+	if !aok || !bok {
+		return false
+	}
+	if as == bs {
+		return true
+	}
+	aq, err := resource.ParseQuantity(as)
+	if err != nil {
+		return false
+	}
+	bq, err := resource.ParseQuantity(bs)
+	if err != nil {
+		return false
+	}
+	return aq.Cmp(bq) == 0
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	default:
+		return "", false
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(value) == 0
+	case []interface{}:
+		return len(value) == 0
+	case string:
+		return value == ""
+	default:
+		return false
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// normalize converts the values into the types of json, so that the values parsed
+// from yaml and the ones of the live objects are comparable
+func normalize(values map[string]interface{}) map[string]interface{} {
+	normalized, _ := normalizeValue(values).(map[string]interface{})
+	return normalized
+}
+
+func normalizeValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
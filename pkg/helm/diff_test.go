@@ -0,0 +1,188 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const diffTestOldManifest = `---
+# Source: demo/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+          resources:
+            limits:
+              cpu: 500m
+          env:
+            - name: MODE
+              value: prod
+---
+# Source: demo/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-auth
+data:
+  password: b2xk
+---
+# Source: demo/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-legacy
+data:
+  a: b
+`
+
+const diffTestNewManifest = `---
+# Source: demo/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+          resources:
+            limits:
+              cpu: "0.5"
+          env:
+            - name: MODE
+              value: prod
+            - name: DEBUG
+              value: "false"
+---
+# Source: demo/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-auth
+data:
+  password: bmV3
+---
+# Source: demo/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+`
+
+func fieldsByPath(fields []*FieldChange) map[string]*FieldChange {
+	byPath := make(map[string]*FieldChange, len(fields))
+	for _, field := range fields {
+		byPath[field.Path] = field
+	}
+	return byPath
+}
+
+// capability_id: rainbond.helm-release.manifest-diff
+func TestDiffManifests(t *testing.T) {
+	diffs, err := DiffManifests(diffTestOldManifest, diffTestNewManifest, "ns")
+	assert.NoError(t, err)
+	changes := make(map[string]*ResourceDiff)
+	for _, diff := range diffs {
+		changes[diff.Kind+"/"+diff.Name] = diff
+	}
+	assert.Len(t, changes, 4)
+	assert.Equal(t, DiffAdded, changes["Service/web"].Change)
+	assert.Equal(t, DiffRemoved, changes["ConfigMap/web-legacy"].Change)
+	assert.Equal(t, "ns", changes["ConfigMap/web-legacy"].Namespace)
+
+	deployment := changes["Deployment/web"]
+	assert.Equal(t, DiffModified, deployment.Change)
+	fields := fieldsByPath(deployment.Fields)
+	assert.Len(t, fields, 3)
+	assert.Equal(t, float64(1), fields["spec.replicas"].Old)
+	assert.Equal(t, float64(2), fields["spec.replicas"].New)
+	assert.Equal(t, "nginx:1.27", fields["spec.template.spec.containers[name=web].image"].New)
+	assert.Equal(t, DiffAdded, fields["spec.template.spec.containers[name=web].env[name=DEBUG]"].Type)
+
+	secret := fieldsByPath(changes["Secret/web-auth"].Fields)
+	assert.Equal(t, sensitiveValue, secret["data.password"].Old)
+	assert.Equal(t, sensitiveValue, secret["data.password"].New)
+}
+
+// capability_id: rainbond.helm-release.values-diff
+func TestDiffValues(t *testing.T) {
+	fields := fieldsByPath(DiffValues(
+		map[string]interface{}{"replicaCount": 1, "image": map[string]interface{}{"tag": "1.0"}, "debug": true},
+		map[string]interface{}{"replicaCount": 2, "image": map[string]interface{}{"tag": "1.0"}, "extra": []interface{}{"a"}},
+	))
+	assert.Len(t, fields, 3)
+	assert.Equal(t, DiffChanged, fields["replicaCount"].Type)
+	assert.Equal(t, DiffRemoved, fields["debug"].Type)
+	assert.Equal(t, DiffAdded, fields["extra"].Type)
+}
+
+// capability_id: rainbond.helm-release.live-drift
+func TestDiffLive(t *testing.T) {
+	resources, err := ParseManifest(diffTestOldManifest, "ns")
+	assert.NoError(t, err)
+	deployment := resources[0]
+	for _, res := range resources {
+		if res.Kind == "Deployment" {
+			deployment = res
+		}
+	}
+	live := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":            "web",
+			"labels":          map[string]interface{}{"app": "web", "extra": "x"},
+			"resourceVersion": "12",
+		},
+		"spec": map[string]interface{}{
+			"replicas":             int64(1),
+			"revisionHistoryLimit": int64(10),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":            "web",
+							"image":           "nginx:1.25",
+							"imagePullPolicy": "IfNotPresent",
+							"resources":       map[string]interface{}{"limits": map[string]interface{}{"cpu": "0.5"}},
+							"env":             []interface{}{map[string]interface{}{"name": "MODE", "value": "prod"}},
+						},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{"replicas": int64(1)},
+	}
+	assert.Nil(t, DiffLive(deployment, live), "defaults, status and equal quantities are not drifts")
+
+	spec := live["spec"].(map[string]interface{})
+	spec["replicas"] = int64(3)
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	container["env"] = []interface{}{map[string]interface{}{"name": "MODE", "value": "debug"}, map[string]interface{}{"name": "HOTFIX", "value": "1"}}
+	diff := DiffLive(deployment, live)
+	if assert.NotNil(t, diff) {
+		fields := fieldsByPath(diff.Fields)
+		assert.Len(t, fields, 3)
+		assert.Equal(t, float64(3), fields["spec.replicas"].New)
+		assert.Equal(t, "debug", fields["spec.template.spec.containers[name=web].env[name=MODE].value"].New)
+		assert.Equal(t, DiffAdded, fields["spec.template.spec.containers[name=web].env[name=HOTFIX]"].Type)
+	}
+}
@@ -507,7 +507,7 @@ func (h *Helm) installLoadedChart(chartPath, releaseName, version, valuesYAML st
 	return client.Run(chartLoaded, vals)
 }
 
-func (h *Helm) upgradeLoadedChart(chartPath, releaseName, version, valuesYAML string) (*release.Release, error) {
+func (h *Helm) upgradeLoadedChart(chartPath, releaseName, version, valuesYAML string, dryRun bool) (*release.Release, error) {
 	vals, err := parseValuesYAML(valuesYAML)
 	if err != nil {
 		return nil, err
@@ -520,6 +520,7 @@ func (h *Helm) upgradeLoadedChart(chartPath, releaseName, version, valuesYAML st
 	client := action.NewUpgrade(h.cfg)
 	client.Namespace = h.namespace
 	client.Version = version
+	client.DryRun = dryRun
 	return client.Run(releaseName, chartLoaded, vals)
 }
 
@@ -592,12 +593,18 @@ func (h *Helm) UpgradeFromReference(chartRef, repoURL, version, releaseName, val
 	if err != nil {
 		return nil, err
 	}
-	return h.upgradeLoadedChart(cp, releaseName, resolvedVersion, valuesYAML)
+	return h.upgradeLoadedChart(cp, releaseName, resolvedVersion, valuesYAML, false)
 }
 
 // UpgradeFromChartPath upgrades a release from a local directory or archive path.
 func (h *Helm) UpgradeFromChartPath(chartPath, version, releaseName, valuesYAML string) (*release.Release, error) {
-	return h.upgradeLoadedChart(chartPath, releaseName, version, valuesYAML)
+	return h.upgradeLoadedChart(chartPath, releaseName, version, valuesYAML, false)
+}
+
+// DryRunUpgradeFromChartPath renders the upgrade of a release from a local directory or
+// archive path without applying it, the manifest of the returned release is the target state.
+func (h *Helm) DryRunUpgradeFromChartPath(chartPath, version, releaseName, valuesYAML string) (*release.Release, error) {
+	return h.upgradeLoadedChart(chartPath, releaseName, version, valuesYAML, true)
 }
 
 // UpgradeFromRepo upgrades a release from a configured Helm repo directly.
@@ -142,6 +142,7 @@ func Timeout(timeout time.Duration) func(next http.Handler) http.Handler {
 func requestTimeout(path string, defaultTimeout time.Duration) time.Duration {
 	if strings.Contains(path, "logs") ||
 		strings.Contains(path, "/platform/backend/plugins/") ||
+		strings.Contains(path, "/terminal-sessions/") ||
 		isEventLogStreamPath(path) {
 		return time.Hour
 	}
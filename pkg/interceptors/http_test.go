@@ -43,6 +43,11 @@ func TestRequestTimeout(t *testing.T) {
 			path: "/v2/events/event-1/stream",
 			want: time.Hour,
 		},
+		{
+			name: "terminal recording playback uses the long timeout",
+			path: "/v2/tenants/team/terminal-sessions/session-1/recording",
+			want: time.Hour,
+		},
 		{
 			name: "event log history keeps the default timeout",
 			path: "/v2/events/event-1/log",
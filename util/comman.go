@@ -464,12 +464,22 @@ func CmdExec(args string) (string, error) {
 
 // Zip zip compressing source dir to target file
 func Zip(source, target string) error {
+	_, err := ZipModifiedSince(source, target, time.Time{})
+	return err
+}
+
+// ZipModifiedSince zip compressing the files of source dir which are modified
+// after since to target file, all the files are compressed if since is zero.
+// The directories are always kept, and the names of all the entries found in
+// source dir are returned, so that the deleted files can be told apart from
+// the unchanged ones when the archive is applied on an earlier one.
+func ZipModifiedSince(source, target string, since time.Time) ([]string, error) {
 	if err := CheckAndCreateDir(filepath.Dir(target)); err != nil {
-		return err
+		return nil, err
 	}
 	zipfile, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer zipfile.Close()
 
@@ -478,7 +488,7 @@ func Zip(source, target string) error {
 
 	info, err := os.Stat(source)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
 	var baseDir string
@@ -486,8 +496,13 @@ func Zip(source, target string) error {
 		baseDir = filepath.Base(source)
 	}
 
-	filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+	var names []string
+	err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			// the file is removed while walking, the data of a running app is changing
+			if os.IsNotExist(err) {
+				return nil
+			}
 			return err
 		}
 		header, err := zip.FileInfoHeader(info)
@@ -502,6 +517,10 @@ func Zip(source, target string) error {
 		} else {
 			header.Method = zip.Deflate
 		}
+		names = append(names, header.Name)
+		if !info.IsDir() && !since.IsZero() && !fileChangeTime(info).After(since) {
+			return nil
+		}
 		//set file uid and
 		elem := reflect.ValueOf(info.Sys()).Elem()
 		uid := elem.FieldByName("Uid").Uint()
@@ -517,6 +536,9 @@ func Zip(source, target string) error {
 		}
 		file, err := os.OpenFile(path, os.O_RDONLY, 0)
 		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
 			return err
 		}
 		defer file.Close()
@@ -524,7 +546,32 @@ func Zip(source, target string) error {
 		return err
 	})
 
-	return err
+	return names, err
+}
+
+// fileChangeTime returns the later one of the modification time and the status
+// change time of the file, the status change time can not be set by the user,
+// so a file copied with its original modification time is also found changed.
+func fileChangeTime(info os.FileInfo) time.Time {
+	changed := info.ModTime()
+	sys := reflect.ValueOf(info.Sys())
+	if sys.Kind() != reflect.Ptr || sys.IsNil() {
+		return changed
+	}
+	for _, name := range []string{"Ctim", "Ctimespec"} {
+		ctim := sys.Elem().FieldByName(name)
+		if !ctim.IsValid() {
+			continue
+		}
+		sec, nsec := ctim.FieldByName("Sec"), ctim.FieldByName("Nsec")
+		if sec.IsValid() && nsec.IsValid() {
+			if ctime := time.Unix(sec.Int(), nsec.Int()); ctime.After(changed) {
+				return ctime
+			}
+		}
+		break
+	}
+	return changed
 }
 
 // UnTar -
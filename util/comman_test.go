@@ -101,6 +101,63 @@ func TestZipLegacy(t *testing.T) {
 	}
 }
 
+// capability_id: rainbond.util.zip-archive
+func TestZipModifiedSince(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "data")
+	target := filepath.Join(root, "data.zip")
+	if err := os.MkdirAll(filepath.Join(source, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(source, "sub", "old.txt")
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// the file timestamps come from a coarse clock
+	time.Sleep(50 * time.Millisecond)
+	since := time.Now()
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(source, "new.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// copied with the original modification time
+	copied := filepath.Join(source, "copied.txt")
+	if err := os.WriteFile(copied, []byte("copied"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(copied, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := ZipModifiedSince(source, target, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	want := []string{"data/", "data/copied.txt", "data/new.txt", "data/sub/", "data/sub/old.txt"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected names %v, got %v", want, names)
+	}
+
+	reader, err := zip.OpenReader(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var entries []string
+	for _, file := range reader.File {
+		entries = append(entries, file.Name)
+	}
+	sort.Strings(entries)
+	want = []string{"data/", "data/copied.txt", "data/new.txt", "data/sub/"}
+	if strings.Join(entries, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected only the modified files and the directories %v, got %v", want, entries)
+	}
+}
+
 // capability_id: rainbond.util.system.identity-and-template-helpers
 // capability_id: rainbond.util.version-timestamp
 func TestCreateVersionByTimeLegacy(t *testing.T) {
@@ -20,6 +20,8 @@ package util
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"math"
@@ -89,6 +91,26 @@ func createSmallFileHash(sourceFile, hashfile *os.File) error {
 	return nil
 }
 
+//ReaderSHA256 compute the sha256 checksum and the size of the content of reader
+func ReaderSHA256(reader io.Reader) (string, int64, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, reader)
+	if err != nil {
+		return "", size, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+//FileSHA256 compute the sha256 checksum and the size of sourcefile
+func FileSHA256(sourceFile string) (string, int64, error) {
+	file, err := os.Open(sourceFile)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	return ReaderSHA256(file)
+}
+
 //CreateHashString create hash string
 func CreateHashString(source string) (hashstr string, err error) {
 	md5h := md5.New()
@@ -57,3 +57,21 @@ func TestCreateHashString(t *testing.T) {
 		t.Fatalf("unexpected hash: %q", hash)
 	}
 }
+
+// capability_id: rainbond.util.core-helpers.hash-ip-string-uuid
+func TestFileSHA256(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "hashtest")
+	if err := os.WriteFile(source, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, size, err := FileSHA256(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 3 || sum != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("unexpected sha256 %s of %d bytes", sum, size)
+	}
+	if _, _, err := FileSHA256(source + ".missing"); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
@@ -3,23 +3,22 @@ package kubeblocks
 
 import "strings"
 
+// ParseComponentName splits the k8s component name of a kubeblocks component,
+// which is named <cluster>-<component>, into the cluster and the component name
+func ParseComponentName(k8sComponentName string) (clusterName, componentName string) {
+	lastDashIndex := strings.LastIndex(k8sComponentName, "-")
+	if lastDashIndex != -1 && lastDashIndex < len(k8sComponentName)-1 {
+		return k8sComponentName[:lastDashIndex], k8sComponentName[lastDashIndex+1:]
+	}
+	return "", ""
+}
+
 // GenerateKubeBlocksSelector generate selector for kubeblocks component
 func GenerateKubeBlocksSelector(k8sComponentName string) map[string]string {
-	var (
-		peer = map[string]bool{
-			"rabbitmq": true,
-		}
-		clusterName   string
-		componentName string
-	)
-
-	if k8sComponentName != "" {
-		lastDashIndex := strings.LastIndex(k8sComponentName, "-")
-		if lastDashIndex != -1 && lastDashIndex < len(k8sComponentName)-1 {
-			clusterName = k8sComponentName[:lastDashIndex]
-			componentName = k8sComponentName[lastDashIndex+1:]
-		}
+	peer := map[string]bool{
+		"rabbitmq": true,
 	}
+	clusterName, componentName := ParseComponentName(k8sComponentName)
 
 	selector := map[string]string{
 		"app.kubernetes.io/instance":        clusterName,
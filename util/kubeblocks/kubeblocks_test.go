@@ -17,3 +17,14 @@ func TestGenerateKubeBlocksSelector(t *testing.T) {
 		t.Fatalf("did not expect role selector for peer component, got %#v", selector)
 	}
 }
+
+// capability_id: rainbond.kubeblocks.parse-component-name
+func TestParseComponentName(t *testing.T) {
+	cluster, component := ParseComponentName("team-demo-mysql")
+	if cluster != "team-demo" || component != "mysql" {
+		t.Fatalf("unexpected cluster %q component %q", cluster, component)
+	}
+	if cluster, component = ParseComponentName("mysql"); cluster != "" || component != "" {
+		t.Fatalf("expected empty names, got %q %q", cluster, component)
+	}
+}
@@ -68,6 +68,7 @@ var translationMetadata = map[string]string{
 	"Pull code error, repository not found":            "拉取代码发生错误，仓库不存在",
 	"Pull code error, empty remote repository":         "拉取代码发生错误，远程仓库为空",
 	"Code branch does not exist":                       "代码分支不存在",
+	"Checkout code commit failed":                      "检出代码提交失败",
 	"Remote repository requires SSH key configuration": "远程代码库需要配置SSH Key",
 	"Pull code timeout":                                "拉取代码超时",
 	"Create SSH public keys error":                     "创建SSH公钥错误",
@@ -20,6 +20,7 @@ package conversion
 
 import (
 	"fmt"
+	"time"
 	k8sutil "github.com/goodrain/rainbond/util/k8s"
 	utilversion "k8s.io/apimachinery/pkg/util/version"
 
@@ -140,7 +141,7 @@ func newHPABeta2(namespace, kind, name string, labels map[string]string, rule *m
 	}
 
 	spec := autoscalingv2beta2.HorizontalPodAutoscalerSpec{
-		MinReplicas: util.Int32(int32(rule.MinReplicas)),
+		MinReplicas: util.Int32(int32(ScheduledMinReplicas(rule, time.Now()))),
 		MaxReplicas: int32(rule.MaxReplicas),
 		ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
 			Kind:       kind,
@@ -150,22 +151,26 @@ func newHPABeta2(namespace, kind, name string, labels map[string]string, rule *m
 	}
 
 	for _, metric := range metrics {
-		if metric.MetricsType != "resource_metrics" {
-			logrus.Warningf("rule id:  %s; unsupported metric type: %s", rule.RuleID, metric.MetricsType)
-			continue
-		}
-		if metric.MetricTargetValue <= 0 {
+		if metric.MetricsType == model.MetricsTypeResource && metric.MetricTargetValue <= 0 {
 			// TODO: If the target value of cpu and memory is 0, it will not take effect.
-			// TODO: The target value of the custom indicator can be 0.
 			continue
 		}
 
-		ms := createResourceMetricsBeta2(metric)
+		ms, err := createMetricSpecBeta2(metric)
+		if err != nil {
+			logrus.Warningf("rule id:  %s; metric %s: %v", rule.RuleID, metric.MetricsName, err)
+			continue
+		}
 		spec.Metrics = append(spec.Metrics, ms)
 	}
 	if len(spec.Metrics) == 0 {
 		return nil
 	}
+	behavior, err := rule.GetBehavior()
+	if err != nil {
+		logrus.Warningf("rule id:  %s; wrong behavior: %v", rule.RuleID, err)
+	}
+	spec.Behavior = newHPABehaviorBeta2(behavior)
 	hpa.Spec = spec
 
 	return hpa
@@ -242,7 +247,7 @@ func newHPA(namespace, kind, name string, labels map[string]string, rule *model.
 	}
 
 	spec := autoscalingv2.HorizontalPodAutoscalerSpec{
-		MinReplicas: util.Int32(int32(rule.MinReplicas)),
+		MinReplicas: util.Int32(int32(ScheduledMinReplicas(rule, time.Now()))),
 		MaxReplicas: int32(rule.MaxReplicas),
 		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
 			Kind:       kind,
@@ -252,22 +257,26 @@ func newHPA(namespace, kind, name string, labels map[string]string, rule *model.
 	}
 
 	for _, metric := range metrics {
-		if metric.MetricsType != "resource_metrics" {
-			logrus.Warningf("rule id:  %s; unsupported metric type: %s", rule.RuleID, metric.MetricsType)
-			continue
-		}
-		if metric.MetricTargetValue <= 0 {
+		if metric.MetricsType == model.MetricsTypeResource && metric.MetricTargetValue <= 0 {
 			// TODO: If the target value of cpu and memory is 0, it will not take effect.
-			// TODO: The target value of the custom indicator can be 0.
 			continue
 		}
 
-		ms := createResourceMetrics(metric)
+		ms, err := createMetricSpec(metric)
+		if err != nil {
+			logrus.Warningf("rule id:  %s; metric %s: %v", rule.RuleID, metric.MetricsName, err)
+			continue
+		}
 		spec.Metrics = append(spec.Metrics, ms)
 	}
 	if len(spec.Metrics) == 0 {
 		return nil
 	}
+	behavior, err := rule.GetBehavior()
+	if err != nil {
+		logrus.Warningf("rule id:  %s; wrong behavior: %v", rule.RuleID, err)
+	}
+	spec.Behavior = newHPABehavior(behavior)
 	hpa.Spec = spec
 
 	return hpa
@@ -0,0 +1,212 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conversion
+
+import (
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/goodrain/rainbond/db/model"
+)
+
+// metricQuantity returns the target quantity of pods, object and external metrics
+func metricQuantity(metric *model.TenantServiceAutoscalerRuleMetrics) (*resource.Quantity, error) {
+	if metric.MetricTargetQuantity != "" {
+		quantity, err := resource.ParseQuantity(metric.MetricTargetQuantity)
+		if err != nil {
+			return nil, fmt.Errorf("wrong target quantity %s: %v", metric.MetricTargetQuantity, err)
+		}
+		return &quantity, nil
+	}
+	if metric.MetricTargetValue <= 0 {
+		return nil, fmt.Errorf("target value must be greater than 0")
+	}
+	return resource.NewQuantity(int64(metric.MetricTargetValue), resource.DecimalSI), nil
+}
+
+func metricSelector(metric *model.TenantServiceAutoscalerRuleMetrics) (*metav1.LabelSelector, error) {
+	selector, err := metric.GetSelector()
+	if err != nil {
+		return nil, fmt.Errorf("wrong metric selector: %v", err)
+	}
+	if len(selector) == 0 {
+		return nil, nil
+	}
+	return &metav1.LabelSelector{MatchLabels: selector}, nil
+}
+
+func createMetricSpec(metric *model.TenantServiceAutoscalerRuleMetrics) (autoscalingv2.MetricSpec, error) {
+	if metric.MetricsType == model.MetricsTypeResource {
+		return createResourceMetrics(metric), nil
+	}
+	var ms autoscalingv2.MetricSpec
+	quantity, err := metricQuantity(metric)
+	if err != nil {
+		return ms, err
+	}
+	selector, err := metricSelector(metric)
+	if err != nil {
+		return ms, err
+	}
+	identifier := autoscalingv2.MetricIdentifier{Name: metric.MetricsName, Selector: selector}
+	target := autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: quantity}
+	if metric.MetricTargetType == model.MetricTargetTypeValue {
+		target = autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType, Value: quantity}
+	}
+
+	switch metric.MetricsType {
+	case model.MetricsTypePods:
+		ms.Type = autoscalingv2.PodsMetricSourceType
+		ms.Pods = &autoscalingv2.PodsMetricSource{Metric: identifier, Target: target}
+	case model.MetricsTypeObject:
+		object, err := metric.GetDescribedObject()
+		if err != nil || object == nil {
+			return ms, fmt.Errorf("object metrics requires the described object")
+		}
+		ms.Type = autoscalingv2.ObjectMetricSourceType
+		ms.Object = &autoscalingv2.ObjectMetricSource{
+			DescribedObject: autoscalingv2.CrossVersionObjectReference{
+				Kind:       object.Kind,
+				Name:       object.Name,
+				APIVersion: object.APIVersion,
+			},
+			Metric: identifier,
+			Target: target,
+		}
+	case model.MetricsTypeExternal:
+		ms.Type = autoscalingv2.ExternalMetricSourceType
+		ms.External = &autoscalingv2.ExternalMetricSource{Metric: identifier, Target: target}
+	default:
+		return ms, fmt.Errorf("unsupported metric type: %s", metric.MetricsType)
+	}
+	return ms, nil
+}
+
+func createMetricSpecBeta2(metric *model.TenantServiceAutoscalerRuleMetrics) (autoscalingv2beta2.MetricSpec, error) {
+	if metric.MetricsType == model.MetricsTypeResource {
+		return createResourceMetricsBeta2(metric), nil
+	}
+	var ms autoscalingv2beta2.MetricSpec
+	quantity, err := metricQuantity(metric)
+	if err != nil {
+		return ms, err
+	}
+	selector, err := metricSelector(metric)
+	if err != nil {
+		return ms, err
+	}
+	identifier := autoscalingv2beta2.MetricIdentifier{Name: metric.MetricsName, Selector: selector}
+	target := autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.AverageValueMetricType, AverageValue: quantity}
+	if metric.MetricTargetType == model.MetricTargetTypeValue {
+		target = autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.ValueMetricType, Value: quantity}
+	}
+
+	switch metric.MetricsType {
+	case model.MetricsTypePods:
+		ms.Type = autoscalingv2beta2.PodsMetricSourceType
+		ms.Pods = &autoscalingv2beta2.PodsMetricSource{Metric: identifier, Target: target}
+	case model.MetricsTypeObject:
+		object, err := metric.GetDescribedObject()
+		if err != nil || object == nil {
+			return ms, fmt.Errorf("object metrics requires the described object")
+		}
+		ms.Type = autoscalingv2beta2.ObjectMetricSourceType
+		ms.Object = &autoscalingv2beta2.ObjectMetricSource{
+			DescribedObject: autoscalingv2beta2.CrossVersionObjectReference{
+				Kind:       object.Kind,
+				Name:       object.Name,
+				APIVersion: object.APIVersion,
+			},
+			Metric: identifier,
+			Target: target,
+		}
+	case model.MetricsTypeExternal:
+		ms.Type = autoscalingv2beta2.ExternalMetricSourceType
+		ms.External = &autoscalingv2beta2.ExternalMetricSource{Metric: identifier, Target: target}
+	default:
+		return ms, fmt.Errorf("unsupported metric type: %s", metric.MetricsType)
+	}
+	return ms, nil
+}
+
+func newHPABehavior(behavior *model.AutoscalerBehavior) *autoscalingv2.HorizontalPodAutoscalerBehavior {
+	if behavior == nil || (behavior.ScaleUp == nil && behavior.ScaleDown == nil) {
+		return nil
+	}
+	return &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   newHPAScalingRules(behavior.ScaleUp),
+		ScaleDown: newHPAScalingRules(behavior.ScaleDown),
+	}
+}
+
+func newHPAScalingRules(rules *model.AutoscalerScalingRules) *autoscalingv2.HPAScalingRules {
+	if rules == nil {
+		return nil
+	}
+	res := &autoscalingv2.HPAScalingRules{
+		StabilizationWindowSeconds: rules.StabilizationWindowSeconds,
+	}
+	if rules.SelectPolicy != "" {
+		selectPolicy := autoscalingv2.ScalingPolicySelect(rules.SelectPolicy)
+		res.SelectPolicy = &selectPolicy
+	}
+	for _, policy := range rules.Policies {
+		res.Policies = append(res.Policies, autoscalingv2.HPAScalingPolicy{
+			Type:          autoscalingv2.HPAScalingPolicyType(policy.Type),
+			Value:         policy.Value,
+			PeriodSeconds: policy.PeriodSeconds,
+		})
+	}
+	return res
+}
+
+func newHPABehaviorBeta2(behavior *model.AutoscalerBehavior) *autoscalingv2beta2.HorizontalPodAutoscalerBehavior {
+	if behavior == nil || (behavior.ScaleUp == nil && behavior.ScaleDown == nil) {
+		return nil
+	}
+	return &autoscalingv2beta2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   newHPAScalingRulesBeta2(behavior.ScaleUp),
+		ScaleDown: newHPAScalingRulesBeta2(behavior.ScaleDown),
+	}
+}
+
+func newHPAScalingRulesBeta2(rules *model.AutoscalerScalingRules) *autoscalingv2beta2.HPAScalingRules {
+	if rules == nil {
+		return nil
+	}
+	res := &autoscalingv2beta2.HPAScalingRules{
+		StabilizationWindowSeconds: rules.StabilizationWindowSeconds,
+	}
+	if rules.SelectPolicy != "" {
+		selectPolicy := autoscalingv2beta2.ScalingPolicySelect(rules.SelectPolicy)
+		res.SelectPolicy = &selectPolicy
+	}
+	for _, policy := range rules.Policies {
+		res.Policies = append(res.Policies, autoscalingv2beta2.HPAScalingPolicy{
+			Type:          autoscalingv2beta2.HPAScalingPolicyType(policy.Type),
+			Value:         policy.Value,
+			PeriodSeconds: policy.PeriodSeconds,
+		})
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conversion
+
+import (
+	"time"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// scheduleLookback is how far to look back for the last time a schedule fired,
+// a schedule which has not fired in it does not take effect.
+var scheduleLookback = 32 * 24 * time.Hour
+
+// lastFireTime returns the last time the schedule fired before now, zero if it did not fire in scheduleLookback
+func lastFireTime(sched cron.Schedule, now time.Time) time.Time {
+	var last time.Time
+	for next := sched.Next(now.Add(-scheduleLookback)); !next.IsZero() && !next.After(now); next = sched.Next(next) {
+		last = next
+	}
+	return last
+}
+
+// ActiveSchedule returns the schedule of the rule which fired last before now, nil if there is none
+func ActiveSchedule(rule *model.TenantServiceAutoscalerRules, now time.Time) *model.AutoscalerSchedule {
+	schedules, err := rule.GetSchedules()
+	if err != nil {
+		logrus.Warningf("rule id: %s; wrong schedules: %v", rule.RuleID, err)
+		return nil
+	}
+	var active *model.AutoscalerSchedule
+	var activeTime time.Time
+	for i := range schedules {
+		sched, err := schedules[i].Parse()
+		if err != nil {
+			logrus.Warningf("rule id: %s; wrong schedule %s: %v", rule.RuleID, schedules[i].Schedule, err)
+			continue
+		}
+		if last := lastFireTime(sched, now); !last.IsZero() && last.After(activeTime) {
+			active, activeTime = &schedules[i], last
+		}
+	}
+	return active
+}
+
+// ScheduledMinReplicas returns the min replicas of the rule at now. The min replicas
+// of the schedule which fired last takes the place of the one of the rule.
+func ScheduledMinReplicas(rule *model.TenantServiceAutoscalerRules, now time.Time) int {
+	minReplicas := rule.MinReplicas
+	if active := ActiveSchedule(rule, now); active != nil {
+		minReplicas = active.MinReplicas
+	}
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	if rule.MaxReplicas > 0 && minReplicas > rule.MaxReplicas {
+		minReplicas = rule.MaxReplicas
+	}
+	return minReplicas
+}
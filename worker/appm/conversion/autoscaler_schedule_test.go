@@ -0,0 +1,44 @@
+package conversion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/db/model"
+)
+
+// capability_id: rainbond.worker.appm.autoscaler.schedule-min-replicas
+func TestScheduledMinReplicas(t *testing.T) {
+	rule := &model.TenantServiceAutoscalerRules{
+		RuleID:      "rule-1",
+		MinReplicas: 2,
+		MaxReplicas: 8,
+		Schedules: `[{"name":"workday","schedule":"0 8 * * 1-5","timezone":"UTC","min_replicas":6},` +
+			`{"name":"night","schedule":"0 20 * * *","timezone":"UTC","min_replicas":1},` +
+			`{"name":"promotion","schedule":"0 0 1 1 *","timezone":"UTC","min_replicas":20}]`,
+	}
+	tests := []struct {
+		now  string
+		want int
+	}{
+		// Wednesday, after the workday schedule fired
+		{now: "2026-03-04T09:00:00Z", want: 6},
+		// Wednesday night
+		{now: "2026-03-04T21:00:00Z", want: 1},
+		// Saturday morning, the workday schedule does not fire on weekends
+		{now: "2026-03-07T09:00:00Z", want: 1},
+		// the promotion schedule is capped by the max replicas
+		{now: "2026-01-01T01:00:00Z", want: 8},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		if got := ScheduledMinReplicas(rule, now); got != tt.want {
+			t.Errorf("at %s expected min replicas %d, got %d", tt.now, tt.want, got)
+		}
+	}
+
+	rule.Schedules = ""
+	if got := ScheduledMinReplicas(rule, time.Now()); got != 2 {
+		t.Fatalf("expected min replicas of the rule without schedules, got %d", got)
+	}
+}
@@ -73,3 +73,71 @@ func TestNewHPA(t *testing.T) {
 		}
 	}
 }
+
+// capability_id: rainbond.worker.appm.autoscaler.build-hpa-spec
+func TestNewHPAWithCustomMetricsAndBehavior(t *testing.T) {
+	rule := &model.TenantServiceAutoscalerRules{
+		RuleID:      "rule-1",
+		MinReplicas: 2,
+		MaxReplicas: 10,
+		Behavior:    `{"scale_down":{"stabilization_window_seconds":600,"select_policy":"Min","policies":[{"type":"Pods","value":1,"period_seconds":60}]}}`,
+	}
+	metrics := []*model.TenantServiceAutoscalerRuleMetrics{
+		{
+			MetricsType:          model.MetricsTypePods,
+			MetricsName:          "http_requests_per_second",
+			MetricTargetType:     model.MetricTargetTypeAverageValue,
+			MetricTargetQuantity: "500m",
+		},
+		{
+			MetricsType:       model.MetricsTypeExternal,
+			MetricsName:       "queue_messages_ready",
+			MetricTargetType:  model.MetricTargetTypeValue,
+			MetricTargetValue: 30,
+			MetricSelector:    `{"queue":"orders"}`,
+		},
+		{
+			MetricsType:       model.MetricsTypeObject,
+			MetricsName:       "requests_per_second",
+			MetricTargetType:  model.MetricTargetTypeValue,
+			MetricTargetValue: 2000,
+			DescribedObject:   `{"kind":"Ingress","name":"main-route","api_version":"networking.k8s.io/v1"}`,
+		},
+		{
+			MetricsType:       model.MetricsTypeObject,
+			MetricsName:       "without_object",
+			MetricTargetType:  model.MetricTargetTypeValue,
+			MetricTargetValue: 1,
+		},
+	}
+
+	hpa := newHPA("ns", "Deployment", "web", nil, rule, metrics)
+	if assert.NotNil(t, hpa) && assert.Len(t, hpa.Spec.Metrics, 3) {
+		pods := hpa.Spec.Metrics[0]
+		assert.Equal(t, "Pods", string(pods.Type))
+		assert.Equal(t, "500m", pods.Pods.Target.AverageValue.String())
+
+		external := hpa.Spec.Metrics[1]
+		assert.Equal(t, "External", string(external.Type))
+		assert.Equal(t, "Value", string(external.External.Target.Type))
+		assert.Equal(t, "30", external.External.Target.Value.String())
+		assert.Equal(t, map[string]string{"queue": "orders"}, external.External.Metric.Selector.MatchLabels)
+
+		object := hpa.Spec.Metrics[2]
+		assert.Equal(t, "Object", string(object.Type))
+		assert.Equal(t, "main-route", object.Object.DescribedObject.Name)
+		assert.Equal(t, "networking.k8s.io/v1", object.Object.DescribedObject.APIVersion)
+	}
+	if assert.NotNil(t, hpa.Spec.Behavior) && assert.NotNil(t, hpa.Spec.Behavior.ScaleDown) {
+		assert.Nil(t, hpa.Spec.Behavior.ScaleUp)
+		assert.Equal(t, int32(600), *hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds)
+		assert.Equal(t, "Min", string(*hpa.Spec.Behavior.ScaleDown.SelectPolicy))
+		assert.Equal(t, "Pods", string(hpa.Spec.Behavior.ScaleDown.Policies[0].Type))
+	}
+
+	beta2 := newHPABeta2("ns", "Deployment", "web", nil, rule, metrics)
+	if assert.NotNil(t, beta2) {
+		assert.Len(t, beta2.Spec.Metrics, 3)
+		assert.NotNil(t, beta2.Spec.Behavior)
+	}
+}
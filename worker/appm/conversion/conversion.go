@@ -42,6 +42,8 @@ func init() {
 	RegistConversion("TenantServiceAutoscaler", TenantServiceAutoscaler)
 	//step4 conv service monitor
 	RegistConversion("TenantServiceMonitor", TenantServiceMonitor)
+	//step5 conv mesh authorization policies
+	RegistConversion("TenantServiceMeshPolicy", TenantServiceMeshPolicy)
 }
 
 // Conversion conversion function
@@ -86,6 +88,7 @@ func InitAppService(dryRun bool, dbmanager db.Manager, serviceID string, configs
 	}
 	if app != nil {
 		appService.AppServiceBase.GovernanceMode = app.GovernanceMode
+		appService.AppServiceBase.ZeroTrust = app.ZeroTrust
 		appService.AppServiceBase.K8sApp = app.K8sApp
 	}
 	if dryRun {
@@ -145,6 +148,7 @@ func InitCacheAppService(dbm db.Manager, serviceID, creatorID string) (*v1.AppSe
 	}
 	if app != nil {
 		appService.AppServiceBase.GovernanceMode = app.GovernanceMode
+		appService.AppServiceBase.ZeroTrust = app.ZeroTrust
 		appService.AppServiceBase.K8sApp = app.K8sApp
 	}
 
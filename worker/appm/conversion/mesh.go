@@ -0,0 +1,23 @@
+package conversion
+
+import (
+	"github.com/goodrain/rainbond/api/handler/app_governance_mode/adaptor"
+	"github.com/goodrain/rainbond/db"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+)
+
+// TenantServiceMeshPolicy translates the dependencies of the component into the
+// authorization policies of the governance mode and, for zero trust applications,
+// a NetworkPolicy. The policies are applied as component manifests.
+func TenantServiceMeshPolicy(as *v1.AppService, dbmanager db.Manager) error {
+	component, err := adaptor.NewMeshComponent(dbmanager, as.GetNamespace(), as.GetK8sWorkloadName(), as.ServiceID)
+	if err != nil {
+		return err
+	}
+	policies := adaptor.BuildComponentPolicies(as.GovernanceMode, as.ZeroTrust, component)
+	if len(policies) == 0 {
+		return nil
+	}
+	as.SetManifests(append(as.GetManifests(), policies...))
+	return nil
+}
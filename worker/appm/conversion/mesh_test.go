@@ -0,0 +1,131 @@
+package conversion
+
+import (
+	"testing"
+
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	typesv1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type meshTestManager struct {
+	db.Manager
+	ports     []*dbmodel.TenantServicesPort
+	relations []*dbmodel.TenantServiceRelation
+	accounts  map[string]string
+}
+
+func (m meshTestManager) TenantServicesPortDao() dbdao.TenantServicesPortDao {
+	return meshTestPortDao{ports: m.ports}
+}
+
+func (m meshTestManager) TenantServiceRelationDao() dbdao.TenantServiceRelationDao {
+	return meshTestRelationDao{relations: m.relations}
+}
+
+func (m meshTestManager) ComponentK8sAttributeDao() dbdao.ComponentK8sAttributeDao {
+	return meshTestAttributeDao{accounts: m.accounts}
+}
+
+type meshTestPortDao struct {
+	dbdao.TenantServicesPortDao
+	ports []*dbmodel.TenantServicesPort
+}
+
+func (d meshTestPortDao) GetPortsByServiceID(string) ([]*dbmodel.TenantServicesPort, error) {
+	return d.ports, nil
+}
+
+type meshTestRelationDao struct {
+	dbdao.TenantServiceRelationDao
+	relations []*dbmodel.TenantServiceRelation
+}
+
+func (d meshTestRelationDao) GetTenantServiceRelationsByDependServiceID(string) ([]*dbmodel.TenantServiceRelation, error) {
+	return d.relations, nil
+}
+
+type meshTestAttributeDao struct {
+	dbdao.ComponentK8sAttributeDao
+	accounts map[string]string
+}
+
+func (d meshTestAttributeDao) GetByComponentIDAndName(componentID, _ string) (*dbmodel.ComponentK8sAttributes, error) {
+	if sa, ok := d.accounts[componentID]; ok {
+		return &dbmodel.ComponentK8sAttributes{AttributeValue: sa}, nil
+	}
+	return nil, nil
+}
+
+// capability_id: rainbond.worker.conversion.mesh-authorization-policy
+func TestTenantServiceMeshPolicyAppendsManifests(t *testing.T) {
+	outer := true
+	manager := meshTestManager{
+		ports:     []*dbmodel.TenantServicesPort{{ContainerPort: 8080}, {ContainerPort: 80, IsOuterService: &outer}},
+		relations: []*dbmodel.TenantServiceRelation{{ServiceID: "web", DependServiceID: "api"}},
+		accounts:  map[string]string{"web": "web-sa"},
+	}
+	newApp := func(mode string) *typesv1.AppService {
+		as := &typesv1.AppService{AppServiceBase: typesv1.AppServiceBase{
+			ServiceID:        "api",
+			K8sApp:           "demo",
+			K8sComponentName: "api",
+			GovernanceMode:   mode,
+		}}
+		as.SetTenant(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}})
+		return as
+	}
+
+	as := newApp(dbmodel.GovernanceModeKubernetesNativeService)
+	if err := TenantServiceMeshPolicy(as, manager); err != nil {
+		t.Fatal(err)
+	}
+	if len(as.GetManifests()) != 0 {
+		t.Fatalf("native mode should not create policies, got %d", len(as.GetManifests()))
+	}
+
+	as = newApp(dbmodel.GovernanceModeKubernetesNativeService)
+	as.ZeroTrust = true
+	if err := TenantServiceMeshPolicy(as, manager); err != nil {
+		t.Fatal(err)
+	}
+	if len(as.GetManifests()) != 1 || as.GetManifests()[0].GetName() != "demo-api-zero-trust" {
+		t.Fatalf("zero trust should create a NetworkPolicy, got %v", as.GetManifests())
+	}
+
+	as = newApp(dbmodel.GovernanceModeLinkerdServiceMesh)
+	if err := TenantServiceMeshPolicy(as, manager); err != nil {
+		t.Fatal(err)
+	}
+	var identities []string
+	for _, m := range as.GetManifests() {
+		if m.GetKind() == "MeshTLSAuthentication" {
+			identities, _, _ = unstructured.NestedStringSlice(m.Object, "spec", "identities")
+		}
+	}
+	if len(identities) != 1 || identities[0] != "web-sa.team.serviceaccount.identity.linkerd.cluster.local" {
+		t.Fatalf("unexpected identities %v", identities)
+	}
+	if account, _ := createServiceAccountName(as, manager); account != "rbd-mesh-api" {
+		t.Fatalf("expected the dedicated service account in linkerd mode, got %q", account)
+	}
+	var hasAccount bool
+	for _, m := range as.GetManifests() {
+		hasAccount = hasAccount || (m.GetKind() == "ServiceAccount" && m.GetName() == "rbd-mesh-api")
+	}
+	if !hasAccount {
+		t.Fatalf("expected the dedicated service account manifest, got %v", as.GetManifests())
+	}
+
+	as = newApp(dbmodel.GovernanceModeCiliumServiceMesh)
+	if err := TenantServiceMeshPolicy(as, manager); err != nil {
+		t.Fatal(err)
+	}
+	if len(as.GetManifests()) != 1 || as.GetManifests()[0].GetName() != "demo-api" {
+		t.Fatalf("expected one cilium policy, got %v", as.GetManifests())
+	}
+}
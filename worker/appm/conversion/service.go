@@ -44,6 +44,7 @@ import (
 	dbmodel "github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/util"
 	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/goodrain/rainbond/worker/grayrelease"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 	yaml "gopkg.in/yaml.v2"
@@ -446,6 +447,15 @@ func getInjectLabels(as *v1.AppService) map[string]string {
 	return injectLabels
 }
 
+func getInjectAnnotations(as *v1.AppService) map[string]string {
+	mode, err := adaptor.NewAppGoveranceModeHandler(as.GovernanceMode, nil)
+	if err != nil {
+		logrus.Warningf("getInjectAnnotations failed: %v", err)
+		return nil
+	}
+	return mode.GetInjectAnnotations()
+}
+
 func CreateHttproute(k8sApp, namespace, appID string, service []*dbmodel.TenantServicesPort, component *dbmodel.TenantServices, gatewayClient *v1beta1.GatewayV1beta1Client) (string, error) {
 	name := k8sApp + "-" + component.K8sComponentName
 	labels := make(map[string]string)
@@ -517,10 +527,14 @@ func CreateRollout(k8sApp, namespace string, service []*dbmodel.TenantServicesPo
 	labels := make(map[string]string)
 	labels["app_id"] = gray.AppID
 	labels["component_id"] = component.ServiceID
+	labels[grayrelease.LabelGrayRelease] = "true"
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	var hostname, httpRouteName string
-	if gray.EntryComponentID == component.ServiceID {
+	switch {
+	case gray.EntryRouteKind == grayrelease.RouteKindApisixRoute:
+		// ApisixRoute 的流量由 worker 中的灰度分析器切分
+	case gray.EntryComponentID == component.ServiceID:
 		httpRouteName = gray.EntryHTTPRoute
 		httproute, err := gatewayClient.HTTPRoutes(namespace).Get(ctx, gray.EntryHTTPRoute, metav1.GetOptions{})
 		if err != nil {
@@ -529,7 +543,7 @@ func CreateRollout(k8sApp, namespace string, service []*dbmodel.TenantServicesPo
 		if httproute.Spec.Hostnames != nil && len(httproute.Spec.Hostnames) > 0 {
 			hostname = string(httproute.Spec.Hostnames[0])
 		}
-	} else {
+	default:
 		err := gatewayClient.HTTPRoutes(namespace).Delete(ctx, k8sApp+"-"+component.K8sComponentName, metav1.DeleteOptions{})
 		if err != nil && !k8serror.IsNotFound(err) {
 			return err
@@ -557,13 +571,22 @@ func CreateRollout(k8sApp, namespace string, service []*dbmodel.TenantServicesPo
 	}
 	labels["hostname"] = hostname
 	var serviceName string
+	var port int32
 	if service != nil && len(service) > 0 {
 		serviceName = service[0].K8sServiceName
+		port = int32(service[0].ContainerPort)
+	}
+	spec, config, err := HandleRolloutSpec(gray, serviceName, httpRouteName, name)
+	if err != nil {
+		return err
 	}
-	spec, err := HandleRolloutSpec(gray, serviceName, httpRouteName, name)
+	config.ServiceAlias = component.ServiceAlias
+	config.Port = port
+	configByte, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
+	annotations[grayrelease.AnnotationConfig] = string(configByte)
 
 	rollout := &v1alpha1.Rollout{
 		TypeMeta: metav1.TypeMeta{
@@ -585,42 +608,36 @@ func CreateRollout(k8sApp, namespace string, service []*dbmodel.TenantServicesPo
 	return nil
 }
 
-func HandleRolloutSpec(gray *dbmodel.AppGrayRelease, serviceName, httpRouteName, deployName string) (v1alpha1.RolloutSpec, error) {
-	var matches []gatewayv1beta1.HTTPRouteMatch
+// HandleRolloutSpec 生成 rollout 的 spec 以及灰度分析器使用的配置
+func HandleRolloutSpec(gray *dbmodel.AppGrayRelease, serviceName, httpRouteName, deployName string) (v1alpha1.RolloutSpec, *grayrelease.Config, error) {
 	var flowEntryRule [][]apimodel.FlowEntryRule
-	err := json.Unmarshal([]byte(gray.FlowEntryRule), &flowEntryRule)
-	if err != nil {
-		return v1alpha1.RolloutSpec{}, err
-	}
-	for _, rules := range flowEntryRule {
-		var headers []gatewayv1beta1.HTTPHeaderMatch
-		for _, header := range rules {
-			headerType := gatewayv1beta1.HeaderMatchType(header.HeaderType)
-			headers = append(headers, gatewayv1beta1.HTTPHeaderMatch{
-				//Name:  v1beta1.HTTPRouteInterface(header.HeaderKey),
-				Type:  &headerType,
-				Value: header.HeaderValue,
-			})
+	if gray.FlowEntryRule != "" {
+		if err := json.Unmarshal([]byte(gray.FlowEntryRule), &flowEntryRule); err != nil {
+			return v1alpha1.RolloutSpec{}, nil, err
 		}
-		httpRouteMatch := gatewayv1beta1.HTTPRouteMatch{Headers: headers}
-		matches = append(matches, httpRouteMatch)
 	}
-	var grayStrategy []int32
-	err = json.Unmarshal([]byte(gray.GrayStrategy), &grayStrategy)
+	grayStrategy, err := grayrelease.ParseGrayStrategy(gray.GrayStrategy)
 	if err != nil {
-		return v1alpha1.RolloutSpec{}, err
-	}
-	var steps []v1alpha1.CanaryStep
-	for _, step := range grayStrategy {
-		weight := step
-		// TODO: matches
-		steps = append(steps, v1alpha1.CanaryStep{
-			Weight: &weight,
-			//Matches: matches,
-		})
+		return v1alpha1.RolloutSpec{}, nil, err
+	}
+	strategy := gray.GrayStrategyType
+	if strategy != apimodel.GrayStrategyBlueGreen {
+		strategy = apimodel.GrayStrategyCanary
+	}
+	routeKind := grayrelease.RouteKindHTTPRoute
+	if gray.EntryRouteKind == grayrelease.RouteKindApisixRoute {
+		routeKind = grayrelease.RouteKindApisixRoute
+	}
+	config := &grayrelease.Config{
+		Strategy:  strategy,
+		RouteKind: routeKind,
+		RouteName: httpRouteName,
+		Service:   serviceName,
+		Matches:   flowEntryRule,
+		Steps:     grayrelease.NormalizeSteps(strategy, grayStrategy),
 	}
 	var trafficRoutings []*v1alpha1.TrafficRouting
-	if serviceName != "" && httpRouteName != "" {
+	if routeKind == grayrelease.RouteKindHTTPRoute && serviceName != "" && httpRouteName != "" {
 		routeName := httpRouteName
 		trafficRoutings = append(trafficRoutings, &v1alpha1.TrafficRouting{
 			Service: serviceName,
@@ -637,9 +654,9 @@ func HandleRolloutSpec(gray *dbmodel.AppGrayRelease, serviceName, httpRouteName,
 		},
 		Strategy: v1alpha1.RolloutStrategy{
 			Canary: &v1alpha1.CanaryStrategy{
-				Steps:           steps,
+				Steps:           grayrelease.CanarySteps(config.Steps, routeKind),
 				TrafficRoutings: trafficRoutings,
 			},
 		},
-	}, nil
+	}, config, nil
 }
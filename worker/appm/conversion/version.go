@@ -36,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
+	"github.com/goodrain/rainbond/api/handler/app_governance_mode/adaptor"
 	"github.com/goodrain/rainbond/builder/sources"
 
 	"github.com/goodrain/rainbond/builder"
@@ -1435,6 +1436,13 @@ func createPodAnnotations(as *v1.AppService, dbmanager db.Manager) (map[string]s
 			logrus.Debugf("custom set vm fixed pod ip for calico, service %s, annotation: %s", as.ServiceID, fixedPodIPAnnotation)
 			annotations["cni.projectcalico.org/ipAddrs"] = fixedPodIPAnnotation
 		}
+	} else {
+		// the mesh proxy must not be injected into the virt-launcher pod
+		for k, v := range getInjectAnnotations(as) {
+			if _, ok := annotations[k]; !ok {
+				annotations[k] = v
+			}
+		}
 	}
 	return annotations, nil
 }
@@ -2105,6 +2113,10 @@ func createServiceAccountName(as *v1.AppService, dbmanager db.Manager) (string,
 	if sa != nil {
 		serviceAN = sa.AttributeValue
 	}
+	// linkerd authorizes by service account, so every component needs its own identity
+	if serviceAN == "" && as.GovernanceMode == model.GovernanceModeLinkerdServiceMesh {
+		serviceAN = adaptor.LinkerdServiceAccountName(as.ServiceID)
+	}
 	return serviceAN, nil
 }
 
@@ -0,0 +1,214 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/goodrain/rainbond/db/model"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// consulWaitTime is the max duration of a consul blocking query.
+var consulWaitTime = 30 * time.Second
+
+// consulMinWait is the min interval between two consul queries, a query returns
+// at once when the index is missing or the service changes frequently.
+var consulMinWait = time.Second
+
+type consul struct {
+	cli *http.Client
+
+	sid        string
+	servers    []*url.URL
+	service    string
+	datacenter string
+	token      string
+	index      uint64
+
+	updateCh *channels.RingChannel
+	stopCh   chan struct{}
+	cache    *endpointCache
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string `json:"ID"`
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+	Checks []struct {
+		Status string `json:"Status"`
+	} `json:"Checks"`
+}
+
+// NewConsul creates a new Discorvery which implemeted by the consul health api.
+// cfg.Key is the name of the service in consul, and cfg.Password is used as the ACL token.
+func NewConsul(cfg *model.ThirdPartySvcDiscoveryCfg,
+	updateCh *channels.RingChannel,
+	stopCh chan struct{}) Discoverier {
+	return &consul{
+		sid:        cfg.ServiceID,
+		servers:    serverURLs(cfg.Servers),
+		service:    cfg.Key,
+		datacenter: cfg.Datacenter,
+		token:      cfg.Password,
+		updateCh:   updateCh,
+		stopCh:     stopCh,
+		cache:      newEndpointCache(cfg.ServiceID),
+	}
+}
+
+// Connect creates the http client of consul.
+func (c *consul) Connect() error {
+	if len(c.servers) == 0 {
+		return fmt.Errorf("consul servers can not be empty")
+	}
+	if c.service == "" {
+		return fmt.Errorf("consul service name can not be empty")
+	}
+	c.cli = &http.Client{Timeout: consulWaitTime + 10*time.Second}
+	return nil
+}
+
+// Fetch fetches the instances of the service from consul.
+func (c *consul) Fetch() ([]*v1.RbdEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	eps, index, err := c.query(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.index = index
+	c.cache.reset(eps)
+	return eps, nil
+}
+
+// Close does nothing, the queries of consul are plain http requests.
+func (c *consul) Close() error {
+	return nil
+}
+
+// Watch watches the instances of the service with consul blocking queries.
+func (c *consul) Watch() {
+	logrus.Infof("Start watching third-party endpoints. Consul service: %s", c.service)
+	poll("consul", consulMinWait, c.cache, c.updateCh, c.stopCh, func(ctx context.Context) ([]*v1.RbdEndpoint, error) {
+		eps, index, err := c.query(ctx, c.index)
+		if err != nil {
+			return nil, err
+		}
+		// the index must be reset if it goes backwards, see consul blocking queries
+		if index < c.index {
+			index = 0
+		}
+		c.index = index
+		return eps, nil
+	})
+}
+
+func (c *consul) query(ctx context.Context, index uint64) ([]*v1.RbdEndpoint, uint64, error) {
+	if c.cli == nil {
+		return nil, 0, fmt.Errorf("can't fetching data from consul without http client")
+	}
+	var lastErr error
+	for _, server := range c.servers {
+		eps, newIndex, err := c.queryServer(ctx, server, index)
+		if err == nil {
+			return eps, newIndex, nil
+		}
+		if ctx.Err() != nil {
+			return nil, 0, err
+		}
+		lastErr = err
+	}
+	return nil, 0, fmt.Errorf("error fetching endpoints from consul: %v", lastErr)
+}
+
+func (c *consul) queryServer(ctx context.Context, server *url.URL, index uint64) ([]*v1.RbdEndpoint, uint64, error) {
+	u := *server
+	u.Path = "/v1/health/service/" + url.PathEscape(c.service)
+	query := url.Values{}
+	if c.datacenter != "" {
+		query.Set("dc", c.datacenter)
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(consulWaitTime.Seconds())))
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	res, err := c.cli.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consul %s response status %d", server.Host, res.StatusCode)
+	}
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("error parsing the data from consul: %v", err)
+	}
+	newIndex, _ := strconv.ParseUint(res.Header.Get("X-Consul-Index"), 10, 64)
+	return c.endpoints(entries), newIndex, nil
+}
+
+func (c *consul) endpoints(entries []consulServiceEntry) []*v1.RbdEndpoint {
+	var res []*v1.RbdEndpoint
+	for _, entry := range entries {
+		ip := entry.Service.Address
+		if ip == "" {
+			ip = entry.Node.Address
+		}
+		// warning checks keep the instance online, the same as consul dns does
+		online := true
+		for _, check := range entry.Checks {
+			if check.Status == "critical" {
+				online = false
+				break
+			}
+		}
+		res = append(res, &v1.RbdEndpoint{
+			UUID:     entry.Node.Node + "/" + entry.Service.ID,
+			Sid:      c.sid,
+			IP:       ip,
+			Port:     entry.Service.Port,
+			IsOnline: online,
+		})
+	}
+	return res
+}
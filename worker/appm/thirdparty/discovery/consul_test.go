@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/goodrain/rainbond/db/model"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+)
+
+// capability_id: rainbond.worker.appm.discovery.consul
+func TestConsulFetchAndWatch(t *testing.T) {
+	var blocking int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/web" || r.URL.Query().Get("dc") != "dc1" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		if r.Header.Get("X-Consul-Token") != "token" {
+			t.Errorf("expected acl token")
+		}
+		status := "passing"
+		if r.URL.Query().Get("index") != "" {
+			atomic.StoreInt32(&blocking, 1)
+			status = "critical"
+		}
+		w.Header().Set("X-Consul-Index", "12")
+		w.Write([]byte(`[{"Node":{"Node":"node1","Address":"10.0.0.1"},` +
+			`"Service":{"ID":"web-1","Address":"","Port":8080},` +
+			`"Checks":[{"Status":"passing"},{"Status":"` + status + `"}]}]`))
+	}))
+	defer server.Close()
+
+	updateCh := channels.NewRingChannel(8)
+	stopCh := make(chan struct{})
+	d, err := NewDiscoverier(&model.ThirdPartySvcDiscoveryCfg{
+		Type:       model.DiscorveryTypeConsul.String(),
+		ServiceID:  "svc-1",
+		Servers:    server.URL,
+		Key:        "web",
+		Datacenter: "dc1",
+		Password:   "token",
+	}, updateCh, stopCh)
+	if err != nil {
+		t.Fatalf("new discoverier: %v", err)
+	}
+	if err := d.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	eps, err := d.Fetch()
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(eps) != 1 || eps[0].IP != "10.0.0.1" || eps[0].Port != 8080 || !eps[0].IsOnline || eps[0].Sid != "svc-1" {
+		t.Fatalf("unexpected endpoints %+v", eps)
+	}
+
+	go d.Watch()
+	defer close(stopCh)
+	select {
+	case obj := <-updateCh.Out():
+		event := obj.(Event)
+		if event.Type != UnhealthyEvent || event.Obj.(*v1.RbdEndpoint).UUID != "node1/web-1" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected unhealthy event")
+	}
+	if atomic.LoadInt32(&blocking) != 1 {
+		t.Fatal("expected blocking query with index")
+	}
+}
+
+// capability_id: rainbond.worker.appm.discovery.consul
+func TestConsulWatchWaitsWithoutIndex(t *testing.T) {
+	defer func(old time.Duration) { consulMinWait = old }(consulMinWait)
+	consulMinWait = 100 * time.Millisecond
+	var queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxy in front of consul may drop the X-Consul-Index header
+		atomic.AddInt32(&queries, 1)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	stopCh := make(chan struct{})
+	d, err := NewDiscoverier(&model.ThirdPartySvcDiscoveryCfg{
+		Type:    model.DiscorveryTypeConsul.String(),
+		Servers: server.URL,
+		Key:     "web",
+	}, channels.NewRingChannel(8), stopCh)
+	if err != nil {
+		t.Fatalf("new discoverier: %v", err)
+	}
+	if err := d.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	go d.Watch()
+	time.Sleep(450 * time.Millisecond)
+	close(stopCh)
+	if n := atomic.LoadInt32(&queries); n > 6 {
+		t.Fatalf("expected the watch to wait between queries, got %d queries", n)
+	}
+}
@@ -14,7 +14,7 @@ func TestNewDiscoverierUnsupportedType(t *testing.T) {
 	defer close(stopCh)
 
 	_, err := NewDiscoverier(&model.ThirdPartySvcDiscoveryCfg{
-		Type: "consul",
+		Type: "zookeeper",
 	}, updateCh, stopCh)
 	if err == nil {
 		t.Fatal("expected unsupported discovery type error")
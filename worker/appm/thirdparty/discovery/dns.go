@@ -0,0 +1,165 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/goodrain/rainbond/db/model"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// dnsPollInterval is the interval to resolve the srv records again.
+var dnsPollInterval = 10 * time.Second
+
+type dnsResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+type dnsSRV struct {
+	resolver dnsResolver
+
+	sid         string
+	name        string
+	nameservers []string
+
+	updateCh *channels.RingChannel
+	stopCh   chan struct{}
+	cache    *endpointCache
+}
+
+// NewDNS creates a new Discorvery which implemeted by dns srv records.
+// cfg.Key is the full name of the srv records, such as _http._tcp.foo.example.com,
+// and cfg.Servers are the optional nameservers, the system resolver is used without them.
+func NewDNS(cfg *model.ThirdPartySvcDiscoveryCfg,
+	updateCh *channels.RingChannel,
+	stopCh chan struct{}) Discoverier {
+	var nameservers []string
+	for _, server := range strings.Split(cfg.Servers, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		nameservers = append(nameservers, server)
+	}
+	return &dnsSRV{
+		sid:         cfg.ServiceID,
+		name:        cfg.Key,
+		nameservers: nameservers,
+		updateCh:    updateCh,
+		stopCh:      stopCh,
+		cache:       newEndpointCache(cfg.ServiceID),
+	}
+}
+
+// Connect creates the resolver, the nameservers are used in turn.
+func (d *dnsSRV) Connect() error {
+	if d.name == "" {
+		return fmt.Errorf("srv record name can not be empty")
+	}
+	if d.resolver != nil {
+		return nil
+	}
+	if len(d.nameservers) == 0 {
+		d.resolver = net.DefaultResolver
+		return nil
+	}
+	var next uint32
+	d.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			server := d.nameservers[int(atomic.AddUint32(&next, 1))%len(d.nameservers)]
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+	return nil
+}
+
+// Fetch resolves the srv records to endpoints.
+func (d *dnsSRV) Fetch() ([]*v1.RbdEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	eps, err := d.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.cache.reset(eps)
+	return eps, nil
+}
+
+// Close does nothing.
+func (d *dnsSRV) Close() error {
+	return nil
+}
+
+// Watch resolves the srv records periodically. dns has no health state,
+// a target disappears from the records when it is unhealthy.
+func (d *dnsSRV) Watch() {
+	logrus.Infof("Start watching third-party endpoints. SRV records: %s", d.name)
+	poll("dns", dnsPollInterval, d.cache, d.updateCh, d.stopCh, d.resolve)
+}
+
+func (d *dnsSRV) resolve(ctx context.Context) ([]*v1.RbdEndpoint, error) {
+	if d.resolver == nil {
+		return nil, fmt.Errorf("can't resolving srv records without resolver")
+	}
+	_, records, err := d.resolver.LookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving srv records %s: %v", d.name, err)
+	}
+	var eps []*v1.RbdEndpoint
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		var ips []string
+		if ip := net.ParseIP(target); ip != nil {
+			ips = []string{ip.String()}
+		} else {
+			addrs, err := d.resolver.LookupIPAddr(ctx, target)
+			if err != nil {
+				logrus.Warningf("error resolving srv target %s: %v", target, err)
+				continue
+			}
+			for _, addr := range addrs {
+				ips = append(ips, addr.IP.String())
+			}
+		}
+		for _, ip := range ips {
+			eps = append(eps, &v1.RbdEndpoint{
+				UUID:     fmt.Sprintf("%s/%s:%d", target, ip, record.Port),
+				Sid:      d.sid,
+				IP:       ip,
+				Port:     int(record.Port),
+				IsOnline: true,
+			})
+		}
+	}
+	return eps, nil
+}
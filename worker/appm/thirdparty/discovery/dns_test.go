@@ -0,0 +1,59 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/goodrain/rainbond/db/model"
+)
+
+type fakeResolver struct {
+	srv []*net.SRV
+	ips map[string][]net.IPAddr
+}
+
+func (f *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return name, f.srv, nil
+}
+
+func (f *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return f.ips[host], nil
+}
+
+// capability_id: rainbond.worker.appm.discovery.dns-srv
+func TestDNSFetchResolvesTargets(t *testing.T) {
+	d := NewDNS(&model.ThirdPartySvcDiscoveryCfg{
+		ServiceID: "svc-1",
+		Key:       "_http._tcp.web.example.com",
+		Servers:   "10.0.0.53",
+	}, nil, nil).(*dnsSRV)
+	if len(d.nameservers) != 1 || d.nameservers[0] != "10.0.0.53:53" {
+		t.Fatalf("unexpected nameservers %v", d.nameservers)
+	}
+	d.resolver = &fakeResolver{
+		srv: []*net.SRV{
+			{Target: "web-1.example.com.", Port: 8080},
+			{Target: "10.0.0.9", Port: 9090},
+		},
+		ips: map[string][]net.IPAddr{
+			"web-1.example.com": {{IP: net.ParseIP("10.0.0.1")}, {IP: net.ParseIP("10.0.0.2")}},
+		},
+	}
+	if err := d.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	eps, err := d.Fetch()
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(eps) != 3 {
+		t.Fatalf("expected 3 endpoints, got %d", len(eps))
+	}
+	if eps[0].IP != "10.0.0.1" || eps[0].Port != 8080 || eps[2].IP != "10.0.0.9" || eps[2].Port != 9090 {
+		t.Fatalf("unexpected endpoints %+v %+v", eps[0], eps[2])
+	}
+	if eps[0].UUID == eps[1].UUID {
+		t.Fatal("expected unique endpoint uuid")
+	}
+}
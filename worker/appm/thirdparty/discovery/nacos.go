@@ -0,0 +1,255 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/goodrain/rainbond/db/model"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// nacosPollInterval is the interval to fetch the instances from nacos.
+var nacosPollInterval = 5 * time.Second
+
+type nacos struct {
+	cli *http.Client
+
+	sid       string
+	servers   []*url.URL
+	service   string
+	group     string
+	namespace string
+	username  string
+	password  string
+
+	// tokenLock guards token, Fetch and Watch query nacos concurrently
+	tokenLock sync.Mutex
+	token     string
+
+	updateCh *channels.RingChannel
+	stopCh   chan struct{}
+	cache    *endpointCache
+}
+
+type nacosInstanceList struct {
+	Hosts []struct {
+		InstanceID string `json:"instanceId"`
+		IP         string `json:"ip"`
+		Port       int    `json:"port"`
+		Healthy    bool   `json:"healthy"`
+		Enabled    bool   `json:"enabled"`
+	} `json:"hosts"`
+}
+
+// NewNacos creates a new Discorvery which implemeted by the nacos open api.
+// cfg.Key is the name of the service, cfg.Group and cfg.Namespace are the
+// group and the namespace id of the service in nacos.
+func NewNacos(cfg *model.ThirdPartySvcDiscoveryCfg,
+	updateCh *channels.RingChannel,
+	stopCh chan struct{}) Discoverier {
+	servers := serverURLs(cfg.Servers)
+	for _, server := range servers {
+		if server.Path == "" || server.Path == "/" {
+			server.Path = "/nacos"
+		}
+	}
+	return &nacos{
+		sid:       cfg.ServiceID,
+		servers:   servers,
+		service:   cfg.Key,
+		group:     cfg.Group,
+		namespace: cfg.Namespace,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		updateCh:  updateCh,
+		stopCh:    stopCh,
+		cache:     newEndpointCache(cfg.ServiceID),
+	}
+}
+
+// Connect creates the http client of nacos, and logins when the auth of nacos is enabled.
+func (n *nacos) Connect() error {
+	if len(n.servers) == 0 {
+		return fmt.Errorf("nacos servers can not be empty")
+	}
+	if n.service == "" {
+		return fmt.Errorf("nacos service name can not be empty")
+	}
+	n.cli = &http.Client{Timeout: 10 * time.Second}
+	if n.username == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return n.login(ctx)
+}
+
+// Fetch fetches the instances of the service from nacos.
+func (n *nacos) Fetch() ([]*v1.RbdEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	eps, err := n.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n.cache.reset(eps)
+	return eps, nil
+}
+
+// Close does nothing, the queries of nacos are plain http requests.
+func (n *nacos) Close() error {
+	return nil
+}
+
+// Watch polls the instances of the service from nacos.
+func (n *nacos) Watch() {
+	logrus.Infof("Start watching third-party endpoints. Nacos service: %s", n.service)
+	poll("nacos", nacosPollInterval, n.cache, n.updateCh, n.stopCh, n.query)
+}
+
+func (n *nacos) login(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("username", n.username)
+	form.Set("password", n.password)
+	var lastErr error
+	for _, server := range n.servers {
+		u := *server
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/auth/login"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res, err := n.cli.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var result struct {
+			AccessToken string `json:"accessToken"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("error login nacos: response status %d", res.StatusCode)
+		}
+		if err != nil {
+			return fmt.Errorf("error login nacos: %v", err)
+		}
+		n.tokenLock.Lock()
+		n.token = result.AccessToken
+		n.tokenLock.Unlock()
+		return nil
+	}
+	return fmt.Errorf("error login nacos: %v", lastErr)
+}
+
+func (n *nacos) accessToken() string {
+	n.tokenLock.Lock()
+	defer n.tokenLock.Unlock()
+	return n.token
+}
+
+func (n *nacos) query(ctx context.Context) ([]*v1.RbdEndpoint, error) {
+	if n.cli == nil {
+		return nil, fmt.Errorf("can't fetching data from nacos without http client")
+	}
+	var lastErr error
+	for _, server := range n.servers {
+		eps, err := n.queryServer(ctx, server)
+		if err == nil {
+			return eps, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("error fetching endpoints from nacos: %v", lastErr)
+}
+
+func (n *nacos) queryServer(ctx context.Context, server *url.URL) ([]*v1.RbdEndpoint, error) {
+	u := *server
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/ns/instance/list"
+	query := url.Values{}
+	query.Set("serviceName", n.service)
+	if n.group != "" {
+		query.Set("groupName", n.group)
+	}
+	if n.namespace != "" {
+		query.Set("namespaceId", n.namespace)
+	}
+	query.Set("healthyOnly", "false")
+	if token := n.accessToken(); token != "" {
+		query.Set("accessToken", token)
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := n.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusForbidden && n.username != "" {
+		// the access token is expired, login again for the next query
+		if err := n.login(ctx); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("nacos access token is expired")
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nacos %s response status %d", server.Host, res.StatusCode)
+	}
+	var list nacosInstanceList
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error parsing the data from nacos: %v", err)
+	}
+	var eps []*v1.RbdEndpoint
+	for _, host := range list.Hosts {
+		// a disabled instance is taken offline by the operator, not a health state
+		if !host.Enabled {
+			continue
+		}
+		uuid := host.InstanceID
+		if uuid == "" {
+			uuid = fmt.Sprintf("%s#%d", host.IP, host.Port)
+		}
+		eps = append(eps, &v1.RbdEndpoint{
+			UUID:     uuid,
+			Sid:      n.sid,
+			IP:       host.IP,
+			Port:     host.Port,
+			IsOnline: host.Healthy,
+		})
+	}
+	return eps, nil
+}
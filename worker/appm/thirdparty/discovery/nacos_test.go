@@ -0,0 +1,73 @@
+package discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eapache/channels"
+	"github.com/goodrain/rainbond/db/model"
+)
+
+// capability_id: rainbond.worker.appm.discovery.nacos
+func TestNacosLoginAndFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nacos/v1/auth/login":
+			if r.FormValue("username") != "nacos" || r.FormValue("password") != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"accessToken":"token","tokenTtl":18000}`))
+		case "/nacos/v1/ns/instance/list":
+			query := r.URL.Query()
+			if query.Get("accessToken") != "token" || query.Get("serviceName") != "order" ||
+				query.Get("groupName") != "prod" || query.Get("namespaceId") != "ns-1" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"hosts":[` +
+				`{"instanceId":"i-1","ip":"10.0.0.1","port":8080,"healthy":true,"enabled":true},` +
+				`{"instanceId":"i-2","ip":"10.0.0.2","port":8080,"healthy":false,"enabled":true},` +
+				`{"ip":"10.0.0.3","port":8080,"healthy":true,"enabled":false}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	d := NewNacos(&model.ThirdPartySvcDiscoveryCfg{
+		ServiceID: "svc-1",
+		Servers:   server.URL,
+		Key:       "order",
+		Group:     "prod",
+		Namespace: "ns-1",
+		Username:  "nacos",
+		Password:  "secret",
+	}, channels.NewRingChannel(8), stopCh)
+	if err := d.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	eps, err := d.Fetch()
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(eps) != 2 {
+		t.Fatalf("expected disabled instance skipped, got %d endpoints", len(eps))
+	}
+	if eps[0].UUID != "i-1" || !eps[0].IsOnline || eps[1].UUID != "i-2" || eps[1].IsOnline {
+		t.Fatalf("unexpected endpoints %+v %+v", eps[0], eps[1])
+	}
+}
+
+// capability_id: rainbond.worker.appm.discovery.nacos
+func TestNacosConnectRequiresService(t *testing.T) {
+	d := NewNacos(&model.ThirdPartySvcDiscoveryCfg{Servers: "127.0.0.1:8848"}, nil, nil)
+	if err := d.Connect(); err == nil {
+		t.Fatal("expected error without service name")
+	}
+	if d.(*nacos).servers[0].String() != "http://127.0.0.1:8848/nacos" {
+		t.Fatalf("unexpected server %s", d.(*nacos).servers[0])
+	}
+}
@@ -0,0 +1,160 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eapache/channels"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// retryInterval is the interval to fetch again after a failed fetch.
+var retryInterval = 5 * time.Second
+
+// endpointCache holds the endpoints last fetched from a service registry,
+// every new fetch is compared with it to produce the events.
+type endpointCache struct {
+	sid     string
+	records map[string]*v1.RbdEndpoint
+	lock    sync.Mutex
+}
+
+func newEndpointCache(sid string) *endpointCache {
+	return &endpointCache{
+		sid:     sid,
+		records: make(map[string]*v1.RbdEndpoint),
+	}
+}
+
+// reset replaces the cached endpoints without producing events.
+func (c *endpointCache) reset(eps []*v1.RbdEndpoint) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.records = make(map[string]*v1.RbdEndpoint, len(eps))
+	for _, ep := range eps {
+		c.records[ep.UUID] = ep
+	}
+}
+
+// diff replaces the cached endpoints and returns the events between the
+// cached and the given endpoints.
+func (c *endpointCache) diff(eps []*v1.RbdEndpoint) []Event {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var events []Event
+	records := make(map[string]*v1.RbdEndpoint, len(eps))
+	for _, ep := range eps {
+		records[ep.UUID] = ep
+		old, ok := c.records[ep.UUID]
+		if !ok {
+			events = append(events, Event{Type: CreateEvent, Obj: ep})
+			if !ep.IsOnline {
+				events = append(events, Event{Type: UnhealthyEvent, Obj: ep})
+			}
+			continue
+		}
+		if old.IP != ep.IP || old.Port != ep.Port {
+			events = append(events, Event{Type: UpdateEvent, Obj: ep})
+		}
+		if old.IsOnline != ep.IsOnline {
+			if ep.IsOnline {
+				events = append(events, Event{Type: HealthEvent, Obj: ep})
+			} else {
+				events = append(events, Event{Type: UnhealthyEvent, Obj: ep})
+			}
+		}
+	}
+	for uuid, old := range c.records {
+		if _, ok := records[uuid]; !ok {
+			events = append(events, Event{Type: DeleteEvent, Obj: &v1.RbdEndpoint{
+				UUID: uuid,
+				Sid:  c.sid,
+				IP:   old.IP,
+				Port: old.Port,
+			}})
+		}
+	}
+	c.records = records
+	return events
+}
+
+// poll fetches the endpoints again and again until stopCh is closed, and
+// sends the changes of the endpoints to updateCh. fetch is called with a
+// context which is canceled when stopCh is closed, so that a blocking
+// query can return in time.
+func poll(name string, interval time.Duration, cache *endpointCache,
+	updateCh *channels.RingChannel, stopCh chan struct{},
+	fetch func(ctx context.Context) ([]*v1.RbdEndpoint, error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		wait := interval
+		eps, err := fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.Warningf("error fetching endpoints from %s: %v", name, err)
+			wait = retryInterval
+		} else {
+			for _, event := range cache.diff(eps) {
+				updateCh.In() <- event
+			}
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// serverURLs parses the comma separated servers, http is the default scheme.
+func serverURLs(servers string) []*url.URL {
+	var res []*url.URL
+	for _, server := range strings.Split(servers, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		if !strings.Contains(server, "://") {
+			server = "http://" + server
+		}
+		u, err := url.Parse(server)
+		if err != nil {
+			logrus.Warningf("ignore wrong server address %s: %v", server, err)
+			continue
+		}
+		res = append(res, u)
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package discovery
+
+import (
+	"testing"
+
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+)
+
+// capability_id: rainbond.worker.appm.discovery.registry-diff
+func TestEndpointCacheDiff(t *testing.T) {
+	cache := newEndpointCache("svc-1")
+	cache.reset([]*v1.RbdEndpoint{
+		{UUID: "a", IP: "10.0.0.1", Port: 80, IsOnline: true},
+		{UUID: "b", IP: "10.0.0.2", Port: 80, IsOnline: true},
+		{UUID: "c", IP: "10.0.0.3", Port: 80, IsOnline: false},
+	})
+	events := cache.diff([]*v1.RbdEndpoint{
+		{UUID: "a", IP: "10.0.0.1", Port: 8080, IsOnline: true},
+		{UUID: "b", IP: "10.0.0.2", Port: 80, IsOnline: false},
+		{UUID: "c", IP: "10.0.0.3", Port: 80, IsOnline: true},
+		{UUID: "d", IP: "10.0.0.4", Port: 80, IsOnline: false},
+	})
+	got := make(map[string][]EventType)
+	for _, event := range events {
+		ep := event.Obj.(*v1.RbdEndpoint)
+		got[ep.UUID] = append(got[ep.UUID], event.Type)
+	}
+	want := map[string][]EventType{
+		"a": {UpdateEvent},
+		"b": {UnhealthyEvent},
+		"c": {HealthEvent},
+		"d": {CreateEvent, UnhealthyEvent},
+	}
+	for uuid, types := range want {
+		if len(got[uuid]) != len(types) {
+			t.Fatalf("endpoint %s: expected events %v, got %v", uuid, types, got[uuid])
+		}
+		for i := range types {
+			if got[uuid][i] != types[i] {
+				t.Fatalf("endpoint %s: expected events %v, got %v", uuid, types, got[uuid])
+			}
+		}
+	}
+
+	events = cache.diff([]*v1.RbdEndpoint{{UUID: "a", IP: "10.0.0.1", Port: 8080, IsOnline: true}})
+	if len(events) != 3 {
+		t.Fatalf("expected 3 delete events, got %+v", events)
+	}
+	for _, event := range events {
+		ep := event.Obj.(*v1.RbdEndpoint)
+		if event.Type != DeleteEvent || ep.Sid != "svc-1" || ep.IP == "" {
+			t.Fatalf("unexpected delete event %+v", ep)
+		}
+	}
+}
+
+// capability_id: rainbond.worker.appm.discovery.registry-diff
+func TestServerURLs(t *testing.T) {
+	urls := serverURLs("127.0.0.1:8500, https://consul.example.com ,")
+	if len(urls) != 2 {
+		t.Fatalf("expected 2 servers, got %d", len(urls))
+	}
+	if urls[0].String() != "http://127.0.0.1:8500" || urls[1].String() != "https://consul.example.com" {
+		t.Fatalf("unexpected servers %s %s", urls[0], urls[1])
+	}
+}
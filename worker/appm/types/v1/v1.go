@@ -125,9 +125,11 @@ type AppServiceBase struct {
 	IsWindowsService bool
 	CreaterID        string
 	//depend all service id
-	Dependces          []string
-	ExtensionSet       map[string]string
-	GovernanceMode     string
+	Dependces      []string
+	ExtensionSet   map[string]string
+	GovernanceMode string
+	// ZeroTrust only the declared consumers can reach the component
+	ZeroTrust          bool
 	K8sApp             string
 	K8sComponentName   string
 	DryRun             bool
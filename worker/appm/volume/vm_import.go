@@ -22,6 +22,8 @@ import (
 const (
 	vmDiskImportSourceTypeRegistry     = "registry"
 	vmDiskImportSourceTypeHTTPArtifact = "http-artifact"
+	// vmDiskImportSourceTypeSnapshot clones the disk from a VolumeSnapshot, used by the vm clone
+	vmDiskImportSourceTypeSnapshot = "snapshot"
 
 	VMArtifactImageAnnotation   = "rainbond.com/vm-artifact-image"
 	VMArtifactServiceAnnotation = "rainbond.com/vm-artifact-service"
@@ -34,16 +36,27 @@ const (
 var ErrInvalidVMRegistryImport = errors.New("invalid VM registry import")
 
 type vmDiskImportConfig struct {
-	VolumeName    string   `json:"volume_name"`
-	DiskKey       string   `json:"disk_key"`
-	DiskName      string   `json:"disk_name"`
-	ImageURL      string   `json:"image_url"`
-	SourceURI     string   `json:"source_uri"`
-	Format        string   `json:"format"`
-	Checksum      string   `json:"checksum"`
-	SourceType    string   `json:"source_type"`
-	CertConfigMap string   `json:"-"`
-	ExtraHeaders  []string `json:"-"`
+	VolumeName         string   `json:"volume_name"`
+	DiskKey            string   `json:"disk_key"`
+	DiskName           string   `json:"disk_name"`
+	ImageURL           string   `json:"image_url"`
+	SourceURI          string   `json:"source_uri"`
+	Format             string   `json:"format"`
+	Checksum           string   `json:"checksum"`
+	SourceType         string   `json:"source_type"`
+	SourceNamespace    string   `json:"source_namespace,omitempty"`
+	SourceName         string   `json:"source_name,omitempty"`
+	CertConfigMap      string   `json:"cert_configmap,omitempty"`
+	SecretExtraHeaders []string `json:"secret_extra_headers,omitempty"`
+	ExtraHeaders       []string `json:"-"`
+}
+
+// hasSource reports whether the config points to a disk source
+func (c vmDiskImportConfig) hasSource() bool {
+	if c.SourceType == vmDiskImportSourceTypeSnapshot {
+		return c.SourceNamespace != "" && c.SourceName != ""
+	}
+	return c.ImageURL != ""
 }
 
 func loadVMDiskImportConfigs(serviceID string, dbmanager db.Manager) (map[string]vmDiskImportConfig, error) {
@@ -75,7 +88,7 @@ func parseVMDiskImportConfigs(raw string) (map[string]vmDiskImportConfig, error)
 			if key == "" {
 				key = item.DiskKey
 			}
-			if key == "" || item.ImageURL == "" {
+			if key == "" || !item.hasSource() {
 				continue
 			}
 			keyed[key] = item
@@ -93,7 +106,7 @@ func normalizeVMDiskImportConfigs(configs map[string]vmDiskImportConfig) map[str
 		if volumeName == "" {
 			volumeName = key
 		}
-		if volumeName == "" || cfg.ImageURL == "" {
+		if volumeName == "" || !cfg.hasSource() {
 			continue
 		}
 		cfg.VolumeName = volumeName
@@ -356,9 +369,10 @@ func buildVMDiskImportDataVolumeTemplate(claim *corev1.PersistentVolumeClaim, la
 	)
 	source := &cdiv1.DataVolumeSource{
 		HTTP: &cdiv1.DataVolumeSourceHTTP{
-			URL:           cfg.ImageURL,
-			CertConfigMap: cfg.CertConfigMap,
-			ExtraHeaders:  cfg.ExtraHeaders,
+			URL:                cfg.ImageURL,
+			CertConfigMap:      cfg.CertConfigMap,
+			ExtraHeaders:       cfg.ExtraHeaders,
+			SecretExtraHeaders: cfg.SecretExtraHeaders,
 		},
 	}
 	templateAnnotations := annotations
@@ -375,6 +389,13 @@ func buildVMDiskImportDataVolumeTemplate(claim *corev1.PersistentVolumeClaim, la
 				PullMethod: &pullMethod,
 			},
 		}
+	case vmDiskImportSourceTypeSnapshot:
+		source = &cdiv1.DataVolumeSource{
+			Snapshot: &cdiv1.DataVolumeSourceSnapshot{
+				Namespace: cfg.SourceNamespace,
+				Name:      cfg.SourceName,
+			},
+		}
 	case vmDiskImportSourceTypeHTTPArtifact:
 		artifactImage := normalizeVMArtifactImageURL(cfg.ImageURL)
 		artifactService := vmArtifactServiceName(claim.Name)
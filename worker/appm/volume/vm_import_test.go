@@ -173,6 +173,35 @@ func TestBuildVMDiskImportDataVolumeTemplate(t *testing.T) {
 	}
 }
 
+// capability_id: rainbond.vm-import.snapshot-clone-source
+func TestBuildVMSnapshotCloneDataVolumeTemplate(t *testing.T) {
+	raw := `{"disk":{"source_type":"snapshot","source_namespace":"source-ns","source_name":"vmsnapshot-disk"},"data-1":{"source_type":"snapshot"}}`
+	configs, err := parseVMDiskImportConfigs(raw)
+	if err != nil {
+		t.Fatalf("expected imports to parse: %v", err)
+	}
+	if _, ok := configs["data-1"]; ok {
+		t.Fatal("expected snapshot import without source to be dropped")
+	}
+	cfg, ok := configs["disk"]
+	if !ok {
+		t.Fatal("expected disk import config")
+	}
+
+	claim := &corev1.PersistentVolumeClaim{}
+	claim.Name = "manual-2"
+	template, err := buildVMDiskImportDataVolumeTemplate(claim, map[string]string{"service_id": "svc-2"}, map[string]string{"volume_name": "disk"}, cfg)
+	if err != nil {
+		t.Fatalf("build snapshot data volume template: %v", err)
+	}
+	if template.Spec.Source == nil || template.Spec.Source.Snapshot == nil {
+		t.Fatalf("expected snapshot source, got %#v", template.Spec.Source)
+	}
+	if template.Spec.Source.Snapshot.Namespace != "source-ns" || template.Spec.Source.Snapshot.Name != "vmsnapshot-disk" {
+		t.Fatalf("unexpected snapshot source %#v", template.Spec.Source.Snapshot)
+	}
+}
+
 func TestBuildVMRegistryImportDataVolumeTemplate(t *testing.T) {
 	storageClassName := "nfs-storage"
 	volumeMode := corev1.PersistentVolumeFilesystem
@@ -94,7 +94,7 @@ func (t *TaskManager) Do() {
 		case <-t.ctx.Done():
 			return
 		default:
-			data, err := t.client.Dequeue(t.ctx, &pb.DequeueRequest{Topic: client.WorkerTopic, ClientHost: hostname + "-worker"})
+			data, err := t.client.Dequeue(t.ctx, &pb.DequeueRequest{Topic: client.WorkerTopic, ClientHost: hostname + "-worker", ManualAck: true})
 			if err != nil {
 				if grpc1.ErrorDesc(err) == context.DeadlineExceeded.Error() {
 					continue
@@ -110,34 +110,62 @@ func (t *TaskManager) Do() {
 			transData, err := model.TransTask(data)
 			if err != nil {
 				logrus.Error("trans mq msg data error ", err.Error())
+				t.deadLetter(data, "trans mq msg data error: "+err.Error())
 				continue
 			}
 			rc := t.handleManager.AnalystToExec(transData)
 			if rc != nil && rc != handle.ErrCallback {
 				logrus.Warningf("execute task: %v", rc)
 				TaskError++
+				t.ack(data)
 			} else if rc != nil && rc == handle.ErrCallback {
 				logrus.Errorf("err callback; analyst to exet: %v", rc)
 				ctx, cancel := context.WithCancel(t.ctx)
-				reply, err := t.client.Enqueue(ctx, &pb.EnqueueRequest{
-					Topic:   client.WorkerTopic,
-					Message: data,
+				reply, err := t.client.Nack(ctx, &pb.NackRequest{
+					Topic:        client.WorkerTopic,
+					Receipt:      data.Receipt,
+					RequeueDelay: 3,
 				})
 				cancel()
 				logrus.Debugf("retry send task to mq ,reply is %v", reply)
 				if err != nil {
-					logrus.Errorf("enqueue task %v to mq topic %v Error", data, client.WorkerTopic)
+					logrus.Errorf("nack task %v to mq topic %v Error", data, client.WorkerTopic)
 					continue
 				}
 				//if handle is waiting, sleep 3 second
 				time.Sleep(time.Second * 3)
 			} else {
 				TaskNum++
+				t.ack(data)
 			}
 		}
 	}
 }
 
+// deadLetter move the task to the dead-letter topic, it can never be handled
+func (t *TaskManager) deadLetter(task *pb.TaskMessage, reason string) {
+	ctx, cancel := context.WithTimeout(t.ctx, time.Second*5)
+	defer cancel()
+	_, err := t.client.Nack(ctx, &pb.NackRequest{
+		Topic:      client.WorkerTopic,
+		Receipt:    task.Receipt,
+		Reason:     reason,
+		DeadLetter: true,
+	})
+	if err != nil {
+		logrus.Errorf("dead-letter task %s to mq failure %s", task.TaskId, err.Error())
+	}
+}
+
+// ack remove the task from mq after it has been handled
+func (t *TaskManager) ack(task *pb.TaskMessage) {
+	ctx, cancel := context.WithTimeout(t.ctx, time.Second*5)
+	defer cancel()
+	if _, err := t.client.Ack(ctx, &pb.AckRequest{Topic: client.WorkerTopic, Receipt: task.Receipt}); err != nil {
+		logrus.Errorf("ack task %s to mq failure %s", task.TaskId, err.Error())
+	}
+}
+
 // Stop 停止
 func (t *TaskManager) Stop() error {
 	logrus.Info("discover manager is stoping")
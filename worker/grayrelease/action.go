@@ -0,0 +1,286 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package grayrelease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	apisixversioned "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/client/clientset/versioned"
+	apimodel "github.com/goodrain/rainbond/api/model"
+	kruiseclient "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/openkruise/kruise-api/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/typed/apis/v1beta1"
+)
+
+const (
+	// ActionPromote approves the current step, for blue-green it switches the traffic
+	ActionPromote = "promote"
+	// ActionPause pauses the rollout
+	ActionPause = "pause"
+	// ActionResume resumes a paused rollout
+	ActionResume = "resume"
+	// ActionRollback switches the traffic back and restores the stable version
+	ActionRollback = "rollback"
+)
+
+// ErrNoCanary the rollout has no canary in progress
+var ErrNoCanary = errors.New("the rollout has no canary in progress")
+
+// Operator performs the gray release actions on the rollouts
+type Operator struct {
+	kruise    kruiseclient.Interface
+	clientset kubernetes.Interface
+	apisix    apisixversioned.Interface
+	gateway   gatewayclient.GatewayV1beta1Interface
+}
+
+// NewOperator creates an Operator, apisix and gateway may be nil when not installed
+func NewOperator(kruise kruiseclient.Interface, clientset kubernetes.Interface, apisix apisixversioned.Interface, gateway gatewayclient.GatewayV1beta1Interface) *Operator {
+	return &Operator{kruise: kruise, clientset: clientset, apisix: apisix, gateway: gateway}
+}
+
+// Do performs the action on the rollout
+func (o *Operator) Do(ctx context.Context, namespace, name, action string) error {
+	switch action {
+	case ActionPromote:
+		return o.Promote(ctx, namespace, name)
+	case ActionPause:
+		return o.setPaused(ctx, namespace, name, true)
+	case ActionResume:
+		return o.setPaused(ctx, namespace, name, false)
+	case ActionRollback:
+		return o.Rollback(ctx, namespace, name, "manual rollback")
+	}
+	return fmt.Errorf("unsupported gray release action %q", action)
+}
+
+// Promote approves the paused step so the rollout moves to the next step
+func (o *Operator) Promote(ctx context.Context, namespace, name string) error {
+	rollout, err := o.kruise.RolloutsV1alpha1().Rollouts(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return o.promote(ctx, rollout)
+}
+
+func (o *Operator) promote(ctx context.Context, rollout *v1alpha1.Rollout) error {
+	status := rollout.Status.CanaryStatus
+	if status == nil {
+		return ErrNoCanary
+	}
+	if status.CurrentStepState != v1alpha1.CanaryStepStatePaused {
+		return fmt.Errorf("step %d of rollout %s is %s, only a paused step can be promoted", status.CurrentStepIndex, rollout.Name, status.CurrentStepState)
+	}
+	patch := fmt.Sprintf(`{"status":{"canaryStatus":{"currentStepState":%q,"lastUpdateTime":%q}}}`,
+		v1alpha1.CanaryStepStateReady, time.Now().UTC().Format(time.RFC3339))
+	_, err := o.kruise.RolloutsV1alpha1().Rollouts(rollout.Namespace).Patch(ctx, rollout.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}, "status")
+	return err
+}
+
+func (o *Operator) setPaused(ctx context.Context, namespace, name string, paused bool) error {
+	patch := fmt.Sprintf(`{"spec":{"strategy":{"paused":%t}}}`, paused)
+	_, err := o.kruise.RolloutsV1alpha1().Rollouts(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// Rollback sends all traffic to the stable version at once and reverts the workload
+// to the stable pod template, kruise then finishes the rollout.
+func (o *Operator) Rollback(ctx context.Context, namespace, name, reason string) error {
+	rollout, err := o.kruise.RolloutsV1alpha1().Rollouts(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return o.rollback(ctx, rollout, reason)
+}
+
+func (o *Operator) rollback(ctx context.Context, rollout *v1alpha1.Rollout, reason string) error {
+	status := rollout.Status.CanaryStatus
+	if status == nil {
+		return ErrNoCanary
+	}
+	config, err := ParseConfig(rollout)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		if config.RouteKind == RouteKindApisixRoute {
+			if err := syncApisixRoutes(ctx, o.apisix, rollout.Namespace, config, apisixSplit{Active: true}); err != nil {
+				return err
+			}
+		} else if err := syncHTTPRouteMatches(ctx, o.gateway, rollout.Namespace, config, status.CanaryService, false); err != nil {
+			return err
+		}
+	}
+	if rollout.Spec.ObjectRef.WorkloadRef == nil {
+		return fmt.Errorf("rollout %s has no workload", rollout.Name)
+	}
+	deploy, err := o.clientset.AppsV1().Deployments(rollout.Namespace).Get(ctx, rollout.Spec.ObjectRef.WorkloadRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	stable, err := o.stableReplicaSet(ctx, deploy, rollout.Status.StableRevision, status.PodTemplateHash)
+	if err != nil {
+		return err
+	}
+	template := stable.Spec.Template.DeepCopy()
+	delete(template.Labels, podTemplateHashLabel)
+	deploy.Spec.Template = *template
+	if _, err := o.clientset.AppsV1().Deployments(rollout.Namespace).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("revert deployment %s: %v", deploy.Name, err)
+	}
+	if rollout.Spec.Strategy.Paused {
+		if err := o.setPaused(ctx, rollout.Namespace, rollout.Name, false); err != nil {
+			return err
+		}
+	}
+	return o.recordAction(ctx, rollout, ActionRollback, reason)
+}
+
+// stableReplicaSet finds the replica set of the stable revision, the newest
+// replica set other than the canary one is used when the revision is unknown.
+func (o *Operator) stableReplicaSet(ctx context.Context, deploy *appsv1.Deployment, stableRevision, canaryHash string) (*appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := o.clientset.AppsV1().ReplicaSets(deploy.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var newest *appsv1.ReplicaSet
+	var newestRevision int64 = -1
+	for i := range list.Items {
+		rs := &list.Items[i]
+		if !ownedBy(rs, deploy) {
+			continue
+		}
+		hash := rs.Labels[podTemplateHashLabel]
+		if stableRevision != "" && hash == stableRevision {
+			return rs, nil
+		}
+		if hash == canaryHash {
+			continue
+		}
+		revision, _ := strconv.ParseInt(rs.Annotations["deployment.kubernetes.io/revision"], 10, 64)
+		if revision > newestRevision {
+			newest, newestRevision = rs, revision
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no stable replica set of deployment %s", deploy.Name)
+	}
+	return newest, nil
+}
+
+func ownedBy(rs *appsv1.ReplicaSet, deploy *appsv1.Deployment) bool {
+	for _, ref := range rs.OwnerReferences {
+		if ref.UID == deploy.UID {
+			return true
+		}
+	}
+	return len(rs.OwnerReferences) == 0 && labels.SelectorFromSet(deploy.Spec.Selector.MatchLabels).Matches(labels.Set(rs.Labels))
+}
+
+// recordAction records the action in the analysis status of the rollout
+func (o *Operator) recordAction(ctx context.Context, rollout *v1alpha1.Rollout, action, message string) error {
+	status := analysisStatus(rollout)
+	if canary := rollout.Status.CanaryStatus; canary != nil {
+		status.Revision = canary.PodTemplateHash
+	}
+	status.Action = action
+	status.Message = message
+	status.LastCheck = time.Now().Format(time.RFC3339)
+	return o.saveAnalysisStatus(ctx, rollout, status)
+}
+
+func (o *Operator) saveAnalysisStatus(ctx context.Context, rollout *v1alpha1.Rollout, status *apimodel.GrayAnalysisStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{AnnotationAnalysis: string(data)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = o.kruise.RolloutsV1alpha1().Rollouts(rollout.Namespace).Patch(ctx, rollout.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// analysisStatus reads the analysis status of the current step
+func analysisStatus(rollout *v1alpha1.Rollout) *apimodel.GrayAnalysisStatus {
+	status := &apimodel.GrayAnalysisStatus{}
+	if raw, ok := rollout.Annotations[AnnotationAnalysis]; ok {
+		json.Unmarshal([]byte(raw), status)
+	}
+	return status
+}
+
+// Status returns the gray release status of the rollout
+func (o *Operator) Status(ctx context.Context, namespace, name string) (*apimodel.GrayReleaseModeRet, error) {
+	rollout, err := o.kruise.RolloutsV1alpha1().Rollouts(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return RolloutStatus(rollout), nil
+}
+
+// RolloutStatus converts the rollout to the gray release status
+func RolloutStatus(rollout *v1alpha1.Rollout) *apimodel.GrayReleaseModeRet {
+	ret := &apimodel.GrayReleaseModeRet{
+		RolloutName: rollout.Name,
+		ComponentID: rollout.Labels["component_id"],
+		Hostname:    rollout.Labels["hostname"],
+		Message:     rollout.Status.Message,
+	}
+	if rollout.Spec.Strategy.Canary != nil {
+		ret.Step = len(rollout.Spec.Strategy.Canary.Steps)
+	}
+	if config, err := ParseConfig(rollout); err == nil && config != nil {
+		ret.Strategy = config.Strategy
+	}
+	if status := rollout.Status.CanaryStatus; status != nil {
+		ret.CanaryReplicas = status.CanaryReplicas
+		ret.CanaryReadyReplicas = status.CanaryReadyReplicas
+		ret.CurrentStepIndex = int(status.CurrentStepIndex)
+		ret.CurrentStepState = string(status.CurrentStepState)
+		ret.NewVersion = status.PodTemplateHash
+		if status.Message != "" {
+			ret.Message = status.Message
+		}
+	}
+	ret.OldVersion = rollout.Status.StableRevision
+	if _, ok := rollout.Annotations[AnnotationAnalysis]; ok {
+		ret.Analysis = analysisStatus(rollout)
+	}
+	return ret
+}
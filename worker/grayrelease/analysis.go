@@ -0,0 +1,194 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package grayrelease
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/goodrain/rainbond/api/client/prometheus"
+	apimodel "github.com/goodrain/rainbond/api/model"
+)
+
+const (
+	// OnFailurePause pauses the rollout when the analysis fails
+	OnFailurePause = "pause"
+	// OnFailureRollback rolls the workload back to the stable version when the analysis fails
+	OnFailureRollback = "rollback"
+
+	// AnalysisRunning the analysis is collecting samples
+	AnalysisRunning = "Running"
+	// AnalysisInconclusive there are not enough requests to judge the canary
+	AnalysisInconclusive = "Inconclusive"
+	// AnalysisPassed the step is promoted
+	AnalysisPassed = "Passed"
+	// AnalysisFailed the on failure action is taken
+	AnalysisFailed = "Failed"
+
+	defaultAnalysisInterval = 30 * time.Second
+	defaultAnalysisWindow   = "1m"
+	defaultSuccessCount     = 3
+	defaultFailureLimit     = 1
+)
+
+// The gateway metrics of APISIX, the canary is selected by the upstream pod ips.
+const (
+	defaultErrorRateQuery = `sum(rate(apisix_http_status{code=~"5..",node=~"{{.PodIPs}}"}[{{.Window}}])) / sum(rate(apisix_http_status{node=~"{{.PodIPs}}"}[{{.Window}}])) * 100`
+	defaultLatencyQuery   = `histogram_quantile(0.99, sum(rate(apisix_http_latency_bucket{type="request",node=~"{{.PodIPs}}"}[{{.Window}}])) by (le))`
+	defaultRequestQuery   = `sum(increase(apisix_http_status{node=~"{{.PodIPs}}"}[{{.Window}}]))`
+)
+
+// queryVars the variables of the analysis queries
+type queryVars struct {
+	Namespace     string
+	Service       string
+	CanaryService string
+	PodIPs        string
+	Window        string
+}
+
+// sample the canary metrics of one check
+type sample struct {
+	ErrorRate float64
+	Latency   float64
+	Requests  float64
+}
+
+// analysisInterval the interval between two checks
+func analysisInterval(analysis *apimodel.GrayAnalysis) time.Duration {
+	if d, err := time.ParseDuration(analysis.Interval); err == nil && d > 0 {
+		return d
+	}
+	return defaultAnalysisInterval
+}
+
+// collect queries the canary metrics
+func collect(prom prometheus.Interface, analysis *apimodel.GrayAnalysis, vars queryVars, now time.Time) (sample, error) {
+	if vars.Window == "" {
+		vars.Window = analysis.Window
+	}
+	if vars.Window == "" {
+		vars.Window = defaultAnalysisWindow
+	}
+	var s sample
+	var err error
+	if s.Requests, err = queryScalar(prom, firstNonEmpty(analysis.RequestQuery, defaultRequestQuery), vars, now); err != nil {
+		return s, err
+	}
+	if analysis.MaxErrorRate > 0 {
+		if s.ErrorRate, err = queryScalar(prom, firstNonEmpty(analysis.ErrorRateQuery, defaultErrorRateQuery), vars, now); err != nil {
+			return s, err
+		}
+	}
+	if analysis.MaxLatency > 0 {
+		// apisix 延迟单位为毫秒
+		if s.Latency, err = queryScalar(prom, firstNonEmpty(analysis.LatencyQuery, defaultLatencyQuery), vars, now); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func queryScalar(prom prometheus.Interface, query string, vars queryVars, now time.Time) (float64, error) {
+	expr, err := renderQuery(query, vars)
+	if err != nil {
+		return 0, err
+	}
+	metric := prom.GetMetric(expr, now)
+	if metric.Error != "" {
+		return 0, fmt.Errorf("query %s: %s", expr, metric.Error)
+	}
+	var value float64
+	for _, v := range metric.MetricValues {
+		if v.Sample != nil && !math.IsNaN(v.Sample.Value()) && !math.IsInf(v.Sample.Value(), 0) {
+			value += v.Sample.Value()
+		}
+	}
+	return value, nil
+}
+
+func renderQuery(query string, vars queryVars) (string, error) {
+	tmpl, err := template.New("query").Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("parse analysis query %q: %v", query, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render analysis query %q: %v", query, err)
+	}
+	return buf.String(), nil
+}
+
+// evaluate updates the status with the sample, the returned phase is Passed
+// when the step can be promoted and Failed when the on failure action is due.
+func evaluate(analysis *apimodel.GrayAnalysis, status *apimodel.GrayAnalysisStatus, s sample) string {
+	status.ErrorRate, status.Latency, status.Requests = s.ErrorRate, s.Latency, s.Requests
+	if s.Requests < float64(analysis.MinRequests) {
+		status.Phase = AnalysisInconclusive
+		status.Message = fmt.Sprintf("%.0f requests in the window, at least %d required", s.Requests, analysis.MinRequests)
+		return status.Phase
+	}
+	var breaches []string
+	if analysis.MaxErrorRate > 0 && s.ErrorRate > analysis.MaxErrorRate {
+		breaches = append(breaches, fmt.Sprintf("error rate %.2f%% > %.2f%%", s.ErrorRate, analysis.MaxErrorRate))
+	}
+	if analysis.MaxLatency > 0 && s.Latency > analysis.MaxLatency {
+		breaches = append(breaches, fmt.Sprintf("p99 latency %.0fms > %.0fms", s.Latency, analysis.MaxLatency))
+	}
+	if len(breaches) > 0 {
+		status.Failures++
+		status.Successes = 0
+		status.Message = strings.Join(breaches, ", ")
+		if status.Failures >= positiveOr(analysis.FailureLimit, defaultFailureLimit) {
+			status.Phase = AnalysisFailed
+		} else {
+			status.Phase = AnalysisRunning
+		}
+		return status.Phase
+	}
+	status.Successes++
+	status.Failures = 0
+	status.Message = ""
+	if status.Successes >= positiveOr(analysis.SuccessCount, defaultSuccessCount) {
+		status.Phase = AnalysisPassed
+	} else {
+		status.Phase = AnalysisRunning
+	}
+	return status.Phase
+}
+
+func positiveOr(value, def int) int {
+	if value > 0 {
+		return value
+	}
+	return def
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
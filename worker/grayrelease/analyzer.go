@@ -0,0 +1,229 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package grayrelease
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goodrain/rainbond/api/client/prometheus"
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/openkruise/kruise-api/rollouts/v1alpha1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Analyzer keeps the traffic of the gray releases in line with the current
+// step and runs the metric analysis of the paused steps.
+type Analyzer struct {
+	*Operator
+	prom     prometheus.Interface
+	interval time.Duration
+}
+
+// NewAnalyzer creates an Analyzer, the analysis is skipped when prom is nil
+func NewAnalyzer(operator *Operator, prom prometheus.Interface) *Analyzer {
+	return &Analyzer{Operator: operator, prom: prom, interval: 15 * time.Second}
+}
+
+// Start syncs the gray releases until ctx is done
+func (a *Analyzer) Start(ctx context.Context) {
+	logrus.Info("start gray release analyzer")
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		a.sync(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			logrus.Info("stop gray release analyzer")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Analyzer) sync(ctx context.Context, now time.Time) {
+	rollouts, err := a.kruise.RolloutsV1alpha1().Rollouts(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: LabelGrayRelease})
+	if err != nil {
+		logrus.Warningf("list gray release rollouts: %v", err)
+		return
+	}
+	for i := range rollouts.Items {
+		rollout := &rollouts.Items[i]
+		if err := a.reconcile(ctx, rollout, now); err != nil {
+			logrus.Warningf("sync gray release %s/%s: %v", rollout.Namespace, rollout.Name, err)
+		}
+	}
+}
+
+func (a *Analyzer) reconcile(ctx context.Context, rollout *v1alpha1.Rollout, now time.Time) error {
+	config, err := ParseConfig(rollout)
+	if err != nil || config == nil {
+		return err
+	}
+	status := rollout.Status.CanaryStatus
+	if status == nil || status.CurrentStepState == v1alpha1.CanaryStepStateCompleted {
+		return a.restoreTraffic(ctx, rollout, config)
+	}
+	if current := analysisStatus(rollout); current.Action == ActionRollback && current.Revision == status.PodTemplateHash {
+		// 回滚后保持流量在稳定版本，直到 kruise 完成发布
+		return a.holdStable(ctx, rollout, config)
+	}
+	step := config.StepAt(status.CurrentStepIndex)
+	if step == nil {
+		return nil
+	}
+	if err := a.syncTraffic(ctx, rollout, config, step); err != nil {
+		return err
+	}
+	if a.prom == nil || step.Analysis == nil || rollout.Spec.Strategy.Paused || status.CurrentStepState != v1alpha1.CanaryStepStatePaused {
+		return nil
+	}
+	return a.analyze(ctx, rollout, config, step.Analysis, now)
+}
+
+// syncTraffic applies the traffic of the current step
+func (a *Analyzer) syncTraffic(ctx context.Context, rollout *v1alpha1.Rollout, config *Config, step *apimodel.GrayStep) error {
+	status := rollout.Status.CanaryStatus
+	if config.RouteKind != RouteKindApisixRoute {
+		// 权重由 kruise 设置，这里只维护请求头匹配规则
+		return syncHTTPRouteMatches(ctx, a.gateway, rollout.Namespace, config, status.CanaryService, step.Matches)
+	}
+	if rollout.Status.StableRevision == "" || status.PodTemplateHash == "" {
+		return nil
+	}
+	if err := ensureGrayServices(ctx, a.clientset, rollout.Namespace, config, rollout.Status.StableRevision, status.PodTemplateHash); err != nil {
+		return err
+	}
+	split := apisixSplit{Active: true, CanaryWeight: int(step.Weight), Matched: step.Matches}
+	if status.CanaryReadyReplicas == 0 {
+		// 灰度实例未就绪前不分配流量
+		split.CanaryWeight, split.Matched = 0, false
+	}
+	return syncApisixRoutes(ctx, a.apisix, rollout.Namespace, config, split)
+}
+
+// holdStable sends all traffic of a rolled back release to the stable version
+func (a *Analyzer) holdStable(ctx context.Context, rollout *v1alpha1.Rollout, config *Config) error {
+	if config.RouteKind != RouteKindApisixRoute {
+		return syncHTTPRouteMatches(ctx, a.gateway, rollout.Namespace, config, rollout.Status.CanaryStatus.CanaryService, false)
+	}
+	return syncApisixRoutes(ctx, a.apisix, rollout.Namespace, config, apisixSplit{Active: true})
+}
+
+// restoreTraffic routes the traffic to the component service after the release
+func (a *Analyzer) restoreTraffic(ctx context.Context, rollout *v1alpha1.Rollout, config *Config) error {
+	if config.RouteKind != RouteKindApisixRoute {
+		canaryService := config.Service + "-canary"
+		if status := rollout.Status.CanaryStatus; status != nil && status.CanaryService != "" {
+			canaryService = status.CanaryService
+		}
+		return syncHTTPRouteMatches(ctx, a.gateway, rollout.Namespace, config, canaryService, false)
+	}
+	if err := syncApisixRoutes(ctx, a.apisix, rollout.Namespace, config, apisixSplit{}); err != nil {
+		return err
+	}
+	return deleteGrayServices(ctx, a.clientset, rollout.Namespace, config)
+}
+
+// analyze checks the canary metrics and promotes, pauses or rolls back the rollout
+func (a *Analyzer) analyze(ctx context.Context, rollout *v1alpha1.Rollout, config *Config, analysis *apimodel.GrayAnalysis, now time.Time) error {
+	stepIndex := rollout.Status.CanaryStatus.CurrentStepIndex
+	current := analysisStatus(rollout)
+	revision := rollout.Status.CanaryStatus.PodTemplateHash
+	if current.Step != stepIndex || current.Revision != revision {
+		current = &apimodel.GrayAnalysisStatus{Revision: revision, Step: stepIndex}
+	}
+	if last, err := time.Parse(time.RFC3339, current.LastCheck); err == nil && now.Sub(last) < analysisInterval(analysis) {
+		return nil
+	}
+	current.LastCheck = now.Format(time.RFC3339)
+	current.Action = ""
+	podIPs, err := a.canaryPodIPs(ctx, rollout, config)
+	if err != nil {
+		return err
+	}
+	s, err := collect(a.prom, analysis, queryVars{
+		Namespace:     rollout.Namespace,
+		Service:       config.Service,
+		CanaryService: rollout.Status.CanaryStatus.CanaryService,
+		PodIPs:        podIPs,
+		Window:        analysis.Window,
+	}, now)
+	if err != nil {
+		current.Phase = AnalysisRunning
+		current.Message = err.Error()
+		return a.saveAnalysisStatus(ctx, rollout, current)
+	}
+	switch evaluate(analysis, current, s) {
+	case AnalysisPassed:
+		current.Action = ActionPromote
+		if err := a.saveAnalysisStatus(ctx, rollout, current); err != nil {
+			return err
+		}
+		logrus.Infof("gray release %s/%s step %d passed the analysis", rollout.Namespace, rollout.Name, stepIndex)
+		return a.promote(ctx, rollout)
+	case AnalysisFailed:
+		if analysis.OnFailure == OnFailureRollback {
+			logrus.Warningf("gray release %s/%s step %d failed the analysis, rollback: %s", rollout.Namespace, rollout.Name, stepIndex, current.Message)
+			if err := a.rollback(ctx, rollout, current.Message); err != nil {
+				return err
+			}
+			current.Action = ActionRollback
+			return a.saveAnalysisStatus(ctx, rollout, current)
+		}
+		logrus.Warningf("gray release %s/%s step %d failed the analysis, pause: %s", rollout.Namespace, rollout.Name, stepIndex, current.Message)
+		current.Action = ActionPause
+		if err := a.saveAnalysisStatus(ctx, rollout, current); err != nil {
+			return err
+		}
+		return a.setPaused(ctx, rollout.Namespace, rollout.Name, true)
+	}
+	return a.saveAnalysisStatus(ctx, rollout, current)
+}
+
+// canaryPodIPs returns the ips of the canary pods as a regular expression alternation,
+// escaped to be embedded in a double quoted PromQL string.
+func (a *Analyzer) canaryPodIPs(ctx context.Context, rollout *v1alpha1.Rollout, config *Config) (string, error) {
+	svc, err := a.clientset.CoreV1().Services(rollout.Namespace).Get(ctx, config.Service, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("get service %s: %v", config.Service, err)
+	}
+	selector := labels.Set{}
+	for k, v := range svc.Spec.Selector {
+		selector[k] = v
+	}
+	selector[podTemplateHashLabel] = rollout.Status.CanaryStatus.PodTemplateHash
+	pods, err := a.clientset.CoreV1().Pods(rollout.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+	var ips []string
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP != "" && pod.Status.Phase == corev1.PodRunning {
+			ips = append(ips, strings.ReplaceAll(regexp.QuoteMeta(pod.Status.PodIP), `\`, `\\`))
+		}
+	}
+	return strings.Join(ips, "|"), nil
+}
@@ -0,0 +1,266 @@
+package grayrelease
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/apis/config/v2"
+	apisixfake "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/client/clientset/versioned/fake"
+	"github.com/goodrain/rainbond/api/client/prometheus"
+	apimodel "github.com/goodrain/rainbond/api/model"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	"github.com/openkruise/kruise-api/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeProm answers the queries by the metric name in the expression
+type fakeProm struct {
+	prometheus.Interface
+	values  map[string]float64
+	queries []string
+}
+
+func (p *fakeProm) GetMetric(expr string, _ time.Time) prometheus.Metric {
+	p.queries = append(p.queries, expr)
+	value := p.values["requests"]
+	switch {
+	case strings.Contains(expr, "histogram_quantile"):
+		value = p.values["latency"]
+	case strings.Contains(expr, "/"):
+		value = p.values["error_rate"]
+	}
+	point := prometheus.Point{float64(time.Now().Unix()), value}
+	return prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{{Sample: &point}}}}
+}
+
+func testRollout(t *testing.T, config *Config) *v1alpha1.Rollout {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app-web",
+			Namespace:   "ns",
+			Labels:      map[string]string{LabelGrayRelease: "true"},
+			Annotations: map[string]string{AnnotationConfig: string(raw)},
+		},
+		Spec: v1alpha1.RolloutSpec{
+			ObjectRef: v1alpha1.ObjectRef{WorkloadRef: &v1alpha1.WorkloadRef{Kind: "Deployment", Name: "app-web"}},
+		},
+		Status: v1alpha1.RolloutStatus{
+			StableRevision: "stable",
+			CanaryStatus: &v1alpha1.CanaryStatus{
+				CanaryService:       "web-canary",
+				PodTemplateHash:     "canary",
+				CurrentStepIndex:    1,
+				CurrentStepState:    v1alpha1.CanaryStepStatePaused,
+				CanaryReplicas:      1,
+				CanaryReadyReplicas: 1,
+			},
+		},
+	}
+}
+
+func testWorkload() []*appsv1.ReplicaSet {
+	uid := types.UID("deploy-uid")
+	rs := func(hash, image, revision string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "app-web-" + hash,
+				Namespace:       "ns",
+				Labels:          map[string]string{"name": "web", podTemplateHashLabel: hash},
+				Annotations:     map[string]string{"deployment.kubernetes.io/revision": revision},
+				OwnerReferences: []metav1.OwnerReference{{UID: uid}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": "web", podTemplateHashLabel: hash}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+			}},
+		}
+	}
+	return []*appsv1.ReplicaSet{rs("stable", "web:v1", "1"), rs("canary", "web:v2", "2")}
+}
+
+func testClientset() *fake.Clientset {
+	replicaSets := testWorkload()
+	return fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-web", Namespace: "ns", UID: "deploy-uid"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "web"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": "web"}},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:v2"}}},
+				},
+			},
+		},
+		replicaSets[0], replicaSets[1],
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"name": "web"},
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(5000)}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-canary-0", Namespace: "ns", Labels: map[string]string{"name": "web", podTemplateHashLabel: "canary"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.7"},
+		},
+	)
+}
+
+// capability_id: rainbond.worker.grayrelease.evaluate
+func TestEvaluate(t *testing.T) {
+	analysis := &apimodel.GrayAnalysis{MaxErrorRate: 5, MaxLatency: 300, MinRequests: 10, SuccessCount: 2, FailureLimit: 2}
+	status := &apimodel.GrayAnalysisStatus{}
+	if phase := evaluate(analysis, status, sample{Requests: 3}); phase != AnalysisInconclusive {
+		t.Fatalf("expected inconclusive, got %s", phase)
+	}
+	if phase := evaluate(analysis, status, sample{Requests: 100, ErrorRate: 1, Latency: 100}); phase != AnalysisRunning {
+		t.Fatalf("expected running after one success, got %s", phase)
+	}
+	if phase := evaluate(analysis, status, sample{Requests: 100, ErrorRate: 1, Latency: 100}); phase != AnalysisPassed {
+		t.Fatalf("expected passed, got %s", phase)
+	}
+
+	status = &apimodel.GrayAnalysisStatus{}
+	if phase := evaluate(analysis, status, sample{Requests: 100, ErrorRate: 9}); phase != AnalysisRunning || status.Failures != 1 {
+		t.Fatalf("expected first failure to be tolerated, got %s %+v", phase, status)
+	}
+	if phase := evaluate(analysis, status, sample{Requests: 100, Latency: 500}); phase != AnalysisFailed {
+		t.Fatalf("expected failed, got %s", phase)
+	}
+	if !strings.Contains(status.Message, "latency") {
+		t.Fatalf("breach not reported: %s", status.Message)
+	}
+}
+
+// capability_id: rainbond.worker.grayrelease.analysis-promote
+func TestAnalyzerPromotesPassingStep(t *testing.T) {
+	rollout := testRollout(t, &Config{
+		Strategy:  apimodel.GrayStrategyCanary,
+		RouteKind: RouteKindHTTPRoute,
+		Service:   "web",
+		Steps:     []apimodel.GrayStep{{Weight: 20, Analysis: &apimodel.GrayAnalysis{MaxErrorRate: 5, SuccessCount: 1}}, {Weight: 100}},
+	})
+	kruise := kruisefake.NewSimpleClientset(rollout)
+	prom := &fakeProm{values: map[string]float64{"requests": 200, "error_rate": 0.5}}
+	analyzer := NewAnalyzer(NewOperator(kruise, testClientset(), nil, nil), prom)
+
+	analyzer.sync(context.Background(), time.Now())
+
+	got, err := kruise.RolloutsV1alpha1().Rollouts("ns").Get(context.Background(), "app-web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.CanaryStatus.CurrentStepState != v1alpha1.CanaryStepStateReady {
+		t.Fatalf("step not promoted: %s", got.Status.CanaryStatus.CurrentStepState)
+	}
+	status := analysisStatus(got)
+	if status.Phase != AnalysisPassed || status.Action != ActionPromote || status.Step != 1 {
+		t.Fatalf("unexpected analysis status %+v", status)
+	}
+	if len(prom.queries) == 0 || !strings.Contains(prom.queries[0], `10\\.0\\.0\\.7`) {
+		t.Fatalf("canary pod ips not rendered into %v", prom.queries)
+	}
+}
+
+// capability_id: rainbond.worker.grayrelease.analysis-rollback
+func TestAnalyzerRollsBackFailingStep(t *testing.T) {
+	rollout := testRollout(t, &Config{
+		Strategy:     apimodel.GrayStrategyCanary,
+		RouteKind:    RouteKindApisixRoute,
+		ServiceAlias: "gr123456",
+		Service:      "web",
+		Steps:        []apimodel.GrayStep{{Weight: 20, Analysis: &apimodel.GrayAnalysis{MaxErrorRate: 5, OnFailure: OnFailureRollback}}},
+	})
+	kruise := kruisefake.NewSimpleClientset(rollout)
+	clientset := testClientset()
+	apisix := apisixfake.NewSimpleClientset(&v2.ApisixRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-route", Namespace: "ns", Labels: map[string]string{"gr123456": "service_alias"}},
+		Spec: v2.ApisixRouteSpec{HTTP: []v2.ApisixRouteHTTP{{
+			Name:     "rule",
+			Backends: []v2.ApisixRouteHTTPBackend{{ServiceName: "web", ServicePort: intstr.FromInt(80)}},
+		}}},
+	})
+	prom := &fakeProm{values: map[string]float64{"requests": 200, "error_rate": 40}}
+	analyzer := NewAnalyzer(NewOperator(kruise, clientset, apisix, nil), prom)
+
+	analyzer.sync(context.Background(), time.Now())
+
+	deploy, err := clientset.AppsV1().Deployments("ns").Get(context.Background(), "app-web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := deploy.Spec.Template.Spec.Containers[0].Image; image != "web:v1" {
+		t.Fatalf("deployment not reverted to the stable template, image %s", image)
+	}
+	if _, ok := deploy.Spec.Template.Labels[podTemplateHashLabel]; ok {
+		t.Fatal("pod-template-hash label must not be copied to the deployment")
+	}
+	route, err := apisix.ApisixV2().ApisixRoutes("ns").Get(context.Background(), "web-route", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends := route.Spec.HTTP[0].Backends
+	if len(backends) != 2 || backends[0].ServiceName != StableServiceName("web") || *backends[0].Weight != 100 || *backends[1].Weight != 0 {
+		t.Fatalf("traffic not switched back to stable: %+v", backends)
+	}
+	if _, err := clientset.CoreV1().Services("ns").Get(context.Background(), CanaryServiceName("web"), metav1.GetOptions{}); err != nil {
+		t.Fatalf("canary service not created: %v", err)
+	}
+	got, _ := kruise.RolloutsV1alpha1().Rollouts("ns").Get(context.Background(), "app-web", metav1.GetOptions{})
+	if status := analysisStatus(got); status.Phase != AnalysisFailed || status.Action != ActionRollback || status.Revision != "canary" {
+		t.Fatalf("unexpected analysis status %+v", status)
+	}
+
+	// the traffic stays on stable until kruise finishes the rollout
+	analyzer.sync(context.Background(), time.Now().Add(time.Minute))
+	route, _ = apisix.ApisixV2().ApisixRoutes("ns").Get(context.Background(), "web-route", metav1.GetOptions{})
+	if backends := route.Spec.HTTP[0].Backends; *backends[1].Weight != 0 {
+		t.Fatalf("rolled back canary received traffic again: %+v", backends)
+	}
+}
+
+// capability_id: rainbond.worker.grayrelease.apisix-split
+func TestApplyApisixSplit(t *testing.T) {
+	config := &Config{
+		Service: "web",
+		Matches: [][]apimodel.FlowEntryRule{{{MatchType: apimodel.FlowEntryMatchCookie, HeaderKey: "beta", HeaderType: "Exact", HeaderValue: "1"}}},
+	}
+	route := &v2.ApisixRoute{Spec: v2.ApisixRouteSpec{HTTP: []v2.ApisixRouteHTTP{
+		{Name: "rule", Priority: 1, Backends: []v2.ApisixRouteHTTPBackend{{ServiceName: "web", ServicePort: intstr.FromInt(80)}}},
+		{Name: "other", Backends: []v2.ApisixRouteHTTPBackend{{ServiceName: "api", ServicePort: intstr.FromInt(80)}}},
+	}}}
+
+	if !applyApisixSplit(route, config, apisixSplit{Active: true, CanaryWeight: 30, Matched: true}) {
+		t.Fatal("expected the route to change")
+	}
+	if len(route.Spec.HTTP) != 3 {
+		t.Fatalf("expected a matched rule, got %+v", route.Spec.HTTP)
+	}
+	if w := route.Spec.HTTP[0].Backends[1].Weight; *w != 30 {
+		t.Fatalf("unexpected canary weight %d", *w)
+	}
+	matched := route.Spec.HTTP[1]
+	if matched.Priority != 2 || matched.Match.NginxVars[0].Subject.Scope != "Cookie" || matched.Backends[0].ServiceName != CanaryServiceName("web") {
+		t.Fatalf("unexpected matched rule %+v", matched)
+	}
+	if applyApisixSplit(route, config, apisixSplit{Active: true, CanaryWeight: 30, Matched: true}) {
+		t.Fatal("applying the same split twice must be a no-op")
+	}
+
+	applyApisixSplit(route, config, apisixSplit{})
+	if len(route.Spec.HTTP) != 2 || route.Spec.HTTP[0].Backends[0].ServiceName != "web" || route.Spec.HTTP[1].Backends[0].ServiceName != "api" {
+		t.Fatalf("route not restored: %+v", route.Spec.HTTP)
+	}
+}
@@ -0,0 +1,214 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package grayrelease drives the gray releases built on Kruise Rollouts:
+// header/cookie matched canary steps, metric analysis between steps,
+// blue-green switching and the traffic split of APISIX routes.
+package grayrelease
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/openkruise/kruise-api/rollouts/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const (
+	// LabelGrayRelease marks the rollouts managed by rainbond gray release
+	LabelGrayRelease = "rainbond.io/gray-release"
+	// AnnotationConfig holds the Config of the rollout
+	AnnotationConfig = "rainbond.io/gray-release"
+	// AnnotationAnalysis holds the GrayAnalysisStatus of the current step
+	AnnotationAnalysis = "rainbond.io/gray-analysis"
+
+	// RouteKindHTTPRoute the traffic is split by Kruise on a Gateway API HTTPRoute
+	RouteKindHTTPRoute = "HTTPRoute"
+	// RouteKindApisixRoute the traffic is split by rainbond on the ApisixRoutes of the component
+	RouteKindApisixRoute = "ApisixRoute"
+)
+
+var (
+	headerMatchExact = gatewayv1beta1.HeaderMatchType("Exact")
+	headerMatchRegex = gatewayv1beta1.HeaderMatchType("RegularExpression")
+)
+
+// Config the gray release settings kept on the rollout, the steps are
+// in the same order as the canary steps of the rollout.
+type Config struct {
+	Strategy  string `json:"strategy"`
+	RouteKind string `json:"route_kind"`
+	// RouteName the HTTPRoute name when RouteKind is HTTPRoute
+	RouteName string `json:"route_name,omitempty"`
+	// ServiceAlias selects the ApisixRoutes when RouteKind is ApisixRoute
+	ServiceAlias string                     `json:"service_alias,omitempty"`
+	Service      string                     `json:"service"`
+	Port         int32                      `json:"port,omitempty"`
+	Matches      [][]apimodel.FlowEntryRule `json:"matches,omitempty"`
+	Steps        []apimodel.GrayStep        `json:"steps"`
+}
+
+// StepAt returns the config of the 1-based kruise step index
+func (c *Config) StepAt(index int32) *apimodel.GrayStep {
+	if index < 1 || int(index) > len(c.Steps) {
+		return nil
+	}
+	return &c.Steps[index-1]
+}
+
+// ParseConfig reads the config from the rollout annotation
+func ParseConfig(rollout *v1alpha1.Rollout) (*Config, error) {
+	raw, ok := rollout.Annotations[AnnotationConfig]
+	if !ok || raw == "" {
+		return nil, nil
+	}
+	var config Config
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, fmt.Errorf("parse gray release config of rollout %s/%s: %v", rollout.Namespace, rollout.Name, err)
+	}
+	return &config, nil
+}
+
+// ParseGrayStrategy parses the gray strategy, both the legacy weights [10, 50, 100]
+// and the step objects are accepted.
+func ParseGrayStrategy(raw string) ([]apimodel.GrayStep, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var weights []int32
+	if err := json.Unmarshal([]byte(raw), &weights); err == nil {
+		steps := make([]apimodel.GrayStep, 0, len(weights))
+		for _, weight := range weights {
+			steps = append(steps, apimodel.GrayStep{Weight: weight})
+		}
+		return steps, validateSteps(steps)
+	}
+	var steps []apimodel.GrayStep
+	if err := json.Unmarshal([]byte(raw), &steps); err != nil {
+		return nil, fmt.Errorf("invalid gray strategy: %v", err)
+	}
+	return steps, validateSteps(steps)
+}
+
+func validateSteps(steps []apimodel.GrayStep) error {
+	for i, step := range steps {
+		if step.Weight < 1 || step.Weight > 100 {
+			return fmt.Errorf("weight of gray step %d must be between 1 and 100", i+1)
+		}
+		if step.Analysis != nil {
+			switch step.Analysis.OnFailure {
+			case "", OnFailurePause, OnFailureRollback:
+			default:
+				return fmt.Errorf("unsupported on_failure %q of gray step %d", step.Analysis.OnFailure, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// NormalizeSteps returns the steps the rollout runs. A blue-green release deploys
+// the new version fully without traffic, then switches all traffic at once; the
+// analysis of the first configured step guards the switch.
+func NormalizeSteps(strategy string, steps []apimodel.GrayStep) []apimodel.GrayStep {
+	if strategy != apimodel.GrayStrategyBlueGreen {
+		return steps
+	}
+	switchStep := apimodel.GrayStep{Weight: 100}
+	if len(steps) > 0 {
+		switchStep.Analysis = steps[0].Analysis
+	}
+	return []apimodel.GrayStep{{Weight: 0}, switchStep}
+}
+
+// CanarySteps converts the steps to kruise canary steps. The routes managed by
+// rainbond (ApisixRoute) are split by replicas because kruise does not route them.
+func CanarySteps(steps []apimodel.GrayStep, routeKind string) []v1alpha1.CanaryStep {
+	var canarySteps []v1alpha1.CanaryStep
+	for _, step := range steps {
+		var canaryStep v1alpha1.CanaryStep
+		if step.Weight == 0 {
+			// 蓝绿预览：新版本全量副本，不分配流量
+			replicas := intstr.FromString("100%")
+			canaryStep.Replicas = &replicas
+		} else if routeKind == RouteKindApisixRoute {
+			replicas := intstr.FromString(fmt.Sprintf("%d%%", step.Weight))
+			canaryStep.Replicas = &replicas
+		} else {
+			weight := step.Weight
+			canaryStep.Weight = &weight
+		}
+		if step.Pause > 0 {
+			duration := step.Pause
+			canaryStep.Pause.Duration = &duration
+		}
+		canarySteps = append(canarySteps, canaryStep)
+	}
+	return canarySteps
+}
+
+// HTTPRouteMatches converts the flow entry rules, rules in the same group are ANDed
+// and the groups are ORed.
+func HTTPRouteMatches(rules [][]apimodel.FlowEntryRule) []gatewayv1beta1.HTTPRouteMatch {
+	var matches []gatewayv1beta1.HTTPRouteMatch
+	for _, group := range rules {
+		var headers []gatewayv1beta1.HTTPHeaderMatch
+		for _, rule := range group {
+			if rule.HeaderKey == "" {
+				continue
+			}
+			if rule.MatchType == apimodel.FlowEntryMatchCookie {
+				// Gateway API 没有 cookie 匹配，使用 Cookie 请求头的正则匹配
+				matchType := headerMatchRegex
+				headers = append(headers, gatewayv1beta1.HTTPHeaderMatch{
+					Type:  &matchType,
+					Name:  "Cookie",
+					Value: cookieRegex(rule),
+				})
+				continue
+			}
+			matchType := headerMatchExact
+			if rule.HeaderType == string(headerMatchRegex) {
+				matchType = headerMatchRegex
+			}
+			headers = append(headers, gatewayv1beta1.HTTPHeaderMatch{
+				Type:  &matchType,
+				Name:  gatewayv1.HTTPHeaderName(rule.HeaderKey),
+				Value: rule.HeaderValue,
+			})
+		}
+		if len(headers) > 0 {
+			matches = append(matches, gatewayv1beta1.HTTPRouteMatch{Headers: headers})
+		}
+	}
+	return matches
+}
+
+// cookieRegex matches the cookie in the Cookie header
+func cookieRegex(rule apimodel.FlowEntryRule) string {
+	value := regexp.QuoteMeta(rule.HeaderValue)
+	if rule.HeaderType == string(headerMatchRegex) {
+		value = rule.HeaderValue
+	}
+	return fmt.Sprintf(`(^|;\s*)%s=%s(;|$)`, regexp.QuoteMeta(rule.HeaderKey), value)
+}
@@ -0,0 +1,79 @@
+package grayrelease
+
+import (
+	"testing"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+)
+
+// capability_id: rainbond.worker.grayrelease.parse-strategy
+func TestParseGrayStrategy(t *testing.T) {
+	steps, err := ParseGrayStrategy(`[10, 50, 100]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[0].Weight != 10 || steps[2].Weight != 100 {
+		t.Fatalf("unexpected legacy steps %+v", steps)
+	}
+
+	steps, err = ParseGrayStrategy(`[{"weight":20,"matches":true,"analysis":{"max_error_rate":1,"on_failure":"rollback"}},{"weight":100}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !steps[0].Matches || steps[0].Analysis == nil || steps[0].Analysis.OnFailure != OnFailureRollback {
+		t.Fatalf("unexpected steps %+v", steps[0])
+	}
+
+	for _, raw := range []string{`[0]`, `[101]`, `[{"weight":10,"analysis":{"on_failure":"ignore"}}]`, `{}`} {
+		if _, err := ParseGrayStrategy(raw); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+// capability_id: rainbond.worker.grayrelease.blue-green-steps
+func TestNormalizeStepsBlueGreen(t *testing.T) {
+	analysis := &apimodel.GrayAnalysis{MaxErrorRate: 1}
+	steps := NormalizeSteps(apimodel.GrayStrategyBlueGreen, []apimodel.GrayStep{{Weight: 30, Analysis: analysis}})
+	if len(steps) != 2 || steps[0].Weight != 0 || steps[1].Weight != 100 || steps[1].Analysis != analysis {
+		t.Fatalf("unexpected blue-green steps %+v", steps)
+	}
+
+	canary := CanarySteps(steps, RouteKindHTTPRoute)
+	if canary[0].Replicas == nil || canary[0].Replicas.String() != "100%" || canary[0].Weight != nil {
+		t.Fatalf("preview step should run full replicas without traffic: %+v", canary[0])
+	}
+	if canary[1].Weight == nil || *canary[1].Weight != 100 {
+		t.Fatalf("switch step should move all traffic: %+v", canary[1])
+	}
+}
+
+// capability_id: rainbond.worker.grayrelease.canary-steps
+func TestCanaryStepsApisixUsesReplicas(t *testing.T) {
+	steps := CanarySteps([]apimodel.GrayStep{{Weight: 20, Pause: 60}}, RouteKindApisixRoute)
+	if steps[0].Weight != nil || steps[0].Replicas == nil || steps[0].Replicas.String() != "20%" {
+		t.Fatalf("unexpected apisix step %+v", steps[0])
+	}
+	if steps[0].Pause.Duration == nil || *steps[0].Pause.Duration != 60 {
+		t.Fatalf("pause not kept: %+v", steps[0].Pause)
+	}
+}
+
+// capability_id: rainbond.worker.grayrelease.httproute-matches
+func TestHTTPRouteMatches(t *testing.T) {
+	matches := HTTPRouteMatches([][]apimodel.FlowEntryRule{
+		{{HeaderKey: "X-Gray", HeaderType: "Exact", HeaderValue: "true"}},
+		{{MatchType: apimodel.FlowEntryMatchCookie, HeaderKey: "user.group", HeaderType: "Exact", HeaderValue: "beta"}},
+	})
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	header := matches[0].Headers[0]
+	if header.Name != "X-Gray" || header.Value != "true" || *header.Type != headerMatchExact {
+		t.Fatalf("unexpected header match %+v", header)
+	}
+	cookie := matches[1].Headers[0]
+	if cookie.Name != "Cookie" || *cookie.Type != headerMatchRegex || cookie.Value != `(^|;\s*)user\.group=beta(;|$)` {
+		t.Fatalf("unexpected cookie match %+v", cookie)
+	}
+}
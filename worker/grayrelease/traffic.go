@@ -0,0 +1,314 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package grayrelease
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v2 "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/apis/config/v2"
+	apisixversioned "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/client/clientset/versioned"
+	apimodel "github.com/goodrain/rainbond/api/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/typed/apis/v1beta1"
+)
+
+const (
+	// podTemplateHashLabel the label deployments put on the pods of a revision
+	podTemplateHashLabel = "pod-template-hash"
+	// apisixMatchRuleSuffix the suffix of the ApisixRoute rules routing matched requests to the canary
+	apisixMatchRuleSuffix = "-gray-match"
+)
+
+// StableServiceName the service selecting the stable pods of an ApisixRoute gray release
+func StableServiceName(service string) string {
+	return service + "-gray-stable"
+}
+
+// CanaryServiceName the service selecting the canary pods of an ApisixRoute gray release
+func CanaryServiceName(service string) string {
+	return service + "-gray-canary"
+}
+
+// applyHTTPRouteMatches removes the matched canary rules from the route and adds them
+// back when enable is true. Header matches take precedence over the weighted rules
+// in the Gateway API, so the matched requests always reach the canary service.
+func applyHTTPRouteMatches(route *gatewayv1beta1.HTTPRoute, matches []gatewayv1beta1.HTTPRouteMatch, canaryService string, port int32, enable bool) bool {
+	var rules []gatewayv1beta1.HTTPRouteRule
+	for _, rule := range route.Spec.Rules {
+		if !isMatchRule(rule, canaryService) {
+			rules = append(rules, rule)
+		}
+	}
+	if enable && len(matches) > 0 {
+		name := gatewayv1beta1.ObjectName(canaryService)
+		backendPort := gatewayv1beta1.PortNumber(port)
+		weight := int32(1)
+		rules = append(rules, gatewayv1beta1.HTTPRouteRule{
+			Matches: matches,
+			BackendRefs: []gatewayv1beta1.HTTPBackendRef{{
+				BackendRef: gatewayv1beta1.BackendRef{
+					BackendObjectReference: gatewayv1beta1.BackendObjectReference{Name: name, Port: &backendPort},
+					Weight:                 &weight,
+				},
+			}},
+		})
+	}
+	if equality.Semantic.DeepEqual(route.Spec.Rules, rules) {
+		return false
+	}
+	route.Spec.Rules = rules
+	return true
+}
+
+func isMatchRule(rule gatewayv1beta1.HTTPRouteRule, canaryService string) bool {
+	if len(rule.Matches) == 0 || len(rule.BackendRefs) == 0 {
+		return false
+	}
+	for _, ref := range rule.BackendRefs {
+		if string(ref.Name) != canaryService {
+			return false
+		}
+	}
+	for _, match := range rule.Matches {
+		if len(match.Headers) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// syncHTTPRouteMatches keeps the matched canary rule of the HTTPRoute in line with the step
+func syncHTTPRouteMatches(ctx context.Context, gateway gatewayclient.GatewayV1beta1Interface, namespace string, config *Config, canaryService string, enable bool) error {
+	if gateway == nil || config.RouteName == "" || canaryService == "" {
+		return nil
+	}
+	route, err := gateway.HTTPRoutes(namespace).Get(ctx, config.RouteName, metav1.GetOptions{})
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !applyHTTPRouteMatches(route, HTTPRouteMatches(config.Matches), canaryService, config.Port, enable) {
+		return nil
+	}
+	_, err = gateway.HTTPRoutes(namespace).Update(ctx, route, metav1.UpdateOptions{})
+	return err
+}
+
+// apisixSplit the desired traffic split of the ApisixRoutes
+type apisixSplit struct {
+	// Active false restores the routes to the component service
+	Active       bool
+	CanaryWeight int
+	// Matched routes the requests matching the flow entry rules to the canary
+	Matched bool
+}
+
+// applyApisixSplit rewrites the rules of the route that proxy to the component
+func applyApisixSplit(route *v2.ApisixRoute, config *Config, split apisixSplit) bool {
+	stable, canary := StableServiceName(config.Service), CanaryServiceName(config.Service)
+	var rules []v2.ApisixRouteHTTP
+	for _, rule := range route.Spec.HTTP {
+		if strings.HasSuffix(rule.Name, apisixMatchRuleSuffix) {
+			continue
+		}
+		port, ok := componentBackendPort(rule.Backends, config.Service, stable, canary)
+		if !ok {
+			rules = append(rules, rule)
+			continue
+		}
+		var backends []v2.ApisixRouteHTTPBackend
+		if split.Active {
+			backends = []v2.ApisixRouteHTTPBackend{
+				{ServiceName: stable, ServicePort: port, Weight: intPtr(100 - split.CanaryWeight)},
+				{ServiceName: canary, ServicePort: port, Weight: intPtr(split.CanaryWeight)},
+			}
+		} else {
+			backends = []v2.ApisixRouteHTTPBackend{{ServiceName: config.Service, ServicePort: port, Weight: intPtr(100)}}
+		}
+		rule.Backends = backends
+		rules = append(rules, rule)
+		if split.Active && split.Matched {
+			for i, exprs := range apisixMatchExprs(config.Matches) {
+				matched := rule
+				matched.Name = fmt.Sprintf("%s-%d%s", truncateName(rule.Name, 40), i, apisixMatchRuleSuffix)
+				matched.Priority = rule.Priority + 1
+				matched.Match.NginxVars = append(append([]v2.ApisixRouteHTTPMatchExpr{}, rule.Match.NginxVars...), exprs...)
+				matched.Backends = []v2.ApisixRouteHTTPBackend{{ServiceName: canary, ServicePort: port, Weight: intPtr(100)}}
+				rules = append(rules, matched)
+			}
+		}
+	}
+	if equality.Semantic.DeepEqual(route.Spec.HTTP, rules) {
+		return false
+	}
+	route.Spec.HTTP = rules
+	return true
+}
+
+func componentBackendPort(backends []v2.ApisixRouteHTTPBackend, names ...string) (intstr.IntOrString, bool) {
+	for _, backend := range backends {
+		for _, name := range names {
+			if backend.ServiceName == name {
+				return backend.ServicePort, true
+			}
+		}
+	}
+	return intstr.IntOrString{}, false
+}
+
+// apisixMatchExprs converts the flow entry rules to APISIX match expressions,
+// one rule is generated for every group.
+func apisixMatchExprs(rules [][]apimodel.FlowEntryRule) [][]v2.ApisixRouteHTTPMatchExpr {
+	var groups [][]v2.ApisixRouteHTTPMatchExpr
+	for _, group := range rules {
+		var exprs []v2.ApisixRouteHTTPMatchExpr
+		for _, rule := range group {
+			if rule.HeaderKey == "" {
+				continue
+			}
+			scope := "Header"
+			if rule.MatchType == apimodel.FlowEntryMatchCookie {
+				scope = "Cookie"
+			}
+			op := "Equal"
+			if rule.HeaderType == string(headerMatchRegex) {
+				op = "RegexMatch"
+			}
+			value := rule.HeaderValue
+			exprs = append(exprs, v2.ApisixRouteHTTPMatchExpr{
+				Subject: v2.ApisixRouteHTTPMatchExprSubject{Scope: scope, Name: rule.HeaderKey},
+				Op:      op,
+				Value:   &value,
+			})
+		}
+		if len(exprs) > 0 {
+			groups = append(groups, exprs)
+		}
+	}
+	return groups
+}
+
+// syncApisixRoutes applies the split to the ApisixRoutes of the component
+func syncApisixRoutes(ctx context.Context, apisix apisixversioned.Interface, namespace string, config *Config, split apisixSplit) error {
+	if apisix == nil || config.ServiceAlias == "" {
+		return nil
+	}
+	routes, err := apisix.ApisixV2().ApisixRoutes(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: config.ServiceAlias + "=service_alias",
+	})
+	if err != nil {
+		return err
+	}
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		if !applyApisixSplit(route, config, split) {
+			continue
+		}
+		if _, err := apisix.ApisixV2().ApisixRoutes(namespace).Update(ctx, route, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("update apisix route %s: %v", route.Name, err)
+		}
+	}
+	return nil
+}
+
+// ensureGrayServices creates the services selecting the stable and canary revisions
+func ensureGrayServices(ctx context.Context, clientset kubernetes.Interface, namespace string, config *Config, stableHash, canaryHash string) error {
+	base, err := clientset.CoreV1().Services(namespace).Get(ctx, config.Service, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for name, hash := range map[string]string{
+		StableServiceName(config.Service): stableHash,
+		CanaryServiceName(config.Service): canaryHash,
+	} {
+		if hash == "" {
+			continue
+		}
+		selector := make(map[string]string, len(base.Spec.Selector)+1)
+		for k, v := range base.Spec.Selector {
+			selector[k] = v
+		}
+		selector[podTemplateHashLabel] = hash
+		existing, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			if existing.Spec.Selector[podTemplateHashLabel] == hash {
+				continue
+			}
+			existing.Spec.Selector = selector
+			if _, err := clientset.CoreV1().Services(namespace).Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+			continue
+		}
+		if !k8serror.IsNotFound(err) {
+			return err
+		}
+		var ports []corev1.ServicePort
+		for _, port := range base.Spec.Ports {
+			ports = append(ports, corev1.ServicePort{Name: port.Name, Protocol: port.Protocol, Port: port.Port, TargetPort: port.TargetPort})
+		}
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{LabelGrayRelease: "true"},
+			},
+			Spec: corev1.ServiceSpec{Selector: selector, Ports: ports},
+		}
+		if _, err := clientset.CoreV1().Services(namespace).Create(ctx, svc, metav1.CreateOptions{}); err != nil && !k8serror.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteGrayServices removes the services after the routes are restored
+func deleteGrayServices(ctx context.Context, clientset kubernetes.Interface, namespace string, config *Config) error {
+	for _, name := range []string{StableServiceName(config.Service), CanaryServiceName(config.Service)} {
+		if err := clientset.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !k8serror.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+
+func truncateName(name string, max int) string {
+	name = invalidNameChars.ReplaceAllString(name, "-")
+	if len(name) > max {
+		return name[:max]
+	}
+	return name
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,190 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/worker/appm/conversion"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes"
+)
+
+// hpaRef the hpa created for an autoscaler rule
+type hpaRef struct {
+	namespace   string
+	name        string
+	minReplicas int32
+}
+
+// Scheduler applies the min replicas of the autoscaler rule schedules to the hpa
+// when the schedules fire, and records every change as a scaling record.
+type Scheduler struct {
+	clientset kubernetes.Interface
+	dbmanager db.Manager
+	// useV2 uses autoscaling/v2, the kubernetes version is at least v1.23
+	useV2    bool
+	interval time.Duration
+}
+
+// AutoscalingV2Available reports whether the kubernetes of the git version serves autoscaling/v2.
+// The git version of a managed cluster carries a vendor suffix, e.g. v1.27.3-eks-2d98532,
+// autoscaling/v2 is assumed when the version can not be parsed.
+func AutoscalingV2Available(gitVersion string) bool {
+	version, err := utilversion.ParseGeneric(gitVersion)
+	if err != nil {
+		logrus.Warningf("parse kubernetes version %q failure %s, use autoscaling/v2", gitVersion, err.Error())
+		return true
+	}
+	return version.AtLeast(utilversion.MustParseGeneric("v1.23.0"))
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(clientset kubernetes.Interface, dbmanager db.Manager, useV2 bool) *Scheduler {
+	return &Scheduler{
+		clientset: clientset,
+		dbmanager: dbmanager,
+		useV2:     useV2,
+		interval:  time.Minute,
+	}
+}
+
+// Start checks the schedules every minute until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	logrus.Info("start autoscaler schedules")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.sync(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			logrus.Info("stop autoscaler schedules")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) sync(ctx context.Context, now time.Time) {
+	rules, err := s.dbmanager.TenantServceAutoscalerRulesDao().ListEnableScheduledOnes()
+	if err != nil {
+		logrus.Warningf("list scheduled autoscaler rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	hpas, err := s.listHPAs(ctx)
+	if err != nil {
+		logrus.Warningf("list hpas of autoscaler rules: %v", err)
+		return
+	}
+	for _, rule := range rules {
+		ref, ok := hpas[rule.RuleID]
+		if !ok {
+			// the component is closed or the rule has no metrics
+			continue
+		}
+		minReplicas := int32(conversion.ScheduledMinReplicas(rule, now))
+		if ref.minReplicas == minReplicas {
+			continue
+		}
+		err := s.patchMinReplicas(ctx, ref, minReplicas)
+		s.record(rule, conversion.ActiveSchedule(rule, now), ref.minReplicas, minReplicas, err, now)
+		if err != nil {
+			logrus.Warningf("rule id: %s; patch min replicas of hpa: %v", rule.RuleID, err)
+		}
+	}
+}
+
+func (s *Scheduler) listHPAs(ctx context.Context) (map[string]hpaRef, error) {
+	opts := metav1.ListOptions{LabelSelector: "rule_id"}
+	hpas := make(map[string]hpaRef)
+	if s.useV2 {
+		list, err := s.clientset.AutoscalingV2().HorizontalPodAutoscalers(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, hpa := range list.Items {
+			ref := hpaRef{namespace: hpa.Namespace, name: hpa.Name, minReplicas: 1}
+			if hpa.Spec.MinReplicas != nil {
+				ref.minReplicas = *hpa.Spec.MinReplicas
+			}
+			hpas[hpa.Labels["rule_id"]] = ref
+		}
+		return hpas, nil
+	}
+	list, err := s.clientset.AutoscalingV2beta2().HorizontalPodAutoscalers(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, hpa := range list.Items {
+		ref := hpaRef{namespace: hpa.Namespace, name: hpa.Name, minReplicas: 1}
+		if hpa.Spec.MinReplicas != nil {
+			ref.minReplicas = *hpa.Spec.MinReplicas
+		}
+		hpas[hpa.Labels["rule_id"]] = ref
+	}
+	return hpas, nil
+}
+
+func (s *Scheduler) patchMinReplicas(ctx context.Context, ref hpaRef, minReplicas int32) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"minReplicas":%d}}`, minReplicas))
+	var err error
+	if s.useV2 {
+		_, err = s.clientset.AutoscalingV2().HorizontalPodAutoscalers(ref.namespace).Patch(ctx, ref.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	} else {
+		_, err = s.clientset.AutoscalingV2beta2().HorizontalPodAutoscalers(ref.namespace).Patch(ctx, ref.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
+
+func (s *Scheduler) record(rule *model.TenantServiceAutoscalerRules, schedule *model.AutoscalerSchedule, old, new int32, err error, now time.Time) {
+	desc := fmt.Sprintf("the min replicas is changed from %d to %d, the schedules of the rule are over", old, new)
+	if schedule != nil {
+		desc = fmt.Sprintf("the min replicas is changed from %d to %d by schedule %s(%s)", old, new, schedule.Name, schedule.Schedule)
+	}
+	reason := "SuccessfulScheduledRescale"
+	if err != nil {
+		desc = fmt.Sprintf("%s: %v", desc, err)
+		reason = "FailedScheduledRescale"
+	}
+	record := &model.TenantServiceScalingRecords{
+		ServiceID:   rule.ServiceID,
+		RuleID:      rule.RuleID,
+		EventName:   util.NewUUID(),
+		RecordType:  "hpa",
+		Reason:      reason,
+		Count:       1,
+		Description: desc,
+		Operator:    "system",
+		LastTime:    now,
+	}
+	if err := s.dbmanager.TenantServiceScalingRecordsDao().AddModel(record); err != nil {
+		logrus.Warningf("save scaling record: %v", err)
+	}
+}
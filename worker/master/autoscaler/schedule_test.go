@@ -0,0 +1,106 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	"github.com/goodrain/rainbond/db/model"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type scheduleTestManager struct {
+	db.Manager
+	rules   dao.TenantServceAutoscalerRulesDao
+	records dao.TenantServiceScalingRecordsDao
+}
+
+func (m scheduleTestManager) TenantServceAutoscalerRulesDao() dao.TenantServceAutoscalerRulesDao {
+	return m.rules
+}
+
+func (m scheduleTestManager) TenantServiceScalingRecordsDao() dao.TenantServiceScalingRecordsDao {
+	return m.records
+}
+
+type scheduleRulesDao struct {
+	dao.TenantServceAutoscalerRulesDao
+	rules []*model.TenantServiceAutoscalerRules
+}
+
+func (d scheduleRulesDao) ListEnableScheduledOnes() ([]*model.TenantServiceAutoscalerRules, error) {
+	return d.rules, nil
+}
+
+type scheduleRecordsDao struct {
+	dao.TenantServiceScalingRecordsDao
+	records []*model.TenantServiceScalingRecords
+}
+
+func (d *scheduleRecordsDao) AddModel(mo model.Interface) error {
+	d.records = append(d.records, mo.(*model.TenantServiceScalingRecords))
+	return nil
+}
+
+// capability_id: rainbond.worker.master.autoscaler.schedule
+func TestSchedulerPatchesMinReplicas(t *testing.T) {
+	minReplicas := int32(2)
+	clientset := fake.NewSimpleClientset(&autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rule-1",
+			Namespace: "ns",
+			Labels:    map[string]string{"rule_id": "rule-1"},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{MinReplicas: &minReplicas, MaxReplicas: 10},
+	})
+	records := &scheduleRecordsDao{}
+	scheduler := NewScheduler(clientset, scheduleTestManager{
+		rules: scheduleRulesDao{rules: []*model.TenantServiceAutoscalerRules{{
+			RuleID:      "rule-1",
+			ServiceID:   "svc-1",
+			Enable:      true,
+			MinReplicas: 2,
+			MaxReplicas: 10,
+			Schedules:   `[{"name":"workday","schedule":"0 8 * * *","timezone":"UTC","min_replicas":5}]`,
+		}}},
+		records: records,
+	}, true)
+
+	now, _ := time.Parse(time.RFC3339, "2026-03-04T09:00:00Z")
+	scheduler.sync(context.Background(), now)
+	hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("ns").Get(context.Background(), "rule-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get hpa: %v", err)
+	}
+	if *hpa.Spec.MinReplicas != 5 {
+		t.Fatalf("expected min replicas 5, got %d", *hpa.Spec.MinReplicas)
+	}
+	if len(records.records) != 1 || records.records[0].Reason != "SuccessfulScheduledRescale" || records.records[0].RuleID != "rule-1" {
+		t.Fatalf("unexpected scaling records %+v", records.records)
+	}
+
+	// nothing changes until the next schedule fires
+	scheduler.sync(context.Background(), now.Add(time.Hour))
+	if len(records.records) != 1 {
+		t.Fatalf("expected no more scaling records, got %d", len(records.records))
+	}
+}
+
+// capability_id: rainbond.worker.master.autoscaler.schedule
+func TestAutoscalingV2Available(t *testing.T) {
+	for version, want := range map[string]bool{
+		"v1.27.3-eks-2d98532":    true,
+		"v1.23.0":                true,
+		"v1.22.17+k3s1":          false,
+		"v1.20.15-gke.1000":      false,
+		"unknown-vendor-version": true,
+	} {
+		if got := AutoscalingV2Available(version); got != want {
+			t.Errorf("AutoscalingV2Available(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
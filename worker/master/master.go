@@ -21,17 +21,21 @@ package master
 import (
 	"context"
 	"fmt"
+	apiprometheus "github.com/goodrain/rainbond/api/client/prometheus"
 	"github.com/goodrain/rainbond/config/configs"
 	"github.com/goodrain/rainbond/config/configs/rbdcomponent"
 	"github.com/goodrain/rainbond/pkg/component/k8s"
 	"strings"
 	"time"
 
+	apisixversioned "github.com/apache/apisix-ingress-controller/pkg/kube/apisix/client/clientset/versioned"
 	"github.com/goodrain/rainbond/db"
 	"github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/pkg/common"
 	"github.com/goodrain/rainbond/util/leader"
 	"github.com/goodrain/rainbond/worker/appm/store"
+	"github.com/goodrain/rainbond/worker/grayrelease"
+	"github.com/goodrain/rainbond/worker/master/autoscaler"
 	mcontroller "github.com/goodrain/rainbond/worker/master/controller"
 	"github.com/goodrain/rainbond/worker/master/controller/helmapp"
 	"github.com/goodrain/rainbond/worker/master/controller/thirdcomponent"
@@ -40,10 +44,12 @@ import (
 	"github.com/goodrain/rainbond/worker/master/volumes/provider/lib/controller"
 	"github.com/goodrain/rainbond/worker/master/volumes/statistical"
 	"github.com/goodrain/rainbond/worker/master/volumes/sync"
+	"github.com/goodrain/rainbond/worker/notification"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/version"
 	ctrl "sigs.k8s.io/controller-runtime"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/typed/apis/v1beta1"
 )
 
 // Controller app runtime master controller
@@ -71,6 +77,9 @@ type Controller struct {
 	volumeTypeEvent     *sync.VolumeTypeEvent
 	version             *version.Info
 	mgr                 ctrl.Manager
+	scheduler           *autoscaler.Scheduler
+	grayAnalyzer        *grayrelease.Analyzer
+	notifier            *notification.Dispatcher
 }
 
 // NewMasterController new master controller
@@ -155,9 +164,35 @@ func NewMasterController(store store.Storer) (*Controller, error) {
 		podEvent:        podevent.New(stopCh),
 		volumeTypeEvent: sync.New(stopCh),
 		version:         serverVersion,
+		scheduler: autoscaler.NewScheduler(k8s.Default().Clientset, db.GetManager(),
+			autoscaler.AutoscalingV2Available(serverVersion.GitVersion)),
+		grayAnalyzer: newGrayAnalyzer(),
+		notifier:     notification.NewDispatcher(),
 	}, nil
 }
 
+// newGrayAnalyzer creates the gray release analyzer, the metric analysis is
+// disabled when prometheus is unavailable.
+func newGrayAnalyzer() *grayrelease.Analyzer {
+	component := k8s.Default()
+	// nil clients must stay nil interfaces, the routes of an uninstalled kind are skipped
+	var apisix apisixversioned.Interface
+	if component.ApiSixClient != nil {
+		apisix = component.ApiSixClient
+	}
+	var gateway gatewayclient.GatewayV1beta1Interface
+	if component.GatewayClient != nil {
+		gateway = component.GatewayClient
+	}
+	operator := grayrelease.NewOperator(component.KruiseClient, component.Clientset, apisix, gateway)
+	prom, err := apiprometheus.NewPrometheus(&apiprometheus.Options{Endpoint: configs.Default().ServerConfig.PrometheusEndpoint})
+	if err != nil {
+		logrus.Warningf("create prometheus client for gray release analysis: %v", err)
+		return grayrelease.NewAnalyzer(operator, nil)
+	}
+	return grayrelease.NewAnalyzer(operator, prom)
+}
+
 // IsLeader is leader
 func (m *Controller) IsLeader() bool {
 	return m.isLeader
@@ -173,6 +208,10 @@ func (m *Controller) Start() error {
 		}()
 		go m.diskCache.Start()
 		defer m.diskCache.Stop()
+		// pushes the pod anomaly events out to the notification channels of the tenants
+		notification.SetDefault(m.notifier)
+		defer notification.SetDefault(nil)
+		go m.notifier.Start(ctx)
 		m.store.RegistPodUpdateListener("podEvent", m.podEvent.GetChan())
 		defer m.store.UnRegistPodUpdateListener("podEvent")
 		go m.podEvent.Handle()
@@ -186,6 +225,12 @@ func (m *Controller) Start() error {
 
 		go m.volumeTypeEvent.Handle()
 
+		// min replicas schedules of autoscaler rules
+		go m.scheduler.Start(ctx)
+
+		// traffic and metric analysis of gray releases
+		go m.grayAnalyzer.Start(ctx)
+
 		// helm app controller
 		go m.helmAppController.Start()
 		defer m.helmAppController.Stop()
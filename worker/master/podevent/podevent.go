@@ -19,6 +19,7 @@ import (
 	"github.com/goodrain/rainbond/event"
 	"github.com/goodrain/rainbond/util"
 	k8sutil "github.com/goodrain/rainbond/util/k8s"
+	"github.com/goodrain/rainbond/worker/notification"
 	"github.com/goodrain/rainbond/worker/server/pb"
 	wutil "github.com/goodrain/rainbond/worker/util"
 	"github.com/jinzhu/gorm"
@@ -906,9 +907,32 @@ func createSystemEventWithFinalStatus(tenantID, serviceID, targetID, optType, st
 	if err = db.GetManager().ServiceEventDao().AddModel(et); err != nil {
 		return
 	}
+	notification.Publish(&notification.Event{
+		EventID:   eventID,
+		TenantID:  tenantID,
+		ServiceID: serviceID,
+		Type:      optType,
+		Severity:  eventSeverity(optType, status),
+		Target:    targetID,
+		Message:   msg,
+	})
 	return
 }
 
+// eventSeverity 事件的通知级别，恢复类事件为 info，导致容器重启或不可用的事件为 critical
+func eventSeverity(optType, status string) string {
+	if status == model.EventStatusSuccess.String() {
+		return model.NotificationSeverityInfo
+	}
+	switch optType {
+	case EventTypeOOMKilled.String(), EventTypeCrashLoopBackOff.String(), "ContainerExitError", "Evicted",
+		EventTypeLivenessRestart.String(), EventTypeStartupProbeFailure.String():
+		return model.NotificationSeverityCritical
+	default:
+		return model.NotificationSeverityWarning
+	}
+}
+
 // getPodContainerLogs 获取 Pod 容器的最后 N 行日志
 func getPodContainerLogs(clientset kubernetes.Interface, pod *corev1.Pod, containerName string, tailLines int64) (string, error) {
 	if containerName == "" {
@@ -0,0 +1,333 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultThrottle the min interval of the notifications of the same component and event type
+	defaultThrottle = 10 * time.Minute
+	// defaultChannelRate the max notifications a channel sends in a minute
+	defaultChannelRate = 20
+	// defaultQueueSize the buffered events waiting for dispatching, the events are dropped when it is full
+	defaultQueueSize = 1024
+	// defaultAttempts the max attempts of a delivery
+	defaultAttempts = 3
+	// defaultBackoff the interval before the first retry, doubled on each retry
+	defaultBackoff = 2 * time.Second
+)
+
+// Event an event that may be pushed out to the notification channels of the tenant
+type Event struct {
+	EventID   string
+	TenantID  string
+	ServiceID string
+	// Type the event type, e.g. OOMKilled, LivenessRestart
+	Type     string
+	Severity string
+	// Target the object the event happens on, e.g. the pod name
+	Target  string
+	Message string
+	Time    time.Time
+}
+
+// Message the notification rendered from an event
+type Message struct {
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+	EventID      string    `json:"event_id,omitempty"`
+	TenantID     string    `json:"tenant_id"`
+	TenantName   string    `json:"tenant_name,omitempty"`
+	ServiceID    string    `json:"service_id"`
+	ServiceAlias string    `json:"service_alias,omitempty"`
+	EventType    string    `json:"event_type"`
+	Severity     string    `json:"severity"`
+	Target       string    `json:"target,omitempty"`
+	Message      string    `json:"message"`
+	Time         time.Time `json:"time"`
+	// Suppressed the count of the same events throttled since the last notification
+	Suppressed int `json:"suppressed"`
+}
+
+// severityRank ranks the severities, unknown severities are taken as info
+func severityRank(severity string) int {
+	switch severity {
+	case dbmodel.NotificationSeverityCritical:
+		return 2
+	case dbmodel.NotificationSeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SplitList splits a comma separated list and drops the empty items
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsOrEmpty(list, item string) bool {
+	items := SplitList(list)
+	if len(items) == 0 {
+		return true
+	}
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRule reports whether the event is subscribed by the rule
+func matchRule(rule *dbmodel.NotificationRule, ev *Event) bool {
+	if !rule.Enabled || rule.TenantID != ev.TenantID {
+		return false
+	}
+	if severityRank(ev.Severity) < severityRank(rule.MinSeverity) {
+		return false
+	}
+	return containsOrEmpty(rule.EventTypes, ev.Type) && containsOrEmpty(rule.ServiceIDs, ev.ServiceID)
+}
+
+func newMessage(ev *Event, tenantName, serviceAlias string, suppressed int) *Message {
+	msg := &Message{
+		Title:        fmt.Sprintf("[%s] %s", strings.ToUpper(ev.Severity), ev.Type),
+		EventID:      ev.EventID,
+		TenantID:     ev.TenantID,
+		TenantName:   tenantName,
+		ServiceID:    ev.ServiceID,
+		ServiceAlias: serviceAlias,
+		EventType:    ev.Type,
+		Severity:     ev.Severity,
+		Target:       ev.Target,
+		Message:      ev.Message,
+		Time:         ev.Time,
+		Suppressed:   suppressed,
+	}
+	component := serviceAlias
+	if component == "" {
+		component = ev.ServiceID
+	}
+	team := tenantName
+	if team == "" {
+		team = ev.TenantID
+	}
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\nteam: %s\ncomponent: %s\n", msg.Title, team, component)
+	if ev.Target != "" {
+		fmt.Fprintf(&text, "target: %s\n", ev.Target)
+	}
+	fmt.Fprintf(&text, "time: %s\n", ev.Time.Format(time.RFC3339))
+	if ev.Message != "" {
+		fmt.Fprintf(&text, "message: %s\n", ev.Message)
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&text, "%d more same events were suppressed since the last notification\n", suppressed)
+	}
+	msg.Text = strings.TrimSuffix(text.String(), "\n")
+	return msg
+}
+
+// Dispatcher matches the events with the notification rules of the tenants and
+// delivers them to the channels with throttling, rate limiting and retries
+type Dispatcher struct {
+	queue    chan *Event
+	throttle *throttle
+	limiter  *rateLimiter
+	sender   *sender
+	attempts int
+	backoff  time.Duration
+	wg       sync.WaitGroup
+}
+
+// NewDispatcher creates a notification dispatcher
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		queue:    make(chan *Event, defaultQueueSize),
+		throttle: newThrottle(),
+		limiter:  newRateLimiter(defaultChannelRate, time.Minute),
+		sender:   newSender(),
+		attempts: defaultAttempts,
+		backoff:  defaultBackoff,
+	}
+}
+
+// Publish queues the event without blocking, the event is dropped when the queue is full
+func (d *Dispatcher) Publish(ev *Event) bool {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	select {
+	case d.queue <- ev:
+		return true
+	default:
+		logrus.Warningf("notification queue is full, drop event %s of component %s", ev.Type, ev.ServiceID)
+		return false
+	}
+}
+
+// Start dispatches the queued events until the context is done
+func (d *Dispatcher) Start(ctx context.Context) {
+	logrus.Info("start notification dispatcher")
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.wg.Wait()
+			logrus.Info("stop notification dispatcher")
+			return
+		case ev := <-d.queue:
+			d.dispatch(ctx, ev)
+		case now := <-ticker.C:
+			d.throttle.gc(now, 24*time.Hour)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, ev *Event) {
+	rules, err := db.GetManager().NotificationRuleDao().ListEnabledByTenantID(ev.TenantID)
+	if err != nil {
+		logrus.Errorf("list notification rules of tenant %s: %v", ev.TenantID, err)
+		return
+	}
+	var tenantName, serviceAlias string
+	resolved := false
+	for _, rule := range rules {
+		if !matchRule(rule, ev) {
+			continue
+		}
+		window := defaultThrottle
+		if rule.ThrottleSeconds > 0 {
+			window = time.Duration(rule.ThrottleSeconds) * time.Second
+		}
+		key := rule.RuleID + "/" + ev.ServiceID + "/" + ev.Type
+		ok, suppressed := d.throttle.allow(key, window, ev.Time)
+		if !ok {
+			continue
+		}
+		channel, err := db.GetManager().NotificationChannelDao().GetByChannelID(rule.ChannelID)
+		if err != nil {
+			logrus.Errorf("get notification channel %s of rule %s: %v", rule.ChannelID, rule.RuleID, err)
+			continue
+		}
+		if !channel.Enabled {
+			continue
+		}
+		if !d.limiter.allow(channel.ChannelID, time.Now()) {
+			// give the event back to the throttle so that it is counted in the next notification
+			d.throttle.release(key, suppressed+1)
+			logrus.Warningf("notification channel %s exceeds the rate limit, event %s of component %s is suppressed", channel.ChannelID, ev.Type, ev.ServiceID)
+			continue
+		}
+		if !resolved {
+			tenantName, serviceAlias = resolveNames(ev)
+			resolved = true
+		}
+		msg := newMessage(ev, tenantName, serviceAlias, suppressed)
+		d.wg.Add(1)
+		go func(channel *dbmodel.NotificationChannel) {
+			defer d.wg.Done()
+			if err := d.deliver(ctx, channel, msg); err != nil {
+				logrus.Errorf("deliver notification %s to channel %s: %v", msg.EventType, channel.ChannelID, err)
+			}
+		}(channel)
+	}
+}
+
+// deliver sends the message to the channel, retries with exponential backoff on failures
+func (d *Dispatcher) deliver(ctx context.Context, channel *dbmodel.NotificationChannel, msg *Message) error {
+	var err error
+	backoff := d.backoff
+	for attempt := 1; attempt <= d.attempts; attempt++ {
+		if err = d.sender.send(channel, msg); err == nil {
+			return nil
+		}
+		if attempt == d.attempts {
+			break
+		}
+		logrus.Debugf("deliver notification to channel %s failed at attempt %d: %v", channel.ChannelID, attempt, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("after %d attempts: %v", d.attempts, err)
+}
+
+func resolveNames(ev *Event) (tenantName, serviceAlias string) {
+	if tenant, err := db.GetManager().TenantDao().GetTenantByUUID(ev.TenantID); err == nil {
+		tenantName = tenant.Name
+	}
+	if ev.ServiceID != "" {
+		if service, err := db.GetManager().TenantServiceDao().GetServiceByID(ev.ServiceID); err == nil {
+			serviceAlias = service.ServiceAlias
+		}
+	}
+	return
+}
+
+// defaultDispatcher is swapped on leader election while controllers publish concurrently
+var defaultDispatcher atomic.Pointer[Dispatcher]
+
+// SetDefault sets the dispatcher used by Publish
+func SetDefault(d *Dispatcher) {
+	defaultDispatcher.Store(d)
+}
+
+// Publish queues the event to the default dispatcher, it does nothing when the
+// dispatcher is not set, e.g. the worker is not the leader
+func Publish(ev *Event) {
+	if d := defaultDispatcher.Load(); d != nil {
+		d.Publish(ev)
+	}
+}
+
+// SendTest sends a test notification to the channel and returns the delivery error
+func SendTest(channel *dbmodel.NotificationChannel) error {
+	ev := &Event{
+		TenantID: channel.TenantID,
+		Type:     "Test",
+		Severity: dbmodel.NotificationSeverityInfo,
+		Message:  fmt.Sprintf("this is a test notification of channel %s", channel.Name),
+		Time:     time.Now(),
+	}
+	tenantName, _ := resolveNames(ev)
+	return newSender().send(channel, newMessage(ev, tenantName, "", 0))
+}
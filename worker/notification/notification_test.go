@@ -0,0 +1,360 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/db"
+	dbdao "github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+// capability_id: rainbond.worker.notification.rule-match
+func TestMatchRule(t *testing.T) {
+	rule := &dbmodel.NotificationRule{
+		TenantID:    "tenant",
+		EventTypes:  "OOMKilled, LivenessRestart",
+		ServiceIDs:  "svc-a",
+		MinSeverity: dbmodel.NotificationSeverityWarning,
+		Enabled:     true,
+	}
+	ev := &Event{TenantID: "tenant", ServiceID: "svc-a", Type: "OOMKilled", Severity: dbmodel.NotificationSeverityCritical}
+	if !matchRule(rule, ev) {
+		t.Fatal("expected the rule to match")
+	}
+	for name, mutate := range map[string]func(e *Event){
+		"tenant":   func(e *Event) { e.TenantID = "other" },
+		"service":  func(e *Event) { e.ServiceID = "svc-b" },
+		"type":     func(e *Event) { e.Type = "ImagePullBackOff" },
+		"severity": func(e *Event) { e.Severity = dbmodel.NotificationSeverityInfo },
+	} {
+		e := *ev
+		mutate(&e)
+		if matchRule(rule, &e) {
+			t.Fatalf("expected the rule not to match another %s", name)
+		}
+	}
+	all := &dbmodel.NotificationRule{TenantID: "tenant", Enabled: true}
+	if !matchRule(all, &Event{TenantID: "tenant", ServiceID: "svc-b", Type: "Evicted"}) {
+		t.Fatal("expected an empty rule to match all the events of the tenant")
+	}
+	all.Enabled = false
+	if matchRule(all, ev) {
+		t.Fatal("expected a disabled rule not to match")
+	}
+}
+
+// capability_id: rainbond.worker.notification.throttle
+func TestThrottle(t *testing.T) {
+	th := newThrottle()
+	now := time.Now()
+	if ok, n := th.allow("k", time.Minute, now); !ok || n != 0 {
+		t.Fatalf("expected the first event to pass, got %v %d", ok, n)
+	}
+	for i := 1; i <= 3; i++ {
+		if ok, _ := th.allow("k", time.Minute, now.Add(time.Duration(i)*time.Second)); ok {
+			t.Fatal("expected the events in the window to be suppressed")
+		}
+	}
+	if ok, _ := th.allow("other", time.Minute, now); !ok {
+		t.Fatal("expected another key to pass")
+	}
+	ok, n := th.allow("k", time.Minute, now.Add(time.Minute))
+	if !ok || n != 3 {
+		t.Fatalf("expected the next window to carry 3 suppressed events, got %v %d", ok, n)
+	}
+	th.release("k", n+1)
+	ok, n = th.allow("k", time.Minute, now.Add(time.Minute+time.Second))
+	if !ok || n != 4 {
+		t.Fatalf("expected a released key to pass with 4 suppressed events, got %v %d", ok, n)
+	}
+	th.gc(now.Add(time.Hour), time.Minute)
+	if len(th.states) != 0 {
+		t.Fatalf("expected idle keys to be dropped, got %d", len(th.states))
+	}
+
+	limiter := newRateLimiter(2, time.Minute)
+	if !limiter.allow("c", now) || !limiter.allow("c", now) || limiter.allow("c", now) {
+		t.Fatal("expected the third notification in a minute to be limited")
+	}
+	if !limiter.allow("c", now.Add(time.Minute)) {
+		t.Fatal("expected the limit to reset in the next window")
+	}
+}
+
+type capturedRequest struct {
+	query string
+	body  map[string]interface{}
+	raw   string
+}
+
+func captureServer(t *testing.T, response string) (*httptest.Server, func() []capturedRequest) {
+	var lock sync.Mutex
+	var requests []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := capturedRequest{query: r.URL.RawQuery, raw: string(data)}
+		_ = json.Unmarshal(data, &req.body)
+		lock.Lock()
+		requests = append(requests, req)
+		lock.Unlock()
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []capturedRequest {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+func testChannel(t *testing.T, channelType string, cfg map[string]interface{}) *dbmodel.NotificationChannel {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &dbmodel.NotificationChannel{ChannelID: "channel", TenantID: "tenant", Type: channelType, Config: string(data), Enabled: true}
+}
+
+// capability_id: rainbond.worker.notification.channel-payloads
+func TestSenderPayloads(t *testing.T) {
+	msg := newMessage(&Event{
+		TenantID: "tenant", ServiceID: "svc", Type: "OOMKilled", Severity: dbmodel.NotificationSeverityCritical,
+		Target: "pod-0", Message: "out of memory", Time: time.Unix(1700000000, 0),
+	}, "team", "web", 2)
+	if !strings.Contains(msg.Text, "component: web") || !strings.Contains(msg.Text, "2 more same events") {
+		t.Fatalf("unexpected text %q", msg.Text)
+	}
+	s := newSender()
+	s.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	srv, requests := captureServer(t, `{"errcode":0}`)
+	channel := testChannel(t, dbmodel.NotificationChannelWebhook, map[string]interface{}{
+		"url": srv.URL, "body_template": `{"alert":"{{.EventType}}","app":"{{.ServiceAlias}}","n":{{.Suppressed}},"text":{{json .Text}}}`,
+	})
+	if err := s.send(channel, msg); err != nil {
+		t.Fatal(err)
+	}
+	templated := requests()[0]
+	if templated.body["alert"] != "OOMKilled" || templated.body["app"] != "web" || templated.body["n"] != float64(2) ||
+		templated.body["text"] != msg.Text {
+		t.Fatalf("unexpected templated body %s", templated.raw)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("internal secret"))
+	}))
+	t.Cleanup(failing.Close)
+	err := s.send(testChannel(t, dbmodel.NotificationChannelWebhook, map[string]interface{}{"url": failing.URL}), msg)
+	if err == nil || err.Error() != "status 403" {
+		t.Fatalf("expected only the status of the failed request, got %v", err)
+	}
+
+	for _, channelType := range []string{dbmodel.NotificationChannelSlack, dbmodel.NotificationChannelDingTalk,
+		dbmodel.NotificationChannelFeishu, dbmodel.NotificationChannelWeCom} {
+		srv, requests := captureServer(t, `{"errcode":0,"code":0}`)
+		channel := testChannel(t, channelType, map[string]interface{}{"url": srv.URL + "/hook?access_token=x", "secret": "SEC"})
+		if err := s.send(channel, msg); err != nil {
+			t.Fatalf("send %s: %v", channelType, err)
+		}
+		req := requests()[0]
+		switch channelType {
+		case dbmodel.NotificationChannelSlack:
+			if req.body["text"] != msg.Text {
+				t.Fatalf("unexpected slack body %s", req.raw)
+			}
+		case dbmodel.NotificationChannelDingTalk:
+			if req.body["msgtype"] != "text" || !strings.Contains(req.query, "timestamp=1700000000000&sign=") {
+				t.Fatalf("unexpected dingtalk request %s %s", req.query, req.raw)
+			}
+		case dbmodel.NotificationChannelFeishu:
+			if req.body["msg_type"] != "text" || req.body["timestamp"] != "1700000000" || req.body["sign"] == "" {
+				t.Fatalf("unexpected feishu body %s", req.raw)
+			}
+		case dbmodel.NotificationChannelWeCom:
+			if req.body["msgtype"] != "text" {
+				t.Fatalf("unexpected wecom body %s", req.raw)
+			}
+		}
+	}
+
+	robot, _ := captureServer(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+	if err := s.send(testChannel(t, dbmodel.NotificationChannelDingTalk, map[string]interface{}{"url": robot.URL}), msg); err == nil {
+		t.Fatal("expected the robot error code to fail the delivery")
+	}
+
+	var mail string
+	s.sendMail = func(addr string, a smtp.Auth, from string, to []string, body []byte) error {
+		if addr != "smtp.example.com:465" || from != "rbd@example.com" || len(to) != 2 || a == nil {
+			t.Fatalf("unexpected smtp call %s %s %v", addr, from, to)
+		}
+		mail = string(body)
+		return nil
+	}
+	email := testChannel(t, dbmodel.NotificationChannelEmail, map[string]interface{}{
+		"smtp_host": "smtp.example.com", "smtp_port": 465, "username": "u", "password": "p",
+		"from": "rbd@example.com", "to": []string{"a@example.com", "b@example.com"},
+	})
+	if err := s.send(email, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(mail, "Subject: [CRITICAL] OOMKilled\r\n") {
+		t.Fatalf("unexpected mail %q", mail)
+	}
+}
+
+type notifyTestManager struct {
+	db.Manager
+	rules    []*dbmodel.NotificationRule
+	channels map[string]*dbmodel.NotificationChannel
+}
+
+func (m *notifyTestManager) NotificationRuleDao() dbdao.NotificationRuleDao {
+	return notifyTestRuleDao{m: m}
+}
+
+func (m *notifyTestManager) NotificationChannelDao() dbdao.NotificationChannelDao {
+	return notifyTestChannelDao{m: m}
+}
+
+func (m *notifyTestManager) TenantDao() dbdao.TenantDao { return notifyTestTenantDao{} }
+
+func (m *notifyTestManager) TenantServiceDao() dbdao.TenantServiceDao { return notifyTestServiceDao{} }
+
+type notifyTestRuleDao struct {
+	dbdao.NotificationRuleDao
+	m *notifyTestManager
+}
+
+func (d notifyTestRuleDao) ListEnabledByTenantID(tenantID string) ([]*dbmodel.NotificationRule, error) {
+	return d.m.rules, nil
+}
+
+type notifyTestChannelDao struct {
+	dbdao.NotificationChannelDao
+	m *notifyTestManager
+}
+
+func (d notifyTestChannelDao) GetByChannelID(channelID string) (*dbmodel.NotificationChannel, error) {
+	if channel, ok := d.m.channels[channelID]; ok {
+		return channel, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type notifyTestTenantDao struct {
+	dbdao.TenantDao
+}
+
+func (notifyTestTenantDao) GetTenantByUUID(uuid string) (*dbmodel.Tenants, error) {
+	return &dbmodel.Tenants{UUID: uuid, Name: "team"}, nil
+}
+
+type notifyTestServiceDao struct {
+	dbdao.TenantServiceDao
+}
+
+func (notifyTestServiceDao) GetServiceByID(serviceID string) (*dbmodel.TenantServices, error) {
+	return &dbmodel.TenantServices{ServiceID: serviceID, ServiceAlias: "web"}, nil
+}
+
+// capability_id: rainbond.worker.notification.dispatch-throttled
+func TestDispatchThrottlesCrashLoop(t *testing.T) {
+	srv, requests := captureServer(t, `ok`)
+	channel := testChannel(t, dbmodel.NotificationChannelWebhook, map[string]interface{}{"url": srv.URL})
+	db.SetTestManager(&notifyTestManager{
+		rules: []*dbmodel.NotificationRule{{
+			RuleID: "rule", TenantID: "tenant", ChannelID: "channel", EventTypes: "CrashLoopBackOff",
+			MinSeverity: dbmodel.NotificationSeverityWarning, ThrottleSeconds: 60, Enabled: true,
+		}},
+		channels: map[string]*dbmodel.NotificationChannel{"channel": channel},
+	})
+	defer db.SetTestManager(nil)
+
+	d := NewDispatcher()
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		d.dispatch(ctx, &Event{TenantID: "tenant", ServiceID: "svc", Type: "CrashLoopBackOff",
+			Severity: dbmodel.NotificationSeverityCritical, Time: start.Add(time.Duration(i) * time.Second)})
+	}
+	d.dispatch(ctx, &Event{TenantID: "tenant", ServiceID: "svc", Type: "Evicted",
+		Severity: dbmodel.NotificationSeverityCritical, Time: start})
+	d.wg.Wait()
+	if got := len(requests()); got != 1 {
+		t.Fatalf("expected one notification for the crash loop, got %d", got)
+	}
+
+	d.dispatch(ctx, &Event{TenantID: "tenant", ServiceID: "svc", Type: "CrashLoopBackOff",
+		Severity: dbmodel.NotificationSeverityCritical, Time: start.Add(2 * time.Minute)})
+	d.wg.Wait()
+	reqs := requests()
+	if len(reqs) != 2 || reqs[1].body["suppressed"] != float64(4) || reqs[1].body["service_alias"] != "web" {
+		t.Fatalf("expected the next notification to carry 4 suppressed events, got %+v", reqs)
+	}
+}
+
+// capability_id: rainbond.worker.notification.retry
+func TestDeliverRetries(t *testing.T) {
+	var lock sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	d := NewDispatcher()
+	d.backoff = time.Millisecond
+	channel := testChannel(t, dbmodel.NotificationChannelSlack, map[string]interface{}{"url": srv.URL})
+	msg := newMessage(&Event{TenantID: "tenant", Type: "OOMKilled", Time: time.Now()}, "", "", 0)
+	if err := d.deliver(context.Background(), channel, msg); err != nil {
+		t.Fatalf("expected the third attempt to succeed: %v", err)
+	}
+	d.attempts = 2
+	calls = 0
+	if err := d.deliver(context.Background(), channel, msg); err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("expected the delivery to fail after 2 attempts, got %v", err)
+	}
+}
+
+// capability_id: rainbond.worker.notification.validate-config
+func TestValidateChannelConfig(t *testing.T) {
+	valid := map[string]*apimodel.NotificationChannelConfig{
+		dbmodel.NotificationChannelWebhook:  {URL: "https://hooks.example.com/x", BodyTemplate: `{"t":"{{.Title}}"}`},
+		dbmodel.NotificationChannelDingTalk: {URL: "https://oapi.dingtalk.com/robot/send?access_token=x"},
+		dbmodel.NotificationChannelEmail:    {SMTPHost: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}},
+	}
+	for channelType, cfg := range valid {
+		if err := ValidateChannelConfig(channelType, cfg); err != nil {
+			t.Fatalf("expected %s config to be valid: %v", channelType, err)
+		}
+	}
+	invalid := map[string]*apimodel.NotificationChannelConfig{
+		dbmodel.NotificationChannelWebhook: {URL: "https://hooks.example.com/x", BodyTemplate: `{{.Title`},
+		dbmodel.NotificationChannelSlack:   {URL: "ftp://example.com"},
+		dbmodel.NotificationChannelEmail:   {SMTPHost: "smtp.example.com"},
+		"sms":                              {URL: "https://example.com"},
+	}
+	for channelType, cfg := range invalid {
+		if err := ValidateChannelConfig(channelType, cfg); err == nil {
+			t.Fatalf("expected %s config to be rejected", channelType)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	apimodel "github.com/goodrain/rainbond/api/model"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+)
+
+// sender delivers a message to a channel once
+type sender struct {
+	client   *http.Client
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now      func() time.Time
+}
+
+func newSender() *sender {
+	return &sender{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		sendMail: smtp.SendMail,
+		now:      time.Now,
+	}
+}
+
+// ParseChannelConfig parses the config stored with the channel
+func ParseChannelConfig(channel *dbmodel.NotificationChannel) (*apimodel.NotificationChannelConfig, error) {
+	var cfg apimodel.NotificationChannelConfig
+	if channel.Config == "" {
+		return &cfg, nil
+	}
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return nil, fmt.Errorf("parse config of channel %s: %v", channel.ChannelID, err)
+	}
+	return &cfg, nil
+}
+
+// ValidateChannelConfig checks the config has what the channel type requires
+func ValidateChannelConfig(channelType string, cfg *apimodel.NotificationChannelConfig) error {
+	switch channelType {
+	case dbmodel.NotificationChannelEmail:
+		if cfg.SMTPHost == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return fmt.Errorf("smtp_host, from and to are required by email channels")
+		}
+		return nil
+	case dbmodel.NotificationChannelWebhook, dbmodel.NotificationChannelSlack,
+		dbmodel.NotificationChannelDingTalk, dbmodel.NotificationChannelFeishu, dbmodel.NotificationChannelWeCom:
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("a http or https url is required by %s channels", channelType)
+		}
+		if cfg.BodyTemplate != "" {
+			if _, err := parseBodyTemplate(cfg.BodyTemplate); err != nil {
+				return fmt.Errorf("parse body template: %v", err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported channel type %s", channelType)
+	}
+}
+
+func (s *sender) send(channel *dbmodel.NotificationChannel, msg *Message) error {
+	cfg, err := ParseChannelConfig(channel)
+	if err != nil {
+		return err
+	}
+	switch channel.Type {
+	case dbmodel.NotificationChannelWebhook:
+		return s.sendWebhook(cfg, msg)
+	case dbmodel.NotificationChannelEmail:
+		return s.sendEmail(cfg, msg)
+	case dbmodel.NotificationChannelSlack:
+		_, err := s.postJSON(cfg.URL, map[string]interface{}{"text": msg.Text})
+		return err
+	case dbmodel.NotificationChannelDingTalk:
+		return s.sendDingTalk(cfg, msg)
+	case dbmodel.NotificationChannelFeishu:
+		return s.sendFeishu(cfg, msg)
+	case dbmodel.NotificationChannelWeCom:
+		return s.postRobot(cfg.URL, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": msg.Text},
+		})
+	default:
+		return fmt.Errorf("unsupported channel type %s", channel.Type)
+	}
+}
+
+// sendWebhook renders the body with the template of the channel, or posts the message as json
+func (s *sender) sendWebhook(cfg *apimodel.NotificationChannelConfig, msg *Message) error {
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	if cfg.BodyTemplate == "" {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = s.do(method, cfg.URL, cfg.Headers, "application/json", body)
+		return err
+	}
+	tmpl, err := parseBodyTemplate(cfg.BodyTemplate)
+	if err != nil {
+		return fmt.Errorf("parse body template: %v", err)
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, msg); err != nil {
+		return fmt.Errorf("render body template: %v", err)
+	}
+	_, err = s.do(method, cfg.URL, cfg.Headers, "application/json", body.Bytes())
+	return err
+}
+
+// bodyTemplateFuncs the functions of webhook body templates. The body is sent as json while
+// text/template does not escape, json renders a value as a json literal, e.g. {"text":{{json .Text}}},
+// so the quotes and newlines of the event message keep the body valid
+var bodyTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func parseBodyTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(bodyTemplateFuncs).Parse(text)
+}
+
+// sendDingTalk posts a text message to a dingtalk robot, signs the request when the secret is set
+func (s *sender) sendDingTalk(cfg *apimodel.NotificationChannelConfig, msg *Message) error {
+	address := cfg.URL
+	if cfg.Secret != "" {
+		timestamp := strconv.FormatInt(s.now().UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.Secret))
+		mac.Write([]byte(timestamp + "\n" + cfg.Secret))
+		sign := url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		sep := "?"
+		if strings.Contains(address, "?") {
+			sep = "&"
+		}
+		address = fmt.Sprintf("%s%stimestamp=%s&sign=%s", address, sep, timestamp, sign)
+	}
+	return s.postRobot(address, map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": msg.Text},
+	})
+}
+
+// sendFeishu posts a text message to a feishu robot, signs the body when the secret is set
+func (s *sender) sendFeishu(cfg *apimodel.NotificationChannelConfig, msg *Message) error {
+	body := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": msg.Text},
+	}
+	if cfg.Secret != "" {
+		timestamp := strconv.FormatInt(s.now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+cfg.Secret))
+		body["timestamp"] = timestamp
+		body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return s.postRobot(cfg.URL, body)
+}
+
+func (s *sender) sendEmail(cfg *apimodel.NotificationChannelConfig, msg *Message) error {
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = 25
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
+	}
+	var mail bytes.Buffer
+	fmt.Fprintf(&mail, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&mail, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&mail, "Subject: %s\r\n", msg.Title)
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	mail.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port))
+	return s.sendMail(addr, auth, cfg.From, cfg.To, mail.Bytes())
+}
+
+func (s *sender) postJSON(address string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(http.MethodPost, address, nil, "application/json", body)
+}
+
+// postRobot posts the payload to a dingtalk, feishu or wecom robot, they answer
+// 200 with a non zero code on failures
+func (s *sender) postRobot(address string, payload interface{}) error {
+	data, err := s.postJSON(address, payload)
+	if err != nil {
+		return err
+	}
+	var robot robotResponse
+	if json.Unmarshal(data, &robot) == nil {
+		if robot.ErrCode != nil && *robot.ErrCode != 0 {
+			return fmt.Errorf("errcode %d: %s", *robot.ErrCode, robot.ErrMsg)
+		}
+		if robot.Code != nil && *robot.Code != 0 {
+			return fmt.Errorf("code %d: %s", *robot.Code, robot.Msg)
+		}
+	}
+	return nil
+}
+
+// robotResponse the response of the dingtalk, feishu and wecom robots
+type robotResponse struct {
+	ErrCode *int   `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    *int   `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+func (s *sender) do(method, address string, headers map[string]string, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// the response of a failed request is not returned, the channel url is set by the tenant
+	// and the test notification hands the error back to it
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("status %d", res.StatusCode)
+	}
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	return data, nil
+}
@@ -0,0 +1,115 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package notification
+
+import (
+	"sync"
+	"time"
+)
+
+type throttleState struct {
+	last       time.Time
+	suppressed int
+}
+
+// throttle lets the first event of a key through in a window and counts the others,
+// the count is carried by the next notification so that a crash looping pod
+// produces one notification per window
+type throttle struct {
+	lock   sync.Mutex
+	states map[string]*throttleState
+}
+
+func newThrottle() *throttle {
+	return &throttle{states: make(map[string]*throttleState)}
+}
+
+// allow reports whether an event of the key may be sent at now, and the count of
+// the events suppressed since the last one sent
+func (t *throttle) allow(key string, window time.Duration, now time.Time) (bool, int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	state, ok := t.states[key]
+	if !ok {
+		t.states[key] = &throttleState{last: now}
+		return true, 0
+	}
+	if now.Sub(state.last) < window {
+		state.suppressed++
+		return false, 0
+	}
+	suppressed := state.suppressed
+	state.last = now
+	state.suppressed = 0
+	return true, suppressed
+}
+
+// release reverts an allowed event that is not sent, the next event of the key is
+// allowed and carries the suppressed count
+func (t *throttle) release(key string, suppressed int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if state, ok := t.states[key]; ok {
+		state.last = time.Time{}
+		state.suppressed += suppressed
+	}
+}
+
+// gc drops the keys idle longer than maxIdle
+func (t *throttle) gc(now time.Time, maxIdle time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, state := range t.states {
+		if now.Sub(state.last) > maxIdle {
+			delete(t.states, key)
+		}
+	}
+}
+
+// rateLimiter caps the notifications of a channel in a fixed window
+type rateLimiter struct {
+	lock    sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow)}
+}
+
+func (r *rateLimiter) allow(key string, now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	w, ok := r.windows[key]
+	if !ok || now.Sub(w.start) >= r.window {
+		r.windows[key] = &rateWindow{start: now, count: 1}
+		return true
+	}
+	if w.count >= r.limit {
+		return false
+	}
+	w.count++
+	return true
+}